@@ -34,7 +34,7 @@ async def chat(req: ChatRequest):
     # If streaming is requested, return streaming response
     if req.stream:
         return StreamingResponse(
-            stream_chat_response(messages, req.max_tokens),
+            stream_chat_response(messages, req.max_tokens, req.model or MODEL),
             media_type="text/plain",
             headers={
                 "Cache-Control": "no-cache",
@@ -45,7 +45,7 @@ async def chat(req: ChatRequest):
 
     # Non-streaming response
     try:
-        response = await ollama.generate_chat(model=MODEL, messages=messages, max_tokens=req.max_tokens)
+        response = await ollama.generate_chat(model=req.model or MODEL, messages=messages, max_tokens=req.max_tokens)
     except Exception as e:
         raise HTTPException(status_code=500, detail=str(e))
 
@@ -69,7 +69,7 @@ async def chat_stream(req: ChatRequest):
         messages.append({"role": m.role, "content": m.content})
 
     return StreamingResponse(
-        stream_chat_sse(messages, req.max_tokens),
+        stream_chat_sse(messages, req.max_tokens, req.model or MODEL),
         media_type="text/event-stream",
         headers={
             "Cache-Control": "no-cache",
@@ -94,18 +94,18 @@ async def legacy_chat_stream(request: Request):
 
     return StreamingResponse(generate(), media_type="text/plain")
 
-async def stream_chat_response(messages: list, max_tokens: int):
+async def stream_chat_response(messages: list, max_tokens: int, model: str = MODEL):
     """Stream chat response as plain text for Laravel backend consumption."""
     try:
-        async for chunk in ollama.stream_chat(model=MODEL, messages=messages):
+        async for chunk in ollama.stream_chat(model=model, messages=messages):
             yield chunk
     except Exception as e:
         yield f"Error: {str(e)}"
 
-async def stream_chat_sse(messages: list, max_tokens: int):
+async def stream_chat_sse(messages: list, max_tokens: int, model: str = MODEL):
     """Stream chat response as Server-Sent Events."""
     try:
-        async for chunk in ollama.stream_chat(model=MODEL, messages=messages):
+        async for chunk in ollama.stream_chat(model=model, messages=messages):
             # Format as Server-Sent Events
             yield f"data: {json.dumps({'content': chunk})}\n\n"
         # Send end signal
@@ -17,6 +17,10 @@ class TrainingRequest(BaseModel):
     version_id: str
     files: List[Dict[str, Any]]  # List of file info: {id, name, path, mime_type}
     db_config: Dict[str, str]  # Database connection info
+    chunk_size: Optional[int] = None  # Overrides CHUNK_SIZE for this run
+    chunk_overlap: Optional[int] = None  # Overrides CHUNK_OVERLAP for this run
+    embedding_model: Optional[str] = None  # Overrides EMBEDDING_MODEL for this run
+    compress_chunks: bool = False  # Store chunk text gzip-compressed (knowledge base opt-in)
 
 class TrainingProgress(BaseModel):
     current_file: int
@@ -59,6 +63,7 @@ async def stream_training(request: TrainingRequest):
             
             # Track file details for detailed progress
             file_details = []
+            embeddings_written = 0
             job_id = request.files[0].get("job_id") if request.files else None
             job_index = request.files[0].get("job_index") if request.files else None
             total_jobs = request.files[0].get("total_jobs") if request.files else None
@@ -111,7 +116,9 @@ async def stream_training(request: TrainingRequest):
                     chunks = await training_service.process_file(
                         file_path=file_path,
                         file_id=file_info.get("id"),
-                        mime_type=file_info.get("mime_type", "")
+                        mime_type=file_info.get("mime_type", ""),
+                        chunk_size=request.chunk_size,
+                        chunk_overlap=request.chunk_overlap
                     )
                     
                     total_chunks = len(chunks)
@@ -144,7 +151,7 @@ async def stream_training(request: TrainingRequest):
                         yield f"data: {json.dumps(progress)}\n\n"
                         
                         # Generate embedding
-                        embedding = await training_service.generate_embedding(chunk["text"])
+                        embedding = await training_service.generate_embedding(chunk["text"], model=request.embedding_model)
                         
                         # Store embedding in database
                         await training_service.store_embedding(
@@ -155,8 +162,10 @@ async def stream_training(request: TrainingRequest):
                             chunk_text=chunk["text"],
                             embedding=embedding,
                             metadata=chunk.get("metadata", {}),
-                            db_config=request.db_config
+                            db_config=request.db_config,
+                            compress=request.compress_chunks
                         )
+                        embeddings_written += 1
                         
                         # Update file detail status
                         file_detail["status"] = "storing"
@@ -237,6 +246,7 @@ async def stream_training(request: TrainingRequest):
                 "total_files": total_files,
                 "percentage": 100,
                 "status": "completed",
+                "embeddings_written": embeddings_written,
                 "message": "Training completed successfully"
             }
             yield f"data: {json.dumps(completion)}\n\n"
@@ -258,3 +268,73 @@ async def stream_training(request: TrainingRequest):
         }
     )
 
+
+class EmbedRequest(BaseModel):
+    texts: List[str]
+    model: Optional[str] = None  # Overrides EMBEDDING_MODEL, e.g. to match a version's model
+
+@router.post("/embed")
+async def embed(request: EmbedRequest):
+    """
+    Generate embeddings for the given texts without storing them.
+    """
+    embeddings = []
+    for text in request.texts:
+        try:
+            embeddings.append(await training_service.generate_embedding(text, model=request.model))
+        except Exception as e:
+            raise HTTPException(status_code=502, detail=str(e))
+
+    return {
+        "model": request.model or training_service.embedding_model,
+        "dimension": len(embeddings[0]) if embeddings else 0,
+        "embeddings": embeddings
+    }
+
+
+class PreviewChunksRequest(BaseModel):
+    path: str
+    file_id: str = ""
+    mime_type: str = ""
+    max_chunks: int = 20
+    chunk_size: Optional[int] = None
+    chunk_overlap: Optional[int] = None
+
+@router.post("/training/preview-chunks")
+async def preview_chunks(request: PreviewChunksRequest):
+    """
+    Chunk a file the way training would, without generating or storing embeddings.
+    Only the first max_chunks chunks are returned.
+    """
+    try:
+        chunks = await training_service.process_file(
+            file_path=request.path,
+            file_id=request.file_id,
+            mime_type=request.mime_type,
+            chunk_size=request.chunk_size,
+            chunk_overlap=request.chunk_overlap
+        )
+    except FileNotFoundError as e:
+        raise HTTPException(status_code=404, detail=str(e))
+    except ValueError as e:
+        raise HTTPException(status_code=422, detail=str(e))
+    except Exception as e:
+        raise HTTPException(status_code=500, detail=str(e))
+
+    preview = chunks[:max(request.max_chunks, 0)]
+    return {
+        "chunk_size": request.chunk_size or training_service.chunk_size,
+        "chunk_overlap": training_service.chunk_overlap if request.chunk_overlap is None else request.chunk_overlap,
+        "total_chunks": len(chunks),
+        "truncated": len(preview) < len(chunks),
+        "chunks": [
+            {
+                "index": i,
+                "text": chunk["text"],
+                "size": len(chunk["text"]),
+                "start": chunk["metadata"].get("chunk_start"),
+                "end": chunk["metadata"].get("chunk_end"),
+            }
+            for i, chunk in enumerate(preview)
+        ]
+    }
@@ -6,6 +6,7 @@ Handles file processing, text chunking, embedding generation, and storage.
 
 import os
 import json
+import gzip
 import asyncio
 from typing import List, Dict, Any, Optional
 import httpx
@@ -50,10 +51,12 @@ class TrainingService:
         self.chunk_size = CHUNK_SIZE
         self.chunk_overlap = CHUNK_OVERLAP
     
-    async def process_file(self, file_path: str, file_id: str, mime_type: str) -> List[Dict[str, Any]]:
+    async def process_file(self, file_path: str, file_id: str, mime_type: str,
+                           chunk_size: Optional[int] = None, chunk_overlap: Optional[int] = None) -> List[Dict[str, Any]]:
         """
         Process a file and extract text content.
         Returns list of text chunks with metadata.
+        chunk_size and chunk_overlap override the configured chunking parameters.
         """
         if not os.path.exists(file_path):
             raise FileNotFoundError(f"File not found: {file_path}")
@@ -88,7 +91,7 @@ class TrainingService:
             raise ValueError(f"No text content extracted from {file_path}")
         
         # Chunk the text
-        chunks = self._chunk_text(text, metadata)
+        chunks = self._chunk_text(text, metadata, chunk_size, chunk_overlap)
         
         return chunks
     
@@ -154,16 +157,22 @@ class TrainingService:
         with open(file_path, "r", encoding="utf-8", errors="ignore") as f:
             return f.read()
     
-    def _chunk_text(self, text: str, metadata: Dict[str, Any]) -> List[Dict[str, Any]]:
+    def _chunk_text(self, text: str, metadata: Dict[str, Any],
+                    chunk_size: Optional[int] = None, chunk_overlap: Optional[int] = None) -> List[Dict[str, Any]]:
         """
         Split text into overlapping chunks.
         """
+        chunk_size = chunk_size or self.chunk_size
+        chunk_overlap = self.chunk_overlap if chunk_overlap is None else chunk_overlap
+        if chunk_size <= 0 or chunk_overlap < 0 or chunk_overlap >= chunk_size:
+            raise ValueError("chunk_overlap must be non-negative and smaller than chunk_size")
+        
         chunks = []
         start = 0
         text_length = len(text)
         
         while start < text_length:
-            end = start + self.chunk_size
+            end = start + chunk_size
             chunk_text = text[start:end]
             
             if chunk_text.strip():
@@ -177,17 +186,18 @@ class TrainingService:
                 })
             
             # Move start position with overlap
-            start = end - self.chunk_overlap
+            start = end - chunk_overlap
         
         return chunks
     
-    async def generate_embedding(self, text: str) -> List[float]:
+    async def generate_embedding(self, text: str, model: Optional[str] = None) -> List[float]:
         """
         Generate embedding vector for text using Ollama.
+        model overrides the configured embedding model.
         """
         url = f"{self.ollama_url}/api/embeddings"
         payload = {
-            "model": self.embedding_model,
+            "model": model or self.embedding_model,
             "input": text
         }
         
@@ -209,10 +219,14 @@ class TrainingService:
         chunk_text: str,
         embedding: List[float],
         metadata: Dict[str, Any],
-        db_config: Dict[str, str]
+        db_config: Dict[str, str],
+        compress: bool = False
     ):
         """
         Store embedding in PostgreSQL using pgvector.
+        
+        With compress, the chunk text is gzip-compressed into chunk_data and chunk_text is left
+        empty; the API decompresses it when reading chunks.
         """
         try:
             import psycopg2
@@ -241,16 +255,28 @@ class TrainingService:
             # Convert embedding to PostgreSQL vector format
             embedding_str = "[" + ",".join(str(f) for f in embedding) + "]"
             
+            # Compressed chunks keep their length for the version's chunk size metrics
+            chunk_data = None
+            chunk_length = None
+            if compress:
+                chunk_data = psycopg2.Binary(gzip.compress(chunk_text.encode("utf-8")))
+                chunk_length = len(chunk_text)
+                chunk_text = ""
+            
             # Insert embedding
             query = """
                 INSERT INTO knowledge_base_embeddings (
                     id, knowledge_base_id, knowledge_base_version_id, knowledge_base_file_id,
-                    chunk_index, chunk_text, embedding, metadata, created_at, updated_at
+                    chunk_index, chunk_text, compressed, chunk_data, chunk_length,
+                    embedding, metadata, created_at, updated_at
                 )
-                VALUES (%s, %s, %s, %s, %s, %s, %s::vector, %s::jsonb, NOW(), NOW())
+                VALUES (%s, %s, %s, %s, %s, %s, %s, %s, %s, %s::vector, %s::jsonb, NOW(), NOW())
                 ON CONFLICT (knowledge_base_version_id, knowledge_base_file_id, chunk_index) 
                 DO UPDATE SET
                     chunk_text = EXCLUDED.chunk_text,
+                    compressed = EXCLUDED.compressed,
+                    chunk_data = EXCLUDED.chunk_data,
+                    chunk_length = EXCLUDED.chunk_length,
                     embedding = EXCLUDED.embedding,
                     metadata = EXCLUDED.metadata,
                     updated_at = NOW()
@@ -263,6 +289,9 @@ class TrainingService:
                 int(file_id),
                 chunk_index,
                 chunk_text,
+                compress,
+                chunk_data,
+                chunk_length,
                 embedding_str,
                 Json(metadata)
             ))
@@ -7,7 +7,6 @@ import (
 	"os"
 	"path/filepath"
 	"regexp"
-	"strconv"
 	"strings"
 	"time"
 
@@ -19,6 +18,8 @@ func main() {
 		command = flag.String("command", "up", "Migration command: up, down, version, create, fresh, force")
 		name    = flag.String("name", "", "Name for new migration (required for create command)")
 		version = flag.Int("version", -1, "Version number (required for force command)")
+		dryRun  = flag.Bool("dry-run", false, "Show what the fresh command would drop and run without changing anything")
+		force   = flag.Bool("force", false, "Confirm the fresh command (required to drop tables)")
 	)
 	flag.Parse()
 
@@ -32,7 +33,7 @@ func main() {
 			log.Fatalf("❌ Rollback failed: %v", err)
 		}
 	case "fresh":
-		if err := migrations.FreshMigrations(); err != nil {
+		if err := migrations.FreshMigrations(migrations.FreshOptions{DryRun: *dryRun, Force: *force}); err != nil {
 			log.Fatalf("❌ Fresh migration failed: %v", err)
 		}
 	case "version":
@@ -131,29 +132,12 @@ func createMigrationFiles(name string) error {
 
 // getNextMigrationVersion finds the highest version number in existing migrations
 func getNextMigrationVersion(migrationsDir string) (int, error) {
-	files, err := os.ReadDir(migrationsDir)
+	maxVersion, err := migrations.LatestFileVersion(migrationsDir)
 	if err != nil {
 		return 1, nil // If directory doesn't exist or is empty, start at 1
 	}
 
-	maxVersion := 0
-	versionRegex := regexp.MustCompile(`^(\d+)_`)
-
-	for _, file := range files {
-		if file.IsDir() {
-			continue
-		}
-
-		matches := versionRegex.FindStringSubmatch(file.Name())
-		if len(matches) > 1 {
-			version, err := strconv.Atoi(matches[1])
-			if err == nil && version > maxVersion {
-				maxVersion = version
-			}
-		}
-	}
-
-	return maxVersion + 1, nil
+	return int(maxVersion) + 1, nil
 }
 
 // sanitizeMigrationName converts a name to snake_case and removes invalid characters
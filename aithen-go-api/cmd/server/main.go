@@ -2,19 +2,29 @@ package main
 
 import (
 	"log"
+	"strconv"
+	"strings"
 
 	"github.com/gin-gonic/gin"
 
 	"github.com/aithen/go-api/internal/auth"
 	"github.com/aithen/go-api/internal/config"
 	"github.com/aithen/go-api/internal/db"
+	"github.com/aithen/go-api/internal/middleware"
+	"github.com/aithen/go-api/internal/migrations"
+	"github.com/aithen/go-api/internal/models"
 	"github.com/aithen/go-api/internal/router"
+	"github.com/aithen/go-api/internal/uploads"
+	"github.com/aithen/go-api/internal/version"
 )
 
 func main() {
 	// Load environment variables
 	config.LoadEnv()
 
+	build := version.Get()
+	log.Printf("starting server version=%s commit=%s build_time=%s go=%s", build.Version, build.Commit, build.BuildTime, build.GoVersion)
+
 	// Initialize JWT with secret from environment
 	jwtSecret := config.GetEnv("JWT_SECRET")
 	if jwtSecret == "" {
@@ -22,19 +32,54 @@ func main() {
 		log.Println("⚠️  JWT_SECRET not set, using default (change in production!)")
 	}
 	auth.SetDefaultJWTSecret(jwtSecret)
+	if err := auth.ConfigureKeys(config.GetEnv("JWT_KEY_ID"), config.GetEnv("JWT_PREVIOUS_KEYS")); err != nil {
+		log.Fatalf("❌ Invalid JWT key configuration: %v", err)
+	}
+	auth.SetIssuerAndAudience(config.GetEnv("JWT_ISSUER"), config.GetEnv("JWT_AUDIENCE"))
+
+	// Fail fast if uploaded files cannot be stored
+	if err := uploads.CheckWritable(); err != nil {
+		log.Fatalf("❌ Upload directory check failed: %v (set UPLOAD_DIR to a writable directory)", err)
+	}
 
 	// Connect to the database
 	db.Connect()
 
-	// Create gin engine
-	r := gin.Default()
+	// Optionally refuse to serve traffic against a database missing migrations
+	if requireMigrations, _ := strconv.ParseBool(config.GetEnv("REQUIRE_MIGRATIONS")); requireMigrations {
+		if err := migrations.CheckUpToDate(); err != nil {
+			log.Fatalf("❌ Migration check failed: %v", err)
+		}
+		log.Println("✅ Database migrations are up to date")
+	}
+
+	// Periodically remove uploaded files whose database records are gone
+	uploads.StartOrphanSweeper(models.NewModels().KnowledgeBases.GetAllFilePaths)
+
+	// Create gin engine, logging requests with our own middleware instead of gin's default logger
+	r := gin.New()
+
+	// Only trust X-Forwarded-For from TRUSTED_PROXIES, so clients cannot pick the IP that
+	// per-IP rate limits and request logs see; with none configured the peer address is used
+	var trustedProxies []string
+	for _, proxy := range strings.Split(config.GetEnv("TRUSTED_PROXIES"), ",") {
+		if proxy = strings.TrimSpace(proxy); proxy != "" {
+			trustedProxies = append(trustedProxies, proxy)
+		}
+	}
+	if err := r.SetTrustedProxies(trustedProxies); err != nil {
+		log.Fatalf("❌ Invalid TRUSTED_PROXIES: %v", err)
+	}
+
+	r.Use(middleware.RequestLogger(), gin.Recovery())
 
 	// Add CORS middleware
 	r.Use(func(c *gin.Context) {
 		c.Writer.Header().Set("Access-Control-Allow-Origin", "*")
 		c.Writer.Header().Set("Access-Control-Allow-Credentials", "true")
-		c.Writer.Header().Set("Access-Control-Allow-Headers", "Content-Type, Content-Length, Accept-Encoding, X-CSRF-Token, Authorization, accept, origin, Cache-Control, X-Requested-With")
+		c.Writer.Header().Set("Access-Control-Allow-Headers", "Content-Type, Content-Length, Accept-Encoding, X-CSRF-Token, Authorization, accept, origin, Cache-Control, X-Requested-With, X-Org-Slug, X-Cache-Bypass")
 		c.Writer.Header().Set("Access-Control-Allow-Methods", "POST, OPTIONS, GET, PUT, DELETE, PATCH")
+		c.Writer.Header().Set("Access-Control-Expose-Headers", "Location, Retry-After")
 
 		if c.Request.Method == "OPTIONS" {
 			c.AbortWithStatus(204)
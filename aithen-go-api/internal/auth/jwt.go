@@ -2,6 +2,9 @@ package auth
 
 import (
 	"errors"
+	"fmt"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/golang-jwt/jwt/v5"
@@ -9,7 +12,27 @@ import (
 
 var (
 	ErrInvalidToken = errors.New("invalid token")
-	jwtSecret       = []byte("your-secret-key-change-in-production") // Default, should be from env
+	ErrEmptySecret  = errors.New("jwt secret must not be empty")
+	jwtIssuer       = "aithen-api"
+	jwtAudience     = "aithen-api"
+)
+
+// defaultKeyID is the key id of the signing secret until JWT_KEY_ID names it
+const defaultKeyID = "default"
+
+// signingKey is a secret tokens are signed or validated with. A zero expiresAt never expires.
+type signingKey struct {
+	secret    []byte
+	expiresAt time.Time
+}
+
+// secretMu guards the signing key set, keyed by the kid header written to tokens
+var (
+	secretMu     sync.RWMutex
+	currentKeyID = defaultKeyID
+	signingKeys  = map[string]signingKey{
+		defaultKeyID: {secret: []byte("your-secret-key-change-in-production")}, // Default, should be from env
+	}
 )
 
 // SetDefaultJWTSecret sets the JWT secret (called from main.go)
@@ -26,10 +49,133 @@ type Claims struct {
 	jwt.RegisteredClaims
 }
 
-// SetJWTSecret sets the JWT secret from environment
+// SetJWTSecret sets the JWT secret from environment. Tokens signed with any other secret
+// stop validating immediately; use RotateSecret to keep them valid for a while.
 func SetJWTSecret(secret string) {
 	if secret != "" {
-		jwtSecret = []byte(secret)
+		secretMu.Lock()
+		signingKeys = map[string]signingKey{currentKeyID: {secret: []byte(secret)}}
+		secretMu.Unlock()
+	}
+}
+
+// ConfigureKeys names the current signing secret and adds previous secrets that tokens are
+// still accepted with (called from main.go with JWT_KEY_ID and JWT_PREVIOUS_KEYS).
+// previousKeys is a comma-separated list of kid:secret pairs. An empty keyID keeps the default.
+func ConfigureKeys(keyID, previousKeys string) error {
+	previous := make(map[string][]byte)
+	for _, pair := range strings.Split(previousKeys, ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+		kid, secret, ok := strings.Cut(pair, ":")
+		if !ok || kid == "" || secret == "" {
+			return fmt.Errorf("invalid previous key %q, expected kid:secret", pair)
+		}
+		previous[kid] = []byte(secret)
+	}
+
+	secretMu.Lock()
+	defer secretMu.Unlock()
+
+	if keyID == "" {
+		keyID = currentKeyID
+	}
+	if _, ok := previous[keyID]; ok {
+		return fmt.Errorf("previous key %q has the same id as the current key", keyID)
+	}
+
+	if keyID != currentKeyID {
+		signingKeys[keyID] = signingKeys[currentKeyID]
+		delete(signingKeys, currentKeyID)
+		currentKeyID = keyID
+	}
+	for kid, secret := range previous {
+		signingKeys[kid] = signingKey{secret: secret}
+	}
+	return nil
+}
+
+// RotateSecret switches new tokens to secret under keyID. Tokens signed with the key being
+// replaced keep validating for grace, so rotation does not log everyone out at once; a grace
+// of 0 invalidates them immediately. Safe to call while tokens are generated and validated.
+func RotateSecret(keyID, secret string, grace time.Duration) error {
+	if secret == "" {
+		return ErrEmptySecret
+	}
+	if keyID == "" {
+		return errors.New("jwt key id must not be empty")
+	}
+
+	secretMu.Lock()
+	defer secretMu.Unlock()
+
+	if keyID != currentKeyID {
+		if grace > 0 {
+			old := signingKeys[currentKeyID]
+			old.expiresAt = time.Now().Add(grace)
+			signingKeys[currentKeyID] = old
+		} else {
+			delete(signingKeys, currentKeyID)
+		}
+	}
+	signingKeys[keyID] = signingKey{secret: []byte(secret)}
+	currentKeyID = keyID
+
+	// Drop keys whose grace period has ended
+	now := time.Now()
+	for kid, key := range signingKeys {
+		if !key.expiresAt.IsZero() && now.After(key.expiresAt) {
+			delete(signingKeys, kid)
+		}
+	}
+	return nil
+}
+
+// signingSecret returns the key id and secret new tokens are signed with
+func signingSecret() (string, []byte) {
+	secretMu.RLock()
+	defer secretMu.RUnlock()
+	return currentKeyID, signingKeys[currentKeyID].secret
+}
+
+// verificationKeys returns the secrets a token is accepted with: the key named by its kid
+// header, or every unexpired key for tokens issued without one
+func verificationKeys(token *jwt.Token) (jwt.VerificationKeySet, error) {
+	secretMu.RLock()
+	defer secretMu.RUnlock()
+
+	now := time.Now()
+	usable := func(key signingKey) bool {
+		return key.expiresAt.IsZero() || now.Before(key.expiresAt)
+	}
+
+	if kid, ok := token.Header["kid"].(string); ok {
+		key, found := signingKeys[kid]
+		if !found || !usable(key) {
+			return jwt.VerificationKeySet{}, ErrInvalidToken
+		}
+		return jwt.VerificationKeySet{Keys: []jwt.VerificationKey{key.secret}}, nil
+	}
+
+	var keys jwt.VerificationKeySet
+	for _, key := range signingKeys {
+		if usable(key) {
+			keys.Keys = append(keys.Keys, key.secret)
+		}
+	}
+	return keys, nil
+}
+
+// SetIssuerAndAudience sets the issuer and audience written to new tokens and required of
+// incoming ones (called from main.go). Empty values keep the defaults.
+func SetIssuerAndAudience(issuer, audience string) {
+	if issuer != "" {
+		jwtIssuer = issuer
+	}
+	if audience != "" {
+		jwtAudience = audience
 	}
 }
 
@@ -44,13 +190,16 @@ func GenerateToken(userID int64, email string) (string, error) {
 			ExpiresAt: jwt.NewNumericDate(expirationTime),
 			IssuedAt:  jwt.NewNumericDate(time.Now()),
 			NotBefore: jwt.NewNumericDate(time.Now()),
-			Issuer:    "aithen-api",
+			Issuer:    jwtIssuer,
+			Audience:  jwt.ClaimStrings{jwtAudience},
 			Subject:   email,
 		},
 	}
 
+	keyID, secret := signingSecret()
 	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	tokenString, err := token.SignedString(jwtSecret)
+	token.Header["kid"] = keyID
+	tokenString, err := token.SignedString(secret)
 	if err != nil {
 		return "", err
 	}
@@ -58,7 +207,10 @@ func GenerateToken(userID int64, email string) (string, error) {
 	return tokenString, nil
 }
 
-// ValidateToken validates a JWT token and returns the claims
+// ValidateToken validates a JWT token and returns the claims.
+// Tokens with a different issuer or audience are rejected even if their signature is valid.
+// So are tokens without an aud claim, such as those issued before audiences were checked;
+// their users must log in again.
 func ValidateToken(tokenString string) (*Claims, error) {
 	claims := &Claims{}
 
@@ -67,8 +219,8 @@ func ValidateToken(tokenString string) (*Claims, error) {
 		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
 			return nil, ErrInvalidToken
 		}
-		return jwtSecret, nil
-	})
+		return verificationKeys(token)
+	}, jwt.WithIssuer(jwtIssuer), jwt.WithAudience(jwtAudience))
 
 	if err != nil {
 		return nil, ErrInvalidToken
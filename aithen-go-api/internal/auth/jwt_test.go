@@ -0,0 +1,174 @@
+package auth
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// useTestKeys replaces the signing keys with a single test secret for the duration of the test
+func useTestKeys(t *testing.T) {
+	t.Helper()
+
+	secretMu.Lock()
+	savedKeyID, savedKeys := currentKeyID, signingKeys
+	currentKeyID = "test"
+	signingKeys = map[string]signingKey{"test": {secret: []byte("test-secret")}}
+	secretMu.Unlock()
+
+	t.Cleanup(func() {
+		secretMu.Lock()
+		currentKeyID, signingKeys = savedKeyID, savedKeys
+		secretMu.Unlock()
+	})
+}
+
+// signTestToken signs claims with the current key, as GenerateToken does
+func signTestToken(t *testing.T, claims *Claims) string {
+	t.Helper()
+
+	keyID, secret := signingSecret()
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+	token.Header["kid"] = keyID
+	signed, err := token.SignedString(secret)
+	if err != nil {
+		t.Fatalf("failed to sign token: %v", err)
+	}
+	return signed
+}
+
+func TestValidateTokenIssuerAndAudience(t *testing.T) {
+	useTestKeys(t)
+
+	tests := []struct {
+		name     string
+		issuer   string
+		audience jwt.ClaimStrings
+		wantErr  bool
+	}{
+		{name: "matching issuer and audience", issuer: jwtIssuer, audience: jwt.ClaimStrings{jwtAudience}},
+		{name: "other issuer", issuer: "other-api", audience: jwt.ClaimStrings{jwtAudience}, wantErr: true},
+		{name: "missing issuer", issuer: "", audience: jwt.ClaimStrings{jwtAudience}, wantErr: true},
+		{name: "other audience", issuer: jwtIssuer, audience: jwt.ClaimStrings{"other-api"}, wantErr: true},
+		{name: "missing audience", issuer: jwtIssuer, audience: nil, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			token := signTestToken(t, &Claims{
+				UserID: 42,
+				Email:  "user@example.com",
+				RegisteredClaims: jwt.RegisteredClaims{
+					ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Hour)),
+					Issuer:    tt.issuer,
+					Audience:  tt.audience,
+				},
+			})
+
+			claims, err := ValidateToken(token)
+			if tt.wantErr {
+				if err != ErrInvalidToken {
+					t.Fatalf("ValidateToken() error = %v, want ErrInvalidToken", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ValidateToken() error = %v", err)
+			}
+			if claims.UserID != 42 {
+				t.Errorf("ValidateToken() user_id = %d, want 42", claims.UserID)
+			}
+		})
+	}
+}
+
+func TestRotateSecretWhileValidating(t *testing.T) {
+	useTestKeys(t)
+
+	const workers = 8
+	const rotations = 50
+
+	done := make(chan struct{})
+	errs := make(chan error, workers)
+	for i := 0; i < workers; i++ {
+		go func() {
+			for {
+				select {
+				case <-done:
+					errs <- nil
+					return
+				default:
+				}
+				token, err := GenerateToken(42, "user@example.com")
+				if err != nil {
+					errs <- err
+					return
+				}
+				// Replaced keys stay valid for the grace period, so no token may be rejected
+				if _, err := ValidateToken(token); err != nil {
+					errs <- err
+					return
+				}
+			}
+		}()
+	}
+
+	for i := 0; i < rotations; i++ {
+		if err := RotateSecret(fmt.Sprintf("key-%d", i), fmt.Sprintf("secret-%d", i), time.Hour); err != nil {
+			t.Fatalf("RotateSecret() error = %v", err)
+		}
+	}
+	close(done)
+
+	for i := 0; i < workers; i++ {
+		if err := <-errs; err != nil {
+			t.Errorf("token generated during rotation failed: %v", err)
+		}
+	}
+}
+
+func TestRotateSecretGracePeriod(t *testing.T) {
+	useTestKeys(t)
+
+	oldToken, err := GenerateToken(42, "user@example.com")
+	if err != nil {
+		t.Fatalf("GenerateToken() error = %v", err)
+	}
+	if err := RotateSecret("next", "next-secret", time.Hour); err != nil {
+		t.Fatalf("RotateSecret() error = %v", err)
+	}
+	newToken, err := GenerateToken(42, "user@example.com")
+	if err != nil {
+		t.Fatalf("GenerateToken() error = %v", err)
+	}
+
+	// Within the grace period tokens signed with either key validate
+	for name, token := range map[string]string{"previous key": oldToken, "current key": newToken} {
+		if _, err := ValidateToken(token); err != nil {
+			t.Errorf("ValidateToken() with the %s during the grace period error = %v", name, err)
+		}
+	}
+
+	// End the grace period without sleeping through it
+	secretMu.Lock()
+	old := signingKeys["test"]
+	old.expiresAt = time.Now().Add(-time.Second)
+	signingKeys["test"] = old
+	secretMu.Unlock()
+
+	if _, err := ValidateToken(oldToken); err != ErrInvalidToken {
+		t.Errorf("ValidateToken() with the previous key after the grace period error = %v, want ErrInvalidToken", err)
+	}
+	if _, err := ValidateToken(newToken); err != nil {
+		t.Errorf("ValidateToken() with the current key after the grace period error = %v", err)
+	}
+
+	// A rotation without grace drops the replaced key at once
+	if err := RotateSecret("last", "last-secret", 0); err != nil {
+		t.Fatalf("RotateSecret() error = %v", err)
+	}
+	if _, err := ValidateToken(newToken); err != ErrInvalidToken {
+		t.Errorf("ValidateToken() after a rotation without grace error = %v, want ErrInvalidToken", err)
+	}
+}
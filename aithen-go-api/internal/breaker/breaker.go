@@ -0,0 +1,135 @@
+package breaker
+
+import (
+	"errors"
+	"sync"
+	"time"
+)
+
+// ErrOpen is returned by Allow while the breaker is rejecting calls
+var ErrOpen = errors.New("circuit breaker is open")
+
+// Breaker states
+const (
+	StateClosed   = "closed"    // Calls pass through
+	StateOpen     = "open"      // Calls fail fast until the cooldown elapses
+	StateHalfOpen = "half_open" // A single probe call is allowed through
+)
+
+// Breaker is a consecutive-failure circuit breaker. After Threshold consecutive
+// failures it opens and rejects calls for Cooldown, then lets one probe through;
+// a successful probe closes it again and a failed probe re-opens it.
+type Breaker struct {
+	threshold int
+	cooldown  time.Duration
+
+	mu       sync.Mutex
+	state    string
+	failures int
+	openedAt time.Time
+	probing  bool
+}
+
+// Snapshot describes the breaker state at a point in time
+type Snapshot struct {
+	State               string     `json:"state"`
+	ConsecutiveFailures int        `json:"consecutive_failures"`
+	Threshold           int        `json:"threshold"`
+	CooldownSeconds     float64    `json:"cooldown_seconds"`
+	OpenedAt            *time.Time `json:"opened_at,omitempty"`
+}
+
+// New creates a closed breaker. A threshold below 1 is treated as 1.
+func New(threshold int, cooldown time.Duration) *Breaker {
+	if threshold < 1 {
+		threshold = 1
+	}
+	return &Breaker{
+		threshold: threshold,
+		cooldown:  cooldown,
+		state:     StateClosed,
+	}
+}
+
+// Allow reports whether a call may proceed, returning ErrOpen if not.
+// Every allowed call must be followed by Success, Failure or Abandon.
+func (b *Breaker) Allow() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	switch b.state {
+	case StateOpen:
+		if time.Since(b.openedAt) < b.cooldown {
+			return ErrOpen
+		}
+		b.state = StateHalfOpen
+		b.probing = true
+		return nil
+	case StateHalfOpen:
+		if b.probing {
+			return ErrOpen
+		}
+		b.probing = true
+		return nil
+	}
+	return nil
+}
+
+// Success records a successful call and closes the breaker
+func (b *Breaker) Success() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.state = StateClosed
+	b.failures = 0
+	b.probing = false
+}
+
+// Failure records a failed call, opening the breaker once the threshold is reached
+// or immediately if the call was a half-open probe
+func (b *Breaker) Failure() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.failures++
+	if b.state == StateHalfOpen || b.failures >= b.threshold {
+		b.state = StateOpen
+		b.openedAt = time.Now()
+	}
+	b.probing = false
+}
+
+// Abandon releases an allowed call without recording an outcome, e.g. when the
+// caller cancelled it. A half-open breaker lets the next call probe instead.
+func (b *Breaker) Abandon() {
+	b.mu.Lock()
+	b.probing = false
+	b.mu.Unlock()
+}
+
+// State returns the current state, reporting an open breaker whose cooldown has
+// elapsed as half-open
+func (b *Breaker) State() string {
+	return b.Snapshot().State
+}
+
+// Snapshot returns the current breaker state
+func (b *Breaker) Snapshot() Snapshot {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	s := Snapshot{
+		State:               b.state,
+		ConsecutiveFailures: b.failures,
+		Threshold:           b.threshold,
+		CooldownSeconds:     b.cooldown.Seconds(),
+	}
+	if b.state == StateOpen && time.Since(b.openedAt) >= b.cooldown {
+		s.State = StateHalfOpen
+	}
+	if b.state != StateClosed {
+		openedAt := b.openedAt
+		s.OpenedAt = &openedAt
+	}
+	return s
+}
@@ -0,0 +1,115 @@
+package breaker
+
+import (
+	"testing"
+	"time"
+)
+
+const testCooldown = 20 * time.Millisecond
+
+// step is one action on the breaker followed by the state it should be in
+type step struct {
+	action    string // allow, reject, success, failure or wait
+	wantState string
+}
+
+func TestBreakerTransitions(t *testing.T) {
+	tests := []struct {
+		name  string
+		steps []step
+	}{
+		{
+			name: "opens once the threshold is reached",
+			steps: []step{
+				{"allow", StateClosed}, {"failure", StateClosed},
+				{"allow", StateClosed}, {"failure", StateClosed},
+				{"allow", StateClosed}, {"failure", StateOpen},
+				{"reject", StateOpen},
+			},
+		},
+		{
+			name: "success resets the failure count",
+			steps: []step{
+				{"allow", StateClosed}, {"failure", StateClosed},
+				{"allow", StateClosed}, {"failure", StateClosed},
+				{"allow", StateClosed}, {"success", StateClosed},
+				{"allow", StateClosed}, {"failure", StateClosed},
+			},
+		},
+		{
+			name: "half-open after the cooldown and closed on success",
+			steps: []step{
+				{"allow", StateClosed}, {"failure", StateClosed},
+				{"allow", StateClosed}, {"failure", StateClosed},
+				{"allow", StateClosed}, {"failure", StateOpen},
+				{"wait", StateHalfOpen},
+				{"allow", StateHalfOpen},
+				{"reject", StateHalfOpen}, // Only one probe at a time
+				{"success", StateClosed},
+				{"allow", StateClosed},
+			},
+		},
+		{
+			name: "half-open probe failure re-opens",
+			steps: []step{
+				{"allow", StateClosed}, {"failure", StateClosed},
+				{"allow", StateClosed}, {"failure", StateClosed},
+				{"allow", StateClosed}, {"failure", StateOpen},
+				{"wait", StateHalfOpen},
+				{"allow", StateHalfOpen},
+				{"failure", StateOpen},
+				{"reject", StateOpen},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := New(3, testCooldown)
+			for i, s := range tt.steps {
+				switch s.action {
+				case "allow":
+					if err := b.Allow(); err != nil {
+						t.Fatalf("step %d: Allow() error = %v, want nil", i, err)
+					}
+				case "reject":
+					if err := b.Allow(); err != ErrOpen {
+						t.Fatalf("step %d: Allow() error = %v, want ErrOpen", i, err)
+					}
+				case "success":
+					b.Success()
+				case "failure":
+					b.Failure()
+				case "wait":
+					time.Sleep(testCooldown + 5*time.Millisecond)
+				}
+				if got := b.State(); got != s.wantState {
+					t.Fatalf("step %d (%s): State() = %q, want %q", i, s.action, got, s.wantState)
+				}
+			}
+		})
+	}
+}
+
+func TestBreakerAbandonReleasesProbe(t *testing.T) {
+	b := New(1, testCooldown)
+	b.Allow()
+	b.Failure()
+	time.Sleep(testCooldown + 5*time.Millisecond)
+
+	if err := b.Allow(); err != nil {
+		t.Fatalf("probe Allow() error = %v", err)
+	}
+	b.Abandon()
+	if err := b.Allow(); err != nil {
+		t.Fatalf("Allow() after Abandon() error = %v, want the next call to probe", err)
+	}
+}
+
+func TestNewClampsThreshold(t *testing.T) {
+	b := New(0, time.Minute)
+	b.Allow()
+	b.Failure()
+	if got := b.State(); got != StateOpen {
+		t.Errorf("State() after one failure = %q, want %q", got, StateOpen)
+	}
+}
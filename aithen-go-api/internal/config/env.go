@@ -3,11 +3,34 @@ package config
 import (
     "log"
     "os"
+    "strconv"
 
     "github.com/joho/godotenv"
 )
 
+// LoadEnv loads the .env file layered with the file for APP_ENV, taken from the process
+// environment or else from .env itself (see LoadEnvForEnvironment)
 func LoadEnv() {
+    env := os.Getenv("APP_ENV")
+    if env == "" {
+        if base, err := godotenv.Read(); err == nil {
+            env = base["APP_ENV"]
+        }
+    }
+    LoadEnvForEnvironment(env)
+}
+
+// LoadEnvForEnvironment loads .env.<env> and then .env. Values in .env.<env> override those
+// in .env, and variables already set in the process environment override both. An empty env
+// loads only .env.
+func LoadEnvForEnvironment(env string) {
+    if env != "" {
+        file := ".env." + env
+        if err := godotenv.Load(file); err != nil {
+            log.Printf("⚠️  No %s file found, using .env and system env", file)
+        }
+    }
+
     err := godotenv.Load()
     if err != nil {
         log.Println("⚠️  No .env file found, using system env")
@@ -17,3 +40,18 @@ func LoadEnv() {
 func GetEnv(key string) string {
     return os.Getenv(key)
 }
+
+// GetEnvInt returns the integer value of an environment variable,
+// or the default if it is unset or not a valid integer
+func GetEnvInt(key string, defaultValue int) int {
+    value := os.Getenv(key)
+    if value == "" {
+        return defaultValue
+    }
+    parsed, err := strconv.Atoi(value)
+    if err != nil {
+        log.Printf("⚠️  Invalid integer for %s: %q, using default %d", key, value, defaultValue)
+        return defaultValue
+    }
+    return parsed
+}
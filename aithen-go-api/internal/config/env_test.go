@@ -0,0 +1,72 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// unsetEnv clears keys for the test; t.Setenv restores their previous values afterwards
+func unsetEnv(t *testing.T, keys ...string) {
+	t.Helper()
+	for _, key := range keys {
+		t.Setenv(key, "")
+		os.Unsetenv(key)
+	}
+}
+
+func writeEnvFile(t *testing.T, dir, name, content string) {
+	t.Helper()
+	if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+		t.Fatalf("failed to write %s: %v", name, err)
+	}
+}
+
+func TestLoadEnvForEnvironmentPrecedence(t *testing.T) {
+	dir := t.TempDir()
+	writeEnvFile(t, dir, ".env", "CONFIG_TEST_BASE=base\nCONFIG_TEST_OVERRIDE=base\nCONFIG_TEST_PROCESS=base\nCONFIG_TEST_LIMIT=10\n")
+	writeEnvFile(t, dir, ".env.staging", "CONFIG_TEST_OVERRIDE=staging\nCONFIG_TEST_PROCESS=staging\n")
+	t.Chdir(dir)
+
+	unsetEnv(t, "CONFIG_TEST_BASE", "CONFIG_TEST_OVERRIDE", "CONFIG_TEST_LIMIT", "CONFIG_TEST_UNSET")
+	t.Setenv("CONFIG_TEST_PROCESS", "process")
+
+	LoadEnvForEnvironment("staging")
+
+	tests := []struct {
+		key  string
+		want string
+	}{
+		{key: "CONFIG_TEST_BASE", want: "base"},        // only in .env
+		{key: "CONFIG_TEST_OVERRIDE", want: "staging"}, // the environment file beats .env
+		{key: "CONFIG_TEST_PROCESS", want: "process"},  // the process environment beats both files
+		{key: "CONFIG_TEST_UNSET", want: ""},           // in neither
+	}
+	for _, tt := range tests {
+		if got := GetEnv(tt.key); got != tt.want {
+			t.Errorf("GetEnv(%q) = %q, want %q", tt.key, got, tt.want)
+		}
+	}
+
+	// A value from a file beats the default, which applies only when no source sets the key
+	if got := GetEnvInt("CONFIG_TEST_LIMIT", 5); got != 10 {
+		t.Errorf("GetEnvInt(CONFIG_TEST_LIMIT) = %d, want 10 from .env", got)
+	}
+	if got := GetEnvInt("CONFIG_TEST_UNSET", 5); got != 5 {
+		t.Errorf("GetEnvInt(CONFIG_TEST_UNSET) = %d, want the default 5", got)
+	}
+}
+
+func TestLoadEnvForEnvironmentWithoutEnvironment(t *testing.T) {
+	dir := t.TempDir()
+	writeEnvFile(t, dir, ".env", "CONFIG_TEST_OVERRIDE=base\n")
+	writeEnvFile(t, dir, ".env.staging", "CONFIG_TEST_OVERRIDE=staging\n")
+	t.Chdir(dir)
+	unsetEnv(t, "CONFIG_TEST_OVERRIDE")
+
+	LoadEnvForEnvironment("")
+
+	if got := GetEnv("CONFIG_TEST_OVERRIDE"); got != "base" {
+		t.Errorf("GetEnv(CONFIG_TEST_OVERRIDE) = %q, want %q with no environment file loaded", got, "base")
+	}
+}
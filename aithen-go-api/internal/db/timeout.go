@@ -0,0 +1,28 @@
+package db
+
+import (
+	"context"
+	"time"
+
+	"github.com/aithen/go-api/internal/config"
+)
+
+// QueryTimeout returns the timeout for database work whose context has no deadline
+// (DB_QUERY_TIMEOUT in seconds, default 30, 0 disables it)
+func QueryTimeout() time.Duration {
+	return time.Duration(config.GetEnvInt("DB_QUERY_TIMEOUT", 30)) * time.Second
+}
+
+// WithQueryTimeout bounds ctx by QueryTimeout unless it already has a deadline, so callers
+// using context.Background() (the training queue, background jobs) cannot hang on a slow query.
+// The returned cancel function must always be called.
+func WithQueryTimeout(ctx context.Context) (context.Context, context.CancelFunc) {
+	if _, ok := ctx.Deadline(); ok {
+		return ctx, func() {}
+	}
+	timeout := QueryTimeout()
+	if timeout <= 0 {
+		return ctx, func() {}
+	}
+	return context.WithTimeout(ctx, timeout)
+}
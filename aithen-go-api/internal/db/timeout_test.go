@@ -0,0 +1,79 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+func TestWithQueryTimeout(t *testing.T) {
+	t.Run("bounds a context without a deadline", func(t *testing.T) {
+		t.Setenv("DB_QUERY_TIMEOUT", "5")
+
+		ctx, cancel := WithQueryTimeout(context.Background())
+		defer cancel()
+		deadline, ok := ctx.Deadline()
+		if !ok {
+			t.Fatal("context has no deadline")
+		}
+		if remaining := time.Until(deadline); remaining <= 4*time.Second || remaining > 5*time.Second {
+			t.Errorf("deadline is %v away, want about 5s", remaining)
+		}
+	})
+
+	t.Run("keeps an existing deadline", func(t *testing.T) {
+		t.Setenv("DB_QUERY_TIMEOUT", "5")
+
+		parent, parentCancel := context.WithTimeout(context.Background(), time.Minute)
+		defer parentCancel()
+		ctx, cancel := WithQueryTimeout(parent)
+		defer cancel()
+		if ctx != parent {
+			t.Error("WithQueryTimeout() replaced a context that already has a deadline")
+		}
+	})
+
+	t.Run("zero disables the timeout", func(t *testing.T) {
+		t.Setenv("DB_QUERY_TIMEOUT", "0")
+
+		ctx, cancel := WithQueryTimeout(context.Background())
+		defer cancel()
+		if _, ok := ctx.Deadline(); ok {
+			t.Error("context has a deadline with DB_QUERY_TIMEOUT=0")
+		}
+	})
+}
+
+func TestWithQueryTimeoutAbortsSlowQuery(t *testing.T) {
+	url := os.Getenv("TEST_DATABASE_URL")
+	if url == "" {
+		t.Skip("TEST_DATABASE_URL not set")
+	}
+	pool, err := pgxpool.New(context.Background(), url)
+	if err != nil {
+		t.Fatalf("failed to connect to test database: %v", err)
+	}
+	defer pool.Close()
+	t.Setenv("DB_QUERY_TIMEOUT", "1")
+
+	ctx, cancel := WithQueryTimeout(context.Background())
+	defer cancel()
+
+	start := time.Now()
+	_, err = pool.Exec(ctx, "SELECT pg_sleep(10)")
+	elapsed := time.Since(start)
+
+	if err == nil {
+		t.Fatal("slow query succeeded, want it aborted by the timeout")
+	}
+	if !errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		t.Errorf("context error = %v, want %v", ctx.Err(), context.DeadlineExceeded)
+	}
+	if elapsed > 5*time.Second {
+		t.Errorf("query ran for %v, want it aborted after about 1s", elapsed)
+	}
+}
@@ -1,12 +1,19 @@
 package handlers
 
 import (
+	"bufio"
 	"bytes"
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
+	"log"
 	"net/http"
+	"net/url"
 	"strings"
+	"time"
+	"unicode/utf8"
 
 	"github.com/aithen/go-api/internal/config"
 	"github.com/gin-gonic/gin"
@@ -18,6 +25,10 @@ type ChatRequest struct {
 	Personality string    `json:"personality,omitempty"`
 	MaxTokens   int       `json:"max_tokens,omitempty"`
 	Stream      bool      `json:"stream,omitempty"`
+	Model       string    `json:"model,omitempty"`
+	// Organization is the slug whose default personality applies when Personality is empty.
+	// It is not forwarded to the AI service.
+	Organization string `json:"organization,omitempty"`
 }
 
 // Message represents a chat message
@@ -26,6 +37,196 @@ type Message struct {
 	Content string `json:"content"`
 }
 
+// validateMaxTokens rejects negative values, applies the configured default when unset
+// and clamps the value to the configured maximum (DEFAULT_MAX_TOKENS, AI_MAX_TOKENS)
+func validateMaxTokens(req *ChatRequest) error {
+	if req.MaxTokens < 0 {
+		return errors.New("max_tokens must be a positive integer")
+	}
+
+	maxTokens := config.GetEnvInt("AI_MAX_TOKENS", 4096)
+	if req.MaxTokens == 0 {
+		req.MaxTokens = getDefaultMaxTokens()
+	}
+	if req.MaxTokens > maxTokens {
+		req.MaxTokens = maxTokens
+	}
+	if req.MaxTokens < 1 {
+		req.MaxTokens = 1
+	}
+	return nil
+}
+
+// allowedModels returns the AI models chat requests may use (AI_ALLOWED_MODELS, a comma-separated
+// list, default "mistral"). The first model is the default.
+func allowedModels() []string {
+	var models []string
+	for _, model := range strings.Split(config.GetEnv("AI_ALLOWED_MODELS"), ",") {
+		if model = strings.TrimSpace(model); model != "" {
+			models = append(models, model)
+		}
+	}
+	if len(models) == 0 {
+		return []string{"mistral"}
+	}
+	return models
+}
+
+// resolveModel returns model if it is allowed, or the default model when it is empty
+func resolveModel(model string) (string, error) {
+	allowed := allowedModels()
+	if model == "" {
+		return allowed[0], nil
+	}
+	for _, m := range allowed {
+		if m == model {
+			return model, nil
+		}
+	}
+	return "", fmt.Errorf("unknown model %q, must be one of: %s", model, strings.Join(allowed, ", "))
+}
+
+// validateModel rejects models that are not allowed and applies the default model when unset
+func validateModel(req *ChatRequest) error {
+	model, err := resolveModel(req.Model)
+	if err != nil {
+		return err
+	}
+	req.Model = model
+	return nil
+}
+
+// GetModels returns the AI models chat requests may use
+func GetModels(c *gin.Context) {
+	models := allowedModels()
+	c.JSON(http.StatusOK, gin.H{
+		"models":  models,
+		"default": models[0],
+	})
+}
+
+// System prompt merge strategies for AI_SYSTEM_PROMPT_MERGE
+const (
+	systemPromptMergeLast   = "last"   // Keep only the latest system message, so client-sent prompts override stored ones
+	systemPromptMergeFirst  = "first"  // Keep only the earliest system message, so stored prompts win
+	systemPromptMergeAppend = "append" // Join every system message into one, in order
+	systemPromptMergeNone   = "none"   // Forward all system messages unchanged
+)
+
+// systemPromptMergeStrategy returns the configured merge strategy (AI_SYSTEM_PROMPT_MERGE, default "last")
+func systemPromptMergeStrategy() string {
+	strategy := strings.ToLower(strings.TrimSpace(config.GetEnv("AI_SYSTEM_PROMPT_MERGE")))
+	switch strategy {
+	case systemPromptMergeLast, systemPromptMergeFirst, systemPromptMergeAppend, systemPromptMergeNone:
+		return strategy
+	case "":
+		return systemPromptMergeLast
+	default:
+		log.Printf("Warning: Unknown AI_SYSTEM_PROMPT_MERGE %q, using %q", strategy, systemPromptMergeLast)
+		return systemPromptMergeLast
+	}
+}
+
+// mergeSystemMessages reduces the system messages of a chat request to a single authoritative
+// one according to AI_SYSTEM_PROMPT_MERGE. The merged message takes the place of the first
+// system message so it still precedes the conversation.
+func mergeSystemMessages(req *ChatRequest) {
+	strategy := systemPromptMergeStrategy()
+	if strategy == systemPromptMergeNone {
+		return
+	}
+
+	first := -1
+	var contents []string
+	for i, message := range req.Messages {
+		if message.Role == "system" {
+			if first < 0 {
+				first = i
+			}
+			contents = append(contents, message.Content)
+		}
+	}
+	if len(contents) < 2 {
+		return
+	}
+
+	var content string
+	switch strategy {
+	case systemPromptMergeFirst:
+		content = contents[0]
+	case systemPromptMergeAppend:
+		content = strings.Join(contents, "\n\n")
+	default:
+		content = contents[len(contents)-1]
+	}
+
+	merged := make([]Message, 0, len(req.Messages)-len(contents)+1)
+	for i, message := range req.Messages {
+		if i == first {
+			merged = append(merged, Message{Role: "system", Content: content})
+		} else if message.Role != "system" {
+			merged = append(merged, message)
+		}
+	}
+	req.Messages = merged
+}
+
+// truncateHistory limits the messages forwarded to the AI service to the first system message
+// plus the most recent AI_MAX_HISTORY_MESSAGES messages (default 50, 0 disables truncation)
+// so long chats stay within the model's context window
+func truncateHistory(req *ChatRequest) {
+	limit := config.GetEnvInt("AI_MAX_HISTORY_MESSAGES", 50)
+	if limit <= 0 {
+		return
+	}
+
+	systemIdx := -1
+	for i, message := range req.Messages {
+		if message.Role == "system" {
+			systemIdx = i
+			break
+		}
+	}
+
+	// The system message does not count toward the limit
+	count := len(req.Messages)
+	if systemIdx >= 0 {
+		count--
+	}
+	if count <= limit {
+		return
+	}
+
+	start := len(req.Messages) - limit
+	truncated := make([]Message, 0, limit+1)
+	if systemIdx >= 0 && systemIdx < start {
+		truncated = append(truncated, req.Messages[systemIdx])
+	} else if systemIdx >= start {
+		// The system message is already inside the window, so keep one more message
+		start--
+	}
+	req.Messages = append(truncated, req.Messages[start:]...)
+}
+
+// getDefaultMaxTokens returns max_tokens for chat requests without one (DEFAULT_MAX_TOKENS,
+// default 1024). AI_DEFAULT_MAX_TOKENS, its former name, is still read when it is unset.
+func getDefaultMaxTokens() int {
+	if config.GetEnv("DEFAULT_MAX_TOKENS") == "" {
+		return config.GetEnvInt("AI_DEFAULT_MAX_TOKENS", 1024)
+	}
+	return config.GetEnvInt("DEFAULT_MAX_TOKENS", 1024)
+}
+
+// getDefaultPersonality returns the personality for chat requests without one and without an
+// organization default (DEFAULT_PERSONALITY). AI_DEFAULT_PERSONALITY, its former name, is still
+// read when it is unset.
+func getDefaultPersonality() string {
+	if personality := config.GetEnv("DEFAULT_PERSONALITY"); personality != "" {
+		return personality
+	}
+	return config.GetEnv("AI_DEFAULT_PERSONALITY")
+}
+
 // getAIServiceURL returns the AI service URL from environment or default
 func getAIServiceURL() string {
 	url := config.GetEnv("AI_SERVICE_URL")
@@ -35,6 +236,135 @@ func getAIServiceURL() string {
 	return url
 }
 
+// getStreamBufferSize returns the read buffer size in bytes for proxied chat streams
+// (AI_STREAM_BUFFER_SIZE, default 4096)
+func getStreamBufferSize() int {
+	size := config.GetEnvInt("AI_STREAM_BUFFER_SIZE", 4096)
+	if size < 16 {
+		size = 16
+	}
+	return size
+}
+
+// getStreamWriteTimeout returns how long a write to a streaming client may block before the
+// stream is aborted (AI_STREAM_WRITE_TIMEOUT in seconds, default 30, 0 disables)
+func getStreamWriteTimeout() time.Duration {
+	seconds := config.GetEnvInt("AI_STREAM_WRITE_TIMEOUT", 30)
+	if seconds < 0 {
+		seconds = 0
+	}
+	return time.Duration(seconds) * time.Second
+}
+
+// getStreamKeepAliveInterval returns how long a stream may stay silent before a keep-alive
+// comment is sent, so proxies do not drop it while the AI service is thinking
+// (AI_STREAM_KEEPALIVE_INTERVAL in seconds, default 15, 0 disables)
+func getStreamKeepAliveInterval() time.Duration {
+	seconds := config.GetEnvInt("AI_STREAM_KEEPALIVE_INTERVAL", 15)
+	if seconds < 0 {
+		seconds = 0
+	}
+	return time.Duration(seconds) * time.Second
+}
+
+// streamLine is a line read from an AI service stream, with the error that ended the stream if any
+type streamLine struct {
+	data []byte
+	err  error
+}
+
+// relayStream copies an AI service stream to the client line by line, flushing each line.
+// Each write must finish within getStreamWriteTimeout; when the client stops reading, cancel
+// is called to abort the upstream request so it does not hold an AI service connection.
+// When nothing has been relayed for getStreamKeepAliveInterval, an SSE comment is sent instead.
+func relayStream(c *gin.Context, body io.Reader, cancel context.CancelFunc) error {
+	rc := http.NewResponseController(c.Writer)
+	timeout := getStreamWriteTimeout()
+	if timeout > 0 {
+		defer rc.SetWriteDeadline(time.Time{})
+	}
+
+	write := func(data []byte) error {
+		if timeout > 0 {
+			if err := rc.SetWriteDeadline(time.Now().Add(timeout)); err != nil && !errors.Is(err, http.ErrNotSupported) {
+				return fmt.Errorf("failed to set write deadline: %w", err)
+			}
+		}
+		if _, err := c.Writer.Write(data); err != nil {
+			return fmt.Errorf("failed to write to client: %w", err)
+		}
+		if err := rc.Flush(); err != nil {
+			return fmt.Errorf("failed to flush to client: %w", err)
+		}
+		return nil
+	}
+
+	// Lines are read separately so keep-alives can be sent while the read blocks
+	lines := make(chan streamLine)
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		reader := bufio.NewReaderSize(body, getStreamBufferSize())
+		for {
+			line, err := reader.ReadBytes('\n')
+			select {
+			case lines <- streamLine{data: line, err: err}:
+			case <-done:
+				return
+			}
+			if err != nil {
+				return
+			}
+		}
+	}()
+
+	interval := getStreamKeepAliveInterval()
+	var ticker *time.Ticker
+	var keepAlive <-chan time.Time
+	if interval > 0 {
+		ticker = time.NewTicker(interval)
+		defer ticker.Stop()
+		keepAlive = ticker.C
+	}
+
+	// Keep-alives only end an event between events, so they never split one
+	betweenEvents := true
+	for {
+		select {
+		case line := <-lines:
+			// On error, line.data holds any trailing data without a newline
+			if len(line.data) > 0 {
+				if err := write(line.data); err != nil {
+					cancel()
+					return err
+				}
+				betweenEvents = len(bytes.TrimRight(line.data, "\r\n")) == 0
+				if ticker != nil {
+					ticker.Reset(interval)
+				}
+			}
+			if line.err == io.EOF {
+				return nil
+			}
+			if line.err != nil {
+				return line.err
+			}
+		case <-keepAlive:
+			comment := ": keep-alive\n"
+			if betweenEvents {
+				comment += "\n"
+			}
+			if err := write([]byte(comment)); err != nil {
+				cancel()
+				return err
+			}
+		case <-c.Request.Context().Done():
+			cancel()
+			return c.Request.Context().Err()
+		}
+	}
+}
+
 // Chat handles non-streaming chat requests
 func Chat(c *gin.Context) {
 	var req ChatRequest
@@ -42,6 +372,24 @@ func Chat(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
+	if err := validateMaxTokens(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err := validateModel(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err := sanitizeChatMessages(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err := applyDefaultPersonality(c.Request.Context(), c.GetInt64("user_id"), &req); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Organization not found"})
+		return
+	}
+	mergeSystemMessages(&req)
+	truncateHistory(&req)
 
 	// Forward request to AI service
 	aiURL := fmt.Sprintf("%s/chat", getAIServiceURL())
@@ -52,8 +400,25 @@ func Chat(c *gin.Context) {
 		return
 	}
 
-	resp, err := http.Post(aiURL, "application/json", bytes.NewBuffer(reqBody))
+	// Bind the upstream call to the client's request so a disconnect cancels it
+	ctx := c.Request.Context()
+	httpReq, err := http.NewRequestWithContext(ctx, "POST", aiURL, bytes.NewBuffer(reqBody))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create request"})
+		return
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+
+	resp, err := doAIRequest(httpReq)
 	if err != nil {
+		if respondAIUnavailable(c, err) {
+			return
+		}
+		if ctx.Err() != nil {
+			// Client went away; nobody is left to receive a response
+			c.Abort()
+			return
+		}
 		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("Failed to connect to AI service: %v", err)})
 		return
 	}
@@ -61,13 +426,67 @@ func Chat(c *gin.Context) {
 
 	body, err := io.ReadAll(resp.Body)
 	if err != nil {
+		if ctx.Err() != nil {
+			c.Abort()
+			return
+		}
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to read response"})
 		return
 	}
 
+	// Redact configured patterns (e.g. emails, phone numbers) before the reply leaves the API
+	if masker := getResponseMasker(); masker != nil && resp.StatusCode == http.StatusOK {
+		body = masker.maskChatResponse(body)
+	}
+
 	c.Data(resp.StatusCode, "application/json", body)
 }
 
+// completeChat sends a non-streaming chat request to the AI service and returns the reply text,
+// masked when AI_RESPONSE_MASKING is on
+func completeChat(ctx context.Context, req *ChatRequest) (string, error) {
+	aiURL := fmt.Sprintf("%s/chat", getAIServiceURL())
+
+	req.Stream = false
+	reqBody, err := json.Marshal(req)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	httpReq, err := http.NewRequestWithContext(ctx, "POST", aiURL, bytes.NewBuffer(reqBody))
+	if err != nil {
+		return "", fmt.Errorf("failed to create request: %w", err)
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+
+	resp, err := doAIRequest(httpReq)
+	if err != nil {
+		return "", fmt.Errorf("failed to connect to AI service: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return "", fmt.Errorf("failed to read response: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("AI service returned status %d: %s", resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+
+	var result struct {
+		Response string `json:"response"`
+	}
+	if err := json.Unmarshal(body, &result); err != nil {
+		return "", fmt.Errorf("invalid AI service response: %w", err)
+	}
+
+	// Replies generated outside Chat, such as regenerated messages, are masked the same way
+	if masker := getResponseMasker(); masker != nil {
+		return masker.mask(result.Response), nil
+	}
+	return result.Response, nil
+}
+
 // ChatStream handles streaming chat requests (SSE)
 func ChatStream(c *gin.Context) {
 	var req ChatRequest
@@ -75,6 +494,24 @@ func ChatStream(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
+	if err := validateMaxTokens(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err := validateModel(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err := sanitizeChatMessages(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err := applyDefaultPersonality(c.Request.Context(), c.GetInt64("user_id"), &req); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Organization not found"})
+		return
+	}
+	mergeSystemMessages(&req)
+	truncateHistory(&req)
 
 	// Forward request to AI service streaming endpoint
 	aiURL := fmt.Sprintf("%s/chat/stream", getAIServiceURL())
@@ -85,8 +522,9 @@ func ChatStream(c *gin.Context) {
 		return
 	}
 
-	// Create request to AI service
-	httpReq, err := http.NewRequest("POST", aiURL, bytes.NewBuffer(reqBody))
+	// Bind the upstream call to the client's request so a disconnect cancels it
+	ctx := c.Request.Context()
+	httpReq, err := http.NewRequestWithContext(ctx, "POST", aiURL, bytes.NewBuffer(reqBody))
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create request"})
 		return
@@ -94,9 +532,16 @@ func ChatStream(c *gin.Context) {
 	httpReq.Header.Set("Content-Type", "application/json")
 
 	// Execute request
-	client := &http.Client{}
-	resp, err := client.Do(httpReq)
+	resp, err := doAIRequest(httpReq)
 	if err != nil {
+		if respondAIUnavailable(c, err) {
+			return
+		}
+		if ctx.Err() != nil {
+			// Client went away; nobody is left to receive a response
+			c.Abort()
+			return
+		}
 		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("Failed to connect to AI service: %v", err)})
 		return
 	}
@@ -137,6 +582,24 @@ func ChatStreamRaw(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
+	if err := validateMaxTokens(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err := validateModel(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err := sanitizeChatMessages(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err := applyDefaultPersonality(c.Request.Context(), c.GetInt64("user_id"), &req); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Organization not found"})
+		return
+	}
+	mergeSystemMessages(&req)
+	truncateHistory(&req)
 
 	// Forward request to AI service streaming endpoint
 	aiURL := fmt.Sprintf("%s/chat/stream", getAIServiceURL())
@@ -147,8 +610,9 @@ func ChatStreamRaw(c *gin.Context) {
 		return
 	}
 
-	// Create request to AI service
-	httpReq, err := http.NewRequest("POST", aiURL, bytes.NewBuffer(reqBody))
+	// Bind the upstream call to the client's request so a disconnect cancels it
+	ctx := c.Request.Context()
+	httpReq, err := http.NewRequestWithContext(ctx, "POST", aiURL, bytes.NewBuffer(reqBody))
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create request"})
 		return
@@ -156,9 +620,16 @@ func ChatStreamRaw(c *gin.Context) {
 	httpReq.Header.Set("Content-Type", "application/json")
 
 	// Execute request
-	client := &http.Client{}
-	resp, err := client.Do(httpReq)
+	resp, err := doAIRequest(httpReq)
 	if err != nil {
+		if respondAIUnavailable(c, err) {
+			return
+		}
+		if ctx.Err() != nil {
+			// Client went away; nobody is left to receive a response
+			c.Abort()
+			return
+		}
 		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("Failed to connect to AI service: %v", err)})
 		return
 	}
@@ -194,33 +665,79 @@ func ChatStreamRaw(c *gin.Context) {
 	}
 }
 
-// GetPersonalities fetches available personalities from AI service
-func GetPersonalities(c *gin.Context) {
-	aiURL := fmt.Sprintf("%s/personalities", getAIServiceURL())
+// EmbedRequest represents the request payload for the embed endpoint.
+// Either a single text or a batch of texts may be given.
+type EmbedRequest struct {
+	Text  string   `json:"text,omitempty"`
+	Texts []string `json:"texts,omitempty"`
+}
+
+// embedTexts returns the texts to embed, enforcing the configured batch size and input
+// length (AI_EMBED_MAX_BATCH, AI_EMBED_MAX_INPUT_CHARS)
+func (r *EmbedRequest) embedTexts() ([]string, error) {
+	texts := r.Texts
+	if r.Text != "" {
+		texts = append([]string{r.Text}, texts...)
+	}
+	if len(texts) == 0 {
+		return nil, errors.New("text or texts is required")
+	}
+
+	maxBatch := config.GetEnvInt("AI_EMBED_MAX_BATCH", 32)
+	if len(texts) > maxBatch {
+		return nil, fmt.Errorf("at most %d texts can be embedded per request", maxBatch)
+	}
+
+	maxChars := config.GetEnvInt("AI_EMBED_MAX_INPUT_CHARS", 8192)
+	for i, text := range texts {
+		if strings.TrimSpace(text) == "" {
+			return nil, fmt.Errorf("text %d is empty", i)
+		}
+		if utf8.RuneCountInString(text) > maxChars {
+			return nil, fmt.Errorf("text %d exceeds %d characters", i, maxChars)
+		}
+	}
+	return texts, nil
+}
 
-	resp, err := http.Get(aiURL)
+// Embed returns embedding vectors for arbitrary text from the AI service without storing them
+func Embed(c *gin.Context) {
+	var req EmbedRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	texts, err := req.embedTexts()
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("Failed to connect to AI service: %v", err)})
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
-	defer resp.Body.Close()
 
-	body, err := io.ReadAll(resp.Body)
+	aiURL := fmt.Sprintf("%s/embed", getAIServiceURL())
+
+	reqBody, err := json.Marshal(gin.H{"texts": texts})
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to read response"})
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to marshal request"})
 		return
 	}
 
-	c.Data(resp.StatusCode, "application/json", body)
-}
-
-// GetPersonality fetches a specific personality by ID
-func GetPersonality(c *gin.Context) {
-	pid := c.Param("id")
-	aiURL := fmt.Sprintf("%s/personalities/%s", getAIServiceURL(), pid)
+	ctx := c.Request.Context()
+	httpReq, err := http.NewRequestWithContext(ctx, "POST", aiURL, bytes.NewBuffer(reqBody))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create request"})
+		return
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
 
-	resp, err := http.Get(aiURL)
+	resp, err := doAIRequest(httpReq)
 	if err != nil {
+		if respondAIUnavailable(c, err) {
+			return
+		}
+		if ctx.Err() != nil {
+			c.Abort()
+			return
+		}
 		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("Failed to connect to AI service: %v", err)})
 		return
 	}
@@ -235,6 +752,19 @@ func GetPersonality(c *gin.Context) {
 	c.Data(resp.StatusCode, "application/json", body)
 }
 
+// GetPersonalities fetches available personalities from AI service (cached)
+func GetPersonalities(c *gin.Context) {
+	aiURL := fmt.Sprintf("%s/personalities", getAIServiceURL())
+	GetPersonalityCache().serve(c, personalitiesListKey, aiURL)
+}
+
+// GetPersonality fetches a specific personality by ID (cached)
+func GetPersonality(c *gin.Context) {
+	pid := c.Param("id")
+	aiURL := fmt.Sprintf("%s/personalities/%s", getAIServiceURL(), url.PathEscape(pid))
+	GetPersonalityCache().serve(c, "id:"+pid, aiURL)
+}
+
 // ChatStreamImproved handles streaming with better buffering and line-by-line processing
 func ChatStreamImproved(c *gin.Context) {
 	var req ChatRequest
@@ -242,6 +772,24 @@ func ChatStreamImproved(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
+	if err := validateMaxTokens(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err := validateModel(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err := sanitizeChatMessages(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err := applyDefaultPersonality(c.Request.Context(), c.GetInt64("user_id"), &req); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Organization not found"})
+		return
+	}
+	mergeSystemMessages(&req)
+	truncateHistory(&req)
 
 	// Forward request to AI service streaming endpoint
 	aiURL := fmt.Sprintf("%s/chat/stream", getAIServiceURL())
@@ -252,8 +800,12 @@ func ChatStreamImproved(c *gin.Context) {
 		return
 	}
 
+	// Cancelled when the client stops reading, which aborts the upstream stream
+	ctx, cancel := context.WithCancel(c.Request.Context())
+	defer cancel()
+
 	// Create request to AI service
-	httpReq, err := http.NewRequest("POST", aiURL, bytes.NewBuffer(reqBody))
+	httpReq, err := http.NewRequestWithContext(ctx, "POST", aiURL, bytes.NewBuffer(reqBody))
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create request"})
 		return
@@ -261,9 +813,11 @@ func ChatStreamImproved(c *gin.Context) {
 	httpReq.Header.Set("Content-Type", "application/json")
 
 	// Execute request
-	client := &http.Client{}
-	resp, err := client.Do(httpReq)
+	resp, err := doAIRequest(httpReq)
 	if err != nil {
+		if respondAIUnavailable(c, err) {
+			return
+		}
 		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("Failed to connect to AI service: %v", err)})
 		return
 	}
@@ -282,35 +836,8 @@ func ChatStreamImproved(c *gin.Context) {
 	c.Header("Access-Control-Allow-Origin", "*")
 	c.Header("Access-Control-Allow-Headers", "Cache-Control")
 
-	// Stream line by line for better SSE handling
-	buffer := make([]byte, 1)
-	var lineBuffer strings.Builder
-
-	for {
-		n, err := resp.Body.Read(buffer)
-		if n > 0 {
-			char := buffer[0]
-			lineBuffer.WriteByte(char)
-
-			// When we hit a newline, flush the line
-			if char == '\n' {
-				line := lineBuffer.String()
-				c.Writer.WriteString(line)
-				c.Writer.Flush()
-				lineBuffer.Reset()
-			}
-		}
-
-		if err == io.EOF {
-			// Flush any remaining buffer
-			if lineBuffer.Len() > 0 {
-				c.Writer.WriteString(lineBuffer.String())
-				c.Writer.Flush()
-			}
-			break
-		}
-		if err != nil {
-			break
-		}
+	// Stream line by line for better SSE handling, flushing each complete line
+	if err := relayStream(c, resp.Body, cancel); err != nil {
+		log.Printf("Chat stream aborted: %v", err)
 	}
 }
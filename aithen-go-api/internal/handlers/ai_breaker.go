@@ -0,0 +1,62 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/aithen/go-api/internal/breaker"
+	"github.com/aithen/go-api/internal/config"
+	"github.com/aithen/go-api/internal/httpclient"
+	"github.com/gin-gonic/gin"
+)
+
+var (
+	aiBreakerInstance *breaker.Breaker
+	aiBreakerOnce     sync.Once
+)
+
+// GetAIBreaker returns the circuit breaker shared by all AI service calls.
+// It opens after AI_BREAKER_FAILURE_THRESHOLD consecutive failures (default 5)
+// and stays open for AI_BREAKER_COOLDOWN seconds (default 30).
+func GetAIBreaker() *breaker.Breaker {
+	aiBreakerOnce.Do(func() {
+		aiBreakerInstance = breaker.New(
+			config.GetEnvInt("AI_BREAKER_FAILURE_THRESHOLD", 5),
+			time.Duration(config.GetEnvInt("AI_BREAKER_COOLDOWN", 30))*time.Second,
+		)
+	})
+	return aiBreakerInstance
+}
+
+// doAIRequest sends a request to the AI service through the circuit breaker.
+// Connection errors and 5xx responses count as failures; breaker.ErrOpen is
+// returned without contacting the service while the breaker is open.
+func doAIRequest(req *http.Request) (*http.Response, error) {
+	b := GetAIBreaker()
+	if err := b.Allow(); err != nil {
+		return nil, err
+	}
+
+	resp, err := httpclient.New(0).Do(req)
+	switch {
+	case err != nil && req.Context().Err() != nil:
+		// Cancelled by our side; says nothing about the AI service's health
+		b.Abandon()
+	case err != nil || resp.StatusCode >= http.StatusInternalServerError:
+		b.Failure()
+	default:
+		b.Success()
+	}
+	return resp, err
+}
+
+// respondAIUnavailable writes a 503 if err is breaker.ErrOpen and reports whether it did
+func respondAIUnavailable(c *gin.Context, err error) bool {
+	if !errors.Is(err, breaker.ErrOpen) {
+		return false
+	}
+	c.JSON(http.StatusServiceUnavailable, gin.H{"error": "AI service is temporarily unavailable, please try again later"})
+	return true
+}
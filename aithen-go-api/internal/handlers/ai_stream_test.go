@@ -0,0 +1,232 @@
+package handlers
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// testChatRequest names a personality so no organization lookup is needed
+const testChatRequest = `{"messages":[{"role":"user","content":"hi"}],"personality":"default"}`
+
+// streamServer serves POST /chat/stream with handler in front of an AI service stub
+func streamServer(t *testing.T, handler gin.HandlerFunc, upstream http.HandlerFunc) *httptest.Server {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+
+	ai := httptest.NewServer(upstream)
+	t.Cleanup(ai.Close)
+	t.Setenv("AI_SERVICE_URL", ai.URL)
+
+	r := gin.New()
+	r.POST("/chat/stream", handler)
+	srv := httptest.NewServer(r)
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestChatStreamImprovedForwardsMultiLineEvents(t *testing.T) {
+	const events = "event: message\n" +
+		"data: first line\n" +
+		"data: second line\n" +
+		"data: third line\n" +
+		"\n" +
+		"data: {\"done\":true}\n" +
+		"\n"
+
+	srv := streamServer(t, ChatStreamImproved, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		// Split mid-line so the relay has to reassemble lines across reads
+		io.WriteString(w, events[:20])
+		w.(http.Flusher).Flush()
+		io.WriteString(w, events[20:])
+	})
+
+	resp, err := http.Post(srv.URL+"/chat/stream", "application/json", strings.NewReader(testChatRequest))
+	if err != nil {
+		t.Fatalf("POST /chat/stream: %v", err)
+	}
+	defer resp.Body.Close()
+
+	got, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatalf("reading stream: %v", err)
+	}
+	if string(got) != events {
+		t.Errorf("relayed stream = %q, want %q", got, events)
+	}
+}
+
+// countingReader counts the Read calls made against the wrapped reader
+type countingReader struct {
+	r     io.Reader
+	reads int
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	c.reads++
+	return c.r.Read(p)
+}
+
+func BenchmarkRelayStream(b *testing.B) {
+	gin.SetMode(gin.TestMode)
+	b.Setenv("AI_STREAM_KEEPALIVE_INTERVAL", "0")
+
+	var stream bytes.Buffer
+	for i := 0; i < 500; i++ {
+		stream.WriteString("data: {\"token\":\"lorem ipsum dolor sit amet\"}\n\n")
+	}
+	payload := stream.Bytes()
+
+	b.Run("line", func(b *testing.B) {
+		var reads int
+		b.SetBytes(int64(len(payload)))
+		for i := 0; i < b.N; i++ {
+			c, _ := gin.CreateTestContext(httptest.NewRecorder())
+			c.Request = httptest.NewRequest(http.MethodPost, "/chat/stream", nil)
+			body := &countingReader{r: bytes.NewReader(payload)}
+			if err := relayStream(c, body, func() {}); err != nil {
+				b.Fatalf("relayStream: %v", err)
+			}
+			reads += body.reads
+		}
+		b.ReportMetric(float64(reads)/float64(b.N), "reads/op")
+	})
+
+	// The byte-at-a-time loop relayStream replaced, for comparison
+	b.Run("byte", func(b *testing.B) {
+		var reads int
+		b.SetBytes(int64(len(payload)))
+		for i := 0; i < b.N; i++ {
+			c, _ := gin.CreateTestContext(httptest.NewRecorder())
+			body := &countingReader{r: bytes.NewReader(payload)}
+			buffer := make([]byte, 1)
+			for {
+				n, err := body.Read(buffer)
+				if n > 0 {
+					c.Writer.Write(buffer[:n])
+					if buffer[0] == '\n' {
+						c.Writer.Flush()
+					}
+				}
+				if err != nil {
+					break
+				}
+			}
+			reads += body.reads
+		}
+		b.ReportMetric(float64(reads)/float64(b.N), "reads/op")
+	})
+}
+
+func TestChatStreamImprovedSendsKeepAliveDuringGap(t *testing.T) {
+	t.Setenv("AI_STREAM_KEEPALIVE_INTERVAL", "1")
+
+	release := make(chan struct{})
+	srv := streamServer(t, ChatStreamImproved, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		io.WriteString(w, "data: first\n\n")
+		w.(http.Flusher).Flush()
+		// Stall like a model that is still thinking
+		select {
+		case <-release:
+		case <-r.Context().Done():
+			return
+		}
+		io.WriteString(w, "data: second\n\n")
+	})
+
+	resp, err := http.Post(srv.URL+"/chat/stream", "application/json", strings.NewReader(testChatRequest))
+	if err != nil {
+		t.Fatalf("POST /chat/stream: %v", err)
+	}
+	defer resp.Body.Close()
+
+	lines := make(chan string)
+	go func() {
+		defer close(lines)
+		reader := bufio.NewReader(resp.Body)
+		for {
+			line, err := reader.ReadString('\n')
+			if err != nil {
+				return
+			}
+			lines <- line
+		}
+	}()
+
+	var got []string
+	deadline := time.After(3 * time.Second)
+	for keepAlive := false; !keepAlive; {
+		select {
+		case line, ok := <-lines:
+			if !ok {
+				t.Fatalf("stream ended before a keep-alive, got %q", got)
+			}
+			got = append(got, line)
+			keepAlive = line == ": keep-alive\n"
+		case <-deadline:
+			t.Fatalf("no keep-alive within 3s of a 1s interval, got %q", got)
+		}
+	}
+	close(release)
+
+	// The keep-alive lands between events and the stream carries on afterwards
+	want := []string{"data: first\n", "\n", ": keep-alive\n"}
+	if strings.Join(got, "") != strings.Join(want, "") {
+		t.Errorf("lines before keep-alive = %q, want %q", got, want)
+	}
+	var rest strings.Builder
+	for line := range lines {
+		rest.WriteString(line)
+	}
+	if !strings.HasSuffix(rest.String(), "data: second\n\n") {
+		t.Errorf("stream after keep-alive = %q, want it to end with the second event", rest.String())
+	}
+}
+
+func TestChatStreamImprovedAbortsSlowConsumer(t *testing.T) {
+	t.Setenv("AI_STREAM_WRITE_TIMEOUT", "1")
+	t.Setenv("AI_STREAM_KEEPALIVE_INTERVAL", "0")
+
+	aborted := make(chan struct{})
+	event := []byte("data: " + strings.Repeat("x", 1024) + "\n\n")
+	srv := streamServer(t, ChatStreamImproved, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		for {
+			if _, err := w.Write(event); err != nil {
+				break
+			}
+			w.(http.Flusher).Flush()
+			if r.Context().Err() != nil {
+				break
+			}
+		}
+		<-r.Context().Done()
+		close(aborted)
+	})
+
+	// A client that sends the request and then never reads the response
+	conn, err := net.Dial("tcp", srv.Listener.Addr().String())
+	if err != nil {
+		t.Fatalf("dial: %v", err)
+	}
+	defer conn.Close()
+	fmt.Fprintf(conn, "POST /chat/stream HTTP/1.1\r\nHost: test\r\nContent-Type: application/json\r\nContent-Length: %d\r\n\r\n%s",
+		len(testChatRequest), testChatRequest)
+
+	select {
+	case <-aborted:
+	case <-time.After(10 * time.Second):
+		t.Fatal("upstream stream was not aborted while the client stopped reading")
+	}
+}
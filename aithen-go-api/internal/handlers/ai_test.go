@@ -0,0 +1,491 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aithen/go-api/internal/models"
+	"github.com/gin-gonic/gin"
+)
+
+func TestGetAIServiceURLIgnoresTrainingService(t *testing.T) {
+	tests := []struct {
+		name        string
+		trainingURL string
+		aiURL       string
+		want        string
+	}{
+		{name: "AI service set", trainingURL: "http://training:9000", aiURL: "http://ai:8000", want: "http://ai:8000"},
+		{name: "only training service set", trainingURL: "http://training:9000", aiURL: "", want: "http://localhost:8000"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("TRAINING_SERVICE_URL", tt.trainingURL)
+			t.Setenv("AI_SERVICE_URL", tt.aiURL)
+			if got := getAIServiceURL(); got != tt.want {
+				t.Errorf("getAIServiceURL() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestChatHandlersCancelUpstreamOnClientDisconnect(t *testing.T) {
+	handlers := []struct {
+		name    string
+		handler gin.HandlerFunc
+	}{
+		{name: "Chat", handler: Chat},
+		{name: "ChatStream", handler: ChatStream},
+		{name: "ChatStreamRaw", handler: ChatStreamRaw},
+		{name: "ChatStreamImproved", handler: ChatStreamImproved},
+	}
+	for _, tt := range handlers {
+		t.Run(tt.name, func(t *testing.T) {
+			received := make(chan struct{})
+			cancelled := make(chan struct{})
+			srv := streamServer(t, tt.handler, func(w http.ResponseWriter, r *http.Request) {
+				// The server only notices a disconnect once the body has been read
+				io.Copy(io.Discard, r.Body)
+				close(received)
+				// Block like a slow generation until the proxy gives up on us
+				select {
+				case <-r.Context().Done():
+					close(cancelled)
+				case <-time.After(10 * time.Second):
+				}
+			})
+
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			req, err := http.NewRequestWithContext(ctx, http.MethodPost, srv.URL+"/chat/stream", strings.NewReader(testChatRequest))
+			if err != nil {
+				t.Fatalf("NewRequest: %v", err)
+			}
+			req.Header.Set("Content-Type", "application/json")
+			go func() {
+				if resp, err := http.DefaultClient.Do(req); err == nil {
+					resp.Body.Close()
+				}
+			}()
+
+			select {
+			case <-received:
+			case <-time.After(5 * time.Second):
+				t.Fatal("request never reached the AI service")
+			}
+			cancel()
+
+			select {
+			case <-cancelled:
+			case <-time.After(5 * time.Second):
+				t.Fatal("upstream request kept running after the client disconnected")
+			}
+		})
+	}
+}
+
+func TestChatAppliesConfiguredDefaults(t *testing.T) {
+	useTestDatabase(t)
+	gin.SetMode(gin.TestMode)
+	t.Setenv("DEFAULT_MAX_TOKENS", "321")
+	t.Setenv("DEFAULT_PERSONALITY", "tutor")
+
+	// A user with no organization, so only the server-wide defaults apply
+	user := createTestUser(t, models.NewModels())
+
+	var forwarded ChatRequest
+	ai := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewDecoder(r.Body).Decode(&forwarded)
+		io.WriteString(w, `{"response":"ok"}`)
+	}))
+	defer ai.Close()
+	t.Setenv("AI_SERVICE_URL", ai.URL)
+
+	r := gin.New()
+	r.POST("/chat", func(c *gin.Context) { c.Set("user_id", user.ID) }, Chat)
+
+	tests := []struct {
+		name            string
+		body            string
+		wantPersonality string
+		wantMaxTokens   int
+	}{
+		{
+			name:            "omitted fields use the defaults",
+			body:            `{"messages":[{"role":"user","content":"hi"}]}`,
+			wantPersonality: "tutor",
+			wantMaxTokens:   321,
+		},
+		{
+			name:            "client values take precedence",
+			body:            `{"messages":[{"role":"user","content":"hi"}],"personality":"coder","max_tokens":50}`,
+			wantPersonality: "coder",
+			wantMaxTokens:   50,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			forwarded = ChatRequest{}
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/chat", strings.NewReader(tt.body)))
+			if w.Code != http.StatusOK {
+				t.Fatalf("status = %d, want %d: %s", w.Code, http.StatusOK, w.Body.String())
+			}
+			if forwarded.Personality != tt.wantPersonality {
+				t.Errorf("forwarded personality = %q, want %q", forwarded.Personality, tt.wantPersonality)
+			}
+			if forwarded.MaxTokens != tt.wantMaxTokens {
+				t.Errorf("forwarded max_tokens = %d, want %d", forwarded.MaxTokens, tt.wantMaxTokens)
+			}
+		})
+	}
+}
+
+func TestDefaultsFallBackToFormerNames(t *testing.T) {
+	tests := []struct {
+		name            string
+		env             map[string]string
+		wantMaxTokens   int
+		wantPersonality string
+	}{
+		{name: "unset", env: map[string]string{}, wantMaxTokens: 1024, wantPersonality: ""},
+		{
+			name:            "former names",
+			env:             map[string]string{"AI_DEFAULT_MAX_TOKENS": "256", "AI_DEFAULT_PERSONALITY": "legacy"},
+			wantMaxTokens:   256,
+			wantPersonality: "legacy",
+		},
+		{
+			name: "new names win",
+			env: map[string]string{
+				"DEFAULT_MAX_TOKENS": "512", "DEFAULT_PERSONALITY": "tutor",
+				"AI_DEFAULT_MAX_TOKENS": "256", "AI_DEFAULT_PERSONALITY": "legacy",
+			},
+			wantMaxTokens:   512,
+			wantPersonality: "tutor",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for _, key := range []string{"DEFAULT_MAX_TOKENS", "DEFAULT_PERSONALITY", "AI_DEFAULT_MAX_TOKENS", "AI_DEFAULT_PERSONALITY"} {
+				t.Setenv(key, tt.env[key])
+			}
+			if got := getDefaultMaxTokens(); got != tt.wantMaxTokens {
+				t.Errorf("getDefaultMaxTokens() = %d, want %d", got, tt.wantMaxTokens)
+			}
+			if got := getDefaultPersonality(); got != tt.wantPersonality {
+				t.Errorf("getDefaultPersonality() = %q, want %q", got, tt.wantPersonality)
+			}
+		})
+	}
+}
+
+func TestMergeSystemMessages(t *testing.T) {
+	messages := []Message{
+		{Role: "system", Content: "stored prompt"},
+		{Role: "user", Content: "hi"},
+		{Role: "system", Content: "client prompt"},
+		{Role: "assistant", Content: "hello"},
+	}
+
+	tests := []struct {
+		name     string
+		strategy string
+		messages []Message
+		want     []Message
+	}{
+		{
+			name:     "last is the default",
+			strategy: "",
+			messages: messages,
+			want:     []Message{{Role: "system", Content: "client prompt"}, {Role: "user", Content: "hi"}, {Role: "assistant", Content: "hello"}},
+		},
+		{
+			name:     "last",
+			strategy: "last",
+			messages: messages,
+			want:     []Message{{Role: "system", Content: "client prompt"}, {Role: "user", Content: "hi"}, {Role: "assistant", Content: "hello"}},
+		},
+		{
+			name:     "first",
+			strategy: "First",
+			messages: messages,
+			want:     []Message{{Role: "system", Content: "stored prompt"}, {Role: "user", Content: "hi"}, {Role: "assistant", Content: "hello"}},
+		},
+		{
+			name:     "append",
+			strategy: "append",
+			messages: messages,
+			want:     []Message{{Role: "system", Content: "stored prompt\n\nclient prompt"}, {Role: "user", Content: "hi"}, {Role: "assistant", Content: "hello"}},
+		},
+		{
+			name:     "none",
+			strategy: "none",
+			messages: messages,
+			want:     messages,
+		},
+		{
+			name:     "unknown falls back to last",
+			strategy: "newest",
+			messages: messages,
+			want:     []Message{{Role: "system", Content: "client prompt"}, {Role: "user", Content: "hi"}, {Role: "assistant", Content: "hello"}},
+		},
+		{
+			name:     "single system message is left in place",
+			strategy: "append",
+			messages: []Message{{Role: "user", Content: "hi"}, {Role: "system", Content: "only"}},
+			want:     []Message{{Role: "user", Content: "hi"}, {Role: "system", Content: "only"}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("AI_SYSTEM_PROMPT_MERGE", tt.strategy)
+			req := &ChatRequest{Messages: append([]Message(nil), tt.messages...)}
+			mergeSystemMessages(req)
+			if !reflect.DeepEqual(req.Messages, tt.want) {
+				t.Errorf("mergeSystemMessages() = %+v, want %+v", req.Messages, tt.want)
+			}
+		})
+	}
+}
+
+func TestTruncateHistory(t *testing.T) {
+	system := Message{Role: "system", Content: "be brief"}
+	turns := func(from, to int) []Message {
+		var messages []Message
+		for i := from; i <= to; i++ {
+			role := "user"
+			if i%2 == 0 {
+				role = "assistant"
+			}
+			messages = append(messages, Message{Role: role, Content: fmt.Sprintf("turn %d", i)})
+		}
+		return messages
+	}
+	withSystem := func(messages []Message) []Message {
+		return append([]Message{system}, messages...)
+	}
+
+	tests := []struct {
+		name     string
+		limit    string
+		messages []Message
+		want     []Message
+	}{
+		{name: "under the limit", limit: "4", messages: withSystem(turns(1, 3)), want: withSystem(turns(1, 3))},
+		{name: "exactly at the limit", limit: "4", messages: withSystem(turns(1, 4)), want: withSystem(turns(1, 4))},
+		{name: "keeps the system message and the newest turns", limit: "4", messages: withSystem(turns(1, 9)), want: withSystem(turns(6, 9))},
+		{name: "without a system message", limit: "3", messages: turns(1, 6), want: turns(4, 6)},
+		{
+			name:     "system message inside the window",
+			limit:    "3",
+			messages: append(append(turns(1, 4), system), turns(5, 6)...),
+			want:     append(append(turns(4, 4), system), turns(5, 6)...),
+		},
+		{name: "0 disables truncation", limit: "0", messages: withSystem(turns(1, 9)), want: withSystem(turns(1, 9))},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("AI_MAX_HISTORY_MESSAGES", tt.limit)
+			req := &ChatRequest{Messages: tt.messages}
+			truncateHistory(req)
+			if !reflect.DeepEqual(req.Messages, tt.want) {
+				t.Errorf("truncateHistory() = %+v, want %+v", req.Messages, tt.want)
+			}
+		})
+	}
+}
+
+func TestValidateMaxTokens(t *testing.T) {
+	tests := []struct {
+		name      string
+		defaultMT string
+		maxMT     string
+		maxTokens int
+		want      int
+		wantErr   bool
+	}{
+		{name: "zero uses the default", maxTokens: 0, want: 1024},
+		{name: "zero uses the configured default", defaultMT: "256", maxTokens: 0, want: 256},
+		{name: "negative is rejected", maxTokens: -1, wantErr: true},
+		{name: "within the maximum is kept", maxTokens: 2000, want: 2000},
+		{name: "at the maximum is kept", maxTokens: 4096, want: 4096},
+		{name: "above the maximum is clamped", maxTokens: 5000, want: 4096},
+		{name: "above the configured maximum is clamped", maxMT: "512", maxTokens: 1000, want: 512},
+		{name: "default above the maximum is clamped", defaultMT: "8000", maxMT: "512", maxTokens: 0, want: 512},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("DEFAULT_MAX_TOKENS", tt.defaultMT)
+			t.Setenv("AI_DEFAULT_MAX_TOKENS", "")
+			t.Setenv("AI_MAX_TOKENS", tt.maxMT)
+
+			req := &ChatRequest{MaxTokens: tt.maxTokens}
+			err := validateMaxTokens(req)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("validateMaxTokens(%d) succeeded with %d, want an error", tt.maxTokens, req.MaxTokens)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("validateMaxTokens(%d) error = %v", tt.maxTokens, err)
+			}
+			if req.MaxTokens != tt.want {
+				t.Errorf("validateMaxTokens(%d) set max_tokens = %d, want %d", tt.maxTokens, req.MaxTokens, tt.want)
+			}
+		})
+	}
+}
+
+func TestEmbedProxiesToAIService(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	t.Setenv("AI_EMBED_MAX_BATCH", "2")
+	t.Setenv("AI_EMBED_MAX_INPUT_CHARS", "10")
+
+	var calls int
+	var gotTexts []string
+	ai := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		if r.Method != http.MethodPost || r.URL.Path != "/embed" {
+			t.Errorf("AI service got %s %s, want POST /embed", r.Method, r.URL.Path)
+		}
+		var body struct {
+			Texts []string `json:"texts"`
+		}
+		json.NewDecoder(r.Body).Decode(&body)
+		gotTexts = body.Texts
+		w.Header().Set("Content-Type", "application/json")
+		io.WriteString(w, `{"embeddings":[[0.1,0.2]],"model":"nomic-embed-text"}`)
+	}))
+	defer ai.Close()
+	t.Setenv("AI_SERVICE_URL", ai.URL)
+
+	r := gin.New()
+	r.POST("/ai/embed", Embed)
+
+	tests := []struct {
+		name      string
+		body      string
+		wantCode  int
+		wantTexts []string
+	}{
+		{name: "single text", body: `{"text":"hello"}`, wantCode: http.StatusOK, wantTexts: []string{"hello"}},
+		{name: "text and texts", body: `{"text":"a","texts":["b"]}`, wantCode: http.StatusOK, wantTexts: []string{"a", "b"}},
+		{name: "nothing to embed", body: `{}`, wantCode: http.StatusBadRequest},
+		{name: "blank text", body: `{"texts":["  "]}`, wantCode: http.StatusBadRequest},
+		{name: "batch too large", body: `{"texts":["a","b","c"]}`, wantCode: http.StatusBadRequest},
+		{name: "text too long", body: `{"text":"hello world!"}`, wantCode: http.StatusBadRequest},
+		{name: "invalid JSON", body: `{`, wantCode: http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			calls, gotTexts = 0, nil
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/ai/embed", strings.NewReader(tt.body)))
+
+			if w.Code != tt.wantCode {
+				t.Fatalf("status = %d, want %d: %s", w.Code, tt.wantCode, w.Body.String())
+			}
+			if tt.wantCode != http.StatusOK {
+				if calls != 0 {
+					t.Errorf("invalid request reached the AI service")
+				}
+				return
+			}
+			if !reflect.DeepEqual(gotTexts, tt.wantTexts) {
+				t.Errorf("AI service got texts %q, want %q", gotTexts, tt.wantTexts)
+			}
+			if !strings.Contains(w.Body.String(), `"embeddings":[[0.1,0.2]]`) {
+				t.Errorf("response = %s, want the AI service's embeddings", w.Body.String())
+			}
+		})
+	}
+}
+
+func TestResolveModel(t *testing.T) {
+	t.Setenv("AI_ALLOWED_MODELS", "mistral, llama3 ,,")
+
+	tests := []struct {
+		model   string
+		want    string
+		wantErr bool
+	}{
+		{model: "", want: "mistral"},
+		{model: "mistral", want: "mistral"},
+		{model: "llama3", want: "llama3"},
+		{model: "gpt-4", wantErr: true},
+		{model: "Mistral", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.model, func(t *testing.T) {
+			got, err := resolveModel(tt.model)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("resolveModel(%q) error = %v, wantErr %v", tt.model, err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("resolveModel(%q) = %q, want %q", tt.model, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestChatModelSelection(t *testing.T) {
+	useTestDatabase(t)
+	gin.SetMode(gin.TestMode)
+	t.Setenv("AI_ALLOWED_MODELS", "mistral,llama3")
+
+	user := createTestUser(t, models.NewModels())
+
+	var forwarded *ChatRequest
+	ai := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		forwarded = &ChatRequest{}
+		json.NewDecoder(r.Body).Decode(forwarded)
+		io.WriteString(w, `{"response":"ok"}`)
+	}))
+	defer ai.Close()
+	t.Setenv("AI_SERVICE_URL", ai.URL)
+
+	r := gin.New()
+	r.POST("/chat", func(c *gin.Context) { c.Set("user_id", user.ID) }, Chat)
+
+	tests := []struct {
+		name       string
+		model      string
+		wantStatus int
+		wantModel  string
+	}{
+		{name: "known model is forwarded", model: `"llama3"`, wantStatus: http.StatusOK, wantModel: "llama3"},
+		{name: "omitted model uses the default", model: `""`, wantStatus: http.StatusOK, wantModel: "mistral"},
+		{name: "unknown model is rejected", model: `"gpt-4"`, wantStatus: http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			forwarded = nil
+			body := `{"messages":[{"role":"user","content":"hi"}],"model":` + tt.model + `}`
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/chat", strings.NewReader(body)))
+			if w.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d: %s", w.Code, tt.wantStatus, w.Body.String())
+			}
+
+			if tt.wantStatus != http.StatusOK {
+				if forwarded != nil {
+					t.Errorf("AI service was called with model %q, want the request rejected first", forwarded.Model)
+				}
+				return
+			}
+			if forwarded == nil || forwarded.Model != tt.wantModel {
+				t.Errorf("forwarded request = %+v, want model %q", forwarded, tt.wantModel)
+			}
+		})
+	}
+}
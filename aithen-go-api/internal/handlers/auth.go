@@ -1,6 +1,7 @@
 package handlers
 
 import (
+	"log"
 	"net/http"
 
 	"github.com/aithen/go-api/internal/auth"
@@ -44,6 +45,17 @@ func Register(c *gin.Context) {
 		return
 	}
 
+	// Validate and normalize the organization contact details
+	contact := models.OrganizationContact{
+		Website: req.OrganizationWebsite,
+		Email:   req.OrganizationEmail,
+		Phone:   req.OrganizationPhone,
+	}
+	if err := contact.Normalize(); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid organization " + err.Error()})
+		return
+	}
+
 	m := models.NewModels()
 	ctx := c.Request.Context()
 
@@ -83,7 +95,7 @@ func Register(c *gin.Context) {
 
 	// Create organization
 	org, err := m.Organizations.Create(ctx, req.OrganizationName, orgSlug, req.OrganizationDescription,
-		req.OrganizationLogoURL, req.OrganizationWebsite, req.OrganizationEmail, req.OrganizationPhone, req.OrganizationAddress)
+		req.OrganizationLogoURL, contact.Website, contact.Email, contact.Phone, req.OrganizationAddress)
 	if err != nil {
 		if err == models.ErrSlugAlreadyExists {
 			c.JSON(http.StatusConflict, gin.H{"error": "Organization slug already exists. Please choose a different name."})
@@ -107,7 +119,7 @@ func Register(c *gin.Context) {
 		return
 	}
 
-	c.JSON(http.StatusCreated, AuthResponse{
+	Created(c, userLocation(user.ID), AuthResponse{
 		User:  user,
 		Token: token,
 	})
@@ -131,6 +143,11 @@ func Login(c *gin.Context) {
 		return
 	}
 
+	// Best-effort: a failure to record the login must not block it
+	if err := m.Users.RecordLogin(ctx, user.ID, c.ClientIP()); err != nil {
+		log.Printf("Warning: Failed to record login for user %d: %v", user.ID, err)
+	}
+
 	// Generate JWT token
 	token, err := auth.GenerateToken(user.ID, user.Email)
 	if err != nil {
@@ -162,7 +179,7 @@ func Me(c *gin.Context) {
 		return
 	}
 
-	c.JSON(http.StatusOK, user)
+	c.JSON(http.StatusOK, models.UserSelfView(*user))
 }
 
 // RefreshToken refreshes the JWT token
@@ -0,0 +1,58 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aithen/go-api/internal/models"
+	"github.com/gin-gonic/gin"
+)
+
+func TestLoginRecordsLastLogin(t *testing.T) {
+	useTestDatabase(t)
+	gin.SetMode(gin.TestMode)
+	m := models.NewModels()
+
+	user := createTestUser(t, m)
+	before := time.Now().Add(-time.Minute)
+
+	r := gin.New()
+	r.POST("/login", Login)
+	body, _ := json.Marshal(LoginRequest{Email: user.Email, Password: "password123"})
+	req := httptest.NewRequest(http.MethodPost, "/login", strings.NewReader(string(body)))
+	req.Header.Set("Content-Type", "application/json")
+	req.RemoteAddr = "203.0.113.7:51234"
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("login status = %d, want %d: %s", w.Code, http.StatusOK, w.Body.String())
+	}
+	if strings.Contains(w.Body.String(), "last_login") {
+		t.Errorf("login response = %s, want no login details outside the self view", w.Body.String())
+	}
+
+	r = gin.New()
+	r.GET("/me", func(c *gin.Context) { c.Set("user_id", user.ID) }, Me)
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/me", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("me status = %d, want %d: %s", w.Code, http.StatusOK, w.Body.String())
+	}
+	var me struct {
+		LastLoginAt *time.Time `json:"last_login_at"`
+		LastLoginIP *string    `json:"last_login_ip"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &me); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if me.LastLoginAt == nil || me.LastLoginAt.Before(before) {
+		t.Errorf("last_login_at = %v, want the time of the login", me.LastLoginAt)
+	}
+	if me.LastLoginIP == nil || *me.LastLoginIP != "203.0.113.7" {
+		t.Errorf("last_login_ip = %v, want 203.0.113.7", me.LastLoginIP)
+	}
+}
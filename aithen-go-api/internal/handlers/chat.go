@@ -1,17 +1,49 @@
 package handlers
 
 import (
+	"bytes"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
 	"strconv"
 
+	"github.com/aithen/go-api/internal/config"
 	"github.com/aithen/go-api/internal/models"
+	"github.com/aithen/go-api/internal/pagination"
 	"github.com/gin-gonic/gin"
 )
 
 // CreateChatRequest represents request to create a new chat
 type CreateChatRequest struct {
-	Title string `json:"title,omitempty"`
+	Title    string          `json:"title,omitempty"`
+	Metadata json.RawMessage `json:"metadata,omitempty"` // JSON object; omitted or null leaves it unchanged on update
+}
+
+// chatMetadataMaxSize returns the maximum size in bytes of a chat's metadata (CHAT_METADATA_MAX_SIZE, default 4096)
+func chatMetadataMaxSize() int {
+	return config.GetEnvInt("CHAT_METADATA_MAX_SIZE", 4096)
+}
+
+// parseChatMetadata validates chat metadata from a request. It returns nil when the metadata is
+// omitted or null, and an error unless it is a JSON object within chatMetadataMaxSize.
+func parseChatMetadata(raw json.RawMessage) (map[string]interface{}, error) {
+	raw = bytes.TrimSpace(raw)
+	if len(raw) == 0 || bytes.Equal(raw, []byte("null")) {
+		return nil, nil
+	}
+	if maxSize := chatMetadataMaxSize(); len(raw) > maxSize {
+		return nil, fmt.Errorf("metadata must be at most %d bytes", maxSize)
+	}
+	if raw[0] != '{' {
+		return nil, errors.New("metadata must be a JSON object")
+	}
+
+	var metadata map[string]interface{}
+	if err := json.Unmarshal(raw, &metadata); err != nil {
+		return nil, errors.New("metadata must be a JSON object")
+	}
+	return metadata, nil
 }
 
 // CreateChat handles creating a new chat
@@ -32,6 +64,12 @@ func CreateChat(c *gin.Context) {
 	models := models.NewModels()
 	ctx := c.Request.Context()
 
+	metadata, err := parseChatMetadata(req.Metadata)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
 	// Use provided title or default to empty string
 	title := req.Title
 	if title == "" {
@@ -39,7 +77,7 @@ func CreateChat(c *gin.Context) {
 	}
 
 	// Create chat
-	chat, err := models.Chats.Create(ctx, userID.(int64), title)
+	chat, err := models.Chats.Create(ctx, userID.(int64), title, metadata)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"error":   "Failed to create chat",
@@ -48,82 +86,86 @@ func CreateChat(c *gin.Context) {
 		return
 	}
 
-	// Log for debugging
-	fmt.Printf("CreateChat: Created chat - ID: %d, UserID: %d, Title: %s\n", chat.ID, chat.UserID, chat.Title)
-
-	c.JSON(http.StatusCreated, chat)
+	Created(c, chatLocation(chat.ID), chat)
 }
 
 // GetChat handles getting a chat by ID
 func GetChat(c *gin.Context) {
-	chatID := c.Param("id")
-	if chatID == "" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Chat ID is required"})
-		return
-	}
-
-	// Get user ID from context
-	userID, exists := c.Get("user_id")
-	if !exists {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
-		return
-	}
-
-	models := models.NewModels()
-	ctx := c.Request.Context()
+	chat := currentChat(c)
 
-	// Parse chat ID
-	id, err := strconv.ParseInt(chatID, 10, 64)
+	// Get messages for this chat
+	messages, err := models.NewModels().Chats.GetMessages(c.Request.Context(), chat.ID)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid chat ID"})
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get messages"})
 		return
 	}
 
-	// Log for debugging
-	fmt.Printf("GetChat: Looking for chat ID: %d, User ID: %d\n", id, userID.(int64))
-
-	// Get chat
-	chat, err := models.Chats.FindByID(ctx, id)
-	if err != nil {
-		fmt.Printf("GetChat: Chat not found - ID: %d, Error: %v\n", id, err)
-		c.JSON(http.StatusNotFound, gin.H{"error": "Chat not found"})
-		return
-	}
+	c.JSON(http.StatusOK, gin.H{
+		"chat":     chat,
+		"messages": messages,
+	})
+}
 
-	fmt.Printf("GetChat: Found chat - ID: %d, UserID: %d\n", chat.ID, chat.UserID)
+// GetMessage returns a single message of a chat, e.g. for deep links to a message.
+// Messages of other chats and chats of other users get 404.
+func GetMessage(c *gin.Context) {
+	chat := currentChat(c)
 
-	// Verify chat belongs to user
-	if chat.UserID != userID.(int64) {
-		c.JSON(http.StatusForbidden, gin.H{"error": "Access denied"})
+	messageID, err := strconv.ParseInt(c.Param("message_id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid message ID"})
 		return
 	}
 
-	// Get messages for this chat
-	messages, err := models.Chats.GetMessages(ctx, id)
+	message, err := models.NewModels().Chats.GetMessageByID(c.Request.Context(), chat.ID, messageID)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get messages"})
+		if errors.Is(err, models.ErrMessageNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Message not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get message"})
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{
-		"chat":     chat,
-		"messages": messages,
-	})
+	c.JSON(http.StatusOK, message)
 }
 
 // AddMessageRequest represents request to add a message to a chat
 type AddMessageRequest struct {
-	Role    string `json:"role" binding:"required"`
-	Content string `json:"content" binding:"required"`
+	Role              string   `json:"role" binding:"required"`
+	Content           string   `json:"content" binding:"required"`
+	AttachmentFileIDs []string `json:"attachment_file_ids,omitempty"`
+	// AI model that generated an assistant message
+	Model *string `json:"model,omitempty"`
+}
+
+// maxMessageAttachments is the maximum number of knowledge base files attached to one message
+const maxMessageAttachments = 10
+
+// parseAttachmentFileIDs parses and de-duplicates attachment file IDs, preserving order
+func parseAttachmentFileIDs(raw []string) ([]int64, error) {
+	if len(raw) > maxMessageAttachments {
+		return nil, fmt.Errorf("at most %d attachments are allowed per message", maxMessageAttachments)
+	}
+
+	seen := make(map[int64]bool, len(raw))
+	fileIDs := make([]int64, 0, len(raw))
+	for _, s := range raw {
+		fileID, err := strconv.ParseInt(s, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid attachment file ID: %q", s)
+		}
+		if !seen[fileID] {
+			seen[fileID] = true
+			fileIDs = append(fileIDs, fileID)
+		}
+	}
+	return fileIDs, nil
 }
 
 // AddMessage handles adding a message to a chat
 func AddMessage(c *gin.Context) {
-	chatID := c.Param("id")
-	if chatID == "" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Chat ID is required"})
-		return
-	}
+	chat := currentChat(c)
 
 	var req AddMessageRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
@@ -137,37 +179,54 @@ func AddMessage(c *gin.Context) {
 		return
 	}
 
-	// Get user ID from context
-	userID, exists := c.Get("user_id")
-	if !exists {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+	content, err := sanitizeContent(req.Content)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
-
-	models := models.NewModels()
-	ctx := c.Request.Context()
-
-	// Parse chat ID
-	id, err := strconv.ParseInt(chatID, 10, 64)
-	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid chat ID"})
+	if content == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "content must not be empty"})
 		return
 	}
+	req.Content = content
 
-	// Verify chat exists and belongs to user
-	chat, err := models.Chats.FindByID(ctx, id)
-	if err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Chat not found"})
-		return
+	// Only assistant messages are generated by a model
+	if req.Model != nil {
+		if req.Role != "assistant" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "model can only be set on assistant messages"})
+			return
+		}
+		model, err := resolveModel(*req.Model)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		req.Model = &model
 	}
 
-	if chat.UserID != userID.(int64) {
-		c.JSON(http.StatusForbidden, gin.H{"error": "Access denied"})
+	m := models.NewModels()
+	ctx := c.Request.Context()
+
+	// Attached files must exist and belong to an organization the user is a member of
+	fileIDs, err := parseAttachmentFileIDs(req.AttachmentFileIDs)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
+	if len(fileIDs) > 0 {
+		accessible, err := m.KnowledgeBases.CountAccessibleFiles(ctx, chat.UserID, fileIDs)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to verify attachments"})
+			return
+		}
+		if accessible != len(fileIDs) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "One or more attachment files were not found or are not accessible"})
+			return
+		}
+	}
 
 	// Add message to chat
-	message, err := models.Chats.AddMessage(ctx, id, req.Role, req.Content)
+	message, err := m.Chats.AddMessage(ctx, chat.ID, req.Role, req.Content, req.Model, fileIDs)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to add message"})
 		return
@@ -176,7 +235,9 @@ func AddMessage(c *gin.Context) {
 	c.JSON(http.StatusCreated, message)
 }
 
-// GetChats handles getting all chats for the current user
+// GetChats handles listing the current user's chats, most recently updated first
+// Query params: limit (default 50, max 200), cursor (next_cursor from the previous page),
+// metadata_key and metadata_value (only chats whose metadata has that string value)
 func GetChats(c *gin.Context) {
 	// Get user ID from context
 	userID, exists := c.Get("user_id")
@@ -185,26 +246,45 @@ func GetChats(c *gin.Context) {
 		return
 	}
 
-	models := models.NewModels()
+	limit, err := pagination.ParseLimit(c.Query("limit"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid limit"})
+		return
+	}
+	cursor, err := pagination.Parse(c.Query("cursor"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid cursor"})
+		return
+	}
+
+	var metadata map[string]interface{}
+	key, value := c.Query("metadata_key"), c.Query("metadata_value")
+	if key != "" || value != "" {
+		if key == "" || value == "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "metadata_key and metadata_value must be given together"})
+			return
+		}
+		metadata = map[string]interface{}{key: value}
+	}
+
+	m := models.NewModels()
 	ctx := c.Request.Context()
 
-	// Get all chats for user
-	chats, err := models.Chats.FindByUserID(ctx, userID.(int64))
+	// Fetch one extra chat to learn whether another page exists
+	chats, err := m.Chats.FindByUserID(ctx, userID.(int64), metadata, cursor, limit+1)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get chats"})
 		return
 	}
 
-	c.JSON(http.StatusOK, chats)
+	c.JSON(http.StatusOK, pagination.NewPage(chats, limit, func(chat *models.Chat) pagination.Cursor {
+		return pagination.Cursor{Time: chat.UpdatedAt, ID: chat.ID}
+	}))
 }
 
-// UpdateChat handles updating a chat's title
+// UpdateChat handles updating a chat's title and metadata
 func UpdateChat(c *gin.Context) {
-	chatID := c.Param("id")
-	if chatID == "" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Chat ID is required"})
-		return
-	}
+	chat := currentChat(c)
 
 	var req CreateChatRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
@@ -212,88 +292,236 @@ func UpdateChat(c *gin.Context) {
 		return
 	}
 
-	// Get user ID from context
-	userID, exists := c.Get("user_id")
-	if !exists {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+	metadata, err := parseChatMetadata(req.Metadata)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 
-	models := models.NewModels()
-	ctx := c.Request.Context()
-
-	// Parse chat ID
-	id, err := strconv.ParseInt(chatID, 10, 64)
+	// Update chat
+	updatedChat, err := models.NewModels().Chats.Update(c.Request.Context(), chat.ID, req.Title, metadata)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid chat ID"})
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update chat"})
+		return
+	}
+
+	c.JSON(http.StatusOK, updatedChat)
+}
+
+// DeleteChat handles deleting a chat
+func DeleteChat(c *gin.Context) {
+	chat := currentChat(c)
+
+	// Delete chat (messages will be cascade deleted)
+	if err := models.NewModels().Chats.Delete(c.Request.Context(), chat.ID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete chat"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Chat deleted successfully"})
+}
+
+// BranchChatRequest represents request to branch a chat from a message
+type BranchChatRequest struct {
+	FromMessageID string `json:"from_message_id" binding:"required"`
+}
+
+// BranchChat handles forking a chat at a given message into a new chat
+func BranchChat(c *gin.Context) {
+	chat := currentChat(c)
+
+	var req BranchChatRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 
-	// Verify chat exists and belongs to user
-	chat, err := models.Chats.FindByID(ctx, id)
+	fromMessageID, err := strconv.ParseInt(req.FromMessageID, 10, 64)
 	if err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Chat not found"})
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid message ID"})
 		return
 	}
 
-	if chat.UserID != userID.(int64) {
-		c.JSON(http.StatusForbidden, gin.H{"error": "Access denied"})
+	// Create the branch
+	branch, err := models.NewModels().Chats.BranchFrom(c.Request.Context(), chat.ID, fromMessageID)
+	if err != nil {
+		if err == models.ErrMessageNotFound {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Message not found in this chat"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to branch chat"})
 		return
 	}
 
-	// Update chat
-	updatedChat, err := models.Chats.Update(ctx, id, req.Title)
+	Created(c, chatLocation(branch.ID), branch)
+}
+
+// CopyChat handles duplicating a whole chat, e.g. to reuse it as a template. Unlike a branch,
+// every message is copied and the copy is not linked to the source chat.
+func CopyChat(c *gin.Context) {
+	chat := currentChat(c)
+
+	copyChat, err := models.NewModels().Chats.Copy(c.Request.Context(), chat.ID)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update chat"})
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to copy chat"})
 		return
 	}
 
-	c.JSON(http.StatusOK, updatedChat)
+	Created(c, chatLocation(copyChat.ID), copyChat)
 }
 
-// DeleteChat handles deleting a chat
-func DeleteChat(c *gin.Context) {
-	chatID := c.Param("id")
-	if chatID == "" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Chat ID is required"})
+// BulkDeleteMessagesRequest represents request to delete several messages from a chat
+type BulkDeleteMessagesRequest struct {
+	MessageIDs []string `json:"message_ids" binding:"required,min=1"`
+}
+
+// maxBulkDeleteMessages is the maximum number of messages deleted in one request
+const maxBulkDeleteMessages = 500
+
+// BulkDeleteMessages handles deleting several messages from a chat. Either all of the
+// messages are deleted or, if any of them is not in the chat, none are.
+func BulkDeleteMessages(c *gin.Context) {
+	chat := currentChat(c)
+
+	var req BulkDeleteMessagesRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 
-	// Get user ID from context
-	userID, exists := c.Get("user_id")
-	if !exists {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+	if len(req.MessageIDs) > maxBulkDeleteMessages {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("at most %d messages can be deleted at once", maxBulkDeleteMessages)})
 		return
 	}
 
-	models := models.NewModels()
-	ctx := c.Request.Context()
+	// Parse and de-duplicate message IDs
+	seen := make(map[int64]bool, len(req.MessageIDs))
+	messageIDs := make([]int64, 0, len(req.MessageIDs))
+	for _, raw := range req.MessageIDs {
+		messageID, err := strconv.ParseInt(raw, 10, 64)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Invalid message ID: %q", raw)})
+			return
+		}
+		if !seen[messageID] {
+			seen[messageID] = true
+			messageIDs = append(messageIDs, messageID)
+		}
+	}
 
-	// Parse chat ID
-	id, err := strconv.ParseInt(chatID, 10, 64)
+	deleted, err := models.NewModels().Chats.DeleteMessages(c.Request.Context(), chat.ID, messageIDs)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid chat ID"})
+		if err == models.ErrMessageNotFound {
+			c.JSON(http.StatusNotFound, gin.H{"error": "One or more messages were not found in this chat"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete messages"})
 		return
 	}
 
-	// Verify chat exists and belongs to user
-	chat, err := models.Chats.FindByID(ctx, id)
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Messages deleted successfully",
+		"deleted": deleted,
+	})
+}
+
+// RegenerateRequest represents optional settings for regenerating the last assistant reply
+type RegenerateRequest struct {
+	Personality string `json:"personality,omitempty"`
+	MaxTokens   int    `json:"max_tokens,omitempty"`
+	Model       string `json:"model,omitempty"` // Defaults to the chat's model
+	// Organization is the slug whose default personality applies when Personality is empty
+	Organization string `json:"organization,omitempty"`
+}
+
+// RegenerateMessage replaces the last assistant message in a chat with a new reply from the AI service
+func RegenerateMessage(c *gin.Context) {
+	chat := currentChat(c)
+
+	// The body is optional
+	var req RegenerateRequest
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	m := models.NewModels()
+	ctx := c.Request.Context()
+
+	messages, err := m.Chats.GetMessages(ctx, chat.ID)
 	if err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Chat not found"})
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get messages"})
 		return
 	}
 
-	if chat.UserID != userID.(int64) {
-		c.JSON(http.StatusForbidden, gin.H{"error": "Access denied"})
+	if len(messages) == 0 || messages[len(messages)-1].Role != "assistant" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "The last message is not an assistant message"})
 		return
 	}
+	last := messages[len(messages)-1]
 
-	// Delete chat (messages will be cascade deleted)
-	err = models.Chats.Delete(ctx, id)
+	// Re-send the conversation up to and including the last user message
+	history := messages[:len(messages)-1]
+	for len(history) > 0 && history[len(history)-1].Role != "user" {
+		history = history[:len(history)-1]
+	}
+	if len(history) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "No user message to regenerate a reply for"})
+		return
+	}
+
+	chatReq := ChatRequest{
+		Messages:     make([]Message, len(history)),
+		Personality:  req.Personality,
+		MaxTokens:    req.MaxTokens,
+		Model:        req.Model,
+		Organization: req.Organization,
+	}
+	if chatReq.Model == "" && chat.Model != nil {
+		chatReq.Model = *chat.Model
+	}
+	for i, message := range history {
+		chatReq.Messages[i] = Message{Role: message.Role, Content: message.Content}
+	}
+	if err := validateMaxTokens(&chatReq); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err := validateModel(&chatReq); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err := applyDefaultPersonality(ctx, chat.UserID, &chatReq); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Organization not found"})
+		return
+	}
+	mergeSystemMessages(&chatReq)
+	truncateHistory(&chatReq)
+
+	// Generate the new reply before touching the stored message so a failure keeps the old one
+	reply, err := completeChat(ctx, &chatReq)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete chat"})
+		if respondAIUnavailable(c, err) {
+			return
+		}
+		c.JSON(http.StatusBadGateway, gin.H{"error": fmt.Sprintf("Failed to regenerate message: %v", err)})
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"message": "Chat deleted successfully"})
+	message, err := m.Chats.ReplaceMessage(ctx, chat.ID, last.ID, reply, &chatReq.Model)
+	if err != nil {
+		if err == models.ErrMessageNotFound {
+			c.JSON(http.StatusConflict, gin.H{"error": "The message was changed while regenerating"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save regenerated message"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"replaced_message_id": fmt.Sprintf("%d", last.ID),
+		"message":             message,
+	})
 }
@@ -0,0 +1,488 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/aithen/go-api/internal/models"
+	"github.com/gin-gonic/gin"
+)
+
+func TestRegenerateMessage(t *testing.T) {
+	useTestDatabase(t)
+	gin.SetMode(gin.TestMode)
+	m := models.NewModels()
+	ctx := context.Background()
+	user := createTestUser(t, m)
+
+	var forwarded ChatRequest
+	ai := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewDecoder(r.Body).Decode(&forwarded)
+		io.WriteString(w, `{"response":"a better answer"}`)
+	}))
+	defer ai.Close()
+	t.Setenv("AI_SERVICE_URL", ai.URL)
+
+	r := gin.New()
+	r.POST("/chats/:id/regenerate", func(c *gin.Context) { c.Set("user_id", user.ID) }, ResolveChat(), RegenerateMessage)
+
+	// newChat creates a chat with messages alternating between the given roles
+	newChat := func(t *testing.T, roles ...string) *models.Chat {
+		t.Helper()
+		chat, err := m.Chats.Create(ctx, user.ID, "Regenerate", nil)
+		if err != nil {
+			t.Fatalf("failed to create chat: %v", err)
+		}
+		for i, role := range roles {
+			if _, err := m.Chats.AddMessage(ctx, chat.ID, role, fmt.Sprintf("message %d", i), nil, nil); err != nil {
+				t.Fatalf("failed to add message: %v", err)
+			}
+		}
+		return chat
+	}
+
+	t.Run("replaces the last assistant message", func(t *testing.T) {
+		chat := newChat(t, "user", "assistant", "user", "assistant")
+		before, _ := m.Chats.GetMessages(ctx, chat.ID)
+		old := before[len(before)-1]
+
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodPost, fmt.Sprintf("/chats/%d/regenerate", chat.ID), nil))
+		if w.Code != http.StatusOK {
+			t.Fatalf("status = %d, want %d: %s", w.Code, http.StatusOK, w.Body.String())
+		}
+
+		// The conversation is re-sent up to the last user message, without the old reply
+		if n := len(forwarded.Messages); n != 3 || forwarded.Messages[n-1].Content != "message 2" {
+			t.Errorf("forwarded messages = %+v, want the first 3 ending with the last user message", forwarded.Messages)
+		}
+
+		after, err := m.Chats.GetMessages(ctx, chat.ID)
+		if err != nil {
+			t.Fatalf("GetMessages() error = %v", err)
+		}
+		if len(after) != len(before) {
+			t.Fatalf("chat has %d messages after regenerating, want %d", len(after), len(before))
+		}
+		last := after[len(after)-1]
+		if last.Role != "assistant" || last.Content != "a better answer" {
+			t.Errorf("last message = %s %q, want the regenerated assistant reply", last.Role, last.Content)
+		}
+		for _, message := range after {
+			if message.Content == old.Content {
+				t.Errorf("old assistant message %q is still stored", old.Content)
+			}
+		}
+	})
+
+	t.Run("last message is not an assistant message", func(t *testing.T) {
+		chat := newChat(t, "user", "assistant", "user")
+
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodPost, fmt.Sprintf("/chats/%d/regenerate", chat.ID), nil))
+		if w.Code != http.StatusBadRequest {
+			t.Fatalf("status = %d, want %d: %s", w.Code, http.StatusBadRequest, w.Body.String())
+		}
+		if messages, _ := m.Chats.GetMessages(ctx, chat.ID); len(messages) != 3 {
+			t.Errorf("chat has %d messages, want 3 unchanged", len(messages))
+		}
+	})
+}
+
+func TestAddMessageAttachments(t *testing.T) {
+	useTestDatabase(t)
+	gin.SetMode(gin.TestMode)
+	m := models.NewModels()
+	ctx := context.Background()
+
+	user := createTestUser(t, m)
+	org := createTestOrganization(t, m, user)
+	kb, err := m.KnowledgeBases.Create(ctx, org.ID, "Docs", "", &user.ID)
+	if err != nil {
+		t.Fatalf("failed to create knowledge base: %v", err)
+	}
+	file := addTestFile(t, m, kb.ID, "notes.txt", "hello")
+
+	otherOwner := createTestUser(t, m)
+	otherOrg := createTestOrganization(t, m, otherOwner)
+	otherKB, err := m.KnowledgeBases.Create(ctx, otherOrg.ID, "Other", "", &otherOwner.ID)
+	if err != nil {
+		t.Fatalf("failed to create knowledge base: %v", err)
+	}
+	foreign := addTestFile(t, m, otherKB.ID, "secret.txt", "private")
+
+	chat, err := m.Chats.Create(ctx, user.ID, "Attachments", nil)
+	if err != nil {
+		t.Fatalf("failed to create chat: %v", err)
+	}
+
+	r := gin.New()
+	r.POST("/chats/:id/messages", func(c *gin.Context) { c.Set("user_id", user.ID) }, ResolveChat(), AddMessage)
+
+	tests := []struct {
+		name       string
+		fileIDs    []string
+		wantStatus int
+	}{
+		{"file in a member organization", []string{fmt.Sprint(file.ID)}, http.StatusCreated},
+		{"file in another organization", []string{fmt.Sprint(foreign.ID)}, http.StatusBadRequest},
+		{"nonexistent file", []string{"1"}, http.StatusBadRequest},
+		{"mix of valid and foreign files", []string{fmt.Sprint(file.ID), fmt.Sprint(foreign.ID)}, http.StatusBadRequest},
+		{"malformed file ID", []string{"not-a-number"}, http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			before, _ := m.Chats.GetMessages(ctx, chat.ID)
+
+			ids, _ := json.Marshal(tt.fileIDs)
+			body := fmt.Sprintf(`{"role":"user","content":"see attached","attachment_file_ids":%s}`, ids)
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, httptest.NewRequest(http.MethodPost, fmt.Sprintf("/chats/%d/messages", chat.ID), strings.NewReader(body)))
+			if w.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d: %s", w.Code, tt.wantStatus, w.Body.String())
+			}
+
+			after, err := m.Chats.GetMessages(ctx, chat.ID)
+			if err != nil {
+				t.Fatalf("GetMessages() error = %v", err)
+			}
+			if tt.wantStatus != http.StatusCreated {
+				if len(after) != len(before) {
+					t.Errorf("chat has %d messages, want %d unchanged", len(after), len(before))
+				}
+				return
+			}
+
+			var message models.Message
+			if err := json.Unmarshal(w.Body.Bytes(), &message); err != nil {
+				t.Fatalf("failed to decode response: %v", err)
+			}
+			if len(message.Attachments) != 1 || message.Attachments[0].FileName != "notes.txt" {
+				t.Errorf("attachments = %+v, want notes.txt", message.Attachments)
+			}
+			stored := after[len(after)-1]
+			if len(stored.Attachments) != 1 || stored.Attachments[0].KnowledgeBaseFileID != file.ID {
+				t.Errorf("stored attachments = %+v, want file %d", stored.Attachments, file.ID)
+			}
+		})
+	}
+}
+
+func TestBulkDeleteMessages(t *testing.T) {
+	useTestDatabase(t)
+	gin.SetMode(gin.TestMode)
+	m := models.NewModels()
+	ctx := context.Background()
+	user := createTestUser(t, m)
+
+	r := gin.New()
+	r.POST("/chats/:id/messages/bulk-delete", func(c *gin.Context) { c.Set("user_id", user.ID) }, ResolveChat(), BulkDeleteMessages)
+
+	// newChat creates a chat of user's with n messages and returns it with the message IDs
+	newChat := func(t *testing.T, owner *models.User, n int) (*models.Chat, []string) {
+		t.Helper()
+		chat, err := m.Chats.Create(ctx, owner.ID, "Bulk delete", nil)
+		if err != nil {
+			t.Fatalf("failed to create chat: %v", err)
+		}
+		var ids []string
+		for i := 0; i < n; i++ {
+			message, err := m.Chats.AddMessage(ctx, chat.ID, "user", fmt.Sprintf("message %d", i), nil, nil)
+			if err != nil {
+				t.Fatalf("failed to add message: %v", err)
+			}
+			ids = append(ids, fmt.Sprint(message.ID))
+		}
+		return chat, ids
+	}
+	bulkDelete := func(chatID int64, ids []string) *httptest.ResponseRecorder {
+		body, _ := json.Marshal(map[string][]string{"message_ids": ids})
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodPost, fmt.Sprintf("/chats/%d/messages/bulk-delete", chatID), strings.NewReader(string(body))))
+		return w
+	}
+	remaining := func(t *testing.T, chatID int64) int {
+		t.Helper()
+		messages, err := m.Chats.GetMessages(ctx, chatID)
+		if err != nil {
+			t.Fatalf("GetMessages() error = %v", err)
+		}
+		return len(messages)
+	}
+
+	t.Run("deletes the given messages", func(t *testing.T) {
+		chat, ids := newChat(t, user, 3)
+
+		// A repeated ID is only counted once
+		w := bulkDelete(chat.ID, []string{ids[0], ids[2], ids[0]})
+		if w.Code != http.StatusOK {
+			t.Fatalf("status = %d, want %d: %s", w.Code, http.StatusOK, w.Body.String())
+		}
+		var resp struct {
+			Deleted int64 `json:"deleted"`
+		}
+		json.Unmarshal(w.Body.Bytes(), &resp)
+		if resp.Deleted != 2 {
+			t.Errorf("deleted = %d, want 2", resp.Deleted)
+		}
+		if n := remaining(t, chat.ID); n != 1 {
+			t.Errorf("chat has %d messages, want 1", n)
+		}
+	})
+
+	t.Run("a foreign message deletes nothing", func(t *testing.T) {
+		chat, ids := newChat(t, user, 2)
+		_, otherIDs := newChat(t, user, 1)
+
+		w := bulkDelete(chat.ID, []string{ids[0], ids[1], otherIDs[0]})
+		if w.Code != http.StatusNotFound {
+			t.Fatalf("status = %d, want %d: %s", w.Code, http.StatusNotFound, w.Body.String())
+		}
+		if n := remaining(t, chat.ID); n != 2 {
+			t.Errorf("chat has %d messages, want 2 after the rejected request", n)
+		}
+	})
+
+	t.Run("an unknown message deletes nothing", func(t *testing.T) {
+		chat, ids := newChat(t, user, 1)
+
+		if w := bulkDelete(chat.ID, []string{ids[0], "1"}); w.Code != http.StatusNotFound {
+			t.Fatalf("status = %d, want %d: %s", w.Code, http.StatusNotFound, w.Body.String())
+		}
+		if n := remaining(t, chat.ID); n != 1 {
+			t.Errorf("chat has %d messages, want 1 after the rejected request", n)
+		}
+	})
+
+	t.Run("another user's chat", func(t *testing.T) {
+		chat, ids := newChat(t, createTestUser(t, m), 1)
+
+		if w := bulkDelete(chat.ID, ids); w.Code != http.StatusNotFound {
+			t.Fatalf("status = %d, want %d: %s", w.Code, http.StatusNotFound, w.Body.String())
+		}
+		if n := remaining(t, chat.ID); n != 1 {
+			t.Errorf("chat has %d messages, want 1", n)
+		}
+	})
+
+	t.Run("malformed message ID", func(t *testing.T) {
+		chat, _ := newChat(t, user, 1)
+
+		if w := bulkDelete(chat.ID, []string{"abc"}); w.Code != http.StatusBadRequest {
+			t.Errorf("status = %d, want %d: %s", w.Code, http.StatusBadRequest, w.Body.String())
+		}
+	})
+}
+
+func TestParseChatMetadata(t *testing.T) {
+	t.Setenv("CHAT_METADATA_MAX_SIZE", "32")
+
+	tests := []struct {
+		name    string
+		raw     string
+		want    map[string]interface{}
+		wantErr bool
+	}{
+		{name: "omitted", raw: ""},
+		{name: "null", raw: " null "},
+		{name: "object", raw: `{"ticket":"T-1","priority":2}`, want: map[string]interface{}{"ticket": "T-1", "priority": float64(2)}},
+		{name: "empty object", raw: `{}`, want: map[string]interface{}{}},
+		{name: "array", raw: `["T-1"]`, wantErr: true},
+		{name: "string", raw: `"T-1"`, wantErr: true},
+		{name: "malformed", raw: `{"ticket":`, wantErr: true},
+		{name: "too large", raw: `{"ticket":"` + strings.Repeat("x", 32) + `"}`, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseChatMetadata(json.RawMessage(tt.raw))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseChatMetadata(%s) error = %v, wantErr %v", tt.raw, err, tt.wantErr)
+			}
+			if fmt.Sprint(got) != fmt.Sprint(tt.want) || (got == nil) != (tt.want == nil) {
+				t.Errorf("parseChatMetadata(%s) = %v, want %v", tt.raw, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestChatMetadata(t *testing.T) {
+	useTestDatabase(t)
+	gin.SetMode(gin.TestMode)
+	m := models.NewModels()
+	user := createTestUser(t, m)
+
+	r := gin.New()
+	r.Use(func(c *gin.Context) { c.Set("user_id", user.ID) })
+	r.POST("/chats", CreateChat)
+	r.GET("/chats", GetChats)
+	r.PUT("/chats/:id", ResolveChat(), UpdateChat)
+	serve := func(method, path, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+	type chatResponse struct {
+		ID       string                 `json:"id"`
+		Title    string                 `json:"title"`
+		Metadata map[string]interface{} `json:"metadata"`
+	}
+	create := func(title, metadata string) chatResponse {
+		t.Helper()
+		w := serve(http.MethodPost, "/chats", fmt.Sprintf(`{"title":%q,"metadata":%s}`, title, metadata))
+		if w.Code != http.StatusCreated {
+			t.Fatalf("create status = %d, want %d: %s", w.Code, http.StatusCreated, w.Body.String())
+		}
+		var chat chatResponse
+		json.Unmarshal(w.Body.Bytes(), &chat)
+		return chat
+	}
+
+	zendesk := create("Ticket", `{"source":"zendesk","ticket":"T-1"}`)
+	if zendesk.Metadata["source"] != "zendesk" || zendesk.Metadata["ticket"] != "T-1" {
+		t.Errorf("created metadata = %v, want the submitted object", zendesk.Metadata)
+	}
+	create("Email", `{"source":"email"}`)
+	plain := create("Plain", "null")
+	if plain.Metadata == nil || len(plain.Metadata) != 0 {
+		t.Errorf("metadata without any submitted = %v, want an empty object", plain.Metadata)
+	}
+
+	t.Run("rejects non-objects", func(t *testing.T) {
+		if w := serve(http.MethodPost, "/chats", `{"title":"Bad","metadata":["x"]}`); w.Code != http.StatusBadRequest {
+			t.Errorf("status = %d, want %d: %s", w.Code, http.StatusBadRequest, w.Body.String())
+		}
+	})
+
+	list := func(t *testing.T, query string) []string {
+		t.Helper()
+		w := serve(http.MethodGet, "/chats?"+query, "")
+		if w.Code != http.StatusOK {
+			t.Fatalf("list status = %d, want %d: %s", w.Code, http.StatusOK, w.Body.String())
+		}
+		var page struct {
+			Data []chatResponse `json:"data"`
+		}
+		json.Unmarshal(w.Body.Bytes(), &page)
+		titles := make([]string, 0, len(page.Data))
+		for _, chat := range page.Data {
+			titles = append(titles, chat.Title)
+		}
+		return titles
+	}
+
+	t.Run("filters by metadata", func(t *testing.T) {
+		if got := list(t, "metadata_key=source&metadata_value=zendesk"); fmt.Sprint(got) != "[Ticket]" {
+			t.Errorf("chats = %v, want [Ticket]", got)
+		}
+		if got := list(t, "metadata_key=source&metadata_value=slack"); len(got) != 0 {
+			t.Errorf("chats = %v, want none", got)
+		}
+		if got := list(t, ""); len(got) != 3 {
+			t.Errorf("unfiltered chats = %v, want all 3", got)
+		}
+	})
+
+	t.Run("requires key and value together", func(t *testing.T) {
+		if w := serve(http.MethodGet, "/chats?metadata_key=source", ""); w.Code != http.StatusBadRequest {
+			t.Errorf("status = %d, want %d: %s", w.Code, http.StatusBadRequest, w.Body.String())
+		}
+	})
+
+	t.Run("update replaces metadata only when given", func(t *testing.T) {
+		w := serve(http.MethodPut, "/chats/"+zendesk.ID, `{"title":"Renamed"}`)
+		if w.Code != http.StatusOK {
+			t.Fatalf("update status = %d, want %d: %s", w.Code, http.StatusOK, w.Body.String())
+		}
+		if got := list(t, "metadata_key=ticket&metadata_value=T-1"); fmt.Sprint(got) != "[Renamed]" {
+			t.Errorf("chats after a title update = %v, want the metadata kept", got)
+		}
+
+		w = serve(http.MethodPut, "/chats/"+zendesk.ID, `{"title":"Renamed","metadata":{"source":"slack"}}`)
+		if w.Code != http.StatusOK {
+			t.Fatalf("update status = %d, want %d: %s", w.Code, http.StatusOK, w.Body.String())
+		}
+		if got := list(t, "metadata_key=source&metadata_value=slack"); fmt.Sprint(got) != "[Renamed]" {
+			t.Errorf("chats after a metadata update = %v, want [Renamed]", got)
+		}
+	})
+}
+
+func TestGetMessage(t *testing.T) {
+	useTestDatabase(t)
+	gin.SetMode(gin.TestMode)
+	m := models.NewModels()
+	ctx := context.Background()
+	user := createTestUser(t, m)
+
+	r := gin.New()
+	r.GET("/chats/:id/messages/:message_id", func(c *gin.Context) { c.Set("user_id", user.ID) }, ResolveChat(), GetMessage)
+	get := func(chatID int64, messageID string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, fmt.Sprintf("/chats/%d/messages/%s", chatID, messageID), nil))
+		return w
+	}
+
+	// newMessage creates a chat of owner's with one message and returns both
+	newMessage := func(t *testing.T, owner *models.User, content string) (*models.Chat, *models.Message) {
+		t.Helper()
+		chat, err := m.Chats.Create(ctx, owner.ID, "Get message", nil)
+		if err != nil {
+			t.Fatalf("failed to create chat: %v", err)
+		}
+		message, err := m.Chats.AddMessage(ctx, chat.ID, "user", content, nil, nil)
+		if err != nil {
+			t.Fatalf("failed to add message: %v", err)
+		}
+		return chat, message
+	}
+
+	chat, message := newMessage(t, user, "hello")
+
+	t.Run("message of the chat", func(t *testing.T) {
+		w := get(chat.ID, fmt.Sprint(message.ID))
+		if w.Code != http.StatusOK {
+			t.Fatalf("status = %d, want %d: %s", w.Code, http.StatusOK, w.Body.String())
+		}
+		var resp struct {
+			ID      string `json:"id"`
+			Content string `json:"content"`
+		}
+		json.Unmarshal(w.Body.Bytes(), &resp)
+		if resp.ID != fmt.Sprint(message.ID) || resp.Content != "hello" {
+			t.Errorf("message = %+v, want %d hello", resp, message.ID)
+		}
+	})
+
+	t.Run("message of another chat", func(t *testing.T) {
+		_, other := newMessage(t, user, "elsewhere")
+		if w := get(chat.ID, fmt.Sprint(other.ID)); w.Code != http.StatusNotFound {
+			t.Errorf("status = %d, want %d: %s", w.Code, http.StatusNotFound, w.Body.String())
+		}
+	})
+
+	t.Run("another user's chat", func(t *testing.T) {
+		foreignChat, foreign := newMessage(t, createTestUser(t, m), "private")
+		w := get(foreignChat.ID, fmt.Sprint(foreign.ID))
+		if w.Code != http.StatusNotFound {
+			t.Errorf("status = %d, want %d: %s", w.Code, http.StatusNotFound, w.Body.String())
+		}
+		if strings.Contains(w.Body.String(), "private") {
+			t.Errorf("response leaks the foreign message: %s", w.Body.String())
+		}
+	})
+
+	t.Run("malformed message ID", func(t *testing.T) {
+		if w := get(chat.ID, "abc"); w.Code != http.StatusBadRequest {
+			t.Errorf("status = %d, want %d: %s", w.Code, http.StatusBadRequest, w.Body.String())
+		}
+	})
+}
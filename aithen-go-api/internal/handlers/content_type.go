@@ -0,0 +1,54 @@
+package handlers
+
+import (
+	"mime"
+	"net/http"
+	"path/filepath"
+	"strings"
+)
+
+// sniffLen is the number of leading bytes http.DetectContentType looks at
+const sniffLen = 512
+
+// canonicalMimeTypes maps extensions the training service parses to the MIME types it expects
+var canonicalMimeTypes = map[string]string{
+	".pdf":  "application/pdf",
+	".doc":  "application/msword",
+	".docx": "application/vnd.openxmlformats-officedocument.wordprocessingml.document",
+	".xls":  "application/vnd.ms-excel",
+	".xlsx": "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet",
+	".csv":  "text/csv",
+	".json": "application/json",
+	".txt":  "text/plain",
+	".md":   "text/markdown",
+}
+
+// detectMimeType picks the MIME type stored for an uploaded file. Known extensions map to their
+// canonical type; otherwise the client's type is kept unless it is missing or generic, in which
+// case it is sniffed from head, the file's first bytes. Parameters such as charset are dropped.
+func detectMimeType(name, declared string, head []byte) string {
+	if mimeType, ok := canonicalMimeTypes[strings.ToLower(filepath.Ext(name))]; ok {
+		return mimeType
+	}
+	if mimeType := baseMimeType(declared); !isGenericMimeType(mimeType) {
+		return mimeType
+	}
+	return baseMimeType(http.DetectContentType(head))
+}
+
+// baseMimeType returns a MIME type without parameters, lower-cased
+func baseMimeType(mimeType string) string {
+	if mediaType, _, err := mime.ParseMediaType(mimeType); err == nil {
+		return mediaType
+	}
+	return strings.ToLower(strings.TrimSpace(mimeType))
+}
+
+// isGenericMimeType reports whether a declared type says nothing about the content
+func isGenericMimeType(mimeType string) bool {
+	switch mimeType {
+	case "", "application/octet-stream", "binary/octet-stream", "application/binary", "application/unknown":
+		return true
+	}
+	return false
+}
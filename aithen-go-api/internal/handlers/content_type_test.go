@@ -0,0 +1,105 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"net/textproto"
+	"testing"
+
+	"github.com/aithen/go-api/internal/models"
+	"github.com/gin-gonic/gin"
+)
+
+// pdfContent is the start of a PDF document, enough for http.DetectContentType
+const pdfContent = "%PDF-1.4\n1 0 obj\n<< /Type /Catalog >>\nendobj\n"
+
+func TestDetectMimeType(t *testing.T) {
+	tests := []struct {
+		name     string
+		file     string
+		declared string
+		head     string
+		want     string
+	}{
+		{name: "known extension overrides a wrong type", file: "report.pdf", declared: "text/plain", head: pdfContent, want: "application/pdf"},
+		{name: "known extension in upper case", file: "REPORT.PDF", declared: "", head: pdfContent, want: "application/pdf"},
+		{name: "known extension without content", file: "notes.md", declared: "application/octet-stream", want: "text/markdown"},
+		{name: "specific declared type kept", file: "photo.png", declared: "image/png", want: "image/png"},
+		{name: "declared parameters dropped", file: "page.html", declared: "Text/HTML; charset=utf-8", want: "text/html"},
+		{name: "missing type sniffed", file: "report", declared: "", head: pdfContent, want: "application/pdf"},
+		{name: "generic type sniffed", file: "report.bin", declared: "application/octet-stream", head: pdfContent, want: "application/pdf"},
+		{name: "sniffed text drops charset", file: "readme", declared: "", head: "plain words", want: "text/plain"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := detectMimeType(tt.file, tt.declared, []byte(tt.head)); got != tt.want {
+				t.Errorf("detectMimeType(%q, %q) = %q, want %q", tt.file, tt.declared, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestUploadKnowledgeBaseFilesDetectsMimeType(t *testing.T) {
+	useTestDatabase(t)
+	gin.SetMode(gin.TestMode)
+	t.Setenv("UPLOAD_DIR", t.TempDir())
+	m := models.NewModels()
+	ctx := context.Background()
+
+	owner := createTestUser(t, m)
+	org := createTestOrganization(t, m, owner)
+	kb, err := m.KnowledgeBases.Create(ctx, org.ID, "Docs", "", &owner.ID)
+	if err != nil {
+		t.Fatalf("failed to create knowledge base: %v", err)
+	}
+
+	// Both PDFs are sent with a misleading header: one wrong, one missing
+	var body bytes.Buffer
+	mw := multipart.NewWriter(&body)
+	for _, part := range []struct{ name, contentType string }{
+		{name: "report.pdf", contentType: "text/plain"},
+		{name: "scan", contentType: ""},
+	} {
+		header := textproto.MIMEHeader{}
+		header.Set("Content-Disposition", fmt.Sprintf(`form-data; name="files"; filename="%s"`, part.name))
+		if part.contentType != "" {
+			header.Set("Content-Type", part.contentType)
+		}
+		w, err := mw.CreatePart(header)
+		if err != nil {
+			t.Fatalf("failed to create part: %v", err)
+		}
+		w.Write([]byte(pdfContent))
+	}
+	mw.Close()
+
+	r := gin.New()
+	r.POST("/knowledge-bases/:id/files", func(c *gin.Context) { c.Set("user_id", owner.ID) }, UploadKnowledgeBaseFiles)
+	req := httptest.NewRequest(http.MethodPost, fmt.Sprintf("/knowledge-bases/%d/files", kb.ID), &body)
+	req.Header.Set("Content-Type", mw.FormDataContentType())
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("status = %d, want %d: %s", w.Code, http.StatusCreated, w.Body.String())
+	}
+
+	files, err := m.KnowledgeBases.GetFilesByKnowledgeBaseID(ctx, kb.ID)
+	if err != nil {
+		t.Fatalf("GetFilesByKnowledgeBaseID() error = %v", err)
+	}
+	if len(files) != 2 {
+		t.Fatalf("knowledge base has %d files, want 2", len(files))
+	}
+	for _, file := range files {
+		if file.MimeType != "application/pdf" {
+			t.Errorf("%s stored as %q, want application/pdf", file.Name, file.MimeType)
+		}
+		if file.FileSize != int64(len(pdfContent)) {
+			t.Errorf("%s stored with %d bytes, want %d", file.Name, file.FileSize, len(pdfContent))
+		}
+	}
+}
@@ -0,0 +1,39 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/aithen/go-api/internal/breaker"
+	"github.com/aithen/go-api/internal/db"
+	"github.com/gin-gonic/gin"
+)
+
+// Readyz reports whether the server can serve traffic. It fails with 503 only when the
+// database is unreachable; the AI service circuit breaker state is reported for visibility.
+func Readyz(c *gin.Context) {
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 2*time.Second)
+	defer cancel()
+
+	status := http.StatusOK
+	database := "ok"
+	if db.DB == nil || db.DB.Ping(ctx) != nil {
+		status = http.StatusServiceUnavailable
+		database = "unavailable"
+	}
+
+	aiService := GetAIBreaker().Snapshot()
+	ready := "ready"
+	if status != http.StatusOK {
+		ready = "not_ready"
+	} else if aiService.State != breaker.StateClosed {
+		ready = "degraded"
+	}
+
+	c.JSON(status, gin.H{
+		"status":     ready,
+		"database":   database,
+		"ai_service": aiService,
+	})
+}
@@ -0,0 +1,235 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/aithen/go-api/internal/models"
+	"github.com/gin-gonic/gin"
+)
+
+// RequireKBPermission returns middleware that checks the current user holds at least the given
+// permission on the knowledge base in the :id path parameter. The user must be an active member
+// of the :slug organization; org owners and admins bypass per-knowledge-base permissions.
+// Members without an explicit permission get read access.
+func RequireKBPermission(permission string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID, exists := c.Get("user_id")
+		if !exists {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+			return
+		}
+
+		kbID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": "Invalid knowledge base ID"})
+			return
+		}
+
+		m := models.NewModels()
+		ctx := c.Request.Context()
+
+		org, err := requestOrganization(c, m)
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusNotFound, gin.H{"error": "Organization not found"})
+			return
+		}
+
+		kb, err := m.KnowledgeBases.FindByID(ctx, kbID)
+		if err != nil || kb.OrganizationID != org.ID {
+			c.AbortWithStatusJSON(http.StatusNotFound, gin.H{"error": "Knowledge base not found"})
+			return
+		}
+
+		// Non-members cannot tell whether the knowledge base exists (see ownership.go)
+		member, err := m.Organizations.GetMember(ctx, org.ID, userID.(int64))
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusNotFound, gin.H{"error": "Knowledge base not found"})
+			return
+		}
+
+		if !models.KBPermissionAllows(effectiveKBPermission(c, m, member, kbID), permission) {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "Insufficient permissions"})
+			return
+		}
+
+		c.Next()
+	}
+}
+
+// effectiveKBPermission resolves a member's permission on a knowledge base
+func effectiveKBPermission(c *gin.Context, m *models.Models, member *models.OrganizationMember, kbID int64) string {
+	if member.Role == "owner" || member.Role == "admin" {
+		return models.KBPermissionAdmin
+	}
+
+	p, err := m.KBPermissions.Get(c.Request.Context(), kbID, member.UserID)
+	if err != nil {
+		return models.KBPermissionRead
+	}
+	return p.Permission
+}
+
+// KBActions lists what a user may do with a knowledge base, so clients need not mirror the
+// permission rules of the knowledge base routes
+type KBActions struct {
+	CanRead   bool `json:"can_read"`   // View, search, clone and preview
+	CanWrite  bool `json:"can_write"`  // Edit the knowledge base and its files and versions
+	CanTrain  bool `json:"can_train"`  // Train, re-embed and retry failed jobs
+	CanDelete bool `json:"can_delete"` // Delete the knowledge base
+	CanManage bool `json:"can_manage"` // Manage permissions and export
+	CanMove   bool `json:"can_move"`   // Move to another organization (org owners and admins)
+}
+
+// MyKBPermissions is the current user's effective permission on a knowledge base
+type MyKBPermissions struct {
+	Permission string `json:"permission"` // read, write or admin
+	Role       string `json:"role"`       // Organization role
+	KBActions
+}
+
+// resolvePermissions derives the allowed actions from an effective knowledge base permission
+// (see effectiveKBPermission) and the member's organization role
+func resolvePermissions(permission, role string) KBActions {
+	return KBActions{
+		CanRead:   models.KBPermissionAllows(permission, models.KBPermissionRead),
+		CanWrite:  models.KBPermissionAllows(permission, models.KBPermissionWrite),
+		CanTrain:  models.KBPermissionAllows(permission, models.KBPermissionWrite),
+		CanDelete: models.KBPermissionAllows(permission, models.KBPermissionAdmin),
+		CanManage: models.KBPermissionAllows(permission, models.KBPermissionAdmin),
+		CanMove:   role == "owner" || role == "admin",
+	}
+}
+
+// GetMyKBPermissions returns the current user's effective permission on a knowledge base and
+// the actions it allows. The route's read guard has already checked membership.
+func GetMyKBPermissions(c *gin.Context) {
+	kbID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid knowledge base ID"})
+		return
+	}
+
+	m := models.NewModels()
+	ctx := c.Request.Context()
+
+	org, err := requestOrganization(c, m)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Organization not found"})
+		return
+	}
+	member, err := m.Organizations.GetMember(ctx, org.ID, c.GetInt64("user_id"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Knowledge base not found"})
+		return
+	}
+
+	permission := effectiveKBPermission(c, m, member, kbID)
+	c.JSON(http.StatusOK, MyKBPermissions{
+		Permission: permission,
+		Role:       member.Role,
+		KBActions:  resolvePermissions(permission, member.Role),
+	})
+}
+
+// GetKBPermissions lists the explicit permissions on a knowledge base
+func GetKBPermissions(c *gin.Context) {
+	kbID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid knowledge base ID"})
+		return
+	}
+
+	m := models.NewModels()
+
+	permissions, err := m.KBPermissions.ListByKnowledgeBase(c.Request.Context(), kbID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve permissions"})
+		return
+	}
+
+	c.JSON(http.StatusOK, permissions)
+}
+
+// GrantKBPermissionRequest represents request to grant a knowledge base permission
+type GrantKBPermissionRequest struct {
+	Permission string `json:"permission" binding:"required"`
+}
+
+// GrantKBPermission sets a member's permission on a knowledge base
+func GrantKBPermission(c *gin.Context) {
+	kbID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid knowledge base ID"})
+		return
+	}
+
+	targetUserID, err := strconv.ParseInt(c.Param("user_id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+
+	var req GrantKBPermissionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if !models.IsValidKBPermission(req.Permission) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Permission must be one of: read, write, admin"})
+		return
+	}
+
+	m := models.NewModels()
+	ctx := c.Request.Context()
+
+	// Only members of the organization can be granted access
+	org, err := requestOrganization(c, m)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Organization not found"})
+		return
+	}
+
+	if _, err := m.Organizations.GetMember(ctx, org.ID, targetUserID); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User is not a member of this organization"})
+		return
+	}
+
+	permission, err := m.KBPermissions.Grant(ctx, kbID, targetUserID, req.Permission)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to grant permission"})
+		return
+	}
+
+	c.JSON(http.StatusOK, permission)
+}
+
+// RevokeKBPermission removes a member's explicit permission on a knowledge base,
+// returning them to the default read access
+func RevokeKBPermission(c *gin.Context) {
+	kbID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid knowledge base ID"})
+		return
+	}
+
+	targetUserID, err := strconv.ParseInt(c.Param("user_id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+
+	m := models.NewModels()
+
+	if err := m.KBPermissions.Revoke(c.Request.Context(), kbID, targetUserID); err != nil {
+		if err == models.ErrKBPermissionNotFound {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Permission not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to revoke permission"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Permission revoked successfully"})
+}
@@ -0,0 +1,190 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/aithen/go-api/internal/models"
+	"github.com/gin-gonic/gin"
+)
+
+func TestKBPermissionAllows(t *testing.T) {
+	tests := []struct {
+		granted  string
+		required string
+		want     bool
+	}{
+		{granted: models.KBPermissionRead, required: models.KBPermissionRead, want: true},
+		{granted: models.KBPermissionRead, required: models.KBPermissionWrite, want: false},
+		{granted: models.KBPermissionRead, required: models.KBPermissionAdmin, want: false},
+		{granted: models.KBPermissionWrite, required: models.KBPermissionRead, want: true},
+		{granted: models.KBPermissionWrite, required: models.KBPermissionAdmin, want: false},
+		{granted: models.KBPermissionAdmin, required: models.KBPermissionAdmin, want: true},
+		{granted: "", required: models.KBPermissionRead, want: false},
+		{granted: models.KBPermissionAdmin, required: "superuser", want: false},
+	}
+	for _, tt := range tests {
+		if got := models.KBPermissionAllows(tt.granted, tt.required); got != tt.want {
+			t.Errorf("KBPermissionAllows(%q, %q) = %v, want %v", tt.granted, tt.required, got, tt.want)
+		}
+	}
+}
+
+func TestRequireKBPermission(t *testing.T) {
+	useTestDatabase(t)
+	gin.SetMode(gin.TestMode)
+	m := models.NewModels()
+	ctx := context.Background()
+
+	owner := createTestUser(t, m)
+	org := createTestOrganization(t, m, owner)
+	admin := addTestMember(t, m, org, "admin")
+	reader := addTestMember(t, m, org, "member")
+	writer := addTestMember(t, m, org, "member")
+	kbAdmin := addTestMember(t, m, org, "member")
+	outsider := createTestUser(t, m)
+
+	kb, err := m.KnowledgeBases.Create(ctx, org.ID, "Docs", "", &owner.ID)
+	if err != nil {
+		t.Fatalf("failed to create knowledge base: %v", err)
+	}
+	if _, err := m.KBPermissions.Grant(ctx, kb.ID, writer.ID, models.KBPermissionWrite); err != nil {
+		t.Fatalf("failed to grant write: %v", err)
+	}
+	if _, err := m.KBPermissions.Grant(ctx, kb.ID, kbAdmin.ID, models.KBPermissionAdmin); err != nil {
+		t.Fatalf("failed to grant admin: %v", err)
+	}
+
+	ok := func(c *gin.Context) { c.Status(http.StatusOK) }
+	request := func(userID int64, method, action string) int {
+		r := gin.New()
+		r.Use(func(c *gin.Context) { c.Set("user_id", userID) })
+		r.GET("/orgs/:slug/knowledge-bases/:id", RequireKBPermission(models.KBPermissionRead), ok)
+		r.POST("/orgs/:slug/knowledge-bases/:id/train", RequireKBPermission(models.KBPermissionWrite), ok)
+		r.DELETE("/orgs/:slug/knowledge-bases/:id", RequireKBPermission(models.KBPermissionAdmin), ok)
+
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(method, fmt.Sprintf("/orgs/%s/knowledge-bases/%d%s", org.Slug, kb.ID, action), nil))
+		return w.Code
+	}
+
+	tests := []struct {
+		name       string
+		userID     int64
+		wantRead   int
+		wantTrain  int
+		wantDelete int
+	}{
+		{name: "org owner bypasses", userID: owner.ID, wantRead: http.StatusOK, wantTrain: http.StatusOK, wantDelete: http.StatusOK},
+		{name: "org admin bypasses", userID: admin.ID, wantRead: http.StatusOK, wantTrain: http.StatusOK, wantDelete: http.StatusOK},
+		{name: "member defaults to read-only", userID: reader.ID, wantRead: http.StatusOK, wantTrain: http.StatusForbidden, wantDelete: http.StatusForbidden},
+		{name: "member with write", userID: writer.ID, wantRead: http.StatusOK, wantTrain: http.StatusOK, wantDelete: http.StatusForbidden},
+		{name: "member with admin", userID: kbAdmin.ID, wantRead: http.StatusOK, wantTrain: http.StatusOK, wantDelete: http.StatusOK},
+		{name: "non-member", userID: outsider.ID, wantRead: http.StatusNotFound, wantTrain: http.StatusNotFound, wantDelete: http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := request(tt.userID, http.MethodGet, ""); got != tt.wantRead {
+				t.Errorf("read status = %d, want %d", got, tt.wantRead)
+			}
+			if got := request(tt.userID, http.MethodPost, "/train"); got != tt.wantTrain {
+				t.Errorf("train status = %d, want %d", got, tt.wantTrain)
+			}
+			if got := request(tt.userID, http.MethodDelete, ""); got != tt.wantDelete {
+				t.Errorf("delete status = %d, want %d", got, tt.wantDelete)
+			}
+		})
+	}
+
+	t.Run("revoking falls back to read", func(t *testing.T) {
+		if err := m.KBPermissions.Revoke(ctx, kb.ID, writer.ID); err != nil {
+			t.Fatalf("Revoke() error = %v", err)
+		}
+		if got := request(writer.ID, http.MethodPost, "/train"); got != http.StatusForbidden {
+			t.Errorf("train status after revoke = %d, want %d", got, http.StatusForbidden)
+		}
+	})
+}
+
+func TestResolvePermissions(t *testing.T) {
+	tests := []struct {
+		name       string
+		permission string
+		role       string
+		want       KBActions
+	}{
+		{name: "owner", permission: models.KBPermissionAdmin, role: "owner",
+			want: KBActions{CanRead: true, CanWrite: true, CanTrain: true, CanDelete: true, CanManage: true, CanMove: true}},
+		{name: "admin", permission: models.KBPermissionAdmin, role: "admin",
+			want: KBActions{CanRead: true, CanWrite: true, CanTrain: true, CanDelete: true, CanManage: true, CanMove: true}},
+		{name: "member", permission: models.KBPermissionRead, role: "member",
+			want: KBActions{CanRead: true}},
+		{name: "member with write", permission: models.KBPermissionWrite, role: "member",
+			want: KBActions{CanRead: true, CanWrite: true, CanTrain: true}},
+		{name: "member with admin", permission: models.KBPermissionAdmin, role: "member",
+			want: KBActions{CanRead: true, CanWrite: true, CanTrain: true, CanDelete: true, CanManage: true}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := resolvePermissions(tt.permission, tt.role); got != tt.want {
+				t.Errorf("resolvePermissions(%q, %q) = %+v, want %+v", tt.permission, tt.role, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGetMyKBPermissions(t *testing.T) {
+	useTestDatabase(t)
+	gin.SetMode(gin.TestMode)
+	m := models.NewModels()
+	ctx := context.Background()
+
+	owner := createTestUser(t, m)
+	org := createTestOrganization(t, m, owner)
+	admin := addTestMember(t, m, org, "admin")
+	member := addTestMember(t, m, org, "member")
+	kb, err := m.KnowledgeBases.Create(ctx, org.ID, "Docs", "", &owner.ID)
+	if err != nil {
+		t.Fatalf("failed to create knowledge base: %v", err)
+	}
+
+	tests := []struct {
+		name           string
+		userID         int64
+		wantPermission string
+		wantRole       string
+	}{
+		{name: "owner", userID: owner.ID, wantPermission: models.KBPermissionAdmin, wantRole: "owner"},
+		{name: "admin", userID: admin.ID, wantPermission: models.KBPermissionAdmin, wantRole: "admin"},
+		{name: "member", userID: member.ID, wantPermission: models.KBPermissionRead, wantRole: "member"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := gin.New()
+			r.GET("/orgs/:slug/knowledge-bases/:id/permissions/me",
+				func(c *gin.Context) { c.Set("user_id", tt.userID) }, RequireKBPermission(models.KBPermissionRead), GetMyKBPermissions)
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, fmt.Sprintf("/orgs/%s/knowledge-bases/%d/permissions/me", org.Slug, kb.ID), nil))
+			if w.Code != http.StatusOK {
+				t.Fatalf("status = %d, want %d: %s", w.Code, http.StatusOK, w.Body.String())
+			}
+
+			var got MyKBPermissions
+			if err := json.Unmarshal(w.Body.Bytes(), &got); err != nil {
+				t.Fatalf("failed to decode response: %v", err)
+			}
+			want := MyKBPermissions{
+				Permission: tt.wantPermission,
+				Role:       tt.wantRole,
+				KBActions:  resolvePermissions(tt.wantPermission, tt.wantRole),
+			}
+			if got != want {
+				t.Errorf("permissions = %+v, want %+v", got, want)
+			}
+		})
+	}
+}
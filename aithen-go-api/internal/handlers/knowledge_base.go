@@ -1,9 +1,13 @@
 package handlers
 
 import (
+	"bytes"
+	"context"
+	"errors"
 	"fmt"
 	"io"
 	"log"
+	"mime/multipart"
 	"net/http"
 	"os"
 	"path/filepath"
@@ -11,15 +15,18 @@ import (
 	"strings"
 	"time"
 
+	"github.com/aithen/go-api/internal/limits"
 	"github.com/aithen/go-api/internal/models"
+	"github.com/aithen/go-api/internal/pagination"
 	"github.com/aithen/go-api/internal/queue"
+	"github.com/aithen/go-api/internal/uploads"
 	"github.com/gin-gonic/gin"
 )
 
 // GetKnowledgeBases retrieves all knowledge bases for an organization
 func GetKnowledgeBases(c *gin.Context) {
 	// Get organization slug from path parameter
-	orgSlug := c.Param("slug")
+	orgSlug := organizationSlug(c)
 
 	if orgSlug == "" {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Organization slug is required"})
@@ -30,7 +37,7 @@ func GetKnowledgeBases(c *gin.Context) {
 	ctx := c.Request.Context()
 
 	// Find organization by slug
-	org, err := m.Organizations.FindBySlug(ctx, orgSlug)
+	org, err := requestOrganization(c, m)
 	if err != nil {
 		if err == models.ErrOrganizationNotFound {
 			c.JSON(http.StatusNotFound, gin.H{"error": "Organization not found"})
@@ -47,26 +54,155 @@ func GetKnowledgeBases(c *gin.Context) {
 		return
 	}
 
-	// Enrich with file counts and other computed fields
-	type QualityMetrics struct {
-		TotalEmbeddings    int      `json:"total_embeddings"`
-		TotalChunks        int      `json:"total_chunks"`
-		EmbeddingDimension int      `json:"embedding_dimension"`
-		TotalStorageSize   int64    `json:"total_storage_size"`
-		AverageChunkSize   int      `json:"average_chunk_size"`
-		QualityScore       *float64 `json:"quality_score,omitempty"`
+	c.JSON(http.StatusOK, enrichKnowledgeBases(ctx, m, kbs))
+}
+
+const (
+	defaultKnowledgeBaseSearchLimit = 50
+	maxKnowledgeBaseSearchLimit     = 200
+)
+
+// SearchKnowledgeBases finds an organization's knowledge bases by name or description
+// Query params: q (required), limit (default 50, max 200), offset
+func SearchKnowledgeBases(c *gin.Context) {
+	query := strings.TrimSpace(c.Query("q"))
+	if query == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Search query (q) is required"})
+		return
+	}
+
+	limit := defaultKnowledgeBaseSearchLimit
+	if v := c.Query("limit"); v != "" {
+		parsed, err := strconv.Atoi(v)
+		if err != nil || parsed < 1 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid limit"})
+			return
+		}
+		limit = parsed
+	}
+	if limit > maxKnowledgeBaseSearchLimit {
+		limit = maxKnowledgeBaseSearchLimit
+	}
+
+	offset := 0
+	if v := c.Query("offset"); v != "" {
+		parsed, err := strconv.Atoi(v)
+		if err != nil || parsed < 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid offset"})
+			return
+		}
+		offset = parsed
+	}
+
+	m := models.NewModels()
+	ctx := c.Request.Context()
+
+	org, err := requestOrganization(c, m)
+	if err != nil {
+		if err == models.ErrOrganizationNotFound {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Organization not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve organization"})
+		return
+	}
+
+	if !requireOrganizationRole(c, m, org, "owner", "admin", "member", "viewer") {
+		return
 	}
 
-	type KnowledgeBaseResponse struct {
-		*models.KnowledgeBase
-		TotalDatasets  int             `json:"total_datasets"`
-		CurrentVersion string          `json:"current_version"`
-		TotalVersions  int             `json:"total_versions"`
-		LastUpdated    string          `json:"last_updated"`
-		QualityMetrics *QualityMetrics `json:"quality_metrics,omitempty"`
+	kbs, total, err := m.KnowledgeBases.SearchByName(ctx, org.ID, query, limit, offset)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to search knowledge bases"})
+		return
 	}
 
-	response := make([]KnowledgeBaseResponse, len(kbs))
+	c.JSON(http.StatusOK, gin.H{
+		"knowledge_bases": enrichKnowledgeBases(ctx, m, kbs),
+		"total":           total,
+		"limit":           limit,
+		"offset":          offset,
+	})
+}
+
+// GetKnowledgeBaseStats returns aggregate knowledge base counts for an organization's dashboard
+func GetKnowledgeBaseStats(c *gin.Context) {
+	m := models.NewModels()
+	ctx := c.Request.Context()
+
+	org, err := requestOrganization(c, m)
+	if err != nil {
+		if err == models.ErrOrganizationNotFound {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Organization not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve organization"})
+		return
+	}
+
+	if !requireOrganizationRole(c, m, org, "owner", "admin", "member", "viewer") {
+		return
+	}
+
+	stats, err := m.KnowledgeBases.GetOrgKBStats(ctx, org.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve knowledge base stats"})
+		return
+	}
+
+	c.JSON(http.StatusOK, stats)
+}
+
+// KnowledgeBaseQualityMetrics are the quality metrics of a knowledge base's completed latest version
+type KnowledgeBaseQualityMetrics struct {
+	TotalEmbeddings    int      `json:"total_embeddings"`
+	TotalChunks        int      `json:"total_chunks"`
+	EmbeddingDimension int      `json:"embedding_dimension"`
+	TotalStorageSize   int64    `json:"total_storage_size"`
+	AverageChunkSize   int      `json:"average_chunk_size"`
+	QualityScore       *float64 `json:"quality_score,omitempty"`
+}
+
+// KnowledgeBaseListItem is a knowledge base with the computed fields shown in lists
+type KnowledgeBaseListItem struct {
+	*models.KnowledgeBase
+	TotalDatasets  int                          `json:"total_datasets"`
+	CurrentVersion string                       `json:"current_version"`
+	TotalVersions  int                          `json:"total_versions"`
+	LastUpdated    string                       `json:"last_updated"`
+	QualityMetrics *KnowledgeBaseQualityMetrics `json:"quality_metrics,omitempty"`
+}
+
+// MarshalJSON adds the computed fields to the knowledge base's own JSON. Without it the embedded
+// knowledge base's MarshalJSON would be promoted and the computed fields dropped.
+func (item KnowledgeBaseListItem) MarshalJSON() ([]byte, error) {
+	return mergeJSONObjects(item.KnowledgeBase, struct {
+		TotalDatasets  int                          `json:"total_datasets"`
+		CurrentVersion string                       `json:"current_version"`
+		TotalVersions  int                          `json:"total_versions"`
+		LastUpdated    string                       `json:"last_updated"`
+		QualityMetrics *KnowledgeBaseQualityMetrics `json:"quality_metrics,omitempty"`
+	}{item.TotalDatasets, item.CurrentVersion, item.TotalVersions, item.LastUpdated, item.QualityMetrics})
+}
+
+// KnowledgeBaseDetail is a knowledge base as returned by GetKnowledgeBase
+type KnowledgeBaseDetail struct {
+	KnowledgeBaseListItem
+	// NeedsRetraining is true when files were added after the latest completed version was
+	// trained, so search does not cover them yet
+	NeedsRetraining bool `json:"needs_retraining"`
+}
+
+// MarshalJSON adds the detail fields to the list item's JSON
+func (d KnowledgeBaseDetail) MarshalJSON() ([]byte, error) {
+	return mergeJSONObjects(d.KnowledgeBaseListItem, struct {
+		NeedsRetraining bool `json:"needs_retraining"`
+	}{d.NeedsRetraining})
+}
+
+// enrichKnowledgeBases adds file counts, version information and quality metrics to knowledge bases
+func enrichKnowledgeBases(ctx context.Context, m *models.Models, kbs []*models.KnowledgeBase) []KnowledgeBaseListItem {
+	response := make([]KnowledgeBaseListItem, len(kbs))
 	for i, kb := range kbs {
 		fileCount, _ := m.KnowledgeBases.GetFileCount(ctx, kb.ID)
 		versionCount, _ := m.KnowledgeBases.GetVersionCount(ctx, kb.ID)
@@ -74,11 +210,11 @@ func GetKnowledgeBases(c *gin.Context) {
 		// Get latest version with quality metrics
 		latestVersion, err := m.KnowledgeBases.GetLatestVersion(ctx, kb.ID)
 		currentVersion := "v1.0.0" // Default if no versions exist
-		var qualityMetrics *QualityMetrics
+		var qualityMetrics *KnowledgeBaseQualityMetrics
 		if err == nil && latestVersion != nil {
 			currentVersion = latestVersion.VersionString
 			if latestVersion.Status == "completed" {
-				qualityMetrics = &QualityMetrics{
+				qualityMetrics = &KnowledgeBaseQualityMetrics{
 					TotalEmbeddings:    latestVersion.TotalEmbeddings,
 					TotalChunks:        latestVersion.TotalChunks,
 					EmbeddingDimension: latestVersion.EmbeddingDimension,
@@ -89,7 +225,7 @@ func GetKnowledgeBases(c *gin.Context) {
 			}
 		}
 
-		response[i] = KnowledgeBaseResponse{
+		response[i] = KnowledgeBaseListItem{
 			KnowledgeBase:  kb,
 			TotalDatasets:  fileCount,
 			CurrentVersion: currentVersion,
@@ -98,8 +234,7 @@ func GetKnowledgeBases(c *gin.Context) {
 			QualityMetrics: qualityMetrics,
 		}
 	}
-
-	c.JSON(http.StatusOK, response)
+	return response
 }
 
 // GetKnowledgeBase retrieves a knowledge base by ID
@@ -129,57 +264,14 @@ func GetKnowledgeBase(c *gin.Context) {
 		return
 	}
 
-	fileCount, _ := m.KnowledgeBases.GetFileCount(ctx, kb.ID)
-	versionCount, _ := m.KnowledgeBases.GetVersionCount(ctx, kb.ID)
-
-	// Get latest version with quality metrics
-	latestVersion, err := m.KnowledgeBases.GetLatestVersion(ctx, kb.ID)
-	currentVersion := "v1.0.0" // Default if no versions exist
-	var qualityMetrics *struct {
-		TotalEmbeddings    int      `json:"total_embeddings"`
-		TotalChunks        int      `json:"total_chunks"`
-		EmbeddingDimension int      `json:"embedding_dimension"`
-		TotalStorageSize   int64    `json:"total_storage_size"`
-		AverageChunkSize   int      `json:"average_chunk_size"`
-		QualityScore       *float64 `json:"quality_score,omitempty"`
-	}
-	if err == nil && latestVersion != nil {
-		currentVersion = latestVersion.VersionString
-		if latestVersion.Status == "completed" {
-			qualityMetrics = &struct {
-				TotalEmbeddings    int      `json:"total_embeddings"`
-				TotalChunks        int      `json:"total_chunks"`
-				EmbeddingDimension int      `json:"embedding_dimension"`
-				TotalStorageSize   int64    `json:"total_storage_size"`
-				AverageChunkSize   int      `json:"average_chunk_size"`
-				QualityScore       *float64 `json:"quality_score,omitempty"`
-			}{
-				TotalEmbeddings:    latestVersion.TotalEmbeddings,
-				TotalChunks:        latestVersion.TotalChunks,
-				EmbeddingDimension: latestVersion.EmbeddingDimension,
-				TotalStorageSize:   latestVersion.TotalStorageSize,
-				AverageChunkSize:   latestVersion.AverageChunkSize,
-				QualityScore:       latestVersion.QualityScore,
-			}
-		}
-	}
-
-	type KnowledgeBaseResponse struct {
-		*models.KnowledgeBase
-		TotalDatasets  int         `json:"total_datasets"`
-		CurrentVersion string      `json:"current_version"`
-		TotalVersions  int         `json:"total_versions"`
-		LastUpdated    string      `json:"last_updated"`
-		QualityMetrics interface{} `json:"quality_metrics,omitempty"`
+	needsRetraining, err := m.KnowledgeBases.NeedsRetraining(ctx, kb.ID)
+	if err != nil {
+		log.Printf("Warning: Failed to check whether knowledge base %d needs retraining: %v", kb.ID, err)
 	}
 
-	response := KnowledgeBaseResponse{
-		KnowledgeBase:  kb,
-		TotalDatasets:  fileCount,
-		CurrentVersion: currentVersion,
-		TotalVersions:  versionCount,
-		LastUpdated:    kb.UpdatedAt.Format("2006-01-02"),
-		QualityMetrics: qualityMetrics,
+	response := KnowledgeBaseDetail{
+		KnowledgeBaseListItem: enrichKnowledgeBases(ctx, m, []*models.KnowledgeBase{kb})[0],
+		NeedsRetraining:       needsRetraining,
 	}
 
 	c.JSON(http.StatusOK, response)
@@ -194,7 +286,7 @@ type CreateKnowledgeBaseRequest struct {
 // CreateKnowledgeBase creates a new knowledge base
 func CreateKnowledgeBase(c *gin.Context) {
 	// Get organization slug from path parameter
-	orgSlug := c.Param("slug")
+	orgSlug := organizationSlug(c)
 
 	if orgSlug == "" {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Organization slug is required"})
@@ -211,7 +303,7 @@ func CreateKnowledgeBase(c *gin.Context) {
 	ctx := c.Request.Context()
 
 	// Find organization by slug
-	org, err := m.Organizations.FindBySlug(ctx, orgSlug)
+	org, err := requestOrganization(c, m)
 	if err != nil {
 		if err == models.ErrOrganizationNotFound {
 			c.JSON(http.StatusNotFound, gin.H{"error": "Organization not found"})
@@ -221,24 +313,56 @@ func CreateKnowledgeBase(c *gin.Context) {
 		return
 	}
 
+	if !enforcePlanLimit(c, m, org.ID, knowledgeBaseLimit(c, m, org.ID)) {
+		return
+	}
+
 	// Create knowledge base
-	kb, err := m.KnowledgeBases.Create(ctx, org.ID, req.Name, req.Description)
+	kb, err := m.KnowledgeBases.Create(ctx, org.ID, req.Name, req.Description, currentUserID(c))
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create knowledge base"})
 		return
 	}
 
-	c.JSON(http.StatusCreated, kb)
+	grantCreatorKBAdmin(c, m, kb.ID)
+
+	Created(c, knowledgeBaseLocation(org.Slug, kb.ID), kb)
 }
 
-// UpdateKnowledgeBaseRequest represents request to update a knowledge base
+// currentUserID returns the authenticated user's ID, or nil if the request has none
+func currentUserID(c *gin.Context) *int64 {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		return nil
+	}
+	id, ok := userID.(int64)
+	if !ok {
+		return nil
+	}
+	return &id
+}
+
+// grantCreatorKBAdmin gives the current user admin permission on a knowledge base they created
+func grantCreatorKBAdmin(c *gin.Context, m *models.Models, kbID int64) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		return
+	}
+	if _, err := m.KBPermissions.Grant(c.Request.Context(), kbID, userID.(int64), models.KBPermissionAdmin); err != nil {
+		log.Printf("Warning: Failed to grant creator permission on knowledge base %d: %v", kbID, err)
+	}
+}
+
+// UpdateKnowledgeBaseRequest represents request to update a knowledge base; omitted fields are left
+// unchanged. Status is not included: it is managed by training.
 type UpdateKnowledgeBaseRequest struct {
-	Name        string `json:"name"`
-	Description string `json:"description"`
-	Status      string `json:"status"`
+	Name        *string `json:"name"`
+	Description *string `json:"description"`
+	// Store chunk text gzip-compressed; applies to chunks written by later training runs
+	CompressChunks *bool `json:"compress_chunks"`
 }
 
-// UpdateKnowledgeBase updates a knowledge base
+// UpdateKnowledgeBase updates the fields present in the request (PUT and PATCH)
 func UpdateKnowledgeBase(c *gin.Context) {
 	kbID := c.Param("id")
 	if kbID == "" {
@@ -258,6 +382,11 @@ func UpdateKnowledgeBase(c *gin.Context) {
 		return
 	}
 
+	if req.Name != nil && strings.TrimSpace(*req.Name) == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Name cannot be empty"})
+		return
+	}
+
 	m := models.NewModels()
 	ctx := c.Request.Context()
 
@@ -273,7 +402,7 @@ func UpdateKnowledgeBase(c *gin.Context) {
 	}
 
 	// Update knowledge base
-	kb, err := m.KnowledgeBases.Update(ctx, id, req.Name, req.Description, req.Status)
+	kb, err := m.KnowledgeBases.Update(ctx, id, req.Name, req.Description, req.CompressChunks)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update knowledge base"})
 		return
@@ -282,7 +411,8 @@ func UpdateKnowledgeBase(c *gin.Context) {
 	c.JSON(http.StatusOK, kb)
 }
 
-// DeleteKnowledgeBase deletes a knowledge base and all related data
+// DeleteKnowledgeBase deletes a knowledge base and all related data. A knowledge base that is
+// training gets 409.
 func DeleteKnowledgeBase(c *gin.Context) {
 	kbID := c.Param("id")
 	if kbID == "" {
@@ -310,45 +440,14 @@ func DeleteKnowledgeBase(c *gin.Context) {
 		return
 	}
 
-	// Step 1: Get all files before deleting to clean up physical storage
+	// Step 1: Get all files before deleting, since their records go with the knowledge base
 	files, err := m.KnowledgeBases.GetFilesByKnowledgeBaseID(ctx, id)
-	if err == nil {
-		// Delete individual files from storage
-		for _, file := range files {
-			if file.FilePath != "" {
-				// Handle both absolute and relative paths
-				filePath := file.FilePath
-				if !filepath.IsAbs(filePath) {
-					wd, err := os.Getwd()
-					if err == nil {
-						filePath = filepath.Join(wd, filePath)
-					}
-				}
-
-				if err := os.Remove(filePath); err != nil {
-					// Log but don't fail - file might already be deleted
-					log.Printf("Warning: Failed to delete file %s: %v", filePath, err)
-				}
-			}
-		}
-	}
-
-	// Step 2: Delete the entire upload directory for this knowledge base
-	uploadDir := filepath.Join("uploads", "knowledge_bases", fmt.Sprintf("%d", id))
-	if !filepath.IsAbs(uploadDir) {
-		wd, err := os.Getwd()
-		if err == nil {
-			uploadDir = filepath.Join(wd, uploadDir)
-		}
-	}
-
-	// Remove the entire directory and all its contents
-	if err := os.RemoveAll(uploadDir); err != nil {
-		// Log but don't fail - directory might not exist or already be deleted
-		log.Printf("Warning: Failed to delete upload directory %s: %v", uploadDir, err)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve knowledge base files"})
+		return
 	}
 
-	// Step 3: Delete knowledge base from database
+	// Step 2: Delete knowledge base from database
 	// This will CASCADE DELETE:
 	// - knowledge_base_files (via FK constraint)
 	// - knowledge_base_versions (via FK constraint)
@@ -359,10 +458,36 @@ func DeleteKnowledgeBase(c *gin.Context) {
 	// 3. knowledge_base_embeddings -> knowledge_base_file_id FK has ON DELETE CASCADE
 	err = m.KnowledgeBases.Delete(ctx, id)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete knowledge base"})
+		switch err {
+		case models.ErrKnowledgeBaseNotFound:
+			c.JSON(http.StatusNotFound, gin.H{"error": "Knowledge base not found"})
+		case models.ErrKnowledgeBaseAlreadyTraining:
+			c.JSON(http.StatusConflict, gin.H{"error": "Cannot delete a knowledge base while it is training"})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete knowledge base"})
+		}
 		return
 	}
 
+	// Step 3: Clean up physical storage only once the records are gone, so a failed delete
+	// never leaves records pointing at missing files
+	for _, file := range files {
+		removeStoredFile(file.FilePath)
+	}
+
+	// Remove the entire upload directory and all its contents
+	uploadDir := uploads.KnowledgeBaseDir(id)
+	if !filepath.IsAbs(uploadDir) {
+		wd, err := os.Getwd()
+		if err == nil {
+			uploadDir = filepath.Join(wd, uploadDir)
+		}
+	}
+	if err := os.RemoveAll(uploadDir); err != nil {
+		// Log but don't fail - directory might not exist or already be deleted
+		log.Printf("Warning: Failed to delete upload directory %s: %v", uploadDir, err)
+	}
+
 	c.JSON(http.StatusOK, gin.H{"message": "Knowledge base and all related data deleted successfully"})
 }
 
@@ -391,27 +516,59 @@ func GetKnowledgeBaseFiles(c *gin.Context) {
 
 	// Transform to match frontend expectations
 	type FileResponse struct {
-		ID         string `json:"id"`
-		Name       string `json:"name"`
-		Size       int64  `json:"size"`
-		UploadedAt string `json:"uploaded_at"`
-		Status     string `json:"status"`
+		ID             string  `json:"id"`
+		Name           string  `json:"name"`
+		Size           int64   `json:"size"`
+		UploadedAt     string  `json:"uploaded_at"`
+		Status         string  `json:"status"`
+		UploadedBy     *string `json:"uploaded_by"`
+		UploadedByName *string `json:"uploaded_by_name"`
+		ChunkCount     *int    `json:"chunk_count,omitempty"`
+	}
+
+	// Chunk counts come from the most recent completed version (versions are ordered newest first)
+	var chunkCounts map[int64]int
+	if versions, err := m.KnowledgeBases.GetAllVersions(ctx, id); err == nil {
+		for _, version := range versions {
+			if version.Status == "completed" {
+				chunkCounts, err = m.KnowledgeBases.GetFileChunkCounts(ctx, version.ID)
+				if err != nil {
+					log.Printf("Warning: Failed to get chunk counts for version %d: %v", version.ID, err)
+				}
+				break
+			}
+		}
 	}
 
 	response := make([]FileResponse, len(files))
 	for i, file := range files {
+		var uploadedBy *string
+		if file.CreatedBy != nil {
+			s := fmt.Sprintf("%d", *file.CreatedBy)
+			uploadedBy = &s
+		}
 		response[i] = FileResponse{
-			ID:         fmt.Sprintf("%d", file.ID),
-			Name:       file.Name,
-			Size:       file.FileSize,
-			UploadedAt: file.CreatedAt.Format("2006-01-02"),
-			Status:     file.Status,
+			ID:             fmt.Sprintf("%d", file.ID),
+			Name:           file.Name,
+			Size:           file.FileSize,
+			UploadedAt:     file.CreatedAt.Format("2006-01-02"),
+			Status:         file.Status,
+			UploadedBy:     uploadedBy,
+			UploadedByName: file.CreatedByName,
+		}
+		if chunkCounts != nil {
+			// Files added after the version was trained report zero chunks
+			count := chunkCounts[file.ID]
+			response[i].ChunkCount = &count
 		}
 	}
 
 	c.JSON(http.StatusOK, response)
 }
 
+// errUploadBatchTimeout is recorded for the files of an asynchronous upload batch that ran out of time
+var errUploadBatchTimeout = errors.New("upload batch timed out")
+
 // UploadKnowledgeBaseFiles handles file uploads for a knowledge base
 func UploadKnowledgeBaseFiles(c *gin.Context) {
 	kbID := c.Param("id")
@@ -430,7 +587,7 @@ func UploadKnowledgeBaseFiles(c *gin.Context) {
 	ctx := c.Request.Context()
 
 	// Verify knowledge base exists
-	_, err = m.KnowledgeBases.FindByID(ctx, id)
+	kb, err := m.KnowledgeBases.FindByID(ctx, id)
 	if err != nil {
 		if err == models.ErrKnowledgeBaseNotFound {
 			c.JSON(http.StatusNotFound, gin.H{"error": "Knowledge base not found"})
@@ -453,88 +610,223 @@ func UploadKnowledgeBaseFiles(c *gin.Context) {
 		return
 	}
 
+	// The whole upload must fit within the organization's storage limit
+	var uploadSize int64
+	for _, fileHeader := range files {
+		uploadSize += fileHeader.Size
+	}
+	if !enforcePlanLimit(c, m, kb.OrganizationID, storageLimit(c, m, kb.OrganizationID, uploadSize)) {
+		return
+	}
+
+	// The files are rejected together, so none are stored past the file count limit
+	fileCount, err := m.KnowledgeBases.GetFileCount(ctx, id)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to count knowledge base files"})
+		return
+	}
+	if !enforceFileCountLimit(c, m, kb.OrganizationID, int64(fileCount), int64(len(files))) {
+		return
+	}
+
 	// Create uploads directory if it doesn't exist
-	uploadDir := filepath.Join("uploads", "knowledge_bases", fmt.Sprintf("%d", id))
+	uploadDir := uploads.KnowledgeBaseDir(id)
 	err = os.MkdirAll(uploadDir, 0755)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create upload directory"})
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("Failed to create upload directory: %v", err)})
+		return
+	}
+
+	plan, fileLimits, err := uploadLimits(c, m, kb.OrganizationID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check plan limits"})
+		return
+	}
+
+	createdBy := currentUserID(c)
+
+	// Asynchronous mode: accept the files and store them in the background
+	if c.Query("async") == "true" {
+		// Take ownership of the multipart form so its temporary files are not
+		// removed when this request finishes; the background worker cleans up.
+		form := c.Request.MultipartForm
+		c.Request.MultipartForm = nil
+
+		tracker := queue.GetUploadBatchTracker()
+		batch := tracker.CreateBatch(id, len(files))
+
+		go func() {
+			defer form.RemoveAll()
+
+			// Not the request context, which ends with this response, but bounded by the batch timeout
+			bgCtx, cancel := tracker.Context()
+			defer cancel()
+			for _, fileHeader := range files {
+				if bgCtx.Err() != nil {
+					tracker.RecordFailure(batch, fileHeader.Filename, errUploadBatchTimeout)
+					continue
+				}
+				kbFile, err := saveUploadedFile(bgCtx, m, id, uploadDir, fileHeader, createdBy, fileLimits)
+				if err != nil {
+					tracker.RecordFailure(batch, fileHeader.Filename, err)
+					continue
+				}
+				tracker.RecordSuccess(batch, kbFile)
+			}
+			if bgCtx.Err() != nil {
+				log.Printf("Warning: Upload batch %s for knowledge base %d timed out", batch.ID, id)
+				tracker.Fail(batch, errUploadBatchTimeout)
+				return
+			}
+			tracker.Finish(batch)
+		}()
+
+		c.JSON(http.StatusAccepted, gin.H{
+			"message":     fmt.Sprintf("Accepted %d file(s) for upload", len(files)),
+			"batch_id":    batch.ID,
+			"channel":     batch.ChannelID, // WebSocket channel for progress updates
+			"total_files": len(files),
+		})
 		return
 	}
 
 	var uploadedFiles []*models.KnowledgeBaseFile
+	var failures []gin.H
+	var limitErr error
 
 	// Process each file
 	for _, fileHeader := range files {
-		// Open uploaded file
-		file, err := fileHeader.Open()
+		kbFile, err := saveUploadedFile(ctx, m, id, uploadDir, fileHeader, createdBy, fileLimits)
 		if err != nil {
+			log.Printf("Warning: Failed to upload %s to knowledge base %d: %v", fileHeader.Filename, id, err)
+			failures = append(failures, gin.H{"file": fileHeader.Filename, "error": err.Error()})
+			var le *limits.LimitError
+			if limitErr == nil && errors.As(err, &le) {
+				limitErr = err
+			}
 			continue
 		}
-		defer file.Close()
 
-		// Generate unique filename
-		timestamp := time.Now().UnixNano()
-		baseName := filepath.Base(fileHeader.Filename)
-		// Remove all extensions to avoid duplication (e.g., .xlsx.xlsx)
-		baseNameWithoutExt := baseName
-		for {
-			ext := filepath.Ext(baseNameWithoutExt)
-			if ext == "" {
-				break
-			}
-			baseNameWithoutExt = baseNameWithoutExt[:len(baseNameWithoutExt)-len(ext)]
-		}
-		// Get the original extension from the original filename
-		ext := filepath.Ext(fileHeader.Filename)
-		filename := fmt.Sprintf("%d_%s%s", timestamp, sanitizeFilename(baseNameWithoutExt), ext)
-		filePath := filepath.Join(uploadDir, filename)
+		uploadedFiles = append(uploadedFiles, kbFile)
+	}
 
-		// Create destination file
-		dst, err := os.Create(filePath)
-		if err != nil {
-			continue
+	if len(uploadedFiles) == 0 {
+		// A concurrent upload used up the limit after the checks above
+		if limitErr != nil && respondLimitError(c, plan, limitErr) {
+			return
 		}
-		defer dst.Close()
-
-		// Copy file content
-		_, err = io.Copy(dst, file)
-		if err != nil {
-			os.Remove(filePath)
-			continue
+		response := gin.H{"error": "Failed to upload any files"}
+		if len(failures) > 0 {
+			response["error"] = fmt.Sprintf("Failed to upload any files: %s", failures[0]["error"])
+			response["failures"] = failures
 		}
+		c.JSON(http.StatusInternalServerError, response)
+		return
+	}
 
-		// Get file size
-		fileInfo, _ := os.Stat(filePath)
-		fileSize := fileInfo.Size()
+	response := gin.H{
+		"message": fmt.Sprintf("Successfully uploaded %d file(s)", len(uploadedFiles)),
+		"files":   uploadedFiles,
+	}
+	if len(failures) > 0 {
+		response["failures"] = failures
+	}
+	c.JSON(http.StatusCreated, response)
+}
 
-		// Get MIME type
-		mimeType := fileHeader.Header.Get("Content-Type")
-		if mimeType == "" {
-			mimeType = "application/octet-stream"
-		}
+// saveUploadedFile copies an uploaded file into the upload directory and creates its database record
+func saveUploadedFile(ctx context.Context, m *models.Models, kbID int64, uploadDir string, fileHeader *multipart.FileHeader, createdBy *int64, l limits.Limits) (*models.KnowledgeBaseFile, error) {
+	// Open uploaded file
+	file, err := fileHeader.Open()
+	if err != nil {
+		return nil, fmt.Errorf("failed to open uploaded file: %w", err)
+	}
+	defer file.Close()
 
-		// Save file record to database
-		kbFile, err := m.KnowledgeBases.AddFile(ctx, id, fileHeader.Filename, filePath, fileSize, mimeType)
-		if err != nil {
-			os.Remove(filePath)
-			continue
+	return storeKnowledgeBaseFile(ctx, m, kbID, uploadDir, fileHeader.Filename, fileHeader.Header.Get("Content-Type"), file, createdBy, l)
+}
+
+// storeKnowledgeBaseFile writes file content into the upload directory under a unique
+// name and creates its database record attributed to createdBy. The file is removed again if the insert fails.
+func storeKnowledgeBaseFile(ctx context.Context, m *models.Models, kbID int64, uploadDir, originalName, mimeType string, src io.Reader, createdBy *int64, l limits.Limits) (*models.KnowledgeBaseFile, error) {
+	// Generate unique filename
+	timestamp := time.Now().UnixNano()
+	baseName := filepath.Base(originalName)
+	// Remove all extensions to avoid duplication (e.g., .xlsx.xlsx)
+	baseNameWithoutExt := baseName
+	for {
+		ext := filepath.Ext(baseNameWithoutExt)
+		if ext == "" {
+			break
 		}
+		baseNameWithoutExt = baseNameWithoutExt[:len(baseNameWithoutExt)-len(ext)]
+	}
+	// Get the original extension from the original filename
+	ext := filepath.Ext(originalName)
+	filename := fmt.Sprintf("%d_%s%s", timestamp, sanitizeFilename(baseNameWithoutExt), ext)
+	filePath := filepath.Join(uploadDir, filename)
 
-		uploadedFiles = append(uploadedFiles, kbFile)
+	// Create destination file
+	dst, err := os.Create(filePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create destination file: %w", err)
 	}
+	defer dst.Close()
 
-	if len(uploadedFiles) == 0 {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to upload any files"})
+	// Keep the first bytes to detect the MIME type when the declared one is missing or generic
+	head := make([]byte, sniffLen)
+	n, err := io.ReadFull(src, head)
+	if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+		os.Remove(filePath)
+		return nil, fmt.Errorf("failed to read file: %w", err)
+	}
+	head = head[:n]
+	mimeType = detectMimeType(originalName, mimeType, head)
+
+	// Copy file content
+	fileSize, err := io.Copy(dst, io.MultiReader(bytes.NewReader(head), src))
+	if err != nil {
+		os.Remove(filePath)
+		return nil, fmt.Errorf("failed to copy file: %w", err)
+	}
+
+	// Save file record to database
+	kbFile, err := m.KnowledgeBases.AddFile(ctx, kbID, originalName, filePath, fileSize, mimeType, createdBy, l)
+	if err != nil {
+		os.Remove(filePath)
+		return nil, err
+	}
+
+	return kbFile, nil
+}
+
+// GetUploadBatch returns the status of an asynchronous upload batch
+func GetUploadBatch(c *gin.Context) {
+	kbID := c.Param("id")
+	batchID := c.Param("batch_id")
+
+	if kbID == "" || batchID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Knowledge base ID and batch ID are required"})
 		return
 	}
 
-	c.JSON(http.StatusCreated, gin.H{
-		"message": fmt.Sprintf("Successfully uploaded %d file(s)", len(uploadedFiles)),
-		"files":   uploadedFiles,
-	})
+	id, err := strconv.ParseInt(kbID, 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid knowledge base ID"})
+		return
+	}
+
+	batch, ok := queue.GetUploadBatchTracker().GetBatch(batchID)
+	if !ok || batch.KnowledgeBaseID != id {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Upload batch not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, batch)
 }
 
-// DeleteKnowledgeBaseFile deletes a file from a knowledge base
+// DeleteKnowledgeBaseFile deletes a file from a knowledge base. A knowledge base that is
+// training gets 409.
 func DeleteKnowledgeBaseFile(c *gin.Context) {
 	kbID := c.Param("id")
 	fileID := c.Param("file_id")
@@ -544,6 +836,12 @@ func DeleteKnowledgeBaseFile(c *gin.Context) {
 		return
 	}
 
+	kbIDInt, err := strconv.ParseInt(kbID, 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid knowledge base ID"})
+		return
+	}
+
 	fileIDInt, err := strconv.ParseInt(fileID, 10, 64)
 	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid file ID"})
@@ -553,37 +851,116 @@ func DeleteKnowledgeBaseFile(c *gin.Context) {
 	m := models.NewModels()
 	ctx := c.Request.Context()
 
-	// Get file to get file path
-	file, err := m.KnowledgeBases.GetFileByID(ctx, fileIDInt)
+	// Delete the file record first; storage is only cleaned up once the record is gone.
+	// A file from another knowledge base is reported as not found.
+	file, err := m.KnowledgeBases.DeleteFile(ctx, kbIDInt, fileIDInt)
 	if err != nil {
-		if err == models.ErrKnowledgeBaseFileNotFound {
+		switch err {
+		case models.ErrKnowledgeBaseNotFound:
+			c.JSON(http.StatusNotFound, gin.H{"error": "Knowledge base not found"})
+		case models.ErrKnowledgeBaseFileNotFound:
 			c.JSON(http.StatusNotFound, gin.H{"error": "File not found"})
-			return
+		case models.ErrKnowledgeBaseAlreadyTraining:
+			c.JSON(http.StatusConflict, gin.H{"error": "Cannot delete files while the knowledge base is training"})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete file"})
 		}
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve file"})
 		return
 	}
 
-	// Verify file belongs to knowledge base
-	kbIDInt, _ := strconv.ParseInt(kbID, 10, 64)
-	if file.KnowledgeBaseID != kbIDInt {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "File does not belong to this knowledge base"})
+	removeStoredFile(file.FilePath)
+
+	c.JSON(http.StatusOK, gin.H{"message": "File deleted successfully"})
+}
+
+// DeleteAllKnowledgeBaseFiles deletes every file from a knowledge base
+func DeleteAllKnowledgeBaseFiles(c *gin.Context) {
+	kbID := c.Param("id")
+	if kbID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Knowledge base ID is required"})
 		return
 	}
 
-	// Delete file from storage
-	if file.FilePath != "" {
-		os.Remove(file.FilePath)
+	id, err := strconv.ParseInt(kbID, 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid knowledge base ID"})
+		return
 	}
 
-	// Delete file record from database
-	err = m.KnowledgeBases.DeleteFile(ctx, fileIDInt)
+	m := models.NewModels()
+	ctx := c.Request.Context()
+
+	// Delete file records first; storage is only cleaned up once the transaction commits
+	files, err := m.KnowledgeBases.DeleteAllFiles(ctx, id)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete file"})
+		switch err {
+		case models.ErrKnowledgeBaseNotFound:
+			c.JSON(http.StatusNotFound, gin.H{"error": "Knowledge base not found"})
+		case models.ErrKnowledgeBaseAlreadyTraining:
+			c.JSON(http.StatusConflict, gin.H{"error": "Cannot delete files while the knowledge base is training"})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete files"})
+		}
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"message": "File deleted successfully"})
+	for _, file := range files {
+		removeStoredFile(file.FilePath)
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": fmt.Sprintf("Deleted %d file(s)", len(files)),
+		"deleted": len(files),
+	})
+}
+
+// removeStoredFile deletes an uploaded file from storage, resolving relative paths
+// against the working directory. Failures are logged, not returned, since the file
+// might already be gone.
+func removeStoredFile(path string) {
+	if path == "" {
+		return
+	}
+
+	// Handle both absolute and relative paths
+	filePath := path
+	if !filepath.IsAbs(filePath) {
+		wd, err := os.Getwd()
+		if err == nil {
+			filePath = filepath.Join(wd, filePath)
+		}
+	}
+
+	if err := os.Remove(filePath); err != nil && !os.IsNotExist(err) {
+		log.Printf("Warning: Failed to delete file %s: %v", filePath, err)
+	}
+}
+
+// Bounds on client-supplied chunking parameters, in characters
+const (
+	minChunkSize    = 100
+	maxChunkSize    = 8000
+	maxChunkOverlap = 4000
+)
+
+// TrainKnowledgeBaseRequest represents optional chunking parameters for a training run
+type TrainKnowledgeBaseRequest struct {
+	ChunkSize    *int `json:"chunk_size"`
+	ChunkOverlap *int `json:"chunk_overlap"`
+}
+
+// validateChunking checks optional chunk size and overlap against sane bounds
+func validateChunking(chunkSize, chunkOverlap *int) error {
+	if chunkSize != nil && (*chunkSize < minChunkSize || *chunkSize > maxChunkSize) {
+		return fmt.Errorf("chunk_size must be between %d and %d", minChunkSize, maxChunkSize)
+	}
+	if chunkOverlap != nil && (*chunkOverlap < 0 || *chunkOverlap > maxChunkOverlap) {
+		return fmt.Errorf("chunk_overlap must be between 0 and %d", maxChunkOverlap)
+	}
+	if chunkSize != nil && chunkOverlap != nil && *chunkOverlap >= *chunkSize {
+		return errors.New("chunk_overlap must be smaller than chunk_size")
+	}
+	return nil
 }
 
 // TrainKnowledgeBase starts training for a knowledge base and creates a new version
@@ -600,63 +977,253 @@ func TrainKnowledgeBase(c *gin.Context) {
 		return
 	}
 
+	// The body is optional; the training service's chunking defaults apply otherwise
+	var req TrainKnowledgeBaseRequest
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+	if err := validateChunking(req.ChunkSize, req.ChunkOverlap); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
 	m := models.NewModels()
 	ctx := c.Request.Context()
 
-	// Verify knowledge base exists
-	kb, err := m.KnowledgeBases.FindByID(ctx, id)
+	kb, files, err := loadTrainableKnowledgeBase(ctx, m, id)
 	if err != nil {
-		if err == models.ErrKnowledgeBaseNotFound {
+		switch {
+		case errors.Is(err, models.ErrKnowledgeBaseNotFound):
 			c.JSON(http.StatusNotFound, gin.H{"error": "Knowledge base not found"})
-			return
+		case errors.Is(err, errNoTrainingFiles):
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Cannot train knowledge base without files"})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve knowledge base"})
 		}
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve knowledge base"})
 		return
 	}
 
-	// Check if knowledge base is already training
-	if kb.Status == "training" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Knowledge base is already being trained"})
+	// A knowledge base that is already training reuses its run, so it does not count again
+	if kb.Status != "training" && !enforcePlanLimit(c, m, kb.OrganizationID, trainingLimit(c, m, kb.OrganizationID)) {
 		return
 	}
 
-	// Get all files for this knowledge base
-	files, err := m.KnowledgeBases.GetFilesByKnowledgeBaseID(ctx, id)
+	version, channelID, err := startTraining(ctx, m, kb, files, req.ChunkSize, req.ChunkOverlap, nil)
+	if errors.Is(err, models.ErrKnowledgeBaseAlreadyTraining) {
+		// Repeated request (e.g. a double-click): report the run that is already in progress
+		c.JSON(http.StatusOK, gin.H{
+			"message":        "Training already in progress",
+			"version":        version,
+			"knowledge_base": kb,
+			"channel":        channelID,
+		})
+		return
+	}
+	if errors.Is(err, queue.ErrQueueFull) {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "The training system is busy, please try again later"})
+		return
+	}
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve files"})
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("Failed to start training: %v", err)})
 		return
 	}
 
+	// Jobs will be processed automatically by the queue system
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":        "Training started successfully",
+		"version":        version,
+		"knowledge_base": kb,
+		"channel":        channelID, // WebSocket channel for progress updates
+	})
+}
+
+// errNoTrainingFiles is returned by loadTrainableKnowledgeBase for a knowledge base without files
+var errNoTrainingFiles = errors.New("knowledge base has no files to train on")
+
+// loadTrainableKnowledgeBase returns a knowledge base and the files a training run would use.
+// It returns models.ErrKnowledgeBaseNotFound or errNoTrainingFiles if it cannot be trained.
+func loadTrainableKnowledgeBase(ctx context.Context, m *models.Models, kbID int64) (*models.KnowledgeBase, []*models.KnowledgeBaseFile, error) {
+	kb, err := m.KnowledgeBases.FindByID(ctx, kbID)
+	if err != nil {
+		if err == models.ErrKnowledgeBaseNotFound {
+			return nil, nil, err
+		}
+		return nil, nil, fmt.Errorf("failed to retrieve knowledge base: %w", err)
+	}
+
+	files, err := m.KnowledgeBases.GetFilesByKnowledgeBaseID(ctx, kbID)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to retrieve files: %w", err)
+	}
 	if len(files) == 0 {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Cannot train knowledge base without files"})
-		return
+		return nil, nil, errNoTrainingFiles
 	}
 
+	return kb, files, nil
+}
+
+// startTraining creates a new version for a knowledge base and enqueues its training jobs.
+// chunkSize and chunkOverlap override the training service's chunking, and embeddingModel its
+// embedding model, when non-nil.
+// It returns the new version and the WebSocket channel used for progress updates.
+// If a version is already training, that version and its channel are returned with
+// models.ErrKnowledgeBaseAlreadyTraining and no jobs are enqueued.
+func startTraining(ctx context.Context, m *models.Models, kb *models.KnowledgeBase, files []*models.KnowledgeBaseFile, chunkSize, chunkOverlap *int, embeddingModel *string) (*models.KnowledgeBaseVersion, string, error) {
+	kbID := kb.ID
+
 	// Create new version (this also sets KB status to 'training')
-	version, err := m.KnowledgeBases.CreateVersion(ctx, id)
+	version, err := m.KnowledgeBases.CreateVersion(ctx, kbID, chunkSize, chunkOverlap, embeddingModel)
+	if errors.Is(err, models.ErrKnowledgeBaseAlreadyTraining) {
+		return version, trainingChannelID(kbID, version.ID), err
+	}
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("Failed to create version: %v", err)})
-		return
+		return nil, "", fmt.Errorf("failed to create version: %w", err)
 	}
 
 	// Start training using queue system
-	channelID := fmt.Sprintf("training_%s_%s", kbID, fmt.Sprintf("%d", version.ID))
+	channelID := trainingChannelID(kbID, version.ID)
 
 	// Initialize queue and enqueue training jobs
 	trainingQueue := queue.GetTrainingQueue()
 	trainingQueue.SetModels(m)
-	if err := trainingQueue.EnqueueTrainingJob(ctx, id, version.ID, files, channelID); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("Failed to enqueue training: %v", err)})
+	if err := trainingQueue.EnqueueTrainingJob(ctx, version, files, channelID); err != nil {
+		// Nothing was queued, so fail the new version and restore the knowledge base's status
+		now := time.Now()
+		if updateErr := m.KnowledgeBases.UpdateVersionStatus(ctx, version.ID, "failed", &now); updateErr != nil {
+			log.Printf("Warning: Failed to mark version %d as failed: %v", version.ID, updateErr)
+		}
+		if updateErr := m.KnowledgeBases.UpdateStatus(ctx, kbID, kb.Status); updateErr != nil {
+			log.Printf("Warning: Failed to restore status of knowledge base %d: %v", kbID, updateErr)
+		}
+		return nil, "", fmt.Errorf("failed to enqueue training: %w", err)
+	}
+
+	return version, channelID, nil
+}
+
+// trainingChannelID returns the WebSocket channel used for a version's training progress
+func trainingChannelID(kbID, versionID int64) string {
+	return fmt.Sprintf("training_%d_%d", kbID, versionID)
+}
+
+// RetryFailedTrainingJobs re-enqueues only the failed jobs of a version's training run
+func RetryFailedTrainingJobs(c *gin.Context) {
+	kbID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid knowledge base ID"})
 		return
 	}
 
-	// Jobs will be processed automatically by the queue system
+	versionID, err := strconv.ParseInt(c.Param("version_id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid version ID"})
+		return
+	}
+
+	m := models.NewModels()
+	ctx := c.Request.Context()
+
+	version, err := m.KnowledgeBases.GetVersionByID(ctx, versionID)
+	if err != nil || version.KnowledgeBaseID != kbID {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Version not found"})
+		return
+	}
+
+	if version.Status != "training" {
+		c.JSON(http.StatusConflict, gin.H{"error": "Only versions that are still training can be retried"})
+		return
+	}
+
+	trainingQueue := queue.GetTrainingQueue()
+	trainingQueue.SetModels(m)
+
+	channelID := trainingChannelID(kbID, versionID)
+	jobs, err := trainingQueue.RetryFailedJobs(channelID)
+	if err != nil {
+		switch err {
+		case queue.ErrNoFailedJobs:
+			c.JSON(http.StatusNotFound, gin.H{"error": "No failed jobs found for this version"})
+		case queue.ErrJobsInProgress:
+			c.JSON(http.StatusConflict, gin.H{"error": "Training jobs are still in progress"})
+		case queue.ErrQueueFull:
+			c.JSON(http.StatusServiceUnavailable, gin.H{"error": "The training system is busy, please try again later"})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retry training jobs"})
+		}
+		return
+	}
 
 	c.JSON(http.StatusOK, gin.H{
-		"message":        "Training started successfully",
-		"version":        version,
-		"knowledge_base": kb,
-		"channel":        channelID, // WebSocket channel for progress updates
+		"message":      "Retrying failed training jobs",
+		"version":      version,
+		"retried_jobs": len(jobs),
+		"channel":      channelID, // WebSocket channel for progress updates
+	})
+}
+
+// DeleteKnowledgeBaseChunk removes a single embedded chunk from a version and recomputes
+// the version's quality metrics
+func DeleteKnowledgeBaseChunk(c *gin.Context) {
+	kbID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid knowledge base ID"})
+		return
+	}
+
+	versionID, err := strconv.ParseInt(c.Param("version_id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid version ID"})
+		return
+	}
+
+	chunkID, err := strconv.ParseInt(c.Param("chunk_id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid chunk ID"})
+		return
+	}
+
+	m := models.NewModels()
+	ctx := c.Request.Context()
+
+	version, err := m.KnowledgeBases.GetVersionByID(ctx, versionID)
+	if err != nil || version.KnowledgeBaseID != kbID {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Version not found"})
+		return
+	}
+
+	// The training service is still writing this version's embeddings
+	if version.Status == "training" {
+		c.JSON(http.StatusConflict, gin.H{"error": "Cannot delete chunks from a version that is currently training"})
+		return
+	}
+
+	if err := m.KnowledgeBases.DeleteEmbedding(ctx, versionID, chunkID); err != nil {
+		if err == models.ErrKnowledgeBaseEmbeddingNotFound {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Chunk not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete chunk"})
+		return
+	}
+
+	if err := m.KnowledgeBases.UpdateVersionQualityMetrics(ctx, versionID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Chunk deleted but failed to update quality metrics"})
+		return
+	}
+
+	version, err = m.KnowledgeBases.GetVersionByID(ctx, versionID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve version"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Chunk deleted successfully",
+		"version": version,
 	})
 }
 
@@ -698,6 +1265,138 @@ func GetKnowledgeBaseVersions(c *gin.Context) {
 	c.JSON(http.StatusOK, versions)
 }
 
+// GetTrainingHistory returns a knowledge base's training runs in chronological order with
+// their outcome, duration and quality score
+func GetTrainingHistory(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid knowledge base ID"})
+		return
+	}
+
+	m := models.NewModels()
+
+	runs, err := m.KnowledgeBases.GetTrainingHistory(c.Request.Context(), id)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve training history"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"runs": runs})
+}
+
+// GetTrainingLog returns the training log of a version as a downloadable text file
+func GetTrainingLog(c *gin.Context) {
+	kbID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid knowledge base ID"})
+		return
+	}
+
+	versionID, err := strconv.ParseInt(c.Param("version_id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid version ID"})
+		return
+	}
+
+	m := models.NewModels()
+	ctx := c.Request.Context()
+
+	version, err := m.KnowledgeBases.GetVersionByID(ctx, versionID)
+	if err != nil || version.KnowledgeBaseID != kbID {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Version not found"})
+		return
+	}
+
+	trainingLog, err := m.KnowledgeBases.GetVersionLog(ctx, versionID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve training log"})
+		return
+	}
+	if trainingLog == "" {
+		c.JSON(http.StatusNotFound, gin.H{"error": "No training log was recorded for this version"})
+		return
+	}
+
+	filename := fmt.Sprintf("training-%d-%s.log", kbID, version.VersionString)
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+	c.Data(http.StatusOK, "text/plain; charset=utf-8", []byte(trainingLog))
+}
+
+// GetTrainingErrors returns which files failed in a version's training run, why,
+// and which job handled them
+func GetTrainingErrors(c *gin.Context) {
+	kbID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid knowledge base ID"})
+		return
+	}
+
+	versionID, err := strconv.ParseInt(c.Param("version_id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid version ID"})
+		return
+	}
+
+	m := models.NewModels()
+	ctx := c.Request.Context()
+
+	version, err := m.KnowledgeBases.GetVersionByID(ctx, versionID)
+	if err != nil || version.KnowledgeBaseID != kbID {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Version not found"})
+		return
+	}
+
+	trainingErrors, err := m.KnowledgeBases.GetTrainingErrors(ctx, versionID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve training errors"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"version": version,
+		"errors":  trainingErrors,
+	})
+}
+
+// GetVersionQualityBreakdown returns the chunk size distribution, per-file embedding counts
+// and file coverage behind a version's quality score
+func GetVersionQualityBreakdown(c *gin.Context) {
+	kbID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid knowledge base ID"})
+		return
+	}
+
+	versionID, err := strconv.ParseInt(c.Param("version_id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid version ID"})
+		return
+	}
+
+	m := models.NewModels()
+	ctx := c.Request.Context()
+
+	version, err := m.KnowledgeBases.GetVersionByID(ctx, versionID)
+	if err != nil || version.KnowledgeBaseID != kbID {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Version not found"})
+		return
+	}
+
+	breakdown, err := m.KnowledgeBases.GetVersionQualityBreakdown(ctx, versionID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve quality breakdown"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"version":     version,
+		"chunk_sizes": breakdown.ChunkSizes,
+		"coverage":    breakdown.Coverage,
+		"files":       breakdown.Files,
+	})
+}
+
 // DeleteKnowledgeBaseVersion deletes a specific version
 func DeleteKnowledgeBaseVersion(c *gin.Context) {
 	kbID := c.Param("id")
@@ -795,3 +1494,59 @@ func sanitizeFilename(filename string) string {
 	filename = strings.ReplaceAll(filename, " ", "_")
 	return filename
 }
+
+// GetOrganizationFiles lists files across every knowledge base of an organization, newest first.
+// Every active member can read every knowledge base, so membership is the only check.
+// Query params: kb_id, status, q (file name), limit (default 50, max 200), cursor
+func GetOrganizationFiles(c *gin.Context) {
+	var filter models.OrganizationFileFilter
+	if v := c.Query("kb_id"); v != "" {
+		kbID, err := strconv.ParseInt(v, 10, 64)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid kb_id"})
+			return
+		}
+		filter.KnowledgeBaseID = &kbID
+	}
+	filter.Status = c.Query("status")
+	filter.Query = c.Query("q")
+
+	limit, err := pagination.ParseLimit(c.Query("limit"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid limit"})
+		return
+	}
+	cursor, err := pagination.Parse(c.Query("cursor"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid cursor"})
+		return
+	}
+
+	m := models.NewModels()
+	ctx := c.Request.Context()
+
+	org, err := requestOrganization(c, m)
+	if err != nil {
+		if err == models.ErrOrganizationNotFound {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Organization not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve organization"})
+		return
+	}
+
+	if !requireOrganizationRole(c, m, org, "owner", "admin", "member", "viewer") {
+		return
+	}
+
+	// Fetch one extra file to learn whether another page exists
+	files, err := m.KnowledgeBases.ListOrganizationFiles(ctx, org.ID, filter, cursor, limit+1)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to list files"})
+		return
+	}
+
+	c.JSON(http.StatusOK, pagination.NewPage(files, limit, func(file *models.OrganizationFile) pagination.Cursor {
+		return pagination.Cursor{Time: file.CreatedAt, ID: file.ID}
+	}))
+}
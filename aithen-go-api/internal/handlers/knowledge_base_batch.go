@@ -0,0 +1,347 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/aithen/go-api/internal/limits"
+	"github.com/aithen/go-api/internal/models"
+	"github.com/aithen/go-api/internal/queue"
+	"github.com/gin-gonic/gin"
+)
+
+// maxTrainBatchSize caps the number of knowledge bases in one batch training request
+const maxTrainBatchSize = 100
+
+// Outcomes of a knowledge base in a batch training request
+const (
+	batchTrainingStarted         = "started"
+	batchTrainingQueued          = "queued"
+	batchTrainingAlreadyTraining = "already_training"
+	batchTrainingRejected        = "rejected"
+)
+
+// TrainBatchRequest represents request to train several knowledge bases of an organization
+type TrainBatchRequest struct {
+	KnowledgeBaseIDs []string `json:"knowledge_base_ids" binding:"required,min=1"`
+	ChunkSize        *int     `json:"chunk_size"`
+	ChunkOverlap     *int     `json:"chunk_overlap"`
+	EmbeddingModel   *string  `json:"embedding_model"` // Defaults to the training service's model
+}
+
+// TrainBatchResult reports what happened to one knowledge base of a batch training request
+type TrainBatchResult struct {
+	KnowledgeBaseID string                       `json:"knowledge_base_id"`
+	Status          string                       `json:"status"` // started, queued, already_training or rejected
+	Error           string                       `json:"error,omitempty"`
+	Version         *models.KnowledgeBaseVersion `json:"version,omitempty"`
+	Channel         string                       `json:"channel,omitempty"` // WebSocket channel for progress updates
+}
+
+// waitingTraining is a training run held back by the organization's concurrent training limit
+type waitingTraining struct {
+	kbID           int64
+	chunkSize      *int
+	chunkOverlap   *int
+	embeddingModel *string
+}
+
+// trainingWaitlist holds batch training runs per organization until a running one finishes.
+// Like the training queue's job state it is kept in memory, so waiting runs are lost on restart.
+type trainingWaitlist struct {
+	mu      sync.Mutex
+	pending map[int64][]waitingTraining // Keyed by organization ID, oldest first
+}
+
+var (
+	waitlistInstance = &trainingWaitlist{pending: make(map[int64][]waitingTraining)}
+	waitlistOnce     sync.Once
+)
+
+// contains reports whether a knowledge base is waiting to train
+func (w *trainingWaitlist) contains(orgID, kbID int64) bool {
+	for _, waiting := range w.pending[orgID] {
+		if waiting.kbID == kbID {
+			return true
+		}
+	}
+	return false
+}
+
+// startWaitingTrainings starts the organization's waiting runs while its plan allows more
+// concurrent trainings. It is called by the training queue whenever a run finishes.
+func startWaitingTrainings(kbID int64) {
+	w := waitlistInstance
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	m := models.NewModels()
+	ctx := context.Background()
+
+	kb, err := m.KnowledgeBases.FindByID(ctx, kbID)
+	if err != nil {
+		return
+	}
+	orgID := kb.OrganizationID
+	if len(w.pending[orgID]) == 0 {
+		return
+	}
+
+	plan, err := m.Organizations.GetPlan(ctx, orgID)
+	if err != nil {
+		log.Printf("Warning: Failed to get plan of organization %d: %v", orgID, err)
+		return
+	}
+	l := limits.ForPlan(plan)
+
+	for len(w.pending[orgID]) > 0 {
+		running, err := m.KnowledgeBases.CountTrainingByOrganization(ctx, orgID)
+		if err != nil {
+			log.Printf("Warning: Failed to count trainings of organization %d: %v", orgID, err)
+			return
+		}
+		if l.CheckConcurrentTrainings(running) != nil {
+			return
+		}
+
+		next := w.pending[orgID][0]
+		w.pending[orgID] = w.pending[orgID][1:]
+
+		kb, files, err := loadTrainableKnowledgeBase(ctx, m, next.kbID)
+		if err != nil || kb.OrganizationID != orgID {
+			log.Printf("Warning: Skipping waiting training of knowledge base %d: %v", next.kbID, err)
+			continue
+		}
+		if _, _, err := startTraining(ctx, m, kb, files, next.chunkSize, next.chunkOverlap, next.embeddingModel); err != nil {
+			log.Printf("Warning: Failed to start waiting training of knowledge base %d: %v", next.kbID, err)
+		}
+	}
+	delete(w.pending, orgID)
+}
+
+// TrainKnowledgeBasesBatch starts training for several knowledge bases of an organization,
+// e.g. to retrain them after an embedding model change. Each knowledge base is checked on its
+// own and needs write permission. Runs beyond the plan's concurrent training limit wait and
+// start as earlier runs of the organization finish. It returns the outcome per knowledge base.
+func TrainKnowledgeBasesBatch(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	var req TrainBatchRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if len(req.KnowledgeBaseIDs) > maxTrainBatchSize {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Too many knowledge bases, the maximum is " + strconv.Itoa(maxTrainBatchSize)})
+		return
+	}
+	if err := validateChunking(req.ChunkSize, req.ChunkOverlap); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if req.EmbeddingModel != nil {
+		model := strings.TrimSpace(*req.EmbeddingModel)
+		if err := validateEmbeddingModel(model); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		req.EmbeddingModel = &model
+	}
+
+	m := models.NewModels()
+	ctx := c.Request.Context()
+
+	// Non-members cannot tell whether the organization exists (see ownership.go)
+	org, err := requestOrganization(c, m)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Organization not found"})
+		return
+	}
+	member, err := m.Organizations.GetMember(ctx, org.ID, userID.(int64))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Organization not found"})
+		return
+	}
+
+	plan, err := m.Organizations.GetPlan(ctx, org.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check plan limits"})
+		return
+	}
+	l := limits.ForPlan(plan)
+	running, err := m.KnowledgeBases.CountTrainingByOrganization(ctx, org.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check plan limits"})
+		return
+	}
+
+	waitlistOnce.Do(func() {
+		queue.GetTrainingQueue().OnRunFinished(startWaitingTrainings)
+	})
+	w := waitlistInstance
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	results := make([]TrainBatchResult, 0, len(req.KnowledgeBaseIDs))
+	counts := map[string]int{}
+	seen := make(map[int64]bool)
+	for _, rawID := range req.KnowledgeBaseIDs {
+		result := trainBatchItem(c, m, org, member, rawID, &req, l, &running, seen)
+		counts[result.Status]++
+		results = append(results, result)
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"results":          results,
+		"started":          counts[batchTrainingStarted],
+		"queued":           counts[batchTrainingQueued],
+		"already_training": counts[batchTrainingAlreadyTraining],
+		"rejected":         counts[batchTrainingRejected],
+	})
+}
+
+// trainBatchItem validates and starts, or queues, the training of one knowledge base of a batch.
+// running is the organization's number of running trainings and is updated as runs start.
+// The caller must hold the waitlist lock.
+func trainBatchItem(c *gin.Context, m *models.Models, org *models.Organization, member *models.OrganizationMember, rawID string, req *TrainBatchRequest, l limits.Limits, running *int64, seen map[int64]bool) TrainBatchResult {
+	ctx := c.Request.Context()
+	result := TrainBatchResult{KnowledgeBaseID: rawID, Status: batchTrainingRejected}
+
+	kbID, err := strconv.ParseInt(rawID, 10, 64)
+	if err != nil {
+		result.Error = "Invalid knowledge base ID"
+		return result
+	}
+	if seen[kbID] {
+		result.Error = "Duplicate knowledge base ID"
+		return result
+	}
+	seen[kbID] = true
+
+	kb, files, err := loadTrainableKnowledgeBase(ctx, m, kbID)
+	switch {
+	case errors.Is(err, models.ErrKnowledgeBaseNotFound) || (err == nil && kb.OrganizationID != org.ID):
+		result.Error = "Knowledge base not found"
+		return result
+	case errors.Is(err, errNoTrainingFiles):
+		result.Error = "Cannot train knowledge base without files"
+		return result
+	case err != nil:
+		result.Error = "Failed to retrieve knowledge base"
+		return result
+	}
+
+	if !models.KBPermissionAllows(effectiveKBPermission(c, m, member, kbID), models.KBPermissionWrite) {
+		result.Error = "Insufficient permissions"
+		return result
+	}
+
+	if waitlistInstance.contains(org.ID, kbID) {
+		result.Status = batchTrainingQueued
+		return result
+	}
+
+	// Runs beyond the plan's concurrent training limit wait for a running one to finish
+	if kb.Status != "training" && l.CheckConcurrentTrainings(*running) != nil {
+		waitlistInstance.pending[org.ID] = append(waitlistInstance.pending[org.ID], waitingTraining{
+			kbID:           kbID,
+			chunkSize:      req.ChunkSize,
+			chunkOverlap:   req.ChunkOverlap,
+			embeddingModel: req.EmbeddingModel,
+		})
+		result.Status = batchTrainingQueued
+		return result
+	}
+
+	version, channelID, err := startTraining(ctx, m, kb, files, req.ChunkSize, req.ChunkOverlap, req.EmbeddingModel)
+	switch {
+	case errors.Is(err, models.ErrKnowledgeBaseAlreadyTraining):
+		result.Status = batchTrainingAlreadyTraining
+		result.Version = version
+		result.Channel = channelID
+	case errors.Is(err, queue.ErrQueueFull):
+		result.Error = "The training system is busy, please try again later"
+	case err != nil:
+		result.Error = "Failed to start training"
+	default:
+		*running++
+		result.Status = batchTrainingStarted
+		result.Version = version
+		result.Channel = channelID
+	}
+	return result
+}
+
+// dropWaitingTraining drops the organization's runs waiting on the concurrent training limit
+// and returns how many there were
+func dropWaitingTraining(orgID int64) int {
+	w := waitlistInstance
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	waiting := len(w.pending[orgID])
+	delete(w.pending, orgID)
+	return waiting
+}
+
+// CancelAllTraining cancels every pending and processing training job of an organization's
+// knowledge bases, e.g. to stop a runaway batch during an incident. Runs waiting on the
+// concurrent training limit are dropped too. Affected versions are marked cancelled.
+// Only owners and admins may cancel.
+func CancelAllTraining(c *gin.Context) {
+	m := models.NewModels()
+	ctx := c.Request.Context()
+
+	org, err := requestOrganization(c, m)
+	if err != nil {
+		if err == models.ErrOrganizationNotFound {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Organization not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve organization"})
+		return
+	}
+
+	if !requireOrganizationRole(c, m, org, "owner", "admin") {
+		return
+	}
+
+	kbs, err := m.KnowledgeBases.FindByOrganizationID(ctx, org.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve knowledge bases"})
+		return
+	}
+	kbIDs := make([]int64, len(kbs))
+	for i, kb := range kbs {
+		kbIDs[i] = kb.ID
+	}
+
+	// Drop waiting runs first so cancelled runs finishing do not start them
+	waiting := dropWaitingTraining(org.ID)
+
+	trainingQueue := queue.GetTrainingQueue()
+	trainingQueue.SetModels(m)
+	runs := trainingQueue.CancelRuns(kbIDs)
+
+	cancelledJobs := 0
+	versionIDs := make([]string, len(runs))
+	for i, run := range runs {
+		cancelledJobs += run.CancelledJobs
+		versionIDs[i] = strconv.FormatInt(run.VersionID, 10)
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"cancelled_jobs":     cancelledJobs,
+		"cancelled_versions": versionIDs,
+		"dropped_waiting":    waiting,
+	})
+}
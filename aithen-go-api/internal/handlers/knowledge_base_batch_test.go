@@ -0,0 +1,121 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/aithen/go-api/internal/models"
+	"github.com/gin-gonic/gin"
+)
+
+func TestTrainKnowledgeBasesBatch(t *testing.T) {
+	useTestDatabase(t)
+	gin.SetMode(gin.TestMode)
+	m := models.NewModels()
+	ctx := context.Background()
+
+	// The stub training service holds every run open until the test ends, so the started run
+	// keeps the free plan's single training slot
+	release := make(chan struct{})
+	stub := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-release:
+		case <-r.Context().Done():
+		}
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	t.Cleanup(stub.Close)
+	t.Cleanup(func() { close(release) })
+	t.Setenv("TRAINING_SERVICE_URL", stub.URL)
+
+	owner := createTestUser(t, m)
+	org := createTestOrganization(t, m, owner)
+	// Dropped before the held run is released, so no waiting run starts after the test
+	t.Cleanup(func() { dropWaitingTraining(org.ID) })
+
+	newKB := func(t *testing.T, org *models.Organization, name string, withFile bool) *models.KnowledgeBase {
+		t.Helper()
+		kb, err := m.KnowledgeBases.Create(ctx, org.ID, name, "", &owner.ID)
+		if err != nil {
+			t.Fatalf("failed to create knowledge base: %v", err)
+		}
+		if withFile {
+			addTestFile(t, m, kb.ID, "intro.txt", "hello")
+		}
+		return kb
+	}
+	first := newKB(t, org, "First", true)
+	second := newKB(t, org, "Second", true)
+	empty := newKB(t, org, "Empty", false)
+	foreign := newKB(t, createTestOrganization(t, m, owner), "Foreign", true)
+
+	ids := []string{
+		fmt.Sprint(first.ID),
+		fmt.Sprint(second.ID),
+		fmt.Sprint(empty.ID),
+		"not-a-number",
+		fmt.Sprint(first.ID),
+		fmt.Sprint(foreign.ID),
+	}
+	body, _ := json.Marshal(map[string][]string{"knowledge_base_ids": ids})
+
+	r := gin.New()
+	r.POST("/orgs/:slug/knowledge-bases/train-batch", func(c *gin.Context) { c.Set("user_id", owner.ID) }, TrainKnowledgeBasesBatch)
+	req := httptest.NewRequest(http.MethodPost, "/orgs/"+org.Slug+"/knowledge-bases/train-batch", strings.NewReader(string(body)))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d, want %d: %s", w.Code, http.StatusOK, w.Body.String())
+	}
+
+	var resp struct {
+		Results  []TrainBatchResult `json:"results"`
+		Started  int                `json:"started"`
+		Queued   int                `json:"queued"`
+		Rejected int                `json:"rejected"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+
+	want := []struct {
+		status string
+		error  string
+	}{
+		{status: batchTrainingStarted},
+		{status: batchTrainingQueued},
+		{status: batchTrainingRejected, error: "Cannot train knowledge base without files"},
+		{status: batchTrainingRejected, error: "Invalid knowledge base ID"},
+		{status: batchTrainingRejected, error: "Duplicate knowledge base ID"},
+		{status: batchTrainingRejected, error: "Knowledge base not found"},
+	}
+	if len(resp.Results) != len(want) {
+		t.Fatalf("got %d results, want %d: %s", len(resp.Results), len(want), w.Body.String())
+	}
+	for i, result := range resp.Results {
+		if result.KnowledgeBaseID != ids[i] || result.Status != want[i].status || result.Error != want[i].error {
+			t.Errorf("result %d = %s %s %q, want %s %s %q", i,
+				result.KnowledgeBaseID, result.Status, result.Error, ids[i], want[i].status, want[i].error)
+		}
+	}
+	if resp.Started != 1 || resp.Queued != 1 || resp.Rejected != 4 {
+		t.Errorf("counts = %d started, %d queued, %d rejected, want 1, 1 and 4", resp.Started, resp.Queued, resp.Rejected)
+	}
+
+	started, err := m.KnowledgeBases.FindByID(ctx, first.ID)
+	if err != nil {
+		t.Fatalf("FindByID() error = %v", err)
+	}
+	if started.Status != "training" {
+		t.Errorf("started knowledge base status = %q, want training", started.Status)
+	}
+	if count, err := m.KnowledgeBases.GetVersionCount(ctx, second.ID); err != nil || count != 0 {
+		t.Errorf("queued knowledge base has %d versions (err %v), want none until a slot frees", count, err)
+	}
+}
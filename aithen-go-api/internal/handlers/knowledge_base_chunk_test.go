@@ -0,0 +1,131 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aithen/go-api/internal/models"
+	"github.com/gin-gonic/gin"
+)
+
+func TestDeleteKnowledgeBaseChunk(t *testing.T) {
+	useTestDatabase(t)
+	gin.SetMode(gin.TestMode)
+	m := models.NewModels()
+	ctx := context.Background()
+
+	owner := createTestUser(t, m)
+	org := createTestOrganization(t, m, owner)
+	kb, err := m.KnowledgeBases.Create(ctx, org.ID, "Docs", "", &owner.ID)
+	if err != nil {
+		t.Fatalf("failed to create knowledge base: %v", err)
+	}
+	file := addTestFile(t, m, kb.ID, "intro.txt", "hello")
+
+	// newVersion creates a version of kb with the given chunks, completed unless training is set,
+	// and returns it with the IDs of its embeddings by chunk index
+	newVersion := func(t *testing.T, kbID, fileID int64, training bool, chunks ...string) (*models.KnowledgeBaseVersion, []int64) {
+		t.Helper()
+		version, err := m.KnowledgeBases.CreateVersion(ctx, kbID, nil, nil, nil)
+		if err != nil {
+			t.Fatalf("failed to create version: %v", err)
+		}
+		ids := make([]int64, len(chunks))
+		for i, chunk := range chunks {
+			if err := m.KnowledgeBases.StoreEmbedding(ctx, kbID, version.ID, fileID, i, chunk, make([]float32, 1536), nil, false); err != nil {
+				t.Fatalf("failed to store embedding: %v", err)
+			}
+			err := m.KnowledgeBases.DB.QueryRow(ctx,
+				`SELECT id FROM knowledge_base_embeddings WHERE knowledge_base_version_id = $1 AND chunk_index = $2`, version.ID, i,
+			).Scan(&ids[i])
+			if err != nil {
+				t.Fatalf("failed to find embedding: %v", err)
+			}
+		}
+		if err := m.KnowledgeBases.UpdateVersionQualityMetrics(ctx, version.ID); err != nil {
+			t.Fatalf("failed to update metrics: %v", err)
+		}
+		if !training {
+			now := time.Now()
+			if err := m.KnowledgeBases.UpdateVersionStatus(ctx, version.ID, "completed", &now); err != nil {
+				t.Fatalf("failed to complete version: %v", err)
+			}
+		}
+		return version, ids
+	}
+
+	deleteChunk := func(kbID, versionID, chunkID int64) *httptest.ResponseRecorder {
+		r := gin.New()
+		r.DELETE("/knowledge-bases/:id/versions/:version_id/chunks/:chunk_id", DeleteKnowledgeBaseChunk)
+		w := httptest.NewRecorder()
+		path := fmt.Sprintf("/knowledge-bases/%d/versions/%d/chunks/%d", kbID, versionID, chunkID)
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodDelete, path, nil))
+		return w
+	}
+
+	version, ids := newVersion(t, kb.ID, file.ID, false, "aa", "bbbb", strings.Repeat("c", 300))
+	before, err := m.KnowledgeBases.GetVersionByID(ctx, version.ID)
+	if err != nil {
+		t.Fatalf("GetVersionByID() error = %v", err)
+	}
+	if before.TotalEmbeddings != 3 || before.AverageChunkSize != 102 {
+		t.Fatalf("metrics before = %d embeddings averaging %d, want 3 averaging 102", before.TotalEmbeddings, before.AverageChunkSize)
+	}
+
+	t.Run("recomputes metrics", func(t *testing.T) {
+		w := deleteChunk(kb.ID, version.ID, ids[2])
+		if w.Code != http.StatusOK {
+			t.Fatalf("status = %d, want %d: %s", w.Code, http.StatusOK, w.Body.String())
+		}
+		var resp struct {
+			Version struct {
+				TotalEmbeddings  int      `json:"total_embeddings"`
+				TotalChunks      int      `json:"total_chunks"`
+				AverageChunkSize int      `json:"average_chunk_size"`
+				QualityScore     *float64 `json:"quality_score"`
+			} `json:"version"`
+		}
+		if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+			t.Fatalf("failed to decode response: %v", err)
+		}
+		got := resp.Version
+		if got.TotalEmbeddings != 2 || got.TotalChunks != 2 || got.AverageChunkSize != 3 {
+			t.Errorf("metrics after = %d embeddings, %d chunks averaging %d, want 2, 2 averaging 3", got.TotalEmbeddings, got.TotalChunks, got.AverageChunkSize)
+		}
+		if got.QualityScore == nil || before.QualityScore == nil || *got.QualityScore >= *before.QualityScore {
+			t.Errorf("quality score = %v, want it below %v after removing the largest chunk", got.QualityScore, before.QualityScore)
+		}
+	})
+
+	t.Run("already deleted chunk", func(t *testing.T) {
+		if w := deleteChunk(kb.ID, version.ID, ids[2]); w.Code != http.StatusNotFound {
+			t.Errorf("status = %d, want %d: %s", w.Code, http.StatusNotFound, w.Body.String())
+		}
+	})
+
+	t.Run("chunk of another version", func(t *testing.T) {
+		_, otherIDs := newVersion(t, kb.ID, file.ID, false, "other")
+		if w := deleteChunk(kb.ID, version.ID, otherIDs[0]); w.Code != http.StatusNotFound {
+			t.Errorf("status = %d, want %d: %s", w.Code, http.StatusNotFound, w.Body.String())
+		}
+	})
+
+	t.Run("version of another knowledge base", func(t *testing.T) {
+		if w := deleteChunk(kb.ID+1, version.ID, ids[0]); w.Code != http.StatusNotFound {
+			t.Errorf("status = %d, want %d: %s", w.Code, http.StatusNotFound, w.Body.String())
+		}
+	})
+
+	t.Run("version in training", func(t *testing.T) {
+		training, trainingIDs := newVersion(t, kb.ID, file.ID, true, "pending")
+		if w := deleteChunk(kb.ID, training.ID, trainingIDs[0]); w.Code != http.StatusConflict {
+			t.Errorf("status = %d, want %d: %s", w.Code, http.StatusConflict, w.Body.String())
+		}
+	})
+}
@@ -0,0 +1,143 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"strconv"
+
+	"github.com/aithen/go-api/internal/limits"
+	"github.com/aithen/go-api/internal/models"
+	"github.com/aithen/go-api/internal/uploads"
+	"github.com/gin-gonic/gin"
+)
+
+// CloneKnowledgeBaseRequest represents request to clone a knowledge base
+type CloneKnowledgeBaseRequest struct {
+	Name        string  `json:"name"`
+	Description *string `json:"description"`
+}
+
+// CloneKnowledgeBase creates a copy of a knowledge base and its files in the same organization.
+// Versions and embeddings are not copied, so the clone starts untrained.
+func CloneKnowledgeBase(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid knowledge base ID"})
+		return
+	}
+
+	// The body is optional; the clone defaults to the source's name and description
+	var req CloneKnowledgeBaseRequest
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	m := models.NewModels()
+	ctx := c.Request.Context()
+
+	source, err := m.KnowledgeBases.FindByID(ctx, id)
+	if err != nil {
+		if err == models.ErrKnowledgeBaseNotFound {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Knowledge base not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve knowledge base"})
+		return
+	}
+
+	files, err := m.KnowledgeBases.GetFilesByKnowledgeBaseID(ctx, source.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve files"})
+		return
+	}
+
+	// The clone and its copied files must fit within the plan limits
+	var cloneSize int64
+	for _, file := range files {
+		cloneSize += file.FileSize
+	}
+	if !enforcePlanLimit(c, m, source.OrganizationID, knowledgeBaseLimit(c, m, source.OrganizationID)) ||
+		!enforcePlanLimit(c, m, source.OrganizationID, storageLimit(c, m, source.OrganizationID, cloneSize)) {
+		return
+	}
+
+	name := req.Name
+	if name == "" {
+		name = source.Name + " (copy)"
+	}
+	description := source.Description
+	if req.Description != nil {
+		description = *req.Description
+	}
+
+	plan, fileLimits, err := uploadLimits(c, m, source.OrganizationID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check plan limits"})
+		return
+	}
+
+	createdBy := currentUserID(c)
+	kb, err := m.KnowledgeBases.Create(ctx, source.OrganizationID, name, description, createdBy)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create knowledge base"})
+		return
+	}
+	grantCreatorKBAdmin(c, m, kb.ID)
+
+	clonedFiles, err := cloneKnowledgeBaseFiles(ctx, m, kb.ID, files, createdBy, fileLimits)
+	if err != nil {
+		// Roll back the partially cloned knowledge base
+		removeKnowledgeBaseUploads(kb.ID)
+		if delErr := m.KnowledgeBases.Delete(ctx, kb.ID); delErr != nil {
+			log.Printf("Warning: Failed to clean up knowledge base %d after failed clone: %v", kb.ID, delErr)
+		}
+		if respondLimitError(c, plan, err) {
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("Failed to clone files: %v", err)})
+		return
+	}
+
+	Created(c, knowledgeBaseLocation(organizationSlug(c), kb.ID), gin.H{
+		"message":           fmt.Sprintf("Cloned knowledge base with %d file(s)", len(clonedFiles)),
+		"knowledge_base_id": fmt.Sprintf("%d", kb.ID),
+		"knowledge_base":    kb,
+	})
+}
+
+// cloneKnowledgeBaseFiles copies each file's content into the upload directory of kbID
+// and creates its database record
+func cloneKnowledgeBaseFiles(ctx context.Context, m *models.Models, kbID int64, files []*models.KnowledgeBaseFile, createdBy *int64, l limits.Limits) ([]*models.KnowledgeBaseFile, error) {
+	uploadDir := uploads.KnowledgeBaseDir(kbID)
+	if err := os.MkdirAll(uploadDir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create upload directory: %w", err)
+	}
+
+	cloned := make([]*models.KnowledgeBaseFile, 0, len(files))
+	for _, file := range files {
+		kbFile, err := cloneKnowledgeBaseFile(ctx, m, kbID, uploadDir, file, createdBy, l)
+		if err != nil {
+			return nil, err
+		}
+		cloned = append(cloned, kbFile)
+	}
+
+	return cloned, nil
+}
+
+// cloneKnowledgeBaseFile streams a single stored file into the upload directory
+func cloneKnowledgeBaseFile(ctx context.Context, m *models.Models, kbID int64, uploadDir string, file *models.KnowledgeBaseFile, createdBy *int64, l limits.Limits) (*models.KnowledgeBaseFile, error) {
+	src, err := os.Open(file.FilePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open %s: %w", file.Name, err)
+	}
+	defer src.Close()
+
+	return storeKnowledgeBaseFile(ctx, m, kbID, uploadDir, file.Name, file.MimeType, src, createdBy, l)
+}
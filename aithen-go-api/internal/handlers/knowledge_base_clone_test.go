@@ -0,0 +1,192 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aithen/go-api/internal/models"
+	"github.com/aithen/go-api/internal/uploads"
+	"github.com/gin-gonic/gin"
+)
+
+func TestCloneKnowledgeBase(t *testing.T) {
+	useTestDatabase(t)
+	gin.SetMode(gin.TestMode)
+	t.Setenv("UPLOAD_DIR", t.TempDir())
+	m := models.NewModels()
+	ctx := context.Background()
+
+	owner := createTestUser(t, m)
+
+	// newSource creates a trained knowledge base with the given files. Each gets its own
+	// organization, so the clones stay within the free plan's knowledge base limit.
+	newSource := func(t *testing.T, files map[string]string) (*models.KnowledgeBase, []*models.KnowledgeBaseFile) {
+		t.Helper()
+		org := createTestOrganization(t, m, owner)
+		kb, err := m.KnowledgeBases.Create(ctx, org.ID, "Handbook", "Team docs", &owner.ID)
+		if err != nil {
+			t.Fatalf("failed to create knowledge base: %v", err)
+		}
+		var added []*models.KnowledgeBaseFile
+		for name, content := range files {
+			added = append(added, addTestFile(t, m, kb.ID, name, content))
+		}
+		version, err := m.KnowledgeBases.CreateVersion(ctx, kb.ID, nil, nil, nil)
+		if err != nil {
+			t.Fatalf("failed to create version: %v", err)
+		}
+		if err := m.KnowledgeBases.StoreEmbedding(ctx, kb.ID, version.ID, added[0].ID, 0, "chunk", make([]float32, 1536), nil, false); err != nil {
+			t.Fatalf("failed to store embedding: %v", err)
+		}
+		now := time.Now()
+		if err := m.KnowledgeBases.UpdateVersionStatus(ctx, version.ID, "completed", &now); err != nil {
+			t.Fatalf("failed to complete version: %v", err)
+		}
+		return kb, added
+	}
+
+	clone := func(kbID int64, body string) *httptest.ResponseRecorder {
+		r := gin.New()
+		r.POST("/orgs/:slug/knowledge-bases/:id/clone", func(c *gin.Context) { c.Set("user_id", owner.ID) }, CloneKnowledgeBase)
+		req := httptest.NewRequest(http.MethodPost, fmt.Sprintf("/orgs/test-org/knowledge-bases/%d/clone", kbID), strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	t.Run("copies files but not versions", func(t *testing.T) {
+		source, sourceFiles := newSource(t, map[string]string{"intro.txt": "hello", "faq.txt": "questions"})
+
+		w := clone(source.ID, "")
+		if w.Code != http.StatusCreated {
+			t.Fatalf("status = %d, want %d: %s", w.Code, http.StatusCreated, w.Body.String())
+		}
+		var resp struct {
+			KnowledgeBaseID string `json:"knowledge_base_id"`
+		}
+		if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+			t.Fatalf("failed to decode response: %v", err)
+		}
+		cloneID, err := strconv.ParseInt(resp.KnowledgeBaseID, 10, 64)
+		if err != nil || cloneID == source.ID {
+			t.Fatalf("knowledge_base_id = %q, want a new knowledge base", resp.KnowledgeBaseID)
+		}
+
+		cloned, err := m.KnowledgeBases.FindByID(ctx, cloneID)
+		if err != nil {
+			t.Fatalf("FindByID() error = %v", err)
+		}
+		if cloned.Name != "Handbook (copy)" || cloned.Description != "Team docs" || cloned.OrganizationID != source.OrganizationID {
+			t.Errorf("clone = %q %q in org %d, want Handbook (copy) with the source's description in org %d",
+				cloned.Name, cloned.Description, cloned.OrganizationID, source.OrganizationID)
+		}
+
+		files, err := m.KnowledgeBases.GetFilesByKnowledgeBaseID(ctx, cloneID)
+		if err != nil {
+			t.Fatalf("GetFilesByKnowledgeBaseID() error = %v", err)
+		}
+		if len(files) != len(sourceFiles) {
+			t.Fatalf("clone has %d files, want %d", len(files), len(sourceFiles))
+		}
+		want := map[string]string{}
+		for _, file := range sourceFiles {
+			content, _ := os.ReadFile(file.FilePath)
+			want[file.Name] = string(content)
+		}
+		cloneDir := uploads.KnowledgeBaseDir(cloneID)
+		for _, file := range files {
+			if filepath.Dir(file.FilePath) != cloneDir {
+				t.Errorf("%s stored at %s, want it in %s", file.Name, file.FilePath, cloneDir)
+			}
+			content, err := os.ReadFile(file.FilePath)
+			if err != nil {
+				t.Errorf("failed to read cloned %s: %v", file.Name, err)
+				continue
+			}
+			if string(content) != want[file.Name] {
+				t.Errorf("cloned %s = %q, want %q", file.Name, content, want[file.Name])
+			}
+		}
+
+		if count, err := m.KnowledgeBases.GetVersionCount(ctx, cloneID); err != nil || count != 0 {
+			t.Errorf("clone has %d versions (err %v), want none", count, err)
+		}
+		if count, err := m.KnowledgeBases.GetVersionCount(ctx, source.ID); err != nil || count != 1 {
+			t.Errorf("source has %d versions (err %v), want its 1 version kept", count, err)
+		}
+	})
+
+	t.Run("request overrides the name", func(t *testing.T) {
+		source, _ := newSource(t, map[string]string{"intro.txt": "hello"})
+
+		w := clone(source.ID, `{"name":"Variant","description":"Experiment"}`)
+		if w.Code != http.StatusCreated {
+			t.Fatalf("status = %d, want %d: %s", w.Code, http.StatusCreated, w.Body.String())
+		}
+		var resp struct {
+			KnowledgeBase struct {
+				Name        string `json:"name"`
+				Description string `json:"description"`
+			} `json:"knowledge_base"`
+		}
+		json.Unmarshal(w.Body.Bytes(), &resp)
+		if resp.KnowledgeBase.Name != "Variant" || resp.KnowledgeBase.Description != "Experiment" {
+			t.Errorf("clone = %+v, want the requested name and description", resp.KnowledgeBase)
+		}
+	})
+
+	t.Run("failed copy removes the clone", func(t *testing.T) {
+		source, sourceFiles := newSource(t, map[string]string{"intro.txt": "hello", "gone.txt": "bye"})
+		for _, file := range sourceFiles {
+			if file.Name == "gone.txt" {
+				os.Remove(file.FilePath)
+			}
+		}
+		before, err := m.KnowledgeBases.FindByOrganizationID(ctx, source.OrganizationID)
+		if err != nil {
+			t.Fatalf("FindByOrganizationID() error = %v", err)
+		}
+		dirsBefore := uploadDirs(t)
+
+		w := clone(source.ID, "")
+		if w.Code != http.StatusInternalServerError {
+			t.Fatalf("status = %d, want %d: %s", w.Code, http.StatusInternalServerError, w.Body.String())
+		}
+
+		after, err := m.KnowledgeBases.FindByOrganizationID(ctx, source.OrganizationID)
+		if err != nil {
+			t.Fatalf("FindByOrganizationID() error = %v", err)
+		}
+		if len(after) != len(before) {
+			t.Errorf("organization has %d knowledge bases, want %d after the failed clone", len(after), len(before))
+		}
+		if dirs := uploadDirs(t); len(dirs) != len(dirsBefore) {
+			t.Errorf("upload directories = %v, want %v after the failed clone", dirs, dirsBefore)
+		}
+	})
+}
+
+// uploadDirs returns the knowledge base upload directories under UPLOAD_DIR
+func uploadDirs(t *testing.T) []string {
+	t.Helper()
+
+	entries, err := os.ReadDir(filepath.Join(uploads.BaseDir(), "knowledge_bases"))
+	if err != nil && !os.IsNotExist(err) {
+		t.Fatalf("failed to list upload directories: %v", err)
+	}
+	dirs := make([]string, 0, len(entries))
+	for _, entry := range entries {
+		dirs = append(dirs, entry.Name())
+	}
+	return dirs
+}
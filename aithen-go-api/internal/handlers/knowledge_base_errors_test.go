@@ -0,0 +1,81 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/aithen/go-api/internal/models"
+	"github.com/gin-gonic/gin"
+)
+
+func TestGetTrainingErrors(t *testing.T) {
+	useTestDatabase(t)
+	gin.SetMode(gin.TestMode)
+	m := models.NewModels()
+	ctx := context.Background()
+
+	owner := createTestUser(t, m)
+	org := createTestOrganization(t, m, owner)
+	kb, err := m.KnowledgeBases.Create(ctx, org.ID, "Docs", "", &owner.ID)
+	if err != nil {
+		t.Fatalf("failed to create knowledge base: %v", err)
+	}
+	addTestFile(t, m, kb.ID, "good.txt", "hello")
+	bad := addTestFile(t, m, kb.ID, "bad.txt", "bye")
+	version, err := m.KnowledgeBases.CreateVersion(ctx, kb.ID, nil, nil, nil)
+	if err != nil {
+		t.Fatalf("failed to create version: %v", err)
+	}
+	if err := m.KnowledgeBases.RecordTrainingError(ctx, version.ID, bad.ID, bad.Name, "training_job_1", 1, "unsupported encoding"); err != nil {
+		t.Fatalf("failed to record training error: %v", err)
+	}
+
+	getErrors := func(kbID, versionID int64) *httptest.ResponseRecorder {
+		r := gin.New()
+		r.GET("/knowledge-bases/:id/versions/:version_id/errors", GetTrainingErrors)
+		w := httptest.NewRecorder()
+		path := fmt.Sprintf("/knowledge-bases/%d/versions/%d/errors", kbID, versionID)
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+		return w
+	}
+
+	t.Run("lists the failed file", func(t *testing.T) {
+		w := getErrors(kb.ID, version.ID)
+		if w.Code != http.StatusOK {
+			t.Fatalf("status = %d, want %d: %s", w.Code, http.StatusOK, w.Body.String())
+		}
+		var resp struct {
+			Errors []struct {
+				FileID       string `json:"file_id"`
+				FileName     string `json:"file_name"`
+				JobID        string `json:"job_id"`
+				JobIndex     int    `json:"job_index"`
+				ErrorMessage string `json:"error_message"`
+			} `json:"errors"`
+		}
+		if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+			t.Fatalf("failed to decode response: %v", err)
+		}
+		if len(resp.Errors) != 1 {
+			t.Fatalf("errors = %+v, want only bad.txt", resp.Errors)
+		}
+		got := resp.Errors[0]
+		if got.FileID != fmt.Sprint(bad.ID) || got.FileName != "bad.txt" || got.JobID != "training_job_1" || got.JobIndex != 1 || got.ErrorMessage != "unsupported encoding" {
+			t.Errorf("error = %+v, want bad.txt failing in training_job_1 with unsupported encoding", got)
+		}
+	})
+
+	t.Run("version of another knowledge base", func(t *testing.T) {
+		other, err := m.KnowledgeBases.Create(ctx, org.ID, "Other", "", &owner.ID)
+		if err != nil {
+			t.Fatalf("failed to create knowledge base: %v", err)
+		}
+		if w := getErrors(other.ID, version.ID); w.Code != http.StatusNotFound {
+			t.Errorf("status = %d, want %d", w.Code, http.StatusNotFound)
+		}
+	})
+}
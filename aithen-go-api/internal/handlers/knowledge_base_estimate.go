@@ -0,0 +1,107 @@
+package handlers
+
+import (
+	"math"
+	"net/http"
+	"strconv"
+
+	"github.com/aithen/go-api/internal/models"
+	"github.com/gin-gonic/gin"
+)
+
+// The training service's chunking defaults (CHUNK_SIZE and CHUNK_OVERLAP), used when the
+// estimate request does not give its own
+const (
+	estimateDefaultChunkSize    = 1000
+	estimateDefaultChunkOverlap = 200
+)
+
+// estimateThroughputSample is the number of recent completed versions used to estimate duration
+const estimateThroughputSample = 50
+
+// TrainingEstimate is a rough forecast of a training run over a knowledge base's current files
+type TrainingEstimate struct {
+	FileCount                int64                   `json:"file_count"`
+	TotalFileSize            int64                   `json:"total_file_size"`
+	ChunkSize                int                     `json:"chunk_size"`
+	ChunkOverlap             int                     `json:"chunk_overlap"`
+	EstimatedChunks          int64                   `json:"estimated_chunks"`
+	EstimatedEmbeddings      int64                   `json:"estimated_embeddings"`
+	EstimatedDurationSeconds *float64                `json:"estimated_duration_seconds"` // nil without training history
+	Throughput               *models.ThroughputStats `json:"throughput"`
+}
+
+// GetTrainingEstimate estimates the chunks, embeddings and duration of training a knowledge base.
+// File sizes stand in for their text length, so binary formats such as PDF are overestimated.
+// The duration uses the throughput of recent completed versions.
+// Query params: chunk_size and chunk_overlap (default to the training service defaults)
+func GetTrainingEstimate(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid knowledge base ID"})
+		return
+	}
+
+	chunkSize, chunkOverlap := estimateDefaultChunkSize, estimateDefaultChunkOverlap
+	if v := c.Query("chunk_size"); v != "" {
+		if chunkSize, err = strconv.Atoi(v); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid chunk_size"})
+			return
+		}
+	}
+	if v := c.Query("chunk_overlap"); v != "" {
+		if chunkOverlap, err = strconv.Atoi(v); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid chunk_overlap"})
+			return
+		}
+	}
+	if err := validateChunking(&chunkSize, &chunkOverlap); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	m := models.NewModels()
+	ctx := c.Request.Context()
+
+	files, err := m.KnowledgeBases.GetFilesByKnowledgeBaseID(ctx, id)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve files"})
+		return
+	}
+
+	stats, err := m.KnowledgeBases.GetThroughputStats(ctx, estimateThroughputSample)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve training history"})
+		return
+	}
+
+	estimate := TrainingEstimate{
+		FileCount:    int64(len(files)),
+		ChunkSize:    chunkSize,
+		ChunkOverlap: chunkOverlap,
+		Throughput:   stats,
+	}
+	for _, file := range files {
+		estimate.TotalFileSize += file.FileSize
+		estimate.EstimatedChunks += estimateChunks(file.FileSize, chunkSize, chunkOverlap)
+	}
+	// The training service stores one embedding per chunk
+	estimate.EstimatedEmbeddings = estimate.EstimatedChunks
+
+	if rate := stats.BytesPerSecond(); rate > 0 {
+		seconds := math.Round(float64(estimate.EstimatedChunks*int64(chunkSize))/rate*10) / 10
+		estimate.EstimatedDurationSeconds = &seconds
+	}
+
+	c.JSON(http.StatusOK, estimate)
+}
+
+// estimateChunks returns the number of chunks the training service splits size characters into:
+// a chunk starts every chunkSize-chunkOverlap characters until the end of the text
+func estimateChunks(size int64, chunkSize, chunkOverlap int) int64 {
+	if size <= 0 {
+		return 0
+	}
+	stride := int64(chunkSize - chunkOverlap)
+	return (size + stride - 1) / stride
+}
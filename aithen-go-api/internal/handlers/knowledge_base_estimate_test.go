@@ -0,0 +1,110 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aithen/go-api/internal/models"
+	"github.com/gin-gonic/gin"
+)
+
+func TestEstimateChunks(t *testing.T) {
+	tests := []struct {
+		size                    int64
+		chunkSize, chunkOverlap int
+		want                    int64
+	}{
+		{size: 0, chunkSize: 1000, chunkOverlap: 200, want: 0},
+		{size: 1, chunkSize: 1000, chunkOverlap: 200, want: 1},
+		{size: 800, chunkSize: 1000, chunkOverlap: 200, want: 1},
+		{size: 801, chunkSize: 1000, chunkOverlap: 200, want: 2},
+		{size: 10000, chunkSize: 1000, chunkOverlap: 200, want: 13},
+		{size: 10000, chunkSize: 500, chunkOverlap: 0, want: 20},
+	}
+	for _, tt := range tests {
+		if got := estimateChunks(tt.size, tt.chunkSize, tt.chunkOverlap); got != tt.want {
+			t.Errorf("estimateChunks(%d, %d, %d) = %d, want %d", tt.size, tt.chunkSize, tt.chunkOverlap, got, tt.want)
+		}
+	}
+}
+
+func TestGetTrainingEstimate(t *testing.T) {
+	useTestDatabase(t)
+	gin.SetMode(gin.TestMode)
+	m := models.NewModels()
+	ctx := context.Background()
+
+	owner := createTestUser(t, m)
+	org := createTestOrganization(t, m, owner)
+	kb, err := m.KnowledgeBases.Create(ctx, org.ID, "Docs", "", &owner.ID)
+	if err != nil {
+		t.Fatalf("failed to create knowledge base: %v", err)
+	}
+	addTestFile(t, m, kb.ID, "big.txt", strings.Repeat("x", 10000))
+
+	// A past run of 10 chunks of 1000 characters in 10 seconds gives the throughput history
+	history, err := m.KnowledgeBases.CreateVersion(ctx, kb.ID, nil, nil, nil)
+	if err != nil {
+		t.Fatalf("failed to create version: %v", err)
+	}
+	now := time.Now()
+	_, err = m.KnowledgeBases.DB.Exec(ctx, `
+		UPDATE knowledge_base_versions
+		SET status = 'completed', total_chunks = 10, average_chunk_size = 1000,
+		    training_started_at = $1, training_completed_at = $2
+		WHERE id = $3
+	`, now.Add(-10*time.Second), now, history.ID)
+	if err != nil {
+		t.Fatalf("failed to seed training history: %v", err)
+	}
+
+	estimate := func(query string) *httptest.ResponseRecorder {
+		r := gin.New()
+		r.GET("/knowledge-bases/:id/train-estimate", GetTrainingEstimate)
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, fmt.Sprintf("/knowledge-bases/%d/train-estimate%s", kb.ID, query), nil))
+		return w
+	}
+
+	w := estimate("")
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d, want %d: %s", w.Code, http.StatusOK, w.Body.String())
+	}
+	var got TrainingEstimate
+	if err := json.Unmarshal(w.Body.Bytes(), &got); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if got.FileCount != 1 || got.TotalFileSize != 10000 {
+		t.Errorf("files = %d totalling %d bytes, want 1 of 10000", got.FileCount, got.TotalFileSize)
+	}
+	if got.EstimatedChunks != 13 || got.EstimatedEmbeddings != 13 {
+		t.Errorf("estimated %d chunks and %d embeddings, want 13 of each", got.EstimatedChunks, got.EstimatedEmbeddings)
+	}
+	if got.Throughput == nil || got.Throughput.Versions < 1 {
+		t.Errorf("throughput = %+v, want it based on the seeded version", got.Throughput)
+	}
+	if got.EstimatedDurationSeconds == nil || *got.EstimatedDurationSeconds <= 0 {
+		t.Errorf("estimated duration = %v, want a positive estimate from the training history", got.EstimatedDurationSeconds)
+	}
+
+	t.Run("custom chunking", func(t *testing.T) {
+		w := estimate("?chunk_size=500&chunk_overlap=0")
+		var got TrainingEstimate
+		json.Unmarshal(w.Body.Bytes(), &got)
+		if w.Code != http.StatusOK || got.EstimatedChunks != 20 {
+			t.Errorf("status %d with %d chunks, want 200 with 20", w.Code, got.EstimatedChunks)
+		}
+	})
+
+	t.Run("invalid chunking", func(t *testing.T) {
+		if w := estimate("?chunk_size=500&chunk_overlap=600"); w.Code != http.StatusBadRequest {
+			t.Errorf("status = %d, want %d: %s", w.Code, http.StatusBadRequest, w.Body.String())
+		}
+	})
+}
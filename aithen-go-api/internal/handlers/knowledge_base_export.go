@@ -0,0 +1,205 @@
+package handlers
+
+import (
+	"archive/zip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+
+	"github.com/aithen/go-api/internal/models"
+	"github.com/gin-gonic/gin"
+)
+
+// manifestSchemaVersion is the version of the export manifest format
+const manifestSchemaVersion = 1
+
+// manifestFileName is the name of the manifest entry inside an export archive
+const manifestFileName = "manifest.json"
+
+// KnowledgeBaseManifest describes the contents of a knowledge base export archive
+type KnowledgeBaseManifest struct {
+	SchemaVersion int                            `json:"schema_version"`
+	ExportedAt    time.Time                      `json:"exported_at"`
+	KnowledgeBase ManifestKnowledgeBase          `json:"knowledge_base"`
+	ActiveVersion *ManifestQualityMetrics        `json:"active_version,omitempty"`
+	Versions      []*models.KnowledgeBaseVersion `json:"versions"`
+	Files         []ManifestFile                 `json:"files"`
+}
+
+// ManifestKnowledgeBase holds the exported knowledge base fields
+type ManifestKnowledgeBase struct {
+	ID          string `json:"id"`
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	Status      string `json:"status"`
+}
+
+// ManifestQualityMetrics holds the quality metrics of the active version
+type ManifestQualityMetrics struct {
+	VersionString      string   `json:"version_string"`
+	TotalEmbeddings    int      `json:"total_embeddings"`
+	TotalChunks        int      `json:"total_chunks"`
+	EmbeddingDimension int      `json:"embedding_dimension"`
+	TotalStorageSize   int64    `json:"total_storage_size"`
+	AverageChunkSize   int      `json:"average_chunk_size"`
+	QualityScore       *float64 `json:"quality_score,omitempty"`
+}
+
+// ManifestFile describes a single file in the export archive
+type ManifestFile struct {
+	ID          string `json:"id"`
+	Name        string `json:"name"`
+	ArchivePath string `json:"archive_path,omitempty"` // Empty when the file was missing on disk
+	FileSize    int64  `json:"file_size"`
+	MimeType    string `json:"mime_type"`
+	Missing     bool   `json:"missing,omitempty"`
+	Error       string `json:"error,omitempty"`
+}
+
+// ExportKnowledgeBase streams a zip archive with the knowledge base files and a manifest
+func ExportKnowledgeBase(c *gin.Context) {
+	orgSlug := organizationSlug(c)
+	kbID := c.Param("id")
+	if orgSlug == "" || kbID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Organization slug and knowledge base ID are required"})
+		return
+	}
+
+	id, err := strconv.ParseInt(kbID, 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid knowledge base ID"})
+		return
+	}
+
+	m := models.NewModels()
+	ctx := c.Request.Context()
+
+	org, err := requestOrganization(c, m)
+	if err != nil {
+		if err == models.ErrOrganizationNotFound {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Organization not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve organization"})
+		return
+	}
+
+	// Only owners and admins may export
+	if !requireOrganizationRole(c, m, org, "owner", "admin") {
+		return
+	}
+
+	kb, err := m.KnowledgeBases.FindByID(ctx, id)
+	if err != nil || kb.OrganizationID != org.ID {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Knowledge base not found"})
+		return
+	}
+
+	files, err := m.KnowledgeBases.GetFilesByKnowledgeBaseID(ctx, id)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve files"})
+		return
+	}
+
+	versions, err := m.KnowledgeBases.GetAllVersions(ctx, id)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve versions"})
+		return
+	}
+
+	manifest := KnowledgeBaseManifest{
+		SchemaVersion: manifestSchemaVersion,
+		ExportedAt:    time.Now().UTC(),
+		KnowledgeBase: ManifestKnowledgeBase{
+			ID:          fmt.Sprintf("%d", kb.ID),
+			Name:        kb.Name,
+			Description: kb.Description,
+			Status:      kb.Status,
+		},
+		Versions: versions,
+		Files:    make([]ManifestFile, 0, len(files)),
+	}
+	if manifest.Versions == nil {
+		manifest.Versions = []*models.KnowledgeBaseVersion{}
+	}
+
+	// Active version is the most recent completed version (versions are ordered newest first)
+	for _, version := range versions {
+		if version.Status == "completed" {
+			manifest.ActiveVersion = &ManifestQualityMetrics{
+				VersionString:      version.VersionString,
+				TotalEmbeddings:    version.TotalEmbeddings,
+				TotalChunks:        version.TotalChunks,
+				EmbeddingDimension: version.EmbeddingDimension,
+				TotalStorageSize:   version.TotalStorageSize,
+				AverageChunkSize:   version.AverageChunkSize,
+				QualityScore:       version.QualityScore,
+			}
+			break
+		}
+	}
+
+	// Stream the archive directly to the response
+	archiveName := fmt.Sprintf("%s-%d.zip", sanitizeFilename(kb.Name), kb.ID)
+	c.Header("Content-Type", "application/zip")
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", archiveName))
+	c.Status(http.StatusOK)
+
+	zw := zip.NewWriter(c.Writer)
+	defer zw.Close()
+
+	for _, file := range files {
+		entry := ManifestFile{
+			ID:       fmt.Sprintf("%d", file.ID),
+			Name:     file.Name,
+			FileSize: file.FileSize,
+			MimeType: file.MimeType,
+		}
+
+		archivePath := fmt.Sprintf("files/%d_%s", file.ID, sanitizeFilename(filepath.Base(file.Name)))
+		if err := writeFileToArchive(zw, archivePath, file.FilePath); err != nil {
+			// Note missing files in the manifest rather than failing the export
+			log.Printf("Warning: Failed to export file %d (%s): %v", file.ID, file.FilePath, err)
+			entry.Missing = true
+			entry.Error = err.Error()
+		} else {
+			entry.ArchivePath = archivePath
+		}
+
+		manifest.Files = append(manifest.Files, entry)
+	}
+
+	w, err := zw.Create(manifestFileName)
+	if err != nil {
+		log.Printf("Error: Failed to write export manifest for knowledge base %d: %v", kb.ID, err)
+		return
+	}
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(manifest); err != nil {
+		log.Printf("Error: Failed to encode export manifest for knowledge base %d: %v", kb.ID, err)
+	}
+}
+
+// writeFileToArchive copies a file from disk into the zip archive
+func writeFileToArchive(zw *zip.Writer, archivePath, filePath string) error {
+	src, err := os.Open(filePath)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+
+	w, err := zw.Create(archivePath)
+	if err != nil {
+		return err
+	}
+
+	_, err = io.Copy(w, src)
+	return err
+}
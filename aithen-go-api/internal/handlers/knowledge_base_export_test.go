@@ -0,0 +1,155 @@
+package handlers
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/aithen/go-api/internal/id"
+	"github.com/aithen/go-api/internal/limits"
+	"github.com/aithen/go-api/internal/models"
+	"github.com/gin-gonic/gin"
+)
+
+// createTestOrganization creates an organization owned by owner. It is deleted with its
+// knowledge bases when its last member's account is.
+func createTestOrganization(t *testing.T, m *models.Models, owner *models.User) *models.Organization {
+	t.Helper()
+
+	ctx := context.Background()
+	org, err := m.Organizations.Create(ctx, "Test Org", fmt.Sprintf("test-org-%d", id.Generate()), "", "", "", "", "", "")
+	if err != nil {
+		t.Fatalf("failed to create organization: %v", err)
+	}
+	if _, err := m.Organizations.AddMember(ctx, org.ID, owner.ID, "owner", "active"); err != nil {
+		t.Fatalf("failed to add owner: %v", err)
+	}
+	return org
+}
+
+// addTestMember adds a new user to org with role and returns them
+func addTestMember(t *testing.T, m *models.Models, org *models.Organization, role string) *models.User {
+	t.Helper()
+
+	user := createTestUser(t, m)
+	if _, err := m.Organizations.AddMember(context.Background(), org.ID, user.ID, role, "active"); err != nil {
+		t.Fatalf("failed to add %s: %v", role, err)
+	}
+	return user
+}
+
+// addTestFile stores content in a temporary file and adds it to knowledge base kbID
+func addTestFile(t *testing.T, m *models.Models, kbID int64, name, content string) *models.KnowledgeBaseFile {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatalf("failed to write %s: %v", name, err)
+	}
+	file, err := m.KnowledgeBases.AddFile(context.Background(), kbID, name, path, int64(len(content)), "text/plain", nil, limits.ForPlan(limits.PlanEnterprise))
+	if err != nil {
+		t.Fatalf("failed to add file %s: %v", name, err)
+	}
+	return file
+}
+
+func TestExportKnowledgeBase(t *testing.T) {
+	useTestDatabase(t)
+	gin.SetMode(gin.TestMode)
+	m := models.NewModels()
+	ctx := context.Background()
+
+	owner := createTestUser(t, m)
+	org := createTestOrganization(t, m, owner)
+	member := addTestMember(t, m, org, "member")
+	kb, err := m.KnowledgeBases.Create(ctx, org.ID, "Handbook", "Team docs", &owner.ID)
+	if err != nil {
+		t.Fatalf("failed to create knowledge base: %v", err)
+	}
+	present := addTestFile(t, m, kb.ID, "intro.txt", "hello")
+	missing := addTestFile(t, m, kb.ID, "gone.txt", "bye")
+	os.Remove(missing.FilePath)
+
+	otherOwner := createTestUser(t, m)
+	otherOrg := createTestOrganization(t, m, otherOwner)
+	otherKB, err := m.KnowledgeBases.Create(ctx, otherOrg.ID, "Other", "", &otherOwner.ID)
+	if err != nil {
+		t.Fatalf("failed to create knowledge base: %v", err)
+	}
+
+	export := func(userID int64, slug string, kbID int64) *httptest.ResponseRecorder {
+		r := gin.New()
+		r.GET("/orgs/:slug/knowledge-bases/:id/export", func(c *gin.Context) { c.Set("user_id", userID) }, ExportKnowledgeBase)
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, fmt.Sprintf("/orgs/%s/knowledge-bases/%d/export", slug, kbID), nil))
+		return w
+	}
+
+	t.Run("owner gets the files and a manifest", func(t *testing.T) {
+		w := export(owner.ID, org.Slug, kb.ID)
+		if w.Code != http.StatusOK {
+			t.Fatalf("status = %d, want %d: %s", w.Code, http.StatusOK, w.Body.String())
+		}
+
+		zr, err := zip.NewReader(bytes.NewReader(w.Body.Bytes()), int64(w.Body.Len()))
+		if err != nil {
+			t.Fatalf("response is not a zip archive: %v", err)
+		}
+		entries := map[string]string{}
+		for _, f := range zr.File {
+			rc, err := f.Open()
+			if err != nil {
+				t.Fatalf("failed to open %s: %v", f.Name, err)
+			}
+			data, _ := io.ReadAll(rc)
+			rc.Close()
+			entries[f.Name] = string(data)
+		}
+
+		var manifest KnowledgeBaseManifest
+		if err := json.Unmarshal([]byte(entries[manifestFileName]), &manifest); err != nil {
+			t.Fatalf("failed to parse manifest: %v", err)
+		}
+		if manifest.SchemaVersion != manifestSchemaVersion || manifest.KnowledgeBase.Name != "Handbook" {
+			t.Errorf("manifest = %+v, want schema %d for Handbook", manifest, manifestSchemaVersion)
+		}
+		if len(manifest.Files) != 2 {
+			t.Fatalf("manifest lists %d files, want 2", len(manifest.Files))
+		}
+		for _, file := range manifest.Files {
+			switch file.ID {
+			case fmt.Sprintf("%d", present.ID):
+				if file.Missing || entries[file.ArchivePath] != "hello" {
+					t.Errorf("present file entry = %+v, want its content in the archive", file)
+				}
+			case fmt.Sprintf("%d", missing.ID):
+				// A file missing on disk is noted rather than failing the export
+				if !file.Missing || file.ArchivePath != "" || file.Error == "" {
+					t.Errorf("missing file entry = %+v, want it marked missing", file)
+				}
+			default:
+				t.Errorf("manifest lists unexpected file %+v", file)
+			}
+		}
+	})
+
+	t.Run("member is forbidden", func(t *testing.T) {
+		if w := export(member.ID, org.Slug, kb.ID); w.Code != http.StatusForbidden {
+			t.Errorf("status = %d, want %d", w.Code, http.StatusForbidden)
+		}
+	})
+
+	t.Run("knowledge base of another organization", func(t *testing.T) {
+		if w := export(owner.ID, org.Slug, otherKB.ID); w.Code != http.StatusNotFound {
+			t.Errorf("status = %d, want %d", w.Code, http.StatusNotFound)
+		}
+	})
+}
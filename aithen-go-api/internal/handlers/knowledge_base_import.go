@@ -0,0 +1,282 @@
+package handlers
+
+import (
+	"archive/zip"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/aithen/go-api/internal/limits"
+	"github.com/aithen/go-api/internal/models"
+	"github.com/aithen/go-api/internal/uploads"
+	"github.com/gin-gonic/gin"
+)
+
+// maxImportFileSize limits the size of a single file extracted from an import archive
+const maxImportFileSize = 100 << 20 // 100 MB
+
+var (
+	errManifestMissing = errors.New("archive does not contain a manifest.json")
+	errFileTooLarge    = errors.New("file exceeds the maximum import size")
+)
+
+// ImportKnowledgeBase creates a new knowledge base from an archive produced by ExportKnowledgeBase
+func ImportKnowledgeBase(c *gin.Context) {
+	orgSlug := organizationSlug(c)
+	if orgSlug == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Organization slug is required"})
+		return
+	}
+
+	m := models.NewModels()
+	ctx := c.Request.Context()
+
+	org, err := requestOrganization(c, m)
+	if err != nil {
+		if err == models.ErrOrganizationNotFound {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Organization not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve organization"})
+		return
+	}
+
+	// Only owners and admins may import
+	if !requireOrganizationRole(c, m, org, "owner", "admin") {
+		return
+	}
+
+	fileHeader, err := c.FormFile("file")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Archive file is required"})
+		return
+	}
+
+	archive, err := fileHeader.Open()
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to read archive"})
+		return
+	}
+	defer archive.Close()
+
+	zr, manifest, err := openImportArchive(archive, fileHeader.Size)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Invalid archive: %v", err)})
+		return
+	}
+
+	// Allow the caller to rename the knowledge base on import
+	name := manifest.KnowledgeBase.Name
+	if override := c.PostForm("name"); override != "" {
+		name = override
+	}
+
+	// The imported knowledge base and its files must fit within the plan limits
+	var importSize, importFiles int64
+	for _, file := range manifest.Files {
+		if !file.Missing {
+			importSize += file.FileSize
+			importFiles++
+		}
+	}
+	if !enforcePlanLimit(c, m, org.ID, knowledgeBaseLimit(c, m, org.ID)) ||
+		!enforcePlanLimit(c, m, org.ID, storageLimit(c, m, org.ID, importSize)) ||
+		!enforceFileCountLimit(c, m, org.ID, 0, importFiles) {
+		return
+	}
+
+	plan, fileLimits, err := uploadLimits(c, m, org.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check plan limits"})
+		return
+	}
+
+	createdBy := currentUserID(c)
+	kb, err := m.KnowledgeBases.Create(ctx, org.ID, name, manifest.KnowledgeBase.Description, createdBy)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create knowledge base"})
+		return
+	}
+	grantCreatorKBAdmin(c, m, kb.ID)
+
+	files, err := extractImportFiles(ctx, m, kb.ID, zr, manifest, createdBy, fileLimits)
+	if err != nil {
+		// Roll back the partially imported knowledge base
+		removeKnowledgeBaseUploads(kb.ID)
+		if delErr := m.KnowledgeBases.Delete(ctx, kb.ID); delErr != nil {
+			log.Printf("Warning: Failed to clean up knowledge base %d after failed import: %v", kb.ID, delErr)
+		}
+		if respondLimitError(c, plan, err) {
+			return
+		}
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Failed to import files: %v", err)})
+		return
+	}
+
+	response := gin.H{
+		"message":           fmt.Sprintf("Imported knowledge base with %d file(s)", len(files)),
+		"knowledge_base_id": fmt.Sprintf("%d", kb.ID),
+		"knowledge_base":    kb,
+	}
+
+	// Optionally kick off training for the imported files
+	if c.PostForm("train") == "true" && len(files) > 0 {
+		if _, err := checkPlanLimit(c, m, org.ID, trainingLimit(c, m, org.ID)); err != nil {
+			response["training_error"] = err.Error()
+		} else if version, channelID, err := startTraining(ctx, m, kb, files, nil, nil, nil); err != nil {
+			log.Printf("Warning: Failed to start training for imported knowledge base %d: %v", kb.ID, err)
+			response["training_error"] = err.Error()
+		} else {
+			response["version"] = version
+			response["channel"] = channelID // WebSocket channel for progress updates
+		}
+	}
+
+	Created(c, knowledgeBaseLocation(org.Slug, kb.ID), response)
+}
+
+// openImportArchive opens the zip archive, validates all entry names and decodes the manifest
+func openImportArchive(r io.ReaderAt, size int64) (*zip.Reader, *KnowledgeBaseManifest, error) {
+	zr, err := zip.NewReader(r, size)
+	if err != nil {
+		return nil, nil, fmt.Errorf("not a valid zip file")
+	}
+
+	var manifestEntry *zip.File
+	for _, entry := range zr.File {
+		if err := validateArchiveEntryName(entry.Name); err != nil {
+			return nil, nil, err
+		}
+		if entry.Name == manifestFileName {
+			manifestEntry = entry
+		}
+	}
+	if manifestEntry == nil {
+		return nil, nil, errManifestMissing
+	}
+
+	rc, err := manifestEntry.Open()
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to open manifest: %w", err)
+	}
+	defer rc.Close()
+
+	var manifest KnowledgeBaseManifest
+	decoder := json.NewDecoder(io.LimitReader(rc, 10<<20))
+	if err := decoder.Decode(&manifest); err != nil {
+		return nil, nil, fmt.Errorf("failed to parse manifest: %w", err)
+	}
+
+	if err := validateManifest(&manifest, zr); err != nil {
+		return nil, nil, err
+	}
+
+	return zr, &manifest, nil
+}
+
+// validateArchiveEntryName rejects absolute paths and path traversal in zip entry names
+func validateArchiveEntryName(name string) error {
+	if name == "" || strings.Contains(name, "\\") || strings.HasPrefix(name, "/") || filepath.IsAbs(name) {
+		return fmt.Errorf("invalid entry name %q", name)
+	}
+	for _, segment := range strings.Split(name, "/") {
+		if segment == ".." {
+			return fmt.Errorf("invalid entry name %q", name)
+		}
+	}
+	return nil
+}
+
+// validateManifest checks the manifest schema and that every referenced file is present
+func validateManifest(manifest *KnowledgeBaseManifest, zr *zip.Reader) error {
+	if manifest.SchemaVersion != manifestSchemaVersion {
+		return fmt.Errorf("unsupported manifest schema version %d", manifest.SchemaVersion)
+	}
+	if strings.TrimSpace(manifest.KnowledgeBase.Name) == "" {
+		return fmt.Errorf("manifest is missing knowledge base name")
+	}
+
+	entries := make(map[string]bool, len(zr.File))
+	for _, entry := range zr.File {
+		entries[entry.Name] = true
+	}
+
+	for i, file := range manifest.Files {
+		if strings.TrimSpace(file.Name) == "" {
+			return fmt.Errorf("manifest file %d is missing a name", i)
+		}
+		if file.Missing {
+			continue
+		}
+		if file.ArchivePath == "" {
+			return fmt.Errorf("manifest file %q is missing an archive path", file.Name)
+		}
+		if err := validateArchiveEntryName(file.ArchivePath); err != nil {
+			return err
+		}
+		if !entries[file.ArchivePath] {
+			return fmt.Errorf("archive is missing %q referenced by the manifest", file.ArchivePath)
+		}
+	}
+
+	return nil
+}
+
+// extractImportFiles stores every archived file listed in the manifest for the knowledge base
+func extractImportFiles(ctx context.Context, m *models.Models, kbID int64, zr *zip.Reader, manifest *KnowledgeBaseManifest, createdBy *int64, l limits.Limits) ([]*models.KnowledgeBaseFile, error) {
+	uploadDir := uploads.KnowledgeBaseDir(kbID)
+	if err := os.MkdirAll(uploadDir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create upload directory: %w", err)
+	}
+
+	entries := make(map[string]*zip.File, len(zr.File))
+	for _, entry := range zr.File {
+		entries[entry.Name] = entry
+	}
+
+	files := make([]*models.KnowledgeBaseFile, 0, len(manifest.Files))
+	for _, file := range manifest.Files {
+		if file.Missing {
+			// File was not available when the archive was exported
+			continue
+		}
+
+		kbFile, err := extractImportFile(ctx, m, kbID, uploadDir, entries[file.ArchivePath], file, createdBy, l)
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, kbFile)
+	}
+
+	return files, nil
+}
+
+// extractImportFile stores a single archived file
+func extractImportFile(ctx context.Context, m *models.Models, kbID int64, uploadDir string, entry *zip.File, file ManifestFile, createdBy *int64, l limits.Limits) (*models.KnowledgeBaseFile, error) {
+	if entry.UncompressedSize64 > maxImportFileSize {
+		return nil, fmt.Errorf("%s: %w", file.Name, errFileTooLarge)
+	}
+
+	rc, err := entry.Open()
+	if err != nil {
+		return nil, fmt.Errorf("failed to open %s: %w", file.Name, err)
+	}
+	defer rc.Close()
+
+	return storeKnowledgeBaseFile(ctx, m, kbID, uploadDir, file.Name, file.MimeType, io.LimitReader(rc, maxImportFileSize), createdBy, l)
+}
+
+// removeKnowledgeBaseUploads deletes the upload directory for a knowledge base
+func removeKnowledgeBaseUploads(kbID int64) {
+	uploadDir := uploads.KnowledgeBaseDir(kbID)
+	if err := os.RemoveAll(uploadDir); err != nil {
+		log.Printf("Warning: Failed to delete upload directory %s: %v", uploadDir, err)
+	}
+}
@@ -0,0 +1,166 @@
+package handlers
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestValidateArchiveEntryName(t *testing.T) {
+	tests := []struct {
+		name    string
+		entry   string
+		wantErr bool
+	}{
+		{name: "manifest", entry: "manifest.json"},
+		{name: "nested file", entry: "files/123/report.pdf"},
+		{name: "dots inside a name", entry: "files/1/notes..txt"},
+		{name: "empty", entry: "", wantErr: true},
+		{name: "parent directory", entry: "../etc/passwd", wantErr: true},
+		{name: "parent directory inside path", entry: "files/../../secret", wantErr: true},
+		{name: "absolute path", entry: "/etc/passwd", wantErr: true},
+		{name: "backslash separator", entry: "files\\..\\secret", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateArchiveEntryName(tt.entry)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("validateArchiveEntryName(%q) error = %v, wantErr %v", tt.entry, err, tt.wantErr)
+			}
+		})
+	}
+}
+
+// buildArchive returns a zip archive with the given entries
+func buildArchive(t *testing.T, entries map[string]string) []byte {
+	t.Helper()
+
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for name, content := range entries {
+		w, err := zw.Create(name)
+		if err != nil {
+			t.Fatalf("failed to create entry %q: %v", name, err)
+		}
+		if _, err := w.Write([]byte(content)); err != nil {
+			t.Fatalf("failed to write entry %q: %v", name, err)
+		}
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatalf("failed to close archive: %v", err)
+	}
+	return buf.Bytes()
+}
+
+// manifestJSON encodes a manifest for a knowledge base with the given files
+func manifestJSON(t *testing.T, schemaVersion int, name string, files ...ManifestFile) string {
+	t.Helper()
+
+	data, err := json.Marshal(KnowledgeBaseManifest{
+		SchemaVersion: schemaVersion,
+		KnowledgeBase: ManifestKnowledgeBase{Name: name},
+		Files:         files,
+	})
+	if err != nil {
+		t.Fatalf("failed to encode manifest: %v", err)
+	}
+	return string(data)
+}
+
+func TestOpenImportArchive(t *testing.T) {
+	doc := ManifestFile{Name: "doc.txt", ArchivePath: "files/1/doc.txt"}
+
+	tests := []struct {
+		name    string
+		archive func(t *testing.T) []byte
+		wantErr string // empty when the archive should open
+	}{
+		{
+			name: "valid archive",
+			archive: func(t *testing.T) []byte {
+				return buildArchive(t, map[string]string{
+					manifestFileName:  manifestJSON(t, manifestSchemaVersion, "Docs", doc),
+					"files/1/doc.txt": "hello",
+				})
+			},
+		},
+		{
+			name:    "not a zip file",
+			archive: func(t *testing.T) []byte { return []byte("definitely not a zip") },
+			wantErr: "not a valid zip file",
+		},
+		{
+			name: "missing manifest",
+			archive: func(t *testing.T) []byte {
+				return buildArchive(t, map[string]string{"files/1/doc.txt": "hello"})
+			},
+			wantErr: errManifestMissing.Error(),
+		},
+		{
+			name: "malformed manifest",
+			archive: func(t *testing.T) []byte {
+				return buildArchive(t, map[string]string{manifestFileName: "{not json"})
+			},
+			wantErr: "failed to parse manifest",
+		},
+		{
+			name: "unsupported schema version",
+			archive: func(t *testing.T) []byte {
+				return buildArchive(t, map[string]string{manifestFileName: manifestJSON(t, manifestSchemaVersion+1, "Docs")})
+			},
+			wantErr: "unsupported manifest schema version",
+		},
+		{
+			name: "missing knowledge base name",
+			archive: func(t *testing.T) []byte {
+				return buildArchive(t, map[string]string{manifestFileName: manifestJSON(t, manifestSchemaVersion, " ")})
+			},
+			wantErr: "missing knowledge base name",
+		},
+		{
+			name: "path traversal entry",
+			archive: func(t *testing.T) []byte {
+				return buildArchive(t, map[string]string{
+					manifestFileName: manifestJSON(t, manifestSchemaVersion, "Docs"),
+					"../evil.txt":    "pwned",
+				})
+			},
+			wantErr: "invalid entry name",
+		},
+		{
+			name: "path traversal in manifest",
+			archive: func(t *testing.T) []byte {
+				evil := ManifestFile{Name: "evil.txt", ArchivePath: "files/../../evil.txt"}
+				return buildArchive(t, map[string]string{manifestFileName: manifestJSON(t, manifestSchemaVersion, "Docs", evil)})
+			},
+			wantErr: "invalid entry name",
+		},
+		{
+			name: "file referenced by manifest is missing",
+			archive: func(t *testing.T) []byte {
+				return buildArchive(t, map[string]string{manifestFileName: manifestJSON(t, manifestSchemaVersion, "Docs", doc)})
+			},
+			wantErr: "archive is missing",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data := tt.archive(t)
+			_, manifest, err := openImportArchive(bytes.NewReader(data), int64(len(data)))
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("openImportArchive() error = %v", err)
+				}
+				if manifest.KnowledgeBase.Name != "Docs" || len(manifest.Files) != 1 {
+					t.Errorf("openImportArchive() manifest = %+v", manifest)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("openImportArchive() error = %v, want it to contain %q", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,93 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/aithen/go-api/internal/models"
+	"github.com/gin-gonic/gin"
+)
+
+// MoveKnowledgeBaseRequest represents request to move a knowledge base to another organization
+type MoveKnowledgeBaseRequest struct {
+	TargetOrgSlug string `json:"target_org_slug" binding:"required"`
+}
+
+// MoveKnowledgeBase moves a knowledge base, with its files and versions, to another organization.
+// The caller must be an owner or admin of both organizations, and the knowledge base must fit
+// within the target organization's plan limits.
+func MoveKnowledgeBase(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid knowledge base ID"})
+		return
+	}
+
+	var req MoveKnowledgeBaseRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	m := models.NewModels()
+	ctx := c.Request.Context()
+
+	source, err := requestOrganization(c, m)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Organization not found"})
+		return
+	}
+	if !requireOrganizationRole(c, m, source, "owner", "admin") {
+		return
+	}
+
+	kb, err := m.KnowledgeBases.FindByID(ctx, id)
+	if err != nil || kb.OrganizationID != source.ID {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Knowledge base not found"})
+		return
+	}
+
+	target, err := m.Organizations.FindBySlug(ctx, req.TargetOrgSlug)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Target organization not found"})
+		return
+	}
+	if target.ID == source.ID {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Knowledge base already belongs to this organization"})
+		return
+	}
+	if !requireOrganizationRole(c, m, target, "owner", "admin") {
+		return
+	}
+
+	// The knowledge base and its files count against the target organization's plan
+	files, err := m.KnowledgeBases.GetFilesByKnowledgeBaseID(ctx, kb.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve files"})
+		return
+	}
+	var size int64
+	for _, file := range files {
+		size += file.FileSize
+	}
+	if !enforcePlanLimit(c, m, target.ID, knowledgeBaseLimit(c, m, target.ID)) ||
+		!enforcePlanLimit(c, m, target.ID, storageLimit(c, m, target.ID, size)) {
+		return
+	}
+
+	moved, err := m.KnowledgeBases.ChangeOrganization(ctx, kb.ID, source.ID, target.ID)
+	if err != nil {
+		if err == models.ErrKnowledgeBaseNotFound {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Knowledge base not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to move knowledge base"})
+		return
+	}
+
+	c.Header("Location", knowledgeBaseLocation(target.Slug, moved.ID))
+	c.JSON(http.StatusOK, gin.H{
+		"message":        "Knowledge base moved to " + target.Name,
+		"knowledge_base": moved,
+	})
+}
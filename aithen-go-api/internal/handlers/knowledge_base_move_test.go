@@ -0,0 +1,126 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aithen/go-api/internal/models"
+	"github.com/gin-gonic/gin"
+)
+
+func TestMoveKnowledgeBase(t *testing.T) {
+	useTestDatabase(t)
+	gin.SetMode(gin.TestMode)
+	m := models.NewModels()
+	ctx := context.Background()
+
+	owner := createTestUser(t, m)
+
+	// setup creates a source organization with a knowledge base and a target organization,
+	// and a caller with the given role in each ("" for no membership)
+	setup := func(t *testing.T, sourceRole, targetRole string) (caller *models.User, source, target *models.Organization, kb *models.KnowledgeBase) {
+		t.Helper()
+		caller = createTestUser(t, m)
+		source = createTestOrganization(t, m, owner)
+		target = createTestOrganization(t, m, owner)
+		for org, role := range map[*models.Organization]string{source: sourceRole, target: targetRole} {
+			if role == "" {
+				continue
+			}
+			if _, err := m.Organizations.AddMember(ctx, org.ID, caller.ID, role, "active"); err != nil {
+				t.Fatalf("failed to add %s: %v", role, err)
+			}
+		}
+		kb, err := m.KnowledgeBases.Create(ctx, source.ID, "Handbook", "", &owner.ID)
+		if err != nil {
+			t.Fatalf("failed to create knowledge base: %v", err)
+		}
+		return caller, source, target, kb
+	}
+
+	move := func(userID int64, sourceSlug string, kbID int64, targetSlug string) *httptest.ResponseRecorder {
+		r := gin.New()
+		r.POST("/orgs/:slug/knowledge-bases/:id/move", func(c *gin.Context) { c.Set("user_id", userID) }, MoveKnowledgeBase)
+		body := fmt.Sprintf(`{"target_org_slug":%q}`, targetSlug)
+		req := httptest.NewRequest(http.MethodPost, fmt.Sprintf("/orgs/%s/knowledge-bases/%d/move", sourceSlug, kbID), strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	t.Run("moves with files and versions", func(t *testing.T) {
+		caller, source, target, kb := setup(t, "admin", "owner")
+		file := addTestFile(t, m, kb.ID, "intro.txt", "hello")
+		version, err := m.KnowledgeBases.CreateVersion(ctx, kb.ID, nil, nil, nil)
+		if err != nil {
+			t.Fatalf("failed to create version: %v", err)
+		}
+		now := time.Now()
+		if err := m.KnowledgeBases.UpdateVersionStatus(ctx, version.ID, "completed", &now); err != nil {
+			t.Fatalf("failed to complete version: %v", err)
+		}
+
+		w := move(caller.ID, source.Slug, kb.ID, target.Slug)
+		if w.Code != http.StatusOK {
+			t.Fatalf("status = %d, want %d: %s", w.Code, http.StatusOK, w.Body.String())
+		}
+
+		moved, err := m.KnowledgeBases.FindByID(ctx, kb.ID)
+		if err != nil {
+			t.Fatalf("FindByID() error = %v", err)
+		}
+		if moved.OrganizationID != target.ID {
+			t.Errorf("organization = %d, want %d", moved.OrganizationID, target.ID)
+		}
+		files, err := m.KnowledgeBases.GetFilesByKnowledgeBaseID(ctx, kb.ID)
+		if err != nil || len(files) != 1 || files[0].ID != file.ID {
+			t.Errorf("files = %v (err %v), want the original file", files, err)
+		}
+		if count, err := m.KnowledgeBases.GetVersionCount(ctx, kb.ID); err != nil || count != 1 {
+			t.Errorf("versions = %d (err %v), want the original version", count, err)
+		}
+	})
+
+	tests := []struct {
+		name                   string
+		sourceRole, targetRole string
+		want                   int
+	}{
+		{name: "member of source", sourceRole: "member", targetRole: "owner", want: http.StatusForbidden},
+		{name: "not in source", sourceRole: "", targetRole: "owner", want: http.StatusForbidden},
+		{name: "member of target", sourceRole: "owner", targetRole: "member", want: http.StatusForbidden},
+		{name: "not in target", sourceRole: "admin", targetRole: "", want: http.StatusForbidden},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			caller, source, target, kb := setup(t, tt.sourceRole, tt.targetRole)
+
+			if w := move(caller.ID, source.Slug, kb.ID, target.Slug); w.Code != tt.want {
+				t.Errorf("status = %d, want %d: %s", w.Code, tt.want, w.Body.String())
+			}
+			if unchanged, err := m.KnowledgeBases.FindByID(ctx, kb.ID); err != nil || unchanged.OrganizationID != source.ID {
+				t.Errorf("knowledge base left its organization after a rejected move (err %v)", err)
+			}
+		})
+	}
+
+	t.Run("unknown target", func(t *testing.T) {
+		caller, source, _, kb := setup(t, "owner", "owner")
+		if w := move(caller.ID, source.Slug, kb.ID, "no-such-org-"+source.Slug); w.Code != http.StatusNotFound {
+			t.Errorf("status = %d, want %d: %s", w.Code, http.StatusNotFound, w.Body.String())
+		}
+	})
+
+	t.Run("same organization", func(t *testing.T) {
+		caller, source, _, kb := setup(t, "owner", "owner")
+		if w := move(caller.ID, source.Slug, kb.ID, source.Slug); w.Code != http.StatusBadRequest {
+			t.Errorf("status = %d, want %d: %s", w.Code, http.StatusBadRequest, w.Body.String())
+		}
+	})
+}
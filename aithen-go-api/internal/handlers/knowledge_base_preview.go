@@ -0,0 +1,120 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+
+	"github.com/aithen/go-api/internal/config"
+	"github.com/aithen/go-api/internal/httpclient"
+	"github.com/aithen/go-api/internal/models"
+	"github.com/aithen/go-api/internal/queue"
+	"github.com/gin-gonic/gin"
+)
+
+// PreviewChunksRequest represents optional chunking parameters for a chunk preview
+type PreviewChunksRequest struct {
+	MaxChunks    int  `json:"max_chunks"`
+	ChunkSize    *int `json:"chunk_size"`
+	ChunkOverlap *int `json:"chunk_overlap"`
+}
+
+// PreviewFileChunks returns how a knowledge base file would be chunked for training, without
+// generating embeddings. At most AI_CHUNK_PREVIEW_MAX chunks (default 20) are returned.
+func PreviewFileChunks(c *gin.Context) {
+	kbID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid knowledge base ID"})
+		return
+	}
+
+	fileID, err := strconv.ParseInt(c.Param("file_id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid file ID"})
+		return
+	}
+
+	// The body is optional; the training service's chunking defaults apply otherwise
+	var req PreviewChunksRequest
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	maxChunks := config.GetEnvInt("AI_CHUNK_PREVIEW_MAX", 20)
+	if req.MaxChunks < 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "max_chunks must be a positive integer"})
+		return
+	}
+	if req.MaxChunks == 0 || req.MaxChunks > maxChunks {
+		req.MaxChunks = maxChunks
+	}
+	if err := validateChunking(req.ChunkSize, req.ChunkOverlap); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	m := models.NewModels()
+	ctx := c.Request.Context()
+
+	file, err := m.KnowledgeBases.GetFileByID(ctx, fileID)
+	if err != nil || file.KnowledgeBaseID != kbID {
+		c.JSON(http.StatusNotFound, gin.H{"error": "File not found"})
+		return
+	}
+
+	// The training service reads the file from disk, so it needs an absolute path
+	path := file.FilePath
+	if !filepath.IsAbs(path) {
+		if wd, err := os.Getwd(); err == nil {
+			path = filepath.Join(wd, path)
+		}
+	}
+
+	reqBody, err := json.Marshal(gin.H{
+		"path":          path,
+		"file_id":       fmt.Sprintf("%d", file.ID),
+		"mime_type":     file.MimeType,
+		"max_chunks":    req.MaxChunks,
+		"chunk_size":    req.ChunkSize,
+		"chunk_overlap": req.ChunkOverlap,
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to marshal request"})
+		return
+	}
+
+	previewURL := fmt.Sprintf("%s/training/preview-chunks", queue.TrainingServiceURL())
+	httpReq, err := http.NewRequestWithContext(ctx, "POST", previewURL, bytes.NewBuffer(reqBody))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create request"})
+		return
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+
+	resp, err := httpclient.New(0).Do(httpReq)
+	if err != nil {
+		if ctx.Err() != nil {
+			c.Abort()
+			return
+		}
+		c.JSON(http.StatusBadGateway, gin.H{"error": fmt.Sprintf("Failed to connect to training service: %v", err)})
+		return
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to read response"})
+		return
+	}
+
+	c.Data(resp.StatusCode, "application/json", body)
+}
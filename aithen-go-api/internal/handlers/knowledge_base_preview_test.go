@@ -0,0 +1,134 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/aithen/go-api/internal/models"
+	"github.com/gin-gonic/gin"
+)
+
+func TestPreviewFileChunks(t *testing.T) {
+	useTestDatabase(t)
+	gin.SetMode(gin.TestMode)
+	m := models.NewModels()
+	ctx := context.Background()
+
+	owner := createTestUser(t, m)
+	org := createTestOrganization(t, m, owner)
+	kb, err := m.KnowledgeBases.Create(ctx, org.ID, "Docs", "", &owner.ID)
+	if err != nil {
+		t.Fatalf("failed to create knowledge base: %v", err)
+	}
+	file := addTestFile(t, m, kb.ID, "intro.txt", "hello world")
+	otherKB, err := m.KnowledgeBases.Create(ctx, org.ID, "Other", "", &owner.ID)
+	if err != nil {
+		t.Fatalf("failed to create knowledge base: %v", err)
+	}
+	otherFile := addTestFile(t, m, otherKB.ID, "other.txt", "elsewhere")
+
+	// The stub training service records the forwarded request and returns a fixed preview
+	var forwarded map[string]interface{}
+	stubStatus := http.StatusOK
+	stub := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/training/preview-chunks" {
+			t.Errorf("training service called at %s, want /training/preview-chunks", r.URL.Path)
+		}
+		forwarded = nil
+		json.NewDecoder(r.Body).Decode(&forwarded)
+		w.WriteHeader(stubStatus)
+		io.WriteString(w, `{"chunks":[{"index":0,"text":"hello world","size":11}],"total_chunks":1,"truncated":false}`)
+	}))
+	defer stub.Close()
+	t.Setenv("TRAINING_SERVICE_URL", stub.URL)
+	t.Setenv("AI_CHUNK_PREVIEW_MAX", "5")
+
+	preview := func(kbID, fileID int64, body string) *httptest.ResponseRecorder {
+		r := gin.New()
+		r.POST("/knowledge-bases/:id/files/:file_id/preview-chunks", PreviewFileChunks)
+		req := httptest.NewRequest(http.MethodPost, fmt.Sprintf("/knowledge-bases/%d/files/%d/preview-chunks", kbID, fileID), strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	t.Run("returns the service's chunks", func(t *testing.T) {
+		w := preview(kb.ID, file.ID, `{"chunk_size":500,"chunk_overlap":50}`)
+		if w.Code != http.StatusOK {
+			t.Fatalf("status = %d, want %d: %s", w.Code, http.StatusOK, w.Body.String())
+		}
+		var resp struct {
+			Chunks []struct {
+				Text string `json:"text"`
+				Size int    `json:"size"`
+			} `json:"chunks"`
+		}
+		if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+			t.Fatalf("failed to decode response: %v", err)
+		}
+		if len(resp.Chunks) != 1 || resp.Chunks[0].Text != "hello world" || resp.Chunks[0].Size != 11 {
+			t.Errorf("chunks = %+v, want the stub's single chunk", resp.Chunks)
+		}
+
+		path, _ := forwarded["path"].(string)
+		if !filepath.IsAbs(path) || filepath.Base(path) != "intro.txt" {
+			t.Errorf("forwarded path = %q, want the file's absolute path", path)
+		}
+		if forwarded["file_id"] != fmt.Sprint(file.ID) || forwarded["chunk_size"] != 500.0 || forwarded["chunk_overlap"] != 50.0 {
+			t.Errorf("forwarded request = %v, want the file ID and chunking parameters", forwarded)
+		}
+		if forwarded["max_chunks"] != 5.0 {
+			t.Errorf("forwarded max_chunks = %v, want the AI_CHUNK_PREVIEW_MAX default of 5", forwarded["max_chunks"])
+		}
+	})
+
+	t.Run("caps max_chunks", func(t *testing.T) {
+		for body, want := range map[string]float64{`{"max_chunks":3}`: 3, `{"max_chunks":500}`: 5} {
+			if w := preview(kb.ID, file.ID, body); w.Code != http.StatusOK {
+				t.Fatalf("status = %d, want %d: %s", w.Code, http.StatusOK, w.Body.String())
+			}
+			if forwarded["max_chunks"] != want {
+				t.Errorf("%s: forwarded max_chunks = %v, want %v", body, forwarded["max_chunks"], want)
+			}
+		}
+	})
+
+	t.Run("passes through service errors", func(t *testing.T) {
+		stubStatus = http.StatusUnprocessableEntity
+		defer func() { stubStatus = http.StatusOK }()
+		if w := preview(kb.ID, file.ID, ""); w.Code != http.StatusUnprocessableEntity {
+			t.Errorf("status = %d, want %d", w.Code, http.StatusUnprocessableEntity)
+		}
+	})
+
+	rejected := []struct {
+		name       string
+		kbID       int64
+		fileID     int64
+		body       string
+		wantStatus int
+	}{
+		{"file in another knowledge base", kb.ID, otherFile.ID, "", http.StatusNotFound},
+		{"negative max_chunks", kb.ID, file.ID, `{"max_chunks":-1}`, http.StatusBadRequest},
+		{"overlap not smaller than chunk size", kb.ID, file.ID, `{"chunk_size":200,"chunk_overlap":200}`, http.StatusBadRequest},
+	}
+	for _, tt := range rejected {
+		t.Run(tt.name, func(t *testing.T) {
+			forwarded = nil
+			if w := preview(tt.kbID, tt.fileID, tt.body); w.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d: %s", w.Code, tt.wantStatus, w.Body.String())
+			}
+			if forwarded != nil {
+				t.Errorf("training service was called for a rejected request: %v", forwarded)
+			}
+		})
+	}
+}
@@ -0,0 +1,120 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/aithen/go-api/internal/config"
+	"github.com/aithen/go-api/internal/models"
+	"github.com/aithen/go-api/internal/queue"
+	"github.com/gin-gonic/gin"
+)
+
+// ReembedKnowledgeBaseRequest represents request to retrain a knowledge base with another embedding model
+type ReembedKnowledgeBaseRequest struct {
+	EmbeddingModel string `json:"embedding_model" binding:"required"`
+}
+
+// allowedEmbeddingModels returns the embedding models knowledge bases may be trained with
+// (AI_EMBEDDING_MODELS, a comma-separated list, default "nomic-embed-text")
+func allowedEmbeddingModels() []string {
+	var models []string
+	for _, model := range strings.Split(config.GetEnv("AI_EMBEDDING_MODELS"), ",") {
+		if model = strings.TrimSpace(model); model != "" {
+			models = append(models, model)
+		}
+	}
+	if len(models) == 0 {
+		return []string{"nomic-embed-text"}
+	}
+	return models
+}
+
+// validateEmbeddingModel rejects embedding models that are not allowed
+func validateEmbeddingModel(model string) error {
+	allowed := allowedEmbeddingModels()
+	for _, m := range allowed {
+		if m == model {
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown embedding model %q, must be one of: %s", model, strings.Join(allowed, ", "))
+}
+
+// ReembedKnowledgeBase trains a new version of a knowledge base over its existing files with
+// another embedding model. Earlier versions are kept and stay searchable until the new version
+// completes, at which point it becomes the latest completed version used for search.
+func ReembedKnowledgeBase(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid knowledge base ID"})
+		return
+	}
+
+	var req ReembedKnowledgeBaseRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	req.EmbeddingModel = strings.TrimSpace(req.EmbeddingModel)
+	if err := validateEmbeddingModel(req.EmbeddingModel); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	m := models.NewModels()
+	ctx := c.Request.Context()
+
+	kb, err := m.KnowledgeBases.FindByID(ctx, id)
+	if err != nil {
+		if err == models.ErrKnowledgeBaseNotFound {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Knowledge base not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve knowledge base"})
+		return
+	}
+
+	files, err := m.KnowledgeBases.GetFilesByKnowledgeBaseID(ctx, id)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve files"})
+		return
+	}
+	if len(files) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Cannot re-embed knowledge base without files"})
+		return
+	}
+
+	if kb.Status != "training" && !enforcePlanLimit(c, m, kb.OrganizationID, trainingLimit(c, m, kb.OrganizationID)) {
+		return
+	}
+
+	version, channelID, err := startTraining(ctx, m, kb, files, nil, nil, &req.EmbeddingModel)
+	if errors.Is(err, models.ErrKnowledgeBaseAlreadyTraining) {
+		// The running version may use another model, so it cannot stand in for this request
+		c.JSON(http.StatusConflict, gin.H{
+			"error":   "Knowledge base is already training, wait for it to finish before re-embedding",
+			"version": version,
+			"channel": channelID,
+		})
+		return
+	}
+	if errors.Is(err, queue.ErrQueueFull) {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "The training system is busy, please try again later"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("Failed to start re-embedding: %v", err)})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":        "Re-embedding started successfully",
+		"version":        version,
+		"knowledge_base": kb,
+		"channel":        channelID, // WebSocket channel for progress updates
+	})
+}
@@ -0,0 +1,119 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aithen/go-api/internal/models"
+	"github.com/gin-gonic/gin"
+)
+
+func TestValidateEmbeddingModel(t *testing.T) {
+	tests := []struct {
+		name    string
+		allowed string
+		model   string
+		wantErr bool
+	}{
+		{name: "default model", model: "nomic-embed-text"},
+		{name: "unknown with default list", model: "text-embedding-3-large", wantErr: true},
+		{name: "configured model", allowed: "nomic-embed-text, text-embedding-3-large", model: "text-embedding-3-large"},
+		{name: "unknown with configured list", allowed: "text-embedding-3-large", model: "nomic-embed-text", wantErr: true},
+		{name: "empty", model: "", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("AI_EMBEDDING_MODELS", tt.allowed)
+			if err := validateEmbeddingModel(tt.model); (err != nil) != tt.wantErr {
+				t.Errorf("validateEmbeddingModel(%q) error = %v, wantErr %v", tt.model, err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestReembedKnowledgeBase(t *testing.T) {
+	useTestDatabase(t)
+	gin.SetMode(gin.TestMode)
+	t.Setenv("AI_EMBEDDING_MODELS", "nomic-embed-text,text-embedding-3-large")
+	m := models.NewModels()
+	ctx := context.Background()
+
+	// The stub training service holds every run open until the test ends, so the new version
+	// stays in training while it is inspected
+	release := make(chan struct{})
+	stub := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-release:
+		case <-r.Context().Done():
+		}
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	t.Cleanup(stub.Close)
+	t.Cleanup(func() { close(release) })
+	t.Setenv("TRAINING_SERVICE_URL", stub.URL)
+
+	owner := createTestUser(t, m)
+	org := createTestOrganization(t, m, owner)
+	kb, err := m.KnowledgeBases.Create(ctx, org.ID, "Docs", "", &owner.ID)
+	if err != nil {
+		t.Fatalf("failed to create knowledge base: %v", err)
+	}
+	addTestFile(t, m, kb.ID, "intro.txt", "hello")
+	previous, err := m.KnowledgeBases.CreateVersion(ctx, kb.ID, nil, nil, nil)
+	if err != nil {
+		t.Fatalf("failed to create version: %v", err)
+	}
+	now := time.Now()
+	if err := m.KnowledgeBases.UpdateVersionStatus(ctx, previous.ID, "completed", &now); err != nil {
+		t.Fatalf("failed to complete version: %v", err)
+	}
+
+	reembed := func(body string) *httptest.ResponseRecorder {
+		r := gin.New()
+		r.POST("/knowledge-bases/:id/reembed", ReembedKnowledgeBase)
+		req := httptest.NewRequest(http.MethodPost, fmt.Sprintf("/knowledge-bases/%d/reembed", kb.ID), strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	t.Run("unknown model", func(t *testing.T) {
+		if w := reembed(`{"embedding_model":"made-up"}`); w.Code != http.StatusBadRequest {
+			t.Errorf("status = %d, want %d: %s", w.Code, http.StatusBadRequest, w.Body.String())
+		}
+	})
+
+	t.Run("starts a version with the new model", func(t *testing.T) {
+		w := reembed(`{"embedding_model":" text-embedding-3-large "}`)
+		if w.Code != http.StatusOK {
+			t.Fatalf("status = %d, want %d: %s", w.Code, http.StatusOK, w.Body.String())
+		}
+
+		versions, err := m.KnowledgeBases.GetAllVersions(ctx, kb.ID)
+		if err != nil {
+			t.Fatalf("GetAllVersions() error = %v", err)
+		}
+		if len(versions) != 2 {
+			t.Fatalf("knowledge base has %d versions, want 2", len(versions))
+		}
+		latest, old := versions[0], versions[1]
+		if latest.EmbeddingModel == nil || *latest.EmbeddingModel != "text-embedding-3-large" || latest.Status != "training" {
+			t.Errorf("new version = %s with model %v, want training with text-embedding-3-large", latest.Status, latest.EmbeddingModel)
+		}
+		if old.ID != previous.ID || old.Status != "completed" {
+			t.Errorf("previous version = %d %s, want %d kept completed", old.ID, old.Status, previous.ID)
+		}
+	})
+
+	t.Run("already training", func(t *testing.T) {
+		if w := reembed(`{"embedding_model":"nomic-embed-text"}`); w.Code != http.StatusConflict {
+			t.Errorf("status = %d, want %d: %s", w.Code, http.StatusConflict, w.Body.String())
+		}
+	})
+}
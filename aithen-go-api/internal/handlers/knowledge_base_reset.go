@@ -0,0 +1,79 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/aithen/go-api/internal/models"
+	"github.com/aithen/go-api/internal/queue"
+	"github.com/gin-gonic/gin"
+)
+
+// ResetKnowledgeBaseStatus recovers a knowledge base stuck training after its run died, e.g.
+// when the server restarted and lost the in-memory training queue. Its training versions are
+// marked failed and it becomes active again. It is refused while the knowledge base still has
+// pending or processing jobs. Only owners and admins may reset.
+func ResetKnowledgeBaseStatus(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid knowledge base ID"})
+		return
+	}
+
+	m := models.NewModels()
+	ctx := c.Request.Context()
+
+	org, err := requestOrganization(c, m)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Organization not found"})
+		return
+	}
+	if !requireOrganizationRole(c, m, org, "owner", "admin") {
+		return
+	}
+
+	kb, err := m.KnowledgeBases.FindByID(ctx, id)
+	if err != nil || kb.OrganizationID != org.ID {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Knowledge base not found"})
+		return
+	}
+	if kb.Status != "training" {
+		c.JSON(http.StatusConflict, gin.H{"error": "Knowledge base is not training"})
+		return
+	}
+	if queue.GetTrainingQueue().HasActiveJobs(id) {
+		c.JSON(http.StatusConflict, gin.H{"error": "Training jobs are still active"})
+		return
+	}
+
+	versionIDs, err := m.KnowledgeBases.ResetTrainingStatus(ctx, id)
+	if err != nil {
+		if errors.Is(err, models.ErrKnowledgeBaseNotFound) {
+			// Finished training since it was loaded
+			c.JSON(http.StatusConflict, gin.H{"error": "Knowledge base is not training"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to reset knowledge base"})
+		return
+	}
+
+	// The reset frees one of the organization's concurrent trainings
+	go startWaitingTrainings(id)
+
+	kb, err = m.KnowledgeBases.FindByID(ctx, id)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve knowledge base"})
+		return
+	}
+
+	failed := make([]string, len(versionIDs))
+	for i, versionID := range versionIDs {
+		failed[i] = strconv.FormatInt(versionID, 10)
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"knowledge_base":  kb,
+		"failed_versions": failed,
+	})
+}
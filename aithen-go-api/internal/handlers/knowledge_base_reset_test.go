@@ -0,0 +1,135 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/aithen/go-api/internal/models"
+	"github.com/gin-gonic/gin"
+)
+
+func TestResetKnowledgeBaseStatus(t *testing.T) {
+	useTestDatabase(t)
+	gin.SetMode(gin.TestMode)
+	m := models.NewModels()
+	ctx := context.Background()
+
+	// The stub training service holds every run open until the test ends, so a started run
+	// keeps active jobs in the queue
+	release := make(chan struct{})
+	stub := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-release:
+		case <-r.Context().Done():
+		}
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	t.Cleanup(stub.Close)
+	t.Cleanup(func() { close(release) })
+	t.Setenv("TRAINING_SERVICE_URL", stub.URL)
+
+	owner := createTestUser(t, m)
+
+	// newKB creates a knowledge base with a file in a new organization, so each case has the
+	// free plan's training slot to itself
+	newKB := func(t *testing.T) (*models.Organization, *models.KnowledgeBase) {
+		t.Helper()
+		org := createTestOrganization(t, m, owner)
+		kb, err := m.KnowledgeBases.Create(ctx, org.ID, "Docs", "", &owner.ID)
+		if err != nil {
+			t.Fatalf("failed to create knowledge base: %v", err)
+		}
+		addTestFile(t, m, kb.ID, "intro.txt", "hello")
+		return org, kb
+	}
+	reset := func(userID int64, org *models.Organization, kbID int64) *httptest.ResponseRecorder {
+		r := gin.New()
+		r.POST("/orgs/:slug/knowledge-bases/:id/reset-status", func(c *gin.Context) { c.Set("user_id", userID) }, ResetKnowledgeBaseStatus)
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodPost, fmt.Sprintf("/orgs/%s/knowledge-bases/%d/reset-status", org.Slug, kbID), nil))
+		return w
+	}
+	status := func(t *testing.T, kbID int64) string {
+		t.Helper()
+		kb, err := m.KnowledgeBases.FindByID(ctx, kbID)
+		if err != nil {
+			t.Fatalf("FindByID() error = %v", err)
+		}
+		return kb.Status
+	}
+
+	t.Run("stuck without jobs", func(t *testing.T) {
+		// A version created without enqueueing jobs is what a run lost on restart leaves behind
+		org, kb := newKB(t)
+		version, err := m.KnowledgeBases.CreateVersion(ctx, kb.ID, nil, nil, nil)
+		if err != nil {
+			t.Fatalf("failed to create version: %v", err)
+		}
+
+		w := reset(owner.ID, org, kb.ID)
+		if w.Code != http.StatusOK {
+			t.Fatalf("status = %d, want %d: %s", w.Code, http.StatusOK, w.Body.String())
+		}
+		if got := status(t, kb.ID); got != "active" {
+			t.Errorf("knowledge base status = %q, want active", got)
+		}
+		got, err := m.KnowledgeBases.GetVersionByID(ctx, version.ID)
+		if err != nil {
+			t.Fatalf("GetVersionByID() error = %v", err)
+		}
+		if got.Status != "failed" {
+			t.Errorf("version status = %q, want failed", got.Status)
+		}
+	})
+
+	t.Run("refused while jobs are active", func(t *testing.T) {
+		org, kb := newKB(t)
+		files, err := m.KnowledgeBases.GetFilesByKnowledgeBaseID(ctx, kb.ID)
+		if err != nil {
+			t.Fatalf("GetFilesByKnowledgeBaseID() error = %v", err)
+		}
+		version, _, err := startTraining(ctx, m, kb, files, nil, nil, nil)
+		if err != nil {
+			t.Fatalf("startTraining() error = %v", err)
+		}
+
+		if w := reset(owner.ID, org, kb.ID); w.Code != http.StatusConflict {
+			t.Fatalf("status = %d, want %d: %s", w.Code, http.StatusConflict, w.Body.String())
+		}
+		if got := status(t, kb.ID); got != "training" {
+			t.Errorf("knowledge base status = %q, want training", got)
+		}
+		got, err := m.KnowledgeBases.GetVersionByID(ctx, version.ID)
+		if err != nil {
+			t.Fatalf("GetVersionByID() error = %v", err)
+		}
+		if got.Status != "training" {
+			t.Errorf("version status = %q, want training", got.Status)
+		}
+	})
+
+	t.Run("not training", func(t *testing.T) {
+		org, kb := newKB(t)
+		if w := reset(owner.ID, org, kb.ID); w.Code != http.StatusConflict {
+			t.Errorf("status = %d, want %d: %s", w.Code, http.StatusConflict, w.Body.String())
+		}
+	})
+
+	t.Run("member", func(t *testing.T) {
+		org, kb := newKB(t)
+		if _, err := m.KnowledgeBases.CreateVersion(ctx, kb.ID, nil, nil, nil); err != nil {
+			t.Fatalf("failed to create version: %v", err)
+		}
+		member := addTestMember(t, m, org, "member")
+
+		if w := reset(member.ID, org, kb.ID); w.Code != http.StatusForbidden {
+			t.Fatalf("status = %d, want %d: %s", w.Code, http.StatusForbidden, w.Body.String())
+		}
+		if got := status(t, kb.ID); got != "training" {
+			t.Errorf("knowledge base status = %q, want training", got)
+		}
+	})
+}
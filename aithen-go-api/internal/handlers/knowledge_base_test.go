@@ -0,0 +1,214 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aithen/go-api/internal/models"
+	"github.com/gin-gonic/gin"
+)
+
+func intPtr(v int) *int { return &v }
+
+func TestValidateChunking(t *testing.T) {
+	tests := []struct {
+		name         string
+		chunkSize    *int
+		chunkOverlap *int
+		wantErr      bool
+	}{
+		{name: "service defaults"},
+		{name: "size only", chunkSize: intPtr(1000)},
+		{name: "overlap only", chunkOverlap: intPtr(200)},
+		{name: "size and overlap", chunkSize: intPtr(1000), chunkOverlap: intPtr(200)},
+		{name: "minimum size", chunkSize: intPtr(minChunkSize)},
+		{name: "maximum size", chunkSize: intPtr(maxChunkSize)},
+		{name: "no overlap", chunkSize: intPtr(1000), chunkOverlap: intPtr(0)},
+		{name: "size too small", chunkSize: intPtr(minChunkSize - 1), wantErr: true},
+		{name: "size too large", chunkSize: intPtr(maxChunkSize + 1), wantErr: true},
+		{name: "negative overlap", chunkOverlap: intPtr(-1), wantErr: true},
+		{name: "overlap too large", chunkOverlap: intPtr(maxChunkOverlap + 1), wantErr: true},
+		{name: "overlap equal to size", chunkSize: intPtr(500), chunkOverlap: intPtr(500), wantErr: true},
+		{name: "overlap larger than size", chunkSize: intPtr(500), chunkOverlap: intPtr(600), wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateChunking(tt.chunkSize, tt.chunkOverlap)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("validateChunking() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestTrainKnowledgeBaseRejectsInvalidChunking(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	// Invalid parameters are rejected before the knowledge base is looked up
+	for _, body := range []string{
+		`{"chunk_size":10}`,
+		`{"chunk_overlap":-5}`,
+		`{"chunk_size":500,"chunk_overlap":800}`,
+		`{"chunk_size":"large"}`,
+	} {
+		t.Run(body, func(t *testing.T) {
+			r := gin.New()
+			r.POST("/knowledge-bases/:id/train", TrainKnowledgeBase)
+			req := httptest.NewRequest(http.MethodPost, "/knowledge-bases/1/train", strings.NewReader(body))
+			req.Header.Set("Content-Type", "application/json")
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+			if w.Code != http.StatusBadRequest {
+				t.Errorf("status = %d, want %d: %s", w.Code, http.StatusBadRequest, w.Body.String())
+			}
+		})
+	}
+}
+
+func TestSearchKnowledgeBasesRejectsInvalidParams(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	// Invalid parameters are rejected before the organization is looked up
+	for _, query := range []string{"", "q=%20%20", "q=docs&limit=0", "q=docs&limit=ten", "q=docs&offset=-1"} {
+		t.Run(query, func(t *testing.T) {
+			r := gin.New()
+			r.GET("/orgs/:slug/knowledge-bases/search", SearchKnowledgeBases)
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/orgs/acme/knowledge-bases/search?"+query, nil))
+			if w.Code != http.StatusBadRequest {
+				t.Errorf("status = %d, want %d: %s", w.Code, http.StatusBadRequest, w.Body.String())
+			}
+		})
+	}
+}
+
+func TestSearchKnowledgeBases(t *testing.T) {
+	useTestDatabase(t)
+	gin.SetMode(gin.TestMode)
+	m := models.NewModels()
+	ctx := context.Background()
+
+	owner := createTestUser(t, m)
+	org := createTestOrganization(t, m, owner)
+	otherOrg := createTestOrganization(t, m, owner)
+	for _, kb := range []struct {
+		orgID int64
+		name  string
+	}{{org.ID, "Sales Handbook"}, {org.ID, "Marketing"}, {otherOrg.ID, "Support Handbook"}} {
+		if _, err := m.KnowledgeBases.Create(ctx, kb.orgID, kb.name, "", &owner.ID); err != nil {
+			t.Fatalf("failed to create knowledge base: %v", err)
+		}
+	}
+	outsider := createTestUser(t, m)
+
+	search := func(userID int64, query string) *httptest.ResponseRecorder {
+		r := gin.New()
+		r.GET("/orgs/:slug/knowledge-bases/search", func(c *gin.Context) { c.Set("user_id", userID) }, SearchKnowledgeBases)
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/orgs/"+org.Slug+"/knowledge-bases/search?"+query, nil))
+		return w
+	}
+
+	for _, tt := range []struct {
+		query     string
+		wantNames []string
+	}{
+		{"q=handbook", []string{"Sales Handbook"}},
+		{"q=finance", []string{}},
+	} {
+		t.Run(tt.query, func(t *testing.T) {
+			w := search(owner.ID, tt.query)
+			if w.Code != http.StatusOK {
+				t.Fatalf("status = %d, want %d: %s", w.Code, http.StatusOK, w.Body.String())
+			}
+			var resp struct {
+				KnowledgeBases []struct {
+					Name string `json:"name"`
+				} `json:"knowledge_bases"`
+				Total int `json:"total"`
+			}
+			if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+				t.Fatalf("failed to decode response: %v", err)
+			}
+			names := make([]string, 0, len(resp.KnowledgeBases))
+			for _, kb := range resp.KnowledgeBases {
+				names = append(names, kb.Name)
+			}
+			if fmt.Sprint(names) != fmt.Sprint(tt.wantNames) || resp.Total != len(tt.wantNames) {
+				t.Errorf("search = %v, total %d, want %v", names, resp.Total, tt.wantNames)
+			}
+		})
+	}
+
+	t.Run("non-member", func(t *testing.T) {
+		if w := search(outsider.ID, "q=handbook"); w.Code != http.StatusForbidden {
+			t.Errorf("status = %d, want %d: %s", w.Code, http.StatusForbidden, w.Body.String())
+		}
+	})
+}
+
+func TestGetKnowledgeBaseNeedsRetraining(t *testing.T) {
+	useTestDatabase(t)
+	gin.SetMode(gin.TestMode)
+	m := models.NewModels()
+	ctx := context.Background()
+
+	owner := createTestUser(t, m)
+	org := createTestOrganization(t, m, owner)
+	kb, err := m.KnowledgeBases.Create(ctx, org.ID, "Docs", "", &owner.ID)
+	if err != nil {
+		t.Fatalf("failed to create knowledge base: %v", err)
+	}
+
+	type detail struct {
+		TotalDatasets   int  `json:"total_datasets"`
+		NeedsRetraining bool `json:"needs_retraining"`
+	}
+	get := func(t *testing.T) detail {
+		t.Helper()
+		r := gin.New()
+		r.GET("/knowledge-bases/:id", GetKnowledgeBase)
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, fmt.Sprintf("/knowledge-bases/%d", kb.ID), nil))
+		if w.Code != http.StatusOK {
+			t.Fatalf("status = %d, want %d: %s", w.Code, http.StatusOK, w.Body.String())
+		}
+		var resp detail
+		if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+			t.Fatalf("failed to decode response: %v", err)
+		}
+		return resp
+	}
+
+	addTestFile(t, m, kb.ID, "intro.txt", "hello")
+	if got := get(t); !got.NeedsRetraining {
+		t.Errorf("needs_retraining = false with files but no completed version, want true")
+	}
+
+	// Training completes at the database's clock, so the file timestamps compare without skew
+	version, err := m.KnowledgeBases.CreateVersion(ctx, kb.ID, nil, nil, nil)
+	if err != nil {
+		t.Fatalf("failed to create version: %v", err)
+	}
+	var completedAt time.Time
+	if err := m.KnowledgeBases.DB.QueryRow(ctx, "SELECT NOW()").Scan(&completedAt); err != nil {
+		t.Fatalf("failed to read database time: %v", err)
+	}
+	if err := m.KnowledgeBases.UpdateVersionStatus(ctx, version.ID, "completed", &completedAt); err != nil {
+		t.Fatalf("failed to complete version: %v", err)
+	}
+	if got := get(t); got.NeedsRetraining || got.TotalDatasets != 1 {
+		t.Errorf("after training = %+v, want 1 dataset and no retraining needed", got)
+	}
+
+	addTestFile(t, m, kb.ID, "faq.txt", "questions")
+	if got := get(t); !got.NeedsRetraining || got.TotalDatasets != 2 {
+		t.Errorf("after adding a file = %+v, want 2 datasets and retraining needed", got)
+	}
+}
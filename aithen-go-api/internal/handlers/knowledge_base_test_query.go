@@ -0,0 +1,170 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/aithen/go-api/internal/models"
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	// defaultTestQuery is searched when a test query request does not provide one
+	defaultTestQuery = "What is this knowledge base about?"
+	// defaultTestQueryResults and maxTestQueryResults bound the results of a test query
+	defaultTestQueryResults = 5
+	maxTestQueryResults     = 20
+)
+
+// TestQueryRequest represents an optional query and result count for a knowledge base test query
+type TestQueryRequest struct {
+	Query string `json:"query"`
+	TopK  int    `json:"top_k"`
+}
+
+// TestKnowledgeBaseQuery runs a query against the latest completed version of a knowledge base
+// and returns the closest chunks with timings, so users can confirm it is searchable
+func TestKnowledgeBaseQuery(c *gin.Context) {
+	kbID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid knowledge base ID"})
+		return
+	}
+
+	// The body is optional; a generic query is used otherwise
+	var req TestQueryRequest
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	req.Query = strings.TrimSpace(req.Query)
+	if req.Query == "" {
+		req.Query = defaultTestQuery
+	}
+	if req.TopK < 0 || req.TopK > maxTestQueryResults {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("top_k must be between 1 and %d", maxTestQueryResults)})
+		return
+	}
+	if req.TopK == 0 {
+		req.TopK = defaultTestQueryResults
+	}
+
+	m := models.NewModels()
+	ctx := c.Request.Context()
+
+	// Search the most recent completed version (versions are ordered newest first)
+	versions, err := m.KnowledgeBases.GetAllVersions(ctx, kbID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve versions"})
+		return
+	}
+	var version *models.KnowledgeBaseVersion
+	for _, v := range versions {
+		if v.Status == "completed" {
+			version = v
+			break
+		}
+	}
+	if version == nil {
+		c.JSON(http.StatusConflict, gin.H{"error": "This knowledge base has no completed version yet. Train it before testing queries."})
+		return
+	}
+
+	embeddingCount, err := m.KnowledgeBases.CountEmbeddingsByVersion(ctx, version.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to count embeddings"})
+		return
+	}
+	if embeddingCount == 0 {
+		c.JSON(http.StatusConflict, gin.H{"error": "The latest completed version has no embeddings. Retrain the knowledge base."})
+		return
+	}
+
+	start := time.Now()
+	embedding, err := embedQuery(ctx, req.Query, version.EmbeddingModel)
+	if err != nil {
+		if respondAIUnavailable(c, err) {
+			return
+		}
+		c.JSON(http.StatusBadGateway, gin.H{"error": fmt.Sprintf("Failed to embed query: %v", err)})
+		return
+	}
+	embedDuration := time.Since(start)
+
+	searchStart := time.Now()
+	results, err := m.KnowledgeBases.SearchEmbeddings(ctx, version.ID, embedding, req.TopK)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("Failed to search knowledge base: %v", err)})
+		return
+	}
+	searchDuration := time.Since(searchStart)
+
+	c.JSON(http.StatusOK, gin.H{
+		"query":           req.Query,
+		"version":         version,
+		"embedding_count": embeddingCount,
+		"results":         results,
+		"timing": gin.H{
+			"embedding_ms": embedDuration.Milliseconds(),
+			"search_ms":    searchDuration.Milliseconds(),
+			"total_ms":     time.Since(start).Milliseconds(),
+		},
+	})
+}
+
+// embedQuery returns the embedding of a single text from the AI service. model selects the
+// embedding model, so queries match the version being searched; nil uses the service default.
+func embedQuery(ctx context.Context, text string, model *string) ([]float32, error) {
+	aiURL := fmt.Sprintf("%s/embed", getAIServiceURL())
+
+	payload := gin.H{"texts": []string{text}}
+	if model != nil {
+		payload["model"] = *model
+	}
+	reqBody, err := json.Marshal(payload)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	httpReq, err := http.NewRequestWithContext(ctx, "POST", aiURL, bytes.NewBuffer(reqBody))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+
+	resp, err := doAIRequest(httpReq)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to AI service: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("AI service returned status %d: %s", resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+
+	var result struct {
+		Embeddings [][]float32 `json:"embeddings"`
+	}
+	if err := json.Unmarshal(body, &result); err != nil {
+		return nil, fmt.Errorf("invalid AI service response: %w", err)
+	}
+	if len(result.Embeddings) == 0 || len(result.Embeddings[0]) == 0 {
+		return nil, errors.New("AI service returned no embedding")
+	}
+	return result.Embeddings[0], nil
+}
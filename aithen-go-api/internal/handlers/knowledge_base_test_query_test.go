@@ -0,0 +1,168 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aithen/go-api/internal/models"
+	"github.com/gin-gonic/gin"
+)
+
+func TestTestKnowledgeBaseQuery(t *testing.T) {
+	useTestDatabase(t)
+	gin.SetMode(gin.TestMode)
+	m := models.NewModels()
+	ctx := context.Background()
+
+	owner := createTestUser(t, m)
+	org := createTestOrganization(t, m, owner)
+
+	vector := make([]float32, 1536)
+	vector[0] = 1
+
+	// The stub AI service embeds every query as the same vector the chunks were stored with
+	var embedded []string
+	stub := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/embed" {
+			t.Errorf("AI service called at %s, want /embed", r.URL.Path)
+		}
+		var req struct {
+			Texts []string `json:"texts"`
+		}
+		json.NewDecoder(r.Body).Decode(&req)
+		embedded = append(embedded, req.Texts...)
+		json.NewEncoder(w).Encode(gin.H{"embeddings": [][]float32{vector}})
+	}))
+	defer stub.Close()
+	t.Setenv("AI_SERVICE_URL", stub.URL)
+
+	newKB := func(t *testing.T, name string) *models.KnowledgeBase {
+		t.Helper()
+		kb, err := m.KnowledgeBases.Create(ctx, org.ID, name, "", &owner.ID)
+		if err != nil {
+			t.Fatalf("failed to create knowledge base: %v", err)
+		}
+		return kb
+	}
+	completeVersion := func(t *testing.T, kbID int64) *models.KnowledgeBaseVersion {
+		t.Helper()
+		version, err := m.KnowledgeBases.CreateVersion(ctx, kbID, nil, nil, nil)
+		if err != nil {
+			t.Fatalf("failed to create version: %v", err)
+		}
+		now := time.Now()
+		if err := m.KnowledgeBases.UpdateVersionStatus(ctx, version.ID, "completed", &now); err != nil {
+			t.Fatalf("failed to complete version: %v", err)
+		}
+		return version
+	}
+
+	testQuery := func(kbID int64, body string) *httptest.ResponseRecorder {
+		r := gin.New()
+		r.POST("/knowledge-bases/:id/test-query", TestKnowledgeBaseQuery)
+		req := httptest.NewRequest(http.MethodPost, fmt.Sprintf("/knowledge-bases/%d/test-query", kbID), strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	t.Run("no completed version", func(t *testing.T) {
+		kb := newKB(t, "Untrained")
+		if _, err := m.KnowledgeBases.CreateVersion(ctx, kb.ID, nil, nil, nil); err != nil {
+			t.Fatalf("failed to create version: %v", err)
+		}
+		if w := testQuery(kb.ID, ""); w.Code != http.StatusConflict {
+			t.Errorf("status = %d, want %d: %s", w.Code, http.StatusConflict, w.Body.String())
+		}
+	})
+
+	t.Run("completed version without embeddings", func(t *testing.T) {
+		kb := newKB(t, "Empty")
+		completeVersion(t, kb.ID)
+		if w := testQuery(kb.ID, ""); w.Code != http.StatusConflict {
+			t.Errorf("status = %d, want %d: %s", w.Code, http.StatusConflict, w.Body.String())
+		}
+	})
+
+	t.Run("invalid top_k", func(t *testing.T) {
+		if w := testQuery(1, fmt.Sprintf(`{"top_k":%d}`, maxTestQueryResults+1)); w.Code != http.StatusBadRequest {
+			t.Errorf("status = %d, want %d: %s", w.Code, http.StatusBadRequest, w.Body.String())
+		}
+	})
+
+	t.Run("searches the latest completed version", func(t *testing.T) {
+		kb := newKB(t, "Trained")
+		file := addTestFile(t, m, kb.ID, "intro.txt", "hello world")
+		version, err := m.KnowledgeBases.CreateVersion(ctx, kb.ID, nil, nil, nil)
+		if err != nil {
+			t.Fatalf("failed to create version: %v", err)
+		}
+		for i, chunk := range []string{"hello", "world"} {
+			if err := m.KnowledgeBases.StoreEmbedding(ctx, kb.ID, version.ID, file.ID, i, chunk, vector, nil, false); err != nil {
+				t.Fatalf("failed to store embedding: %v", err)
+			}
+		}
+		now := time.Now()
+		if err := m.KnowledgeBases.UpdateVersionStatus(ctx, version.ID, "completed", &now); err != nil {
+			t.Fatalf("failed to complete version: %v", err)
+		}
+
+		for _, tt := range []struct {
+			name, body, query string
+			results           int
+		}{
+			{"default query", "", defaultTestQuery, 2},
+			{"provided query", `{"query":"  greetings  ","top_k":1}`, "greetings", 1},
+		} {
+			t.Run(tt.name, func(t *testing.T) {
+				embedded = nil
+				w := testQuery(kb.ID, tt.body)
+				if w.Code != http.StatusOK {
+					t.Fatalf("status = %d, want %d: %s", w.Code, http.StatusOK, w.Body.String())
+				}
+				var resp struct {
+					Query   string `json:"query"`
+					Version struct {
+						ID string `json:"id"`
+					} `json:"version"`
+					EmbeddingCount int `json:"embedding_count"`
+					Results        []struct {
+						FileName  string `json:"file_name"`
+						ChunkText string `json:"chunk_text"`
+					} `json:"results"`
+					Timing map[string]int64 `json:"timing"`
+				}
+				if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+					t.Fatalf("failed to decode response: %v", err)
+				}
+				if resp.Query != tt.query || len(embedded) != 1 || embedded[0] != tt.query {
+					t.Errorf("query = %q, embedded %q, want %q", resp.Query, embedded, tt.query)
+				}
+				if resp.Version.ID != strconv.FormatInt(version.ID, 10) || resp.EmbeddingCount != 2 {
+					t.Errorf("version %s with %d embeddings, want version %d with 2", resp.Version.ID, resp.EmbeddingCount, version.ID)
+				}
+				if len(resp.Results) != tt.results {
+					t.Fatalf("got %d results, want %d", len(resp.Results), tt.results)
+				}
+				for _, result := range resp.Results {
+					if result.FileName != "intro.txt" {
+						t.Errorf("result from %q, want intro.txt", result.FileName)
+					}
+				}
+				for _, key := range []string{"embedding_ms", "search_ms", "total_ms"} {
+					if _, ok := resp.Timing[key]; !ok {
+						t.Errorf("timing is missing %s", key)
+					}
+				}
+			})
+		}
+	})
+}
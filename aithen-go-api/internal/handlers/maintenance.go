@@ -0,0 +1,33 @@
+package handlers
+
+import (
+	"log"
+	"net/http"
+
+	"github.com/aithen/go-api/internal/middleware"
+	"github.com/gin-gonic/gin"
+)
+
+// SetMaintenanceModeRequest represents request to switch maintenance mode
+type SetMaintenanceModeRequest struct {
+	Enabled *bool `json:"enabled" binding:"required"`
+}
+
+// GetMaintenanceMode reports whether mutating requests are currently rejected
+func GetMaintenanceMode(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{"enabled": middleware.MaintenanceModeEnabled()})
+}
+
+// SetMaintenanceMode switches maintenance mode on or off for this API instance
+func SetMaintenanceMode(c *gin.Context) {
+	var req SetMaintenanceModeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	middleware.SetMaintenanceMode(*req.Enabled)
+	log.Printf("Maintenance mode set to %t by user %d", *req.Enabled, c.GetInt64("user_id"))
+
+	c.JSON(http.StatusOK, gin.H{"enabled": *req.Enabled})
+}
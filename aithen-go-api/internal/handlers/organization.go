@@ -48,3 +48,121 @@ func GetPublicOrganization(c *gin.Context) {
 	c.JSON(http.StatusOK, response)
 }
 
+// CheckSlugAvailability reports whether an organization slug is free, so registration forms can
+// check it before submitting. The slug is normalized with GenerateSlug first, and the
+// normalized form is returned since it may differ from the input.
+// This endpoint is public and rate limited per client IP.
+func CheckSlugAvailability(c *gin.Context) {
+	slug := c.Query("slug")
+	if slug == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "slug parameter is required"})
+		return
+	}
+
+	normalized := models.GenerateSlug(slug)
+
+	m := models.NewModels()
+	_, err := m.Organizations.FindBySlug(c.Request.Context(), normalized)
+	if err != nil && err != models.ErrOrganizationNotFound {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check slug"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"available":  err == models.ErrOrganizationNotFound,
+		"normalized": normalized,
+	})
+}
+
+
+// requireOrganizationRole verifies the current user is an active member of the organization
+// with one of the given roles. It writes the error response and returns false otherwise.
+func requireOrganizationRole(c *gin.Context, m *models.Models, org *models.Organization, roles ...string) bool {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return false
+	}
+
+	member, err := m.Organizations.GetMember(c.Request.Context(), org.ID, userID.(int64))
+	if err != nil {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Access denied"})
+		return false
+	}
+
+	for _, role := range roles {
+		if member.Role == role {
+			return true
+		}
+	}
+
+	c.JSON(http.StatusForbidden, gin.H{"error": "Insufficient permissions"})
+	return false
+}
+
+// GetMyOrganization returns the full organization and the current user's membership in it.
+// Users who are not active members get 403.
+func GetMyOrganization(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	m := models.NewModels()
+	ctx := c.Request.Context()
+
+	org, err := requestOrganization(c, m)
+	if err != nil {
+		if err == models.ErrOrganizationNotFound {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Organization not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve organization"})
+		return
+	}
+
+	member, err := m.Organizations.GetMembership(ctx, org.ID, userID.(int64))
+	if err != nil {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Access denied"})
+		return
+	}
+	if member.Status != "active" {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Membership is not active", "status": member.Status})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"organization": org,
+		"membership":   member,
+	})
+}
+
+// GetOrganizationStorage returns file and embedding storage usage across the organization's
+// knowledge bases. Only owners and admins may view it.
+func GetOrganizationStorage(c *gin.Context) {
+	m := models.NewModels()
+	ctx := c.Request.Context()
+
+	org, err := requestOrganization(c, m)
+	if err != nil {
+		if err == models.ErrOrganizationNotFound {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Organization not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve organization"})
+		return
+	}
+
+	if !requireOrganizationRole(c, m, org, "owner", "admin") {
+		return
+	}
+
+	stats, err := m.KnowledgeBases.GetOrganizationStorageStats(ctx, org.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve storage usage"})
+		return
+	}
+
+	c.JSON(http.StatusOK, stats)
+}
@@ -0,0 +1,294 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/aithen/go-api/internal/models"
+	"github.com/gin-gonic/gin"
+)
+
+// UpdateMemberRoleRequest represents request to change an organization member's role
+type UpdateMemberRoleRequest struct {
+	Role string `json:"role" binding:"required"`
+}
+
+// AddMemberRequest represents request to add an existing user to an organization
+type AddMemberRequest struct {
+	Email string `json:"email" binding:"required,email"`
+	Role  string `json:"role"` // Optional, defaults to the organization's default member role
+}
+
+// SetDefaultMemberRoleRequest represents request to set an organization's default member role
+type SetDefaultMemberRoleRequest struct {
+	Role *string `json:"role"` // null or empty clears it, so DEFAULT_MEMBER_ROLE applies
+}
+
+// Roles that admins may manage; only owners may grant or change admin and owner roles
+var adminManagedRoles = map[string]bool{"member": true, "viewer": true}
+
+const soleOwnerLeaveError = "You are the only owner of this organization. Make another member an owner before leaving."
+
+// AddOrganizationMember adds an existing user, found by email, as an active member of the
+// organization. Owners and admins may add members, but admins may only give the member or
+// viewer role. A user who is already a member gets 409, and an organization at its plan's
+// member limit gets 402.
+func AddOrganizationMember(c *gin.Context) {
+	var req AddMemberRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if req.Role != "" && !models.IsValidMemberRole(req.Role) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Role must be one of: owner, admin, member, viewer"})
+		return
+	}
+
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	m := models.NewModels()
+	ctx := c.Request.Context()
+
+	org, err := requestOrganization(c, m)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Organization not found"})
+		return
+	}
+
+	caller, err := m.Organizations.GetMember(ctx, org.ID, userID.(int64))
+	if err != nil {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Access denied"})
+		return
+	}
+	if caller.Role != "owner" && caller.Role != "admin" {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Insufficient permissions"})
+		return
+	}
+	if caller.Role == "admin" && req.Role != "" && !adminManagedRoles[req.Role] {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Admins can only add members and viewers"})
+		return
+	}
+
+	if !enforcePlanLimit(c, m, org.ID, memberLimit(c, m, org.ID)) {
+		return
+	}
+
+	user, err := m.Users.FindByEmail(ctx, req.Email)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return
+	}
+
+	member, err := m.Organizations.AddMember(ctx, org.ID, user.ID, req.Role, "active")
+	if err != nil {
+		if err == models.ErrAlreadyMember {
+			c.JSON(http.StatusConflict, gin.H{"error": "User is already a member of this organization"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to add member"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, member)
+}
+
+// UpdateOrganizationMemberRole promotes or demotes an active member of the organization.
+// Owners may set any role, admins may only move members between member and viewer, and the
+// organization's last owner cannot be demoted.
+func UpdateOrganizationMemberRole(c *gin.Context) {
+	targetUserID, err := strconv.ParseInt(c.Param("user_id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+
+	var req UpdateMemberRoleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if !models.IsValidMemberRole(req.Role) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Role must be one of: owner, admin, member, viewer"})
+		return
+	}
+
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	m := models.NewModels()
+	ctx := c.Request.Context()
+
+	org, err := requestOrganization(c, m)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Organization not found"})
+		return
+	}
+
+	caller, err := m.Organizations.GetMember(ctx, org.ID, userID.(int64))
+	if err != nil {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Access denied"})
+		return
+	}
+	if caller.Role != "owner" && caller.Role != "admin" {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Insufficient permissions"})
+		return
+	}
+
+	target, err := m.Organizations.GetMember(ctx, org.ID, targetUserID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User is not a member of this organization"})
+		return
+	}
+
+	if caller.Role == "admin" && (!adminManagedRoles[target.Role] || !adminManagedRoles[req.Role]) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Admins can only change roles between member and viewer"})
+		return
+	}
+
+	member, err := m.Organizations.UpdateMemberRole(ctx, org.ID, targetUserID, req.Role)
+	if err != nil {
+		switch err {
+		case models.ErrLastOwner:
+			c.JSON(http.StatusConflict, gin.H{"error": "The organization's last owner cannot be demoted"})
+		case models.ErrMemberNotFound:
+			c.JSON(http.StatusNotFound, gin.H{"error": "User is not a member of this organization"})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update member role"})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, member)
+}
+
+// LeaveOrganization removes the caller's own membership. The sole owner gets 409 and must
+// make another member an owner first, unless nobody else is an active member: then the
+// organization is deleted along with the membership.
+func LeaveOrganization(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	m := models.NewModels()
+	ctx := c.Request.Context()
+
+	org, err := requestOrganization(c, m)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Organization not found"})
+		return
+	}
+
+	member, err := m.Organizations.GetMembership(ctx, org.ID, userID.(int64))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "You are not a member of this organization"})
+		return
+	}
+
+	// RemoveMember repeats this check under lock; checking first avoids opening a transaction
+	if member.Role == "owner" && member.Status == "active" {
+		owners, err := m.Organizations.CountOwners(ctx, org.ID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to leave organization"})
+			return
+		}
+		members, err := m.Organizations.CountMembers(ctx, org.ID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to leave organization"})
+			return
+		}
+		if owners <= 1 && members > 1 {
+			c.JSON(http.StatusConflict, gin.H{"error": soleOwnerLeaveError})
+			return
+		}
+	}
+
+	deleted, err := m.Organizations.RemoveMember(ctx, org.ID, userID.(int64))
+	if err != nil {
+		switch err {
+		case models.ErrLastOwner:
+			c.JSON(http.StatusConflict, gin.H{"error": soleOwnerLeaveError})
+		case models.ErrMemberNotFound:
+			c.JSON(http.StatusNotFound, gin.H{"error": "You are not a member of this organization"})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to leave organization"})
+		}
+		return
+	}
+
+	if deleted != nil {
+		cleanupDeletedOrganization(m, *deleted)
+		c.JSON(http.StatusOK, gin.H{"message": "You have left the organization", "organization_deleted": true})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "You have left the organization"})
+}
+
+// GetOrganizationDefaultMemberRole returns the role given to members added without one
+func GetOrganizationDefaultMemberRole(c *gin.Context) {
+	m := models.NewModels()
+
+	org := findMemberOrganization(c, m, "owner", "admin", "member", "viewer")
+	if org == nil {
+		return
+	}
+
+	role, err := m.Organizations.GetDefaultMemberRole(c.Request.Context(), org.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get default member role"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"default_member_role": role})
+}
+
+// SetOrganizationDefaultMemberRole sets or clears the role given to members added without one.
+// Only owners may change it, and it cannot be owner.
+func SetOrganizationDefaultMemberRole(c *gin.Context) {
+	var req SetDefaultMemberRoleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var role *string
+	if req.Role != nil && *req.Role != "" {
+		if !models.IsJoinableMemberRole(*req.Role) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Default member role must be one of: admin, member, viewer"})
+			return
+		}
+		role = req.Role
+	}
+
+	m := models.NewModels()
+	ctx := c.Request.Context()
+
+	org := findMemberOrganization(c, m, "owner")
+	if org == nil {
+		return
+	}
+
+	if err := m.Organizations.SetDefaultMemberRole(ctx, org.ID, role); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to set default member role"})
+		return
+	}
+
+	effective, err := m.Organizations.GetDefaultMemberRole(ctx, org.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get default member role"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"default_member_role": effective})
+}
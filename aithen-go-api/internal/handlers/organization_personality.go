@@ -0,0 +1,213 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/aithen/go-api/internal/models"
+	"github.com/gin-gonic/gin"
+)
+
+// errChatOrganizationNotFound is returned when a chat request names an organization the user is
+// not an active member of
+var errChatOrganizationNotFound = errors.New("organization not found")
+
+// OrganizationPersonality is a personality as listed for an organization
+type OrganizationPersonality struct {
+	ID        string `json:"id"`
+	IsDefault bool   `json:"is_default"`
+}
+
+// SetDefaultPersonalityRequest represents request to set an organization's default personality
+type SetDefaultPersonalityRequest struct {
+	PersonalityID *string `json:"personality_id"` // null or empty clears the default
+}
+
+// findMemberOrganization returns the organization in the :slug path parameter if the current
+// user is an active member with one of the given roles. It writes the error response and
+// returns nil otherwise.
+func findMemberOrganization(c *gin.Context, m *models.Models, roles ...string) *models.Organization {
+	org, err := requestOrganization(c, m)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Organization not found"})
+		return nil
+	}
+	if !requireOrganizationRole(c, m, org, roles...) {
+		return nil
+	}
+	return org
+}
+
+// GetOrganizationPersonalities lists the personalities available in the AI service, marking the
+// organization's default
+func GetOrganizationPersonalities(c *gin.Context) {
+	m := models.NewModels()
+	ctx := c.Request.Context()
+
+	org := findMemberOrganization(c, m, "owner", "admin", "member", "viewer")
+	if org == nil {
+		return
+	}
+
+	defaultID, err := m.Organizations.GetDefaultPersonality(ctx, org.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get default personality"})
+		return
+	}
+
+	ids, err := listPersonalityIDs(ctx)
+	if err != nil {
+		if respondAIUnavailable(c, err) {
+			return
+		}
+		c.JSON(http.StatusBadGateway, gin.H{"error": err.Error()})
+		return
+	}
+
+	personalities := make([]OrganizationPersonality, len(ids))
+	for i, id := range ids {
+		personalities[i] = OrganizationPersonality{ID: id, IsDefault: defaultID != nil && *defaultID == id}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"personalities":          personalities,
+		"default_personality_id": defaultID,
+	})
+}
+
+// GetOrganizationDefaultPersonality returns the organization's default personality, or null when none is set
+func GetOrganizationDefaultPersonality(c *gin.Context) {
+	m := models.NewModels()
+
+	org := findMemberOrganization(c, m, "owner", "admin", "member", "viewer")
+	if org == nil {
+		return
+	}
+
+	defaultID, err := m.Organizations.GetDefaultPersonality(c.Request.Context(), org.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get default personality"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"default_personality_id": defaultID})
+}
+
+// SetOrganizationDefaultPersonality sets or clears the organization's default personality.
+// Only owners and admins may change it, and the personality must exist in the AI service.
+func SetOrganizationDefaultPersonality(c *gin.Context) {
+	var req SetDefaultPersonalityRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	m := models.NewModels()
+	ctx := c.Request.Context()
+
+	org := findMemberOrganization(c, m, "owner", "admin")
+	if org == nil {
+		return
+	}
+
+	var personalityID *string
+	if req.PersonalityID != nil {
+		if id := strings.TrimSpace(*req.PersonalityID); id != "" {
+			personalityID = &id
+		}
+	}
+
+	if personalityID != nil {
+		ids, err := listPersonalityIDs(ctx)
+		if err != nil {
+			if respondAIUnavailable(c, err) {
+				return
+			}
+			c.JSON(http.StatusBadGateway, gin.H{"error": err.Error()})
+			return
+		}
+		known := false
+		for _, id := range ids {
+			if id == *personalityID {
+				known = true
+				break
+			}
+		}
+		if !known {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Unknown personality"})
+			return
+		}
+	}
+
+	if err := m.Organizations.SetDefaultPersonality(ctx, org.ID, personalityID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to set default personality"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"default_personality_id": personalityID})
+}
+
+// applyDefaultPersonality fills in req.Personality when the request does not name one. An
+// organization's default comes first, then the server-wide DEFAULT_PERSONALITY; without either
+// the AI service's own default applies. The organization is the one named by req.Organization, or
+// the user's only active organization when none is named. req.Organization is cleared so it is not
+// forwarded to the AI service.
+func applyDefaultPersonality(ctx context.Context, userID int64, req *ChatRequest) error {
+	slug := req.Organization
+	req.Organization = ""
+	if req.Personality != "" {
+		return nil
+	}
+
+	personality, err := organizationDefaultPersonality(ctx, userID, slug)
+	if err != nil {
+		return err
+	}
+	if personality == "" {
+		personality = getDefaultPersonality()
+	}
+	req.Personality = personality
+	return nil
+}
+
+// organizationDefaultPersonality returns the default personality of the organization named by
+// slug, or of the user's only active organization when slug is empty. It returns "" when there
+// is no such organization or it has no default.
+func organizationDefaultPersonality(ctx context.Context, userID int64, slug string) (string, error) {
+	m := models.NewModels()
+
+	var orgID int64
+	if slug != "" {
+		org, err := m.Organizations.FindBySlug(ctx, slug)
+		if err != nil {
+			return "", errChatOrganizationNotFound
+		}
+		if _, err := m.Organizations.GetMember(ctx, org.ID, userID); err != nil {
+			return "", errChatOrganizationNotFound
+		}
+		orgID = org.ID
+	} else {
+		orgs, err := m.Organizations.GetUserOrganizations(ctx, userID)
+		if err != nil {
+			log.Printf("Warning: Failed to get organizations of user %d: %v", userID, err)
+			return "", nil
+		}
+		if len(orgs) != 1 {
+			return "", nil
+		}
+		orgID = orgs[0].ID
+	}
+
+	defaultID, err := m.Organizations.GetDefaultPersonality(ctx, orgID)
+	if err != nil {
+		log.Printf("Warning: Failed to get default personality of organization %d: %v", orgID, err)
+		return "", nil
+	}
+	if defaultID == nil {
+		return "", nil
+	}
+	return *defaultID, nil
+}
@@ -0,0 +1,93 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/aithen/go-api/internal/models"
+	"github.com/gin-gonic/gin"
+)
+
+func TestChatUsesOrganizationDefaultPersonality(t *testing.T) {
+	useTestDatabase(t)
+	gin.SetMode(gin.TestMode)
+	t.Setenv("DEFAULT_PERSONALITY", "tutor")
+	m := models.NewModels()
+	ctx := context.Background()
+
+	user := createTestUser(t, m)
+	org := createTestOrganization(t, m, user)
+	pirate := "pirate"
+	if err := m.Organizations.SetDefaultPersonality(ctx, org.ID, &pirate); err != nil {
+		t.Fatalf("failed to set default personality: %v", err)
+	}
+
+	var forwarded map[string]interface{}
+	ai := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		forwarded = nil
+		json.NewDecoder(r.Body).Decode(&forwarded)
+		io.WriteString(w, `{"response":"ok"}`)
+	}))
+	defer ai.Close()
+	t.Setenv("AI_SERVICE_URL", ai.URL)
+
+	r := gin.New()
+	r.POST("/chat", func(c *gin.Context) { c.Set("user_id", user.ID) }, Chat)
+	chat := func(t *testing.T, fields string) (int, string) {
+		t.Helper()
+		forwarded = nil
+		body := `{"messages":[{"role":"user","content":"hi"}]` + fields + `}`
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/chat", strings.NewReader(body)))
+		if w.Code != http.StatusOK {
+			return w.Code, ""
+		}
+		if _, ok := forwarded["organization"]; ok {
+			t.Errorf("forwarded request = %v, want the organization field removed", forwarded)
+		}
+		personality, _ := forwarded["personality"].(string)
+		return w.Code, personality
+	}
+
+	t.Run("user's only organization", func(t *testing.T) {
+		if status, personality := chat(t, ""); status != http.StatusOK || personality != "pirate" {
+			t.Errorf("status %d, personality %q, want the organization default pirate", status, personality)
+		}
+	})
+
+	t.Run("explicit personality wins", func(t *testing.T) {
+		if status, personality := chat(t, `,"personality":"coach"`); status != http.StatusOK || personality != "coach" {
+			t.Errorf("status %d, personality %q, want coach", status, personality)
+		}
+	})
+
+	// With a second organization the default only applies when the request names one
+	createTestOrganization(t, m, user)
+
+	t.Run("named organization", func(t *testing.T) {
+		if status, personality := chat(t, `,"organization":"`+org.Slug+`"`); status != http.StatusOK || personality != "pirate" {
+			t.Errorf("status %d, personality %q, want the organization default pirate", status, personality)
+		}
+	})
+
+	t.Run("ambiguous organization uses the server default", func(t *testing.T) {
+		if status, personality := chat(t, ""); status != http.StatusOK || personality != "tutor" {
+			t.Errorf("status %d, personality %q, want the server default tutor", status, personality)
+		}
+	})
+
+	t.Run("organization the user is not in", func(t *testing.T) {
+		other := createTestOrganization(t, m, createTestUser(t, m))
+		if status, _ := chat(t, `,"organization":"`+other.Slug+`"`); status != http.StatusNotFound {
+			t.Errorf("status = %d, want %d", status, http.StatusNotFound)
+		}
+		if forwarded != nil {
+			t.Errorf("AI service was called with %v, want the request rejected first", forwarded)
+		}
+	})
+}
@@ -0,0 +1,29 @@
+package handlers
+
+import (
+	"github.com/aithen/go-api/internal/models"
+	"github.com/gin-gonic/gin"
+)
+
+// organizationSlug returns the slug of the organization a request is scoped to: the one
+// resolved by middleware.ResolveOrganization from the X-Org-Slug header or subdomain, falling
+// back to the :slug path param. Org-scoped handlers use it instead of c.Param("slug") so their
+// routes also work without the slug in the path.
+func organizationSlug(c *gin.Context) string {
+	if slug := c.GetString("organization_slug"); slug != "" {
+		return slug
+	}
+	return c.Param("slug")
+}
+
+// requestOrganization returns the organization a request is scoped to. The one already
+// resolved by middleware.ResolveOrganization is reused; otherwise it is looked up by
+// organizationSlug, and an unknown slug gives models.ErrOrganizationNotFound.
+func requestOrganization(c *gin.Context, m *models.Models) (*models.Organization, error) {
+	if org, ok := c.Get("organization"); ok {
+		if org, ok := org.(*models.Organization); ok {
+			return org, nil
+		}
+	}
+	return m.Organizations.FindBySlug(c.Request.Context(), organizationSlug(c))
+}
@@ -0,0 +1,135 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/aithen/go-api/internal/id"
+	"github.com/aithen/go-api/internal/models"
+	"github.com/gin-gonic/gin"
+)
+
+func TestGetMyOrganization(t *testing.T) {
+	useTestDatabase(t)
+	gin.SetMode(gin.TestMode)
+	m := models.NewModels()
+	ctx := context.Background()
+
+	owner := createTestUser(t, m)
+	org := createTestOrganization(t, m, owner)
+	admin := addTestMember(t, m, org, "admin")
+	invited := createTestUser(t, m)
+	if _, err := m.Organizations.AddMember(ctx, org.ID, invited.ID, "member", "invited"); err != nil {
+		t.Fatalf("failed to invite member: %v", err)
+	}
+	outsider := createTestUser(t, m)
+
+	tests := []struct {
+		name       string
+		userID     int64
+		slug       string
+		wantStatus int
+		wantRole   string
+	}{
+		{name: "owner", userID: owner.ID, slug: org.Slug, wantStatus: http.StatusOK, wantRole: "owner"},
+		{name: "admin", userID: admin.ID, slug: org.Slug, wantStatus: http.StatusOK, wantRole: "admin"},
+		{name: "invited member", userID: invited.ID, slug: org.Slug, wantStatus: http.StatusForbidden},
+		{name: "non-member", userID: outsider.ID, slug: org.Slug, wantStatus: http.StatusForbidden},
+		{name: "unknown organization", userID: owner.ID, slug: "no-such-org", wantStatus: http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := gin.New()
+			r.GET("/orgs/:slug/me", func(c *gin.Context) { c.Set("user_id", tt.userID) }, GetMyOrganization)
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/orgs/"+tt.slug+"/me", nil))
+			if w.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d: %s", w.Code, tt.wantStatus, w.Body.String())
+			}
+			if tt.wantStatus != http.StatusOK {
+				return
+			}
+
+			var resp struct {
+				Organization struct {
+					ID   string `json:"id"`
+					Slug string `json:"slug"`
+				} `json:"organization"`
+				Membership struct {
+					Role     string `json:"role"`
+					Status   string `json:"status"`
+					JoinedAt string `json:"joined_at"`
+				} `json:"membership"`
+			}
+			if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+				t.Fatalf("failed to decode response: %v", err)
+			}
+			if resp.Organization.Slug != org.Slug || resp.Organization.ID == "" {
+				t.Errorf("organization = %+v, want %s", resp.Organization, org.Slug)
+			}
+			if resp.Membership.Role != tt.wantRole || resp.Membership.Status != "active" || resp.Membership.JoinedAt == "" {
+				t.Errorf("membership = %+v, want an active %s membership", resp.Membership, tt.wantRole)
+			}
+		})
+	}
+}
+
+func TestCheckSlugAvailability(t *testing.T) {
+	useTestDatabase(t)
+	gin.SetMode(gin.TestMode)
+	m := models.NewModels()
+
+	owner := createTestUser(t, m)
+	org := createTestOrganization(t, m, owner)
+	free := fmt.Sprintf("free-org-%d", id.Generate())
+
+	tests := []struct {
+		name           string
+		slug           string
+		wantAvailable  bool
+		wantNormalized string
+	}{
+		{name: "taken", slug: org.Slug, wantAvailable: false, wantNormalized: org.Slug},
+		{name: "free", slug: free, wantAvailable: true, wantNormalized: free},
+		{name: "normalizes to a taken slug", slug: " " + strings.ToUpper(strings.ReplaceAll(org.Slug, "-", "_")) + "! ", wantAvailable: false, wantNormalized: org.Slug},
+		{name: "normalizes to a free slug", slug: strings.ReplaceAll(free, "-", " "), wantAvailable: true, wantNormalized: free},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := gin.New()
+			r.GET("/api/orgs/slug-available", CheckSlugAvailability)
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/orgs/slug-available?slug="+url.QueryEscape(tt.slug), nil))
+			if w.Code != http.StatusOK {
+				t.Fatalf("status = %d, want %d: %s", w.Code, http.StatusOK, w.Body.String())
+			}
+
+			var resp struct {
+				Available  bool   `json:"available"`
+				Normalized string `json:"normalized"`
+			}
+			if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+				t.Fatalf("failed to decode response: %v", err)
+			}
+			if resp.Available != tt.wantAvailable || resp.Normalized != tt.wantNormalized {
+				t.Errorf("slug %q = available %v as %q, want %v as %q", tt.slug, resp.Available, resp.Normalized, tt.wantAvailable, tt.wantNormalized)
+			}
+		})
+	}
+
+	t.Run("missing slug", func(t *testing.T) {
+		r := gin.New()
+		r.GET("/api/orgs/slug-available", CheckSlugAvailability)
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/orgs/slug-available", nil))
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("status = %d, want %d", w.Code, http.StatusBadRequest)
+		}
+	})
+}
@@ -0,0 +1,56 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/aithen/go-api/internal/models"
+	"github.com/gin-gonic/gin"
+)
+
+// Resources a caller may not access are reported as not found rather than forbidden, so
+// responses do not reveal which IDs exist: a chat owned by another user, or a knowledge base
+// in an organization the caller does not belong to, gets the same 404 as a missing one.
+// 403 is kept for callers who can see a resource but lack the role or permission for the action.
+
+// chatContextKey is the context key under which ResolveChat stores the chat
+const chatContextKey = "chat"
+
+// ResolveChat returns middleware that loads the chat in the :id path parameter and stores it
+// in the context for the handlers that follow, which read it with currentChat. It responds
+// with 400 for an invalid ID, with 404 whether the chat is missing or owned by someone else,
+// and with 500 when the chat cannot be loaded.
+func ResolveChat() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID, exists := c.Get("user_id")
+		if !exists {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+			return
+		}
+
+		chatID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": "Invalid chat ID"})
+			return
+		}
+
+		chat, err := models.NewModels().Chats.FindByID(c.Request.Context(), chatID)
+		if err != nil && !errors.Is(err, models.ErrChatNotFound) {
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "Failed to get chat"})
+			return
+		}
+		if err != nil || chat.UserID != userID.(int64) {
+			c.AbortWithStatusJSON(http.StatusNotFound, gin.H{"error": "Chat not found"})
+			return
+		}
+
+		c.Set(chatContextKey, chat)
+		c.Next()
+	}
+}
+
+// currentChat returns the chat stored by ResolveChat
+func currentChat(c *gin.Context) *models.Chat {
+	return c.MustGet(chatContextKey).(*models.Chat)
+}
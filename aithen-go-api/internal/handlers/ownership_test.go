@@ -0,0 +1,244 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/aithen/go-api/internal/db"
+	"github.com/aithen/go-api/internal/id"
+	"github.com/aithen/go-api/internal/models"
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// chatRouter serves GET /chats/:id behind ResolveChat as the given user (0 for none)
+func chatRouter(userID int64) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+
+	r := gin.New()
+	r.Use(func(c *gin.Context) {
+		if userID != 0 {
+			c.Set("user_id", userID)
+		}
+	})
+	r.GET("/chats/:id", ResolveChat(), func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{"id": currentChat(c).ID})
+	})
+	return r
+}
+
+func TestResolveChatRejectsBadRequests(t *testing.T) {
+	tests := []struct {
+		name   string
+		userID int64
+		path   string
+		want   int
+	}{
+		{name: "unauthenticated", userID: 0, path: "/chats/1", want: http.StatusUnauthorized},
+		{name: "invalid chat ID", userID: 1, path: "/chats/abc", want: http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			chatRouter(tt.userID).ServeHTTP(w, httptest.NewRequest(http.MethodGet, tt.path, nil))
+			if w.Code != tt.want {
+				t.Errorf("status = %d, want %d", w.Code, tt.want)
+			}
+		})
+	}
+}
+
+func TestResolveChatDatabaseError(t *testing.T) {
+	// Nothing listens on port 1, so every query fails with a connection error
+	pool, err := pgxpool.New(context.Background(), "postgres://test@127.0.0.1:1/test?connect_timeout=1")
+	if err != nil {
+		t.Fatalf("failed to create pool: %v", err)
+	}
+	saved := db.DB
+	db.DB = pool
+	t.Cleanup(func() {
+		db.DB = saved
+		pool.Close()
+	})
+
+	w := httptest.NewRecorder()
+	chatRouter(1).ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/chats/1", nil))
+	if w.Code != http.StatusInternalServerError {
+		t.Errorf("status = %d, want %d", w.Code, http.StatusInternalServerError)
+	}
+}
+
+// useTestDatabase points db.DB at the migrated database in TEST_DATABASE_URL for the duration
+// of the test, skipping it when unset
+func useTestDatabase(t *testing.T) {
+	t.Helper()
+
+	url := os.Getenv("TEST_DATABASE_URL")
+	if url == "" {
+		t.Skip("TEST_DATABASE_URL not set")
+	}
+	pool, err := pgxpool.New(context.Background(), url)
+	if err != nil {
+		t.Fatalf("failed to connect to test database: %v", err)
+	}
+
+	saved := db.DB
+	db.DB = pool
+	t.Cleanup(func() {
+		db.DB = saved
+		pool.Close()
+	})
+}
+
+// createTestUser creates a user that is deleted, with their chats, when the test ends
+func createTestUser(t *testing.T, m *models.Models) *models.User {
+	t.Helper()
+
+	user, err := m.Users.Create(context.Background(), fmt.Sprintf("user-%d@example.com", id.Generate()), "Test User", "password123")
+	if err != nil {
+		t.Fatalf("failed to create user: %v", err)
+	}
+	t.Cleanup(func() { m.Users.DeleteAccount(context.Background(), user.ID) })
+	return user
+}
+
+func TestResolveChatOwnership(t *testing.T) {
+	useTestDatabase(t)
+	m := models.NewModels()
+
+	owner := createTestUser(t, m)
+	other := createTestUser(t, m)
+	chat, err := m.Chats.Create(context.Background(), owner.ID, "Private", nil)
+	if err != nil {
+		t.Fatalf("failed to create chat: %v", err)
+	}
+
+	tests := []struct {
+		name   string
+		userID int64
+		chatID int64
+		want   int
+	}{
+		{name: "owner", userID: owner.ID, chatID: chat.ID, want: http.StatusOK},
+		{name: "another user's chat", userID: other.ID, chatID: chat.ID, want: http.StatusNotFound},
+		{name: "missing chat", userID: owner.ID, chatID: id.Generate(), want: http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			path := fmt.Sprintf("/chats/%d", tt.chatID)
+			chatRouter(tt.userID).ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+			if w.Code != tt.want {
+				t.Errorf("status = %d, want %d", w.Code, tt.want)
+			}
+		})
+	}
+}
+
+func TestForeignChatsReportedAsMissing(t *testing.T) {
+	useTestDatabase(t)
+	gin.SetMode(gin.TestMode)
+	m := models.NewModels()
+	ctx := context.Background()
+
+	owner := createTestUser(t, m)
+	other := createTestUser(t, m)
+	chat, err := m.Chats.Create(ctx, owner.ID, "Private", nil)
+	if err != nil {
+		t.Fatalf("failed to create chat: %v", err)
+	}
+	message, err := m.Chats.AddMessage(ctx, chat.ID, "user", "secret", nil, nil)
+	if err != nil {
+		t.Fatalf("failed to add message: %v", err)
+	}
+
+	// The chat routes as registered by the router, served as the other user
+	r := gin.New()
+	r.Use(func(c *gin.Context) { c.Set("user_id", other.ID) })
+	group := r.Group("/chats/:id", ResolveChat())
+	group.GET("", GetChat)
+	group.PUT("", UpdateChat)
+	group.DELETE("", DeleteChat)
+	group.POST("/messages", AddMessage)
+	group.GET("/messages/:message_id", GetMessage)
+	group.POST("/messages/bulk-delete", BulkDeleteMessages)
+	group.POST("/branch", BranchChat)
+	group.POST("/copy", CopyChat)
+	group.POST("/regenerate", RegenerateMessage)
+
+	routes := []struct{ method, path, body string }{
+		{http.MethodGet, "", ""},
+		{http.MethodPut, "", `{"title":"Mine now"}`},
+		{http.MethodDelete, "", ""},
+		{http.MethodPost, "/messages", `{"role":"user","content":"hi"}`},
+		{http.MethodGet, fmt.Sprintf("/messages/%d", message.ID), ""},
+		{http.MethodPost, "/messages/bulk-delete", fmt.Sprintf(`{"message_ids":["%d"]}`, message.ID)},
+		{http.MethodPost, "/branch", fmt.Sprintf(`{"message_id":"%d"}`, message.ID)},
+		{http.MethodPost, "/copy", ""},
+		{http.MethodPost, "/regenerate", ""},
+	}
+	serve := func(method, path, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	for _, route := range routes {
+		t.Run(route.method+" "+route.path, func(t *testing.T) {
+			foreign := serve(route.method, fmt.Sprintf("/chats/%d%s", chat.ID, route.path), route.body)
+			missing := serve(route.method, fmt.Sprintf("/chats/%d%s", id.Generate(), route.path), route.body)
+			if foreign.Code != http.StatusNotFound {
+				t.Errorf("status = %d, want %d: %s", foreign.Code, http.StatusNotFound, foreign.Body.String())
+			}
+			if foreign.Body.String() != missing.Body.String() {
+				t.Errorf("body = %s, want the same body as a missing chat: %s", foreign.Body.String(), missing.Body.String())
+			}
+		})
+	}
+
+	unchanged, err := m.Chats.FindByID(ctx, chat.ID)
+	if err != nil || unchanged.Title != "Private" {
+		t.Errorf("chat after foreign requests = %+v (err %v), want it unchanged", unchanged, err)
+	}
+	if _, err := m.Chats.GetMessageByID(ctx, chat.ID, message.ID); err != nil {
+		t.Errorf("message after foreign requests: %v, want it kept", err)
+	}
+}
+
+func TestForeignKnowledgeBasesReportedAsMissing(t *testing.T) {
+	useTestDatabase(t)
+	gin.SetMode(gin.TestMode)
+	m := models.NewModels()
+
+	owner := createTestUser(t, m)
+	org := createTestOrganization(t, m, owner)
+	kb, err := m.KnowledgeBases.Create(context.Background(), org.ID, "Docs", "", &owner.ID)
+	if err != nil {
+		t.Fatalf("failed to create knowledge base: %v", err)
+	}
+	outsider := createTestUser(t, m)
+
+	r := gin.New()
+	r.Use(func(c *gin.Context) { c.Set("user_id", outsider.ID) })
+	r.GET("/orgs/:slug/knowledge-bases/:id", RequireKBPermission(models.KBPermissionRead), GetKnowledgeBase)
+
+	get := func(kbID int64) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, fmt.Sprintf("/orgs/%s/knowledge-bases/%d", org.Slug, kbID), nil))
+		return w
+	}
+	foreign, missing := get(kb.ID), get(id.Generate())
+	if foreign.Code != http.StatusNotFound {
+		t.Errorf("status = %d, want %d: %s", foreign.Code, http.StatusNotFound, foreign.Body.String())
+	}
+	if foreign.Body.String() != missing.Body.String() {
+		t.Errorf("body = %s, want the same body as a missing knowledge base: %s", foreign.Body.String(), missing.Body.String())
+	}
+}
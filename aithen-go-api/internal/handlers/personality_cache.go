@@ -0,0 +1,175 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/aithen/go-api/internal/config"
+	"github.com/gin-gonic/gin"
+)
+
+// personalitiesListKey is the cache key for the full personalities list
+const personalitiesListKey = "list"
+
+// cachedResponse is an upstream response body stored in the personalities cache
+type cachedResponse struct {
+	body      []byte
+	fetchedAt time.Time
+}
+
+// PersonalityCache caches personality responses from the AI service in memory
+type PersonalityCache struct {
+	entries map[string]cachedResponse
+	ttl     time.Duration
+	mu      sync.RWMutex
+}
+
+var (
+	personalityCacheInstance *PersonalityCache
+	personalityCacheOnce     sync.Once
+)
+
+// GetPersonalityCache returns the singleton personalities cache.
+// The TTL is read from PERSONALITIES_CACHE_TTL in seconds (default 300).
+func GetPersonalityCache() *PersonalityCache {
+	personalityCacheOnce.Do(func() {
+		personalityCacheInstance = &PersonalityCache{
+			entries: make(map[string]cachedResponse),
+			ttl:     time.Duration(config.GetEnvInt("PERSONALITIES_CACHE_TTL", 300)) * time.Second,
+		}
+	})
+	return personalityCacheInstance
+}
+
+// get returns the cached entry for key and whether it is still fresh
+func (pc *PersonalityCache) get(key string) (cachedResponse, bool, bool) {
+	pc.mu.RLock()
+	defer pc.mu.RUnlock()
+
+	entry, ok := pc.entries[key]
+	if !ok {
+		return cachedResponse{}, false, false
+	}
+	return entry, true, time.Since(entry.fetchedAt) < pc.ttl
+}
+
+// set stores a response body for key
+func (pc *PersonalityCache) set(key string, body []byte) {
+	pc.mu.Lock()
+	pc.entries[key] = cachedResponse{body: body, fetchedAt: time.Now()}
+	pc.mu.Unlock()
+}
+
+// Invalidate removes all cached personality responses
+func (pc *PersonalityCache) Invalidate() {
+	pc.mu.Lock()
+	pc.entries = make(map[string]cachedResponse)
+	pc.mu.Unlock()
+}
+
+// serve answers the request from the cache when fresh, otherwise fetches from the AI service.
+// Stale entries are served if the AI service is unavailable. Setting the X-Cache-Bypass
+// header forces a fetch from the AI service.
+func (pc *PersonalityCache) serve(c *gin.Context, key, aiURL string) {
+	ctx := c.Request.Context()
+	status, body, cacheStatus, err := pc.lookup(ctx, key, aiURL, c.GetHeader("X-Cache-Bypass") != "")
+	if ctx.Err() != nil {
+		// Client disconnected while the AI service was being queried
+		c.Abort()
+		return
+	}
+	if err != nil {
+		if respondAIUnavailable(c, err) {
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.Header("X-Cache", cacheStatus)
+	c.Data(status, "application/json", body)
+}
+
+// lookup returns the response for key from the cache when fresh, otherwise from the AI service,
+// along with its X-Cache status (HIT, MISS or STALE). Stale entries are returned if the AI
+// service is unavailable; bypass skips fresh entries.
+func (pc *PersonalityCache) lookup(ctx context.Context, key, aiURL string, bypass bool) (int, []byte, string, error) {
+	entry, found, fresh := pc.get(key)
+	if found && fresh && !bypass {
+		return http.StatusOK, entry.body, "HIT", nil
+	}
+
+	status, body, err := fetchPersonalities(ctx, aiURL)
+	if ctx.Err() != nil {
+		return 0, nil, "", ctx.Err()
+	}
+	if err != nil || status >= http.StatusInternalServerError {
+		if found {
+			// Serve stale data while the AI service is down
+			log.Printf("Warning: Serving stale personalities for %q: status=%d err=%v", key, status, err)
+			return http.StatusOK, entry.body, "STALE", nil
+		}
+		if err != nil {
+			return 0, nil, "", err
+		}
+	}
+
+	// Only successful responses are cached
+	if status == http.StatusOK {
+		pc.set(key, body)
+	}
+
+	return status, body, "MISS", nil
+}
+
+// listPersonalityIDs returns the IDs of the personalities available in the AI service, using the cache
+func listPersonalityIDs(ctx context.Context) ([]string, error) {
+	aiURL := fmt.Sprintf("%s/personalities", getAIServiceURL())
+	status, body, _, err := GetPersonalityCache().lookup(ctx, personalitiesListKey, aiURL, false)
+	if err != nil {
+		return nil, err
+	}
+	if status != http.StatusOK {
+		return nil, fmt.Errorf("AI service returned status %d", status)
+	}
+
+	var ids []string
+	if err := json.Unmarshal(body, &ids); err != nil {
+		return nil, fmt.Errorf("invalid personalities response: %w", err)
+	}
+	return ids, nil
+}
+
+// fetchPersonalities performs a GET against the AI service and returns the status and body.
+// The request is cancelled when ctx is done.
+func fetchPersonalities(ctx context.Context, aiURL string) (int, []byte, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", aiURL, nil)
+	if err != nil {
+		return 0, nil, fmt.Errorf("Failed to create request: %v", err)
+	}
+
+	resp, err := doAIRequest(req)
+	if err != nil {
+		return 0, nil, fmt.Errorf("Failed to connect to AI service: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return 0, nil, fmt.Errorf("Failed to read response")
+	}
+
+	return resp.StatusCode, body, nil
+}
+
+// InvalidatePersonalityCache clears the cached personalities so the next request hits the AI service
+func InvalidatePersonalityCache(c *gin.Context) {
+	GetPersonalityCache().Invalidate()
+	c.JSON(http.StatusOK, gin.H{"message": "Personality cache cleared"})
+}
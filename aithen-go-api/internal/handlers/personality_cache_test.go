@@ -0,0 +1,73 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestPersonalityCacheServe(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	var calls atomic.Int32
+	var failing atomic.Bool
+	ai := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		if failing.Load() {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.Write([]byte(`["default","tutor"]`))
+	}))
+	defer ai.Close()
+
+	pc := &PersonalityCache{entries: make(map[string]cachedResponse), ttl: time.Hour}
+	r := gin.New()
+	r.GET("/personalities", func(c *gin.Context) {
+		pc.serve(c, personalitiesListKey, ai.URL+"/personalities")
+	})
+
+	steps := []struct {
+		name      string
+		bypass    bool
+		expire    bool
+		fail      bool
+		wantCache string
+		wantCalls int32
+	}{
+		{name: "first call fetches", wantCache: "MISS", wantCalls: 1},
+		{name: "second call is served from the cache", wantCache: "HIT", wantCalls: 1},
+		{name: "bypass header forces a refresh", bypass: true, wantCache: "MISS", wantCalls: 2},
+		{name: "expired entry is served stale when the AI service fails", expire: true, fail: true, wantCache: "STALE", wantCalls: 3},
+	}
+	for _, step := range steps {
+		if step.expire {
+			pc.ttl = 0
+		}
+		failing.Store(step.fail)
+
+		req := httptest.NewRequest(http.MethodGet, "/personalities", nil)
+		if step.bypass {
+			req.Header.Set("X-Cache-Bypass", "1")
+		}
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+
+		if w.Code != http.StatusOK {
+			t.Errorf("%s: status = %d, want %d", step.name, w.Code, http.StatusOK)
+		}
+		if got := w.Header().Get("X-Cache"); got != step.wantCache {
+			t.Errorf("%s: X-Cache = %q, want %q", step.name, got, step.wantCache)
+		}
+		if got := w.Body.String(); got != `["default","tutor"]` {
+			t.Errorf("%s: body = %q, want the cached personalities", step.name, got)
+		}
+		if got := calls.Load(); got != step.wantCalls {
+			t.Errorf("%s: AI service called %d times, want %d", step.name, got, step.wantCalls)
+		}
+	}
+}
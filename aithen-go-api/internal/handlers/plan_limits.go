@@ -0,0 +1,151 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/aithen/go-api/internal/config"
+	"github.com/aithen/go-api/internal/limits"
+	"github.com/aithen/go-api/internal/models"
+	"github.com/gin-gonic/gin"
+)
+
+// checkPlanLimit runs check against the organization's plan limits and returns the plan.
+// A *limits.LimitError is returned if a limit would be exceeded.
+func checkPlanLimit(c *gin.Context, m *models.Models, orgID int64, check func(l limits.Limits) error) (string, error) {
+	plan, err := m.Organizations.GetPlan(c.Request.Context(), orgID)
+	if err != nil {
+		return "", err
+	}
+	return plan, check(limits.ForPlan(plan))
+}
+
+// enforcePlanLimit runs check against the organization's plan limits. If a limit would be
+// exceeded it responds with 402 Payment Required naming the limit and returns false.
+func enforcePlanLimit(c *gin.Context, m *models.Models, orgID int64, check func(l limits.Limits) error) bool {
+	plan, err := checkPlanLimit(c, m, orgID, check)
+	if err == nil {
+		return true
+	}
+	if respondLimitError(c, plan, err) {
+		return false
+	}
+
+	c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check plan limits"})
+	return false
+}
+
+// respondLimitError writes a 402 naming the limit if err is a *limits.LimitError and reports
+// whether it did
+func respondLimitError(c *gin.Context, plan string, err error) bool {
+	var limitErr *limits.LimitError
+	if !errors.As(err, &limitErr) {
+		return false
+	}
+	c.JSON(http.StatusPaymentRequired, gin.H{
+		"error": "Plan limit reached",
+		"limit": limitErr.Limit,
+		"max":   limitErr.Max,
+		"plan":  plan,
+	})
+	return true
+}
+
+// uploadLimits returns the organization's plan and the limits its knowledge bases' files are
+// stored under: the plan's limits, with MAX_FILES_PER_KB applied when it is lower. The
+// handlers check the whole request up front for a clear response; models.AddFile checks
+// each file again under lock, so concurrent requests cannot both slip under a limit.
+func uploadLimits(c *gin.Context, m *models.Models, orgID int64) (string, limits.Limits, error) {
+	plan, err := m.Organizations.GetPlan(c.Request.Context(), orgID)
+	if err != nil {
+		return "", limits.Limits{}, err
+	}
+	l := limits.ForPlan(plan)
+	if max := int64(config.GetEnvInt("MAX_FILES_PER_KB", 0)); max > 0 && (l.MaxFilesPerKB == limits.Unlimited || max < l.MaxFilesPerKB) {
+		l.MaxFilesPerKB = max
+	}
+	return plan, l, nil
+}
+
+// knowledgeBaseLimit checks that the organization can create another knowledge base
+func knowledgeBaseLimit(c *gin.Context, m *models.Models, orgID int64) func(l limits.Limits) error {
+	return func(l limits.Limits) error {
+		count, err := m.KnowledgeBases.CountByOrganization(c.Request.Context(), orgID)
+		if err != nil {
+			return err
+		}
+		return l.CheckKnowledgeBases(count)
+	}
+}
+
+// storageLimit checks that the organization can store additional bytes
+func storageLimit(c *gin.Context, m *models.Models, orgID, additional int64) func(l limits.Limits) error {
+	return func(l limits.Limits) error {
+		used, err := m.KnowledgeBases.GetOrganizationStorageSize(c.Request.Context(), orgID)
+		if err != nil {
+			return err
+		}
+		return l.CheckStorage(used, additional)
+	}
+}
+
+// memberLimit checks that the organization can add another active member
+func memberLimit(c *gin.Context, m *models.Models, orgID int64) func(l limits.Limits) error {
+	return func(l limits.Limits) error {
+		count, err := m.Organizations.CountMembers(c.Request.Context(), orgID)
+		if err != nil {
+			return err
+		}
+		return l.CheckMembers(count)
+	}
+}
+
+// trainingLimit checks that the organization can start another training run
+func trainingLimit(c *gin.Context, m *models.Models, orgID int64) func(l limits.Limits) error {
+	return func(l limits.Limits) error {
+		running, err := m.KnowledgeBases.CountTrainingByOrganization(c.Request.Context(), orgID)
+		if err != nil {
+			return err
+		}
+		return l.CheckConcurrentTrainings(running)
+	}
+}
+
+// enforceFileCountLimit checks that additional files fit in a knowledge base that already has
+// existing files. The deployment-wide MAX_FILES_PER_KB cap (default 0, none) is answered with
+// 400 and the plan's limit with 402; both responses include the current and incoming counts.
+func enforceFileCountLimit(c *gin.Context, m *models.Models, orgID, existing, additional int64) bool {
+	if max := int64(config.GetEnvInt("MAX_FILES_PER_KB", 0)); max > 0 && existing+additional > max {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":      fmt.Sprintf("A knowledge base can hold at most %d files", max),
+			"file_count": existing,
+			"incoming":   additional,
+			"max":        max,
+		})
+		return false
+	}
+
+	plan, err := checkPlanLimit(c, m, orgID, func(l limits.Limits) error {
+		return l.CheckFilesPerKB(existing, additional)
+	})
+	if err == nil {
+		return true
+	}
+
+	var limitErr *limits.LimitError
+	if errors.As(err, &limitErr) {
+		c.JSON(http.StatusPaymentRequired, gin.H{
+			"error":      "Plan limit reached",
+			"limit":      limitErr.Limit,
+			"max":        limitErr.Max,
+			"plan":       plan,
+			"file_count": existing,
+			"incoming":   additional,
+		})
+		return false
+	}
+
+	c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check plan limits"})
+	return false
+}
@@ -0,0 +1,122 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/aithen/go-api/internal/models"
+	"github.com/gin-gonic/gin"
+)
+
+func TestUploadFileCountLimit(t *testing.T) {
+	useTestDatabase(t)
+	gin.SetMode(gin.TestMode)
+	t.Setenv("UPLOAD_DIR", t.TempDir())
+	m := models.NewModels()
+	ctx := context.Background()
+
+	owner := createTestUser(t, m)
+	org := createTestOrganization(t, m, owner)
+
+	// newKB creates a knowledge base with n files in the free plan organization
+	newKB := func(t *testing.T, n int) *models.KnowledgeBase {
+		t.Helper()
+		kb, err := m.KnowledgeBases.Create(ctx, org.ID, "Docs", "", &owner.ID)
+		if err != nil {
+			t.Fatalf("failed to create knowledge base: %v", err)
+		}
+		for i := 0; i < n; i++ {
+			addTestFile(t, m, kb.ID, fmt.Sprintf("existing-%d.txt", i), "hello")
+		}
+		return kb
+	}
+	upload := func(kbID int64, n int) *httptest.ResponseRecorder {
+		var body bytes.Buffer
+		mw := multipart.NewWriter(&body)
+		for i := 0; i < n; i++ {
+			part, _ := mw.CreateFormFile("files", fmt.Sprintf("new-%d.txt", i))
+			part.Write([]byte("new content"))
+		}
+		mw.Close()
+
+		r := gin.New()
+		r.POST("/knowledge-bases/:id/files", func(c *gin.Context) { c.Set("user_id", owner.ID) }, UploadKnowledgeBaseFiles)
+		req := httptest.NewRequest(http.MethodPost, fmt.Sprintf("/knowledge-bases/%d/files", kbID), &body)
+		req.Header.Set("Content-Type", mw.FormDataContentType())
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+	type limitResponse struct {
+		Error     string `json:"error"`
+		FileCount int64  `json:"file_count"`
+		Incoming  int64  `json:"incoming"`
+		Max       int64  `json:"max"`
+	}
+	fileCount := func(t *testing.T, kbID int64) int {
+		t.Helper()
+		count, err := m.KnowledgeBases.GetFileCount(ctx, kbID)
+		if err != nil {
+			t.Fatalf("GetFileCount() error = %v", err)
+		}
+		return count
+	}
+
+	t.Run("past the deployment cap", func(t *testing.T) {
+		t.Setenv("MAX_FILES_PER_KB", "3")
+		kb := newKB(t, 2)
+
+		w := upload(kb.ID, 2)
+		if w.Code != http.StatusBadRequest {
+			t.Fatalf("status = %d, want %d: %s", w.Code, http.StatusBadRequest, w.Body.String())
+		}
+		var resp limitResponse
+		if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+			t.Fatalf("failed to decode response: %v", err)
+		}
+		if resp.Error == "" || resp.FileCount != 2 || resp.Incoming != 2 || resp.Max != 3 {
+			t.Errorf("response = %+v, want an error with 2 files, 2 incoming and a max of 3", resp)
+		}
+		if n := fileCount(t, kb.ID); n != 2 {
+			t.Errorf("knowledge base has %d files, want the 2 it had", n)
+		}
+	})
+
+	t.Run("up to the deployment cap", func(t *testing.T) {
+		t.Setenv("MAX_FILES_PER_KB", "3")
+		kb := newKB(t, 2)
+
+		if w := upload(kb.ID, 1); w.Code != http.StatusCreated {
+			t.Fatalf("status = %d, want %d: %s", w.Code, http.StatusCreated, w.Body.String())
+		}
+		if n := fileCount(t, kb.ID); n != 3 {
+			t.Errorf("knowledge base has %d files, want 3", n)
+		}
+	})
+
+	t.Run("past the plan limit", func(t *testing.T) {
+		t.Setenv("MAX_FILES_PER_KB", "0")
+		kb := newKB(t, 49)
+
+		w := upload(kb.ID, 2)
+		if w.Code != http.StatusPaymentRequired {
+			t.Fatalf("status = %d, want %d: %s", w.Code, http.StatusPaymentRequired, w.Body.String())
+		}
+		var resp limitResponse
+		if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+			t.Fatalf("failed to decode response: %v", err)
+		}
+		if resp.FileCount != 49 || resp.Incoming != 2 || resp.Max != 50 {
+			t.Errorf("response = %+v, want 49 files, 2 incoming and the free plan's max of 50", resp)
+		}
+		if n := fileCount(t, kb.ID); n != 49 {
+			t.Errorf("knowledge base has %d files, want the 49 it had", n)
+		}
+	})
+}
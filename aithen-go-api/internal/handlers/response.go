@@ -0,0 +1,46 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Created responds with 201, a Location header pointing at the new resource and the body
+func Created(c *gin.Context, location string, body interface{}) {
+	c.Header("Location", location)
+	c.JSON(http.StatusCreated, body)
+}
+
+// mergeJSONObjects marshals base and extra, both of which must encode as JSON objects, and
+// returns a single object with the fields of both. Fields of extra win on conflict.
+func mergeJSONObjects(base, extra interface{}) ([]byte, error) {
+	fields := map[string]json.RawMessage{}
+	for _, v := range []interface{}{base, extra} {
+		data, err := json.Marshal(v)
+		if err != nil {
+			return nil, err
+		}
+		if err := json.Unmarshal(data, &fields); err != nil {
+			return nil, err
+		}
+	}
+	return json.Marshal(fields)
+}
+
+// chatLocation returns the canonical URL of a chat
+func chatLocation(chatID int64) string {
+	return fmt.Sprintf("/api/chats/%d", chatID)
+}
+
+// knowledgeBaseLocation returns the canonical URL of a knowledge base
+func knowledgeBaseLocation(orgSlug string, kbID int64) string {
+	return fmt.Sprintf("/api/orgs/%s/knowledge-bases/%d", orgSlug, kbID)
+}
+
+// userLocation returns the canonical URL of a user
+func userLocation(userID int64) string {
+	return fmt.Sprintf("/api/users/%d", userID)
+}
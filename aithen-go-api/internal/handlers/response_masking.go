@@ -0,0 +1,104 @@
+package handlers
+
+import (
+	"encoding/json"
+	"log"
+	"regexp"
+	"strings"
+	"sync"
+
+	"github.com/aithen/go-api/internal/config"
+)
+
+// builtinMaskPatterns are the PII patterns that can be enabled by name in AI_RESPONSE_MASKING
+var builtinMaskPatterns = map[string]*regexp.Regexp{
+	"email": regexp.MustCompile(`[A-Za-z0-9._%+-]+@[A-Za-z0-9.-]+\.[A-Za-z]{2,}`),
+	"phone": regexp.MustCompile(`(?:\+\d{1,3}[\s.-]?)?(?:\(\d{3}\)|\b\d{3})[\s.-]?\d{3}[\s.-]?\d{4}\b`),
+}
+
+// responseMasker redacts matched spans from AI chat replies
+type responseMasker struct {
+	patterns    []*regexp.Regexp
+	replacement string
+}
+
+var (
+	responseMaskerInstance *responseMasker
+	responseMaskerOnce     sync.Once
+)
+
+// getResponseMasker returns the configured masker, or nil when masking is off (the default).
+// AI_RESPONSE_MASKING enables built-in patterns by name (comma-separated: email, phone),
+// AI_RESPONSE_MASK_PATTERN adds a custom regular expression and AI_RESPONSE_MASK_REPLACEMENT
+// sets the text matches are replaced with (default [REDACTED]).
+func getResponseMasker() *responseMasker {
+	responseMaskerOnce.Do(func() {
+		var patterns []*regexp.Regexp
+		for _, name := range strings.Split(config.GetEnv("AI_RESPONSE_MASKING"), ",") {
+			name = strings.ToLower(strings.TrimSpace(name))
+			if name == "" {
+				continue
+			}
+			pattern, ok := builtinMaskPatterns[name]
+			if !ok {
+				log.Printf("Warning: Ignoring unknown AI_RESPONSE_MASKING pattern %q", name)
+				continue
+			}
+			patterns = append(patterns, pattern)
+		}
+
+		if custom := config.GetEnv("AI_RESPONSE_MASK_PATTERN"); custom != "" {
+			pattern, err := regexp.Compile(custom)
+			if err != nil {
+				log.Printf("Warning: Ignoring invalid AI_RESPONSE_MASK_PATTERN: %v", err)
+			} else {
+				patterns = append(patterns, pattern)
+			}
+		}
+
+		if len(patterns) == 0 {
+			return
+		}
+
+		replacement := config.GetEnv("AI_RESPONSE_MASK_REPLACEMENT")
+		if replacement == "" {
+			replacement = "[REDACTED]"
+		}
+		responseMaskerInstance = &responseMasker{patterns: patterns, replacement: replacement}
+	})
+	return responseMaskerInstance
+}
+
+// mask replaces every span matched by the configured patterns
+func (rm *responseMasker) mask(text string) string {
+	for _, pattern := range rm.patterns {
+		text = pattern.ReplaceAllLiteralString(text, rm.replacement)
+	}
+	return text
+}
+
+// maskChatResponse masks the "response" field of a chat response body from the AI service.
+// Bodies that are not a JSON object with a string "response" are returned unchanged.
+func (rm *responseMasker) maskChatResponse(body []byte) []byte {
+	var payload map[string]json.RawMessage
+	if err := json.Unmarshal(body, &payload); err != nil {
+		return body
+	}
+
+	var reply string
+	if err := json.Unmarshal(payload["response"], &reply); err != nil {
+		return body
+	}
+
+	masked, err := json.Marshal(rm.mask(reply))
+	if err != nil {
+		return body
+	}
+	payload["response"] = masked
+
+	out, err := json.Marshal(payload)
+	if err != nil {
+		return body
+	}
+	return out
+}
@@ -0,0 +1,64 @@
+package handlers
+
+import (
+	"regexp"
+	"testing"
+)
+
+func TestResponseMaskerMask(t *testing.T) {
+	rm := &responseMasker{
+		patterns: []*regexp.Regexp{
+			builtinMaskPatterns["email"],
+			builtinMaskPatterns["phone"],
+			// A custom pattern, as AI_RESPONSE_MASK_PATTERN would add for API tokens
+			regexp.MustCompile(`sk-[A-Za-z0-9]{16,}`),
+		},
+		replacement: "[REDACTED]",
+	}
+
+	tests := []struct {
+		name string
+		text string
+		want string
+	}{
+		{name: "empty", text: "", want: ""},
+		{name: "short text without matches", text: "ok", want: "ok"},
+		{name: "email", text: "Write to jane.doe+kb@example.co.uk today", want: "Write to [REDACTED] today"},
+		{name: "several emails", text: "a@b.io and c@d.io", want: "[REDACTED] and [REDACTED]"},
+		{name: "phone", text: "Call (555) 123-4567 now", want: "Call [REDACTED] now"},
+		{name: "international phone", text: "Call +1 555.123.4567", want: "Call [REDACTED]"},
+		{name: "token", text: "key=sk-abcdEFGH12345678ijkl", want: "key=[REDACTED]"},
+		{name: "token prefix too short", text: "sk-abc", want: "sk-abc"},
+		{name: "at sign without domain", text: "@ mention", want: "@ mention"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := rm.mask(tt.text); got != tt.want {
+				t.Errorf("mask(%q) = %q, want %q", tt.text, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestResponseMaskerMaskChatResponse(t *testing.T) {
+	rm := &responseMasker{patterns: []*regexp.Regexp{builtinMaskPatterns["email"]}, replacement: "***"}
+
+	tests := []struct {
+		name string
+		body string
+		want string
+	}{
+		{name: "response is masked", body: `{"response":"mail a@b.io","model":"m"}`, want: `{"model":"m","response":"mail ***"}`},
+		{name: "empty response", body: `{"response":""}`, want: `{"response":""}`},
+		{name: "no response field", body: `{"error":"a@b.io"}`, want: `{"error":"a@b.io"}`},
+		{name: "response is not a string", body: `{"response":42}`, want: `{"response":42}`},
+		{name: "not JSON", body: `a@b.io`, want: `a@b.io`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := string(rm.maskChatResponse([]byte(tt.body))); got != tt.want {
+				t.Errorf("maskChatResponse(%s) = %s, want %s", tt.body, got, tt.want)
+			}
+		})
+	}
+}
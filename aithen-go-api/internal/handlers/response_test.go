@@ -0,0 +1,122 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/aithen/go-api/internal/auth"
+	"github.com/aithen/go-api/internal/id"
+	"github.com/aithen/go-api/internal/models"
+	"github.com/gin-gonic/gin"
+)
+
+func TestCreated(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	Created(c, "/api/things/42", gin.H{"id": "42"})
+
+	if w.Code != http.StatusCreated {
+		t.Errorf("status = %d, want %d", w.Code, http.StatusCreated)
+	}
+	if got := w.Header().Get("Location"); got != "/api/things/42" {
+		t.Errorf("Location = %q, want /api/things/42", got)
+	}
+	if got := strings.TrimSpace(w.Body.String()); got != `{"id":"42"}` {
+		t.Errorf("body = %s, want the given body", got)
+	}
+}
+
+func TestCreateHandlersSetLocation(t *testing.T) {
+	useTestDatabase(t)
+	gin.SetMode(gin.TestMode)
+	auth.SetJWTSecret("test-secret")
+	m := models.NewModels()
+	ctx := context.Background()
+
+	owner := createTestUser(t, m)
+	org := createTestOrganization(t, m, owner)
+	email := fmt.Sprintf("register-%d@example.com", id.Generate())
+	t.Cleanup(func() {
+		if user, err := m.Users.FindByEmail(context.Background(), email); err == nil {
+			m.Users.DeleteAccount(context.Background(), user.ID)
+		}
+	})
+
+	asOwner := func(c *gin.Context) { c.Set("user_id", owner.ID) }
+	tests := []struct {
+		name    string
+		route   string
+		path    string
+		handler gin.HandlerFunc
+		body    string
+		// location returns the Location the response must have, given its body
+		location func(body []byte) string
+	}{
+		{
+			name:    "CreateChat",
+			route:   "/chats",
+			path:    "/chats",
+			handler: CreateChat,
+			body:    `{"title":"Hello"}`,
+			location: func(body []byte) string {
+				var chat struct {
+					ID string `json:"id"`
+				}
+				json.Unmarshal(body, &chat)
+				return "/api/chats/" + chat.ID
+			},
+		},
+		{
+			name:    "CreateKnowledgeBase",
+			route:   "/orgs/:slug/knowledge-bases",
+			path:    "/orgs/" + org.Slug + "/knowledge-bases",
+			handler: CreateKnowledgeBase,
+			body:    `{"name":"Docs"}`,
+			location: func(body []byte) string {
+				var kb struct {
+					ID string `json:"id"`
+				}
+				json.Unmarshal(body, &kb)
+				return fmt.Sprintf("/api/orgs/%s/knowledge-bases/%s", org.Slug, kb.ID)
+			},
+		},
+		{
+			name:    "Register",
+			route:   "/auth/register",
+			path:    "/auth/register",
+			handler: Register,
+			body:    fmt.Sprintf(`{"email":%q,"name":"New User","password":"password123","organization_name":"New Org"}`, email),
+			location: func(body []byte) string {
+				user, err := m.Users.FindByEmail(ctx, email)
+				if err != nil {
+					return "(user not created)"
+				}
+				return fmt.Sprintf("/api/users/%d", user.ID)
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := gin.New()
+			r.POST(tt.route, asOwner, tt.handler)
+			req := httptest.NewRequest(http.MethodPost, tt.path, strings.NewReader(tt.body))
+			req.Header.Set("Content-Type", "application/json")
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+
+			if w.Code != http.StatusCreated {
+				t.Fatalf("status = %d, want %d: %s", w.Code, http.StatusCreated, w.Body.String())
+			}
+			if got, want := w.Header().Get("Location"), tt.location(w.Body.Bytes()); got != want {
+				t.Errorf("Location = %q, want %q", got, want)
+			}
+		})
+	}
+}
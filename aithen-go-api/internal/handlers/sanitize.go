@@ -0,0 +1,64 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/aithen/go-api/internal/config"
+)
+
+var (
+	errInvalidUTF8      = errors.New("content must be valid UTF-8")
+	errControlCharacter = errors.New("content must not contain control characters other than newlines and tabs")
+)
+
+// rejectControlCharacters reports whether disallowed control characters are rejected
+// (CHAT_CONTROL_CHARACTERS=reject) rather than stripped (strip, the default)
+func rejectControlCharacters() bool {
+	return strings.EqualFold(strings.TrimSpace(config.GetEnv("CHAT_CONTROL_CHARACTERS")), "reject")
+}
+
+// isDisallowedControl reports whether r is a control character that chat content may not
+// contain. Newlines, carriage returns and tabs are allowed.
+func isDisallowedControl(r rune) bool {
+	return unicode.IsControl(r) && r != '\n' && r != '\r' && r != '\t'
+}
+
+// sanitizeContent validates chat message content. Invalid UTF-8 and content longer than
+// CHAT_MAX_CONTENT_LENGTH characters (default 100000, 0 disables) are always rejected; other
+// control characters, including null bytes, are stripped or rejected per CHAT_CONTROL_CHARACTERS.
+func sanitizeContent(content string) (string, error) {
+	if !utf8.ValidString(content) {
+		return "", errInvalidUTF8
+	}
+	if maxChars := config.GetEnvInt("CHAT_MAX_CONTENT_LENGTH", 100000); maxChars > 0 && utf8.RuneCountInString(content) > maxChars {
+		return "", fmt.Errorf("content exceeds %d characters", maxChars)
+	}
+	if strings.IndexFunc(content, isDisallowedControl) < 0 {
+		return content, nil
+	}
+	if rejectControlCharacters() {
+		return "", errControlCharacter
+	}
+	return strings.Map(func(r rune) rune {
+		if isDisallowedControl(r) {
+			return -1
+		}
+		return r
+	}, content), nil
+}
+
+// sanitizeChatMessages applies sanitizeContent to every message of a chat request
+func sanitizeChatMessages(req *ChatRequest) error {
+	for i := range req.Messages {
+		content, err := sanitizeContent(req.Messages[i].Content)
+		if err != nil {
+			return err
+		}
+		req.Messages[i].Content = content
+	}
+	return nil
+}
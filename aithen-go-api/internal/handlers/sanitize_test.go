@@ -0,0 +1,58 @@
+package handlers
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestSanitizeContent(t *testing.T) {
+	tests := []struct {
+		name    string
+		mode    string
+		content string
+		want    string
+		wantErr bool
+	}{
+		{name: "empty", content: "", want: ""},
+		{name: "plain text is unchanged", content: "héllo", want: "héllo"},
+		{name: "newlines, carriage returns and tabs are kept", content: "a\r\nb\tc\n", want: "a\r\nb\tc\n"},
+		{name: "null bytes are stripped", content: "a\x00b\x00", want: "ab"},
+		{name: "other control characters are stripped", content: "\x1b[31mred\x7f\u0085", want: "[31mred"},
+		{name: "control characters rejected", mode: "reject", content: "a\x00b", wantErr: true},
+		{name: "reject mode leaves clean content", mode: "REJECT", content: "a\tb", want: "a\tb"},
+		{name: "invalid UTF-8", content: "a\xffb", wantErr: true},
+		{name: "truncated multi-byte sequence", content: "caf\xc3", wantErr: true},
+		{name: "at the length cap", content: strings.Repeat("é", 10), want: strings.Repeat("é", 10)},
+		{name: "over the length cap", content: strings.Repeat("é", 11), wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("CHAT_CONTROL_CHARACTERS", tt.mode)
+			// The cap counts characters, not bytes
+			t.Setenv("CHAT_MAX_CONTENT_LENGTH", "10")
+
+			got, err := sanitizeContent(tt.content)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("sanitizeContent(%q) = %q, want an error", tt.content, got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("sanitizeContent(%q) error = %v", tt.content, err)
+			}
+			if got != tt.want {
+				t.Errorf("sanitizeContent(%q) = %q, want %q", tt.content, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSanitizeContentLengthCapDisabled(t *testing.T) {
+	t.Setenv("CHAT_MAX_CONTENT_LENGTH", "0")
+
+	content := strings.Repeat("a", 200000)
+	if got, err := sanitizeContent(content); err != nil || got != content {
+		t.Errorf("sanitizeContent() with the cap disabled = %d characters, %v, want the content unchanged", len(got), err)
+	}
+}
@@ -0,0 +1,23 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/aithen/go-api/internal/models"
+	"github.com/aithen/go-api/internal/uploads"
+	"github.com/gin-gonic/gin"
+)
+
+// PurgeOrphanedUploads removes uploaded files that have no database record and are older
+// than the configured grace period, without waiting for the periodic sweep
+func PurgeOrphanedUploads(c *gin.Context) {
+	m := models.NewModels()
+
+	result, err := uploads.PurgeOrphans(c.Request.Context(), m.KnowledgeBases.GetAllFilePaths, uploads.OrphanGracePeriod())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, result)
+}
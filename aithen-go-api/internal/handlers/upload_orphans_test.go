@@ -0,0 +1,69 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/aithen/go-api/internal/limits"
+	"github.com/aithen/go-api/internal/models"
+	"github.com/aithen/go-api/internal/uploads"
+	"github.com/gin-gonic/gin"
+)
+
+func TestPurgeOrphanedUploads(t *testing.T) {
+	useTestDatabase(t)
+	gin.SetMode(gin.TestMode)
+	t.Setenv("UPLOAD_DIR", t.TempDir())
+	t.Setenv("UPLOAD_ORPHAN_GRACE_PERIOD", "0")
+	m := models.NewModels()
+	ctx := context.Background()
+
+	owner := createTestUser(t, m)
+	org := createTestOrganization(t, m, owner)
+	kb, err := m.KnowledgeBases.Create(ctx, org.ID, "Docs", "", &owner.ID)
+	if err != nil {
+		t.Fatalf("failed to create knowledge base: %v", err)
+	}
+
+	dir := uploads.KnowledgeBaseDir(kb.ID)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		t.Fatalf("failed to create upload directory: %v", err)
+	}
+	recorded := filepath.Join(dir, "recorded.txt")
+	orphan := filepath.Join(dir, "orphan.txt")
+	for _, path := range []string{recorded, orphan} {
+		if err := os.WriteFile(path, []byte("data"), 0644); err != nil {
+			t.Fatalf("failed to write %s: %v", path, err)
+		}
+	}
+	if _, err := m.KnowledgeBases.AddFile(ctx, kb.ID, "recorded.txt", recorded, 4, "text/plain", &owner.ID, limits.ForPlan(limits.PlanEnterprise)); err != nil {
+		t.Fatalf("failed to add file: %v", err)
+	}
+
+	r := gin.New()
+	r.POST("/admin/uploads/purge-orphans", PurgeOrphanedUploads)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/admin/uploads/purge-orphans", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d, want %d: %s", w.Code, http.StatusOK, w.Body.String())
+	}
+
+	var result uploads.OrphanSweepResult
+	if err := json.Unmarshal(w.Body.Bytes(), &result); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if len(result.Removed) != 1 || result.Removed[0] != orphan {
+		t.Errorf("removed = %v, want [%s]", result.Removed, orphan)
+	}
+	if _, err := os.Stat(orphan); !os.IsNotExist(err) {
+		t.Errorf("orphan still exists (stat error %v)", err)
+	}
+	if _, err := os.Stat(recorded); err != nil {
+		t.Errorf("recorded file was removed: %v", err)
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"strconv"
 
 	"github.com/aithen/go-api/internal/models"
+	"github.com/aithen/go-api/internal/queue"
 	"github.com/gin-gonic/gin"
 )
 
@@ -50,21 +51,60 @@ func CreateUser(c *gin.Context) {
 		return
 	}
 
-	c.JSON(http.StatusCreated, user)
+	Created(c, userLocation(user.ID), user)
 }
 
-// GetAllUsers retrieves all users
+const (
+	defaultUserListLimit = 50
+	maxUserListLimit     = 200
+)
+
+// GetAllUsers retrieves users with optional search, sort and pagination
+// Query params: limit (default 50, max 200), offset, search, sort
 func GetAllUsers(c *gin.Context) {
-	models := models.NewModels()
+	limit := defaultUserListLimit
+	if v := c.Query("limit"); v != "" {
+		parsed, err := strconv.Atoi(v)
+		if err != nil || parsed < 1 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid limit"})
+			return
+		}
+		limit = parsed
+	}
+	if limit > maxUserListLimit {
+		limit = maxUserListLimit
+	}
+
+	offset := 0
+	if v := c.Query("offset"); v != "" {
+		parsed, err := strconv.Atoi(v)
+		if err != nil || parsed < 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid offset"})
+			return
+		}
+		offset = parsed
+	}
+
+	m := models.NewModels()
 	ctx := c.Request.Context()
 
-	users, err := models.Users.All(ctx)
+	users, total, err := m.Users.List(ctx, models.UserListOptions{
+		Limit:  limit,
+		Offset: offset,
+		Search: c.Query("search"),
+		Sort:   c.Query("sort"),
+	})
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
 
-	c.JSON(http.StatusOK, users)
+	c.JSON(http.StatusOK, gin.H{
+		"users":  users,
+		"total":  total,
+		"limit":  limit,
+		"offset": offset,
+	})
 }
 
 // UpdateUser updates a user
@@ -116,3 +156,86 @@ func DeleteUser(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{"message": "User deleted successfully"})
 }
 
+
+// DeleteMe deletes the current authenticated user's account and data. Organizations deleted
+// with it have their training runs cancelled and their uploaded files removed.
+func DeleteMe(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	m := models.NewModels()
+	ctx := c.Request.Context()
+
+	deleted, err := m.Users.DeleteAccount(ctx, userID.(int64))
+	if err != nil {
+		if err == models.ErrSoleOrganizationOwner {
+			c.JSON(http.StatusConflict, gin.H{"error": "You are the sole owner of an organization with other members. Transfer ownership before deleting your account."})
+			return
+		}
+		if err == models.ErrUserNotFound {
+			c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete account"})
+		return
+	}
+
+	// Organizations deleted with the account leave training runs and uploaded files behind
+	for _, org := range deleted {
+		cleanupDeletedOrganization(m, org)
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Account deleted successfully"})
+}
+
+// cleanupDeletedOrganization cancels the training runs and removes the uploaded files of an
+// organization deleted along with its last member
+func cleanupDeletedOrganization(m *models.Models, org models.DeletedOrganization) {
+	trainingQueue := queue.GetTrainingQueue()
+	trainingQueue.SetModels(m)
+	dropWaitingTraining(org.ID)
+	trainingQueue.CancelRuns(org.KnowledgeBaseIDs)
+	for _, kbID := range org.KnowledgeBaseIDs {
+		removeKnowledgeBaseUploads(kbID)
+	}
+}
+
+// GetMyMemberships returns every organization the current user belongs to with their role and
+// status, including pending and inactive memberships
+func GetMyMemberships(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	m := models.NewModels()
+	memberships, err := m.Organizations.GetUserMemberships(c.Request.Context(), userID.(int64))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get memberships"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"memberships": memberships})
+}
+
+// GetMyStats returns aggregate chat statistics for the current user
+func GetMyStats(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	m := models.NewModels()
+	stats, err := m.Chats.GetUserStats(c.Request.Context(), userID.(int64))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get chat statistics"})
+		return
+	}
+
+	c.JSON(http.StatusOK, stats)
+}
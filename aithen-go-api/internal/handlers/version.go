@@ -0,0 +1,13 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/aithen/go-api/internal/version"
+	"github.com/gin-gonic/gin"
+)
+
+// GetVersion returns the version, commit and build time of the running server
+func GetVersion(c *gin.Context) {
+	c.JSON(http.StatusOK, version.Get())
+}
@@ -0,0 +1,39 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/aithen/go-api/internal/version"
+	"github.com/gin-gonic/gin"
+)
+
+func TestGetVersion(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	saved := version.Version
+	t.Cleanup(func() { version.Version = saved })
+	version.Version = "1.2.3"
+
+	r := gin.New()
+	r.GET("/api/version", GetVersion)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/version", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d, want %d", w.Code, http.StatusOK)
+	}
+
+	var got map[string]string
+	if err := json.Unmarshal(w.Body.Bytes(), &got); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if got["version"] != "1.2.3" {
+		t.Errorf("version = %q, want 1.2.3", got["version"])
+	}
+	for _, key := range []string{"commit", "build_time", "go_version"} {
+		if got[key] == "" {
+			t.Errorf("response is missing %s: %v", key, got)
+		}
+	}
+}
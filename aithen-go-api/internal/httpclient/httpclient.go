@@ -0,0 +1,105 @@
+package httpclient
+
+import (
+	"errors"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/aithen/go-api/internal/config"
+)
+
+var (
+	transportInstance http.RoundTripper
+	transportOnce     sync.Once
+)
+
+// Transport returns the transport shared by all outbound HTTP calls, so connections to the
+// AI and training services are pooled and reused. Pooling is configured by
+// HTTP_MAX_IDLE_CONNS (default 100), HTTP_MAX_IDLE_CONNS_PER_HOST (default 32),
+// HTTP_IDLE_CONN_TIMEOUT seconds (default 90) and HTTP_KEEP_ALIVE seconds (default 30,
+// 0 disables keep-alives). Requests whose connection could not be established are retried
+// up to HTTP_RETRY_ATTEMPTS times (default 2) with exponential backoff starting at
+// HTTP_RETRY_BACKOFF milliseconds (default 100).
+func Transport() http.RoundTripper {
+	transportOnce.Do(func() {
+		keepAlive := time.Duration(config.GetEnvInt("HTTP_KEEP_ALIVE", 30)) * time.Second
+		dialer := &net.Dialer{Timeout: 30 * time.Second, KeepAlive: keepAlive}
+		if keepAlive <= 0 {
+			dialer.KeepAlive = -1
+		}
+
+		base := &http.Transport{
+			Proxy:                 http.ProxyFromEnvironment,
+			DialContext:           dialer.DialContext,
+			ForceAttemptHTTP2:     true,
+			MaxIdleConns:          config.GetEnvInt("HTTP_MAX_IDLE_CONNS", 100),
+			MaxIdleConnsPerHost:   config.GetEnvInt("HTTP_MAX_IDLE_CONNS_PER_HOST", 32),
+			IdleConnTimeout:       time.Duration(config.GetEnvInt("HTTP_IDLE_CONN_TIMEOUT", 90)) * time.Second,
+			DisableKeepAlives:     keepAlive <= 0,
+			TLSHandshakeTimeout:   10 * time.Second,
+			ExpectContinueTimeout: time.Second,
+		}
+
+		transportInstance = &retryTransport{
+			base:     base,
+			attempts: config.GetEnvInt("HTTP_RETRY_ATTEMPTS", 2),
+			backoff:  time.Duration(config.GetEnvInt("HTTP_RETRY_BACKOFF", 100)) * time.Millisecond,
+		}
+	})
+	return transportInstance
+}
+
+// New returns a client using the shared transport. A zero timeout means no timeout,
+// as needed by streamed responses; such calls are bounded by their request context.
+func New(timeout time.Duration) *http.Client {
+	return &http.Client{Transport: Transport(), Timeout: timeout}
+}
+
+// retryTransport retries requests that failed before a connection was established.
+// Nothing reached the server in that case, so retrying is safe for any method.
+type retryTransport struct {
+	base     http.RoundTripper
+	attempts int
+	backoff  time.Duration
+}
+
+// RoundTrip implements http.RoundTripper
+func (t *retryTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	backoff := t.backoff
+	for attempt := 0; ; attempt++ {
+		resp, err := t.base.RoundTrip(req)
+		if err == nil || attempt >= t.attempts || !isDialError(err) {
+			return resp, err
+		}
+
+		// The body may have been read, so a retry needs a fresh copy
+		if req.Body != nil && req.Body != http.NoBody {
+			if req.GetBody == nil {
+				return resp, err
+			}
+			body, bodyErr := req.GetBody()
+			if bodyErr != nil {
+				return resp, err
+			}
+			req = req.Clone(req.Context())
+			req.Body = body
+		}
+
+		timer := time.NewTimer(backoff)
+		select {
+		case <-req.Context().Done():
+			timer.Stop()
+			return nil, req.Context().Err()
+		case <-timer.C:
+		}
+		backoff *= 2
+	}
+}
+
+// isDialError reports whether err happened while connecting, before the request was sent
+func isDialError(err error) bool {
+	var opErr *net.OpError
+	return errors.As(err, &opErr) && opErr.Op == "dial"
+}
@@ -0,0 +1,91 @@
+package httpclient
+
+import (
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+)
+
+func TestTransportReusesConnections(t *testing.T) {
+	var newConns atomic.Int32
+	srv := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, "ok")
+	}))
+	srv.Config.ConnState = func(_ net.Conn, state http.ConnState) {
+		if state == http.StateNew {
+			newConns.Add(1)
+		}
+	}
+	srv.Start()
+	defer srv.Close()
+
+	client := New(0)
+	for i := 0; i < 5; i++ {
+		resp, err := client.Get(srv.URL)
+		if err != nil {
+			t.Fatalf("request %d: %v", i, err)
+		}
+		// The connection only returns to the pool once the body is drained and closed
+		io.Copy(io.Discard, resp.Body)
+		resp.Body.Close()
+	}
+
+	if got := newConns.Load(); got != 1 {
+		t.Errorf("opened %d connections for 5 sequential requests, want 1", got)
+	}
+}
+
+// scriptedTransport returns the next scripted result on each call
+type scriptedTransport struct {
+	results []error
+	calls   int
+}
+
+func (s *scriptedTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	err := s.results[s.calls]
+	s.calls++
+	if err != nil {
+		return nil, err
+	}
+	return &http.Response{StatusCode: http.StatusInternalServerError, Body: http.NoBody, Request: req}, nil
+}
+
+func TestRetryTransport(t *testing.T) {
+	dialErr := &net.OpError{Op: "dial", Net: "tcp", Err: errors.New("connection refused")}
+	readErr := &net.OpError{Op: "read", Net: "tcp", Err: errors.New("connection reset by peer")}
+
+	tests := []struct {
+		name      string
+		results   []error
+		wantCalls int
+		wantErr   error
+	}{
+		{name: "response is not retried", results: []error{nil}, wantCalls: 1},
+		{name: "dial error is retried", results: []error{dialErr, dialErr, nil}, wantCalls: 3},
+		{name: "dial errors give up after the attempts", results: []error{dialErr, dialErr, dialErr, nil}, wantCalls: 3, wantErr: dialErr},
+		{name: "error after connecting is not retried", results: []error{readErr, nil}, wantCalls: 1, wantErr: readErr},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			base := &scriptedTransport{results: tt.results}
+			rt := &retryTransport{base: base, attempts: 2}
+
+			req, _ := http.NewRequest(http.MethodPost, "http://ai.invalid/chat", strings.NewReader(`{}`))
+			resp, err := rt.RoundTrip(req)
+			if base.calls != tt.wantCalls {
+				t.Errorf("base called %d times, want %d", base.calls, tt.wantCalls)
+			}
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("err = %v, want %v", err, tt.wantErr)
+			}
+			if tt.wantErr == nil && (resp == nil || resp.StatusCode != http.StatusInternalServerError) {
+				t.Errorf("resp = %v, want the 500 response passed through", resp)
+			}
+		})
+	}
+}
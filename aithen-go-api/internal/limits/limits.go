@@ -0,0 +1,115 @@
+package limits
+
+import (
+	"fmt"
+)
+
+// Organization plans
+const (
+	PlanFree       = "free"
+	PlanPro        = "pro"
+	PlanEnterprise = "enterprise"
+)
+
+// Unlimited marks a limit that is not enforced
+const Unlimited = -1
+
+// Names of the individual limits, as reported to clients
+const (
+	LimitKnowledgeBases     = "max_knowledge_bases"
+	LimitStorage            = "max_storage_bytes"
+	LimitMembers            = "max_members"
+	LimitConcurrentTraining = "max_concurrent_trainings"
+	LimitFilesPerKB         = "max_files_per_knowledge_base"
+)
+
+// Limits holds the usage limits of a plan
+type Limits struct {
+	MaxKnowledgeBases      int64 `json:"max_knowledge_bases"`
+	MaxStorageBytes        int64 `json:"max_storage_bytes"`
+	MaxMembers             int64 `json:"max_members"`
+	MaxConcurrentTrainings int64 `json:"max_concurrent_trainings"`
+	MaxFilesPerKB          int64 `json:"max_files_per_knowledge_base"`
+}
+
+// plans maps each plan to its limits
+var plans = map[string]Limits{
+	PlanFree: {
+		MaxKnowledgeBases:      3,
+		MaxStorageBytes:        100 << 20, // 100 MB
+		MaxMembers:             3,
+		MaxConcurrentTrainings: 1,
+		MaxFilesPerKB:          50,
+	},
+	PlanPro: {
+		MaxKnowledgeBases:      25,
+		MaxStorageBytes:        10 << 30, // 10 GB
+		MaxMembers:             25,
+		MaxConcurrentTrainings: 3,
+		MaxFilesPerKB:          1000,
+	},
+	PlanEnterprise: {
+		MaxKnowledgeBases:      Unlimited,
+		MaxStorageBytes:        Unlimited,
+		MaxMembers:             Unlimited,
+		MaxConcurrentTrainings: Unlimited,
+		MaxFilesPerKB:          Unlimited,
+	},
+}
+
+// IsValidPlan reports whether plan is a known plan
+func IsValidPlan(plan string) bool {
+	_, ok := plans[plan]
+	return ok
+}
+
+// ForPlan returns the limits for a plan. Unknown plans get the free tier limits.
+func ForPlan(plan string) Limits {
+	if l, ok := plans[plan]; ok {
+		return l
+	}
+	return plans[PlanFree]
+}
+
+// LimitError reports that an action would exceed a plan limit
+type LimitError struct {
+	Limit string // One of the Limit* names
+	Max   int64
+}
+
+func (e *LimitError) Error() string {
+	return fmt.Sprintf("plan limit reached: %s is %d", e.Limit, e.Max)
+}
+
+// check returns a LimitError if total exceeds max
+func check(limit string, total, max int64) error {
+	if max != Unlimited && total > max {
+		return &LimitError{Limit: limit, Max: max}
+	}
+	return nil
+}
+
+// CheckKnowledgeBases checks whether another knowledge base can be added to the existing count
+func (l Limits) CheckKnowledgeBases(existing int64) error {
+	return check(LimitKnowledgeBases, existing+1, l.MaxKnowledgeBases)
+}
+
+// CheckStorage checks whether additional bytes fit alongside the storage already used
+func (l Limits) CheckStorage(used, additional int64) error {
+	return check(LimitStorage, used+additional, l.MaxStorageBytes)
+}
+
+// CheckMembers checks whether another member can be added to the existing count
+func (l Limits) CheckMembers(existing int64) error {
+	return check(LimitMembers, existing+1, l.MaxMembers)
+}
+
+// CheckConcurrentTrainings checks whether another training can start alongside those running
+func (l Limits) CheckConcurrentTrainings(running int64) error {
+	return check(LimitConcurrentTraining, running+1, l.MaxConcurrentTrainings)
+}
+
+// CheckFilesPerKB checks whether additional files fit in a knowledge base alongside its existing ones
+func (l Limits) CheckFilesPerKB(existing, additional int64) error {
+	return check(LimitFilesPerKB, existing+additional, l.MaxFilesPerKB)
+}
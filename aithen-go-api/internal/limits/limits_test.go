@@ -0,0 +1,61 @@
+package limits
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestFreePlanLimits(t *testing.T) {
+	free := ForPlan(PlanFree)
+
+	tests := []struct {
+		name      string
+		check     func() error
+		wantLimit string // empty when the check should pass
+	}{
+		{name: "knowledge base under limit", check: func() error { return free.CheckKnowledgeBases(2) }},
+		{name: "knowledge base at limit", check: func() error { return free.CheckKnowledgeBases(3) }, wantLimit: LimitKnowledgeBases},
+		{name: "storage fits", check: func() error { return free.CheckStorage(50<<20, 50<<20) }},
+		{name: "storage exceeded", check: func() error { return free.CheckStorage(100<<20, 1) }, wantLimit: LimitStorage},
+		{name: "member under limit", check: func() error { return free.CheckMembers(2) }},
+		{name: "member at limit", check: func() error { return free.CheckMembers(3) }, wantLimit: LimitMembers},
+		{name: "first training", check: func() error { return free.CheckConcurrentTrainings(0) }},
+		{name: "second concurrent training", check: func() error { return free.CheckConcurrentTrainings(1) }, wantLimit: LimitConcurrentTraining},
+		{name: "files fit", check: func() error { return free.CheckFilesPerKB(40, 10) }},
+		{name: "files exceeded", check: func() error { return free.CheckFilesPerKB(40, 11) }, wantLimit: LimitFilesPerKB},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.check()
+			if tt.wantLimit == "" {
+				if err != nil {
+					t.Fatalf("check() error = %v, want nil", err)
+				}
+				return
+			}
+			var limitErr *LimitError
+			if !errors.As(err, &limitErr) {
+				t.Fatalf("check() error = %v, want *LimitError", err)
+			}
+			if limitErr.Limit != tt.wantLimit {
+				t.Errorf("check() limit = %q, want %q", limitErr.Limit, tt.wantLimit)
+			}
+		})
+	}
+}
+
+func TestEnterprisePlanIsUnlimited(t *testing.T) {
+	enterprise := ForPlan(PlanEnterprise)
+	if err := enterprise.CheckMembers(1 << 20); err != nil {
+		t.Errorf("CheckMembers() error = %v, want nil", err)
+	}
+	if err := enterprise.CheckStorage(1<<40, 1<<40); err != nil {
+		t.Errorf("CheckStorage() error = %v, want nil", err)
+	}
+}
+
+func TestForPlanUnknownFallsBackToFree(t *testing.T) {
+	if got, want := ForPlan("platinum"), ForPlan(PlanFree); got != want {
+		t.Errorf("ForPlan(\"platinum\") = %+v, want free tier %+v", got, want)
+	}
+}
@@ -0,0 +1,51 @@
+package middleware
+
+import (
+	"net/http"
+
+	"github.com/aithen/go-api/internal/config"
+	"github.com/gin-gonic/gin"
+)
+
+// uploadRoutes holds the full route paths that accept uploads. It is filled while routes are
+// registered, before the server starts, and only read afterwards.
+var uploadRoutes = map[string]bool{}
+
+// AllowUploadBody marks the route with the given full path (as in gin's FullPath, e.g.
+// "/api/orgs/:slug/knowledge-bases/:id/files") as an upload route, so BodySizeLimit applies
+// MAX_UPLOAD_BODY_SIZE to it instead of MAX_REQUEST_BODY_SIZE
+func AllowUploadBody(fullPath string) {
+	uploadRoutes[fullPath] = true
+}
+
+// BodySizeLimit returns middleware that caps request bodies at MAX_REQUEST_BODY_SIZE bytes
+// (default 10 MB). Routes marked with AllowUploadBody use MAX_UPLOAD_BODY_SIZE instead
+// (default 0, leaving them to the upload handlers' own limits); the route decides, never a
+// request header. Requests that declare a larger Content-Length get 413 up front; bodies sent
+// without one are cut off at the limit, which fails the handler's read.
+func BodySizeLimit() gin.HandlerFunc {
+	jsonLimit := int64(config.GetEnvInt("MAX_REQUEST_BODY_SIZE", 10<<20))
+	uploadLimit := int64(config.GetEnvInt("MAX_UPLOAD_BODY_SIZE", 0))
+
+	return func(c *gin.Context) {
+		limit := jsonLimit
+		if uploadRoutes[c.FullPath()] {
+			limit = uploadLimit
+		}
+		if limit <= 0 || c.Request.Body == nil || c.Request.Body == http.NoBody {
+			c.Next()
+			return
+		}
+
+		if c.Request.ContentLength > limit {
+			c.AbortWithStatusJSON(http.StatusRequestEntityTooLarge, gin.H{
+				"error":     "Request body too large",
+				"max_bytes": limit,
+			})
+			return
+		}
+
+		c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, limit)
+		c.Next()
+	}
+}
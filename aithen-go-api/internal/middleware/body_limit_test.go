@@ -0,0 +1,69 @@
+package middleware
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestBodySizeLimit(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	t.Setenv("MAX_REQUEST_BODY_SIZE", "16")
+	t.Setenv("MAX_UPLOAD_BODY_SIZE", "64")
+
+	AllowUploadBody("/test/upload")
+	t.Cleanup(func() { delete(uploadRoutes, "/test/upload") })
+
+	r := gin.New()
+	r.Use(BodySizeLimit())
+	readBody := func(c *gin.Context) {
+		if _, err := io.ReadAll(c.Request.Body); err != nil {
+			c.Status(http.StatusBadRequest)
+			return
+		}
+		c.Status(http.StatusOK)
+	}
+	r.POST("/test/json", readBody)
+	r.POST("/test/upload", readBody)
+
+	tests := []struct {
+		name        string
+		path        string
+		size        int
+		contentType string
+		chunked     bool // send without a Content-Length
+		want        int
+	}{
+		{name: "small JSON body", path: "/test/json", size: 16, want: http.StatusOK},
+		{name: "oversized JSON body", path: "/test/json", size: 17, want: http.StatusRequestEntityTooLarge},
+		{name: "multipart header does not lift the cap", path: "/test/json", size: 32, contentType: "multipart/form-data; boundary=x", want: http.StatusRequestEntityTooLarge},
+		{name: "oversized body without length is cut off", path: "/test/json", size: 32, chunked: true, want: http.StatusBadRequest},
+		{name: "upload route uses the upload cap", path: "/test/upload", size: 32, want: http.StatusOK},
+		{name: "oversized upload", path: "/test/upload", size: 65, want: http.StatusRequestEntityTooLarge},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var body io.Reader = strings.NewReader(strings.Repeat("a", tt.size))
+			if tt.chunked {
+				body = io.MultiReader(body)
+			}
+			req := httptest.NewRequest(http.MethodPost, tt.path, body)
+			if tt.chunked {
+				req.ContentLength = -1
+			}
+			if tt.contentType != "" {
+				req.Header.Set("Content-Type", tt.contentType)
+			}
+
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+			if w.Code != tt.want {
+				t.Errorf("status = %d, want %d", w.Code, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,80 @@
+package middleware
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"github.com/aithen/go-api/internal/config"
+	"github.com/gin-gonic/gin"
+)
+
+var (
+	maintenanceMode     atomic.Bool
+	maintenanceModeOnce sync.Once
+)
+
+// Routes that keep accepting writes in maintenance mode, matched by prefix
+var maintenanceExemptPrefixes = []string{
+	"/api/auth/",
+	"/api/admin/maintenance", // Otherwise maintenance mode could not be switched off
+	"/ping",
+	"/readyz",
+}
+
+// loadMaintenanceMode applies MAINTENANCE_MODE the first time the flag is read
+func loadMaintenanceMode() {
+	maintenanceModeOnce.Do(func() {
+		if enabled, err := strconv.ParseBool(config.GetEnv("MAINTENANCE_MODE")); err == nil && enabled {
+			maintenanceMode.Store(true)
+		}
+	})
+}
+
+// MaintenanceModeEnabled reports whether mutating requests are currently rejected
+func MaintenanceModeEnabled() bool {
+	loadMaintenanceMode()
+	return maintenanceMode.Load()
+}
+
+// SetMaintenanceMode switches maintenance mode on or off at runtime for this API instance
+func SetMaintenanceMode(enabled bool) {
+	loadMaintenanceMode()
+	maintenanceMode.Store(enabled)
+}
+
+// MaintenanceMode returns middleware that rejects non-read requests with 503 and a Retry-After
+// header (MAINTENANCE_RETRY_AFTER seconds, default 120) while maintenance mode is on.
+// GET, HEAD and OPTIONS requests, authentication and health routes are always let through.
+func MaintenanceMode() gin.HandlerFunc {
+	retryAfter := config.GetEnvInt("MAINTENANCE_RETRY_AFTER", 120)
+
+	return func(c *gin.Context) {
+		if !MaintenanceModeEnabled() {
+			c.Next()
+			return
+		}
+
+		switch c.Request.Method {
+		case http.MethodGet, http.MethodHead, http.MethodOptions:
+			c.Next()
+			return
+		}
+
+		path := c.Request.URL.Path
+		for _, prefix := range maintenanceExemptPrefixes {
+			if strings.HasPrefix(path, prefix) {
+				c.Next()
+				return
+			}
+		}
+
+		c.Header("Retry-After", strconv.Itoa(retryAfter))
+		c.AbortWithStatusJSON(http.StatusServiceUnavailable, gin.H{
+			"error":       "The service is in maintenance mode, please try again later",
+			"retry_after": retryAfter,
+		})
+	}
+}
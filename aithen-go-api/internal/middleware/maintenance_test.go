@@ -0,0 +1,64 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestMaintenanceMode(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	SetMaintenanceMode(true)
+	t.Cleanup(func() { SetMaintenanceMode(false) })
+
+	r := gin.New()
+	r.Use(MaintenanceMode())
+	ok := func(c *gin.Context) { c.Status(http.StatusOK) }
+	r.GET("/api/chats", ok)
+	r.POST("/api/chats", ok)
+	r.DELETE("/api/chats/:id", ok)
+	r.POST("/api/auth/login", ok)
+	r.PUT("/api/admin/maintenance", ok)
+
+	tests := []struct {
+		name   string
+		method string
+		path   string
+		want   int
+	}{
+		{name: "GET allowed", method: http.MethodGet, path: "/api/chats", want: http.StatusOK},
+		{name: "POST blocked", method: http.MethodPost, path: "/api/chats", want: http.StatusServiceUnavailable},
+		{name: "DELETE blocked", method: http.MethodDelete, path: "/api/chats/1", want: http.StatusServiceUnavailable},
+		{name: "login allowed", method: http.MethodPost, path: "/api/auth/login", want: http.StatusOK},
+		{name: "switching maintenance off allowed", method: http.MethodPut, path: "/api/admin/maintenance", want: http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, httptest.NewRequest(tt.method, tt.path, nil))
+			if w.Code != tt.want {
+				t.Fatalf("%s %s status = %d, want %d", tt.method, tt.path, w.Code, tt.want)
+			}
+			if tt.want == http.StatusServiceUnavailable && w.Header().Get("Retry-After") == "" {
+				t.Error("blocked request has no Retry-After header")
+			}
+		})
+	}
+}
+
+func TestMaintenanceModeOffAllowsWrites(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	SetMaintenanceMode(false)
+
+	r := gin.New()
+	r.Use(MaintenanceMode())
+	r.POST("/api/chats", func(c *gin.Context) { c.Status(http.StatusOK) })
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/api/chats", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("POST status = %d, want %d", w.Code, http.StatusOK)
+	}
+}
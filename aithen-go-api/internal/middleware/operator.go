@@ -0,0 +1,49 @@
+package middleware
+
+import (
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/aithen/go-api/internal/config"
+	"github.com/gin-gonic/gin"
+)
+
+// parseOperatorIDs parses a comma-separated list of user IDs, skipping invalid entries
+func parseOperatorIDs(raw string) map[int64]bool {
+	ids := make(map[int64]bool)
+	for _, part := range strings.Split(raw, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		id, err := strconv.ParseInt(part, 10, 64)
+		if err != nil {
+			log.Printf("⚠️  Invalid user ID in ADMIN_USER_IDS: %q", part)
+			continue
+		}
+		ids[id] = true
+	}
+	return ids
+}
+
+// RequireOperator returns middleware that lets only the operators listed in ADMIN_USER_IDS
+// (comma-separated user IDs) through. Other authenticated users get 403. With the list
+// empty, nobody can use the routes it guards.
+func RequireOperator() gin.HandlerFunc {
+	operators := parseOperatorIDs(config.GetEnv("ADMIN_USER_IDS"))
+
+	return func(c *gin.Context) {
+		userID, err := GetUserID(c)
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+			return
+		}
+		if !operators[userID] {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "Operator access required"})
+			return
+		}
+		c.Next()
+	}
+}
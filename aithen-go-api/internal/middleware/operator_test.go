@@ -0,0 +1,45 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestRequireOperator(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	tests := []struct {
+		name      string
+		operators string
+		userID    any // nil when the request is not authenticated
+		want      int
+	}{
+		{name: "operator", operators: "1, 2", userID: int64(2), want: http.StatusOK},
+		{name: "other user", operators: "1,2", userID: int64(3), want: http.StatusForbidden},
+		{name: "no operators configured", operators: "", userID: int64(1), want: http.StatusForbidden},
+		{name: "invalid entries skipped", operators: "abc,7", userID: int64(7), want: http.StatusOK},
+		{name: "unauthenticated", operators: "1", userID: nil, want: http.StatusUnauthorized},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("ADMIN_USER_IDS", tt.operators)
+
+			r := gin.New()
+			r.Use(func(c *gin.Context) {
+				if tt.userID != nil {
+					c.Set("user_id", tt.userID)
+				}
+			})
+			r.PUT("/api/admin/maintenance", RequireOperator(), func(c *gin.Context) { c.Status(http.StatusOK) })
+
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, httptest.NewRequest(http.MethodPut, "/api/admin/maintenance", nil))
+			if w.Code != tt.want {
+				t.Errorf("status = %d, want %d", w.Code, tt.want)
+			}
+		})
+	}
+}
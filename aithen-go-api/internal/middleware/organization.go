@@ -0,0 +1,84 @@
+package middleware
+
+import (
+	"net"
+	"net/http"
+	"strings"
+
+	"github.com/aithen/go-api/internal/config"
+	"github.com/aithen/go-api/internal/models"
+	"github.com/gin-gonic/gin"
+)
+
+// OrgSlugHeader names the organization a request is scoped to, for clients of org-scoped
+// routes that omit the :slug path param
+const OrgSlugHeader = "X-Org-Slug"
+
+// ResolveOrganization resolves the organization a request is scoped to and stores it in the
+// context as "organization", "organization_id" and "organization_slug". The organization comes
+// from the X-Org-Slug header, then the subdomain of ORG_BASE_DOMAIN (e.g. acme.example.com when
+// it is example.com), then the :slug path param. A header naming an unknown organization gets 404,
+// while unknown subdomains (such as the API's own host) are ignored. An organization that
+// disagrees with the :slug path param gets 400. Unknown :slug path params are left to the
+// handlers, which report them as before.
+func ResolveOrganization() gin.HandlerFunc {
+	baseDomain := strings.ToLower(strings.TrimPrefix(config.GetEnv("ORG_BASE_DOMAIN"), "."))
+
+	return func(c *gin.Context) {
+		m := models.NewModels()
+		ctx := c.Request.Context()
+		pathSlug := c.Param("slug")
+
+		var org *models.Organization
+		if slug := strings.TrimSpace(c.GetHeader(OrgSlugHeader)); slug != "" {
+			found, err := m.Organizations.FindBySlug(ctx, slug)
+			if err != nil {
+				c.AbortWithStatusJSON(http.StatusNotFound, gin.H{"error": "Organization not found"})
+				return
+			}
+			org = found
+		} else if slug := subdomainSlug(c.Request.Host, baseDomain); baseDomain != "" && slug != "" {
+			org, _ = m.Organizations.FindBySlug(ctx, slug)
+		}
+
+		if org != nil && pathSlug != "" && org.Slug != pathSlug {
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": "Organization in path does not match the request's organization"})
+			return
+		}
+		if org == nil && pathSlug != "" {
+			org, _ = m.Organizations.FindBySlug(ctx, pathSlug)
+		}
+
+		if org != nil {
+			c.Set("organization_id", org.ID)
+			c.Set("organization_slug", org.Slug)
+			c.Set("organization", org)
+		}
+		c.Next()
+	}
+}
+
+// subdomainSlug returns the single-label subdomain of host under baseDomain, or "" if host is
+// not a subdomain of it
+func subdomainSlug(host, baseDomain string) string {
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+	host = strings.ToLower(host)
+
+	sub, ok := strings.CutSuffix(host, "."+baseDomain)
+	if !ok || sub == "" || strings.Contains(sub, ".") {
+		return ""
+	}
+	return sub
+}
+
+// GetOrganizationID gets the organization ID resolved by ResolveOrganization from context
+func GetOrganizationID(c *gin.Context) (int64, bool) {
+	orgID, exists := c.Get("organization_id")
+	if !exists {
+		return 0, false
+	}
+	id, ok := orgID.(int64)
+	return id, ok
+}
@@ -0,0 +1,147 @@
+package middleware
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/aithen/go-api/internal/db"
+	"github.com/aithen/go-api/internal/id"
+	"github.com/aithen/go-api/internal/models"
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+func TestSubdomainSlug(t *testing.T) {
+	tests := []struct {
+		name string
+		host string
+		want string
+	}{
+		{name: "organization subdomain", host: "acme.example.com", want: "acme"},
+		{name: "with port", host: "acme.example.com:8080", want: "acme"},
+		{name: "mixed case", host: "Acme.Example.com", want: "acme"},
+		{name: "base domain", host: "example.com", want: ""},
+		{name: "nested subdomain", host: "api.acme.example.com", want: ""},
+		{name: "other domain", host: "acme.example.org", want: ""},
+		{name: "suffix without dot", host: "acmeexample.com", want: ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := subdomainSlug(tt.host, "example.com"); got != tt.want {
+				t.Errorf("subdomainSlug(%q) = %q, want %q", tt.host, got, tt.want)
+			}
+		})
+	}
+}
+
+// useTestDatabase points db.DB at the migrated database in TEST_DATABASE_URL for the duration
+// of the test, skipping it when unset
+func useTestDatabase(t *testing.T) {
+	t.Helper()
+
+	url := os.Getenv("TEST_DATABASE_URL")
+	if url == "" {
+		t.Skip("TEST_DATABASE_URL not set")
+	}
+	pool, err := pgxpool.New(context.Background(), url)
+	if err != nil {
+		t.Fatalf("failed to connect to test database: %v", err)
+	}
+
+	saved := db.DB
+	db.DB = pool
+	t.Cleanup(func() {
+		db.DB = saved
+		pool.Close()
+	})
+}
+
+// createTestOrganization creates an organization whose owner is deleted with it when the test ends
+func createTestOrganization(t *testing.T, m *models.Models) *models.Organization {
+	t.Helper()
+
+	ctx := context.Background()
+	owner, err := m.Users.Create(ctx, fmt.Sprintf("user-%d@example.com", id.Generate()), "Test User", "password123")
+	if err != nil {
+		t.Fatalf("failed to create user: %v", err)
+	}
+	t.Cleanup(func() { m.Users.DeleteAccount(context.Background(), owner.ID) })
+
+	org, err := m.Organizations.Create(ctx, "Test Org", fmt.Sprintf("test-org-%d", id.Generate()), "", "", "", "", "", "")
+	if err != nil {
+		t.Fatalf("failed to create organization: %v", err)
+	}
+	if _, err := m.Organizations.AddMember(ctx, org.ID, owner.ID, "owner", "active"); err != nil {
+		t.Fatalf("failed to add owner: %v", err)
+	}
+	return org
+}
+
+func TestResolveOrganization(t *testing.T) {
+	useTestDatabase(t)
+	gin.SetMode(gin.TestMode)
+	t.Setenv("ORG_BASE_DOMAIN", "example.com")
+
+	m := models.NewModels()
+	acme := createTestOrganization(t, m)
+	globex := createTestOrganization(t, m)
+
+	r := gin.New()
+	r.Use(ResolveOrganization())
+	respond := func(c *gin.Context) {
+		slug := c.GetString("organization_slug")
+		if org, ok := c.Get("organization"); ok && org.(*models.Organization).Slug != slug {
+			c.Status(http.StatusInternalServerError)
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"slug": slug})
+	}
+	r.GET("/api/orgs/:slug/me", respond)
+	r.GET("/api/org/me", respond)
+
+	tests := []struct {
+		name     string
+		path     string
+		header   string
+		host     string
+		want     int
+		wantSlug string
+	}{
+		{name: "path only", path: "/api/orgs/" + acme.Slug + "/me", want: http.StatusOK, wantSlug: acme.Slug},
+		{name: "header only", path: "/api/org/me", header: globex.Slug, want: http.StatusOK, wantSlug: globex.Slug},
+		{name: "subdomain only", path: "/api/org/me", host: acme.Slug + ".example.com", want: http.StatusOK, wantSlug: acme.Slug},
+		{name: "header wins over subdomain", path: "/api/org/me", header: globex.Slug, host: acme.Slug + ".example.com", want: http.StatusOK, wantSlug: globex.Slug},
+		{name: "header matching path", path: "/api/orgs/" + acme.Slug + "/me", header: acme.Slug, want: http.StatusOK, wantSlug: acme.Slug},
+		{name: "header disagreeing with path", path: "/api/orgs/" + acme.Slug + "/me", header: globex.Slug, want: http.StatusBadRequest},
+		{name: "unknown header", path: "/api/org/me", header: "no-such-org-" + acme.Slug, want: http.StatusNotFound},
+		{name: "unknown subdomain ignored", path: "/api/org/me", host: "api.example.com", want: http.StatusOK, wantSlug: ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, tt.path, nil)
+			if tt.header != "" {
+				req.Header.Set(OrgSlugHeader, tt.header)
+			}
+			if tt.host != "" {
+				req.Host = tt.host
+			}
+
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+			if w.Code != tt.want {
+				t.Fatalf("status = %d, want %d (%s)", w.Code, tt.want, w.Body.String())
+			}
+			if tt.want == http.StatusOK {
+				wantBody := fmt.Sprintf(`"slug":%q`, tt.wantSlug)
+				if !strings.Contains(w.Body.String(), wantBody) {
+					t.Errorf("body = %s, want %s", w.Body.String(), wantBody)
+				}
+			}
+		})
+	}
+}
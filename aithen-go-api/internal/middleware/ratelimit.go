@@ -0,0 +1,102 @@
+package middleware
+
+import (
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/aithen/go-api/internal/config"
+	"github.com/aithen/go-api/internal/models"
+	"github.com/aithen/go-api/internal/ratelimit"
+	"github.com/gin-gonic/gin"
+)
+
+// RateLimit returns middleware that rejects requests with 429 and a Retry-After header once
+// any of the keys returned by keysFunc exceeds its limit. Requests without keys are not limited.
+func RateLimit(limiter *ratelimit.SlidingWindow, keysFunc func(c *gin.Context) []ratelimit.Key) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		keys := keysFunc(c)
+		if len(keys) == 0 {
+			c.Next()
+			return
+		}
+
+		if ok, retryAfter := limiter.Allow(keys...); !ok {
+			seconds := int(math.Ceil(retryAfter.Seconds()))
+			c.Header("Retry-After", strconv.Itoa(seconds))
+			c.AbortWithStatusJSON(http.StatusTooManyRequests, gin.H{
+				"error":       "Too many requests, please try again later",
+				"retry_after": seconds,
+			})
+			return
+		}
+
+		c.Next()
+	}
+}
+
+var (
+	aiChatLimiter     *ratelimit.SlidingWindow
+	aiChatLimiterOnce sync.Once
+)
+
+// AIChatRateLimit limits AI chat requests per minute for each user (AI_CHAT_USER_RATE_LIMIT,
+// default 20) and for each organization the user is an active member of (AI_CHAT_ORG_RATE_LIMIT,
+// default 100). Set a limit to 0 to disable it.
+func AIChatRateLimit() gin.HandlerFunc {
+	aiChatLimiterOnce.Do(func() {
+		aiChatLimiter = ratelimit.New(time.Minute)
+	})
+
+	userLimit := config.GetEnvInt("AI_CHAT_USER_RATE_LIMIT", 20)
+	orgLimit := config.GetEnvInt("AI_CHAT_ORG_RATE_LIMIT", 100)
+
+	return RateLimit(aiChatLimiter, func(c *gin.Context) []ratelimit.Key {
+		userID, err := GetUserID(c)
+		if err != nil {
+			return nil
+		}
+
+		keys := []ratelimit.Key{{Name: fmt.Sprintf("user:%d", userID), Limit: userLimit}}
+		if orgLimit <= 0 {
+			return keys
+		}
+
+		// Chat requests are not scoped to an organization, so they count against every
+		// organization the user belongs to
+		orgs, err := models.NewModels().Organizations.GetUserOrganizations(c.Request.Context(), userID)
+		if err != nil {
+			return keys
+		}
+		for _, org := range orgs {
+			keys = append(keys, ratelimit.Key{Name: fmt.Sprintf("org:%d", org.ID), Limit: orgLimit})
+		}
+		return keys
+	})
+}
+
+var (
+	slugCheckLimiter     *ratelimit.SlidingWindow
+	slugCheckLimiterOnce sync.Once
+)
+
+// SlugCheckRateLimit limits organization slug availability checks per minute for each client IP
+// (ORG_SLUG_CHECK_RATE_LIMIT, default 30), so the public endpoint cannot be used to enumerate
+// organizations quickly. Set the limit to 0 to disable it.
+func SlugCheckRateLimit() gin.HandlerFunc {
+	slugCheckLimiterOnce.Do(func() {
+		slugCheckLimiter = ratelimit.New(time.Minute)
+	})
+
+	limit := config.GetEnvInt("ORG_SLUG_CHECK_RATE_LIMIT", 30)
+
+	return RateLimit(slugCheckLimiter, func(c *gin.Context) []ratelimit.Key {
+		if limit <= 0 {
+			return nil
+		}
+		return []ratelimit.Key{{Name: "slug-check:" + c.ClientIP(), Limit: limit}}
+	})
+}
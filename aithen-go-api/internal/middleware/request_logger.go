@@ -0,0 +1,68 @@
+package middleware
+
+import (
+	"fmt"
+	"log"
+	"math/rand/v2"
+	"strings"
+	"time"
+
+	"github.com/aithen/go-api/internal/config"
+	"github.com/gin-gonic/gin"
+)
+
+// Paths whose successful requests are not logged unless REQUEST_LOG_SKIP_PATHS overrides them
+const defaultRequestLogSkipPaths = "/ping,/readyz,/healthz,/metrics"
+
+// RequestLogger returns middleware that logs one line of key=value fields per request in
+// place of gin's default logger. Successful requests to REQUEST_LOG_SKIP_PATHS (comma
+// separated, default /ping,/readyz,/healthz,/metrics) are not logged, and other successful
+// requests are logged at REQUEST_LOG_SAMPLE_RATE percent (default 100). Requests ending
+// with a 4xx or 5xx status or a handler error are always logged.
+func RequestLogger() gin.HandlerFunc {
+	skipPaths := config.GetEnv("REQUEST_LOG_SKIP_PATHS")
+	if skipPaths == "" {
+		skipPaths = defaultRequestLogSkipPaths
+	}
+	skip := make(map[string]bool)
+	for _, path := range strings.Split(skipPaths, ",") {
+		if path = strings.TrimSpace(path); path != "" {
+			skip[path] = true
+		}
+	}
+
+	sampleRate := config.GetEnvInt("REQUEST_LOG_SAMPLE_RATE", 100)
+
+	return func(c *gin.Context) {
+		start := time.Now()
+		c.Next()
+
+		status := c.Writer.Status()
+		failed := status >= 400 || len(c.Errors) > 0
+		if !failed {
+			if skip[c.Request.URL.Path] {
+				return
+			}
+			if sampleRate < 100 && rand.IntN(100) >= sampleRate {
+				return
+			}
+		}
+
+		userID := "-"
+		if id, ok := c.Get("user_id"); ok {
+			userID = fmt.Sprint(id)
+		}
+		requestID := c.GetHeader("X-Request-ID")
+		if requestID == "" {
+			requestID = "-"
+		}
+
+		line := "request method=%s path=%s status=%d latency=%s user_id=%s request_id=%s client_ip=%s"
+		args := []any{c.Request.Method, c.Request.URL.Path, status, time.Since(start), userID, requestID, c.ClientIP()}
+		if len(c.Errors) > 0 {
+			line += " error=%q"
+			args = append(args, c.Errors.String())
+		}
+		log.Printf(line, args...)
+	}
+}
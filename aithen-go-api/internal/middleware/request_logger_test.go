@@ -0,0 +1,124 @@
+package middleware
+
+import (
+	"bytes"
+	"errors"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+// requestLoggerServer returns a router using RequestLogger and the buffer it logs to
+func requestLoggerServer(t *testing.T) (*gin.Engine, *bytes.Buffer) {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+
+	var buf bytes.Buffer
+	flags, output := log.Flags(), log.Writer()
+	log.SetFlags(0)
+	log.SetOutput(&buf)
+	t.Cleanup(func() {
+		log.SetFlags(flags)
+		log.SetOutput(output)
+	})
+
+	r := gin.New()
+	r.Use(RequestLogger())
+	r.GET("/ping", func(c *gin.Context) { c.Status(http.StatusOK) })
+	r.GET("/ok", func(c *gin.Context) {
+		c.Set("user_id", int64(42))
+		c.Status(http.StatusOK)
+	})
+	r.GET("/fail", func(c *gin.Context) { c.Status(http.StatusInternalServerError) })
+	r.GET("/handler-error", func(c *gin.Context) {
+		c.Error(errors.New("upstream unavailable"))
+		c.Status(http.StatusOK)
+	})
+	return r, &buf
+}
+
+func logLines(buf *bytes.Buffer) []string {
+	var lines []string
+	for _, line := range strings.Split(buf.String(), "\n") {
+		if line != "" {
+			lines = append(lines, line)
+		}
+	}
+	return lines
+}
+
+func TestRequestLoggerFields(t *testing.T) {
+	r, buf := requestLoggerServer(t)
+
+	req := httptest.NewRequest(http.MethodGet, "/ok", nil)
+	req.Header.Set("X-Request-ID", "req-1")
+	r.ServeHTTP(httptest.NewRecorder(), req)
+
+	lines := logLines(buf)
+	if len(lines) != 1 {
+		t.Fatalf("logged %d lines, want 1: %q", len(lines), lines)
+	}
+	for _, field := range []string{"method=GET", "path=/ok", "status=200", "latency=", "user_id=42", "request_id=req-1", "client_ip="} {
+		if !strings.Contains(lines[0], field) {
+			t.Errorf("log line %q is missing %s", lines[0], field)
+		}
+	}
+}
+
+func TestRequestLoggerSkipsPathsAndSamples(t *testing.T) {
+	tests := []struct {
+		name      string
+		rate      string
+		path      string
+		wantLines int
+	}{
+		{name: "health check is skipped", rate: "100", path: "/ping", wantLines: 0},
+		{name: "every success is logged at 100", rate: "100", path: "/ok", wantLines: 20},
+		{name: "no success is logged at 0", rate: "0", path: "/ok", wantLines: 0},
+		{name: "error status is always logged", rate: "0", path: "/fail", wantLines: 20},
+		{name: "handler error is always logged", rate: "0", path: "/handler-error", wantLines: 20},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("REQUEST_LOG_SAMPLE_RATE", tt.rate)
+			t.Setenv("REQUEST_LOG_SKIP_PATHS", "")
+			r, buf := requestLoggerServer(t)
+
+			for i := 0; i < 20; i++ {
+				r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, tt.path, nil))
+			}
+			if got := len(logLines(buf)); got != tt.wantLines {
+				t.Errorf("logged %d of 20 requests to %s, want %d", got, tt.path, tt.wantLines)
+			}
+		})
+	}
+}
+
+func TestRequestLoggerSkippedPathErrorsAreLogged(t *testing.T) {
+	t.Setenv("REQUEST_LOG_SKIP_PATHS", "/fail")
+	r, buf := requestLoggerServer(t)
+
+	r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/fail", nil))
+	if lines := logLines(buf); len(lines) != 1 || !strings.Contains(lines[0], "status=500") {
+		t.Errorf("logged %q, want the failed request on a skipped path", lines)
+	}
+}
+
+func TestRequestLoggerSampleRate(t *testing.T) {
+	t.Setenv("REQUEST_LOG_SAMPLE_RATE", "25")
+	r, buf := requestLoggerServer(t)
+
+	const requests = 2000
+	for i := 0; i < requests; i++ {
+		r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/ok", nil))
+	}
+
+	// 25% of 2000 is 500; the bounds are many standard deviations wide
+	if got := len(logLines(buf)); got < 350 || got > 650 {
+		t.Errorf("logged %d of %d requests at a 25%% sample rate, want about 500", got, requests)
+	}
+}
@@ -0,0 +1,66 @@
+package migrations
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+)
+
+// migrationVersionRegex matches the version prefix of a migration file name
+var migrationVersionRegex = regexp.MustCompile(`^(\d+)_`)
+
+// LatestFileVersion returns the highest migration version among the files in dir, or 0 if
+// there are none
+func LatestFileVersion(dir string) (uint, error) {
+	files, err := os.ReadDir(dir)
+	if err != nil {
+		return 0, err
+	}
+
+	var maxVersion uint
+	for _, file := range files {
+		if file.IsDir() {
+			continue
+		}
+
+		matches := migrationVersionRegex.FindStringSubmatch(file.Name())
+		if len(matches) > 1 {
+			version, err := strconv.ParseUint(matches[1], 10, 64)
+			if err == nil && uint(version) > maxVersion {
+				maxVersion = uint(version)
+			}
+		}
+	}
+
+	return maxVersion, nil
+}
+
+// CheckUpToDate returns an error if the database has not applied the latest migration file
+// or was left dirty by a failed migration. It is run at server startup when REQUIRE_MIGRATIONS
+// is set.
+func CheckUpToDate() error {
+	latest, err := LatestFileVersion(filepath.Join("internal", "migrations", "files"))
+	if err != nil {
+		return fmt.Errorf("failed to read migration files: %w", err)
+	}
+
+	current, dirty, err := GetMigrationVersion()
+	if err != nil {
+		return fmt.Errorf("failed to get database migration version: %w", err)
+	}
+
+	return compareVersions(current, latest, dirty)
+}
+
+// compareVersions checks a database migration version against the latest migration file
+func compareVersions(current, latest uint, dirty bool) error {
+	if dirty {
+		return fmt.Errorf("database is at dirty migration version %d; fix it with the force command", current)
+	}
+	if current < latest {
+		return fmt.Errorf("database is at migration version %d but the latest migration is %d; run the migrations first", current, latest)
+	}
+	return nil
+}
@@ -0,0 +1,59 @@
+package migrations
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestCompareVersions(t *testing.T) {
+	tests := []struct {
+		name    string
+		current uint
+		latest  uint
+		dirty   bool
+		wantErr bool
+	}{
+		{name: "behind", current: 24, latest: 26, wantErr: true},
+		{name: "equal", current: 26, latest: 26},
+		// A database migrated by a newer build still works with this one
+		{name: "ahead", current: 27, latest: 26},
+		{name: "dirty", current: 26, latest: 26, dirty: true, wantErr: true},
+		{name: "dirty and ahead", current: 27, latest: 26, dirty: true, wantErr: true},
+		{name: "fresh database", current: 0, latest: 26, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := compareVersions(tt.current, tt.latest, tt.dirty)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("compareVersions(%d, %d, %v) error = %v, wantErr %v", tt.current, tt.latest, tt.dirty, err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestLatestFileVersion(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{
+		"000001_create_users.up.sql",
+		"000001_create_users.down.sql",
+		"000012_add_plans.up.sql",
+		"000003_add_index.up.sql",
+		"README.md",
+	} {
+		if err := os.WriteFile(filepath.Join(dir, name), nil, 0644); err != nil {
+			t.Fatalf("failed to write %s: %v", name, err)
+		}
+	}
+	if err := os.Mkdir(filepath.Join(dir, "000099_dir"), 0755); err != nil {
+		t.Fatalf("failed to create directory: %v", err)
+	}
+
+	got, err := LatestFileVersion(dir)
+	if err != nil {
+		t.Fatalf("LatestFileVersion() error = %v", err)
+	}
+	if got != 12 {
+		t.Errorf("LatestFileVersion() = %d, want 12", got)
+	}
+}
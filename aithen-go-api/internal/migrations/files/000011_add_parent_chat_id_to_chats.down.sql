@@ -0,0 +1,7 @@
+-- Migration: add_parent_chat_id_to_chats (rollback)
+-- Removes parent_chat_id column from chats table
+
+DROP INDEX IF EXISTS idx_chats_parent_chat_id;
+
+ALTER TABLE chats
+    DROP COLUMN IF EXISTS parent_chat_id;
@@ -0,0 +1,8 @@
+-- Migration: add_parent_chat_id_to_chats
+-- Created: 2026-10-17
+-- Links branched chats to the chat they were forked from
+
+ALTER TABLE chats
+    ADD COLUMN IF NOT EXISTS parent_chat_id BIGINT REFERENCES chats(id) ON DELETE SET NULL;
+
+CREATE INDEX IF NOT EXISTS idx_chats_parent_chat_id ON chats(parent_chat_id);
@@ -0,0 +1,16 @@
+-- Migration: create_kb_permissions_table (rollback)
+-- Drops kb_permissions table
+
+-- Drop foreign key constraints first
+ALTER TABLE kb_permissions
+    DROP CONSTRAINT IF EXISTS fk_kb_permissions_user;
+
+ALTER TABLE kb_permissions
+    DROP CONSTRAINT IF EXISTS fk_kb_permissions_knowledge_base;
+
+-- Drop indexes
+DROP INDEX IF EXISTS idx_kb_permissions_user_id;
+DROP INDEX IF EXISTS idx_kb_permissions_kb_id;
+
+-- Drop table
+DROP TABLE IF EXISTS kb_permissions;
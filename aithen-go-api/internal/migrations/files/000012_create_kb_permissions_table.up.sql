@@ -0,0 +1,27 @@
+-- Migration: create_kb_permissions_table
+-- Created: 2026-10-17
+-- Creates kb_permissions table for per-user knowledge base access within an organization
+
+-- Members without a row get read access; org owners/admins bypass this table
+CREATE TABLE IF NOT EXISTS kb_permissions (
+    id BIGINT PRIMARY KEY,
+    knowledge_base_id BIGINT NOT NULL,
+    user_id BIGINT NOT NULL,
+    permission VARCHAR(20) NOT NULL CHECK (permission IN ('read', 'write', 'admin')),
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE(knowledge_base_id, user_id)
+);
+
+-- Create indexes
+CREATE INDEX IF NOT EXISTS idx_kb_permissions_kb_id ON kb_permissions(knowledge_base_id);
+CREATE INDEX IF NOT EXISTS idx_kb_permissions_user_id ON kb_permissions(user_id);
+
+-- Create foreign key constraints
+ALTER TABLE kb_permissions
+    ADD CONSTRAINT fk_kb_permissions_knowledge_base
+    FOREIGN KEY (knowledge_base_id) REFERENCES knowledge_bases(id) ON DELETE CASCADE;
+
+ALTER TABLE kb_permissions
+    ADD CONSTRAINT fk_kb_permissions_user
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE;
@@ -0,0 +1,11 @@
+-- Migration: add_created_by_to_knowledge_bases (rollback)
+-- Removes created_by columns from knowledge_bases and knowledge_base_files
+
+DROP INDEX IF EXISTS idx_knowledge_base_files_created_by;
+DROP INDEX IF EXISTS idx_knowledge_bases_created_by;
+
+ALTER TABLE knowledge_base_files
+    DROP COLUMN IF EXISTS created_by;
+
+ALTER TABLE knowledge_bases
+    DROP COLUMN IF EXISTS created_by;
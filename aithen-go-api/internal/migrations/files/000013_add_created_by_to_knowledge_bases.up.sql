@@ -0,0 +1,13 @@
+-- Migration: add_created_by_to_knowledge_bases
+-- Created: 2026-10-17
+-- Tracks which user created each knowledge base and uploaded each file
+-- Existing rows are left as NULL (creator unknown)
+
+ALTER TABLE knowledge_bases
+    ADD COLUMN IF NOT EXISTS created_by BIGINT REFERENCES users(id) ON DELETE SET NULL;
+
+ALTER TABLE knowledge_base_files
+    ADD COLUMN IF NOT EXISTS created_by BIGINT REFERENCES users(id) ON DELETE SET NULL;
+
+CREATE INDEX IF NOT EXISTS idx_knowledge_bases_created_by ON knowledge_bases(created_by);
+CREATE INDEX IF NOT EXISTS idx_knowledge_base_files_created_by ON knowledge_base_files(created_by);
@@ -0,0 +1,16 @@
+-- Migration: create_message_attachments_table (rollback)
+-- Drops message_attachments table
+
+-- Drop foreign key constraints first
+ALTER TABLE message_attachments
+    DROP CONSTRAINT IF EXISTS fk_message_attachments_kb_file;
+
+ALTER TABLE message_attachments
+    DROP CONSTRAINT IF EXISTS fk_message_attachments_message;
+
+-- Drop indexes
+DROP INDEX IF EXISTS idx_message_attachments_kb_file_id;
+DROP INDEX IF EXISTS idx_message_attachments_message_id;
+
+-- Drop table
+DROP TABLE IF EXISTS message_attachments;
@@ -0,0 +1,24 @@
+-- Migration: create_message_attachments_table
+-- Created: 2026-10-17
+-- Links chat messages to knowledge base files attached as context
+
+CREATE TABLE IF NOT EXISTS message_attachments (
+    id BIGINT PRIMARY KEY,
+    message_id BIGINT NOT NULL,
+    knowledge_base_file_id BIGINT NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE(message_id, knowledge_base_file_id)
+);
+
+-- Create indexes
+CREATE INDEX IF NOT EXISTS idx_message_attachments_message_id ON message_attachments(message_id);
+CREATE INDEX IF NOT EXISTS idx_message_attachments_kb_file_id ON message_attachments(knowledge_base_file_id);
+
+-- Create foreign key constraints
+ALTER TABLE message_attachments
+    ADD CONSTRAINT fk_message_attachments_message
+    FOREIGN KEY (message_id) REFERENCES messages(id) ON DELETE CASCADE;
+
+ALTER TABLE message_attachments
+    ADD CONSTRAINT fk_message_attachments_kb_file
+    FOREIGN KEY (knowledge_base_file_id) REFERENCES knowledge_base_files(id) ON DELETE CASCADE;
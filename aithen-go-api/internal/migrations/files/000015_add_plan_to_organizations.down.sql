@@ -0,0 +1,5 @@
+-- Migration: add_plan_to_organizations (rollback)
+-- Removes plan column from organizations table
+
+ALTER TABLE organizations
+    DROP COLUMN IF EXISTS plan;
@@ -0,0 +1,7 @@
+-- Migration: add_plan_to_organizations
+-- Created: 2026-10-17
+-- Adds the subscription plan that determines an organization's usage limits
+
+ALTER TABLE organizations
+    ADD COLUMN IF NOT EXISTS plan VARCHAR(20) NOT NULL DEFAULT 'free'
+    CHECK (plan IN ('free', 'pro', 'enterprise'));
@@ -0,0 +1,6 @@
+-- Migration: add_chunking_to_versions (rollback)
+-- Removes chunking parameter columns from knowledge_base_versions table
+
+ALTER TABLE knowledge_base_versions
+    DROP COLUMN IF EXISTS chunk_overlap,
+    DROP COLUMN IF EXISTS chunk_size;
@@ -0,0 +1,7 @@
+-- Migration: add_chunking_to_versions
+-- Created: 2026-10-17
+-- Records the chunking parameters requested for each training run (NULL means the training service defaults)
+
+ALTER TABLE knowledge_base_versions
+    ADD COLUMN IF NOT EXISTS chunk_size INTEGER,
+    ADD COLUMN IF NOT EXISTS chunk_overlap INTEGER;
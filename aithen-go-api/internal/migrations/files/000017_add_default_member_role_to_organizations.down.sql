@@ -0,0 +1,5 @@
+-- Migration: add_default_member_role_to_organizations (rollback)
+-- Removes default_member_role column from organizations table
+
+ALTER TABLE organizations
+    DROP COLUMN IF EXISTS default_member_role;
@@ -0,0 +1,7 @@
+-- Migration: add_default_member_role_to_organizations
+-- Created: 2026-10-17
+-- Adds the role given to members who join an organization (NULL falls back to DEFAULT_MEMBER_ROLE)
+
+ALTER TABLE organizations
+    ADD COLUMN IF NOT EXISTS default_member_role VARCHAR(50)
+    CHECK (default_member_role IN ('admin', 'member', 'viewer'));
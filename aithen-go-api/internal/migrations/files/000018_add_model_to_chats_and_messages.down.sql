@@ -0,0 +1,8 @@
+-- Migration: add_model_to_chats_and_messages (rollback)
+-- Removes model columns from chats and messages tables
+
+ALTER TABLE messages
+    DROP COLUMN IF EXISTS model;
+
+ALTER TABLE chats
+    DROP COLUMN IF EXISTS model;
@@ -0,0 +1,9 @@
+-- Migration: add_model_to_chats_and_messages
+-- Created: 2026-10-17
+-- Records the AI model used for assistant messages and the default model of each chat
+
+ALTER TABLE chats
+    ADD COLUMN IF NOT EXISTS model VARCHAR(100);
+
+ALTER TABLE messages
+    ADD COLUMN IF NOT EXISTS model VARCHAR(100);
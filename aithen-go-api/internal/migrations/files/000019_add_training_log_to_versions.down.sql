@@ -0,0 +1,5 @@
+-- Migration: add_training_log_to_versions (rollback)
+-- Removes training_log column from knowledge_base_versions table
+
+ALTER TABLE knowledge_base_versions
+    DROP COLUMN IF EXISTS training_log;
@@ -0,0 +1,6 @@
+-- Migration: add_training_log_to_versions
+-- Created: 2026-10-17
+-- Stores the progress and error messages of each version's training run
+
+ALTER TABLE knowledge_base_versions
+    ADD COLUMN IF NOT EXISTS training_log TEXT;
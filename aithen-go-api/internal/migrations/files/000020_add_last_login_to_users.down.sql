@@ -0,0 +1,6 @@
+-- Migration: add_last_login_to_users (rollback)
+-- Removes last_login_at and last_login_ip columns from users table
+
+ALTER TABLE users
+    DROP COLUMN IF EXISTS last_login_ip,
+    DROP COLUMN IF EXISTS last_login_at;
@@ -0,0 +1,7 @@
+-- Migration: add_last_login_to_users
+-- Created: 2026-10-17
+-- Records when and from which IP address each user last logged in
+
+ALTER TABLE users
+    ADD COLUMN IF NOT EXISTS last_login_at TIMESTAMP,
+    ADD COLUMN IF NOT EXISTS last_login_ip VARCHAR(45);
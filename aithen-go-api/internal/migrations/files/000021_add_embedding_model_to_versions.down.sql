@@ -0,0 +1,5 @@
+-- Migration: add_embedding_model_to_versions (rollback)
+-- Removes embedding_model column from knowledge_base_versions table
+
+ALTER TABLE knowledge_base_versions
+    DROP COLUMN IF EXISTS embedding_model;
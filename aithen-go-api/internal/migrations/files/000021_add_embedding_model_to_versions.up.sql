@@ -0,0 +1,6 @@
+-- Migration: add_embedding_model_to_versions
+-- Created: 2026-10-17
+-- Records the embedding model a version was trained with (NULL means the training service default)
+
+ALTER TABLE knowledge_base_versions
+    ADD COLUMN IF NOT EXISTS embedding_model VARCHAR(255);
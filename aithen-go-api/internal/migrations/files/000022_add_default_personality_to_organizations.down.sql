@@ -0,0 +1,5 @@
+-- Migration: add_default_personality_to_organizations (rollback)
+-- Removes default_personality_id column from organizations table
+
+ALTER TABLE organizations
+    DROP COLUMN IF EXISTS default_personality_id;
@@ -0,0 +1,6 @@
+-- Migration: add_default_personality_to_organizations
+-- Created: 2026-10-17
+-- Adds the personality used for members' chat requests that do not name one (NULL uses the AI service default)
+
+ALTER TABLE organizations
+    ADD COLUMN IF NOT EXISTS default_personality_id VARCHAR(255);
@@ -0,0 +1,7 @@
+-- Migration: add_metadata_to_chats (rollback)
+-- Removes metadata column and its index from chats table
+
+DROP INDEX IF EXISTS idx_chats_metadata;
+
+ALTER TABLE chats
+    DROP COLUMN IF EXISTS metadata;
@@ -0,0 +1,8 @@
+-- Migration: add_metadata_to_chats
+-- Created: 2026-10-17
+-- Stores integrator-supplied metadata on chats (e.g. ticket IDs), searchable with @>
+
+ALTER TABLE chats
+    ADD COLUMN IF NOT EXISTS metadata JSONB NOT NULL DEFAULT '{}'::jsonb;
+
+CREATE INDEX IF NOT EXISTS idx_chats_metadata ON chats USING GIN (metadata);
@@ -0,0 +1,4 @@
+-- Migration: create_knowledge_base_training_errors_table (rollback)
+-- Drops knowledge_base_training_errors table
+
+DROP TABLE IF EXISTS knowledge_base_training_errors;
@@ -0,0 +1,18 @@
+-- Migration: create_knowledge_base_training_errors_table
+-- Created: 2026-10-17
+-- Records why individual files failed during a version's training run
+
+CREATE TABLE IF NOT EXISTS knowledge_base_training_errors (
+    id BIGINT PRIMARY KEY,
+    knowledge_base_version_id BIGINT NOT NULL REFERENCES knowledge_base_versions(id) ON DELETE CASCADE,
+    knowledge_base_file_id BIGINT NOT NULL REFERENCES knowledge_base_files(id) ON DELETE CASCADE,
+    file_name VARCHAR(255) NOT NULL, -- File name at the time of the run
+    job_id VARCHAR(255) NOT NULL, -- Training job that handled the file
+    job_index INTEGER NOT NULL,
+    error_message TEXT NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+-- One error per file per version; a retry that fails again replaces it
+CREATE UNIQUE INDEX IF NOT EXISTS idx_knowledge_base_training_errors_version_file
+ON knowledge_base_training_errors(knowledge_base_version_id, knowledge_base_file_id);
@@ -0,0 +1,11 @@
+-- Migration: add_cancelled_status_to_versions (rollback)
+-- Marks cancelled versions as failed and restores the original status check
+
+UPDATE knowledge_base_versions SET status = 'failed' WHERE status = 'cancelled';
+
+ALTER TABLE knowledge_base_versions
+    DROP CONSTRAINT IF EXISTS knowledge_base_versions_status_check;
+
+ALTER TABLE knowledge_base_versions
+    ADD CONSTRAINT knowledge_base_versions_status_check
+    CHECK (status IN ('training', 'completed', 'failed'));
@@ -0,0 +1,10 @@
+-- Migration: add_cancelled_status_to_versions
+-- Created: 2026-10-17
+-- Allows versions whose training run was cancelled to be marked as such
+
+ALTER TABLE knowledge_base_versions
+    DROP CONSTRAINT IF EXISTS knowledge_base_versions_status_check;
+
+ALTER TABLE knowledge_base_versions
+    ADD CONSTRAINT knowledge_base_versions_status_check
+    CHECK (status IN ('training', 'completed', 'failed', 'cancelled'));
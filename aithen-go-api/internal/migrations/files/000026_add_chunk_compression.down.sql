@@ -0,0 +1,22 @@
+-- Migration: add_chunk_compression (rollback)
+-- Removes chunk compression columns. SQL cannot gunzip the compressed chunks back into
+-- chunk_text, so the rollback refuses to run while any exist rather than lose their text;
+-- retrain the affected knowledge bases with compress_chunks off first.
+
+DO $$
+BEGIN
+    IF EXISTS (SELECT 1 FROM knowledge_base_embeddings WHERE compressed) THEN
+        RAISE EXCEPTION 'cannot roll back chunk compression: compressed chunks exist; retrain their knowledge bases with compress_chunks off or delete those versions first';
+    END IF;
+END $$;
+
+ALTER TABLE knowledge_base_versions
+    DROP COLUMN IF EXISTS compression_savings;
+
+ALTER TABLE knowledge_base_embeddings
+    DROP COLUMN IF EXISTS chunk_length,
+    DROP COLUMN IF EXISTS chunk_data,
+    DROP COLUMN IF EXISTS compressed;
+
+ALTER TABLE knowledge_bases
+    DROP COLUMN IF EXISTS compress_chunks;
@@ -0,0 +1,15 @@
+-- Migration: add_chunk_compression
+-- Created: 2026-10-17
+-- Allows knowledge bases to store chunk text gzip-compressed and records the bytes saved per version
+
+ALTER TABLE knowledge_bases
+    ADD COLUMN IF NOT EXISTS compress_chunks BOOLEAN NOT NULL DEFAULT false;
+
+-- Compressed chunks keep an empty chunk_text, their gzip data and their length in characters
+ALTER TABLE knowledge_base_embeddings
+    ADD COLUMN IF NOT EXISTS compressed BOOLEAN NOT NULL DEFAULT false,
+    ADD COLUMN IF NOT EXISTS chunk_data BYTEA,
+    ADD COLUMN IF NOT EXISTS chunk_length INTEGER;
+
+ALTER TABLE knowledge_base_versions
+    ADD COLUMN IF NOT EXISTS compression_savings BIGINT NOT NULL DEFAULT 0;
@@ -1,10 +1,13 @@
 package migrations
 
 import (
+	"context"
 	"database/sql"
 	"fmt"
 	"log"
 	"path/filepath"
+	"sort"
+	"strings"
 
 	"github.com/aithen/go-api/internal/config"
 	"github.com/golang-migrate/migrate/v4"
@@ -25,6 +28,34 @@ func buildDatabaseURL() string {
 	)
 }
 
+// migrationLockKey identifies the Postgres advisory lock that serializes migration
+// commands, so concurrent deploys do not run migrations against each other
+const migrationLockKey int64 = 7368656661746868
+
+// withMigrationLock runs fn while holding the migration advisory lock. The lock is
+// session-level, so it is taken on a dedicated connection and released afterwards.
+func withMigrationLock(db *sql.DB, fn func() error) error {
+	ctx := context.Background()
+
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get connection for migration lock: %w", err)
+	}
+	defer conn.Close()
+
+	log.Println("🔒 Acquiring migration lock...")
+	if _, err := conn.ExecContext(ctx, "SELECT pg_advisory_lock($1)", migrationLockKey); err != nil {
+		return fmt.Errorf("failed to acquire migration lock: %w", err)
+	}
+	defer func() {
+		if _, err := conn.ExecContext(ctx, "SELECT pg_advisory_unlock($1)", migrationLockKey); err != nil {
+			log.Printf("⚠️  Failed to release migration lock: %v", err)
+		}
+	}()
+
+	return fn()
+}
+
 // RunMigrations runs all pending migrations
 func RunMigrations() error {
 	// Load environment variables
@@ -40,6 +71,13 @@ func RunMigrations() error {
 	}
 	defer db.Close()
 
+	return withMigrationLock(db, func() error {
+		return migrateUp(db)
+	})
+}
+
+// migrateUp runs all pending migrations on db. The caller must hold the migration lock.
+func migrateUp(db *sql.DB) error {
 	// Create postgres driver instance
 	driver, err := postgres.WithInstance(db, &postgres.Config{})
 	if err != nil {
@@ -103,16 +141,18 @@ func DownMigrations() error {
 		return fmt.Errorf("failed to create migrate instance: %w", err)
 	}
 
-	if err := m.Down(); err != nil {
-		if err == migrate.ErrNoChange {
-			log.Println("✅ No migrations to rollback")
-			return nil
+	return withMigrationLock(db, func() error {
+		if err := m.Down(); err != nil {
+			if err == migrate.ErrNoChange {
+				log.Println("✅ No migrations to rollback")
+				return nil
+			}
+			return fmt.Errorf("failed to rollback migrations: %w", err)
 		}
-		return fmt.Errorf("failed to rollback migrations: %w", err)
-	}
 
-	log.Println("✅ Migration rolled back successfully")
-	return nil
+		log.Println("✅ Migration rolled back successfully")
+		return nil
+	})
 }
 
 // GetMigrationVersion returns the current migration version
@@ -155,10 +195,27 @@ func GetMigrationVersion() (uint, bool, error) {
 	return version, dirty, nil
 }
 
+// FreshOptions controls how FreshMigrations runs
+type FreshOptions struct {
+	// DryRun reports what would be dropped and migrated without changing anything
+	DryRun bool
+	// Force confirms the destructive run; without it FreshMigrations refuses to drop tables
+	Force bool
+}
+
 // FreshMigrations drops all tables and re-runs all migrations (like Laravel's migrate:fresh)
-func FreshMigrations() error {
+func FreshMigrations(opts FreshOptions) error {
 	config.LoadEnv()
 
+	if !opts.DryRun {
+		if config.GetEnv("APP_ENV") == "production" {
+			return fmt.Errorf("refusing to drop tables when APP_ENV=production")
+		}
+		if !opts.Force {
+			return fmt.Errorf("refusing to drop tables without -force (use -dry-run to preview)")
+		}
+	}
+
 	dbUrl := buildDatabaseURL()
 
 	db, err := sql.Open("pgx", dbUrl)
@@ -167,6 +224,57 @@ func FreshMigrations() error {
 	}
 	defer db.Close()
 
+	if opts.DryRun {
+		return reportFreshPlan(db)
+	}
+
+	// Drop and re-migrate under one lock so another instance cannot migrate in between
+	return withMigrationLock(db, func() error {
+		return dropAndMigrate(db)
+	})
+}
+
+// reportFreshPlan logs the tables a fresh run would drop and the migrations it would apply
+func reportFreshPlan(db *sql.DB) error {
+	rows, err := db.Query("SELECT tablename FROM pg_tables WHERE schemaname = 'public' ORDER BY tablename")
+	if err != nil {
+		return fmt.Errorf("failed to list tables: %w", err)
+	}
+	defer rows.Close()
+
+	var tables []string
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return fmt.Errorf("failed to scan table name: %w", err)
+		}
+		tables = append(tables, name)
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("failed to list tables: %w", err)
+	}
+
+	migrationFiles, err := filepath.Glob(filepath.Join("internal", "migrations", "files", "*.up.sql"))
+	if err != nil {
+		return fmt.Errorf("failed to list migration files: %w", err)
+	}
+	sort.Strings(migrationFiles)
+
+	log.Println("🔍 Dry run: no changes will be made")
+	log.Printf("Tables that would be dropped (%d):", len(tables))
+	for _, name := range tables {
+		log.Printf("   🗑️  %s", name)
+	}
+	log.Printf("Migrations that would run (%d):", len(migrationFiles))
+	for _, file := range migrationFiles {
+		log.Printf("   📄 %s", strings.TrimSuffix(filepath.Base(file), ".up.sql"))
+	}
+
+	return nil
+}
+
+// dropAndMigrate drops all tables and runs all migrations. The caller must hold the migration lock.
+func dropAndMigrate(db *sql.DB) error {
 	log.Println("🔄 Dropping all tables...")
 
 	// Drop all tables in the public schema
@@ -181,7 +289,7 @@ func FreshMigrations() error {
 		END $$;
 	`
 
-	_, err = db.Exec(dropTablesQuery)
+	_, err := db.Exec(dropTablesQuery)
 	if err != nil {
 		return fmt.Errorf("failed to drop tables: %w", err)
 	}
@@ -190,7 +298,7 @@ func FreshMigrations() error {
 
 	// Now run migrations
 	log.Println("🔄 Running migrations...")
-	return RunMigrations()
+	return migrateUp(db)
 }
 
 // ForceVersion forces the database to a specific migration version (clears dirty flag)
@@ -222,10 +330,12 @@ func ForceVersion(version int) error {
 		return fmt.Errorf("failed to create migrate instance: %w", err)
 	}
 
-	if err := m.Force(version); err != nil {
-		return fmt.Errorf("failed to force version: %w", err)
-	}
+	return withMigrationLock(db, func() error {
+		if err := m.Force(version); err != nil {
+			return fmt.Errorf("failed to force version: %w", err)
+		}
 
-	log.Printf("✅ Forced database version to %d (dirty flag cleared)", version)
-	return nil
+		log.Printf("✅ Forced database version to %d (dirty flag cleared)", version)
+		return nil
+	})
 }
@@ -0,0 +1,174 @@
+package migrations
+
+import (
+	"bytes"
+	"database/sql"
+	"fmt"
+	"log"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// useTestDatabase points the DB_* settings at TEST_DATABASE_URL and runs the test from the
+// project root, where the migration files are resolved. It skips the test when the URL is unset.
+func useTestDatabase(t *testing.T) *sql.DB {
+	t.Helper()
+
+	raw := os.Getenv("TEST_DATABASE_URL")
+	if raw == "" {
+		t.Skip("TEST_DATABASE_URL not set")
+	}
+	u, err := url.Parse(raw)
+	if err != nil {
+		t.Fatalf("invalid TEST_DATABASE_URL: %v", err)
+	}
+
+	password, _ := u.User.Password()
+	name := strings.TrimPrefix(u.Path, "/")
+	if u.RawQuery != "" {
+		// buildDatabaseURL appends DB_NAME last, so connection options ride along with it
+		name += "?" + u.RawQuery
+	}
+	t.Setenv("DB_USER", u.User.Username())
+	t.Setenv("DB_PASS", password)
+	t.Setenv("DB_HOST", u.Hostname())
+	t.Setenv("DB_PORT", u.Port())
+	t.Setenv("DB_NAME", name)
+	t.Chdir("../..")
+
+	db, err := sql.Open("pgx", raw)
+	if err != nil {
+		t.Fatalf("failed to open test database: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+	return db
+}
+
+func TestWithMigrationLockSerializes(t *testing.T) {
+	db := useTestDatabase(t)
+
+	var running, overlapped atomic.Int32
+	var wg sync.WaitGroup
+	for i := 0; i < 2; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			err := withMigrationLock(db, func() error {
+				if running.Add(1) > 1 {
+					overlapped.Store(1)
+				}
+				time.Sleep(200 * time.Millisecond)
+				running.Add(-1)
+				return nil
+			})
+			if err != nil {
+				t.Errorf("withMigrationLock() error = %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if overlapped.Load() != 0 {
+		t.Error("two callers held the migration lock at the same time")
+	}
+}
+
+func TestRunMigrationsConcurrently(t *testing.T) {
+	useTestDatabase(t)
+
+	errs := make(chan error, 2)
+	for i := 0; i < 2; i++ {
+		go func() { errs <- RunMigrations() }()
+	}
+	for i := 0; i < 2; i++ {
+		if err := <-errs; err != nil {
+			t.Errorf("RunMigrations() error = %v", err)
+		}
+	}
+
+	version, dirty, err := GetMigrationVersion()
+	if err != nil {
+		t.Fatalf("GetMigrationVersion() error = %v", err)
+	}
+	if dirty {
+		t.Errorf("database is dirty at version %d after concurrent runs", version)
+	}
+	latest, err := LatestFileVersion(filepath.Join("internal", "migrations", "files"))
+	if err != nil {
+		t.Fatalf("LatestFileVersion() error = %v", err)
+	}
+	if version != latest {
+		t.Errorf("version = %d, want %d", version, latest)
+	}
+}
+
+func TestFreshMigrationsRefusesWithoutConfirmation(t *testing.T) {
+	// No .env file, so only the variables set here apply
+	t.Chdir(t.TempDir())
+
+	tests := []struct {
+		name   string
+		appEnv string
+		opts   FreshOptions
+		want   string
+	}{
+		{name: "without force", appEnv: "development", opts: FreshOptions{}, want: "-force"},
+		{name: "in production", appEnv: "production", opts: FreshOptions{Force: true}, want: "APP_ENV=production"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("APP_ENV", tt.appEnv)
+			err := FreshMigrations(tt.opts)
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("FreshMigrations(%+v) error = %v, want a refusal mentioning %s", tt.opts, err, tt.want)
+			}
+		})
+	}
+}
+
+func TestFreshMigrationsDryRun(t *testing.T) {
+	db := useTestDatabase(t)
+	// A dry run is allowed anywhere, even without -force
+	t.Setenv("APP_ENV", "production")
+
+	table := fmt.Sprintf("fresh_dry_run_%d", time.Now().UnixNano())
+	if _, err := db.Exec("CREATE TABLE " + table + " (id integer)"); err != nil {
+		t.Fatalf("failed to create table: %v", err)
+	}
+	t.Cleanup(func() { db.Exec("DROP TABLE IF EXISTS " + table) })
+
+	var out bytes.Buffer
+	log.SetOutput(&out)
+	t.Cleanup(func() { log.SetOutput(os.Stderr) })
+
+	if err := FreshMigrations(FreshOptions{DryRun: true}); err != nil {
+		t.Fatalf("FreshMigrations() error = %v", err)
+	}
+
+	if !strings.Contains(out.String(), table) {
+		t.Errorf("dry run output does not list %s:\n%s", table, out.String())
+	}
+	files, err := filepath.Glob(filepath.Join("internal", "migrations", "files", "*.up.sql"))
+	if err != nil || len(files) == 0 {
+		t.Fatalf("failed to list migration files: %v", err)
+	}
+	for _, file := range files {
+		if name := strings.TrimSuffix(filepath.Base(file), ".up.sql"); !strings.Contains(out.String(), name) {
+			t.Errorf("dry run output does not list migration %s", name)
+		}
+	}
+
+	var exists bool
+	if err := db.QueryRow("SELECT EXISTS (SELECT 1 FROM pg_tables WHERE schemaname = 'public' AND tablename = $1)", table).Scan(&exists); err != nil {
+		t.Fatalf("failed to check table: %v", err)
+	}
+	if !exists {
+		t.Errorf("dry run dropped %s", table)
+	}
+}
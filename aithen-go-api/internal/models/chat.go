@@ -7,34 +7,54 @@ import (
 	"fmt"
 	"time"
 
+	"github.com/aithen/go-api/internal/db"
 	"github.com/aithen/go-api/internal/id"
+	"github.com/aithen/go-api/internal/pagination"
+	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
 )
 
 var (
-	ErrChatNotFound = errors.New("chat not found")
+	ErrChatNotFound    = errors.New("chat not found")
+	ErrMessageNotFound = errors.New("message not found")
 )
 
 // Chat represents a chat session in the database
 type Chat struct {
-	ID        int64     `json:"-" db:"id"`
-	UserID    int64     `json:"-" db:"user_id"`
-	Title     string    `json:"title" db:"title"`
-	CreatedAt time.Time `json:"created_at" db:"created_at"`
-	UpdatedAt time.Time `json:"updated_at" db:"updated_at"`
+	ID           int64                  `json:"-" db:"id"`
+	UserID       int64                  `json:"-" db:"user_id"`
+	Title        string                 `json:"title" db:"title"`
+	ParentChatID *int64                 `json:"-" db:"parent_chat_id"`      // Set when branched from another chat
+	Model        *string                `json:"model,omitempty" db:"model"` // Model of the latest assistant reply, reused by default
+	Metadata     map[string]interface{} `json:"metadata" db:"metadata"`     // Integrator-supplied JSON object, {} when unset
+	CreatedAt    time.Time              `json:"created_at" db:"created_at"`
+	UpdatedAt    time.Time              `json:"updated_at" db:"updated_at"`
+	// Populated by FindByUserID for chat list views
+	LastMessagePreview *string    `json:"last_message_preview,omitempty" db:"last_message_preview"`
+	LastMessageAt      *time.Time `json:"last_message_at,omitempty" db:"last_message_at"`
 }
 
+// lastMessagePreviewLength is the number of characters kept in chat list previews
+const lastMessagePreviewLength = 120
+
 // MarshalJSON custom marshaling to convert int64 IDs to strings
 func (c Chat) MarshalJSON() ([]byte, error) {
 	type Alias Chat
+	var parentChatID *string
+	if c.ParentChatID != nil {
+		id := fmt.Sprintf("%d", *c.ParentChatID)
+		parentChatID = &id
+	}
 	return json.Marshal(&struct {
-		ID     string `json:"id"`
-		UserID string `json:"user_id"`
+		ID           string  `json:"id"`
+		UserID       string  `json:"user_id"`
+		ParentChatID *string `json:"parent_chat_id,omitempty"`
 		*Alias
 	}{
-		ID:     fmt.Sprintf("%d", c.ID),
-		UserID: fmt.Sprintf("%d", c.UserID),
-		Alias:  (*Alias)(&c),
+		ID:           fmt.Sprintf("%d", c.ID),
+		UserID:       fmt.Sprintf("%d", c.UserID),
+		ParentChatID: parentChatID,
+		Alias:        (*Alias)(&c),
 	})
 }
 
@@ -44,7 +64,10 @@ type Message struct {
 	ChatID    int64     `json:"-" db:"chat_id"`
 	Role      string    `json:"role" db:"role"`
 	Content   string    `json:"content" db:"content"`
+	Model     *string   `json:"model,omitempty" db:"model"` // AI model that generated an assistant message
 	CreatedAt time.Time `json:"created_at" db:"created_at"`
+	// Knowledge base files attached to the message as context
+	Attachments []*MessageAttachment `json:"attachments,omitempty"`
 }
 
 // MarshalJSON custom marshaling to convert int64 IDs to strings
@@ -61,6 +84,15 @@ func (m Message) MarshalJSON() ([]byte, error) {
 	})
 }
 
+// UserChatStats summarizes a user's chat activity
+type UserChatStats struct {
+	TotalChats         int64      `json:"total_chats"`
+	TotalMessages      int64      `json:"total_messages"`
+	MessagesLast7Days  int64      `json:"messages_last_7_days"`
+	MessagesLast30Days int64      `json:"messages_last_30_days"`
+	LastActivityAt     *time.Time `json:"last_activity_at"` // Latest message or chat update, nil without any chats
+}
+
 // ChatModel handles database operations for chats
 type ChatModel struct {
 	DB *pgxpool.Pool
@@ -71,20 +103,23 @@ func NewChatModel(db *pgxpool.Pool) *ChatModel {
 	return &ChatModel{DB: db}
 }
 
-// Create creates a new chat
-func (m *ChatModel) Create(ctx context.Context, userID int64, title string) (*Chat, error) {
+// Create creates a new chat. A nil metadata stores an empty object.
+func (m *ChatModel) Create(ctx context.Context, userID int64, title string, metadata map[string]interface{}) (*Chat, error) {
+	ctx, cancel := db.WithQueryTimeout(ctx)
+	defer cancel()
+
 	// Generate Snowflake ID
 	chatID := id.Generate()
 
 	query := `
-		INSERT INTO chats (id, user_id, title, created_at, updated_at)
-		VALUES ($1, $2, $3, NOW(), NOW())
-		RETURNING id, user_id, title, created_at, updated_at
+		INSERT INTO chats (id, user_id, title, metadata, created_at, updated_at)
+		VALUES ($1, $2, $3, COALESCE($4, '{}'::jsonb), NOW(), NOW())
+		RETURNING id, user_id, title, parent_chat_id, model, metadata, created_at, updated_at
 	`
 
 	var chat Chat
-	err := m.DB.QueryRow(ctx, query, chatID, userID, title).Scan(
-		&chat.ID, &chat.UserID, &chat.Title, &chat.CreatedAt, &chat.UpdatedAt,
+	err := m.DB.QueryRow(ctx, query, chatID, userID, title, metadata).Scan(
+		&chat.ID, &chat.UserID, &chat.Title, &chat.ParentChatID, &chat.Model, &chat.Metadata, &chat.CreatedAt, &chat.UpdatedAt,
 	)
 
 	if err != nil {
@@ -97,8 +132,11 @@ func (m *ChatModel) Create(ctx context.Context, userID int64, title string) (*Ch
 
 // FindByID finds a chat by ID
 func (m *ChatModel) FindByID(ctx context.Context, id int64) (*Chat, error) {
+	ctx, cancel := db.WithQueryTimeout(ctx)
+	defer cancel()
+
 	query := `
-		SELECT id, user_id, title, created_at, updated_at
+		SELECT id, user_id, title, parent_chat_id, model, metadata, created_at, updated_at
 		FROM chats
 		WHERE id = $1
 	`
@@ -107,28 +145,55 @@ func (m *ChatModel) FindByID(ctx context.Context, id int64) (*Chat, error) {
 
 	var chat Chat
 	err := m.DB.QueryRow(ctx, query, id).Scan(
-		&chat.ID, &chat.UserID, &chat.Title, &chat.CreatedAt, &chat.UpdatedAt,
+		&chat.ID, &chat.UserID, &chat.Title, &chat.ParentChatID, &chat.Model, &chat.Metadata, &chat.CreatedAt, &chat.UpdatedAt,
 	)
 
 	if err != nil {
 		fmt.Printf("FindByID: Error querying chat ID %d: %v\n", id, err)
-		return nil, ErrChatNotFound
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrChatNotFound
+		}
+		return nil, fmt.Errorf("failed to find chat: %w", err)
 	}
 
 	fmt.Printf("FindByID: Found chat - ID: %d, UserID: %d\n", chat.ID, chat.UserID)
 	return &chat, nil
 }
 
-// FindByUserID finds all chats for a user
-func (m *ChatModel) FindByUserID(ctx context.Context, userID int64) ([]*Chat, error) {
+// FindByUserID finds a user's chats, most recently updated first, including a preview of
+// each chat's latest message. When metadata is non-nil, only chats whose metadata contains it
+// are returned. Results start after the given cursor (nil for the first page) and at most
+// limit chats are returned.
+func (m *ChatModel) FindByUserID(ctx context.Context, userID int64, metadata map[string]interface{}, after *pagination.Cursor, limit int) ([]*Chat, error) {
+	ctx, cancel := db.WithQueryTimeout(ctx)
+	defer cancel()
+
 	query := `
-		SELECT id, user_id, title, created_at, updated_at
-		FROM chats
-		WHERE user_id = $1
-		ORDER BY updated_at DESC
+		SELECT c.id, c.user_id, c.title, c.parent_chat_id, c.model, c.metadata, c.created_at, c.updated_at,
+		       LEFT(lm.content, $2), lm.created_at
+		FROM chats c
+		LEFT JOIN LATERAL (
+			SELECT content, created_at
+			FROM messages
+			WHERE chat_id = c.id
+			ORDER BY created_at DESC, id DESC
+			LIMIT 1
+		) lm ON TRUE
+		WHERE c.user_id = $1
+		  AND ($3::timestamp IS NULL OR (c.updated_at, c.id) < ($3, $4))
+		  AND ($6::jsonb IS NULL OR c.metadata @> $6)
+		ORDER BY c.updated_at DESC, c.id DESC
+		LIMIT $5
 	`
 
-	rows, err := m.DB.Query(ctx, query, userID)
+	var afterTime *time.Time
+	var afterID int64
+	if after != nil {
+		afterTime = &after.Time
+		afterID = after.ID
+	}
+
+	rows, err := m.DB.Query(ctx, query, userID, lastMessagePreviewLength, afterTime, afterID, limit, metadata)
 	if err != nil {
 		return nil, err
 	}
@@ -137,7 +202,8 @@ func (m *ChatModel) FindByUserID(ctx context.Context, userID int64) ([]*Chat, er
 	var chats []*Chat
 	for rows.Next() {
 		var chat Chat
-		err := rows.Scan(&chat.ID, &chat.UserID, &chat.Title, &chat.CreatedAt, &chat.UpdatedAt)
+		err := rows.Scan(&chat.ID, &chat.UserID, &chat.Title, &chat.ParentChatID, &chat.Model, &chat.Metadata, &chat.CreatedAt, &chat.UpdatedAt,
+			&chat.LastMessagePreview, &chat.LastMessageAt)
 		if err != nil {
 			return nil, err
 		}
@@ -147,18 +213,21 @@ func (m *ChatModel) FindByUserID(ctx context.Context, userID int64) ([]*Chat, er
 	return chats, rows.Err()
 }
 
-// Update updates a chat's title and updated_at
-func (m *ChatModel) Update(ctx context.Context, id int64, title string) (*Chat, error) {
+// Update updates a chat's title and updated_at. A nil metadata leaves the chat's metadata unchanged.
+func (m *ChatModel) Update(ctx context.Context, id int64, title string, metadata map[string]interface{}) (*Chat, error) {
+	ctx, cancel := db.WithQueryTimeout(ctx)
+	defer cancel()
+
 	query := `
 		UPDATE chats
-		SET title = $1, updated_at = NOW()
+		SET title = $1, metadata = COALESCE($3, metadata), updated_at = NOW()
 		WHERE id = $2
-		RETURNING id, user_id, title, created_at, updated_at
+		RETURNING id, user_id, title, parent_chat_id, model, metadata, created_at, updated_at
 	`
 
 	var chat Chat
-	err := m.DB.QueryRow(ctx, query, title, id).Scan(
-		&chat.ID, &chat.UserID, &chat.Title, &chat.CreatedAt, &chat.UpdatedAt,
+	err := m.DB.QueryRow(ctx, query, title, id, metadata).Scan(
+		&chat.ID, &chat.UserID, &chat.Title, &chat.ParentChatID, &chat.Model, &chat.Metadata, &chat.CreatedAt, &chat.UpdatedAt,
 	)
 
 	if err != nil {
@@ -170,44 +239,148 @@ func (m *ChatModel) Update(ctx context.Context, id int64, title string) (*Chat,
 
 // Delete deletes a chat by ID
 func (m *ChatModel) Delete(ctx context.Context, id int64) error {
+	ctx, cancel := db.WithQueryTimeout(ctx)
+	defer cancel()
+
 	query := `DELETE FROM chats WHERE id = $1`
 	_, err := m.DB.Exec(ctx, query, id)
 	return err
 }
 
-// AddMessage adds a message to a chat
-func (m *ChatModel) AddMessage(ctx context.Context, chatID int64, role, content string) (*Message, error) {
+// AddMessage adds a message to a chat, attaching the given knowledge base files, and bumps the
+// chat's updated_at in the same transaction. The caller is responsible for checking the user can
+// access the attached files. model records the AI model that generated an assistant message and
+// becomes the chat's default model.
+func (m *ChatModel) AddMessage(ctx context.Context, chatID int64, role, content string, model *string, attachmentFileIDs []int64) (*Message, error) {
+	ctx, cancel := db.WithQueryTimeout(ctx)
+	defer cancel()
+
 	// Generate Snowflake ID
 	messageID := id.Generate()
 
+	tx, err := m.DB.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
 	query := `
-		INSERT INTO messages (id, chat_id, role, content, created_at)
-		VALUES ($1, $2, $3, $4, NOW())
-		RETURNING id, chat_id, role, content, created_at
+		INSERT INTO messages (id, chat_id, role, content, model, created_at)
+		VALUES ($1, $2, $3, $4, $5, NOW())
+		RETURNING id, chat_id, role, content, model, created_at
 	`
 
 	var message Message
-	err := m.DB.QueryRow(ctx, query, messageID, chatID, role, content).Scan(
-		&message.ID, &message.ChatID, &message.Role, &message.Content, &message.CreatedAt,
+	err = tx.QueryRow(ctx, query, messageID, chatID, role, content, model).Scan(
+		&message.ID, &message.ChatID, &message.Role, &message.Content, &message.Model, &message.CreatedAt,
 	)
 
 	if err != nil {
 		return nil, err
 	}
 
-	// Update chat's updated_at timestamp
-	_, err = m.DB.Exec(ctx, `UPDATE chats SET updated_at = NOW() WHERE id = $1`, chatID)
+	if len(attachmentFileIDs) > 0 {
+		message.Attachments, err = attachFiles(ctx, tx, message.ID, attachmentFileIDs)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// Bump updated_at in the same transaction, so the message is rolled back if this fails
+	if _, err := tx.Exec(ctx, `UPDATE chats SET model = COALESCE($1, model), updated_at = NOW() WHERE id = $2`, model, chatID); err != nil {
+		return nil, fmt.Errorf("failed to update chat: %w", err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, err
+	}
+
+	return &message, nil
+}
+
+// ReplaceMessage deletes a message from a chat and adds a new message with the same role in
+// a single transaction. It is used to regenerate assistant replies; model records the AI model
+// used and becomes the chat's default model.
+func (m *ChatModel) ReplaceMessage(ctx context.Context, chatID, messageID int64, content string, model *string) (*Message, error) {
+	ctx, cancel := db.WithQueryTimeout(ctx)
+	defer cancel()
+
+	tx, err := m.DB.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	var role string
+	err = tx.QueryRow(ctx, `DELETE FROM messages WHERE id = $1 AND chat_id = $2 RETURNING role`, messageID, chatID).Scan(&role)
+	if err != nil {
+		return nil, ErrMessageNotFound
+	}
+
+	query := `
+		INSERT INTO messages (id, chat_id, role, content, model, created_at)
+		VALUES ($1, $2, $3, $4, $5, NOW())
+		RETURNING id, chat_id, role, content, model, created_at
+	`
+
+	var message Message
+	err = tx.QueryRow(ctx, query, id.Generate(), chatID, role, content, model).Scan(
+		&message.ID, &message.ChatID, &message.Role, &message.Content, &message.Model, &message.CreatedAt,
+	)
 	if err != nil {
-		// Log error but don't fail the message insertion
+		return nil, err
+	}
+
+	if _, err := tx.Exec(ctx, `UPDATE chats SET model = COALESCE($1, model), updated_at = NOW() WHERE id = $2`, model, chatID); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, err
 	}
 
 	return &message, nil
 }
 
+// DeleteMessages deletes the given messages from a chat in a single transaction and returns
+// the number deleted. If any ID does not belong to the chat nothing is deleted and
+// ErrMessageNotFound is returned. IDs must be unique.
+func (m *ChatModel) DeleteMessages(ctx context.Context, chatID int64, ids []int64) (int64, error) {
+	ctx, cancel := db.WithQueryTimeout(ctx)
+	defer cancel()
+
+	tx, err := m.DB.Begin(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	result, err := tx.Exec(ctx, `DELETE FROM messages WHERE chat_id = $1 AND id = ANY($2)`, chatID, ids)
+	if err != nil {
+		return 0, err
+	}
+	if result.RowsAffected() != int64(len(ids)) {
+		return 0, ErrMessageNotFound
+	}
+
+	if _, err := tx.Exec(ctx, `UPDATE chats SET updated_at = NOW() WHERE id = $1`, chatID); err != nil {
+		return 0, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return 0, err
+	}
+
+	return result.RowsAffected(), nil
+}
+
 // GetMessages retrieves all messages for a chat
 func (m *ChatModel) GetMessages(ctx context.Context, chatID int64) ([]*Message, error) {
+	ctx, cancel := db.WithQueryTimeout(ctx)
+	defer cancel()
+
 	query := `
-		SELECT id, chat_id, role, content, created_at
+		SELECT id, chat_id, role, content, model, created_at
 		FROM messages
 		WHERE chat_id = $1
 		ORDER BY created_at ASC
@@ -222,12 +395,275 @@ func (m *ChatModel) GetMessages(ctx context.Context, chatID int64) ([]*Message,
 	var messages []*Message
 	for rows.Next() {
 		var message Message
-		err := rows.Scan(&message.ID, &message.ChatID, &message.Role, &message.Content, &message.CreatedAt)
+		err := rows.Scan(&message.ID, &message.ChatID, &message.Role, &message.Content, &message.Model, &message.CreatedAt)
 		if err != nil {
 			return nil, err
 		}
 		messages = append(messages, &message)
 	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	attachments, err := m.GetAttachmentsForChat(ctx, chatID)
+	if err != nil {
+		return nil, err
+	}
+	for _, message := range messages {
+		message.Attachments = attachments[message.ID]
+	}
+
+	return messages, nil
+}
+
+// GetMessageByID retrieves a single message of a chat with its attachments.
+// It returns ErrMessageNotFound if the message does not exist or belongs to another chat.
+func (m *ChatModel) GetMessageByID(ctx context.Context, chatID, messageID int64) (*Message, error) {
+	ctx, cancel := db.WithQueryTimeout(ctx)
+	defer cancel()
+
+	query := `
+		SELECT id, chat_id, role, content, model, created_at
+		FROM messages
+		WHERE id = $1 AND chat_id = $2
+	`
+
+	var message Message
+	err := m.DB.QueryRow(ctx, query, messageID, chatID).Scan(
+		&message.ID, &message.ChatID, &message.Role, &message.Content, &message.Model, &message.CreatedAt,
+	)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrMessageNotFound
+		}
+		return nil, err
+	}
+
+	message.Attachments, err = m.GetAttachmentsForMessage(ctx, messageID)
+	if err != nil {
+		return nil, err
+	}
+
+	return &message, nil
+}
+
+// Copy creates a new chat for the same user with the source chat's title suffixed " (copy)"
+// and a copy of all its messages and their attachments. Unlike BranchFrom, the copy is not
+// linked to the source chat.
+func (m *ChatModel) Copy(ctx context.Context, chatID int64) (*Chat, error) {
+	ctx, cancel := db.WithQueryTimeout(ctx)
+	defer cancel()
+
+	tx, err := m.DB.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	var source Chat
+	err = tx.QueryRow(ctx, `SELECT id, user_id, title, model, metadata FROM chats WHERE id = $1`, chatID).Scan(
+		&source.ID, &source.UserID, &source.Title, &source.Model, &source.Metadata,
+	)
+	if err != nil {
+		return nil, ErrChatNotFound
+	}
+
+	insertChat := `
+		INSERT INTO chats (id, user_id, title, model, metadata, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, NOW(), NOW())
+		RETURNING id, user_id, title, parent_chat_id, model, metadata, created_at, updated_at
+	`
+	var copyChat Chat
+	err = tx.QueryRow(ctx, insertChat, id.Generate(), source.UserID, source.Title+" (copy)", source.Model, source.Metadata).Scan(
+		&copyChat.ID, &copyChat.UserID, &copyChat.Title, &copyChat.ParentChatID, &copyChat.Model, &copyChat.Metadata, &copyChat.CreatedAt, &copyChat.UpdatedAt,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create copy: %w", err)
+	}
+
+	rows, err := tx.Query(ctx, `
+		SELECT id, role, content, model, created_at
+		FROM messages
+		WHERE chat_id = $1
+		ORDER BY created_at ASC, id ASC
+	`, chatID)
+	if err != nil {
+		return nil, err
+	}
+
+	var copied []Message
+	for rows.Next() {
+		var message Message
+		if err := rows.Scan(&message.ID, &message.Role, &message.Content, &message.Model, &message.CreatedAt); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		copied = append(copied, message)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	// Attached file IDs per source message, in attachment order
+	rows, err = tx.Query(ctx, `
+		SELECT a.message_id, a.knowledge_base_file_id
+		FROM message_attachments a
+		JOIN messages msg ON msg.id = a.message_id
+		WHERE msg.chat_id = $1
+		ORDER BY a.created_at ASC, a.id ASC
+	`, chatID)
+	if err != nil {
+		return nil, err
+	}
+
+	attachedFiles := make(map[int64][]int64)
+	for rows.Next() {
+		var messageID, fileID int64
+		if err := rows.Scan(&messageID, &fileID); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		attachedFiles[messageID] = append(attachedFiles[messageID], fileID)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	// Copy messages with new IDs, preserving their original timestamps
+	for _, message := range copied {
+		messageID := id.Generate()
+		_, err := tx.Exec(ctx, `
+			INSERT INTO messages (id, chat_id, role, content, model, created_at)
+			VALUES ($1, $2, $3, $4, $5, $6)
+		`, messageID, copyChat.ID, message.Role, message.Content, message.Model, message.CreatedAt)
+		if err != nil {
+			return nil, fmt.Errorf("failed to copy message: %w", err)
+		}
+
+		if fileIDs := attachedFiles[message.ID]; len(fileIDs) > 0 {
+			if _, err := attachFiles(ctx, tx, messageID, fileIDs); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, err
+	}
+
+	return &copyChat, nil
+}
+
+// BranchFrom creates a new chat for the same user containing a copy of the source chat's
+// messages up to and including fromMessageID. The new chat is linked via parent_chat_id.
+func (m *ChatModel) BranchFrom(ctx context.Context, chatID, fromMessageID int64) (*Chat, error) {
+	ctx, cancel := db.WithQueryTimeout(ctx)
+	defer cancel()
+
+	tx, err := m.DB.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	var source Chat
+	err = tx.QueryRow(ctx, `SELECT id, user_id, title, model, metadata FROM chats WHERE id = $1`, chatID).Scan(
+		&source.ID, &source.UserID, &source.Title, &source.Model, &source.Metadata,
+	)
+	if err != nil {
+		return nil, ErrChatNotFound
+	}
+
+	// Resolve the branch point within the source chat
+	var branchPoint time.Time
+	err = tx.QueryRow(ctx, `SELECT created_at FROM messages WHERE id = $1 AND chat_id = $2`, fromMessageID, chatID).Scan(&branchPoint)
+	if err != nil {
+		return nil, ErrMessageNotFound
+	}
+
+	branchID := id.Generate()
+	insertChat := `
+		INSERT INTO chats (id, user_id, title, parent_chat_id, model, metadata, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, NOW(), NOW())
+		RETURNING id, user_id, title, parent_chat_id, model, metadata, created_at, updated_at
+	`
+	var branch Chat
+	err = tx.QueryRow(ctx, insertChat, branchID, source.UserID, source.Title+" (branch)", source.ID, source.Model, source.Metadata).Scan(
+		&branch.ID, &branch.UserID, &branch.Title, &branch.ParentChatID, &branch.Model, &branch.Metadata, &branch.CreatedAt, &branch.UpdatedAt,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create branch: %w", err)
+	}
+
+	// Collect messages up to and including the branch point, in order
+	rows, err := tx.Query(ctx, `
+		SELECT role, content, model, created_at
+		FROM messages
+		WHERE chat_id = $1 AND (created_at, id) <= ($2, $3)
+		ORDER BY created_at ASC, id ASC
+	`, chatID, branchPoint, fromMessageID)
+	if err != nil {
+		return nil, err
+	}
+
+	var copied []Message
+	for rows.Next() {
+		var message Message
+		if err := rows.Scan(&message.Role, &message.Content, &message.Model, &message.CreatedAt); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		copied = append(copied, message)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	// Copy messages with new IDs, preserving their original timestamps
+	for _, message := range copied {
+		_, err := tx.Exec(ctx, `
+			INSERT INTO messages (id, chat_id, role, content, model, created_at)
+			VALUES ($1, $2, $3, $4, $5, $6)
+		`, id.Generate(), branch.ID, message.Role, message.Content, message.Model, message.CreatedAt)
+		if err != nil {
+			return nil, fmt.Errorf("failed to copy message: %w", err)
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, err
+	}
+
+	return &branch, nil
+}
+
+// GetUserStats returns aggregate chat statistics for a user, computed in the database
+func (m *ChatModel) GetUserStats(ctx context.Context, userID int64) (*UserChatStats, error) {
+	ctx, cancel := db.WithQueryTimeout(ctx)
+	defer cancel()
+
+	query := `
+		SELECT
+			(SELECT COUNT(*) FROM chats WHERE user_id = $1),
+			COUNT(msg.id),
+			COUNT(msg.id) FILTER (WHERE msg.created_at >= NOW() - INTERVAL '7 days'),
+			COUNT(msg.id) FILTER (WHERE msg.created_at >= NOW() - INTERVAL '30 days'),
+			GREATEST(MAX(msg.created_at), (SELECT MAX(updated_at) FROM chats WHERE user_id = $1))
+		FROM messages msg
+		JOIN chats c ON c.id = msg.chat_id
+		WHERE c.user_id = $1
+	`
+
+	var stats UserChatStats
+	err := m.DB.QueryRow(ctx, query, userID).Scan(
+		&stats.TotalChats, &stats.TotalMessages, &stats.MessagesLast7Days, &stats.MessagesLast30Days, &stats.LastActivityAt,
+	)
+	if err != nil {
+		return nil, err
+	}
 
-	return messages, rows.Err()
+	return &stats, nil
 }
@@ -0,0 +1,295 @@
+package models
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestFindByUserIDLastMessagePreview(t *testing.T) {
+	m := testModels(t)
+	ctx := context.Background()
+	user := createTestUser(t, m)
+
+	empty, err := m.Chats.Create(ctx, user.ID, "Empty", nil)
+	if err != nil {
+		t.Fatalf("failed to create chat: %v", err)
+	}
+	busy, err := m.Chats.Create(ctx, user.ID, "Busy", nil)
+	if err != nil {
+		t.Fatalf("failed to create chat: %v", err)
+	}
+	long := strings.Repeat("é", lastMessagePreviewLength+30)
+	for _, content := range []string{"first", "second", long} {
+		if _, err := m.Chats.AddMessage(ctx, busy.ID, "user", content, nil, nil); err != nil {
+			t.Fatalf("failed to add message: %v", err)
+		}
+	}
+
+	chats, err := m.Chats.FindByUserID(ctx, user.ID, nil, nil, 10)
+	if err != nil {
+		t.Fatalf("FindByUserID() error = %v", err)
+	}
+	byID := map[int64]*Chat{}
+	for _, chat := range chats {
+		byID[chat.ID] = chat
+	}
+
+	if chat := byID[empty.ID]; chat == nil || chat.LastMessagePreview != nil || chat.LastMessageAt != nil {
+		t.Errorf("chat without messages = %+v, want no preview", chat)
+	}
+
+	chat := byID[busy.ID]
+	if chat == nil || chat.LastMessagePreview == nil || chat.LastMessageAt == nil {
+		t.Fatalf("chat with messages = %+v, want a preview", chat)
+	}
+	// The newest message, cut to the preview length in characters
+	if want := strings.Repeat("é", lastMessagePreviewLength); *chat.LastMessagePreview != want {
+		t.Errorf("preview = %q, want the latest message cut to %d characters", *chat.LastMessagePreview, lastMessagePreviewLength)
+	}
+}
+
+// createTestChat creates a chat for user with one message per content, alternating user and assistant
+func createTestChat(t *testing.T, m *Models, user *User, contents ...string) (*Chat, []*Message) {
+	t.Helper()
+
+	ctx := context.Background()
+	chat, err := m.Chats.Create(ctx, user.ID, "Test Chat", nil)
+	if err != nil {
+		t.Fatalf("failed to create chat: %v", err)
+	}
+	var messages []*Message
+	for i, content := range contents {
+		role := "user"
+		if i%2 == 1 {
+			role = "assistant"
+		}
+		message, err := m.Chats.AddMessage(ctx, chat.ID, role, content, nil, nil)
+		if err != nil {
+			t.Fatalf("failed to add message: %v", err)
+		}
+		messages = append(messages, message)
+	}
+	return chat, messages
+}
+
+func TestBranchFrom(t *testing.T) {
+	m := testModels(t)
+	ctx := context.Background()
+	user := createTestUser(t, m)
+	chat, messages := createTestChat(t, m, user, "q1", "a1", "q2", "a2")
+
+	branch, err := m.Chats.BranchFrom(ctx, chat.ID, messages[1].ID)
+	if err != nil {
+		t.Fatalf("BranchFrom() error = %v", err)
+	}
+	if branch.ParentChatID == nil || *branch.ParentChatID != chat.ID || branch.UserID != user.ID {
+		t.Errorf("BranchFrom() = %+v, want a chat of user %d with parent %d", branch, user.ID, chat.ID)
+	}
+
+	copied, err := m.Chats.GetMessages(ctx, branch.ID)
+	if err != nil {
+		t.Fatalf("GetMessages() error = %v", err)
+	}
+	if len(copied) != 2 || copied[0].Content != "q1" || copied[1].Content != "a1" {
+		t.Fatalf("branch messages = %d, want q1 and a1 up to the branch point", len(copied))
+	}
+	if copied[0].ID == messages[0].ID {
+		t.Error("branch shares message IDs with the original chat")
+	}
+
+	// Editing the branch leaves the original untouched
+	if _, err := m.Chats.AddMessage(ctx, branch.ID, "user", "another way", nil, nil); err != nil {
+		t.Fatalf("failed to add message to branch: %v", err)
+	}
+	if _, err := m.Chats.DeleteMessages(ctx, branch.ID, []int64{copied[0].ID}); err != nil {
+		t.Fatalf("failed to delete branch message: %v", err)
+	}
+	original, err := m.Chats.GetMessages(ctx, chat.ID)
+	if err != nil {
+		t.Fatalf("GetMessages() error = %v", err)
+	}
+	if len(original) != 4 || original[0].Content != "q1" || original[3].Content != "a2" {
+		t.Errorf("original chat has %d messages after editing the branch, want its 4 unchanged", len(original))
+	}
+
+	t.Run("message from another chat", func(t *testing.T) {
+		_, other := createTestChat(t, m, user, "elsewhere")
+		if _, err := m.Chats.BranchFrom(ctx, chat.ID, other[0].ID); err != ErrMessageNotFound {
+			t.Errorf("BranchFrom() error = %v, want ErrMessageNotFound", err)
+		}
+	})
+}
+
+func TestAddMessageRecordsModel(t *testing.T) {
+	m := testModels(t)
+	ctx := context.Background()
+
+	user := createTestUser(t, m)
+	chat, err := m.Chats.Create(ctx, user.ID, "Models", nil)
+	if err != nil {
+		t.Fatalf("failed to create chat: %v", err)
+	}
+
+	model := "llama3"
+	message, err := m.Chats.AddMessage(ctx, chat.ID, "assistant", "hello", &model, nil)
+	if err != nil {
+		t.Fatalf("AddMessage() error = %v", err)
+	}
+	if message.Model == nil || *message.Model != model {
+		t.Errorf("message model = %v, want %q", message.Model, model)
+	}
+
+	// A message without a model keeps the chat's remembered model
+	if _, err := m.Chats.AddMessage(ctx, chat.ID, "user", "thanks", nil, nil); err != nil {
+		t.Fatalf("AddMessage() error = %v", err)
+	}
+	got, err := m.Chats.FindByID(ctx, chat.ID)
+	if err != nil {
+		t.Fatalf("FindByID() error = %v", err)
+	}
+	if got.Model == nil || *got.Model != model {
+		t.Errorf("chat model = %v, want %q", got.Model, model)
+	}
+}
+
+func TestGetUserStats(t *testing.T) {
+	m := testModels(t)
+	ctx := context.Background()
+
+	t.Run("user without chats", func(t *testing.T) {
+		user := createTestUser(t, m)
+		stats, err := m.Chats.GetUserStats(ctx, user.ID)
+		if err != nil {
+			t.Fatalf("GetUserStats() error = %v", err)
+		}
+		if stats.TotalChats != 0 || stats.TotalMessages != 0 || stats.LastActivityAt != nil {
+			t.Errorf("GetUserStats() = %+v, want zeros and no activity", stats)
+		}
+	})
+
+	t.Run("counts messages by age", func(t *testing.T) {
+		user := createTestUser(t, m)
+		chat, messages := createTestChat(t, m, user, "one day", "three days", "ten days", "forty days")
+		empty, _ := createTestChat(t, m, user)
+		// Another user's chats are not counted
+		createTestChat(t, m, createTestUser(t, m), "elsewhere")
+
+		now := time.Now()
+		for i, days := range []int{1, 3, 10, 40} {
+			if _, err := m.Chats.DB.Exec(ctx, `UPDATE messages SET created_at = $1 WHERE id = $2`, now.AddDate(0, 0, -days), messages[i].ID); err != nil {
+				t.Fatalf("failed to backdate message: %v", err)
+			}
+		}
+		if _, err := m.Chats.DB.Exec(ctx, `UPDATE chats SET updated_at = $1 WHERE id IN ($2, $3)`, now.AddDate(0, 0, -2), chat.ID, empty.ID); err != nil {
+			t.Fatalf("failed to backdate chats: %v", err)
+		}
+
+		stats, err := m.Chats.GetUserStats(ctx, user.ID)
+		if err != nil {
+			t.Fatalf("GetUserStats() error = %v", err)
+		}
+		if stats.TotalChats != 2 || stats.TotalMessages != 4 || stats.MessagesLast7Days != 2 || stats.MessagesLast30Days != 3 {
+			t.Errorf("GetUserStats() = %+v, want 2 chats, 4 messages, 2 in the last 7 days and 3 in the last 30", stats)
+		}
+		if want := now.AddDate(0, 0, -1); stats.LastActivityAt == nil || stats.LastActivityAt.Sub(want).Abs() > time.Second {
+			t.Errorf("LastActivityAt = %v, want the newest message at %v", stats.LastActivityAt, want)
+		}
+	})
+}
+
+func TestAddMessageRollsBackWhenChatUpdateFails(t *testing.T) {
+	m := testModels(t)
+	ctx := context.Background()
+	user := createTestUser(t, m)
+	chat, _ := createTestChat(t, m, user, "before")
+
+	// A trigger that rejects updates to this chat makes the updated_at bump fail
+	name := fmt.Sprintf("reject_chat_update_%d", chat.ID)
+	setup := fmt.Sprintf(`
+		CREATE FUNCTION %[1]s() RETURNS trigger AS $$
+		BEGIN
+			RAISE EXCEPTION 'chat update rejected';
+		END $$ LANGUAGE plpgsql;
+		CREATE TRIGGER %[1]s BEFORE UPDATE ON chats FOR EACH ROW WHEN (OLD.id = %[2]d) EXECUTE FUNCTION %[1]s();
+	`, name, chat.ID)
+	if _, err := m.Chats.DB.Exec(ctx, setup); err != nil {
+		t.Fatalf("failed to create trigger: %v", err)
+	}
+	t.Cleanup(func() {
+		m.Chats.DB.Exec(context.Background(), fmt.Sprintf(`DROP TRIGGER IF EXISTS %[1]s ON chats; DROP FUNCTION IF EXISTS %[1]s();`, name))
+	})
+
+	if _, err := m.Chats.AddMessage(ctx, chat.ID, "user", "after", nil, nil); err == nil {
+		t.Fatal("AddMessage() error = nil, want the chat update failure")
+	}
+
+	messages, err := m.Chats.GetMessages(ctx, chat.ID)
+	if err != nil {
+		t.Fatalf("GetMessages() error = %v", err)
+	}
+	if len(messages) != 1 || messages[0].Content != "before" {
+		t.Errorf("messages = %d, want only the message added before the failure", len(messages))
+	}
+}
+
+func TestCopy(t *testing.T) {
+	m := testModels(t)
+	ctx := context.Background()
+	user := createTestUser(t, m)
+	chat, messages := createTestChat(t, m, user, "q1", "a1", "q2", "a2")
+
+	copyChat, err := m.Chats.Copy(ctx, chat.ID)
+	if err != nil {
+		t.Fatalf("Copy() error = %v", err)
+	}
+	if copyChat.ID == chat.ID || copyChat.UserID != user.ID || copyChat.Title != chat.Title+" (copy)" || copyChat.ParentChatID != nil {
+		t.Errorf("Copy() = %+v, want an unlinked chat of user %d titled %q", copyChat, user.ID, chat.Title+" (copy)")
+	}
+
+	copied, err := m.Chats.GetMessages(ctx, copyChat.ID)
+	if err != nil {
+		t.Fatalf("GetMessages() error = %v", err)
+	}
+	if len(copied) != len(messages) {
+		t.Fatalf("copy has %d messages, want %d", len(copied), len(messages))
+	}
+	for i, message := range copied {
+		if message.Content != messages[i].Content || message.Role != messages[i].Role {
+			t.Errorf("copied message %d = %s %q, want %s %q", i, message.Role, message.Content, messages[i].Role, messages[i].Content)
+		}
+		if message.ID == messages[i].ID {
+			t.Errorf("copied message %d shares its ID with the original", i)
+		}
+	}
+
+	// Later edits to either chat leave the other untouched
+	if _, err := m.Chats.AddMessage(ctx, copyChat.ID, "user", "q3", nil, nil); err != nil {
+		t.Fatalf("failed to add message to copy: %v", err)
+	}
+	if _, err := m.Chats.DeleteMessages(ctx, chat.ID, []int64{messages[0].ID}); err != nil {
+		t.Fatalf("failed to delete original message: %v", err)
+	}
+	original, err := m.Chats.GetMessages(ctx, chat.ID)
+	if err != nil {
+		t.Fatalf("GetMessages() error = %v", err)
+	}
+	if len(original) != 3 || original[0].Content != "a1" {
+		t.Errorf("original chat has %d messages, want the 3 left after its own delete", len(original))
+	}
+	copied, err = m.Chats.GetMessages(ctx, copyChat.ID)
+	if err != nil {
+		t.Fatalf("GetMessages() error = %v", err)
+	}
+	if len(copied) != 5 || copied[0].Content != "q1" || copied[4].Content != "q3" {
+		t.Errorf("copy has %d messages, want the 4 copied plus its own", len(copied))
+	}
+
+	t.Run("unknown chat", func(t *testing.T) {
+		if _, err := m.Chats.Copy(ctx, 1); err != ErrChatNotFound {
+			t.Errorf("Copy() error = %v, want ErrChatNotFound", err)
+		}
+	})
+}
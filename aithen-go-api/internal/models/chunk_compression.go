@@ -0,0 +1,37 @@
+package models
+
+import (
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+)
+
+// compressChunkText gzip-compresses a chunk's text for knowledge bases with compress_chunks set.
+// The training service writes the same format, so both can be read by decompressChunkText.
+func compressChunkText(text string) ([]byte, error) {
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	if _, err := zw.Write([]byte(text)); err != nil {
+		return nil, fmt.Errorf("failed to compress chunk text: %w", err)
+	}
+	if err := zw.Close(); err != nil {
+		return nil, fmt.Errorf("failed to compress chunk text: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// decompressChunkText restores the text of a compressed chunk
+func decompressChunkText(data []byte) (string, error) {
+	zr, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return "", fmt.Errorf("failed to decompress chunk text: %w", err)
+	}
+	defer zr.Close()
+
+	text, err := io.ReadAll(zr)
+	if err != nil {
+		return "", fmt.Errorf("failed to decompress chunk text: %w", err)
+	}
+	return string(text), nil
+}
@@ -0,0 +1,67 @@
+package models
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"testing"
+
+	"github.com/aithen/go-api/internal/id"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// testModels connects to the migrated database at TEST_DATABASE_URL, skipping the test when
+// it is unset
+func testModels(t *testing.T) *Models {
+	t.Helper()
+
+	url := os.Getenv("TEST_DATABASE_URL")
+	if url == "" {
+		t.Skip("TEST_DATABASE_URL not set")
+	}
+
+	pool, err := pgxpool.New(context.Background(), url)
+	if err != nil {
+		t.Fatalf("failed to connect to test database: %v", err)
+	}
+	t.Cleanup(pool.Close)
+
+	return &Models{
+		Users:          NewUserModel(pool),
+		Chats:          NewChatModel(pool),
+		Organizations:  NewOrganizationModel(pool),
+		KnowledgeBases: NewKnowledgeBaseModel(pool),
+		KBPermissions:  NewKBPermissionModel(pool),
+	}
+}
+
+// createTestUser creates a user that is deleted, with the organizations only it belongs to,
+// when the test ends
+func createTestUser(t *testing.T, m *Models) *User {
+	t.Helper()
+
+	ctx := context.Background()
+	user, err := m.Users.Create(ctx, fmt.Sprintf("user-%d@example.com", id.Generate()), "Test User", "password123")
+	if err != nil {
+		t.Fatalf("failed to create user: %v", err)
+	}
+	t.Cleanup(func() {
+		m.Users.DeleteAccount(context.Background(), user.ID)
+	})
+	return user
+}
+
+// createTestOrganization creates an organization owned by owner
+func createTestOrganization(t *testing.T, m *Models, owner *User) *Organization {
+	t.Helper()
+
+	ctx := context.Background()
+	org, err := m.Organizations.Create(ctx, "Test Org", fmt.Sprintf("test-org-%d", id.Generate()), "", "", "", "", "", "")
+	if err != nil {
+		t.Fatalf("failed to create organization: %v", err)
+	}
+	if _, err := m.Organizations.AddMember(ctx, org.ID, owner.ID, "owner", "active"); err != nil {
+		t.Fatalf("failed to add owner: %v", err)
+	}
+	return org
+}
@@ -0,0 +1,178 @@
+package models
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/aithen/go-api/internal/db"
+	"github.com/aithen/go-api/internal/id"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// Knowledge base permission levels, from least to most privileged
+const (
+	KBPermissionRead  = "read"
+	KBPermissionWrite = "write"
+	KBPermissionAdmin = "admin"
+)
+
+var (
+	ErrKBPermissionNotFound = errors.New("knowledge base permission not found")
+	ErrInvalidKBPermission  = errors.New("invalid knowledge base permission")
+)
+
+// kbPermissionRank orders permission levels so higher levels include lower ones
+var kbPermissionRank = map[string]int{
+	KBPermissionRead:  1,
+	KBPermissionWrite: 2,
+	KBPermissionAdmin: 3,
+}
+
+// IsValidKBPermission reports whether permission is a known permission level
+func IsValidKBPermission(permission string) bool {
+	_, ok := kbPermissionRank[permission]
+	return ok
+}
+
+// KBPermissionAllows reports whether the granted permission satisfies the required one
+func KBPermissionAllows(granted, required string) bool {
+	return kbPermissionRank[granted] >= kbPermissionRank[required] && kbPermissionRank[required] > 0
+}
+
+// KBPermission represents a user's explicit permission on a knowledge base
+type KBPermission struct {
+	ID              int64     `json:"-" db:"id"`
+	KnowledgeBaseID int64     `json:"-" db:"knowledge_base_id"`
+	UserID          int64     `json:"-" db:"user_id"`
+	Permission      string    `json:"permission" db:"permission"`
+	CreatedAt       time.Time `json:"created_at" db:"created_at"`
+	UpdatedAt       time.Time `json:"updated_at" db:"updated_at"`
+}
+
+// MarshalJSON custom marshaling to convert int64 IDs to strings
+func (p KBPermission) MarshalJSON() ([]byte, error) {
+	type Alias KBPermission
+	return json.Marshal(&struct {
+		ID              string `json:"id"`
+		KnowledgeBaseID string `json:"knowledge_base_id"`
+		UserID          string `json:"user_id"`
+		*Alias
+	}{
+		ID:              fmt.Sprintf("%d", p.ID),
+		KnowledgeBaseID: fmt.Sprintf("%d", p.KnowledgeBaseID),
+		UserID:          fmt.Sprintf("%d", p.UserID),
+		Alias:           (*Alias)(&p),
+	})
+}
+
+// KBPermissionModel handles database operations for knowledge base permissions
+type KBPermissionModel struct {
+	DB *pgxpool.Pool
+}
+
+// NewKBPermissionModel creates a new KBPermissionModel instance
+func NewKBPermissionModel(db *pgxpool.Pool) *KBPermissionModel {
+	return &KBPermissionModel{DB: db}
+}
+
+// Grant sets a user's permission on a knowledge base, replacing any existing permission
+func (m *KBPermissionModel) Grant(ctx context.Context, knowledgeBaseID, userID int64, permission string) (*KBPermission, error) {
+	ctx, cancel := db.WithQueryTimeout(ctx)
+	defer cancel()
+
+	if !IsValidKBPermission(permission) {
+		return nil, ErrInvalidKBPermission
+	}
+
+	query := `
+		INSERT INTO kb_permissions (id, knowledge_base_id, user_id, permission, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, NOW(), NOW())
+		ON CONFLICT (knowledge_base_id, user_id)
+		DO UPDATE SET permission = EXCLUDED.permission, updated_at = NOW()
+		RETURNING id, knowledge_base_id, user_id, permission, created_at, updated_at
+	`
+
+	var p KBPermission
+	err := m.DB.QueryRow(ctx, query, id.Generate(), knowledgeBaseID, userID, permission).Scan(
+		&p.ID, &p.KnowledgeBaseID, &p.UserID, &p.Permission, &p.CreatedAt, &p.UpdatedAt,
+	)
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to grant permission: %w", err)
+	}
+
+	return &p, nil
+}
+
+// Revoke removes a user's explicit permission on a knowledge base
+func (m *KBPermissionModel) Revoke(ctx context.Context, knowledgeBaseID, userID int64) error {
+	ctx, cancel := db.WithQueryTimeout(ctx)
+	defer cancel()
+
+	query := `DELETE FROM kb_permissions WHERE knowledge_base_id = $1 AND user_id = $2`
+	result, err := m.DB.Exec(ctx, query, knowledgeBaseID, userID)
+	if err != nil {
+		return err
+	}
+	if result.RowsAffected() == 0 {
+		return ErrKBPermissionNotFound
+	}
+	return nil
+}
+
+// Get gets a user's explicit permission on a knowledge base
+func (m *KBPermissionModel) Get(ctx context.Context, knowledgeBaseID, userID int64) (*KBPermission, error) {
+	ctx, cancel := db.WithQueryTimeout(ctx)
+	defer cancel()
+
+	query := `
+		SELECT id, knowledge_base_id, user_id, permission, created_at, updated_at
+		FROM kb_permissions
+		WHERE knowledge_base_id = $1 AND user_id = $2
+	`
+
+	var p KBPermission
+	err := m.DB.QueryRow(ctx, query, knowledgeBaseID, userID).Scan(
+		&p.ID, &p.KnowledgeBaseID, &p.UserID, &p.Permission, &p.CreatedAt, &p.UpdatedAt,
+	)
+
+	if err != nil {
+		return nil, ErrKBPermissionNotFound
+	}
+
+	return &p, nil
+}
+
+// ListByKnowledgeBase gets all explicit permissions on a knowledge base
+func (m *KBPermissionModel) ListByKnowledgeBase(ctx context.Context, knowledgeBaseID int64) ([]*KBPermission, error) {
+	ctx, cancel := db.WithQueryTimeout(ctx)
+	defer cancel()
+
+	query := `
+		SELECT id, knowledge_base_id, user_id, permission, created_at, updated_at
+		FROM kb_permissions
+		WHERE knowledge_base_id = $1
+		ORDER BY created_at ASC
+	`
+
+	rows, err := m.DB.Query(ctx, query, knowledgeBaseID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	permissions := []*KBPermission{}
+	for rows.Next() {
+		var p KBPermission
+		err := rows.Scan(&p.ID, &p.KnowledgeBaseID, &p.UserID, &p.Permission, &p.CreatedAt, &p.UpdatedAt)
+		if err != nil {
+			return nil, err
+		}
+		permissions = append(permissions, &p)
+	}
+
+	return permissions, rows.Err()
+}
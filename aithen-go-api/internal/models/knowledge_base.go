@@ -5,16 +5,24 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"strings"
 	"time"
+	"unicode/utf8"
 
+	"github.com/aithen/go-api/internal/db"
 	"github.com/aithen/go-api/internal/id"
+	"github.com/aithen/go-api/internal/limits"
+	"github.com/aithen/go-api/internal/pagination"
+	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
 )
 
 var (
-	ErrKnowledgeBaseNotFound        = errors.New("knowledge base not found")
-	ErrKnowledgeBaseFileNotFound    = errors.New("knowledge base file not found")
-	ErrKnowledgeBaseVersionNotFound = errors.New("knowledge base version not found")
+	ErrKnowledgeBaseNotFound          = errors.New("knowledge base not found")
+	ErrKnowledgeBaseFileNotFound      = errors.New("knowledge base file not found")
+	ErrKnowledgeBaseVersionNotFound   = errors.New("knowledge base version not found")
+	ErrKnowledgeBaseAlreadyTraining   = errors.New("knowledge base is already being trained")
+	ErrKnowledgeBaseEmbeddingNotFound = errors.New("knowledge base embedding not found")
 )
 
 // KnowledgeBase represents a knowledge base in the database
@@ -24,6 +32,9 @@ type KnowledgeBase struct {
 	Name           string    `json:"name" db:"name"`
 	Description    string    `json:"description" db:"description"`
 	Status         string    `json:"status" db:"status"`
+	CompressChunks bool      `json:"compress_chunks" db:"compress_chunks"` // Store new chunk text gzip-compressed
+	CreatedBy      *int64    `json:"-" db:"created_by"`
+	CreatedByName  *string   `json:"created_by_name" db:"created_by_name"` // Joined from users
 	CreatedAt      time.Time `json:"created_at" db:"created_at"`
 	UpdatedAt      time.Time `json:"updated_at" db:"updated_at"`
 }
@@ -32,12 +43,14 @@ type KnowledgeBase struct {
 func (kb KnowledgeBase) MarshalJSON() ([]byte, error) {
 	type Alias KnowledgeBase
 	return json.Marshal(&struct {
-		ID             string `json:"id"`
-		OrganizationID string `json:"organization_id"`
+		ID             string  `json:"id"`
+		OrganizationID string  `json:"organization_id"`
+		CreatedBy      *string `json:"created_by"`
 		*Alias
 	}{
 		ID:             fmt.Sprintf("%d", kb.ID),
 		OrganizationID: fmt.Sprintf("%d", kb.OrganizationID),
+		CreatedBy:      formatOptionalID(kb.CreatedBy),
 		Alias:          (*Alias)(&kb),
 	})
 }
@@ -51,6 +64,8 @@ type KnowledgeBaseFile struct {
 	FileSize        int64     `json:"file_size" db:"file_size"`
 	MimeType        string    `json:"mime_type" db:"mime_type"`
 	Status          string    `json:"status" db:"status"`
+	CreatedBy       *int64    `json:"-" db:"created_by"`
+	CreatedByName   *string   `json:"created_by_name" db:"created_by_name"` // Joined from users
 	CreatedAt       time.Time `json:"created_at" db:"created_at"`
 	UpdatedAt       time.Time `json:"updated_at" db:"updated_at"`
 }
@@ -59,16 +74,27 @@ type KnowledgeBaseFile struct {
 func (kbf KnowledgeBaseFile) MarshalJSON() ([]byte, error) {
 	type Alias KnowledgeBaseFile
 	return json.Marshal(&struct {
-		ID              string `json:"id"`
-		KnowledgeBaseID string `json:"knowledge_base_id"`
+		ID              string  `json:"id"`
+		KnowledgeBaseID string  `json:"knowledge_base_id"`
+		CreatedBy       *string `json:"created_by"`
 		*Alias
 	}{
 		ID:              fmt.Sprintf("%d", kbf.ID),
 		KnowledgeBaseID: fmt.Sprintf("%d", kbf.KnowledgeBaseID),
+		CreatedBy:       formatOptionalID(kbf.CreatedBy),
 		Alias:           (*Alias)(&kbf),
 	})
 }
 
+// formatOptionalID converts a nullable int64 ID to a string, preserving null
+func formatOptionalID(id *int64) *string {
+	if id == nil {
+		return nil
+	}
+	s := fmt.Sprintf("%d", *id)
+	return &s
+}
+
 // KnowledgeBaseModel handles database operations for knowledge bases
 type KnowledgeBaseModel struct {
 	DB *pgxpool.Pool
@@ -79,19 +105,23 @@ func NewKnowledgeBaseModel(db *pgxpool.Pool) *KnowledgeBaseModel {
 	return &KnowledgeBaseModel{DB: db}
 }
 
-// Create creates a new knowledge base
-func (m *KnowledgeBaseModel) Create(ctx context.Context, organizationID int64, name, description string) (*KnowledgeBase, error) {
+// Create creates a new knowledge base. createdBy is the creating user's ID, or nil if unknown.
+func (m *KnowledgeBaseModel) Create(ctx context.Context, organizationID int64, name, description string, createdBy *int64) (*KnowledgeBase, error) {
+	ctx, cancel := db.WithQueryTimeout(ctx)
+	defer cancel()
+
 	kbID := id.Generate()
 
 	query := `
-		INSERT INTO knowledge_bases (id, organization_id, name, description, status, created_at, updated_at)
-		VALUES ($1, $2, $3, $4, 'active', NOW(), NOW())
-		RETURNING id, organization_id, name, description, status, created_at, updated_at
+		INSERT INTO knowledge_bases (id, organization_id, name, description, status, created_by, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, 'active', $5, NOW(), NOW())
+		RETURNING id, organization_id, name, description, status, compress_chunks, created_by,
+		          (SELECT name FROM users WHERE id = knowledge_bases.created_by), created_at, updated_at
 	`
 
 	var kb KnowledgeBase
-	err := m.DB.QueryRow(ctx, query, kbID, organizationID, name, description).Scan(
-		&kb.ID, &kb.OrganizationID, &kb.Name, &kb.Description, &kb.Status, &kb.CreatedAt, &kb.UpdatedAt,
+	err := m.DB.QueryRow(ctx, query, kbID, organizationID, name, description, createdBy).Scan(
+		&kb.ID, &kb.OrganizationID, &kb.Name, &kb.Description, &kb.Status, &kb.CompressChunks, &kb.CreatedBy, &kb.CreatedByName, &kb.CreatedAt, &kb.UpdatedAt,
 	)
 
 	if err != nil {
@@ -103,15 +133,19 @@ func (m *KnowledgeBaseModel) Create(ctx context.Context, organizationID int64, n
 
 // FindByID finds a knowledge base by ID
 func (m *KnowledgeBaseModel) FindByID(ctx context.Context, id int64) (*KnowledgeBase, error) {
+	ctx, cancel := db.WithQueryTimeout(ctx)
+	defer cancel()
+
 	query := `
-		SELECT id, organization_id, name, description, status, created_at, updated_at
-		FROM knowledge_bases
-		WHERE id = $1
+		SELECT kb.id, kb.organization_id, kb.name, kb.description, kb.status, kb.compress_chunks, kb.created_by, u.name, kb.created_at, kb.updated_at
+		FROM knowledge_bases kb
+		LEFT JOIN users u ON u.id = kb.created_by
+		WHERE kb.id = $1
 	`
 
 	var kb KnowledgeBase
 	err := m.DB.QueryRow(ctx, query, id).Scan(
-		&kb.ID, &kb.OrganizationID, &kb.Name, &kb.Description, &kb.Status, &kb.CreatedAt, &kb.UpdatedAt,
+		&kb.ID, &kb.OrganizationID, &kb.Name, &kb.Description, &kb.Status, &kb.CompressChunks, &kb.CreatedBy, &kb.CreatedByName, &kb.CreatedAt, &kb.UpdatedAt,
 	)
 
 	if err != nil {
@@ -123,11 +157,15 @@ func (m *KnowledgeBaseModel) FindByID(ctx context.Context, id int64) (*Knowledge
 
 // FindByOrganizationID finds all knowledge bases for an organization
 func (m *KnowledgeBaseModel) FindByOrganizationID(ctx context.Context, organizationID int64) ([]*KnowledgeBase, error) {
+	ctx, cancel := db.WithQueryTimeout(ctx)
+	defer cancel()
+
 	query := `
-		SELECT id, organization_id, name, description, status, created_at, updated_at
-		FROM knowledge_bases
-		WHERE organization_id = $1
-		ORDER BY created_at DESC
+		SELECT kb.id, kb.organization_id, kb.name, kb.description, kb.status, kb.compress_chunks, kb.created_by, u.name, kb.created_at, kb.updated_at
+		FROM knowledge_bases kb
+		LEFT JOIN users u ON u.id = kb.created_by
+		WHERE kb.organization_id = $1
+		ORDER BY kb.created_at DESC
 	`
 
 	rows, err := m.DB.Query(ctx, query, organizationID)
@@ -140,7 +178,7 @@ func (m *KnowledgeBaseModel) FindByOrganizationID(ctx context.Context, organizat
 	for rows.Next() {
 		var kb KnowledgeBase
 		err := rows.Scan(
-			&kb.ID, &kb.OrganizationID, &kb.Name, &kb.Description, &kb.Status, &kb.CreatedAt, &kb.UpdatedAt,
+			&kb.ID, &kb.OrganizationID, &kb.Name, &kb.Description, &kb.Status, &kb.CompressChunks, &kb.CreatedBy, &kb.CreatedByName, &kb.CreatedAt, &kb.UpdatedAt,
 		)
 		if err != nil {
 			return nil, err
@@ -151,18 +189,74 @@ func (m *KnowledgeBaseModel) FindByOrganizationID(ctx context.Context, organizat
 	return kbs, rows.Err()
 }
 
-// Update updates a knowledge base
-func (m *KnowledgeBaseModel) Update(ctx context.Context, id int64, name, description, status string) (*KnowledgeBase, error) {
+// likeEscaper escapes LIKE wildcards so search terms match literally
+var likeEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+
+// SearchByName finds an organization's knowledge bases whose name or description contains the
+// query (case-insensitive), newest first. It returns one page and the total number of matches.
+func (m *KnowledgeBaseModel) SearchByName(ctx context.Context, organizationID int64, query string, limit, offset int) ([]*KnowledgeBase, int, error) {
+	ctx, cancel := db.WithQueryTimeout(ctx)
+	defer cancel()
+
+	pattern := "%" + likeEscaper.Replace(strings.TrimSpace(query)) + "%"
+
+	var total int
+	countQuery := `
+		SELECT COUNT(*)
+		FROM knowledge_bases
+		WHERE organization_id = $1 AND (name ILIKE $2 OR description ILIKE $2)
+	`
+	if err := m.DB.QueryRow(ctx, countQuery, organizationID, pattern).Scan(&total); err != nil {
+		return nil, 0, err
+	}
+
+	searchQuery := `
+		SELECT kb.id, kb.organization_id, kb.name, kb.description, kb.status, kb.compress_chunks, kb.created_by, u.name, kb.created_at, kb.updated_at
+		FROM knowledge_bases kb
+		LEFT JOIN users u ON u.id = kb.created_by
+		WHERE kb.organization_id = $1 AND (kb.name ILIKE $2 OR kb.description ILIKE $2)
+		ORDER BY kb.created_at DESC, kb.id DESC
+		LIMIT $3 OFFSET $4
+	`
+
+	rows, err := m.DB.Query(ctx, searchQuery, organizationID, pattern, limit, offset)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	kbs := make([]*KnowledgeBase, 0)
+	for rows.Next() {
+		var kb KnowledgeBase
+		err := rows.Scan(
+			&kb.ID, &kb.OrganizationID, &kb.Name, &kb.Description, &kb.Status, &kb.CompressChunks, &kb.CreatedBy, &kb.CreatedByName, &kb.CreatedAt, &kb.UpdatedAt,
+		)
+		if err != nil {
+			return nil, 0, err
+		}
+		kbs = append(kbs, &kb)
+	}
+
+	return kbs, total, rows.Err()
+}
+
+// Update updates the fields of a knowledge base that are non-nil, leaving the others unchanged
+func (m *KnowledgeBaseModel) Update(ctx context.Context, id int64, name, description *string, compressChunks *bool) (*KnowledgeBase, error) {
+	ctx, cancel := db.WithQueryTimeout(ctx)
+	defer cancel()
+
 	query := `
 		UPDATE knowledge_bases
-		SET name = $1, description = $2, status = COALESCE(NULLIF($3, ''), status), updated_at = NOW()
-		WHERE id = $4
-		RETURNING id, organization_id, name, description, status, created_at, updated_at
+		SET name = COALESCE($1, name), description = COALESCE($2, description),
+		    compress_chunks = COALESCE($4, compress_chunks), updated_at = NOW()
+		WHERE id = $3
+		RETURNING id, organization_id, name, description, status, compress_chunks, created_by,
+		          (SELECT name FROM users WHERE id = knowledge_bases.created_by), created_at, updated_at
 	`
 
 	var kb KnowledgeBase
-	err := m.DB.QueryRow(ctx, query, name, description, status, id).Scan(
-		&kb.ID, &kb.OrganizationID, &kb.Name, &kb.Description, &kb.Status, &kb.CreatedAt, &kb.UpdatedAt,
+	err := m.DB.QueryRow(ctx, query, name, description, id, compressChunks).Scan(
+		&kb.ID, &kb.OrganizationID, &kb.Name, &kb.Description, &kb.Status, &kb.CompressChunks, &kb.CreatedBy, &kb.CreatedByName, &kb.CreatedAt, &kb.UpdatedAt,
 	)
 
 	if err != nil {
@@ -172,42 +266,192 @@ func (m *KnowledgeBaseModel) Update(ctx context.Context, id int64, name, descrip
 	return &kb, nil
 }
 
-// Delete deletes a knowledge base by ID (cascade deletes files)
-func (m *KnowledgeBaseModel) Delete(ctx context.Context, id int64) error {
-	query := `DELETE FROM knowledge_bases WHERE id = $1`
-	_, err := m.DB.Exec(ctx, query, id)
+// ChangeOrganization moves a knowledge base from one organization to another. Files and
+// versions stay attached to the knowledge base; explicit permissions held by users who are
+// not active members of the target organization are removed. Returns
+// ErrKnowledgeBaseNotFound if the knowledge base does not belong to fromOrgID.
+func (m *KnowledgeBaseModel) ChangeOrganization(ctx context.Context, id, fromOrgID, toOrgID int64) (*KnowledgeBase, error) {
+	ctx, cancel := db.WithQueryTimeout(ctx)
+	defer cancel()
+
+	tx, err := m.DB.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	query := `
+		UPDATE knowledge_bases
+		SET organization_id = $1, updated_at = NOW()
+		WHERE id = $2 AND organization_id = $3
+		RETURNING id, organization_id, name, description, status, compress_chunks, created_by,
+		          (SELECT name FROM users WHERE id = knowledge_bases.created_by), created_at, updated_at
+	`
+
+	var kb KnowledgeBase
+	err = tx.QueryRow(ctx, query, toOrgID, id, fromOrgID).Scan(
+		&kb.ID, &kb.OrganizationID, &kb.Name, &kb.Description, &kb.Status, &kb.CompressChunks, &kb.CreatedBy, &kb.CreatedByName, &kb.CreatedAt, &kb.UpdatedAt,
+	)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrKnowledgeBaseNotFound
+		}
+		return nil, fmt.Errorf("failed to move knowledge base: %w", err)
+	}
+
+	_, err = tx.Exec(ctx, `
+		DELETE FROM kb_permissions p
+		WHERE p.knowledge_base_id = $1
+		  AND NOT EXISTS (
+			SELECT 1 FROM organization_members om
+			WHERE om.organization_id = $2 AND om.user_id = p.user_id AND om.status = 'active'
+		  )
+	`, id, toOrgID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to remove permissions: %w", err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return &kb, nil
+}
+
+// UpdateStatus updates only the status of a knowledge base
+func (m *KnowledgeBaseModel) UpdateStatus(ctx context.Context, id int64, status string) error {
+	ctx, cancel := db.WithQueryTimeout(ctx)
+	defer cancel()
+
+	query := `UPDATE knowledge_bases SET status = $1, updated_at = NOW() WHERE id = $2`
+	_, err := m.DB.Exec(ctx, query, status, id)
 	return err
 }
 
-// AddFile adds a file to a knowledge base
-func (m *KnowledgeBaseModel) AddFile(ctx context.Context, knowledgeBaseID int64, name, filePath string, fileSize int64, mimeType string) (*KnowledgeBaseFile, error) {
+// Delete deletes a knowledge base by ID (cascade deletes files). It returns
+// ErrKnowledgeBaseAlreadyTraining without deleting anything if the knowledge base is training.
+func (m *KnowledgeBaseModel) Delete(ctx context.Context, id int64) error {
+	ctx, cancel := db.WithQueryTimeout(ctx)
+	defer cancel()
+
+	tx, err := m.DB.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	if err := lockIdleKnowledgeBase(ctx, tx, id); err != nil {
+		return err
+	}
+
+	if _, err := tx.Exec(ctx, `DELETE FROM knowledge_bases WHERE id = $1`, id); err != nil {
+		return fmt.Errorf("failed to delete knowledge base: %w", err)
+	}
+
+	return tx.Commit(ctx)
+}
+
+// lockIdleKnowledgeBase locks a knowledge base row so training cannot start while its data is
+// removed. It returns ErrKnowledgeBaseNotFound if the knowledge base does not exist and
+// ErrKnowledgeBaseAlreadyTraining if it is training.
+func lockIdleKnowledgeBase(ctx context.Context, tx pgx.Tx, knowledgeBaseID int64) error {
+	var status string
+	err := tx.QueryRow(ctx, `SELECT status FROM knowledge_bases WHERE id = $1 FOR UPDATE`, knowledgeBaseID).Scan(&status)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return ErrKnowledgeBaseNotFound
+		}
+		return fmt.Errorf("failed to lock knowledge base: %w", err)
+	}
+	if status == "training" {
+		return ErrKnowledgeBaseAlreadyTraining
+	}
+	return nil
+}
+
+// AddFile adds a file to a knowledge base. createdBy is the uploading user's ID, or nil if unknown.
+// The file must fit within l: the knowledge base's file count and its organization's storage are
+// checked under a lock on the organization, held until the insert commits, so concurrent uploads
+// cannot both pass the check. A *limits.LimitError is returned when a limit would be exceeded.
+func (m *KnowledgeBaseModel) AddFile(ctx context.Context, knowledgeBaseID int64, name, filePath string, fileSize int64, mimeType string, createdBy *int64, l limits.Limits) (*KnowledgeBaseFile, error) {
+	ctx, cancel := db.WithQueryTimeout(ctx)
+	defer cancel()
+
+	tx, err := m.DB.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	var organizationID int64
+	err = tx.QueryRow(ctx, `SELECT organization_id FROM knowledge_bases WHERE id = $1`, knowledgeBaseID).Scan(&organizationID)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrKnowledgeBaseNotFound
+		}
+		return nil, fmt.Errorf("failed to add file: %w", err)
+	}
+
+	// Storage is shared by the organization's knowledge bases, so uploads to any of them serialize
+	if _, err := tx.Exec(ctx, `SELECT pg_advisory_xact_lock($1)`, organizationID); err != nil {
+		return nil, fmt.Errorf("failed to lock organization: %w", err)
+	}
+
+	var fileCount, storageUsed int64
+	usageQuery := `
+		SELECT
+			(SELECT COUNT(*) FROM knowledge_base_files WHERE knowledge_base_id = $1),
+			(SELECT COALESCE(SUM(f.file_size), 0)
+			 FROM knowledge_base_files f
+			 JOIN knowledge_bases kb ON kb.id = f.knowledge_base_id
+			 WHERE kb.organization_id = $2)
+	`
+	if err := tx.QueryRow(ctx, usageQuery, knowledgeBaseID, organizationID).Scan(&fileCount, &storageUsed); err != nil {
+		return nil, fmt.Errorf("failed to check file limits: %w", err)
+	}
+	if err := l.CheckFilesPerKB(fileCount, 1); err != nil {
+		return nil, err
+	}
+	if err := l.CheckStorage(storageUsed, fileSize); err != nil {
+		return nil, err
+	}
+
 	fileID := id.Generate()
 
 	query := `
-		INSERT INTO knowledge_base_files (id, knowledge_base_id, name, file_path, file_size, mime_type, status, created_at, updated_at)
-		VALUES ($1, $2, $3, $4, $5, $6, 'ready', NOW(), NOW())
-		RETURNING id, knowledge_base_id, name, file_path, file_size, mime_type, status, created_at, updated_at
+		INSERT INTO knowledge_base_files (id, knowledge_base_id, name, file_path, file_size, mime_type, status, created_by, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, 'ready', $7, NOW(), NOW())
+		RETURNING id, knowledge_base_id, name, file_path, file_size, mime_type, status, created_by,
+		          (SELECT name FROM users WHERE id = knowledge_base_files.created_by), created_at, updated_at
 	`
 
 	var file KnowledgeBaseFile
-	err := m.DB.QueryRow(ctx, query, fileID, knowledgeBaseID, name, filePath, fileSize, mimeType).Scan(
-		&file.ID, &file.KnowledgeBaseID, &file.Name, &file.FilePath, &file.FileSize, &file.MimeType, &file.Status, &file.CreatedAt, &file.UpdatedAt,
+	err = tx.QueryRow(ctx, query, fileID, knowledgeBaseID, name, filePath, fileSize, mimeType, createdBy).Scan(
+		&file.ID, &file.KnowledgeBaseID, &file.Name, &file.FilePath, &file.FileSize, &file.MimeType, &file.Status, &file.CreatedBy, &file.CreatedByName, &file.CreatedAt, &file.UpdatedAt,
 	)
 
 	if err != nil {
 		return nil, fmt.Errorf("failed to add file: %w", err)
 	}
 
+	if err := tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("failed to commit file: %w", err)
+	}
+
 	return &file, nil
 }
 
 // GetFilesByKnowledgeBaseID gets all files for a knowledge base
 func (m *KnowledgeBaseModel) GetFilesByKnowledgeBaseID(ctx context.Context, knowledgeBaseID int64) ([]*KnowledgeBaseFile, error) {
+	ctx, cancel := db.WithQueryTimeout(ctx)
+	defer cancel()
+
 	query := `
-		SELECT id, knowledge_base_id, name, file_path, file_size, mime_type, status, created_at, updated_at
-		FROM knowledge_base_files
-		WHERE knowledge_base_id = $1
-		ORDER BY created_at DESC
+		SELECT f.id, f.knowledge_base_id, f.name, f.file_path, f.file_size, f.mime_type, f.status, f.created_by, u.name, f.created_at, f.updated_at
+		FROM knowledge_base_files f
+		LEFT JOIN users u ON u.id = f.created_by
+		WHERE f.knowledge_base_id = $1
+		ORDER BY f.created_at DESC
 	`
 
 	rows, err := m.DB.Query(ctx, query, knowledgeBaseID)
@@ -220,7 +464,7 @@ func (m *KnowledgeBaseModel) GetFilesByKnowledgeBaseID(ctx context.Context, know
 	for rows.Next() {
 		var file KnowledgeBaseFile
 		err := rows.Scan(
-			&file.ID, &file.KnowledgeBaseID, &file.Name, &file.FilePath, &file.FileSize, &file.MimeType, &file.Status, &file.CreatedAt, &file.UpdatedAt,
+			&file.ID, &file.KnowledgeBaseID, &file.Name, &file.FilePath, &file.FileSize, &file.MimeType, &file.Status, &file.CreatedBy, &file.CreatedByName, &file.CreatedAt, &file.UpdatedAt,
 		)
 		if err != nil {
 			return nil, err
@@ -231,41 +475,524 @@ func (m *KnowledgeBaseModel) GetFilesByKnowledgeBaseID(ctx context.Context, know
 	return files, rows.Err()
 }
 
-// DeleteFile deletes a file from a knowledge base
-func (m *KnowledgeBaseModel) DeleteFile(ctx context.Context, fileID int64) error {
-	query := `DELETE FROM knowledge_base_files WHERE id = $1`
-	_, err := m.DB.Exec(ctx, query, fileID)
-	return err
+// GetAllFilePaths returns the stored path of every knowledge base file, used to find
+// uploads on disk that no longer have a database record
+func (m *KnowledgeBaseModel) GetAllFilePaths(ctx context.Context) ([]string, error) {
+	ctx, cancel := db.WithQueryTimeout(ctx)
+	defer cancel()
+
+	rows, err := m.DB.Query(ctx, `SELECT file_path FROM knowledge_base_files`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var paths []string
+	for rows.Next() {
+		var path string
+		if err := rows.Scan(&path); err != nil {
+			return nil, err
+		}
+		paths = append(paths, path)
+	}
+
+	return paths, rows.Err()
+}
+
+// OrganizationFile is a knowledge base file listed across an organization, with the name of
+// the knowledge base it belongs to
+type OrganizationFile struct {
+	KnowledgeBaseFile
+	KnowledgeBaseName string
+}
+
+// MarshalJSON adds the knowledge base name to the file's JSON
+func (f OrganizationFile) MarshalJSON() ([]byte, error) {
+	file, err := json.Marshal(f.KnowledgeBaseFile)
+	if err != nil {
+		return nil, err
+	}
+
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(file, &fields); err != nil {
+		return nil, err
+	}
+	name, err := json.Marshal(f.KnowledgeBaseName)
+	if err != nil {
+		return nil, err
+	}
+	fields["knowledge_base_name"] = name
+
+	return json.Marshal(fields)
+}
+
+// OrganizationFileFilter narrows ListOrganizationFiles; zero values do not filter
+type OrganizationFileFilter struct {
+	KnowledgeBaseID *int64
+	Status          string
+	Query           string // Case-insensitive match on the file name
+}
+
+// ListOrganizationFiles lists files across all knowledge bases of an organization, newest
+// first. Results start after the given cursor (nil for the first page) and at most limit
+// files are returned.
+func (m *KnowledgeBaseModel) ListOrganizationFiles(ctx context.Context, organizationID int64, filter OrganizationFileFilter, after *pagination.Cursor, limit int) ([]*OrganizationFile, error) {
+	ctx, cancel := db.WithQueryTimeout(ctx)
+	defer cancel()
+
+	var status, pattern *string
+	if filter.Status != "" {
+		status = &filter.Status
+	}
+	if q := strings.TrimSpace(filter.Query); q != "" {
+		p := "%" + likeEscaper.Replace(q) + "%"
+		pattern = &p
+	}
+	var afterTime *time.Time
+	var afterID int64
+	if after != nil {
+		afterTime = &after.Time
+		afterID = after.ID
+	}
+
+	query := `
+		SELECT f.id, f.knowledge_base_id, f.name, f.file_path, f.file_size, f.mime_type, f.status, f.created_by, u.name,
+		       f.created_at, f.updated_at, kb.name
+		FROM knowledge_base_files f
+		JOIN knowledge_bases kb ON kb.id = f.knowledge_base_id
+		LEFT JOIN users u ON u.id = f.created_by
+		WHERE kb.organization_id = $1
+		  AND ($2::bigint IS NULL OR f.knowledge_base_id = $2)
+		  AND ($3::text IS NULL OR f.status = $3)
+		  AND ($4::text IS NULL OR f.name ILIKE $4)
+		  AND ($5::timestamp IS NULL OR (f.created_at, f.id) < ($5, $6))
+		ORDER BY f.created_at DESC, f.id DESC
+		LIMIT $7
+	`
+
+	rows, err := m.DB.Query(ctx, query, organizationID, filter.KnowledgeBaseID, status, pattern, afterTime, afterID, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	files := make([]*OrganizationFile, 0)
+	for rows.Next() {
+		var file OrganizationFile
+		err := rows.Scan(
+			&file.ID, &file.KnowledgeBaseID, &file.Name, &file.FilePath, &file.FileSize, &file.MimeType, &file.Status, &file.CreatedBy, &file.CreatedByName,
+			&file.CreatedAt, &file.UpdatedAt, &file.KnowledgeBaseName,
+		)
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, &file)
+	}
+
+	return files, rows.Err()
+}
+
+// DeleteFile deletes a file record from a knowledge base and returns it so its stored file can
+// be removed. It returns ErrKnowledgeBaseFileNotFound if the file does not belong to the knowledge
+// base, and ErrKnowledgeBaseAlreadyTraining without deleting anything if the knowledge base is training.
+func (m *KnowledgeBaseModel) DeleteFile(ctx context.Context, knowledgeBaseID, fileID int64) (*KnowledgeBaseFile, error) {
+	ctx, cancel := db.WithQueryTimeout(ctx)
+	defer cancel()
+
+	tx, err := m.DB.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	if err := lockIdleKnowledgeBase(ctx, tx, knowledgeBaseID); err != nil {
+		return nil, err
+	}
+
+	query := `
+		DELETE FROM knowledge_base_files
+		WHERE id = $1 AND knowledge_base_id = $2
+		RETURNING id, knowledge_base_id, name, file_path, file_size, mime_type, status, created_by,
+		          (SELECT name FROM users WHERE id = knowledge_base_files.created_by), created_at, updated_at
+	`
+
+	var file KnowledgeBaseFile
+	err = tx.QueryRow(ctx, query, fileID, knowledgeBaseID).Scan(
+		&file.ID, &file.KnowledgeBaseID, &file.Name, &file.FilePath, &file.FileSize, &file.MimeType, &file.Status, &file.CreatedBy, &file.CreatedByName, &file.CreatedAt, &file.UpdatedAt,
+	)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrKnowledgeBaseFileNotFound
+		}
+		return nil, fmt.Errorf("failed to delete file: %w", err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("failed to commit file deletion: %w", err)
+	}
+
+	return &file, nil
+}
+
+// DeleteAllFiles deletes every file record for a knowledge base in a single transaction and
+// returns the deleted records so their stored files can be removed. It returns
+// ErrKnowledgeBaseAlreadyTraining without deleting anything if the knowledge base is training.
+func (m *KnowledgeBaseModel) DeleteAllFiles(ctx context.Context, knowledgeBaseID int64) ([]*KnowledgeBaseFile, error) {
+	ctx, cancel := db.WithQueryTimeout(ctx)
+	defer cancel()
+
+	tx, err := m.DB.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	if err := lockIdleKnowledgeBase(ctx, tx, knowledgeBaseID); err != nil {
+		return nil, err
+	}
+
+	query := `
+		DELETE FROM knowledge_base_files
+		WHERE knowledge_base_id = $1
+		RETURNING id, knowledge_base_id, name, file_path, file_size, mime_type, status, created_by,
+		          (SELECT name FROM users WHERE id = knowledge_base_files.created_by), created_at, updated_at
+	`
+
+	rows, err := tx.Query(ctx, query, knowledgeBaseID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to delete files: %w", err)
+	}
+
+	var files []*KnowledgeBaseFile
+	for rows.Next() {
+		var file KnowledgeBaseFile
+		err := rows.Scan(
+			&file.ID, &file.KnowledgeBaseID, &file.Name, &file.FilePath, &file.FileSize, &file.MimeType, &file.Status, &file.CreatedBy, &file.CreatedByName, &file.CreatedAt, &file.UpdatedAt,
+		)
+		if err != nil {
+			rows.Close()
+			return nil, err
+		}
+		files = append(files, &file)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("failed to commit file deletion: %w", err)
+	}
+
+	return files, nil
 }
 
 // GetFileByID gets a file by ID
 func (m *KnowledgeBaseModel) GetFileByID(ctx context.Context, fileID int64) (*KnowledgeBaseFile, error) {
+	ctx, cancel := db.WithQueryTimeout(ctx)
+	defer cancel()
+
 	query := `
-		SELECT id, knowledge_base_id, name, file_path, file_size, mime_type, status, created_at, updated_at
-		FROM knowledge_base_files
-		WHERE id = $1
+		SELECT f.id, f.knowledge_base_id, f.name, f.file_path, f.file_size, f.mime_type, f.status, f.created_by, u.name, f.created_at, f.updated_at
+		FROM knowledge_base_files f
+		LEFT JOIN users u ON u.id = f.created_by
+		WHERE f.id = $1
 	`
 
 	var file KnowledgeBaseFile
 	err := m.DB.QueryRow(ctx, query, fileID).Scan(
-		&file.ID, &file.KnowledgeBaseID, &file.Name, &file.FilePath, &file.FileSize, &file.MimeType, &file.Status, &file.CreatedAt, &file.UpdatedAt,
+		&file.ID, &file.KnowledgeBaseID, &file.Name, &file.FilePath, &file.FileSize, &file.MimeType, &file.Status, &file.CreatedBy, &file.CreatedByName, &file.CreatedAt, &file.UpdatedAt,
 	)
 
 	if err != nil {
 		return nil, ErrKnowledgeBaseFileNotFound
 	}
 
-	return &file, nil
+	return &file, nil
+}
+
+// CountEmbeddingsByVersion returns the number of embeddings stored for a version
+func (m *KnowledgeBaseModel) CountEmbeddingsByVersion(ctx context.Context, versionID int64) (int64, error) {
+	ctx, cancel := db.WithQueryTimeout(ctx)
+	defer cancel()
+
+	query := `SELECT COUNT(*) FROM knowledge_base_embeddings WHERE knowledge_base_version_id = $1`
+	var count int64
+	err := m.DB.QueryRow(ctx, query, versionID).Scan(&count)
+	return count, err
+}
+
+// GetFileChunkCounts returns the number of embedded chunks each file produced in a version, keyed by file ID
+func (m *KnowledgeBaseModel) GetFileChunkCounts(ctx context.Context, versionID int64) (map[int64]int, error) {
+	ctx, cancel := db.WithQueryTimeout(ctx)
+	defer cancel()
+
+	query := `
+		SELECT knowledge_base_file_id, COUNT(*)
+		FROM knowledge_base_embeddings
+		WHERE knowledge_base_version_id = $1
+		GROUP BY knowledge_base_file_id
+	`
+
+	rows, err := m.DB.Query(ctx, query, versionID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	counts := make(map[int64]int)
+	for rows.Next() {
+		var fileID int64
+		var count int
+		if err := rows.Scan(&fileID, &count); err != nil {
+			return nil, err
+		}
+		counts[fileID] = count
+	}
+
+	return counts, rows.Err()
+}
+
+// KnowledgeBaseStorageStats summarizes the files and embeddings stored for a knowledge base
+type KnowledgeBaseStorageStats struct {
+	KnowledgeBaseID int64  `json:"-"`
+	Name            string `json:"name"`
+	Versions        int64  `json:"versions"`
+	Files           int64  `json:"files"`
+	FileBytes       int64  `json:"file_bytes"`
+	Embeddings      int64  `json:"embeddings"`
+	EmbeddingBytes  int64  `json:"embedding_bytes"`
+}
+
+// MarshalJSON custom marshaling to convert int64 IDs to strings
+func (s KnowledgeBaseStorageStats) MarshalJSON() ([]byte, error) {
+	type Alias KnowledgeBaseStorageStats
+	return json.Marshal(&struct {
+		KnowledgeBaseID string `json:"knowledge_base_id"`
+		*Alias
+	}{
+		KnowledgeBaseID: fmt.Sprintf("%d", s.KnowledgeBaseID),
+		Alias:           (*Alias)(&s),
+	})
+}
+
+// OrganizationStorageStats aggregates storage across an organization's knowledge bases
+type OrganizationStorageStats struct {
+	KnowledgeBases  int64                        `json:"knowledge_bases"`
+	Versions        int64                        `json:"versions"`
+	Files           int64                        `json:"files"`
+	FileBytes       int64                        `json:"file_bytes"`
+	Embeddings      int64                        `json:"embeddings"`
+	EmbeddingBytes  int64                        `json:"embedding_bytes"`
+	ByKnowledgeBase []*KnowledgeBaseStorageStats `json:"by_knowledge_base"`
+}
+
+// GetOrganizationStorageStats returns file and embedding storage for every knowledge base in an
+// organization along with the totals. Embedding bytes are estimated the same way as a version's
+// total_storage_size and cover all versions.
+func (m *KnowledgeBaseModel) GetOrganizationStorageStats(ctx context.Context, organizationID int64) (*OrganizationStorageStats, error) {
+	ctx, cancel := db.WithQueryTimeout(ctx)
+	defer cancel()
+
+	query := `
+		SELECT kb.id, kb.name,
+		       COALESCE(v.versions, 0), COALESCE(f.files, 0), COALESCE(f.file_bytes, 0),
+		       COALESCE(e.embeddings, 0), COALESCE(e.embedding_bytes, 0)
+		FROM knowledge_bases kb
+		LEFT JOIN (
+			SELECT knowledge_base_id, COUNT(*) AS versions
+			FROM knowledge_base_versions
+			GROUP BY knowledge_base_id
+		) v ON v.knowledge_base_id = kb.id
+		LEFT JOIN (
+			SELECT knowledge_base_id, COUNT(*) AS files, SUM(file_size) AS file_bytes
+			FROM knowledge_base_files
+			GROUP BY knowledge_base_id
+		) f ON f.knowledge_base_id = kb.id
+		LEFT JOIN (
+			SELECT knowledge_base_id, COUNT(*) AS embeddings,
+			       SUM(CASE WHEN compressed THEN OCTET_LENGTH(chunk_data) ELSE LENGTH(chunk_text) END + (vector_dims(embedding) * 4) + LENGTH(COALESCE(metadata::text, '{}'))) AS embedding_bytes
+			FROM knowledge_base_embeddings
+			GROUP BY knowledge_base_id
+		) e ON e.knowledge_base_id = kb.id
+		WHERE kb.organization_id = $1
+		ORDER BY kb.created_at DESC
+	`
+
+	rows, err := m.DB.Query(ctx, query, organizationID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	stats := &OrganizationStorageStats{ByKnowledgeBase: []*KnowledgeBaseStorageStats{}}
+	for rows.Next() {
+		var kb KnowledgeBaseStorageStats
+		err := rows.Scan(&kb.KnowledgeBaseID, &kb.Name, &kb.Versions, &kb.Files, &kb.FileBytes, &kb.Embeddings, &kb.EmbeddingBytes)
+		if err != nil {
+			return nil, err
+		}
+
+		stats.KnowledgeBases++
+		stats.Versions += kb.Versions
+		stats.Files += kb.Files
+		stats.FileBytes += kb.FileBytes
+		stats.Embeddings += kb.Embeddings
+		stats.EmbeddingBytes += kb.EmbeddingBytes
+		stats.ByKnowledgeBase = append(stats.ByKnowledgeBase, &kb)
+	}
+
+	return stats, rows.Err()
+}
+
+// OrganizationKBStats summarizes an organization's knowledge bases for its dashboard
+type OrganizationKBStats struct {
+	KnowledgeBases      int64            `json:"knowledge_bases"`
+	ByStatus            map[string]int64 `json:"by_status"`
+	Files               int64            `json:"files"`
+	Embeddings          int64            `json:"embeddings"`
+	AverageQualityScore *float64         `json:"average_quality_score"`
+}
+
+// GetOrgKBStats returns knowledge base counts by status, file and embedding totals and the
+// average quality score of completed versions for an organization in one grouped query.
+// The average is nil when no completed version has a score.
+func (m *KnowledgeBaseModel) GetOrgKBStats(ctx context.Context, organizationID int64) (*OrganizationKBStats, error) {
+	ctx, cancel := db.WithQueryTimeout(ctx)
+	defer cancel()
+
+	query := `
+		SELECT kb.status, COUNT(*),
+		       COALESCE(SUM(f.files), 0)::bigint, COALESCE(SUM(e.embeddings), 0)::bigint,
+		       COALESCE(SUM(q.score_total), 0)::float8, COALESCE(SUM(q.scored), 0)::bigint
+		FROM knowledge_bases kb
+		LEFT JOIN (
+			SELECT knowledge_base_id, COUNT(*) AS files
+			FROM knowledge_base_files
+			GROUP BY knowledge_base_id
+		) f ON f.knowledge_base_id = kb.id
+		LEFT JOIN (
+			SELECT knowledge_base_id, COUNT(*) AS embeddings
+			FROM knowledge_base_embeddings
+			GROUP BY knowledge_base_id
+		) e ON e.knowledge_base_id = kb.id
+		LEFT JOIN (
+			SELECT knowledge_base_id, SUM(quality_score) AS score_total, COUNT(quality_score) AS scored
+			FROM knowledge_base_versions
+			WHERE status = 'completed'
+			GROUP BY knowledge_base_id
+		) q ON q.knowledge_base_id = kb.id
+		WHERE kb.organization_id = $1
+		GROUP BY kb.status
+	`
+
+	rows, err := m.DB.Query(ctx, query, organizationID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	// Every status is reported so the dashboard can render zero counts
+	stats := &OrganizationKBStats{ByStatus: map[string]int64{"active": 0, "training": 0, "error": 0}}
+	var scoreTotal float64
+	var scored int64
+	for rows.Next() {
+		var status string
+		var count, files, embeddings, statusScored int64
+		var statusScoreTotal float64
+		if err := rows.Scan(&status, &count, &files, &embeddings, &statusScoreTotal, &statusScored); err != nil {
+			return nil, err
+		}
+
+		stats.KnowledgeBases += count
+		stats.ByStatus[status] = count
+		stats.Files += files
+		stats.Embeddings += embeddings
+		scoreTotal += statusScoreTotal
+		scored += statusScored
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	if scored > 0 {
+		average := scoreTotal / float64(scored)
+		stats.AverageQualityScore = &average
+	}
+
+	return stats, nil
 }
 
 // GetFileCount returns the count of files for a knowledge base
 func (m *KnowledgeBaseModel) GetFileCount(ctx context.Context, knowledgeBaseID int64) (int, error) {
+	ctx, cancel := db.WithQueryTimeout(ctx)
+	defer cancel()
+
 	query := `SELECT COUNT(*) FROM knowledge_base_files WHERE knowledge_base_id = $1`
 	var count int
 	err := m.DB.QueryRow(ctx, query, knowledgeBaseID).Scan(&count)
 	return count, err
 }
 
+// NeedsRetraining reports whether a knowledge base has files added after its latest completed
+// version finished training, or files but no completed version at all
+func (m *KnowledgeBaseModel) NeedsRetraining(ctx context.Context, knowledgeBaseID int64) (bool, error) {
+	ctx, cancel := db.WithQueryTimeout(ctx)
+	defer cancel()
+
+	query := `
+		SELECT EXISTS (
+			SELECT 1 FROM knowledge_base_files
+			WHERE knowledge_base_id = $1
+			  AND created_at > COALESCE((
+				SELECT MAX(training_completed_at) FROM knowledge_base_versions
+				WHERE knowledge_base_id = $1 AND status = 'completed'
+			  ), '-infinity')
+		)
+	`
+	var needsRetraining bool
+	err := m.DB.QueryRow(ctx, query, knowledgeBaseID).Scan(&needsRetraining)
+	return needsRetraining, err
+}
+
+// CountByOrganization returns the number of knowledge bases in an organization
+func (m *KnowledgeBaseModel) CountByOrganization(ctx context.Context, organizationID int64) (int64, error) {
+	ctx, cancel := db.WithQueryTimeout(ctx)
+	defer cancel()
+
+	query := `SELECT COUNT(*) FROM knowledge_bases WHERE organization_id = $1`
+	var count int64
+	err := m.DB.QueryRow(ctx, query, organizationID).Scan(&count)
+	return count, err
+}
+
+// CountTrainingByOrganization returns the number of knowledge bases currently training in an organization
+func (m *KnowledgeBaseModel) CountTrainingByOrganization(ctx context.Context, organizationID int64) (int64, error) {
+	ctx, cancel := db.WithQueryTimeout(ctx)
+	defer cancel()
+
+	query := `SELECT COUNT(*) FROM knowledge_bases WHERE organization_id = $1 AND status = 'training'`
+	var count int64
+	err := m.DB.QueryRow(ctx, query, organizationID).Scan(&count)
+	return count, err
+}
+
+// GetOrganizationStorageSize returns the total size in bytes of all files in an organization's knowledge bases
+func (m *KnowledgeBaseModel) GetOrganizationStorageSize(ctx context.Context, organizationID int64) (int64, error) {
+	ctx, cancel := db.WithQueryTimeout(ctx)
+	defer cancel()
+
+	query := `
+		SELECT COALESCE(SUM(f.file_size), 0)
+		FROM knowledge_base_files f
+		JOIN knowledge_bases kb ON kb.id = f.knowledge_base_id
+		WHERE kb.organization_id = $1
+	`
+	var size int64
+	err := m.DB.QueryRow(ctx, query, organizationID).Scan(&size)
+	return size, err
+}
+
 // KnowledgeBaseVersion represents a version of a knowledge base
 type KnowledgeBaseVersion struct {
 	ID                  int64      `json:"-" db:"id"`
@@ -280,7 +1007,11 @@ type KnowledgeBaseVersion struct {
 	EmbeddingDimension  int        `json:"embedding_dimension" db:"embedding_dimension"`
 	TotalStorageSize    int64      `json:"total_storage_size" db:"total_storage_size"`
 	AverageChunkSize    int        `json:"average_chunk_size" db:"average_chunk_size"`
+	CompressionSavings  int64      `json:"compression_savings" db:"compression_savings"` // Bytes saved by compressing chunk text
 	QualityScore        *float64   `json:"quality_score,omitempty" db:"quality_score"`
+	ChunkSize           *int       `json:"chunk_size" db:"chunk_size"`           // nil when the training service default was used
+	ChunkOverlap        *int       `json:"chunk_overlap" db:"chunk_overlap"`     // nil when the training service default was used
+	EmbeddingModel      *string    `json:"embedding_model" db:"embedding_model"` // nil when the training service default was used
 	CreatedAt           time.Time  `json:"created_at" db:"created_at"`
 	UpdatedAt           time.Time  `json:"updated_at" db:"updated_at"`
 }
@@ -299,12 +1030,57 @@ func (kbv KnowledgeBaseVersion) MarshalJSON() ([]byte, error) {
 	})
 }
 
-// CreateVersion creates a new version for a knowledge base
-func (m *KnowledgeBaseModel) CreateVersion(ctx context.Context, knowledgeBaseID int64) (*KnowledgeBaseVersion, error) {
+// CreateVersion creates a new version for a knowledge base. embeddingModel is recorded on the
+// version and overrides the training service's embedding model when non-nil.
+// Concurrent calls for the same knowledge base are serialized with an advisory lock. If a
+// version is already training, it is returned together with ErrKnowledgeBaseAlreadyTraining
+// instead of creating another one.
+func (m *KnowledgeBaseModel) CreateVersion(ctx context.Context, knowledgeBaseID int64, chunkSize, chunkOverlap *int, embeddingModel *string) (*KnowledgeBaseVersion, error) {
+	ctx, cancel := db.WithQueryTimeout(ctx)
+	defer cancel()
+
+	tx, err := m.DB.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	// Held until the transaction ends, so a second caller sees the first caller's version
+	if _, err := tx.Exec(ctx, `SELECT pg_advisory_xact_lock($1)`, knowledgeBaseID); err != nil {
+		return nil, fmt.Errorf("failed to lock knowledge base: %w", err)
+	}
+
+	var version KnowledgeBaseVersion
+	var trainingCompletedAt *time.Time
+
+	// Return the in-progress version if training was already started
+	existingQuery := `
+		SELECT id, knowledge_base_id, version_number, version_string, status, training_started_at, training_completed_at,
+		       total_embeddings, total_chunks, embedding_dimension, total_storage_size, average_chunk_size, compression_savings, quality_score,
+		       chunk_size, chunk_overlap, embedding_model, created_at, updated_at
+		FROM knowledge_base_versions
+		WHERE knowledge_base_id = $1 AND status = 'training'
+		ORDER BY version_number DESC
+		LIMIT 1
+	`
+	err = tx.QueryRow(ctx, existingQuery, knowledgeBaseID).Scan(
+		&version.ID, &version.KnowledgeBaseID, &version.VersionNumber, &version.VersionString,
+		&version.Status, &version.TrainingStartedAt, &trainingCompletedAt,
+		&version.TotalEmbeddings, &version.TotalChunks, &version.EmbeddingDimension, &version.TotalStorageSize,
+		&version.AverageChunkSize, &version.CompressionSavings, &version.QualityScore, &version.ChunkSize, &version.ChunkOverlap, &version.EmbeddingModel, &version.CreatedAt, &version.UpdatedAt,
+	)
+	if err == nil {
+		version.TrainingCompletedAt = trainingCompletedAt
+		return &version, ErrKnowledgeBaseAlreadyTraining
+	}
+	if !errors.Is(err, pgx.ErrNoRows) {
+		return nil, fmt.Errorf("failed to check for training version: %w", err)
+	}
+
 	// Get the latest version number
 	var latestVersion int
 	query := `SELECT COALESCE(MAX(version_number), 0) FROM knowledge_base_versions WHERE knowledge_base_id = $1`
-	err := m.DB.QueryRow(ctx, query, knowledgeBaseID).Scan(&latestVersion)
+	err = tx.QueryRow(ctx, query, knowledgeBaseID).Scan(&latestVersion)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get latest version: %w", err)
 	}
@@ -316,20 +1092,18 @@ func (m *KnowledgeBaseModel) CreateVersion(ctx context.Context, knowledgeBaseID
 	versionID := id.Generate()
 
 	insertQuery := `
-		INSERT INTO knowledge_base_versions (id, knowledge_base_id, version_number, version_string, status, training_started_at, created_at, updated_at)
-		VALUES ($1, $2, $3, $4, 'training', NOW(), NOW(), NOW())
+		INSERT INTO knowledge_base_versions (id, knowledge_base_id, version_number, version_string, status, chunk_size, chunk_overlap, embedding_model, training_started_at, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, 'training', $5, $6, $7, NOW(), NOW(), NOW())
 		RETURNING id, knowledge_base_id, version_number, version_string, status, training_started_at, training_completed_at, 
-		          total_embeddings, total_chunks, embedding_dimension, total_storage_size, average_chunk_size, quality_score, 
-		          created_at, updated_at
+		          total_embeddings, total_chunks, embedding_dimension, total_storage_size, average_chunk_size, compression_savings, quality_score,
+		          chunk_size, chunk_overlap, embedding_model, created_at, updated_at
 	`
 
-	var version KnowledgeBaseVersion
-	var trainingCompletedAt *time.Time
-	err = m.DB.QueryRow(ctx, insertQuery, versionID, knowledgeBaseID, newVersionNumber, versionString).Scan(
+	err = tx.QueryRow(ctx, insertQuery, versionID, knowledgeBaseID, newVersionNumber, versionString, chunkSize, chunkOverlap, embeddingModel).Scan(
 		&version.ID, &version.KnowledgeBaseID, &version.VersionNumber, &version.VersionString,
 		&version.Status, &version.TrainingStartedAt, &trainingCompletedAt,
 		&version.TotalEmbeddings, &version.TotalChunks, &version.EmbeddingDimension, &version.TotalStorageSize,
-		&version.AverageChunkSize, &version.QualityScore, &version.CreatedAt, &version.UpdatedAt,
+		&version.AverageChunkSize, &version.CompressionSavings, &version.QualityScore, &version.ChunkSize, &version.ChunkOverlap, &version.EmbeddingModel, &version.CreatedAt, &version.UpdatedAt,
 	)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create version: %w", err)
@@ -339,20 +1113,27 @@ func (m *KnowledgeBaseModel) CreateVersion(ctx context.Context, knowledgeBaseID
 
 	// Update knowledge base status to 'training'
 	updateKBQuery := `UPDATE knowledge_bases SET status = 'training', updated_at = NOW() WHERE id = $1`
-	_, err = m.DB.Exec(ctx, updateKBQuery, knowledgeBaseID)
+	_, err = tx.Exec(ctx, updateKBQuery, knowledgeBaseID)
 	if err != nil {
 		return nil, fmt.Errorf("failed to update knowledge base status: %w", err)
 	}
 
+	if err := tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("failed to commit version: %w", err)
+	}
+
 	return &version, nil
 }
 
 // GetLatestVersion gets the latest version for a knowledge base
 func (m *KnowledgeBaseModel) GetLatestVersion(ctx context.Context, knowledgeBaseID int64) (*KnowledgeBaseVersion, error) {
+	ctx, cancel := db.WithQueryTimeout(ctx)
+	defer cancel()
+
 	query := `
 		SELECT id, knowledge_base_id, version_number, version_string, status, training_started_at, training_completed_at,
-		       total_embeddings, total_chunks, embedding_dimension, total_storage_size, average_chunk_size, quality_score,
-		       created_at, updated_at
+		       total_embeddings, total_chunks, embedding_dimension, total_storage_size, average_chunk_size, compression_savings, quality_score,
+		       chunk_size, chunk_overlap, embedding_model, created_at, updated_at
 		FROM knowledge_base_versions
 		WHERE knowledge_base_id = $1
 		ORDER BY version_number DESC
@@ -365,7 +1146,7 @@ func (m *KnowledgeBaseModel) GetLatestVersion(ctx context.Context, knowledgeBase
 		&version.ID, &version.KnowledgeBaseID, &version.VersionNumber, &version.VersionString,
 		&version.Status, &version.TrainingStartedAt, &trainingCompletedAt,
 		&version.TotalEmbeddings, &version.TotalChunks, &version.EmbeddingDimension, &version.TotalStorageSize,
-		&version.AverageChunkSize, &version.QualityScore, &version.CreatedAt, &version.UpdatedAt,
+		&version.AverageChunkSize, &version.CompressionSavings, &version.QualityScore, &version.ChunkSize, &version.ChunkOverlap, &version.EmbeddingModel, &version.CreatedAt, &version.UpdatedAt,
 	)
 	if err != nil {
 		return nil, ErrKnowledgeBaseVersionNotFound
@@ -377,6 +1158,9 @@ func (m *KnowledgeBaseModel) GetLatestVersion(ctx context.Context, knowledgeBase
 
 // GetVersionCount returns the total number of versions for a knowledge base
 func (m *KnowledgeBaseModel) GetVersionCount(ctx context.Context, knowledgeBaseID int64) (int, error) {
+	ctx, cancel := db.WithQueryTimeout(ctx)
+	defer cancel()
+
 	query := `SELECT COUNT(*) FROM knowledge_base_versions WHERE knowledge_base_id = $1`
 	var count int
 	err := m.DB.QueryRow(ctx, query, knowledgeBaseID).Scan(&count)
@@ -385,10 +1169,13 @@ func (m *KnowledgeBaseModel) GetVersionCount(ctx context.Context, knowledgeBaseI
 
 // GetAllVersions gets all versions for a knowledge base, ordered by version number descending
 func (m *KnowledgeBaseModel) GetAllVersions(ctx context.Context, knowledgeBaseID int64) ([]*KnowledgeBaseVersion, error) {
+	ctx, cancel := db.WithQueryTimeout(ctx)
+	defer cancel()
+
 	query := `
 		SELECT id, knowledge_base_id, version_number, version_string, status, training_started_at, training_completed_at,
-		       total_embeddings, total_chunks, embedding_dimension, total_storage_size, average_chunk_size, quality_score,
-		       created_at, updated_at
+		       total_embeddings, total_chunks, embedding_dimension, total_storage_size, average_chunk_size, compression_savings, quality_score,
+		       chunk_size, chunk_overlap, embedding_model, created_at, updated_at
 		FROM knowledge_base_versions
 		WHERE knowledge_base_id = $1
 		ORDER BY version_number DESC
@@ -408,7 +1195,7 @@ func (m *KnowledgeBaseModel) GetAllVersions(ctx context.Context, knowledgeBaseID
 			&version.ID, &version.KnowledgeBaseID, &version.VersionNumber, &version.VersionString,
 			&version.Status, &version.TrainingStartedAt, &trainingCompletedAt,
 			&version.TotalEmbeddings, &version.TotalChunks, &version.EmbeddingDimension, &version.TotalStorageSize,
-			&version.AverageChunkSize, &version.QualityScore, &version.CreatedAt, &version.UpdatedAt,
+			&version.AverageChunkSize, &version.CompressionSavings, &version.QualityScore, &version.ChunkSize, &version.ChunkOverlap, &version.EmbeddingModel, &version.CreatedAt, &version.UpdatedAt,
 		)
 		if err != nil {
 			return nil, err
@@ -420,19 +1207,145 @@ func (m *KnowledgeBaseModel) GetAllVersions(ctx context.Context, knowledgeBaseID
 	return versions, rows.Err()
 }
 
+// TrainingRun is one entry in a knowledge base's training history
+type TrainingRun struct {
+	VersionID           int64      `json:"-"`
+	VersionNumber       int        `json:"version_number"`
+	VersionString       string     `json:"version_string"`
+	Status              string     `json:"status"`
+	TrainingStartedAt   time.Time  `json:"training_started_at"`
+	TrainingCompletedAt *time.Time `json:"training_completed_at"`
+	DurationSeconds     *float64   `json:"duration_seconds"` // nil while the run is in progress
+	QualityScore        *float64   `json:"quality_score"`
+}
+
+// MarshalJSON custom marshaling to convert int64 IDs to strings
+func (r TrainingRun) MarshalJSON() ([]byte, error) {
+	type Alias TrainingRun
+	return json.Marshal(&struct {
+		VersionID string `json:"version_id"`
+		*Alias
+	}{
+		VersionID: fmt.Sprintf("%d", r.VersionID),
+		Alias:     (*Alias)(&r),
+	})
+}
+
+// GetTrainingHistory returns every training run of a knowledge base, oldest first, with the
+// duration of each finished run
+func (m *KnowledgeBaseModel) GetTrainingHistory(ctx context.Context, knowledgeBaseID int64) ([]*TrainingRun, error) {
+	ctx, cancel := db.WithQueryTimeout(ctx)
+	defer cancel()
+
+	query := `
+		SELECT id, version_number, version_string, status, training_started_at, training_completed_at,
+		       EXTRACT(EPOCH FROM (training_completed_at - training_started_at))::float8, quality_score
+		FROM knowledge_base_versions
+		WHERE knowledge_base_id = $1
+		ORDER BY training_started_at ASC, version_number ASC
+	`
+
+	rows, err := m.DB.Query(ctx, query, knowledgeBaseID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	runs := []*TrainingRun{}
+	for rows.Next() {
+		var run TrainingRun
+		err := rows.Scan(
+			&run.VersionID, &run.VersionNumber, &run.VersionString, &run.Status, &run.TrainingStartedAt,
+			&run.TrainingCompletedAt, &run.DurationSeconds, &run.QualityScore,
+		)
+		if err != nil {
+			return nil, err
+		}
+		runs = append(runs, &run)
+	}
+
+	return runs, rows.Err()
+}
+
+// ThroughputStats summarizes how fast recent completed versions were trained
+type ThroughputStats struct {
+	Versions     int64   `json:"versions"`      // Completed versions the stats are based on
+	TotalChunks  int64   `json:"total_chunks"`  // Chunks embedded across those versions
+	ChunkBytes   int64   `json:"chunk_bytes"`   // Approximate chunk text embedded (chunks times average chunk size)
+	TotalSeconds float64 `json:"total_seconds"` // Time spent training those versions
+}
+
+// BytesPerSecond returns the chunk text embedded per second of training, or 0 without history
+func (s *ThroughputStats) BytesPerSecond() float64 {
+	if s.TotalSeconds <= 0 {
+		return 0
+	}
+	return float64(s.ChunkBytes) / s.TotalSeconds
+}
+
+// GetThroughputStats returns training throughput over the most recent completed versions across
+// all knowledge bases, at most sampleSize of them. Versions without chunks or a duration are skipped.
+func (m *KnowledgeBaseModel) GetThroughputStats(ctx context.Context, sampleSize int) (*ThroughputStats, error) {
+	ctx, cancel := db.WithQueryTimeout(ctx)
+	defer cancel()
+
+	query := `
+		SELECT COUNT(*), COALESCE(SUM(total_chunks), 0), COALESCE(SUM(total_chunks::bigint * average_chunk_size), 0),
+		       COALESCE(SUM(seconds), 0)
+		FROM (
+			SELECT total_chunks, average_chunk_size,
+			       EXTRACT(EPOCH FROM (training_completed_at - training_started_at))::float8 AS seconds
+			FROM knowledge_base_versions
+			WHERE status = 'completed' AND total_chunks > 0
+			  AND training_completed_at > training_started_at
+			ORDER BY training_completed_at DESC
+			LIMIT $1
+		) recent
+	`
+
+	var stats ThroughputStats
+	err := m.DB.QueryRow(ctx, query, sampleSize).Scan(&stats.Versions, &stats.TotalChunks, &stats.ChunkBytes, &stats.TotalSeconds)
+	if err != nil {
+		return nil, err
+	}
+	return &stats, nil
+}
+
 // DeleteVersion deletes a version by ID
 func (m *KnowledgeBaseModel) DeleteVersion(ctx context.Context, versionID int64) error {
+	ctx, cancel := db.WithQueryTimeout(ctx)
+	defer cancel()
+
 	query := `DELETE FROM knowledge_base_versions WHERE id = $1`
 	_, err := m.DB.Exec(ctx, query, versionID)
 	return err
 }
 
+// DeleteEmbedding deletes a single embedding (chunk) of a version
+func (m *KnowledgeBaseModel) DeleteEmbedding(ctx context.Context, versionID, embeddingID int64) error {
+	ctx, cancel := db.WithQueryTimeout(ctx)
+	defer cancel()
+
+	query := `DELETE FROM knowledge_base_embeddings WHERE id = $1 AND knowledge_base_version_id = $2`
+	result, err := m.DB.Exec(ctx, query, embeddingID, versionID)
+	if err != nil {
+		return err
+	}
+	if result.RowsAffected() == 0 {
+		return ErrKnowledgeBaseEmbeddingNotFound
+	}
+	return nil
+}
+
 // GetVersionByID gets a specific version by ID
 func (m *KnowledgeBaseModel) GetVersionByID(ctx context.Context, versionID int64) (*KnowledgeBaseVersion, error) {
+	ctx, cancel := db.WithQueryTimeout(ctx)
+	defer cancel()
+
 	query := `
 		SELECT id, knowledge_base_id, version_number, version_string, status, training_started_at, training_completed_at,
-		       total_embeddings, total_chunks, embedding_dimension, total_storage_size, average_chunk_size, quality_score,
-		       created_at, updated_at
+		       total_embeddings, total_chunks, embedding_dimension, total_storage_size, average_chunk_size, compression_savings, quality_score,
+		       chunk_size, chunk_overlap, embedding_model, created_at, updated_at
 		FROM knowledge_base_versions
 		WHERE id = $1
 	`
@@ -443,7 +1356,7 @@ func (m *KnowledgeBaseModel) GetVersionByID(ctx context.Context, versionID int64
 		&version.ID, &version.KnowledgeBaseID, &version.VersionNumber, &version.VersionString,
 		&version.Status, &version.TrainingStartedAt, &trainingCompletedAt,
 		&version.TotalEmbeddings, &version.TotalChunks, &version.EmbeddingDimension, &version.TotalStorageSize,
-		&version.AverageChunkSize, &version.QualityScore, &version.CreatedAt, &version.UpdatedAt,
+		&version.AverageChunkSize, &version.CompressionSavings, &version.QualityScore, &version.ChunkSize, &version.ChunkOverlap, &version.EmbeddingModel, &version.CreatedAt, &version.UpdatedAt,
 	)
 	if err != nil {
 		return nil, ErrKnowledgeBaseVersionNotFound
@@ -453,8 +1366,66 @@ func (m *KnowledgeBaseModel) GetVersionByID(ctx context.Context, versionID int64
 	return &version, nil
 }
 
+// ResetTrainingStatus recovers a knowledge base left training by a run that died: its training
+// versions are marked failed and the knowledge base becomes active again, in one transaction.
+// It returns the IDs of the failed versions, or ErrKnowledgeBaseNotFound if the knowledge base
+// is not training.
+func (m *KnowledgeBaseModel) ResetTrainingStatus(ctx context.Context, knowledgeBaseID int64) ([]int64, error) {
+	ctx, cancel := db.WithQueryTimeout(ctx)
+	defer cancel()
+
+	tx, err := m.DB.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	result, err := tx.Exec(ctx, `
+		UPDATE knowledge_bases SET status = 'active', updated_at = NOW()
+		WHERE id = $1 AND status = 'training'
+	`, knowledgeBaseID)
+	if err != nil {
+		return nil, err
+	}
+	if result.RowsAffected() == 0 {
+		return nil, ErrKnowledgeBaseNotFound
+	}
+
+	rows, err := tx.Query(ctx, `
+		UPDATE knowledge_base_versions
+		SET status = 'failed', training_completed_at = NOW(), updated_at = NOW()
+		WHERE knowledge_base_id = $1 AND status = 'training'
+		RETURNING id
+	`, knowledgeBaseID)
+	if err != nil {
+		return nil, err
+	}
+	versionIDs := []int64{}
+	for rows.Next() {
+		var versionID int64
+		if err := rows.Scan(&versionID); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		versionIDs = append(versionIDs, versionID)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return versionIDs, nil
+}
+
 // UpdateVersionStatus updates the status of a version
 func (m *KnowledgeBaseModel) UpdateVersionStatus(ctx context.Context, versionID int64, status string, completedAt *time.Time) error {
+	ctx, cancel := db.WithQueryTimeout(ctx)
+	defer cancel()
+
 	query := `
 		UPDATE knowledge_base_versions
 		SET status = $1, training_completed_at = $2, updated_at = NOW()
@@ -464,8 +1435,255 @@ func (m *KnowledgeBaseModel) UpdateVersionStatus(ctx context.Context, versionID
 	return err
 }
 
+// AppendVersionLog appends text to a version's training log, keeping only the last maxChars
+// characters so the most recent messages (usually the errors) survive truncation
+func (m *KnowledgeBaseModel) AppendVersionLog(ctx context.Context, versionID int64, text string, maxChars int) error {
+	ctx, cancel := db.WithQueryTimeout(ctx)
+	defer cancel()
+
+	query := `
+		UPDATE knowledge_base_versions
+		SET training_log = RIGHT(COALESCE(training_log, '') || $1, $2)
+		WHERE id = $3
+	`
+	_, err := m.DB.Exec(ctx, query, text, maxChars, versionID)
+	return err
+}
+
+// GetVersionLog returns a version's training log, which is empty if nothing was recorded
+func (m *KnowledgeBaseModel) GetVersionLog(ctx context.Context, versionID int64) (string, error) {
+	ctx, cancel := db.WithQueryTimeout(ctx)
+	defer cancel()
+
+	var trainingLog *string
+	err := m.DB.QueryRow(ctx, `SELECT training_log FROM knowledge_base_versions WHERE id = $1`, versionID).Scan(&trainingLog)
+	if err != nil {
+		return "", ErrKnowledgeBaseVersionNotFound
+	}
+	if trainingLog == nil {
+		return "", nil
+	}
+	return *trainingLog, nil
+}
+
+// KnowledgeBaseTrainingError records why a file failed during a version's training run
+type KnowledgeBaseTrainingError struct {
+	ID           int64     `json:"-" db:"id"`
+	VersionID    int64     `json:"-" db:"knowledge_base_version_id"`
+	FileID       int64     `json:"-" db:"knowledge_base_file_id"`
+	FileName     string    `json:"file_name" db:"file_name"`
+	JobID        string    `json:"job_id" db:"job_id"`
+	JobIndex     int       `json:"job_index" db:"job_index"`
+	ErrorMessage string    `json:"error_message" db:"error_message"`
+	CreatedAt    time.Time `json:"created_at" db:"created_at"`
+}
+
+// MarshalJSON custom marshaling to convert int64 IDs to strings
+func (e KnowledgeBaseTrainingError) MarshalJSON() ([]byte, error) {
+	type Alias KnowledgeBaseTrainingError
+	return json.Marshal(&struct {
+		ID        string `json:"id"`
+		VersionID string `json:"version_id"`
+		FileID    string `json:"file_id"`
+		*Alias
+	}{
+		ID:        fmt.Sprintf("%d", e.ID),
+		VersionID: fmt.Sprintf("%d", e.VersionID),
+		FileID:    fmt.Sprintf("%d", e.FileID),
+		Alias:     (*Alias)(&e),
+	})
+}
+
+// RecordTrainingError saves why a file failed in a version's training run,
+// replacing any earlier error for the same file and version
+func (m *KnowledgeBaseModel) RecordTrainingError(ctx context.Context, versionID, fileID int64, fileName, jobID string, jobIndex int, message string) error {
+	ctx, cancel := db.WithQueryTimeout(ctx)
+	defer cancel()
+
+	query := `
+		INSERT INTO knowledge_base_training_errors (
+			id, knowledge_base_version_id, knowledge_base_file_id, file_name, job_id, job_index, error_message, created_at
+		)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, NOW())
+		ON CONFLICT (knowledge_base_version_id, knowledge_base_file_id)
+		DO UPDATE SET
+			file_name = EXCLUDED.file_name,
+			job_id = EXCLUDED.job_id,
+			job_index = EXCLUDED.job_index,
+			error_message = EXCLUDED.error_message,
+			created_at = NOW()
+	`
+
+	_, err := m.DB.Exec(ctx, query, id.Generate(), versionID, fileID, fileName, jobID, jobIndex, message)
+	return err
+}
+
+// ClearTrainingErrors removes the recorded errors of files in a version, e.g. before they are retried
+func (m *KnowledgeBaseModel) ClearTrainingErrors(ctx context.Context, versionID int64, fileIDs []int64) error {
+	ctx, cancel := db.WithQueryTimeout(ctx)
+	defer cancel()
+
+	query := `DELETE FROM knowledge_base_training_errors WHERE knowledge_base_version_id = $1 AND knowledge_base_file_id = ANY($2)`
+	_, err := m.DB.Exec(ctx, query, versionID, fileIDs)
+	return err
+}
+
+// GetTrainingErrors returns the per-file errors recorded for a version, in job order
+func (m *KnowledgeBaseModel) GetTrainingErrors(ctx context.Context, versionID int64) ([]*KnowledgeBaseTrainingError, error) {
+	ctx, cancel := db.WithQueryTimeout(ctx)
+	defer cancel()
+
+	query := `
+		SELECT id, knowledge_base_version_id, knowledge_base_file_id, file_name, job_id, job_index, error_message, created_at
+		FROM knowledge_base_training_errors
+		WHERE knowledge_base_version_id = $1
+		ORDER BY job_index, file_name
+	`
+
+	rows, err := m.DB.Query(ctx, query, versionID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	trainingErrors := []*KnowledgeBaseTrainingError{}
+	for rows.Next() {
+		var e KnowledgeBaseTrainingError
+		if err := rows.Scan(&e.ID, &e.VersionID, &e.FileID, &e.FileName, &e.JobID, &e.JobIndex, &e.ErrorMessage, &e.CreatedAt); err != nil {
+			return nil, err
+		}
+		trainingErrors = append(trainingErrors, &e)
+	}
+
+	return trainingErrors, rows.Err()
+}
+
+// ChunkSizeDistribution describes the sizes in characters of a version's chunks
+type ChunkSizeDistribution struct {
+	Chunks  int64   `json:"chunks"`
+	Min     int64   `json:"min"`
+	Max     int64   `json:"max"`
+	Average float64 `json:"average"`
+	P25     float64 `json:"p25"`
+	Median  float64 `json:"median"`
+	P75     float64 `json:"p75"`
+	P90     float64 `json:"p90"`
+	P99     float64 `json:"p99"`
+}
+
+// FileEmbeddingStats are the embeddings a version produced for one file
+type FileEmbeddingStats struct {
+	FileID           int64   `json:"-"`
+	Name             string  `json:"name"`
+	Embeddings       int64   `json:"embeddings"`
+	TotalChunkSize   int64   `json:"total_chunk_size"`
+	AverageChunkSize float64 `json:"average_chunk_size"`
+}
+
+// MarshalJSON custom marshaling to convert int64 IDs to strings
+func (f FileEmbeddingStats) MarshalJSON() ([]byte, error) {
+	type Alias FileEmbeddingStats
+	return json.Marshal(&struct {
+		FileID string `json:"file_id"`
+		*Alias
+	}{
+		FileID: fmt.Sprintf("%d", f.FileID),
+		Alias:  (*Alias)(&f),
+	})
+}
+
+// EmbeddingCoverage counts a version's files with and without embeddings
+type EmbeddingCoverage struct {
+	Files            int64   `json:"files"`
+	FilesEmbedded    int64   `json:"files_embedded"`
+	FilesNotEmbedded int64   `json:"files_not_embedded"`
+	CoveragePercent  float64 `json:"coverage_percent"`
+}
+
+// VersionQualityBreakdown explains a version's quality score
+type VersionQualityBreakdown struct {
+	ChunkSizes ChunkSizeDistribution `json:"chunk_sizes"`
+	Coverage   EmbeddingCoverage     `json:"coverage"`
+	Files      []*FileEmbeddingStats `json:"files"`
+}
+
+// GetVersionQualityBreakdown returns the chunk size distribution, per-file embedding counts and
+// file coverage of a version. Its files are those uploaded before training started plus any
+// that have embeddings in it; files are ordered by embedding count, largest first.
+func (m *KnowledgeBaseModel) GetVersionQualityBreakdown(ctx context.Context, versionID int64) (*VersionQualityBreakdown, error) {
+	ctx, cancel := db.WithQueryTimeout(ctx)
+	defer cancel()
+
+	sizeQuery := `
+		WITH sizes AS (
+			SELECT CASE WHEN compressed THEN chunk_length ELSE LENGTH(chunk_text) END AS size
+			FROM knowledge_base_embeddings
+			WHERE knowledge_base_version_id = $1
+		)
+		SELECT COUNT(*), COALESCE(MIN(size), 0), COALESCE(MAX(size), 0), COALESCE(AVG(size), 0)::float8,
+		       COALESCE(percentile_cont(0.25) WITHIN GROUP (ORDER BY size), 0),
+		       COALESCE(percentile_cont(0.5) WITHIN GROUP (ORDER BY size), 0),
+		       COALESCE(percentile_cont(0.75) WITHIN GROUP (ORDER BY size), 0),
+		       COALESCE(percentile_cont(0.9) WITHIN GROUP (ORDER BY size), 0),
+		       COALESCE(percentile_cont(0.99) WITHIN GROUP (ORDER BY size), 0)
+		FROM sizes
+	`
+
+	breakdown := &VersionQualityBreakdown{Files: []*FileEmbeddingStats{}}
+	d := &breakdown.ChunkSizes
+	err := m.DB.QueryRow(ctx, sizeQuery, versionID).Scan(&d.Chunks, &d.Min, &d.Max, &d.Average, &d.P25, &d.Median, &d.P75, &d.P90, &d.P99)
+	if err != nil {
+		return nil, err
+	}
+
+	fileQuery := `
+		SELECT f.id, f.name, COUNT(e.id),
+		       COALESCE(SUM(CASE WHEN e.compressed THEN e.chunk_length ELSE LENGTH(e.chunk_text) END), 0)::bigint,
+		       COALESCE(AVG(CASE WHEN e.compressed THEN e.chunk_length ELSE LENGTH(e.chunk_text) END), 0)::float8
+		FROM knowledge_base_versions v
+		JOIN knowledge_base_files f ON f.knowledge_base_id = v.knowledge_base_id
+		LEFT JOIN knowledge_base_embeddings e ON e.knowledge_base_file_id = f.id AND e.knowledge_base_version_id = v.id
+		WHERE v.id = $1
+		GROUP BY f.id, f.name, f.created_at, v.training_started_at
+		HAVING f.created_at <= v.training_started_at OR COUNT(e.id) > 0
+		ORDER BY COUNT(e.id) DESC, f.name
+	`
+
+	rows, err := m.DB.Query(ctx, fileQuery, versionID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var f FileEmbeddingStats
+		if err := rows.Scan(&f.FileID, &f.Name, &f.Embeddings, &f.TotalChunkSize, &f.AverageChunkSize); err != nil {
+			return nil, err
+		}
+		breakdown.Coverage.Files++
+		if f.Embeddings > 0 {
+			breakdown.Coverage.FilesEmbedded++
+		} else {
+			breakdown.Coverage.FilesNotEmbedded++
+		}
+		breakdown.Files = append(breakdown.Files, &f)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	if breakdown.Coverage.Files > 0 {
+		breakdown.Coverage.CoveragePercent = float64(breakdown.Coverage.FilesEmbedded) / float64(breakdown.Coverage.Files) * 100
+	}
+
+	return breakdown, nil
+}
+
 // UpdateVersionQualityMetrics calculates and updates quality metrics for a version
 func (m *KnowledgeBaseModel) UpdateVersionQualityMetrics(ctx context.Context, versionID int64) error {
+	ctx, cancel := db.WithQueryTimeout(ctx)
+	defer cancel()
+
 	// Calculate metrics from embeddings
 	query := `
 		UPDATE knowledge_base_versions v
@@ -488,7 +1706,7 @@ func (m *KnowledgeBaseModel) UpdateVersionQualityMetrics(ctx context.Context, ve
 			), 1536),
 			total_storage_size = (
 				SELECT COALESCE(SUM(
-					LENGTH(e.chunk_text) + 
+					CASE WHEN e.compressed THEN OCTET_LENGTH(e.chunk_data) ELSE LENGTH(e.chunk_text) END +
 					(vector_dims(e.embedding) * 4) +
 					LENGTH(COALESCE(e.metadata::text, '{}'))
 				), 0)
@@ -496,16 +1714,21 @@ func (m *KnowledgeBaseModel) UpdateVersionQualityMetrics(ctx context.Context, ve
 				WHERE e.knowledge_base_version_id = v.id
 			),
 			average_chunk_size = (
-				SELECT COALESCE(AVG(LENGTH(e.chunk_text))::INTEGER, 0)
+				SELECT COALESCE(AVG(CASE WHEN e.compressed THEN e.chunk_length ELSE LENGTH(e.chunk_text) END)::INTEGER, 0)
 				FROM knowledge_base_embeddings e 
 				WHERE e.knowledge_base_version_id = v.id
 			),
+			compression_savings = (
+				SELECT COALESCE(SUM(e.chunk_length - OCTET_LENGTH(e.chunk_data)), 0)
+				FROM knowledge_base_embeddings e 
+				WHERE e.knowledge_base_version_id = v.id AND e.compressed
+			),
 			quality_score = (
 				SELECT CASE 
 					WHEN COUNT(*) = 0 THEN NULL
 					ELSE LEAST(100, GREATEST(0,
 						-- Base score from chunk quality (average chunk size vs optimal)
-						LEAST(50, (AVG(LENGTH(e.chunk_text)) / 1000.0 * 50)) +
+						LEAST(50, (AVG(CASE WHEN e.compressed THEN e.chunk_length ELSE LENGTH(e.chunk_text) END) / 1000.0 * 50)) +
 						-- Score from embedding coverage (files processed)
 						LEAST(30, (COUNT(DISTINCT e.knowledge_base_file_id) * 5.0)) +
 						-- Score from chunk diversity
@@ -536,7 +1759,74 @@ type KnowledgeBaseEmbedding struct {
 	UpdatedAt              time.Time `json:"updated_at" db:"updated_at"`
 }
 
-// StoreEmbedding stores an embedding in the database
+// KnowledgeBaseSearchResult is a chunk matched by a similarity search
+type KnowledgeBaseSearchResult struct {
+	ID         int64   `json:"-"` // Embedding ID, used to delete a single chunk
+	FileID     int64   `json:"-"`
+	FileName   string  `json:"file_name"`
+	ChunkIndex int     `json:"chunk_index"`
+	ChunkText  string  `json:"chunk_text"`
+	Score      float64 `json:"score"` // Cosine similarity, higher is closer
+}
+
+// MarshalJSON custom marshaling to convert int64 IDs to strings
+func (r KnowledgeBaseSearchResult) MarshalJSON() ([]byte, error) {
+	type Alias KnowledgeBaseSearchResult
+	return json.Marshal(&struct {
+		ID     string `json:"id"`
+		FileID string `json:"file_id"`
+		*Alias
+	}{
+		ID:     fmt.Sprintf("%d", r.ID),
+		FileID: fmt.Sprintf("%d", r.FileID),
+		Alias:  (*Alias)(&r),
+	})
+}
+
+// SearchEmbeddings returns the chunks of a version closest to the embedding by cosine distance
+func (m *KnowledgeBaseModel) SearchEmbeddings(ctx context.Context, versionID int64, embedding []float32, limit int) ([]*KnowledgeBaseSearchResult, error) {
+	ctx, cancel := db.WithQueryTimeout(ctx)
+	defer cancel()
+
+	query := `
+		SELECT e.id, e.knowledge_base_file_id, f.name, e.chunk_index, e.chunk_text, e.compressed, e.chunk_data,
+		       1 - (e.embedding <=> $2::vector) AS score
+		FROM knowledge_base_embeddings e
+		INNER JOIN knowledge_base_files f ON f.id = e.knowledge_base_file_id
+		WHERE e.knowledge_base_version_id = $1 AND e.embedding IS NOT NULL
+		ORDER BY e.embedding <=> $2::vector
+		LIMIT $3
+	`
+
+	rows, err := m.DB.Query(ctx, query, versionID, formatVector(embedding), limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	results := []*KnowledgeBaseSearchResult{}
+	for rows.Next() {
+		var result KnowledgeBaseSearchResult
+		var compressed bool
+		var chunkData []byte
+		if err := rows.Scan(&result.ID, &result.FileID, &result.FileName, &result.ChunkIndex, &result.ChunkText, &compressed, &chunkData, &result.Score); err != nil {
+			return nil, err
+		}
+		if compressed {
+			text, err := decompressChunkText(chunkData)
+			if err != nil {
+				return nil, err
+			}
+			result.ChunkText = text
+		}
+		results = append(results, &result)
+	}
+
+	return results, rows.Err()
+}
+
+// StoreEmbedding stores an embedding in the database. With compress, the chunk text is stored
+// gzip-compressed in chunk_data and chunk_text is left empty.
 func (m *KnowledgeBaseModel) StoreEmbedding(
 	ctx context.Context,
 	knowledgeBaseID, versionID, fileID int64,
@@ -544,7 +1834,23 @@ func (m *KnowledgeBaseModel) StoreEmbedding(
 	chunkText string,
 	embedding []float32,
 	metadata map[string]interface{},
+	compress bool,
 ) error {
+	ctx, cancel := db.WithQueryTimeout(ctx)
+	defer cancel()
+
+	// Compressed chunks keep their length for the version's chunk size metrics
+	var chunkData []byte
+	var chunkLength *int
+	if compress {
+		data, err := compressChunkText(chunkText)
+		if err != nil {
+			return err
+		}
+		length := utf8.RuneCountInString(chunkText)
+		chunkData, chunkLength, chunkText = data, &length, ""
+	}
+
 	embeddingID := id.Generate()
 
 	// Convert metadata to JSON string
@@ -562,19 +1868,23 @@ func (m *KnowledgeBaseModel) StoreEmbedding(
 	query := `
 		INSERT INTO knowledge_base_embeddings (
 			id, knowledge_base_id, knowledge_base_version_id, knowledge_base_file_id,
-			chunk_index, chunk_text, embedding, metadata, created_at, updated_at
+			chunk_index, chunk_text, compressed, chunk_data, chunk_length,
+			embedding, metadata, created_at, updated_at
 		)
-		VALUES ($1, $2, $3, $4, $5, $6, $7::vector, $8::jsonb, NOW(), NOW())
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10::vector, $11::jsonb, NOW(), NOW())
 		ON CONFLICT (knowledge_base_version_id, knowledge_base_file_id, chunk_index) 
 		DO UPDATE SET
 			chunk_text = EXCLUDED.chunk_text,
+			compressed = EXCLUDED.compressed,
+			chunk_data = EXCLUDED.chunk_data,
+			chunk_length = EXCLUDED.chunk_length,
 			embedding = EXCLUDED.embedding,
 			metadata = EXCLUDED.metadata,
 			updated_at = NOW()
 	`
 
 	_, err := m.DB.Exec(ctx, query, embeddingID, knowledgeBaseID, versionID, fileID,
-		chunkIndex, chunkText, embeddingStr, metadataJSON)
+		chunkIndex, chunkText, compress, chunkData, chunkLength, embeddingStr, metadataJSON)
 	return err
 }
 
@@ -0,0 +1,842 @@
+package models
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/aithen/go-api/internal/limits"
+	"github.com/aithen/go-api/internal/pagination"
+)
+
+// createTestKnowledgeBase creates a knowledge base with one file in a new organization
+func createTestKnowledgeBase(t *testing.T, m *Models) (*KnowledgeBase, *KnowledgeBaseFile) {
+	t.Helper()
+
+	ctx := context.Background()
+	user := createTestUser(t, m)
+	org := createTestOrganization(t, m, user)
+	kb, err := m.KnowledgeBases.Create(ctx, org.ID, "Docs", "", &user.ID)
+	if err != nil {
+		t.Fatalf("failed to create knowledge base: %v", err)
+	}
+	file, err := m.KnowledgeBases.AddFile(ctx, kb.ID, "notes.txt", "uploads/notes.txt", 5, "text/plain", &user.ID, limits.ForPlan(limits.PlanEnterprise))
+	if err != nil {
+		t.Fatalf("failed to add file: %v", err)
+	}
+	return kb, file
+}
+
+func TestDeleteFile(t *testing.T) {
+	m := testModels(t)
+	ctx := context.Background()
+
+	t.Run("deletes the record and returns it", func(t *testing.T) {
+		kb, file := createTestKnowledgeBase(t, m)
+
+		deleted, err := m.KnowledgeBases.DeleteFile(ctx, kb.ID, file.ID)
+		if err != nil {
+			t.Fatalf("DeleteFile() error = %v", err)
+		}
+		if deleted.ID != file.ID || deleted.FilePath != file.FilePath {
+			t.Errorf("DeleteFile() = %+v, want file %d", deleted, file.ID)
+		}
+		if _, err := m.KnowledgeBases.GetFileByID(ctx, file.ID); err != ErrKnowledgeBaseFileNotFound {
+			t.Errorf("GetFileByID() after delete error = %v, want ErrKnowledgeBaseFileNotFound", err)
+		}
+	})
+
+	t.Run("a file from another knowledge base is not found", func(t *testing.T) {
+		_, file := createTestKnowledgeBase(t, m)
+		other, _ := createTestKnowledgeBase(t, m)
+
+		if _, err := m.KnowledgeBases.DeleteFile(ctx, other.ID, file.ID); err != ErrKnowledgeBaseFileNotFound {
+			t.Fatalf("DeleteFile() error = %v, want ErrKnowledgeBaseFileNotFound", err)
+		}
+		if _, err := m.KnowledgeBases.GetFileByID(ctx, file.ID); err != nil {
+			t.Errorf("file was deleted from its own knowledge base: %v", err)
+		}
+	})
+
+	t.Run("refused while training", func(t *testing.T) {
+		kb, file := createTestKnowledgeBase(t, m)
+		if err := m.KnowledgeBases.UpdateStatus(ctx, kb.ID, "training"); err != nil {
+			t.Fatalf("failed to set status: %v", err)
+		}
+
+		if _, err := m.KnowledgeBases.DeleteFile(ctx, kb.ID, file.ID); err != ErrKnowledgeBaseAlreadyTraining {
+			t.Fatalf("DeleteFile() error = %v, want ErrKnowledgeBaseAlreadyTraining", err)
+		}
+		if _, err := m.KnowledgeBases.GetFileByID(ctx, file.ID); err != nil {
+			t.Errorf("file was deleted while training: %v", err)
+		}
+	})
+}
+
+func TestDeleteKnowledgeBaseRefusedWhileTraining(t *testing.T) {
+	m := testModels(t)
+	ctx := context.Background()
+
+	kb, _ := createTestKnowledgeBase(t, m)
+	if err := m.KnowledgeBases.UpdateStatus(ctx, kb.ID, "training"); err != nil {
+		t.Fatalf("failed to set status: %v", err)
+	}
+
+	if err := m.KnowledgeBases.Delete(ctx, kb.ID); err != ErrKnowledgeBaseAlreadyTraining {
+		t.Fatalf("Delete() error = %v, want ErrKnowledgeBaseAlreadyTraining", err)
+	}
+	if _, err := m.KnowledgeBases.FindByID(ctx, kb.ID); err != nil {
+		t.Errorf("knowledge base was deleted while training: %v", err)
+	}
+
+	if err := m.KnowledgeBases.UpdateStatus(ctx, kb.ID, "active"); err != nil {
+		t.Fatalf("failed to set status: %v", err)
+	}
+	if err := m.KnowledgeBases.Delete(ctx, kb.ID); err != nil {
+		t.Fatalf("Delete() after training error = %v", err)
+	}
+	if err := m.KnowledgeBases.Delete(ctx, kb.ID); err != ErrKnowledgeBaseNotFound {
+		t.Errorf("Delete() of a deleted knowledge base error = %v, want ErrKnowledgeBaseNotFound", err)
+	}
+}
+
+func TestAddFileConcurrentStaysWithinLimit(t *testing.T) {
+	m := testModels(t)
+	kb, _ := createTestKnowledgeBase(t, m)
+	l := limits.ForPlan(limits.PlanEnterprise)
+	l.MaxFilesPerKB = 2
+
+	const attempts = 10
+	errs := make(chan error, attempts)
+	var wg sync.WaitGroup
+	for i := 0; i < attempts; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := m.KnowledgeBases.AddFile(context.Background(), kb.ID, "more.txt", "uploads/more.txt", 5, "text/plain", nil, l)
+			errs <- err
+		}()
+	}
+	wg.Wait()
+	close(errs)
+
+	added := 0
+	for err := range errs {
+		var limitErr *limits.LimitError
+		switch {
+		case err == nil:
+			added++
+		case errors.As(err, &limitErr):
+		default:
+			t.Fatalf("AddFile() error = %v, want nil or a limit error", err)
+		}
+	}
+	if added != 1 {
+		t.Fatalf("AddFile() succeeded %d times, want 1", added)
+	}
+	if count, err := m.KnowledgeBases.GetFileCount(context.Background(), kb.ID); err != nil || count != 2 {
+		t.Errorf("GetFileCount() = %d, %v, want 2", count, err)
+	}
+}
+
+func TestCreateVersionConcurrentCreatesOneVersion(t *testing.T) {
+	m := testModels(t)
+	kb, _ := createTestKnowledgeBase(t, m)
+
+	const attempts = 2
+	type result struct {
+		version *KnowledgeBaseVersion
+		err     error
+	}
+	results := make(chan result, attempts)
+	var wg sync.WaitGroup
+	for i := 0; i < attempts; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			version, err := m.KnowledgeBases.CreateVersion(context.Background(), kb.ID, nil, nil, nil)
+			results <- result{version, err}
+		}()
+	}
+	wg.Wait()
+	close(results)
+
+	created := 0
+	var versionIDs []int64
+	for r := range results {
+		switch r.err {
+		case nil:
+			created++
+		case ErrKnowledgeBaseAlreadyTraining:
+		default:
+			t.Fatalf("CreateVersion() error = %v, want nil or ErrKnowledgeBaseAlreadyTraining", r.err)
+		}
+		versionIDs = append(versionIDs, r.version.ID)
+	}
+	if created != 1 {
+		t.Fatalf("CreateVersion() created %d versions, want 1", created)
+	}
+	// The caller that lost the race gets the version that is already training
+	if versionIDs[0] != versionIDs[1] {
+		t.Errorf("CreateVersion() returned versions %v, want the same version to both callers", versionIDs)
+	}
+	if versions, err := m.KnowledgeBases.GetAllVersions(context.Background(), kb.ID); err != nil || len(versions) != 1 {
+		t.Errorf("GetAllVersions() = %d versions, %v, want 1", len(versions), err)
+	}
+}
+
+func TestKnowledgeBaseCreatedBy(t *testing.T) {
+	m := testModels(t)
+	ctx := context.Background()
+	owner := createTestUser(t, m)
+	org := createTestOrganization(t, m, owner)
+	uploader := createTestUser(t, m)
+	if _, err := m.Organizations.AddMember(ctx, org.ID, uploader.ID, "member", "active"); err != nil {
+		t.Fatalf("failed to add member: %v", err)
+	}
+	l := limits.ForPlan(limits.PlanEnterprise)
+
+	kb, err := m.KnowledgeBases.Create(ctx, org.ID, "Docs", "", &uploader.ID)
+	if err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	file, err := m.KnowledgeBases.AddFile(ctx, kb.ID, "notes.txt", "uploads/notes.txt", 5, "text/plain", &uploader.ID, l)
+	if err != nil {
+		t.Fatalf("AddFile() error = %v", err)
+	}
+	anonymous, err := m.KnowledgeBases.AddFile(ctx, kb.ID, "legacy.txt", "uploads/legacy.txt", 5, "text/plain", nil, l)
+	if err != nil {
+		t.Fatalf("AddFile() without a creator error = %v", err)
+	}
+
+	if kb.CreatedBy == nil || *kb.CreatedBy != uploader.ID || kb.CreatedByName == nil || *kb.CreatedByName != uploader.Name {
+		t.Errorf("Create() created_by = %v (%v), want user %d (%s)", kb.CreatedBy, kb.CreatedByName, uploader.ID, uploader.Name)
+	}
+	if file.CreatedBy == nil || *file.CreatedBy != uploader.ID || file.CreatedByName == nil || *file.CreatedByName != uploader.Name {
+		t.Errorf("AddFile() created_by = %v (%v), want user %d (%s)", file.CreatedBy, file.CreatedByName, uploader.ID, uploader.Name)
+	}
+	if anonymous.CreatedBy != nil || anonymous.CreatedByName != nil {
+		t.Errorf("AddFile() without a creator created_by = %v (%v), want null", anonymous.CreatedBy, anonymous.CreatedByName)
+	}
+
+	// Deleting the creator keeps their knowledge base and files, with the creator unknown
+	if _, err := m.Users.DeleteAccount(ctx, uploader.ID); err != nil {
+		t.Fatalf("DeleteAccount() error = %v", err)
+	}
+	found, err := m.KnowledgeBases.FindByID(ctx, kb.ID)
+	if err != nil {
+		t.Fatalf("FindByID() error = %v", err)
+	}
+	if found.CreatedBy != nil || found.CreatedByName != nil {
+		t.Errorf("created_by after deleting the creator = %v (%v), want null", found.CreatedBy, found.CreatedByName)
+	}
+	files, err := m.KnowledgeBases.GetFilesByKnowledgeBaseID(ctx, kb.ID)
+	if err != nil {
+		t.Fatalf("GetFilesByKnowledgeBaseID() error = %v", err)
+	}
+	for _, f := range files {
+		if f.CreatedBy != nil {
+			t.Errorf("file %s created_by after deleting the creator = %d, want null", f.Name, *f.CreatedBy)
+		}
+	}
+}
+
+func TestGetFileChunkCounts(t *testing.T) {
+	m := testModels(t)
+	ctx := context.Background()
+
+	kb, first := createTestKnowledgeBase(t, m)
+	second, err := m.KnowledgeBases.AddFile(ctx, kb.ID, "guide.txt", "uploads/guide.txt", 5, "text/plain", nil, limits.ForPlan(limits.PlanEnterprise))
+	if err != nil {
+		t.Fatalf("failed to add file: %v", err)
+	}
+	unembedded, err := m.KnowledgeBases.AddFile(ctx, kb.ID, "empty.txt", "uploads/empty.txt", 5, "text/plain", nil, limits.ForPlan(limits.PlanEnterprise))
+	if err != nil {
+		t.Fatalf("failed to add file: %v", err)
+	}
+	version, err := m.KnowledgeBases.CreateVersion(ctx, kb.ID, nil, nil, nil)
+	if err != nil {
+		t.Fatalf("failed to create version: %v", err)
+	}
+
+	embedding := make([]float32, 1536)
+	seed := map[int64]int{first.ID: 3, second.ID: 1}
+	for fileID, chunks := range seed {
+		for i := 0; i < chunks; i++ {
+			if err := m.KnowledgeBases.StoreEmbedding(ctx, kb.ID, version.ID, fileID, i, "chunk", embedding, nil, false); err != nil {
+				t.Fatalf("failed to store embedding: %v", err)
+			}
+		}
+	}
+
+	counts, err := m.KnowledgeBases.GetFileChunkCounts(ctx, version.ID)
+	if err != nil {
+		t.Fatalf("GetFileChunkCounts() error = %v", err)
+	}
+	if len(counts) != 2 || counts[first.ID] != 3 || counts[second.ID] != 1 {
+		t.Errorf("GetFileChunkCounts() = %v, want %d:3 and %d:1", counts, first.ID, second.ID)
+	}
+	if _, ok := counts[unembedded.ID]; ok {
+		t.Errorf("GetFileChunkCounts() includes file %d, which has no embeddings", unembedded.ID)
+	}
+
+	now := time.Now()
+	if err := m.KnowledgeBases.UpdateVersionStatus(ctx, version.ID, "completed", &now); err != nil {
+		t.Fatalf("failed to complete version: %v", err)
+	}
+	other, err := m.KnowledgeBases.CreateVersion(ctx, kb.ID, nil, nil, nil)
+	if err != nil {
+		t.Fatalf("failed to create version: %v", err)
+	}
+	if counts, err := m.KnowledgeBases.GetFileChunkCounts(ctx, other.ID); err != nil || len(counts) != 0 {
+		t.Errorf("GetFileChunkCounts() for a version without embeddings = %v, %v, want empty", counts, err)
+	}
+}
+
+func TestGetOrganizationStorageStats(t *testing.T) {
+	m := testModels(t)
+	ctx := context.Background()
+
+	user := createTestUser(t, m)
+	org := createTestOrganization(t, m, user)
+	enterprise := limits.ForPlan(limits.PlanEnterprise)
+
+	// addKB creates a knowledge base with files of the given sizes and one version per entry of
+	// embeddings, each storing that many chunks for the first file
+	addKB := func(name string, fileSizes []int64, embeddings []int) *KnowledgeBase {
+		t.Helper()
+		kb, err := m.KnowledgeBases.Create(ctx, org.ID, name, "", &user.ID)
+		if err != nil {
+			t.Fatalf("failed to create knowledge base: %v", err)
+		}
+		var files []*KnowledgeBaseFile
+		for i, size := range fileSizes {
+			file, err := m.KnowledgeBases.AddFile(ctx, kb.ID, fmt.Sprintf("file-%d.txt", i), fmt.Sprintf("uploads/file-%d.txt", i), size, "text/plain", nil, enterprise)
+			if err != nil {
+				t.Fatalf("failed to add file: %v", err)
+			}
+			files = append(files, file)
+		}
+		for _, chunks := range embeddings {
+			version, err := m.KnowledgeBases.CreateVersion(ctx, kb.ID, nil, nil, nil)
+			if err != nil {
+				t.Fatalf("failed to create version: %v", err)
+			}
+			for i := 0; i < chunks; i++ {
+				if err := m.KnowledgeBases.StoreEmbedding(ctx, kb.ID, version.ID, files[0].ID, i, "chunk", make([]float32, 1536), nil, false); err != nil {
+					t.Fatalf("failed to store embedding: %v", err)
+				}
+			}
+			now := time.Now()
+			if err := m.KnowledgeBases.UpdateVersionStatus(ctx, version.ID, "completed", &now); err != nil {
+				t.Fatalf("failed to complete version: %v", err)
+			}
+		}
+		return kb
+	}
+	first := addKB("First", []int64{5, 7}, []int{2, 1})
+	second := addKB("Second", []int64{3}, []int{1})
+
+	// Another organization's knowledge base is not counted
+	otherOrg := createTestOrganization(t, m, user)
+	if _, err := m.KnowledgeBases.Create(ctx, otherOrg.ID, "Other", "", &user.ID); err != nil {
+		t.Fatalf("failed to create knowledge base: %v", err)
+	}
+
+	stats, err := m.KnowledgeBases.GetOrganizationStorageStats(ctx, org.ID)
+	if err != nil {
+		t.Fatalf("GetOrganizationStorageStats() error = %v", err)
+	}
+
+	// Each embedding is estimated as its chunk text, 4 bytes per dimension and its metadata
+	const embeddingBytes = int64(len("chunk") + 1536*4 + len("{}"))
+	if stats.KnowledgeBases != 2 || stats.Versions != 3 || stats.Files != 3 || stats.FileBytes != 15 ||
+		stats.Embeddings != 4 || stats.EmbeddingBytes != 4*embeddingBytes {
+		t.Errorf("totals = %+v, want 2 knowledge bases, 3 versions, 3 files of 15 bytes and 4 embeddings of %d bytes",
+			stats, 4*embeddingBytes)
+	}
+
+	want := map[int64]KnowledgeBaseStorageStats{
+		first.ID:  {Name: "First", Versions: 2, Files: 2, FileBytes: 12, Embeddings: 3, EmbeddingBytes: 3 * embeddingBytes},
+		second.ID: {Name: "Second", Versions: 1, Files: 1, FileBytes: 3, Embeddings: 1, EmbeddingBytes: embeddingBytes},
+	}
+	if len(stats.ByKnowledgeBase) != len(want) {
+		t.Fatalf("ByKnowledgeBase has %d entries, want %d", len(stats.ByKnowledgeBase), len(want))
+	}
+	for _, got := range stats.ByKnowledgeBase {
+		w, ok := want[got.KnowledgeBaseID]
+		w.KnowledgeBaseID = got.KnowledgeBaseID
+		if !ok || *got != w {
+			t.Errorf("ByKnowledgeBase entry = %+v, want %+v", *got, w)
+		}
+	}
+}
+
+func TestCreateVersionRecordsChunking(t *testing.T) {
+	m := testModels(t)
+	ctx := context.Background()
+
+	kb, _ := createTestKnowledgeBase(t, m)
+	size, overlap := 800, 100
+	version, err := m.KnowledgeBases.CreateVersion(ctx, kb.ID, &size, &overlap, nil)
+	if err != nil {
+		t.Fatalf("CreateVersion() error = %v", err)
+	}
+
+	got, err := m.KnowledgeBases.GetVersionByID(ctx, version.ID)
+	if err != nil {
+		t.Fatalf("GetVersionByID() error = %v", err)
+	}
+	if got.ChunkSize == nil || *got.ChunkSize != size || got.ChunkOverlap == nil || *got.ChunkOverlap != overlap {
+		t.Errorf("version chunking = %v/%v, want %d/%d", got.ChunkSize, got.ChunkOverlap, size, overlap)
+	}
+
+	// Without parameters the version records that the service's defaults were used
+	now := time.Now()
+	if err := m.KnowledgeBases.UpdateVersionStatus(ctx, version.ID, "completed", &now); err != nil {
+		t.Fatalf("failed to complete version: %v", err)
+	}
+	defaults, err := m.KnowledgeBases.CreateVersion(ctx, kb.ID, nil, nil, nil)
+	if err != nil {
+		t.Fatalf("CreateVersion() error = %v", err)
+	}
+	if defaults.ChunkSize != nil || defaults.ChunkOverlap != nil {
+		t.Errorf("version chunking = %v/%v, want nil for the service defaults", defaults.ChunkSize, defaults.ChunkOverlap)
+	}
+}
+
+func TestAppendVersionLog(t *testing.T) {
+	m := testModels(t)
+	ctx := context.Background()
+
+	kb, _ := createTestKnowledgeBase(t, m)
+	version, err := m.KnowledgeBases.CreateVersion(ctx, kb.ID, nil, nil, nil)
+	if err != nil {
+		t.Fatalf("failed to create version: %v", err)
+	}
+
+	if got, err := m.KnowledgeBases.GetVersionLog(ctx, version.ID); err != nil || got != "" {
+		t.Errorf("GetVersionLog() before any messages = %q, %v, want empty", got, err)
+	}
+
+	for _, text := range []string{"first\n", "second\n", "third\n"} {
+		if err := m.KnowledgeBases.AppendVersionLog(ctx, version.ID, text, 14); err != nil {
+			t.Fatalf("AppendVersionLog() error = %v", err)
+		}
+	}
+
+	// Only the most recent characters are kept
+	got, err := m.KnowledgeBases.GetVersionLog(ctx, version.ID)
+	if err != nil {
+		t.Fatalf("GetVersionLog() error = %v", err)
+	}
+	if want := "cond\nthird\n"; len(got) != 14 || got[len(got)-len(want):] != want {
+		t.Errorf("GetVersionLog() = %q, want the last 14 characters ending in %q", got, want)
+	}
+}
+
+func TestSearchByName(t *testing.T) {
+	m := testModels(t)
+	ctx := context.Background()
+
+	user := createTestUser(t, m)
+	org := createTestOrganization(t, m, user)
+	for _, kb := range []struct{ name, description string }{
+		{"Sales Handbook", ""},
+		{"Engineering", "Handbook for engineers"},
+		{"Marketing", "Campaigns"},
+	} {
+		if _, err := m.KnowledgeBases.Create(ctx, org.ID, kb.name, kb.description, &user.ID); err != nil {
+			t.Fatalf("failed to create knowledge base: %v", err)
+		}
+	}
+	// A match in another organization must not show up
+	otherOrg := createTestOrganization(t, m, user)
+	if _, err := m.KnowledgeBases.Create(ctx, otherOrg.ID, "Handbook", "", &user.ID); err != nil {
+		t.Fatalf("failed to create knowledge base: %v", err)
+	}
+
+	tests := []struct {
+		name          string
+		query         string
+		limit, offset int
+		wantNames     []string
+		wantTotal     int
+	}{
+		{name: "matches name and description", query: "handbook", limit: 10, wantNames: []string{"Engineering", "Sales Handbook"}, wantTotal: 2},
+		{name: "case-insensitive", query: "  MARKET ", limit: 10, wantNames: []string{"Marketing"}, wantTotal: 1},
+		{name: "first page", query: "handbook", limit: 1, wantNames: []string{"Engineering"}, wantTotal: 2},
+		{name: "second page", query: "handbook", limit: 1, offset: 1, wantNames: []string{"Sales Handbook"}, wantTotal: 2},
+		{name: "no match", query: "finance", limit: 10, wantNames: []string{}, wantTotal: 0},
+		{name: "wildcards are literal", query: "%", limit: 10, wantNames: []string{}, wantTotal: 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			kbs, total, err := m.KnowledgeBases.SearchByName(ctx, org.ID, tt.query, tt.limit, tt.offset)
+			if err != nil {
+				t.Fatalf("SearchByName() error = %v", err)
+			}
+			names := make([]string, 0, len(kbs))
+			for _, kb := range kbs {
+				if kb.OrganizationID != org.ID {
+					t.Errorf("SearchByName() returned %q from organization %d", kb.Name, kb.OrganizationID)
+				}
+				names = append(names, kb.Name)
+			}
+			if fmt.Sprint(names) != fmt.Sprint(tt.wantNames) || total != tt.wantTotal {
+				t.Errorf("SearchByName() = %v, total %d, want %v, total %d", names, total, tt.wantNames, tt.wantTotal)
+			}
+		})
+	}
+}
+
+func TestChangeOrganization(t *testing.T) {
+	m := testModels(t)
+	ctx := context.Background()
+
+	kb, _ := createTestKnowledgeBase(t, m)
+	user := createTestUser(t, m)
+	target := createTestOrganization(t, m, user)
+
+	// The move only applies while the knowledge base still belongs to the source organization
+	if _, err := m.KnowledgeBases.ChangeOrganization(ctx, kb.ID, target.ID, target.ID); err != ErrKnowledgeBaseNotFound {
+		t.Errorf("ChangeOrganization() from the wrong organization error = %v, want ErrKnowledgeBaseNotFound", err)
+	}
+
+	moved, err := m.KnowledgeBases.ChangeOrganization(ctx, kb.ID, kb.OrganizationID, target.ID)
+	if err != nil {
+		t.Fatalf("ChangeOrganization() error = %v", err)
+	}
+	if moved.ID != kb.ID || moved.OrganizationID != target.ID || moved.Name != kb.Name {
+		t.Errorf("ChangeOrganization() = %+v, want knowledge base %d in organization %d", moved, kb.ID, target.ID)
+	}
+	if count, err := m.KnowledgeBases.GetFileCount(ctx, kb.ID); err != nil || count != 1 {
+		t.Errorf("file count = %d (err %v), want the file kept", count, err)
+	}
+}
+
+func TestGetTrainingHistory(t *testing.T) {
+	m := testModels(t)
+	ctx := context.Background()
+
+	kb, _ := createTestKnowledgeBase(t, m)
+	start := time.Now().Add(-2 * time.Hour).Truncate(time.Second)
+	runs := []struct {
+		status   string
+		started  time.Time
+		duration time.Duration // 0 leaves the run in progress
+	}{
+		{"completed", start, 90 * time.Second},
+		{"failed", start.Add(time.Hour), 30 * time.Second},
+		{"training", start.Add(90 * time.Minute), 0},
+	}
+	for _, run := range runs {
+		version, err := m.KnowledgeBases.CreateVersion(ctx, kb.ID, nil, nil, nil)
+		if err != nil {
+			t.Fatalf("failed to create version: %v", err)
+		}
+		if _, err := m.KnowledgeBases.DB.Exec(ctx, `UPDATE knowledge_base_versions SET training_started_at = $1 WHERE id = $2`, run.started, version.ID); err != nil {
+			t.Fatalf("failed to set start time: %v", err)
+		}
+		if run.duration > 0 {
+			completed := run.started.Add(run.duration)
+			if err := m.KnowledgeBases.UpdateVersionStatus(ctx, version.ID, run.status, &completed); err != nil {
+				t.Fatalf("failed to finish version: %v", err)
+			}
+		}
+	}
+
+	history, err := m.KnowledgeBases.GetTrainingHistory(ctx, kb.ID)
+	if err != nil {
+		t.Fatalf("GetTrainingHistory() error = %v", err)
+	}
+	if len(history) != len(runs) {
+		t.Fatalf("GetTrainingHistory() returned %d runs, want %d", len(history), len(runs))
+	}
+	for i, run := range runs {
+		got := history[i]
+		if got.Status != run.status || !got.TrainingStartedAt.Equal(run.started) {
+			t.Errorf("run %d = %s started %v, want %s started %v", i, got.Status, got.TrainingStartedAt, run.status, run.started)
+		}
+		if run.duration == 0 {
+			if got.DurationSeconds != nil || got.TrainingCompletedAt != nil {
+				t.Errorf("run %d duration = %v, completed %v, want neither while in progress", i, got.DurationSeconds, got.TrainingCompletedAt)
+			}
+			continue
+		}
+		if got.DurationSeconds == nil || *got.DurationSeconds != run.duration.Seconds() {
+			t.Errorf("run %d duration = %v, want %v seconds", i, got.DurationSeconds, run.duration.Seconds())
+		}
+	}
+}
+
+func TestListOrganizationFiles(t *testing.T) {
+	m := testModels(t)
+	ctx := context.Background()
+
+	user := createTestUser(t, m)
+	org := createTestOrganization(t, m, user)
+	addFiles := func(orgID int64, kbName string, names ...string) *KnowledgeBase {
+		t.Helper()
+		kb, err := m.KnowledgeBases.Create(ctx, orgID, kbName, "", &user.ID)
+		if err != nil {
+			t.Fatalf("failed to create knowledge base: %v", err)
+		}
+		for _, name := range names {
+			if _, err := m.KnowledgeBases.AddFile(ctx, kb.ID, name, "uploads/"+name, 5, "text/plain", &user.ID, limits.ForPlan(limits.PlanEnterprise)); err != nil {
+				t.Fatalf("failed to add file: %v", err)
+			}
+		}
+		return kb
+	}
+	handbook := addFiles(org.ID, "Handbook", "intro.txt", "policies.pdf")
+	addFiles(org.ID, "Support", "faq.txt", "broken.pdf")
+	addFiles(createTestOrganization(t, m, user).ID, "Elsewhere", "other.txt")
+
+	if _, err := m.KnowledgeBases.DB.Exec(ctx, `UPDATE knowledge_base_files SET status = 'error' WHERE name = 'broken.pdf' AND knowledge_base_id IN (SELECT id FROM knowledge_bases WHERE organization_id = $1)`, org.ID); err != nil {
+		t.Fatalf("failed to set file status: %v", err)
+	}
+
+	tests := []struct {
+		name      string
+		filter    OrganizationFileFilter
+		wantNames []string
+	}{
+		{name: "all knowledge bases", wantNames: []string{"broken.pdf", "faq.txt", "policies.pdf", "intro.txt"}},
+		{name: "one knowledge base", filter: OrganizationFileFilter{KnowledgeBaseID: &handbook.ID}, wantNames: []string{"policies.pdf", "intro.txt"}},
+		{name: "by status", filter: OrganizationFileFilter{Status: "error"}, wantNames: []string{"broken.pdf"}},
+		{name: "by name", filter: OrganizationFileFilter{Query: "PDF"}, wantNames: []string{"broken.pdf", "policies.pdf"}},
+		{name: "status in one knowledge base", filter: OrganizationFileFilter{KnowledgeBaseID: &handbook.ID, Status: "error"}, wantNames: []string{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			files, err := m.KnowledgeBases.ListOrganizationFiles(ctx, org.ID, tt.filter, nil, 10)
+			if err != nil {
+				t.Fatalf("ListOrganizationFiles() error = %v", err)
+			}
+			names := make([]string, 0, len(files))
+			for _, file := range files {
+				names = append(names, file.Name)
+			}
+			if fmt.Sprint(names) != fmt.Sprint(tt.wantNames) {
+				t.Errorf("ListOrganizationFiles() = %v, want %v", names, tt.wantNames)
+			}
+		})
+	}
+
+	t.Run("includes the knowledge base and pages with a cursor", func(t *testing.T) {
+		first, err := m.KnowledgeBases.ListOrganizationFiles(ctx, org.ID, OrganizationFileFilter{KnowledgeBaseID: &handbook.ID}, nil, 1)
+		if err != nil {
+			t.Fatalf("ListOrganizationFiles() error = %v", err)
+		}
+		if len(first) != 1 || first[0].KnowledgeBaseName != "Handbook" || first[0].KnowledgeBaseID != handbook.ID {
+			t.Fatalf("first page = %+v, want one Handbook file", first)
+		}
+		after := &pagination.Cursor{Time: first[0].CreatedAt, ID: first[0].ID}
+		second, err := m.KnowledgeBases.ListOrganizationFiles(ctx, org.ID, OrganizationFileFilter{KnowledgeBaseID: &handbook.ID}, after, 10)
+		if err != nil {
+			t.Fatalf("ListOrganizationFiles() error = %v", err)
+		}
+		if len(second) != 1 || second[0].Name != "intro.txt" {
+			t.Errorf("second page = %+v, want intro.txt", second)
+		}
+	})
+}
+
+func TestGetOrgKBStats(t *testing.T) {
+	m := testModels(t)
+	ctx := context.Background()
+
+	user := createTestUser(t, m)
+	org := createTestOrganization(t, m, user)
+	newKB := func(orgID int64, name string, files ...string) *KnowledgeBase {
+		t.Helper()
+		kb, err := m.KnowledgeBases.Create(ctx, orgID, name, "", &user.ID)
+		if err != nil {
+			t.Fatalf("failed to create knowledge base: %v", err)
+		}
+		for _, file := range files {
+			if _, err := m.KnowledgeBases.AddFile(ctx, kb.ID, file, "uploads/"+file, 5, "text/plain", &user.ID, limits.ForPlan(limits.PlanEnterprise)); err != nil {
+				t.Fatalf("failed to add file: %v", err)
+			}
+		}
+		return kb
+	}
+	// newVersion creates a version with n embeddings, completed with the given quality score
+	// unless score is nil
+	newVersion := func(kb *KnowledgeBase, n int, score *float64) {
+		t.Helper()
+		version, err := m.KnowledgeBases.CreateVersion(ctx, kb.ID, nil, nil, nil)
+		if err != nil {
+			t.Fatalf("failed to create version: %v", err)
+		}
+		files, err := m.KnowledgeBases.GetFilesByKnowledgeBaseID(ctx, kb.ID)
+		if err != nil {
+			t.Fatalf("failed to get files: %v", err)
+		}
+		for i := 0; i < n; i++ {
+			if err := m.KnowledgeBases.StoreEmbedding(ctx, kb.ID, version.ID, files[0].ID, i, "chunk", make([]float32, 1536), nil, false); err != nil {
+				t.Fatalf("failed to store embedding: %v", err)
+			}
+		}
+		if score == nil {
+			return
+		}
+		now := time.Now()
+		if err := m.KnowledgeBases.UpdateVersionStatus(ctx, version.ID, "completed", &now); err != nil {
+			t.Fatalf("failed to complete version: %v", err)
+		}
+		if _, err := m.KnowledgeBases.DB.Exec(ctx, `UPDATE knowledge_base_versions SET quality_score = $1 WHERE id = $2`, *score, version.ID); err != nil {
+			t.Fatalf("failed to set quality score: %v", err)
+		}
+		if err := m.KnowledgeBases.UpdateStatus(ctx, kb.ID, "active"); err != nil {
+			t.Fatalf("failed to set status: %v", err)
+		}
+	}
+	score := func(v float64) *float64 { return &v }
+
+	handbook := newKB(org.ID, "Handbook", "intro.txt", "policies.txt")
+	newVersion(handbook, 3, score(0.6))
+	newVersion(handbook, 1, score(0.8))
+	support := newKB(org.ID, "Support", "faq.txt")
+	newVersion(support, 1, nil) // Still training, so its score does not count
+	broken := newKB(org.ID, "Broken")
+	if err := m.KnowledgeBases.UpdateStatus(ctx, broken.ID, "error"); err != nil {
+		t.Fatalf("failed to set status: %v", err)
+	}
+	elsewhere := newKB(createTestOrganization(t, m, user).ID, "Elsewhere", "other.txt")
+	newVersion(elsewhere, 2, score(0.1))
+
+	stats, err := m.KnowledgeBases.GetOrgKBStats(ctx, org.ID)
+	if err != nil {
+		t.Fatalf("GetOrgKBStats() error = %v", err)
+	}
+	if stats.KnowledgeBases != 3 || stats.Files != 3 || stats.Embeddings != 5 {
+		t.Errorf("totals = %d knowledge bases, %d files, %d embeddings, want 3, 3 and 5", stats.KnowledgeBases, stats.Files, stats.Embeddings)
+	}
+	wantByStatus := map[string]int64{"active": 1, "training": 1, "error": 1}
+	if fmt.Sprint(stats.ByStatus) != fmt.Sprint(wantByStatus) {
+		t.Errorf("by status = %v, want %v", stats.ByStatus, wantByStatus)
+	}
+	if stats.AverageQualityScore == nil || *stats.AverageQualityScore < 0.699 || *stats.AverageQualityScore > 0.701 {
+		t.Errorf("average quality score = %v, want 0.7 from the completed versions", stats.AverageQualityScore)
+	}
+
+	t.Run("organization without knowledge bases", func(t *testing.T) {
+		empty, err := m.KnowledgeBases.GetOrgKBStats(ctx, createTestOrganization(t, m, user).ID)
+		if err != nil {
+			t.Fatalf("GetOrgKBStats() error = %v", err)
+		}
+		wantByStatus := map[string]int64{"active": 0, "training": 0, "error": 0}
+		if empty.KnowledgeBases != 0 || fmt.Sprint(empty.ByStatus) != fmt.Sprint(wantByStatus) || empty.AverageQualityScore != nil {
+			t.Errorf("stats = %+v, want zero counts for every status and no average", empty)
+		}
+	})
+}
+
+func TestGetVersionQualityBreakdown(t *testing.T) {
+	m := testModels(t)
+	ctx := context.Background()
+
+	user := createTestUser(t, m)
+	org := createTestOrganization(t, m, user)
+	kb, err := m.KnowledgeBases.Create(ctx, org.ID, "Docs", "", &user.ID)
+	if err != nil {
+		t.Fatalf("failed to create knowledge base: %v", err)
+	}
+	addFile := func(name string) *KnowledgeBaseFile {
+		t.Helper()
+		file, err := m.KnowledgeBases.AddFile(ctx, kb.ID, name, "uploads/"+name, 5, "text/plain", &user.ID, limits.ForPlan(limits.PlanEnterprise))
+		if err != nil {
+			t.Fatalf("failed to add file: %v", err)
+		}
+		return file
+	}
+	big := addFile("big.txt")
+	small := addFile("small.txt")
+	addFile("empty.txt")
+
+	version, err := m.KnowledgeBases.CreateVersion(ctx, kb.ID, nil, nil, nil)
+	if err != nil {
+		t.Fatalf("failed to create version: %v", err)
+	}
+	// Uploaded after training started, so not part of the version
+	addFile("late.txt")
+
+	// One huge chunk skews the average well above the median
+	chunks := []struct {
+		file *KnowledgeBaseFile
+		size int
+	}{
+		{big, 100}, {big, 200}, {big, 300}, {big, 5000}, {small, 10},
+	}
+	for i, chunk := range chunks {
+		if err := m.KnowledgeBases.StoreEmbedding(ctx, kb.ID, version.ID, chunk.file.ID, i, strings.Repeat("x", chunk.size), make([]float32, 1536), nil, false); err != nil {
+			t.Fatalf("failed to store embedding: %v", err)
+		}
+	}
+
+	breakdown, err := m.KnowledgeBases.GetVersionQualityBreakdown(ctx, version.ID)
+	if err != nil {
+		t.Fatalf("GetVersionQualityBreakdown() error = %v", err)
+	}
+
+	near := func(got, want float64) bool { return got > want-0.01 && got < want+0.01 }
+	d := breakdown.ChunkSizes
+	if d.Chunks != 5 || d.Min != 10 || d.Max != 5000 || !near(d.Average, 1122) {
+		t.Errorf("chunk sizes = %d chunks from %d to %d averaging %v, want 5 from 10 to 5000 averaging 1122", d.Chunks, d.Min, d.Max, d.Average)
+	}
+	if !near(d.P25, 100) || !near(d.Median, 200) || !near(d.P75, 300) || !near(d.P90, 3120) || !near(d.P99, 4812) {
+		t.Errorf("percentiles = %+v, want p25 100, median 200, p75 300, p90 3120 and p99 4812", d)
+	}
+
+	wantFiles := []struct {
+		name       string
+		embeddings int64
+		total      int64
+	}{
+		{"big.txt", 4, 5600},
+		{"small.txt", 1, 10},
+		{"empty.txt", 0, 0},
+	}
+	if len(breakdown.Files) != len(wantFiles) {
+		t.Fatalf("breakdown has %d files, want %d", len(breakdown.Files), len(wantFiles))
+	}
+	for i, want := range wantFiles {
+		got := breakdown.Files[i]
+		if got.Name != want.name || got.Embeddings != want.embeddings || got.TotalChunkSize != want.total {
+			t.Errorf("file %d = %s with %d embeddings totalling %d, want %s with %d totalling %d",
+				i, got.Name, got.Embeddings, got.TotalChunkSize, want.name, want.embeddings, want.total)
+		}
+	}
+	if breakdown.Files[0].FileID != big.ID || !near(breakdown.Files[0].AverageChunkSize, 1400) {
+		t.Errorf("big.txt = %+v, want file %d averaging 1400", breakdown.Files[0], big.ID)
+	}
+
+	c := breakdown.Coverage
+	if c.Files != 3 || c.FilesEmbedded != 2 || c.FilesNotEmbedded != 1 || !near(c.CoveragePercent, 66.67) {
+		t.Errorf("coverage = %+v, want 2 of 3 files embedded", c)
+	}
+
+	t.Run("version without embeddings", func(t *testing.T) {
+		now := time.Now()
+		if err := m.KnowledgeBases.UpdateVersionStatus(ctx, version.ID, "completed", &now); err != nil {
+			t.Fatalf("failed to complete version: %v", err)
+		}
+		empty, err := m.KnowledgeBases.CreateVersion(ctx, kb.ID, nil, nil, nil)
+		if err != nil {
+			t.Fatalf("failed to create version: %v", err)
+		}
+		breakdown, err := m.KnowledgeBases.GetVersionQualityBreakdown(ctx, empty.ID)
+		if err != nil {
+			t.Fatalf("GetVersionQualityBreakdown() error = %v", err)
+		}
+		if breakdown.ChunkSizes.Chunks != 0 || breakdown.Coverage.Files != 4 || breakdown.Coverage.FilesEmbedded != 0 {
+			t.Errorf("breakdown = %+v, want no chunks and none of the 4 files embedded", breakdown)
+		}
+	})
+}
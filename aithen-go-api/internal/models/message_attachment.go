@@ -0,0 +1,161 @@
+package models
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/aithen/go-api/internal/db"
+	"github.com/aithen/go-api/internal/id"
+	"github.com/jackc/pgx/v5"
+)
+
+// MessageAttachment represents a knowledge base file attached to a chat message
+type MessageAttachment struct {
+	ID                  int64     `json:"-" db:"id"`
+	MessageID           int64     `json:"-" db:"message_id"`
+	KnowledgeBaseFileID int64     `json:"-" db:"knowledge_base_file_id"`
+	KnowledgeBaseID     int64     `json:"-" db:"knowledge_base_id"` // Joined from knowledge_base_files
+	FileName            string    `json:"file_name" db:"file_name"`
+	FileSize            int64     `json:"file_size" db:"file_size"`
+	MimeType            string    `json:"mime_type" db:"mime_type"`
+	CreatedAt           time.Time `json:"created_at" db:"created_at"`
+}
+
+// MarshalJSON custom marshaling to convert int64 IDs to strings
+func (a MessageAttachment) MarshalJSON() ([]byte, error) {
+	type Alias MessageAttachment
+	return json.Marshal(&struct {
+		ID                  string `json:"id"`
+		MessageID           string `json:"message_id"`
+		KnowledgeBaseFileID string `json:"knowledge_base_file_id"`
+		KnowledgeBaseID     string `json:"knowledge_base_id"`
+		*Alias
+	}{
+		ID:                  fmt.Sprintf("%d", a.ID),
+		MessageID:           fmt.Sprintf("%d", a.MessageID),
+		KnowledgeBaseFileID: fmt.Sprintf("%d", a.KnowledgeBaseFileID),
+		KnowledgeBaseID:     fmt.Sprintf("%d", a.KnowledgeBaseID),
+		Alias:               (*Alias)(&a),
+	})
+}
+
+// messageAttachmentColumns selects an attachment joined with its file's metadata
+const messageAttachmentColumns = `
+	a.id, a.message_id, a.knowledge_base_file_id, f.knowledge_base_id, f.name, f.file_size, COALESCE(f.mime_type, ''), a.created_at
+`
+
+// scanMessageAttachment scans a row selected with messageAttachmentColumns
+func scanMessageAttachment(row pgx.Row) (*MessageAttachment, error) {
+	var a MessageAttachment
+	err := row.Scan(&a.ID, &a.MessageID, &a.KnowledgeBaseFileID, &a.KnowledgeBaseID, &a.FileName, &a.FileSize, &a.MimeType, &a.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return &a, nil
+}
+
+// CountAccessibleFiles returns how many of the given knowledge base files exist and belong to an
+// organization where the user is an active member
+func (m *KnowledgeBaseModel) CountAccessibleFiles(ctx context.Context, userID int64, fileIDs []int64) (int, error) {
+	ctx, cancel := db.WithQueryTimeout(ctx)
+	defer cancel()
+
+	query := `
+		SELECT COUNT(DISTINCT f.id)
+		FROM knowledge_base_files f
+		JOIN knowledge_bases kb ON kb.id = f.knowledge_base_id
+		JOIN organization_members om ON om.organization_id = kb.organization_id
+		WHERE f.id = ANY($1) AND om.user_id = $2 AND om.status = 'active'
+	`
+	var count int
+	err := m.DB.QueryRow(ctx, query, fileIDs, userID).Scan(&count)
+	return count, err
+}
+
+// attachFiles links knowledge base files to a message within tx and returns the attachments
+func attachFiles(ctx context.Context, tx pgx.Tx, messageID int64, fileIDs []int64) ([]*MessageAttachment, error) {
+	attachments := make([]*MessageAttachment, 0, len(fileIDs))
+	for _, fileID := range fileIDs {
+		query := `
+			WITH a AS (
+				INSERT INTO message_attachments (id, message_id, knowledge_base_file_id, created_at)
+				VALUES ($1, $2, $3, NOW())
+				RETURNING id, message_id, knowledge_base_file_id, created_at
+			)
+			SELECT ` + messageAttachmentColumns + `
+			FROM a
+			JOIN knowledge_base_files f ON f.id = a.knowledge_base_file_id
+		`
+		attachment, err := scanMessageAttachment(tx.QueryRow(ctx, query, id.Generate(), messageID, fileID))
+		if err != nil {
+			return nil, fmt.Errorf("failed to attach file %d: %w", fileID, err)
+		}
+		attachments = append(attachments, attachment)
+	}
+	return attachments, nil
+}
+
+// GetAttachmentsForMessage returns the attachments of a single message
+func (m *ChatModel) GetAttachmentsForMessage(ctx context.Context, messageID int64) ([]*MessageAttachment, error) {
+	ctx, cancel := db.WithQueryTimeout(ctx)
+	defer cancel()
+
+	query := `
+		SELECT ` + messageAttachmentColumns + `
+		FROM message_attachments a
+		JOIN knowledge_base_files f ON f.id = a.knowledge_base_file_id
+		WHERE a.message_id = $1
+		ORDER BY a.created_at ASC, a.id ASC
+	`
+
+	rows, err := m.DB.Query(ctx, query, messageID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var attachments []*MessageAttachment
+	for rows.Next() {
+		attachment, err := scanMessageAttachment(rows)
+		if err != nil {
+			return nil, err
+		}
+		attachments = append(attachments, attachment)
+	}
+
+	return attachments, rows.Err()
+}
+
+// GetAttachmentsForChat returns the attachments of every message in a chat, keyed by message ID
+func (m *ChatModel) GetAttachmentsForChat(ctx context.Context, chatID int64) (map[int64][]*MessageAttachment, error) {
+	ctx, cancel := db.WithQueryTimeout(ctx)
+	defer cancel()
+
+	query := `
+		SELECT ` + messageAttachmentColumns + `
+		FROM message_attachments a
+		JOIN messages msg ON msg.id = a.message_id
+		JOIN knowledge_base_files f ON f.id = a.knowledge_base_file_id
+		WHERE msg.chat_id = $1
+		ORDER BY a.created_at ASC, a.id ASC
+	`
+
+	rows, err := m.DB.Query(ctx, query, chatID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	byMessage := make(map[int64][]*MessageAttachment)
+	for rows.Next() {
+		attachment, err := scanMessageAttachment(rows)
+		if err != nil {
+			return nil, err
+		}
+		byMessage[attachment.MessageID] = append(byMessage[attachment.MessageID], attachment)
+	}
+
+	return byMessage, rows.Err()
+}
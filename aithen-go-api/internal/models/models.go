@@ -10,6 +10,7 @@ type Models struct {
 	Chats         *ChatModel
 	Organizations  *OrganizationModel
 	KnowledgeBases *KnowledgeBaseModel
+	KBPermissions  *KBPermissionModel
 	// Add other models here as you create them
 	// Sessions *SessionModel
 	// Messages *MessageModel
@@ -22,6 +23,7 @@ func NewModels() *Models {
 		Chats:         NewChatModel(db.DB),
 		Organizations:  NewOrganizationModel(db.DB),
 		KnowledgeBases: NewKnowledgeBaseModel(db.DB),
+		KBPermissions:  NewKBPermissionModel(db.DB),
 		// Initialize other models here
 		// Sessions: NewSessionModel(db.DB),
 		// Messages: NewMessageModel(db.DB),
@@ -5,16 +5,25 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"log"
 	"strings"
+	"sync"
 	"time"
 
+	"github.com/aithen/go-api/internal/config"
+	"github.com/aithen/go-api/internal/db"
 	"github.com/aithen/go-api/internal/id"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
 	"github.com/jackc/pgx/v5/pgxpool"
 )
 
 var (
 	ErrOrganizationNotFound = errors.New("organization not found")
 	ErrSlugAlreadyExists    = errors.New("organization slug already exists")
+	ErrMemberNotFound       = errors.New("organization member not found")
+	ErrAlreadyMember        = errors.New("user is already a member of this organization")
+	ErrLastOwner            = errors.New("organization must keep at least one owner")
 )
 
 // Organization represents an organization in the database
@@ -110,6 +119,9 @@ func GenerateSlug(name string) string {
 
 // GenerateUniqueSlug generates a unique slug by checking the database and appending a number if needed
 func (m *OrganizationModel) GenerateUniqueSlug(ctx context.Context, baseSlug string) (string, error) {
+	ctx, cancel := db.WithQueryTimeout(ctx)
+	defer cancel()
+
 	slug := GenerateSlug(baseSlug)
 	originalSlug := slug
 	counter := 1
@@ -133,6 +145,9 @@ func (m *OrganizationModel) GenerateUniqueSlug(ctx context.Context, baseSlug str
 
 // Create creates a new organization
 func (m *OrganizationModel) Create(ctx context.Context, name, slug, description, logoURL, website, email, phone, address string) (*Organization, error) {
+	ctx, cancel := db.WithQueryTimeout(ctx)
+	defer cancel()
+
 	// Generate Snowflake ID
 	orgID := id.Generate()
 
@@ -160,6 +175,9 @@ func (m *OrganizationModel) Create(ctx context.Context, name, slug, description,
 
 // FindByID finds an organization by ID
 func (m *OrganizationModel) FindByID(ctx context.Context, id int64) (*Organization, error) {
+	ctx, cancel := db.WithQueryTimeout(ctx)
+	defer cancel()
+
 	query := `
 		SELECT id, name, slug, description, logo_url, website, email, phone, address, created_at, updated_at
 		FROM organizations
@@ -178,8 +196,66 @@ func (m *OrganizationModel) FindByID(ctx context.Context, id int64) (*Organizati
 	return &org, nil
 }
 
-// FindBySlug finds an organization by slug
+// slugCacheEntry is an organization cached by FindBySlug
+type slugCacheEntry struct {
+	org      Organization
+	cachedAt time.Time
+}
+
+var (
+	slugCache   = make(map[string]slugCacheEntry)
+	slugCacheMu sync.RWMutex
+)
+
+// slugCacheTTL returns how long FindBySlug caches organizations (ORG_SLUG_CACHE_TTL in seconds,
+// default 60, 0 disables the cache)
+func slugCacheTTL() time.Duration {
+	return time.Duration(config.GetEnvInt("ORG_SLUG_CACHE_TTL", 60)) * time.Second
+}
+
+// FindBySlug finds an organization by slug. Organizations found are cached in memory for
+// ORG_SLUG_CACHE_TTL, since most org-scoped requests look one up; missing slugs are not cached
+// so newly created organizations resolve immediately. Each call returns its own copy.
 func (m *OrganizationModel) FindBySlug(ctx context.Context, slug string) (*Organization, error) {
+	ttl := slugCacheTTL()
+	if ttl > 0 {
+		slugCacheMu.RLock()
+		entry, ok := slugCache[slug]
+		slugCacheMu.RUnlock()
+		if ok && time.Since(entry.cachedAt) < ttl {
+			org := entry.org
+			return &org, nil
+		}
+	}
+
+	org, err := m.findBySlug(ctx, slug)
+	if err != nil {
+		return nil, err
+	}
+
+	if ttl > 0 {
+		slugCacheMu.Lock()
+		slugCache[slug] = slugCacheEntry{org: *org, cachedAt: time.Now()}
+		slugCacheMu.Unlock()
+	}
+	return org, nil
+}
+
+// invalidateSlugCache drops slugs from FindBySlug's cache, so deleted organizations stop
+// resolving and a new organization taking one of the slugs is loaded fresh
+func invalidateSlugCache(slugs ...string) {
+	slugCacheMu.Lock()
+	defer slugCacheMu.Unlock()
+	for _, slug := range slugs {
+		delete(slugCache, slug)
+	}
+}
+
+// findBySlug loads an organization by slug from the database
+func (m *OrganizationModel) findBySlug(ctx context.Context, slug string) (*Organization, error) {
+	ctx, cancel := db.WithQueryTimeout(ctx)
+	defer cancel()
+
 	query := `
 		SELECT id, name, slug, description, logo_url, website, email, phone, address, created_at, updated_at
 		FROM organizations
@@ -198,8 +274,81 @@ func (m *OrganizationModel) FindBySlug(ctx context.Context, slug string) (*Organ
 	return &org, nil
 }
 
-// AddMember adds a user to an organization
+// IsValidMemberRole reports whether role is a valid organization member role
+func IsValidMemberRole(role string) bool {
+	return role == "owner" || joinableMemberRoles[role]
+}
+
+// Roles that may be given to members joining an organization (owner is only set explicitly)
+var joinableMemberRoles = map[string]bool{"admin": true, "member": true, "viewer": true}
+
+// IsJoinableMemberRole reports whether role may be an organization's default member role
+func IsJoinableMemberRole(role string) bool {
+	return joinableMemberRoles[role]
+}
+
+// DefaultMemberRole returns the server-wide role for members joining an organization
+// without its own default (DEFAULT_MEMBER_ROLE, default "member")
+func DefaultMemberRole() string {
+	role := config.GetEnv("DEFAULT_MEMBER_ROLE")
+	if role == "" {
+		return "member"
+	}
+	if !joinableMemberRoles[role] {
+		log.Printf("⚠️  Invalid DEFAULT_MEMBER_ROLE %q, using \"member\"", role)
+		return "member"
+	}
+	return role
+}
+
+// GetDefaultMemberRole returns the role given to members joining an organization:
+// its default_member_role, or DefaultMemberRole when none is set
+func (m *OrganizationModel) GetDefaultMemberRole(ctx context.Context, organizationID int64) (string, error) {
+	ctx, cancel := db.WithQueryTimeout(ctx)
+	defer cancel()
+
+	var role *string
+	err := m.DB.QueryRow(ctx, `SELECT default_member_role FROM organizations WHERE id = $1`, organizationID).Scan(&role)
+	if err != nil {
+		return "", ErrOrganizationNotFound
+	}
+	if role == nil {
+		return DefaultMemberRole(), nil
+	}
+	return *role, nil
+}
+
+// SetDefaultMemberRole sets the role given to members joining an organization; nil clears it,
+// so DefaultMemberRole applies
+func (m *OrganizationModel) SetDefaultMemberRole(ctx context.Context, organizationID int64, role *string) error {
+	ctx, cancel := db.WithQueryTimeout(ctx)
+	defer cancel()
+
+	query := `UPDATE organizations SET default_member_role = $1, updated_at = NOW() WHERE id = $2`
+	result, err := m.DB.Exec(ctx, query, role, organizationID)
+	if err != nil {
+		return fmt.Errorf("failed to set default member role: %w", err)
+	}
+	if result.RowsAffected() == 0 {
+		return ErrOrganizationNotFound
+	}
+	return nil
+}
+
+// AddMember adds a user to an organization. An empty role gives the organization's default
+// member role (see GetDefaultMemberRole), as used when members are added without a role.
 func (m *OrganizationModel) AddMember(ctx context.Context, organizationID, userID int64, role, status string) (*OrganizationMember, error) {
+	ctx, cancel := db.WithQueryTimeout(ctx)
+	defer cancel()
+
+	if role == "" {
+		defaultRole, err := m.GetDefaultMemberRole(ctx, organizationID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to add member: %w", err)
+		}
+		role = defaultRole
+	}
+
 	// Generate Snowflake ID
 	memberID := id.Generate()
 
@@ -215,6 +364,11 @@ func (m *OrganizationModel) AddMember(ctx context.Context, organizationID, userI
 	)
 
 	if err != nil {
+		// The (organization_id, user_id) unique constraint settles concurrent adds of the same user
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == "23505" {
+			return nil, ErrAlreadyMember
+		}
 		return nil, fmt.Errorf("failed to add member: %w", err)
 	}
 
@@ -223,6 +377,9 @@ func (m *OrganizationModel) AddMember(ctx context.Context, organizationID, userI
 
 // GetUserOrganizations gets all organizations a user belongs to
 func (m *OrganizationModel) GetUserOrganizations(ctx context.Context, userID int64) ([]*Organization, error) {
+	ctx, cancel := db.WithQueryTimeout(ctx)
+	defer cancel()
+
 	query := `
 		SELECT o.id, o.name, o.slug, o.description, o.logo_url, o.website, o.email, o.phone, o.address, o.created_at, o.updated_at
 		FROM organizations o
@@ -251,3 +408,311 @@ func (m *OrganizationModel) GetUserOrganizations(ctx context.Context, userID int
 
 	return orgs, rows.Err()
 }
+
+// UserMembership is an organization together with a user's membership in it
+type UserMembership struct {
+	Organization *Organization       `json:"organization"`
+	Membership   *OrganizationMember `json:"membership"`
+}
+
+// GetUserMemberships gets every organization a user belongs to with their role and status,
+// including pending and inactive memberships, active ones first
+func (m *OrganizationModel) GetUserMemberships(ctx context.Context, userID int64) ([]*UserMembership, error) {
+	ctx, cancel := db.WithQueryTimeout(ctx)
+	defer cancel()
+
+	query := `
+		SELECT o.id, o.name, o.slug, o.description, o.logo_url, o.website, o.email, o.phone, o.address, o.created_at, o.updated_at,
+		       om.id, om.organization_id, om.user_id, om.role, om.status, om.joined_at, om.created_at, om.updated_at
+		FROM organization_members om
+		INNER JOIN organizations o ON o.id = om.organization_id
+		WHERE om.user_id = $1
+		ORDER BY om.status = 'active' DESC, o.created_at DESC
+	`
+
+	rows, err := m.DB.Query(ctx, query, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	memberships := []*UserMembership{}
+	for rows.Next() {
+		var org Organization
+		var member OrganizationMember
+		err := rows.Scan(
+			&org.ID, &org.Name, &org.Slug, &org.Description, &org.LogoURL, &org.Website, &org.Email, &org.Phone, &org.Address, &org.CreatedAt, &org.UpdatedAt,
+			&member.ID, &member.OrganizationID, &member.UserID, &member.Role, &member.Status, &member.JoinedAt, &member.CreatedAt, &member.UpdatedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+		memberships = append(memberships, &UserMembership{Organization: &org, Membership: &member})
+	}
+
+	return memberships, rows.Err()
+}
+
+// GetMember gets a user's active membership in an organization
+func (m *OrganizationModel) GetMember(ctx context.Context, organizationID, userID int64) (*OrganizationMember, error) {
+	ctx, cancel := db.WithQueryTimeout(ctx)
+	defer cancel()
+
+	query := `
+		SELECT id, organization_id, user_id, role, status, joined_at, created_at, updated_at
+		FROM organization_members
+		WHERE organization_id = $1 AND user_id = $2 AND status = 'active'
+	`
+
+	var member OrganizationMember
+	err := m.DB.QueryRow(ctx, query, organizationID, userID).Scan(
+		&member.ID, &member.OrganizationID, &member.UserID, &member.Role, &member.Status, &member.JoinedAt, &member.CreatedAt, &member.UpdatedAt,
+	)
+
+	if err != nil {
+		return nil, ErrMemberNotFound
+	}
+
+	return &member, nil
+}
+
+// GetMembership gets a user's membership in an organization regardless of its status
+func (m *OrganizationModel) GetMembership(ctx context.Context, organizationID, userID int64) (*OrganizationMember, error) {
+	ctx, cancel := db.WithQueryTimeout(ctx)
+	defer cancel()
+
+	query := `
+		SELECT id, organization_id, user_id, role, status, joined_at, created_at, updated_at
+		FROM organization_members
+		WHERE organization_id = $1 AND user_id = $2
+	`
+
+	var member OrganizationMember
+	err := m.DB.QueryRow(ctx, query, organizationID, userID).Scan(
+		&member.ID, &member.OrganizationID, &member.UserID, &member.Role, &member.Status, &member.JoinedAt, &member.CreatedAt, &member.UpdatedAt,
+	)
+
+	if err != nil {
+		return nil, ErrMemberNotFound
+	}
+
+	return &member, nil
+}
+
+// GetPlan returns the subscription plan of an organization
+func (m *OrganizationModel) GetPlan(ctx context.Context, organizationID int64) (string, error) {
+	ctx, cancel := db.WithQueryTimeout(ctx)
+	defer cancel()
+
+	var plan string
+	err := m.DB.QueryRow(ctx, `SELECT plan FROM organizations WHERE id = $1`, organizationID).Scan(&plan)
+	if err != nil {
+		return "", ErrOrganizationNotFound
+	}
+	return plan, nil
+}
+
+// GetDefaultPersonality returns the personality used for chat requests by an organization's
+// members that do not name one, or nil when none is set
+func (m *OrganizationModel) GetDefaultPersonality(ctx context.Context, organizationID int64) (*string, error) {
+	ctx, cancel := db.WithQueryTimeout(ctx)
+	defer cancel()
+
+	var personalityID *string
+	err := m.DB.QueryRow(ctx, `SELECT default_personality_id FROM organizations WHERE id = $1`, organizationID).Scan(&personalityID)
+	if err != nil {
+		return nil, ErrOrganizationNotFound
+	}
+	return personalityID, nil
+}
+
+// SetDefaultPersonality sets an organization's default personality; nil clears it
+func (m *OrganizationModel) SetDefaultPersonality(ctx context.Context, organizationID int64, personalityID *string) error {
+	ctx, cancel := db.WithQueryTimeout(ctx)
+	defer cancel()
+
+	query := `UPDATE organizations SET default_personality_id = $1, updated_at = NOW() WHERE id = $2`
+	result, err := m.DB.Exec(ctx, query, personalityID, organizationID)
+	if err != nil {
+		return fmt.Errorf("failed to set default personality: %w", err)
+	}
+	if result.RowsAffected() == 0 {
+		return ErrOrganizationNotFound
+	}
+	return nil
+}
+
+// CountMembers returns the number of active members in an organization
+func (m *OrganizationModel) CountMembers(ctx context.Context, organizationID int64) (int64, error) {
+	ctx, cancel := db.WithQueryTimeout(ctx)
+	defer cancel()
+
+	query := `SELECT COUNT(*) FROM organization_members WHERE organization_id = $1 AND status = 'active'`
+	var count int64
+	err := m.DB.QueryRow(ctx, query, organizationID).Scan(&count)
+	return count, err
+}
+
+// UpdateMemberRole changes an active member's role. Demoting the organization's last active
+// owner returns ErrLastOwner; the owners are locked so concurrent demotions cannot both pass.
+func (m *OrganizationModel) UpdateMemberRole(ctx context.Context, organizationID, userID int64, role string) (*OrganizationMember, error) {
+	ctx, cancel := db.WithQueryTimeout(ctx)
+	defer cancel()
+
+	tx, err := m.DB.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	lastOwner, err := isLastOwner(ctx, tx, organizationID, userID)
+	if err != nil {
+		return nil, err
+	}
+	if lastOwner && role != "owner" {
+		return nil, ErrLastOwner
+	}
+
+	query := `
+		UPDATE organization_members SET role = $3, updated_at = NOW()
+		WHERE organization_id = $1 AND user_id = $2 AND status = 'active'
+		RETURNING id, organization_id, user_id, role, status, joined_at, created_at, updated_at
+	`
+
+	var member OrganizationMember
+	err = tx.QueryRow(ctx, query, organizationID, userID, role).Scan(
+		&member.ID, &member.OrganizationID, &member.UserID, &member.Role, &member.Status, &member.JoinedAt, &member.CreatedAt, &member.UpdatedAt,
+	)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrMemberNotFound
+		}
+		return nil, fmt.Errorf("failed to update member role: %w", err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return &member, nil
+}
+
+// isLastOwner reports whether userID is the only active owner of an organization. The owner
+// rows stay locked until tx ends, so concurrent demotions or departures cannot both pass.
+func isLastOwner(ctx context.Context, tx pgx.Tx, organizationID, userID int64) (bool, error) {
+	rows, err := tx.Query(ctx, `
+		SELECT user_id FROM organization_members
+		WHERE organization_id = $1 AND role = 'owner' AND status = 'active'
+		FOR UPDATE
+	`, organizationID)
+	if err != nil {
+		return false, err
+	}
+	defer rows.Close()
+
+	owners := 0
+	isOwner := false
+	for rows.Next() {
+		var ownerID int64
+		if err := rows.Scan(&ownerID); err != nil {
+			return false, err
+		}
+		owners++
+		isOwner = isOwner || ownerID == userID
+	}
+	if err := rows.Err(); err != nil {
+		return false, err
+	}
+
+	return isOwner && owners == 1, nil
+}
+
+// CountOwners returns the number of active owners of an organization
+func (m *OrganizationModel) CountOwners(ctx context.Context, organizationID int64) (int64, error) {
+	ctx, cancel := db.WithQueryTimeout(ctx)
+	defer cancel()
+
+	query := `SELECT COUNT(*) FROM organization_members WHERE organization_id = $1 AND role = 'owner' AND status = 'active'`
+	var count int64
+	err := m.DB.QueryRow(ctx, query, organizationID).Scan(&count)
+	return count, err
+}
+
+// RemoveMember removes a user's membership along with their explicit permissions on the
+// organization's knowledge bases. Removing the last active owner returns ErrLastOwner while other
+// active members remain. When the last owner is also the last active member, the organization is
+// deleted with the membership and returned, so its uploads and training runs can be cleaned up;
+// otherwise the returned organization is nil.
+func (m *OrganizationModel) RemoveMember(ctx context.Context, organizationID, userID int64) (*DeletedOrganization, error) {
+	ctx, cancel := db.WithQueryTimeout(ctx)
+	defer cancel()
+
+	tx, err := m.DB.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	lastOwner, err := isLastOwner(ctx, tx, organizationID, userID)
+	if err != nil {
+		return nil, err
+	}
+	if lastOwner {
+		var others int
+		err := tx.QueryRow(ctx, `
+			SELECT COUNT(*) FROM organization_members
+			WHERE organization_id = $1 AND user_id <> $2 AND status = 'active'
+		`, organizationID, userID).Scan(&others)
+		if err != nil {
+			return nil, fmt.Errorf("failed to count members: %w", err)
+		}
+		if others > 0 {
+			return nil, ErrLastOwner
+		}
+	}
+
+	result, err := tx.Exec(ctx, `DELETE FROM organization_members WHERE organization_id = $1 AND user_id = $2`, organizationID, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to remove member: %w", err)
+	}
+	if result.RowsAffected() == 0 {
+		return nil, ErrMemberNotFound
+	}
+
+	_, err = tx.Exec(ctx, `
+		DELETE FROM kb_permissions
+		WHERE user_id = $2 AND knowledge_base_id IN (SELECT id FROM knowledge_bases WHERE organization_id = $1)
+	`, organizationID, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to remove member permissions: %w", err)
+	}
+
+	// Nobody is left to manage the organization, so it goes with its last member (cascades
+	// knowledge bases and invited or suspended memberships)
+	var deleted *DeletedOrganization
+	if lastOwner {
+		deleted = &DeletedOrganization{ID: organizationID}
+		err := tx.QueryRow(ctx, `
+			SELECT o.slug, COALESCE(array_agg(kb.id) FILTER (WHERE kb.id IS NOT NULL), '{}')
+			FROM organizations o
+			LEFT JOIN knowledge_bases kb ON kb.organization_id = o.id
+			WHERE o.id = $1
+			GROUP BY o.slug
+		`, organizationID).Scan(&deleted.Slug, &deleted.KnowledgeBaseIDs)
+		if err != nil {
+			return nil, fmt.Errorf("failed to delete organization: %w", err)
+		}
+		if _, err := tx.Exec(ctx, `DELETE FROM organizations WHERE id = $1`, organizationID); err != nil {
+			return nil, fmt.Errorf("failed to delete organization: %w", err)
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	if deleted != nil {
+		invalidateSlugCache(deleted.Slug)
+	}
+	return deleted, nil
+}
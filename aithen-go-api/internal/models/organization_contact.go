@@ -0,0 +1,118 @@
+package models
+
+import (
+	"fmt"
+	"net/mail"
+	"net/url"
+	"strings"
+)
+
+// Bounds on the number of digits in an organization phone number (E.164 allows up to 15)
+const (
+	minPhoneDigits = 7
+	maxPhoneDigits = 15
+)
+
+// ContactFieldError reports an invalid organization contact field
+type ContactFieldError struct {
+	Field   string
+	Message string
+}
+
+func (e *ContactFieldError) Error() string {
+	return fmt.Sprintf("%s: %s", e.Field, e.Message)
+}
+
+// OrganizationContact holds the free-text contact fields of an organization
+type OrganizationContact struct {
+	Website string
+	Email   string
+	Phone   string
+}
+
+// Normalize validates and normalizes the contact fields in place. Empty fields are allowed.
+// Websites get an https:// scheme when none is given, emails are reduced to the bare address
+// and phone numbers keep only digits and common separators. A *ContactFieldError is returned
+// for input that is clearly invalid, leaving the fields unchanged.
+func (oc *OrganizationContact) Normalize() error {
+	website, err := normalizeWebsite(oc.Website)
+	if err != nil {
+		return err
+	}
+	email, err := normalizeEmail(oc.Email)
+	if err != nil {
+		return err
+	}
+	phone, err := normalizePhone(oc.Phone)
+	if err != nil {
+		return err
+	}
+
+	oc.Website, oc.Email, oc.Phone = website, email, phone
+	return nil
+}
+
+// normalizeWebsite ensures the website is an http(s) URL with a host
+func normalizeWebsite(website string) (string, error) {
+	website = strings.TrimSpace(website)
+	if website == "" {
+		return "", nil
+	}
+
+	if !strings.Contains(website, "://") {
+		website = "https://" + website
+	}
+
+	u, err := url.Parse(website)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" ||
+		!strings.Contains(u.Hostname(), ".") || strings.ContainsAny(website, " \t") {
+		return "", &ContactFieldError{Field: "website", Message: "must be a valid http or https URL"}
+	}
+
+	return u.String(), nil
+}
+
+// normalizeEmail checks the email is a single bare address
+func normalizeEmail(email string) (string, error) {
+	email = strings.TrimSpace(email)
+	if email == "" {
+		return "", nil
+	}
+
+	addr, err := mail.ParseAddress(email)
+	if err != nil || addr.Address != email || !strings.Contains(email[strings.LastIndex(email, "@")+1:], ".") {
+		return "", &ContactFieldError{Field: "email", Message: "must be a valid email address"}
+	}
+
+	return addr.Address, nil
+}
+
+// normalizePhone strips characters other than digits, spaces and + - ( ) . separators
+// and checks the remaining number has a plausible number of digits
+func normalizePhone(phone string) (string, error) {
+	phone = strings.TrimSpace(phone)
+	if phone == "" {
+		return "", nil
+	}
+
+	var b strings.Builder
+	digits := 0
+	for _, r := range phone {
+		switch {
+		case r >= '0' && r <= '9':
+			digits++
+			b.WriteRune(r)
+		case r == '+' && b.Len() == 0:
+			b.WriteRune(r)
+		case r == ' ' || r == '-' || r == '(' || r == ')' || r == '.':
+			b.WriteRune(r)
+		}
+	}
+
+	if digits < minPhoneDigits || digits > maxPhoneDigits {
+		return "", &ContactFieldError{Field: "phone", Message: fmt.Sprintf("must contain between %d and %d digits", minPhoneDigits, maxPhoneDigits)}
+	}
+
+	// Collapse runs of spaces left behind by stripped characters
+	return strings.Join(strings.Fields(b.String()), " "), nil
+}
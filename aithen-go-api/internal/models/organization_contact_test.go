@@ -0,0 +1,58 @@
+package models
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestOrganizationContactNormalize(t *testing.T) {
+	tests := []struct {
+		name      string
+		contact   OrganizationContact
+		want      OrganizationContact
+		wantField string
+	}{
+		{name: "all empty", contact: OrganizationContact{}, want: OrganizationContact{}},
+		{name: "whitespace only is empty", contact: OrganizationContact{Website: "  ", Email: " ", Phone: "\t"}, want: OrganizationContact{}},
+
+		{name: "website gets a scheme", contact: OrganizationContact{Website: "example.com"}, want: OrganizationContact{Website: "https://example.com"}},
+		{name: "website keeps http", contact: OrganizationContact{Website: " http://example.com/about "}, want: OrganizationContact{Website: "http://example.com/about"}},
+		{name: "website with another scheme", contact: OrganizationContact{Website: "ftp://example.com"}, wantField: "website"},
+		{name: "website without a dot", contact: OrganizationContact{Website: "localhost"}, wantField: "website"},
+		{name: "website with spaces", contact: OrganizationContact{Website: "exa mple.com"}, wantField: "website"},
+
+		{name: "email", contact: OrganizationContact{Email: " hello@example.com "}, want: OrganizationContact{Email: "hello@example.com"}},
+		{name: "email without a domain dot", contact: OrganizationContact{Email: "hello@example"}, wantField: "email"},
+		{name: "email with a display name", contact: OrganizationContact{Email: "Jane <jane@example.com>"}, wantField: "email"},
+		{name: "email without an at sign", contact: OrganizationContact{Email: "example.com"}, wantField: "email"},
+
+		{name: "phone keeps separators", contact: OrganizationContact{Phone: "+1 (555) 123-4567"}, want: OrganizationContact{Phone: "+1 (555) 123-4567"}},
+		{name: "phone strips other characters", contact: OrganizationContact{Phone: "tel: 555.123.4567 ext"}, want: OrganizationContact{Phone: "555.123.4567"}},
+		{name: "phone plus only leading", contact: OrganizationContact{Phone: "555+1234567"}, want: OrganizationContact{Phone: "5551234567"}},
+		{name: "phone with too few digits", contact: OrganizationContact{Phone: "123-45"}, wantField: "phone"},
+		{name: "phone with too many digits", contact: OrganizationContact{Phone: "1234567890123456"}, wantField: "phone"},
+		{name: "phone without digits", contact: OrganizationContact{Phone: "call us"}, wantField: "phone"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			contact := tt.contact
+			err := contact.Normalize()
+			if tt.wantField != "" {
+				var fieldErr *ContactFieldError
+				if !errors.As(err, &fieldErr) || fieldErr.Field != tt.wantField {
+					t.Fatalf("Normalize() error = %v, want a %s error", err, tt.wantField)
+				}
+				if contact != tt.contact {
+					t.Errorf("Normalize() changed the fields to %+v on error", contact)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Normalize() error = %v", err)
+			}
+			if contact != tt.want {
+				t.Errorf("Normalize() = %+v, want %+v", contact, tt.want)
+			}
+		})
+	}
+}
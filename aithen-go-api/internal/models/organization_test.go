@@ -0,0 +1,276 @@
+package models
+
+import (
+	"context"
+	"sync"
+	"testing"
+)
+
+func TestAddMemberConcurrentAddsOneMembership(t *testing.T) {
+	m := testModels(t)
+	owner := createTestUser(t, m)
+	org := createTestOrganization(t, m, owner)
+	user := createTestUser(t, m)
+
+	const attempts = 10
+	errs := make(chan error, attempts)
+	var wg sync.WaitGroup
+	for i := 0; i < attempts; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := m.Organizations.AddMember(context.Background(), org.ID, user.ID, "member", "active")
+			errs <- err
+		}()
+	}
+	wg.Wait()
+	close(errs)
+
+	added := 0
+	for err := range errs {
+		switch err {
+		case nil:
+			added++
+		case ErrAlreadyMember:
+		default:
+			t.Fatalf("AddMember() error = %v, want nil or ErrAlreadyMember", err)
+		}
+	}
+	if added != 1 {
+		t.Fatalf("AddMember() succeeded %d times, want 1", added)
+	}
+}
+
+func TestDefaultMemberRole(t *testing.T) {
+	tests := []struct {
+		name string
+		env  string
+		want string
+	}{
+		{name: "unset", env: "", want: "member"},
+		{name: "viewer", env: "viewer", want: "viewer"},
+		{name: "admin", env: "admin", want: "admin"},
+		{name: "owner is not joinable", env: "owner", want: "member"},
+		{name: "unknown role", env: "superuser", want: "member"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("DEFAULT_MEMBER_ROLE", tt.env)
+			if got := DefaultMemberRole(); got != tt.want {
+				t.Errorf("DefaultMemberRole() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAddMemberWithoutRoleUsesDefaultMemberRole(t *testing.T) {
+	m := testModels(t)
+	t.Setenv("DEFAULT_MEMBER_ROLE", "member")
+	ctx := context.Background()
+	owner := createTestUser(t, m)
+	org := createTestOrganization(t, m, owner)
+
+	viewer := "viewer"
+	tests := []struct {
+		name       string
+		orgDefault *string
+		role       string
+		wantRole   string
+	}{
+		{name: "server default", orgDefault: nil, role: "", wantRole: "member"},
+		{name: "organization default", orgDefault: &viewer, role: "", wantRole: "viewer"},
+		{name: "explicit role", orgDefault: &viewer, role: "admin", wantRole: "admin"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := m.Organizations.SetDefaultMemberRole(ctx, org.ID, tt.orgDefault); err != nil {
+				t.Fatalf("SetDefaultMemberRole() error = %v", err)
+			}
+			user := createTestUser(t, m)
+			member, err := m.Organizations.AddMember(ctx, org.ID, user.ID, tt.role, "active")
+			if err != nil {
+				t.Fatalf("AddMember() error = %v", err)
+			}
+			if member.Role != tt.wantRole {
+				t.Errorf("AddMember() role = %q, want %q", member.Role, tt.wantRole)
+			}
+		})
+	}
+}
+
+func TestFindBySlugForgetsDeletedOrganizations(t *testing.T) {
+	m := testModels(t)
+	t.Setenv("ORG_SLUG_CACHE_TTL", "3600")
+	ctx := context.Background()
+	user := createTestUser(t, m)
+	org := createTestOrganization(t, m, user)
+
+	// Cache the organization, then delete it with its only member
+	if _, err := m.Organizations.FindBySlug(ctx, org.Slug); err != nil {
+		t.Fatalf("FindBySlug() error = %v", err)
+	}
+	if _, err := m.Users.DeleteAccount(ctx, user.ID); err != nil {
+		t.Fatalf("DeleteAccount() error = %v", err)
+	}
+
+	if _, err := m.Organizations.FindBySlug(ctx, org.Slug); err != ErrOrganizationNotFound {
+		t.Fatalf("FindBySlug() after delete error = %v, want ErrOrganizationNotFound", err)
+	}
+}
+
+func TestUpdateMemberRoleLastOwner(t *testing.T) {
+	m := testModels(t)
+	ctx := context.Background()
+
+	tests := []struct {
+		name       string
+		extraOwner bool
+		wantErr    error
+		wantOwners int64
+	}{
+		{name: "last owner cannot be demoted", extraOwner: false, wantErr: ErrLastOwner, wantOwners: 1},
+		{name: "owner with a co-owner can be demoted", extraOwner: true, wantErr: nil, wantOwners: 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Created first so it is deleted last, once it is the organization's only member
+			coOwner := createTestUser(t, m)
+			owner := createTestUser(t, m)
+			org := createTestOrganization(t, m, owner)
+			if tt.extraOwner {
+				if _, err := m.Organizations.AddMember(ctx, org.ID, coOwner.ID, "owner", "active"); err != nil {
+					t.Fatalf("failed to add co-owner: %v", err)
+				}
+			}
+
+			_, err := m.Organizations.UpdateMemberRole(ctx, org.ID, owner.ID, "member")
+			if err != tt.wantErr {
+				t.Fatalf("UpdateMemberRole() error = %v, want %v", err, tt.wantErr)
+			}
+			owners, err := m.Organizations.CountOwners(ctx, org.ID)
+			if err != nil {
+				t.Fatalf("CountOwners() error = %v", err)
+			}
+			if owners != tt.wantOwners {
+				t.Errorf("CountOwners() = %d, want %d", owners, tt.wantOwners)
+			}
+		})
+	}
+}
+
+func TestRemoveMember(t *testing.T) {
+	m := testModels(t)
+	ctx := context.Background()
+
+	tests := []struct {
+		name        string
+		role        string   // role of the member leaving; "" for the organization's only owner
+		others      []string // statuses of further members with the member role
+		wantErr     error
+		wantDeleted bool
+	}{
+		{name: "member leaves", role: "member"},
+		{name: "co-owner leaves", role: "owner"},
+		{name: "sole owner is blocked while active members remain", role: "", others: []string{"active"}, wantErr: ErrLastOwner},
+		{name: "solo owner leaves and the organization is deleted", role: "", wantDeleted: true},
+		{name: "invited and suspended members do not block the solo owner", role: "", others: []string{"invited", "suspended"}, wantDeleted: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			owner := createTestUser(t, m)
+			org := createTestOrganization(t, m, owner)
+			for _, status := range tt.others {
+				other := createTestUser(t, m)
+				if _, err := m.Organizations.AddMember(ctx, org.ID, other.ID, "member", status); err != nil {
+					t.Fatalf("failed to add %s member: %v", status, err)
+				}
+			}
+
+			leaving := owner
+			if tt.role != "" {
+				leaving = createTestUser(t, m)
+				if _, err := m.Organizations.AddMember(ctx, org.ID, leaving.ID, tt.role, "active"); err != nil {
+					t.Fatalf("failed to add member: %v", err)
+				}
+			}
+
+			deleted, err := m.Organizations.RemoveMember(ctx, org.ID, leaving.ID)
+			if err != tt.wantErr {
+				t.Fatalf("RemoveMember() error = %v, want %v", err, tt.wantErr)
+			}
+			if (deleted != nil) != tt.wantDeleted {
+				t.Fatalf("RemoveMember() deleted = %+v, want deleted %v", deleted, tt.wantDeleted)
+			}
+			if deleted != nil && (deleted.ID != org.ID || deleted.Slug != org.Slug) {
+				t.Errorf("RemoveMember() deleted = %+v, want organization %d", deleted, org.ID)
+			}
+			_, err = m.Organizations.GetMembership(ctx, org.ID, leaving.ID)
+			if stillMember := err == nil; stillMember != (tt.wantErr != nil) {
+				t.Errorf("membership after RemoveMember() present = %v, want %v", stillMember, tt.wantErr != nil)
+			}
+			_, err = m.Organizations.FindBySlug(ctx, org.Slug)
+			if gone := err == ErrOrganizationNotFound; gone != tt.wantDeleted {
+				t.Errorf("organization gone after RemoveMember() = %v, want %v", gone, tt.wantDeleted)
+			}
+		})
+	}
+}
+
+func TestGetUserMemberships(t *testing.T) {
+	m := testModels(t)
+	ctx := context.Background()
+
+	user := createTestUser(t, m)
+	other := createTestUser(t, m)
+
+	active := createTestOrganization(t, m, user)
+	invitedTo := createTestOrganization(t, m, other)
+	if _, err := m.Organizations.AddMember(ctx, invitedTo.ID, user.ID, "member", "invited"); err != nil {
+		t.Fatalf("failed to invite user: %v", err)
+	}
+	suspendedFrom := createTestOrganization(t, m, other)
+	if _, err := m.Organizations.AddMember(ctx, suspendedFrom.ID, user.ID, "viewer", "suspended"); err != nil {
+		t.Fatalf("failed to add suspended user: %v", err)
+	}
+	// An organization the user does not belong to is not listed
+	createTestOrganization(t, m, other)
+
+	memberships, err := m.Organizations.GetUserMemberships(ctx, user.ID)
+	if err != nil {
+		t.Fatalf("GetUserMemberships() error = %v", err)
+	}
+	if len(memberships) != 3 {
+		t.Fatalf("GetUserMemberships() returned %d memberships, want 3", len(memberships))
+	}
+
+	// Active memberships come first
+	if first := memberships[0]; first.Organization.ID != active.ID || first.Membership.Status != "active" || first.Membership.Role != "owner" {
+		t.Errorf("first membership = %s %s in %d, want the active owner membership in %d",
+			first.Membership.Status, first.Membership.Role, first.Organization.ID, active.ID)
+	}
+
+	want := map[int64][2]string{
+		active.ID:        {"owner", "active"},
+		invitedTo.ID:     {"member", "invited"},
+		suspendedFrom.ID: {"viewer", "suspended"},
+	}
+	for _, membership := range memberships {
+		w, ok := want[membership.Organization.ID]
+		if !ok {
+			t.Errorf("unexpected membership in organization %d", membership.Organization.ID)
+			continue
+		}
+		if membership.Membership.Role != w[0] || membership.Membership.Status != w[1] {
+			t.Errorf("membership in %d = %s/%s, want %s/%s", membership.Organization.ID,
+				membership.Membership.Role, membership.Membership.Status, w[0], w[1])
+		}
+		if membership.Membership.OrganizationID != membership.Organization.ID || membership.Membership.UserID != user.ID {
+			t.Errorf("membership %+v does not match its organization %d and user %d", membership.Membership, membership.Organization.ID, user.ID)
+		}
+	}
+
+	none, err := m.Organizations.GetUserMemberships(ctx, createTestUser(t, m).ID)
+	if err != nil || none == nil || len(none) != 0 {
+		t.Errorf("GetUserMemberships() for a user without organizations = %v, %v, want an empty list", none, err)
+	}
+}
@@ -5,16 +5,20 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"strings"
 	"time"
 
+	"github.com/aithen/go-api/internal/db"
 	"github.com/aithen/go-api/internal/id"
+	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
 	"golang.org/x/crypto/bcrypt"
 )
 
 var (
-	ErrInvalidCredentials = errors.New("invalid email or password")
-	ErrUserNotFound       = errors.New("user not found")
+	ErrInvalidCredentials    = errors.New("invalid email or password")
+	ErrUserNotFound          = errors.New("user not found")
+	ErrSoleOrganizationOwner = errors.New("user is the sole owner of an organization with other members")
 )
 
 // User represents a user in the database
@@ -25,6 +29,9 @@ type User struct {
 	Password  string    `json:"-" db:"password"` // Hidden from JSON
 	CreatedAt time.Time `json:"created_at" db:"created_at"`
 	UpdatedAt time.Time `json:"updated_at" db:"updated_at"`
+	// Loaded by FindByID and only serialized through UserSelfView
+	LastLoginAt *time.Time `json:"-" db:"last_login_at"`
+	LastLoginIP *string    `json:"-" db:"last_login_ip"`
 }
 
 // MarshalJSON custom marshaling to convert int64 ID to string
@@ -39,6 +46,26 @@ func (u User) MarshalJSON() ([]byte, error) {
 	})
 }
 
+// UserSelfView is the JSON view of a user's own account. It adds login details that are
+// hidden from other users.
+type UserSelfView User
+
+// MarshalJSON custom marshaling to convert int64 ID to string and include login details
+func (u UserSelfView) MarshalJSON() ([]byte, error) {
+	type Alias User
+	return json.Marshal(&struct {
+		ID string `json:"id"`
+		*Alias
+		LastLoginAt *time.Time `json:"last_login_at"`
+		LastLoginIP *string    `json:"last_login_ip"`
+	}{
+		ID:          fmt.Sprintf("%d", u.ID),
+		Alias:       (*Alias)(&u),
+		LastLoginAt: u.LastLoginAt,
+		LastLoginIP: u.LastLoginIP,
+	})
+}
+
 // UserModel handles database operations for users
 type UserModel struct {
 	DB *pgxpool.Pool
@@ -51,6 +78,9 @@ func NewUserModel(db *pgxpool.Pool) *UserModel {
 
 // Create creates a new user with hashed password
 func (m *UserModel) Create(ctx context.Context, email, name, password string) (*User, error) {
+	ctx, cancel := db.WithQueryTimeout(ctx)
+	defer cancel()
+
 	// Hash password
 	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
 	if err != nil {
@@ -80,6 +110,9 @@ func (m *UserModel) Create(ctx context.Context, email, name, password string) (*
 
 // Authenticate verifies user credentials and returns the user
 func (m *UserModel) Authenticate(ctx context.Context, email, password string) (*User, error) {
+	ctx, cancel := db.WithQueryTimeout(ctx)
+	defer cancel()
+
 	query := `
 		SELECT id, email, name, password, created_at, updated_at
 		FROM users
@@ -106,17 +139,29 @@ func (m *UserModel) Authenticate(ctx context.Context, email, password string) (*
 	return &user, nil
 }
 
+// RecordLogin stores the time and client IP address of a successful login
+func (m *UserModel) RecordLogin(ctx context.Context, userID int64, ip string) error {
+	ctx, cancel := db.WithQueryTimeout(ctx)
+	defer cancel()
+
+	_, err := m.DB.Exec(ctx, `UPDATE users SET last_login_at = NOW(), last_login_ip = $1 WHERE id = $2`, ip, userID)
+	return err
+}
+
 // FindByID finds a user by ID
 func (m *UserModel) FindByID(ctx context.Context, id int64) (*User, error) {
+	ctx, cancel := db.WithQueryTimeout(ctx)
+	defer cancel()
+
 	query := `
-		SELECT id, email, name, created_at, updated_at
+		SELECT id, email, name, created_at, updated_at, last_login_at, last_login_ip
 		FROM users
 		WHERE id = $1
 	`
 
 	var user User
 	err := m.DB.QueryRow(ctx, query, id).Scan(
-		&user.ID, &user.Email, &user.Name, &user.CreatedAt, &user.UpdatedAt,
+		&user.ID, &user.Email, &user.Name, &user.CreatedAt, &user.UpdatedAt, &user.LastLoginAt, &user.LastLoginIP,
 	)
 
 	if err != nil {
@@ -128,6 +173,9 @@ func (m *UserModel) FindByID(ctx context.Context, id int64) (*User, error) {
 
 // FindByEmail finds a user by email (without password)
 func (m *UserModel) FindByEmail(ctx context.Context, email string) (*User, error) {
+	ctx, cancel := db.WithQueryTimeout(ctx)
+	defer cancel()
+
 	query := `
 		SELECT id, email, name, created_at, updated_at
 		FROM users
@@ -148,6 +196,9 @@ func (m *UserModel) FindByEmail(ctx context.Context, email string) (*User, error
 
 // Update updates a user
 func (m *UserModel) Update(ctx context.Context, id int64, email, name string) (*User, error) {
+	ctx, cancel := db.WithQueryTimeout(ctx)
+	defer cancel()
+
 	query := `
 		UPDATE users
 		SET email = $1, name = $2, updated_at = NOW()
@@ -169,13 +220,164 @@ func (m *UserModel) Update(ctx context.Context, id int64, email, name string) (*
 
 // Delete deletes a user by ID
 func (m *UserModel) Delete(ctx context.Context, id int64) error {
+	ctx, cancel := db.WithQueryTimeout(ctx)
+	defer cancel()
+
 	query := `DELETE FROM users WHERE id = $1`
 	_, err := m.DB.Exec(ctx, query, id)
 	return err
 }
 
+// DeletedOrganization is an organization deleted with its last member's account, with the
+// knowledge bases deleted along with it
+type DeletedOrganization struct {
+	ID               int64
+	Slug             string
+	KnowledgeBaseIDs []int64
+}
+
+// DeleteAccount deletes a user together with their chats and organization memberships.
+// Organizations where the user is the only active member are deleted as well and returned, so
+// their uploads and training runs can be cleaned up; invited and suspended memberships go with
+// them. Deletion is refused with ErrSoleOrganizationOwner if the user is the only active owner
+// of an organization that still has other active members, so ownership must be transferred first.
+func (m *UserModel) DeleteAccount(ctx context.Context, userID int64) ([]DeletedOrganization, error) {
+	ctx, cancel := db.WithQueryTimeout(ctx)
+	defer cancel()
+
+	tx, err := m.DB.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	// Lock the memberships of every organization the user belongs to, so other members cannot
+	// leave or be demoted between the ownership check and the deletes (see isLastOwner)
+	lockQuery := `
+		SELECT id FROM organization_members
+		WHERE organization_id IN (SELECT organization_id FROM organization_members WHERE user_id = $1)
+		FOR UPDATE
+	`
+	if _, err := tx.Exec(ctx, lockQuery, userID); err != nil {
+		return nil, fmt.Errorf("failed to lock organization memberships: %w", err)
+	}
+
+	// Block deletion if the user is the sole owner of an organization with other members.
+	// Invited and suspended members count neither as owners nor as members left behind.
+	soleOwnerQuery := `
+		SELECT COUNT(*)
+		FROM organization_members om
+		WHERE om.user_id = $1
+		  AND om.role = 'owner'
+		  AND om.status = 'active'
+		  AND NOT EXISTS (
+			SELECT 1 FROM organization_members other
+			WHERE other.organization_id = om.organization_id
+			  AND other.user_id <> om.user_id
+			  AND other.role = 'owner'
+			  AND other.status = 'active'
+		  )
+		  AND EXISTS (
+			SELECT 1 FROM organization_members other
+			WHERE other.organization_id = om.organization_id
+			  AND other.user_id <> om.user_id
+			  AND other.status = 'active'
+		  )
+	`
+	var blocked int
+	if err := tx.QueryRow(ctx, soleOwnerQuery, userID).Scan(&blocked); err != nil {
+		return nil, fmt.Errorf("failed to check organization ownership: %w", err)
+	}
+	if blocked > 0 {
+		return nil, ErrSoleOrganizationOwner
+	}
+
+	// Delete organizations where the user is the only active member (cascades knowledge bases)
+	deleted, err := collectSoleMemberOrganizations(ctx, tx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to delete organizations: %w", err)
+	}
+	if len(deleted) > 0 {
+		orgIDs := make([]int64, len(deleted))
+		for i, org := range deleted {
+			orgIDs[i] = org.ID
+		}
+		if _, err := tx.Exec(ctx, `DELETE FROM organizations WHERE id = ANY($1)`, orgIDs); err != nil {
+			return nil, fmt.Errorf("failed to delete organizations: %w", err)
+		}
+	}
+
+	// Delete remaining memberships
+	if _, err := tx.Exec(ctx, `DELETE FROM organization_members WHERE user_id = $1`, userID); err != nil {
+		return nil, fmt.Errorf("failed to delete organization memberships: %w", err)
+	}
+
+	// Delete chats and their messages
+	if _, err := tx.Exec(ctx, `DELETE FROM messages WHERE chat_id IN (SELECT id FROM chats WHERE user_id = $1)`, userID); err != nil {
+		return nil, fmt.Errorf("failed to delete messages: %w", err)
+	}
+	if _, err := tx.Exec(ctx, `DELETE FROM chats WHERE user_id = $1`, userID); err != nil {
+		return nil, fmt.Errorf("failed to delete chats: %w", err)
+	}
+
+	// Delete the user row
+	tag, err := tx.Exec(ctx, `DELETE FROM users WHERE id = $1`, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to delete user: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return nil, ErrUserNotFound
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, err
+	}
+
+	// Deleted organizations must not keep resolving from FindBySlug's cache
+	for _, org := range deleted {
+		invalidateSlugCache(org.Slug)
+	}
+	return deleted, nil
+}
+
+// collectSoleMemberOrganizations returns the organizations whose only active member is the
+// user, with their knowledge bases
+func collectSoleMemberOrganizations(ctx context.Context, tx pgx.Tx, userID int64) ([]DeletedOrganization, error) {
+	query := `
+		SELECT o.id, o.slug, COALESCE(array_agg(kb.id) FILTER (WHERE kb.id IS NOT NULL), '{}')
+		FROM organizations o
+		LEFT JOIN knowledge_bases kb ON kb.organization_id = o.id
+		WHERE o.id IN (
+			SELECT om.organization_id FROM organization_members om WHERE om.user_id = $1 AND om.status = 'active'
+		)
+		AND NOT EXISTS (
+			SELECT 1 FROM organization_members other
+			WHERE other.organization_id = o.id AND other.user_id <> $1 AND other.status = 'active'
+		)
+		GROUP BY o.id, o.slug
+	`
+	rows, err := tx.Query(ctx, query, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var orgs []DeletedOrganization
+	for rows.Next() {
+		var org DeletedOrganization
+		if err := rows.Scan(&org.ID, &org.Slug, &org.KnowledgeBaseIDs); err != nil {
+			return nil, err
+		}
+		orgs = append(orgs, org)
+	}
+	return orgs, rows.Err()
+}
+
 // All retrieves all users
 func (m *UserModel) All(ctx context.Context) ([]*User, error) {
+	ctx, cancel := db.WithQueryTimeout(ctx)
+	defer cancel()
+
 	query := `
 		SELECT id, email, name, created_at, updated_at
 		FROM users
@@ -200,3 +402,72 @@ func (m *UserModel) All(ctx context.Context) ([]*User, error) {
 
 	return users, rows.Err()
 }
+
+// UserListOptions controls filtering, ordering and pagination for List
+type UserListOptions struct {
+	Limit  int
+	Offset int
+	Search string // Matches name or email (case-insensitive)
+	Sort   string // created_at, -created_at, name, -name, email, -email
+}
+
+// userSortColumns maps allowed sort keys to ORDER BY clauses
+var userSortColumns = map[string]string{
+	"created_at":  "created_at ASC",
+	"-created_at": "created_at DESC",
+	"name":        "name ASC",
+	"-name":       "name DESC",
+	"email":       "email ASC",
+	"-email":      "email DESC",
+}
+
+// List retrieves a page of users matching the options along with the total match count
+func (m *UserModel) List(ctx context.Context, opts UserListOptions) ([]*User, int, error) {
+	ctx, cancel := db.WithQueryTimeout(ctx)
+	defer cancel()
+
+	orderBy, ok := userSortColumns[opts.Sort]
+	if !ok {
+		orderBy = userSortColumns["-created_at"]
+	}
+
+	where := ""
+	args := []interface{}{}
+	if search := strings.TrimSpace(opts.Search); search != "" {
+		args = append(args, "%"+search+"%")
+		where = "WHERE name ILIKE $1 OR email ILIKE $1"
+	}
+
+	var total int
+	countQuery := fmt.Sprintf(`SELECT COUNT(*) FROM users %s`, where)
+	if err := m.DB.QueryRow(ctx, countQuery, args...).Scan(&total); err != nil {
+		return nil, 0, err
+	}
+
+	args = append(args, opts.Limit, opts.Offset)
+	query := fmt.Sprintf(`
+		SELECT id, email, name, created_at, updated_at
+		FROM users
+		%s
+		ORDER BY %s, id DESC
+		LIMIT $%d OFFSET $%d
+	`, where, orderBy, len(args)-1, len(args))
+
+	rows, err := m.DB.Query(ctx, query, args...)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	users := make([]*User, 0)
+	for rows.Next() {
+		var user User
+		err := rows.Scan(&user.ID, &user.Email, &user.Name, &user.CreatedAt, &user.UpdatedAt)
+		if err != nil {
+			return nil, 0, err
+		}
+		users = append(users, &user)
+	}
+
+	return users, total, rows.Err()
+}
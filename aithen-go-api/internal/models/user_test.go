@@ -0,0 +1,205 @@
+package models
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aithen/go-api/internal/id"
+)
+
+func TestDeleteAccount(t *testing.T) {
+	m := testModels(t)
+	ctx := context.Background()
+
+	t.Run("returns sole member organizations with their knowledge bases", func(t *testing.T) {
+		user := createTestUser(t, m)
+		org := createTestOrganization(t, m, user)
+		kb, err := m.KnowledgeBases.Create(ctx, org.ID, "Docs", "", &user.ID)
+		if err != nil {
+			t.Fatalf("failed to create knowledge base: %v", err)
+		}
+
+		deleted, err := m.Users.DeleteAccount(ctx, user.ID)
+		if err != nil {
+			t.Fatalf("DeleteAccount() error = %v", err)
+		}
+		if len(deleted) != 1 || deleted[0].ID != org.ID || deleted[0].Slug != org.Slug {
+			t.Fatalf("DeleteAccount() deleted = %+v, want organization %d", deleted, org.ID)
+		}
+		if ids := deleted[0].KnowledgeBaseIDs; len(ids) != 1 || ids[0] != kb.ID {
+			t.Errorf("DeleteAccount() knowledge bases = %v, want [%d]", ids, kb.ID)
+		}
+		if _, err := m.Organizations.FindBySlug(ctx, org.Slug); err != ErrOrganizationNotFound {
+			t.Errorf("FindBySlug() after delete error = %v, want ErrOrganizationNotFound", err)
+		}
+	})
+
+	t.Run("refuses the sole owner of an organization with other members", func(t *testing.T) {
+		owner := createTestUser(t, m)
+		org := createTestOrganization(t, m, owner)
+		member := createTestUser(t, m)
+		if _, err := m.Organizations.AddMember(ctx, org.ID, member.ID, "member", "active"); err != nil {
+			t.Fatalf("failed to add member: %v", err)
+		}
+
+		if _, err := m.Users.DeleteAccount(ctx, owner.ID); err != ErrSoleOrganizationOwner {
+			t.Fatalf("DeleteAccount() error = %v, want ErrSoleOrganizationOwner", err)
+		}
+		if _, err := m.Organizations.GetMember(ctx, org.ID, owner.ID); err != nil {
+			t.Errorf("owner membership was removed: %v", err)
+		}
+	})
+
+	for _, status := range []string{"invited", "suspended"} {
+		t.Run("an "+status+" co-owner does not count as an owner", func(t *testing.T) {
+			owner := createTestUser(t, m)
+			org := createTestOrganization(t, m, owner)
+			coOwner := createTestUser(t, m)
+			if _, err := m.Organizations.AddMember(ctx, org.ID, coOwner.ID, "owner", status); err != nil {
+				t.Fatalf("failed to add co-owner: %v", err)
+			}
+			member := createTestUser(t, m)
+			if _, err := m.Organizations.AddMember(ctx, org.ID, member.ID, "member", "active"); err != nil {
+				t.Fatalf("failed to add member: %v", err)
+			}
+
+			if _, err := m.Users.DeleteAccount(ctx, owner.ID); err != ErrSoleOrganizationOwner {
+				t.Fatalf("DeleteAccount() error = %v, want ErrSoleOrganizationOwner", err)
+			}
+		})
+	}
+
+	t.Run("invited and suspended members do not keep the organization", func(t *testing.T) {
+		owner := createTestUser(t, m)
+		org := createTestOrganization(t, m, owner)
+		for _, status := range []string{"invited", "suspended"} {
+			member := createTestUser(t, m)
+			if _, err := m.Organizations.AddMember(ctx, org.ID, member.ID, "member", status); err != nil {
+				t.Fatalf("failed to add %s member: %v", status, err)
+			}
+		}
+
+		deleted, err := m.Users.DeleteAccount(ctx, owner.ID)
+		if err != nil {
+			t.Fatalf("DeleteAccount() error = %v", err)
+		}
+		if len(deleted) != 1 || deleted[0].ID != org.ID {
+			t.Fatalf("DeleteAccount() deleted = %+v, want organization %d", deleted, org.ID)
+		}
+		if _, err := m.Organizations.FindBySlug(ctx, org.Slug); err != ErrOrganizationNotFound {
+			t.Errorf("FindBySlug() after delete error = %v, want ErrOrganizationNotFound", err)
+		}
+	})
+}
+
+func TestListUsers(t *testing.T) {
+	m := testModels(t)
+	ctx := context.Background()
+
+	// A marker only these users' emails contain, so other rows in the database do not match
+	marker := fmt.Sprintf("list%d", id.Generate())
+	for _, prefix := range []string{"carol", "alice", "bob"} {
+		user, err := m.Users.Create(ctx, fmt.Sprintf("%s-%s@example.com", prefix, marker), strings.ToUpper(prefix[:1])+prefix[1:], "password123")
+		if err != nil {
+			t.Fatalf("failed to create user: %v", err)
+		}
+		t.Cleanup(func() { m.Users.DeleteAccount(context.Background(), user.ID) })
+	}
+
+	tests := []struct {
+		name      string
+		opts      UserListOptions
+		wantNames []string
+		wantTotal int
+	}{
+		{name: "search by email", opts: UserListOptions{Search: marker, Sort: "email", Limit: 10}, wantNames: []string{"Alice", "Bob", "Carol"}, wantTotal: 3},
+		{name: "search is case-insensitive", opts: UserListOptions{Search: "ALICE-" + strings.ToUpper(marker), Limit: 10}, wantNames: []string{"Alice"}, wantTotal: 1},
+		{name: "search matches nothing", opts: UserListOptions{Search: marker + "-none", Limit: 10}, wantNames: []string{}, wantTotal: 0},
+		{name: "descending sort", opts: UserListOptions{Search: marker, Sort: "-email", Limit: 10}, wantNames: []string{"Carol", "Bob", "Alice"}, wantTotal: 3},
+		{name: "first page", opts: UserListOptions{Search: marker, Sort: "email", Limit: 2}, wantNames: []string{"Alice", "Bob"}, wantTotal: 3},
+		{name: "last partial page", opts: UserListOptions{Search: marker, Sort: "email", Limit: 2, Offset: 2}, wantNames: []string{"Carol"}, wantTotal: 3},
+		{name: "offset past the end", opts: UserListOptions{Search: marker, Sort: "email", Limit: 2, Offset: 3}, wantNames: []string{}, wantTotal: 3},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			users, total, err := m.Users.List(ctx, tt.opts)
+			if err != nil {
+				t.Fatalf("List() error = %v", err)
+			}
+			names := make([]string, 0, len(users))
+			for _, user := range users {
+				names = append(names, user.Name)
+			}
+			if strings.Join(names, ",") != strings.Join(tt.wantNames, ",") || total != tt.wantTotal {
+				t.Errorf("List() = %v, total %d, want %v, total %d", names, total, tt.wantNames, tt.wantTotal)
+			}
+		})
+	}
+}
+
+func TestRecordLogin(t *testing.T) {
+	m := testModels(t)
+	ctx := context.Background()
+
+	user := createTestUser(t, m)
+	found, err := m.Users.FindByID(ctx, user.ID)
+	if err != nil {
+		t.Fatalf("FindByID() error = %v", err)
+	}
+	if found.LastLoginAt != nil || found.LastLoginIP != nil {
+		t.Errorf("new user last login = %v from %v, want none", found.LastLoginAt, found.LastLoginIP)
+	}
+
+	before := time.Now().Add(-time.Minute)
+	if err := m.Users.RecordLogin(ctx, user.ID, "203.0.113.7"); err != nil {
+		t.Fatalf("RecordLogin() error = %v", err)
+	}
+	found, err = m.Users.FindByID(ctx, user.ID)
+	if err != nil {
+		t.Fatalf("FindByID() error = %v", err)
+	}
+	if found.LastLoginAt == nil || found.LastLoginAt.Before(before) {
+		t.Errorf("LastLoginAt = %v, want the time of the login", found.LastLoginAt)
+	}
+	if found.LastLoginIP == nil || *found.LastLoginIP != "203.0.113.7" {
+		t.Errorf("LastLoginIP = %v, want 203.0.113.7", found.LastLoginIP)
+	}
+}
+
+func TestUserLoginDetailsOnlyInSelfView(t *testing.T) {
+	at := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	ip := "203.0.113.7"
+	user := User{ID: 42, Email: "ada@example.com", Name: "Ada", LastLoginAt: &at, LastLoginIP: &ip}
+
+	public, err := json.Marshal(user)
+	if err != nil {
+		t.Fatalf("failed to marshal user: %v", err)
+	}
+	if strings.Contains(string(public), "last_login") {
+		t.Errorf("user JSON = %s, want no login details", public)
+	}
+
+	self, err := json.Marshal(UserSelfView(user))
+	if err != nil {
+		t.Fatalf("failed to marshal self view: %v", err)
+	}
+	var got struct {
+		ID          string     `json:"id"`
+		Email       string     `json:"email"`
+		LastLoginAt *time.Time `json:"last_login_at"`
+		LastLoginIP *string    `json:"last_login_ip"`
+	}
+	if err := json.Unmarshal(self, &got); err != nil {
+		t.Fatalf("failed to decode self view: %v", err)
+	}
+	if got.ID != "42" || got.Email != user.Email {
+		t.Errorf("self view = %s, want the user's id and email", self)
+	}
+	if got.LastLoginAt == nil || !got.LastLoginAt.Equal(at) || got.LastLoginIP == nil || *got.LastLoginIP != ip {
+		t.Errorf("self view = %s, want last login at %v from %s", self, at, ip)
+	}
+}
@@ -0,0 +1,96 @@
+package pagination
+
+import (
+	"encoding/base64"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Default and maximum page sizes for cursor-paginated lists
+const (
+	DefaultLimit = 50
+	MaxLimit     = 200
+)
+
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// Cursor marks the last row of a page. Lists are ordered by a timestamp and then
+// by ID, so the pair identifies a position that stays stable under inserts.
+type Cursor struct {
+	Time time.Time
+	ID   int64
+}
+
+// Page is the standard response envelope for cursor-paginated lists
+type Page[T any] struct {
+	Data       []T    `json:"data"`
+	NextCursor string `json:"next_cursor,omitempty"`
+	HasMore    bool   `json:"has_more"`
+}
+
+// Encode returns the opaque string form of a cursor
+func Encode(c Cursor) string {
+	raw := strconv.FormatInt(c.Time.UnixNano(), 10) + ":" + strconv.FormatInt(c.ID, 10)
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+// Parse decodes a cursor produced by Encode. An empty string means the first page
+// and returns a nil cursor.
+func Parse(s string) (*Cursor, error) {
+	if s == "" {
+		return nil, nil
+	}
+
+	raw, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+
+	ts, id, ok := strings.Cut(string(raw), ":")
+	if !ok {
+		return nil, ErrInvalidCursor
+	}
+	nanos, err := strconv.ParseInt(ts, 10, 64)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+	parsedID, err := strconv.ParseInt(id, 10, 64)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+
+	return &Cursor{Time: time.Unix(0, nanos).UTC(), ID: parsedID}, nil
+}
+
+// ParseLimit parses a page size query value, applying DefaultLimit when empty and
+// capping it at MaxLimit
+func ParseLimit(s string) (int, error) {
+	if s == "" {
+		return DefaultLimit, nil
+	}
+	limit, err := strconv.Atoi(s)
+	if err != nil || limit < 1 {
+		return 0, errors.New("invalid limit")
+	}
+	if limit > MaxLimit {
+		limit = MaxLimit
+	}
+	return limit, nil
+}
+
+// NewPage builds a page from up to limit+1 fetched items. The extra item, if present,
+// only signals that more rows exist; cursorOf builds the cursor from the last kept item.
+func NewPage[T any](items []T, limit int, cursorOf func(T) Cursor) Page[T] {
+	page := Page[T]{Data: items}
+	if len(items) > limit {
+		page.Data = items[:limit]
+		page.HasMore = true
+		page.NextCursor = Encode(cursorOf(page.Data[limit-1]))
+	}
+	if page.Data == nil {
+		page.Data = []T{}
+	}
+	return page
+}
@@ -0,0 +1,109 @@
+package pagination
+
+import (
+	"encoding/base64"
+	"testing"
+	"time"
+)
+
+func TestEncodeParseRoundTrip(t *testing.T) {
+	tests := []struct {
+		name   string
+		cursor Cursor
+	}{
+		{name: "nanosecond timestamp", cursor: Cursor{Time: time.Date(2026, 10, 17, 9, 30, 0, 123456789, time.UTC), ID: 1234567890123}},
+		{name: "zero ID", cursor: Cursor{Time: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC), ID: 0}},
+		{name: "negative ID", cursor: Cursor{Time: time.Unix(0, 1).UTC(), ID: -5}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Parse(Encode(tt.cursor))
+			if err != nil {
+				t.Fatalf("Parse(Encode()) error = %v", err)
+			}
+			if !got.Time.Equal(tt.cursor.Time) || got.ID != tt.cursor.ID {
+				t.Errorf("Parse(Encode()) = %+v, want %+v", *got, tt.cursor)
+			}
+		})
+	}
+}
+
+func TestParse(t *testing.T) {
+	encode := func(raw string) string { return base64.RawURLEncoding.EncodeToString([]byte(raw)) }
+
+	tests := []struct {
+		name    string
+		input   string
+		wantNil bool
+		wantErr bool
+	}{
+		{name: "empty is the first page", input: "", wantNil: true},
+		{name: "not base64", input: "%%%", wantErr: true},
+		{name: "missing separator", input: encode("12345"), wantErr: true},
+		{name: "non-numeric timestamp", input: encode("abc:1"), wantErr: true},
+		{name: "non-numeric ID", input: encode("1:abc"), wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Parse(tt.input)
+			if tt.wantErr {
+				if err != ErrInvalidCursor {
+					t.Fatalf("Parse(%q) error = %v, want ErrInvalidCursor", tt.input, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Parse(%q) error = %v", tt.input, err)
+			}
+			if (got == nil) != tt.wantNil {
+				t.Errorf("Parse(%q) = %v, want nil %v", tt.input, got, tt.wantNil)
+			}
+		})
+	}
+}
+
+func TestParseLimit(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		want    int
+		wantErr bool
+	}{
+		{name: "default", input: "", want: DefaultLimit},
+		{name: "within range", input: "20", want: 20},
+		{name: "at max", input: "200", want: MaxLimit},
+		{name: "capped at max", input: "1000", want: MaxLimit},
+		{name: "zero", input: "0", wantErr: true},
+		{name: "negative", input: "-1", wantErr: true},
+		{name: "not a number", input: "ten", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseLimit(tt.input)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseLimit(%q) error = %v, wantErr %v", tt.input, err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("ParseLimit(%q) = %d, want %d", tt.input, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNewPage(t *testing.T) {
+	cursorOf := func(i int) Cursor { return Cursor{Time: time.Unix(int64(i), 0).UTC(), ID: int64(i)} }
+
+	page := NewPage([]int{1, 2, 3}, 2, cursorOf)
+	if len(page.Data) != 2 || !page.HasMore {
+		t.Fatalf("NewPage() = %+v, want 2 items and more", page)
+	}
+	next, err := Parse(page.NextCursor)
+	if err != nil || next.ID != 2 {
+		t.Errorf("NewPage() next cursor = %+v (%v), want the last kept item", next, err)
+	}
+
+	last := NewPage([]int(nil), 2, cursorOf)
+	if last.Data == nil || last.HasMore || last.NextCursor != "" {
+		t.Errorf("NewPage(nil) = %+v, want an empty final page", last)
+	}
+}
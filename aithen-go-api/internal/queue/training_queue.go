@@ -5,6 +5,7 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"log"
@@ -15,6 +16,8 @@ import (
 	"sync"
 	"time"
 
+	"github.com/aithen/go-api/internal/config"
+	"github.com/aithen/go-api/internal/httpclient"
 	"github.com/aithen/go-api/internal/models"
 	"github.com/aithen/go-api/internal/websocket"
 )
@@ -26,6 +29,15 @@ const (
 	MaxConcurrentJobs = 3
 )
 
+var (
+	// ErrNoFailedJobs is returned when a channel has no failed jobs to retry
+	ErrNoFailedJobs = errors.New("no failed jobs to retry")
+	// ErrJobsInProgress is returned when a channel still has pending or processing jobs
+	ErrJobsInProgress = errors.New("training jobs are still in progress")
+	// ErrQueueFull is returned when the queue has no room for all of a run's jobs
+	ErrQueueFull = errors.New("training queue is full")
+)
+
 // TrainingJob represents a single training job
 type TrainingJob struct {
 	ID              string
@@ -34,11 +46,28 @@ type TrainingJob struct {
 	Files           []*models.KnowledgeBaseFile
 	JobIndex        int
 	TotalJobs       int
-	Status          string // pending, processing, completed, failed
+	Status          string // pending, processing, completed, failed, cancelled
 	StartedAt       *time.Time
 	CompletedAt     *time.Time
 	Error           error
 	ChannelID       string
+	ChunkSize       *int    // Overrides the training service's chunk size when set
+	ChunkOverlap    *int    // Overrides the training service's chunk overlap when set
+	EmbeddingModel  *string // Overrides the training service's embedding model when set
+
+	log strings.Builder // Messages of the current attempt, appended to the version's training log
+
+	// Per-file outcome of the current attempt, used to record which files failed and why
+	completedFiles    map[int64]bool
+	fileErrorRecorded bool
+
+	cancel context.CancelFunc // Stops the training service call while the job is processing
+}
+
+// logf adds a timestamped line to the job's log
+func (j *TrainingJob) logf(format string, args ...interface{}) {
+	fmt.Fprintf(&j.log, "%s [job %d/%d] %s\n",
+		time.Now().UTC().Format(time.RFC3339), j.JobIndex, j.TotalJobs, fmt.Sprintf(format, args...))
 }
 
 // TrainingQueue manages training jobs
@@ -49,6 +78,7 @@ type TrainingQueue struct {
 	processQueue chan *TrainingJob
 	wsHub        *websocket.Hub
 	models       *models.Models
+	runFinished  func(kbID int64) // Called when all jobs of a run have finished
 }
 
 var (
@@ -62,7 +92,7 @@ func GetTrainingQueue() *TrainingQueue {
 		queueInstance = &TrainingQueue{
 			jobs:         make([]*TrainingJob, 0),
 			activeJobs:   make(map[string]*TrainingJob),
-			processQueue: make(chan *TrainingJob, 100),
+			processQueue: make(chan *TrainingJob, queueSize()),
 			wsHub:        websocket.GetHub(),
 		}
 		go queueInstance.processJobs()
@@ -70,6 +100,107 @@ func GetTrainingQueue() *TrainingQueue {
 	return queueInstance
 }
 
+// queueSize returns the number of jobs that can wait for a worker (TRAINING_QUEUE_SIZE, default 100)
+func queueSize() int {
+	size := config.GetEnvInt("TRAINING_QUEUE_SIZE", 100)
+	if size < 1 {
+		size = 1
+	}
+	return size
+}
+
+// hasRoomLocked reports whether n more jobs fit in the process queue. The caller must hold q.mu:
+// only enqueuers send on the channel and they are serialized by q.mu, so the free space cannot
+// shrink before the caller's sends, which therefore never block.
+func (q *TrainingQueue) hasRoomLocked(n int) bool {
+	if free := cap(q.processQueue) - len(q.processQueue); free < n {
+		log.Printf("Warning: Job queue is full (%d free slots, %d jobs)", free, n)
+		return false
+	}
+	return true
+}
+
+// trainingLogMaxSize returns the number of characters kept in a version's training log
+// (TRAINING_LOG_MAX_SIZE, default 1000000)
+func trainingLogMaxSize() int {
+	size := config.GetEnvInt("TRAINING_LOG_MAX_SIZE", 1000000)
+	if size < 1 {
+		size = 1
+	}
+	return size
+}
+
+// appendVersionLog appends text to a version's training log, logging rather than returning failures
+func (q *TrainingQueue) appendVersionLog(versionID int64, text string) {
+	if q.models == nil || text == "" {
+		return
+	}
+	if err := q.models.KnowledgeBases.AppendVersionLog(context.Background(), versionID, text, trainingLogMaxSize()); err != nil {
+		log.Printf("Warning: Failed to append training log for version %d: %v", versionID, err)
+	}
+}
+
+// clearFileErrors removes the errors recorded for a job's files by an earlier attempt
+func (q *TrainingQueue) clearFileErrors(job *TrainingJob) {
+	if q.models == nil {
+		return
+	}
+	fileIDs := make([]int64, len(job.Files))
+	for i, file := range job.Files {
+		fileIDs[i] = file.ID
+	}
+	if err := q.models.KnowledgeBases.ClearTrainingErrors(context.Background(), job.VersionID, fileIDs); err != nil {
+		log.Printf("Warning: Failed to clear training errors for version %d: %v", job.VersionID, err)
+	}
+}
+
+// recordFileError saves why a file of a job failed, logging rather than returning failures
+func (q *TrainingQueue) recordFileError(job *TrainingJob, file *models.KnowledgeBaseFile, message string) {
+	if q.models == nil {
+		return
+	}
+	err := q.models.KnowledgeBases.RecordTrainingError(context.Background(), job.VersionID, file.ID, file.Name, job.ID, job.JobIndex, message)
+	if err != nil {
+		log.Printf("Warning: Failed to record training error for file %d: %v", file.ID, err)
+	}
+}
+
+// jobFile returns the file of a job with the given ID, as sent to the training service
+func jobFile(job *TrainingJob, fileID string) *models.KnowledgeBaseFile {
+	for _, file := range job.Files {
+		if fmt.Sprintf("%d", file.ID) == fileID {
+			return file
+		}
+	}
+	return nil
+}
+
+// fileErrorMessage returns the error of a file from an error event's file_details, falling back
+// to the event's message
+func fileErrorMessage(data map[string]interface{}, fileID string) string {
+	if details, ok := data["file_details"].([]interface{}); ok {
+		for _, d := range details {
+			detail, _ := d.(map[string]interface{})
+			if id, _ := detail["file_id"].(string); id != fileID {
+				continue
+			}
+			if msg, _ := detail["error"].(string); msg != "" {
+				return msg
+			}
+		}
+	}
+	msg, _ := data["message"].(string)
+	return msg
+}
+
+// OnRunFinished sets a function called, in its own goroutine, with the knowledge base ID
+// whenever all jobs of a training run have finished, whether they succeeded or not
+func (q *TrainingQueue) OnRunFinished(fn func(kbID int64)) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.runFinished = fn
+}
+
 // SetModels sets the models instance for the queue
 func (q *TrainingQueue) SetModels(m *models.Models) {
 	q.mu.Lock()
@@ -77,8 +208,11 @@ func (q *TrainingQueue) SetModels(m *models.Models) {
 	q.models = m
 }
 
-// EnqueueTrainingJob creates and enqueues training jobs for a knowledge base
-func (q *TrainingQueue) EnqueueTrainingJob(ctx context.Context, kbID, versionID int64, files []*models.KnowledgeBaseFile, channelID string) error {
+// EnqueueTrainingJob creates and enqueues training jobs for a knowledge base version,
+// using the version's chunking parameters and embedding model
+func (q *TrainingQueue) EnqueueTrainingJob(ctx context.Context, version *models.KnowledgeBaseVersion, files []*models.KnowledgeBaseFile, channelID string) error {
+	kbID, versionID := version.KnowledgeBaseID, version.ID
+
 	q.mu.Lock()
 	defer q.mu.Unlock()
 
@@ -92,6 +226,10 @@ func (q *TrainingQueue) EnqueueTrainingJob(ctx context.Context, kbID, versionID
 
 	log.Printf("Chunking %d files into %d jobs (max %d files per job)", totalFiles, totalJobs, MaxFilesPerJob)
 
+	if !q.hasRoomLocked(totalJobs) {
+		return ErrQueueFull
+	}
+
 	// Create jobs for each batch
 	jobs := make([]*TrainingJob, 0, totalJobs)
 	for i := 0; i < totalJobs; i++ {
@@ -113,11 +251,14 @@ func (q *TrainingQueue) EnqueueTrainingJob(ctx context.Context, kbID, versionID
 			TotalJobs:       totalJobs,
 			Status:          "pending",
 			ChannelID:       channelID,
+			ChunkSize:       version.ChunkSize,
+			ChunkOverlap:    version.ChunkOverlap,
+			EmbeddingModel:  version.EmbeddingModel,
 		}
 
 		jobs = append(jobs, job)
-		q.jobs = append(q.jobs, job)
 	}
+	q.jobs = append(q.jobs, jobs...)
 
 	// Send initial job queue message
 	q.wsHub.Broadcast(channelID, "job_queue_created", map[string]interface{}{
@@ -126,29 +267,172 @@ func (q *TrainingQueue) EnqueueTrainingJob(ctx context.Context, kbID, versionID
 		"jobs":        jobs,
 	}, nil, nil)
 
-	// Enqueue all jobs
+	// Enqueue all jobs (room was checked above)
 	for _, job := range jobs {
-		select {
-		case q.processQueue <- job:
-			log.Printf("Enqueued job %s (%d/%d)", job.ID, job.JobIndex, job.TotalJobs)
-		default:
-			log.Printf("Warning: Job queue is full, job %s may be delayed", job.ID)
-			// Try again in a goroutine
-			go func(j *TrainingJob) {
-				time.Sleep(1 * time.Second)
-				q.processQueue <- j
-			}(job)
-		}
+		q.processQueue <- job
+		log.Printf("Enqueued job %s (%d/%d)", job.ID, job.JobIndex, job.TotalJobs)
 	}
 
 	return nil
 }
 
+// RetryFailedJobs re-enqueues the failed jobs of a channel, keeping their version and job indexes.
+// Job state is held in memory, so only jobs from the current process can be retried.
+// It returns the retried jobs.
+func (q *TrainingQueue) RetryFailedJobs(channelID string) ([]*TrainingJob, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	var failedJobs []*TrainingJob
+	for _, job := range q.jobs {
+		if job.ChannelID != channelID {
+			continue
+		}
+		switch job.Status {
+		case "pending", "processing":
+			return nil, ErrJobsInProgress
+		case "failed":
+			failedJobs = append(failedJobs, job)
+		}
+	}
+
+	if len(failedJobs) == 0 {
+		return nil, ErrNoFailedJobs
+	}
+
+	if !q.hasRoomLocked(len(failedJobs)) {
+		return nil, ErrQueueFull
+	}
+
+	totalFiles := 0
+	for _, job := range failedJobs {
+		job.Status = "pending"
+		job.StartedAt = nil
+		job.CompletedAt = nil
+		job.Error = nil
+		job.log.Reset()
+		totalFiles += len(job.Files)
+	}
+
+	log.Printf("Retrying %d failed jobs for channel %s", len(failedJobs), channelID)
+
+	// Send job queue message for the retried subset
+	q.wsHub.Broadcast(channelID, "job_queue_created", map[string]interface{}{
+		"total_jobs":  len(failedJobs),
+		"total_files": totalFiles,
+		"jobs":        failedJobs,
+		"retry":       true,
+	}, nil, nil)
+
+	for _, job := range failedJobs {
+		q.processQueue <- job
+		log.Printf("Re-enqueued job %s (%d/%d)", job.ID, job.JobIndex, job.TotalJobs)
+	}
+
+	return failedJobs, nil
+}
+
+// HasActiveJobs reports whether a knowledge base has pending or processing jobs in this process
+func (q *TrainingQueue) HasActiveJobs(kbID int64) bool {
+	q.mu.RLock()
+	defer q.mu.RUnlock()
+
+	for _, job := range q.jobs {
+		if job.KnowledgeBaseID == kbID && (job.Status == "pending" || job.Status == "processing") {
+			return true
+		}
+	}
+	return false
+}
+
+// CancelledRun describes a training run stopped by CancelRuns
+type CancelledRun struct {
+	KnowledgeBaseID int64
+	VersionID       int64
+	ChannelID       string
+	CancelledJobs   int
+}
+
+// CancelRuns cancels the pending and processing jobs of the given knowledge bases. Pending jobs
+// are dropped when they reach a worker and processing jobs have their training service call
+// stopped. Each affected version is marked cancelled and its knowledge base active again.
+// Job state is held in memory, so only jobs from the current process can be cancelled.
+// It returns the cancelled runs.
+func (q *TrainingQueue) CancelRuns(kbIDs []int64) []CancelledRun {
+	wanted := make(map[int64]bool, len(kbIDs))
+	for _, kbID := range kbIDs {
+		wanted[kbID] = true
+	}
+
+	q.mu.Lock()
+	var runs []CancelledRun
+	runIndex := make(map[string]int)
+	now := time.Now()
+	for _, job := range q.jobs {
+		if !wanted[job.KnowledgeBaseID] || (job.Status != "pending" && job.Status != "processing") {
+			continue
+		}
+		if job.cancel != nil {
+			job.cancel()
+		}
+		job.Status = "cancelled"
+		job.CompletedAt = &now
+
+		i, ok := runIndex[job.ChannelID]
+		if !ok {
+			i = len(runs)
+			runIndex[job.ChannelID] = i
+			runs = append(runs, CancelledRun{
+				KnowledgeBaseID: job.KnowledgeBaseID,
+				VersionID:       job.VersionID,
+				ChannelID:       job.ChannelID,
+			})
+		}
+		runs[i].CancelledJobs++
+	}
+	m, runFinished := q.models, q.runFinished
+	q.mu.Unlock()
+
+	for _, run := range runs {
+		log.Printf("Cancelled %d jobs for channel %s", run.CancelledJobs, run.ChannelID)
+		q.appendVersionLog(run.VersionID, fmt.Sprintf("%s Training cancelled: %d jobs stopped\n",
+			now.UTC().Format(time.RFC3339), run.CancelledJobs))
+		q.wsHub.Broadcast(run.ChannelID, "all_jobs_completed", map[string]interface{}{
+			"status":    "cancelled",
+			"cancelled": run.CancelledJobs,
+		}, nil, nil)
+
+		if m != nil {
+			ctx := context.Background()
+			if err := m.KnowledgeBases.UpdateVersionStatus(ctx, run.VersionID, "cancelled", &now); err != nil {
+				log.Printf("Warning: Failed to mark version %d cancelled: %v", run.VersionID, err)
+			}
+			if err := m.KnowledgeBases.UpdateStatus(ctx, run.KnowledgeBaseID, "active"); err != nil {
+				log.Printf("Warning: Failed to reset status of knowledge base %d: %v", run.KnowledgeBaseID, err)
+			}
+		}
+
+		if runFinished != nil {
+			go runFinished(run.KnowledgeBaseID)
+		}
+	}
+
+	return runs
+}
+
 // processJobs processes jobs from the queue
 func (q *TrainingQueue) processJobs() {
 	semaphore := make(chan struct{}, MaxConcurrentJobs)
 
 	for job := range q.processQueue {
+		// Jobs cancelled while pending are dropped without taking a slot
+		q.mu.RLock()
+		cancelled := job.Status == "cancelled"
+		q.mu.RUnlock()
+		if cancelled {
+			continue
+		}
+
 		// Wait for available slot
 		semaphore <- struct{}{}
 
@@ -156,13 +440,29 @@ func (q *TrainingQueue) processJobs() {
 			defer func() { <-semaphore }()
 
 			q.mu.Lock()
+			if j.Status == "cancelled" {
+				q.mu.Unlock()
+				return
+			}
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
 			j.Status = "processing"
 			now := time.Now()
 			j.StartedAt = &now
+			j.completedFiles = make(map[int64]bool)
+			j.fileErrorRecorded = false
+			j.cancel = cancel
 			q.activeJobs[j.ID] = j
 			q.mu.Unlock()
 
+			q.clearFileErrors(j)
+
 			log.Printf("Processing job %s (%d/%d) with %d files", j.ID, j.JobIndex, j.TotalJobs, len(j.Files))
+			fileNames := make([]string, len(j.Files))
+			for i, file := range j.Files {
+				fileNames[i] = file.Name
+			}
+			j.logf("Started with %d files: %s", len(j.Files), strings.Join(fileNames, ", "))
 
 			// Send job start message
 			q.wsHub.Broadcast(j.ChannelID, "job_started", map[string]interface{}{
@@ -174,22 +474,44 @@ func (q *TrainingQueue) processJobs() {
 			}, nil, nil)
 
 			// Process the job (this will call the training service)
-			err := q.processJob(context.Background(), j)
+			err := q.processJob(ctx, j)
 
 			q.mu.Lock()
+			j.cancel = nil
+			delete(q.activeJobs, j.ID)
+			if j.Status == "cancelled" {
+				// CancelRuns has already finished the run
+				log.Printf("Job %s cancelled", j.ID)
+				q.mu.Unlock()
+				return
+			}
 			now = time.Now()
 			j.CompletedAt = &now
 			if err != nil {
 				j.Status = "failed"
 				j.Error = err
 				log.Printf("Job %s failed: %v", j.ID, err)
+				j.logf("Failed: %v", err)
 			} else {
 				j.Status = "completed"
 				log.Printf("Job %s completed successfully", j.ID)
+				j.logf("Completed")
 			}
-			delete(q.activeJobs, j.ID)
+			jobLog := j.log.String()
 			q.mu.Unlock()
 
+			q.appendVersionLog(j.VersionID, jobLog)
+
+			// A failure not tied to a file by the training service (e.g. a lost connection)
+			// is recorded for every file the job had not finished
+			if err != nil && !j.fileErrorRecorded {
+				for _, file := range j.Files {
+					if !j.completedFiles[file.ID] {
+						q.recordFileError(j, file, err.Error())
+					}
+				}
+			}
+
 			// Send job completion message
 			msgType := "job_completed"
 			if err != nil {
@@ -300,9 +622,22 @@ func (q *TrainingQueue) callTrainingService(ctx context.Context, job *TrainingJo
 		"job_index":         job.JobIndex,
 		"total_jobs":        job.TotalJobs,
 	}
+	if job.ChunkSize != nil {
+		trainingReq["chunk_size"] = *job.ChunkSize
+	}
+	if job.ChunkOverlap != nil {
+		trainingReq["chunk_overlap"] = *job.ChunkOverlap
+	}
+	if job.EmbeddingModel != nil {
+		trainingReq["embedding_model"] = *job.EmbeddingModel
+	}
+	// Read per job so a change to the knowledge base's setting applies from its next run
+	if kb, err := q.models.KnowledgeBases.FindByID(ctx, job.KnowledgeBaseID); err == nil && kb.CompressChunks {
+		trainingReq["compress_chunks"] = true
+	}
 
 	// Call Python training service
-	aiServiceURL := getTrainingServiceURL()
+	aiServiceURL := TrainingServiceURL()
 	trainingURL := fmt.Sprintf("%s/training/stream", aiServiceURL)
 
 	reqBody, err := json.Marshal(trainingReq)
@@ -316,8 +651,7 @@ func (q *TrainingQueue) callTrainingService(ctx context.Context, job *TrainingJo
 	}
 	httpReq.Header.Set("Content-Type", "application/json")
 
-	client := &http.Client{Timeout: 0}
-	resp, err := client.Do(httpReq)
+	resp, err := httpclient.New(0).Do(httpReq)
 	if err != nil {
 		return fmt.Errorf("failed to connect to training service: %v", err)
 	}
@@ -329,6 +663,7 @@ func (q *TrainingQueue) callTrainingService(ctx context.Context, job *TrainingJo
 	}
 
 	// Parse SSE stream and forward to WebSocket
+	completed := false
 	scanner := bufio.NewScanner(resp.Body)
 	for scanner.Scan() {
 		line := scanner.Text()
@@ -385,15 +720,37 @@ func (q *TrainingQueue) callTrainingService(ctx context.Context, job *TrainingJo
 			if t, ok := progressData["type"].(string); ok {
 				msgType = t
 			}
+			if progress.Message != "" {
+				job.logf("%s: %s", msgType, progress.Message)
+			}
+
+			// A malformed completion fails the job instead of being reported as success
+			if msgType == "complete" {
+				if err := validateCompleteEvent(progressData, len(job.Files)); err != nil {
+					return fmt.Errorf("invalid completion from training service: %w", err)
+				}
+			}
 
 			// Broadcast progress update
 			q.wsHub.Broadcast(job.ChannelID, msgType, progressData, progress, nil)
 
 			// Handle completion
 			if msgType == "complete" {
+				completed = true
 				break
 			}
 
+			fileID, _ := progressData["current_file_id"].(string)
+			if file := jobFile(job, fileID); file != nil {
+				if msgType == "progress" && progress.Status == "completed" {
+					job.completedFiles[file.ID] = true
+				}
+				if msgType == "error" {
+					q.recordFileError(job, file, fileErrorMessage(progressData, fileID))
+					job.fileErrorRecorded = true
+				}
+			}
+
 			// Handle errors
 			if msgType == "error" {
 				return fmt.Errorf("training error: %v", progressData["message"])
@@ -401,15 +758,52 @@ func (q *TrainingQueue) callTrainingService(ctx context.Context, job *TrainingJo
 		}
 	}
 
-	return scanner.Err()
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+	if !completed {
+		return fmt.Errorf("training service closed the stream without a completion event")
+	}
+	return nil
+}
+
+// validateCompleteEvent checks that a "complete" event from the training service reports
+// a completed status, covers every file in the job and includes the embeddings it wrote
+func validateCompleteEvent(data map[string]interface{}, totalFiles int) error {
+	if status, _ := data["status"].(string); status != "completed" {
+		return fmt.Errorf("unexpected status %q", data["status"])
+	}
+
+	files, ok := data["total_files"].(float64)
+	if !ok {
+		return fmt.Errorf("missing total_files")
+	}
+	if int(files) != totalFiles {
+		return fmt.Errorf("total_files is %d, expected %d", int(files), totalFiles)
+	}
+
+	written, ok := data["embeddings_written"].(float64)
+	if !ok {
+		return fmt.Errorf("missing embeddings_written")
+	}
+	if written < 0 || written != float64(int64(written)) {
+		return fmt.Errorf("invalid embeddings_written %v", written)
+	}
+
+	return nil
 }
 
-func getTrainingServiceURL() string {
-	url := os.Getenv("AI_SERVICE_URL")
-	if url == "" {
-		return "http://localhost:8000"
+// TrainingServiceURL returns the training service URL.
+// Precedence: TRAINING_SERVICE_URL, then AI_SERVICE_URL, then http://localhost:8000.
+// This allows training to run on a separately scaled service from chat.
+func TrainingServiceURL() string {
+	if url := os.Getenv("TRAINING_SERVICE_URL"); url != "" {
+		return url
+	}
+	if url := os.Getenv("AI_SERVICE_URL"); url != "" {
+		return url
 	}
-	return url
+	return "http://localhost:8000"
 }
 
 // checkAllJobsCompleted checks if all jobs for a channel are completed
@@ -430,6 +824,9 @@ func (q *TrainingQueue) checkAllJobsCompleted(channelID string, versionID, kbID
 				completed++
 			case "failed":
 				failed++
+			case "cancelled":
+				// CancelRuns has already finished the run
+				return
 			}
 		}
 	}
@@ -438,13 +835,31 @@ func (q *TrainingQueue) checkAllJobsCompleted(channelID string, versionID, kbID
 	if pending == 0 && processing == 0 {
 		if failed > 0 {
 			// Some jobs failed
+			q.appendVersionLog(versionID, fmt.Sprintf("%s Training finished: %d jobs completed, %d failed\n",
+				time.Now().UTC().Format(time.RFC3339), completed, failed))
 			q.wsHub.Broadcast(channelID, "all_jobs_completed", map[string]interface{}{
 				"status":    "partial_failure",
 				"completed": completed,
 				"failed":    failed,
 			}, nil, fmt.Errorf("%d jobs failed", failed))
+		} else if q.models != nil && !q.versionHasEmbeddings(versionID) {
+			// The service reported success but the version has nothing to search
+			err := fmt.Errorf("training completed but no embeddings were stored for version %d", versionID)
+			log.Printf("Warning: %v", err)
+			q.appendVersionLog(versionID, fmt.Sprintf("%s Training failed: %v\n", time.Now().UTC().Format(time.RFC3339), err))
+			q.wsHub.Broadcast(channelID, "all_jobs_completed", map[string]interface{}{
+				"status":    "failed",
+				"completed": completed,
+			}, nil, err)
+
+			ctx := context.Background()
+			now := time.Now()
+			q.models.KnowledgeBases.UpdateVersionStatus(ctx, versionID, "failed", &now)
+			q.models.KnowledgeBases.UpdateStatus(ctx, kbID, "error")
 		} else {
 			// All jobs completed successfully
+			q.appendVersionLog(versionID, fmt.Sprintf("%s Training finished: %d jobs completed\n",
+				time.Now().UTC().Format(time.RFC3339), completed))
 			q.wsHub.Broadcast(channelID, "all_jobs_completed", map[string]interface{}{
 				"status":    "success",
 				"completed": completed,
@@ -458,19 +873,35 @@ func (q *TrainingQueue) checkAllJobsCompleted(channelID string, versionID, kbID
 				if err := q.models.KnowledgeBases.UpdateVersionQualityMetrics(ctx, versionID); err != nil {
 					log.Printf("Warning: Failed to update quality metrics for version %d: %v", versionID, err)
 				}
-				q.models.KnowledgeBases.Update(ctx, kbID, "", "", "active")
+				q.models.KnowledgeBases.UpdateStatus(ctx, kbID, "active")
 			}
 		}
+
+		// q.mu is held, so the hook runs separately in case it enqueues another run
+		if q.runFinished != nil {
+			go q.runFinished(kbID)
+		}
 	}
 }
 
+// versionHasEmbeddings reports whether any embeddings were stored for a version.
+// Lookup errors are treated as having embeddings so a transient failure does not fail the version.
+func (q *TrainingQueue) versionHasEmbeddings(versionID int64) bool {
+	count, err := q.models.KnowledgeBases.CountEmbeddingsByVersion(context.Background(), versionID)
+	if err != nil {
+		log.Printf("Warning: Failed to count embeddings for version %d: %v", versionID, err)
+		return true
+	}
+	return count > 0
+}
+
 // GetJobStatus returns the status of jobs for a channel
 func (q *TrainingQueue) GetJobStatus(channelID string) map[string]interface{} {
 	q.mu.RLock()
 	defer q.mu.RUnlock()
 
 	var jobs []map[string]interface{}
-	var pending, processing, completed, failed int
+	var pending, processing, completed, failed, cancelled int
 
 	for _, job := range q.jobs {
 		if job.ChannelID == channelID {
@@ -494,6 +925,8 @@ func (q *TrainingQueue) GetJobStatus(channelID string) map[string]interface{} {
 				completed++
 			case "failed":
 				failed++
+			case "cancelled":
+				cancelled++
 			}
 		}
 	}
@@ -504,5 +937,6 @@ func (q *TrainingQueue) GetJobStatus(channelID string) map[string]interface{} {
 		"processing": processing,
 		"completed":  completed,
 		"failed":     failed,
+		"cancelled":  cancelled,
 	}
 }
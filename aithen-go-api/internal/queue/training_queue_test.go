@@ -0,0 +1,660 @@
+package queue
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/aithen/go-api/internal/id"
+	"github.com/aithen/go-api/internal/limits"
+	"github.com/aithen/go-api/internal/models"
+	"github.com/aithen/go-api/internal/websocket"
+	gorillaws "github.com/gorilla/websocket"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+func TestTrainingServiceURL(t *testing.T) {
+	tests := []struct {
+		name        string
+		trainingURL string
+		aiURL       string
+		want        string
+	}{
+		{name: "training service set", trainingURL: "http://training:9000", aiURL: "http://ai:8000", want: "http://training:9000"},
+		{name: "falls back to AI service", trainingURL: "", aiURL: "http://ai:8000", want: "http://ai:8000"},
+		{name: "falls back to localhost", trainingURL: "", aiURL: "", want: "http://localhost:8000"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("TRAINING_SERVICE_URL", tt.trainingURL)
+			t.Setenv("AI_SERVICE_URL", tt.aiURL)
+			if got := TrainingServiceURL(); got != tt.want {
+				t.Errorf("TrainingServiceURL() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestQueuesBroadcastOnSharedHub(t *testing.T) {
+	// The router serves WebSocket clients from websocket.GetHub, so progress must go there too
+	hub := websocket.GetHub()
+	if GetTrainingQueue().wsHub != hub {
+		t.Error("training queue broadcasts on a different hub than websocket.GetHub()")
+	}
+	if GetUploadBatchTracker().wsHub != hub {
+		t.Error("upload batch tracker broadcasts on a different hub than websocket.GetHub()")
+	}
+}
+
+// subscribe connects a WebSocket client to channel on hub and waits until it is registered
+func subscribe(t *testing.T, hub *websocket.Hub, channel string) *gorillaws.Conn {
+	t.Helper()
+
+	upgrader := gorillaws.Upgrader{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		websocket.ServeWs(hub, conn, channel, 1, time.Time{})
+	}))
+	t.Cleanup(srv.Close)
+
+	conn, _, err := gorillaws.DefaultDialer.Dial("ws"+strings.TrimPrefix(srv.URL, "http"), nil)
+	if err != nil {
+		t.Fatalf("failed to dial: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+
+	deadline := time.Now().Add(2 * time.Second)
+	for hub.Channels()[channel] != 1 {
+		if time.Now().After(deadline) {
+			t.Fatalf("client was not registered on %s", channel)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	return conn
+}
+
+func TestRetryFailedJobs(t *testing.T) {
+	const channel = "training_1_2"
+
+	// newQueue returns a queue without workers, so re-enqueued jobs stay on processQueue
+	newQueue := func(hub *websocket.Hub, jobs ...*TrainingJob) *TrainingQueue {
+		return &TrainingQueue{
+			jobs:         jobs,
+			activeJobs:   make(map[string]*TrainingJob),
+			processQueue: make(chan *TrainingJob, 10),
+			wsHub:        hub,
+		}
+	}
+	job := func(id, channelID, status string, files int) *TrainingJob {
+		j := &TrainingJob{ID: id, ChannelID: channelID, Status: status, TotalJobs: 3}
+		for i := 0; i < files; i++ {
+			j.Files = append(j.Files, &models.KnowledgeBaseFile{ID: int64(i + 1)})
+		}
+		if status == "failed" {
+			j.Error = errors.New("training service unavailable")
+			now := time.Now()
+			j.StartedAt, j.CompletedAt = &now, &now
+		}
+		return j
+	}
+
+	t.Run("re-enqueues only the failed jobs", func(t *testing.T) {
+		hub := websocket.NewHub()
+		go hub.Run()
+		conn := subscribe(t, hub, channel)
+
+		completed := job("job_1", channel, "completed", 2)
+		failed1 := job("job_2", channel, "failed", 1)
+		failed2 := job("job_3", channel, "failed", 2)
+		otherRun := job("job_4", "training_1_3", "failed", 1)
+		q := newQueue(hub, completed, failed1, failed2, otherRun)
+
+		retried, err := q.RetryFailedJobs(channel)
+		if err != nil {
+			t.Fatalf("RetryFailedJobs() error = %v", err)
+		}
+		if len(retried) != 2 || retried[0] != failed1 || retried[1] != failed2 {
+			t.Fatalf("RetryFailedJobs() = %v, want job_2 and job_3", retried)
+		}
+		for _, j := range retried {
+			if j.Status != "pending" || j.Error != nil || j.StartedAt != nil || j.CompletedAt != nil {
+				t.Errorf("job %s = %s with error %v, want a reset pending job", j.ID, j.Status, j.Error)
+			}
+		}
+		if completed.Status != "completed" || otherRun.Status != "failed" {
+			t.Errorf("statuses = %s, %s, want completed and other run's job untouched", completed.Status, otherRun.Status)
+		}
+
+		if n := len(q.processQueue); n != 2 {
+			t.Fatalf("process queue has %d jobs, want 2", n)
+		}
+		for _, want := range []string{"job_2", "job_3"} {
+			if got := (<-q.processQueue).ID; got != want {
+				t.Errorf("enqueued %s, want %s", got, want)
+			}
+		}
+
+		var msg struct {
+			Type string `json:"type"`
+			Data struct {
+				TotalJobs  int  `json:"total_jobs"`
+				TotalFiles int  `json:"total_files"`
+				Retry      bool `json:"retry"`
+			} `json:"data"`
+		}
+		conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+		if err := conn.ReadJSON(&msg); err != nil {
+			t.Fatalf("failed to read broadcast: %v", err)
+		}
+		if msg.Type != "job_queue_created" || msg.Data.TotalJobs != 2 || msg.Data.TotalFiles != 3 || !msg.Data.Retry {
+			t.Errorf("broadcast = %+v, want job_queue_created for 2 jobs and 3 files", msg)
+		}
+	})
+
+	t.Run("no failed jobs", func(t *testing.T) {
+		q := newQueue(websocket.NewHub(), job("job_1", channel, "completed", 1))
+		if _, err := q.RetryFailedJobs(channel); err != ErrNoFailedJobs {
+			t.Errorf("RetryFailedJobs() error = %v, want %v", err, ErrNoFailedJobs)
+		}
+	})
+
+	t.Run("jobs still in progress", func(t *testing.T) {
+		failed := job("job_1", channel, "failed", 1)
+		q := newQueue(websocket.NewHub(), failed, job("job_2", channel, "processing", 1))
+		if _, err := q.RetryFailedJobs(channel); err != ErrJobsInProgress {
+			t.Errorf("RetryFailedJobs() error = %v, want %v", err, ErrJobsInProgress)
+		}
+		if failed.Status != "failed" || len(q.processQueue) != 0 {
+			t.Errorf("failed job was retried while others are in progress")
+		}
+	})
+}
+
+func TestValidateCompleteEvent(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    map[string]interface{}
+		wantErr bool
+	}{
+		{name: "valid", data: map[string]interface{}{"status": "completed", "total_files": 2.0, "embeddings_written": 14.0}},
+		{name: "no embeddings written", data: map[string]interface{}{"status": "completed", "total_files": 2.0, "embeddings_written": 0.0}},
+		{name: "wrong status", data: map[string]interface{}{"status": "failed", "total_files": 2.0, "embeddings_written": 14.0}, wantErr: true},
+		{name: "missing total_files", data: map[string]interface{}{"status": "completed", "embeddings_written": 14.0}, wantErr: true},
+		{name: "total_files mismatch", data: map[string]interface{}{"status": "completed", "total_files": 1.0, "embeddings_written": 14.0}, wantErr: true},
+		{name: "missing embeddings_written", data: map[string]interface{}{"status": "completed", "total_files": 2.0}, wantErr: true},
+		{name: "negative embeddings_written", data: map[string]interface{}{"status": "completed", "total_files": 2.0, "embeddings_written": -1.0}, wantErr: true},
+		{name: "fractional embeddings_written", data: map[string]interface{}{"status": "completed", "total_files": 2.0, "embeddings_written": 1.5}, wantErr: true},
+		{name: "embeddings_written as string", data: map[string]interface{}{"status": "completed", "total_files": 2.0, "embeddings_written": "14"}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateCompleteEvent(tt.data, 2)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("validateCompleteEvent() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+// testModels connects to the migrated database at TEST_DATABASE_URL, skipping the test when
+// it is unset
+func testModels(t *testing.T) *models.Models {
+	t.Helper()
+
+	url := os.Getenv("TEST_DATABASE_URL")
+	if url == "" {
+		t.Skip("TEST_DATABASE_URL not set")
+	}
+	pool, err := pgxpool.New(context.Background(), url)
+	if err != nil {
+		t.Fatalf("failed to connect to test database: %v", err)
+	}
+	t.Cleanup(pool.Close)
+
+	return &models.Models{
+		Users:          models.NewUserModel(pool),
+		Organizations:  models.NewOrganizationModel(pool),
+		KnowledgeBases: models.NewKnowledgeBaseModel(pool),
+	}
+}
+
+// createTestKnowledgeBase creates a knowledge base in a new organization. Its owner is deleted
+// with the organization when the test ends.
+func createTestKnowledgeBase(t *testing.T, m *models.Models) *models.KnowledgeBase {
+	t.Helper()
+
+	ctx := context.Background()
+	user, err := m.Users.Create(ctx, fmt.Sprintf("user-%d@example.com", id.Generate()), "Test User", "password123")
+	if err != nil {
+		t.Fatalf("failed to create user: %v", err)
+	}
+	t.Cleanup(func() { m.Users.DeleteAccount(context.Background(), user.ID) })
+
+	org, err := m.Organizations.Create(ctx, "Test Org", fmt.Sprintf("test-org-%d", id.Generate()), "", "", "", "", "", "")
+	if err != nil {
+		t.Fatalf("failed to create organization: %v", err)
+	}
+	if _, err := m.Organizations.AddMember(ctx, org.ID, user.ID, "owner", "active"); err != nil {
+		t.Fatalf("failed to add owner: %v", err)
+	}
+	kb, err := m.KnowledgeBases.Create(ctx, org.ID, "Docs", "", &user.ID)
+	if err != nil {
+		t.Fatalf("failed to create knowledge base: %v", err)
+	}
+	return kb
+}
+
+func TestCheckAllJobsCompletedWithoutEmbeddings(t *testing.T) {
+	m := testModels(t)
+	ctx := context.Background()
+
+	kb := createTestKnowledgeBase(t, m)
+	version, err := m.KnowledgeBases.CreateVersion(ctx, kb.ID, nil, nil, nil)
+	if err != nil {
+		t.Fatalf("failed to create version: %v", err)
+	}
+
+	// Every job reported success, but the service stored no embeddings for the version
+	channelID := fmt.Sprintf("training_%d_%d", kb.ID, version.ID)
+	q := &TrainingQueue{
+		jobs: []*TrainingJob{
+			{ID: "job_1", ChannelID: channelID, KnowledgeBaseID: kb.ID, VersionID: version.ID, Status: "completed"},
+			{ID: "job_2", ChannelID: channelID, KnowledgeBaseID: kb.ID, VersionID: version.ID, Status: "completed"},
+		},
+		activeJobs:   make(map[string]*TrainingJob),
+		processQueue: make(chan *TrainingJob, 1),
+		wsHub:        websocket.NewHub(),
+		models:       m,
+	}
+	q.checkAllJobsCompleted(channelID, version.ID, kb.ID)
+
+	got, err := m.KnowledgeBases.GetVersionByID(ctx, version.ID)
+	if err != nil {
+		t.Fatalf("GetVersionByID() error = %v", err)
+	}
+	if got.Status != "failed" {
+		t.Errorf("version status = %q, want failed", got.Status)
+	}
+	updated, err := m.KnowledgeBases.FindByID(ctx, kb.ID)
+	if err != nil {
+		t.Fatalf("FindByID() error = %v", err)
+	}
+	if updated.Status != "error" {
+		t.Errorf("knowledge base status = %q, want error", updated.Status)
+	}
+	if log, err := m.KnowledgeBases.GetVersionLog(ctx, version.ID); err != nil || !strings.Contains(log, "no embeddings were stored") {
+		t.Errorf("version log = %q, %v, want the failure reason", log, err)
+	}
+}
+
+func TestQueueSize(t *testing.T) {
+	tests := []struct {
+		value string
+		want  int
+	}{
+		{value: "", want: 100},
+		{value: "250", want: 250},
+		{value: "0", want: 1},
+		{value: "-3", want: 1},
+		{value: "lots", want: 100},
+	}
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			t.Setenv("TRAINING_QUEUE_SIZE", tt.value)
+			if got := queueSize(); got != tt.want {
+				t.Errorf("queueSize() = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestEnqueueTrainingJobOverflow(t *testing.T) {
+	// A queue without workers and room for two jobs
+	q := &TrainingQueue{
+		activeJobs:   make(map[string]*TrainingJob),
+		processQueue: make(chan *TrainingJob, 2),
+		wsHub:        websocket.NewHub(),
+		models:       &models.Models{},
+	}
+	files := func(n int) []*models.KnowledgeBaseFile {
+		list := make([]*models.KnowledgeBaseFile, n)
+		for i := range list {
+			list[i] = &models.KnowledgeBaseFile{ID: int64(i + 1)}
+		}
+		return list
+	}
+
+	first := &models.KnowledgeBaseVersion{ID: 1, KnowledgeBaseID: 1}
+	if err := q.EnqueueTrainingJob(context.Background(), first, files(1), "training_1_1"); err != nil {
+		t.Fatalf("EnqueueTrainingJob() error = %v", err)
+	}
+
+	before := runtime.NumGoroutine()
+	done := make(chan error, 1)
+	second := &models.KnowledgeBaseVersion{ID: 2, KnowledgeBaseID: 2}
+	go func() {
+		// Two jobs do not fit in the one remaining slot
+		done <- q.EnqueueTrainingJob(context.Background(), second, files(MaxFilesPerJob+1), "training_2_2")
+	}()
+
+	select {
+	case err := <-done:
+		if err != ErrQueueFull {
+			t.Fatalf("EnqueueTrainingJob() error = %v, want %v", err, ErrQueueFull)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("EnqueueTrainingJob() blocked on a full queue")
+	}
+
+	if n := len(q.processQueue); n != 1 {
+		t.Errorf("process queue has %d jobs, want only the first run's job", n)
+	}
+	if n := len(q.jobs); n != 1 {
+		t.Errorf("queue tracks %d jobs, want the rejected run's jobs dropped", n)
+	}
+	// Nothing is left waiting to send on the full channel
+	time.Sleep(50 * time.Millisecond)
+	if after := runtime.NumGoroutine(); after > before {
+		t.Errorf("goroutines grew from %d to %d after the rejected enqueue", before, after)
+	}
+}
+
+func TestTrainingRunsRecordLogs(t *testing.T) {
+	m := testModels(t)
+	ctx := context.Background()
+
+	// The stub training service fails the runs of versions in failVersions and otherwise stores
+	// one embedding per file before reporting completion
+	var failVersions sync.Map
+	stub := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			KnowledgeBaseID string `json:"knowledge_base_id"`
+			VersionID       string `json:"version_id"`
+			Files           []struct {
+				ID string `json:"id"`
+			} `json:"files"`
+		}
+		json.NewDecoder(r.Body).Decode(&req)
+		w.Header().Set("Content-Type", "text/event-stream")
+
+		if _, fail := failVersions.Load(req.VersionID); fail {
+			fmt.Fprintf(w, "data: {\"type\":\"error\",\"message\":\"parser crashed\",\"current_file_id\":%q}\n\n", req.Files[0].ID)
+			return
+		}
+		kbID, _ := strconv.ParseInt(req.KnowledgeBaseID, 10, 64)
+		versionID, _ := strconv.ParseInt(req.VersionID, 10, 64)
+		for i, file := range req.Files {
+			fileID, _ := strconv.ParseInt(file.ID, 10, 64)
+			if err := m.KnowledgeBases.StoreEmbedding(r.Context(), kbID, versionID, fileID, 0, "chunk", make([]float32, 1536), nil, false); err != nil {
+				t.Errorf("stub failed to store embedding: %v", err)
+			}
+			fmt.Fprintf(w, "data: {\"type\":\"progress\",\"message\":\"embedded file %d\",\"current_file_id\":%q,\"status\":\"completed\"}\n\n", i+1, file.ID)
+		}
+		fmt.Fprintf(w, "data: {\"type\":\"complete\",\"status\":\"completed\",\"total_files\":%d,\"embeddings_written\":%d}\n\n", len(req.Files), len(req.Files))
+	}))
+	defer stub.Close()
+	t.Setenv("TRAINING_SERVICE_URL", stub.URL)
+
+	hub := websocket.NewHub()
+	go hub.Run()
+	q := &TrainingQueue{
+		activeJobs:   make(map[string]*TrainingJob),
+		processQueue: make(chan *TrainingJob, 10),
+		wsHub:        hub,
+		models:       m,
+	}
+	go q.processJobs()
+	t.Cleanup(func() { close(q.processQueue) })
+
+	// train runs one training job for a new knowledge base and returns the version's log
+	train := func(t *testing.T, fail bool) string {
+		t.Helper()
+		kb := createTestKnowledgeBase(t, m)
+		file, err := m.KnowledgeBases.AddFile(ctx, kb.ID, "notes.txt", filepath.Join(t.TempDir(), "notes.txt"), 5, "text/plain", nil, limits.ForPlan(limits.PlanEnterprise))
+		if err != nil {
+			t.Fatalf("failed to add file: %v", err)
+		}
+		version, err := m.KnowledgeBases.CreateVersion(ctx, kb.ID, nil, nil, nil)
+		if err != nil {
+			t.Fatalf("failed to create version: %v", err)
+		}
+		if fail {
+			failVersions.Store(fmt.Sprint(version.ID), true)
+		}
+		channelID := fmt.Sprintf("training_%d_%d", kb.ID, version.ID)
+		if err := q.EnqueueTrainingJob(ctx, version, []*models.KnowledgeBaseFile{file}, channelID); err != nil {
+			t.Fatalf("EnqueueTrainingJob() error = %v", err)
+		}
+
+		// The run's summary is the last line written to the log
+		deadline := time.Now().Add(5 * time.Second)
+		for {
+			trainingLog, err := m.KnowledgeBases.GetVersionLog(ctx, version.ID)
+			if err != nil {
+				t.Fatalf("GetVersionLog() error = %v", err)
+			}
+			if strings.Contains(trainingLog, "Training finished") || strings.Contains(trainingLog, "Training failed") {
+				return trainingLog
+			}
+			if time.Now().After(deadline) {
+				t.Fatalf("run did not finish, log so far: %q", trainingLog)
+			}
+			time.Sleep(50 * time.Millisecond)
+		}
+	}
+
+	t.Run("completed run", func(t *testing.T) {
+		trainingLog := train(t, false)
+		for _, want := range []string{"Started with 1 files: notes.txt", "embedded file 1", "Completed", "Training finished: 1 jobs completed"} {
+			if !strings.Contains(trainingLog, want) {
+				t.Errorf("log is missing %q:\n%s", want, trainingLog)
+			}
+		}
+	})
+
+	t.Run("failed run", func(t *testing.T) {
+		trainingLog := train(t, true)
+		for _, want := range []string{"Started with 1 files: notes.txt", "error: parser crashed", "Failed: training error: parser crashed", "0 jobs completed, 1 failed"} {
+			if !strings.Contains(trainingLog, want) {
+				t.Errorf("log is missing %q:\n%s", want, trainingLog)
+			}
+		}
+	})
+}
+
+func TestTrainingRunRecordsFileErrors(t *testing.T) {
+	m := testModels(t)
+	ctx := context.Background()
+
+	// The stub training service embeds the first file and fails on the second, reporting why in
+	// the error event's file_details
+	stub := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			KnowledgeBaseID string `json:"knowledge_base_id"`
+			VersionID       string `json:"version_id"`
+			Files           []struct {
+				ID string `json:"id"`
+			} `json:"files"`
+		}
+		json.NewDecoder(r.Body).Decode(&req)
+		w.Header().Set("Content-Type", "text/event-stream")
+
+		kbID, _ := strconv.ParseInt(req.KnowledgeBaseID, 10, 64)
+		versionID, _ := strconv.ParseInt(req.VersionID, 10, 64)
+		fileID, _ := strconv.ParseInt(req.Files[0].ID, 10, 64)
+		if err := m.KnowledgeBases.StoreEmbedding(r.Context(), kbID, versionID, fileID, 0, "chunk", make([]float32, 1536), nil, false); err != nil {
+			t.Errorf("stub failed to store embedding: %v", err)
+		}
+		fmt.Fprintf(w, "data: {\"type\":\"progress\",\"message\":\"embedded file 1\",\"current_file_id\":%q,\"status\":\"completed\"}\n\n", req.Files[0].ID)
+		fmt.Fprintf(w, "data: {\"type\":\"error\",\"message\":\"1 file failed\",\"current_file_id\":%q,\"file_details\":[{\"file_id\":%q,\"error\":\"unsupported encoding\"}]}\n\n",
+			req.Files[1].ID, req.Files[1].ID)
+	}))
+	defer stub.Close()
+	t.Setenv("TRAINING_SERVICE_URL", stub.URL)
+
+	hub := websocket.NewHub()
+	go hub.Run()
+	q := &TrainingQueue{
+		activeJobs:   make(map[string]*TrainingJob),
+		processQueue: make(chan *TrainingJob, 10),
+		wsHub:        hub,
+		models:       m,
+	}
+	go q.processJobs()
+	t.Cleanup(func() { close(q.processQueue) })
+
+	kb := createTestKnowledgeBase(t, m)
+	var files []*models.KnowledgeBaseFile
+	for _, name := range []string{"good.txt", "bad.txt"} {
+		file, err := m.KnowledgeBases.AddFile(ctx, kb.ID, name, filepath.Join(t.TempDir(), name), 5, "text/plain", nil, limits.ForPlan(limits.PlanEnterprise))
+		if err != nil {
+			t.Fatalf("failed to add file: %v", err)
+		}
+		files = append(files, file)
+	}
+	version, err := m.KnowledgeBases.CreateVersion(ctx, kb.ID, nil, nil, nil)
+	if err != nil {
+		t.Fatalf("failed to create version: %v", err)
+	}
+	channelID := fmt.Sprintf("training_%d_%d", kb.ID, version.ID)
+	if err := q.EnqueueTrainingJob(ctx, version, files, channelID); err != nil {
+		t.Fatalf("EnqueueTrainingJob() error = %v", err)
+	}
+
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		trainingLog, err := m.KnowledgeBases.GetVersionLog(ctx, version.ID)
+		if err != nil {
+			t.Fatalf("GetVersionLog() error = %v", err)
+		}
+		if strings.Contains(trainingLog, "Training failed") {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("run did not fail, log so far: %q", trainingLog)
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+
+	trainingErrors, err := m.KnowledgeBases.GetTrainingErrors(ctx, version.ID)
+	if err != nil {
+		t.Fatalf("GetTrainingErrors() error = %v", err)
+	}
+	if len(trainingErrors) != 1 {
+		t.Fatalf("recorded %d training errors, want 1 for bad.txt: %+v", len(trainingErrors), trainingErrors)
+	}
+	got := trainingErrors[0]
+	if got.FileID != files[1].ID || got.FileName != "bad.txt" {
+		t.Errorf("error recorded for file %d %q, want %d bad.txt", got.FileID, got.FileName, files[1].ID)
+	}
+	if got.ErrorMessage != "unsupported encoding" {
+		t.Errorf("error message = %q, want the reason from file_details", got.ErrorMessage)
+	}
+	if want := channelID + "_job_1"; got.JobID != want || got.JobIndex != 1 {
+		t.Errorf("job = %s (%d), want %s (1)", got.JobID, got.JobIndex, want)
+	}
+}
+
+func TestCancelRuns(t *testing.T) {
+	hub := websocket.NewHub()
+	go hub.Run()
+
+	processingCancelled := false
+	job := func(id string, kbID, versionID int64, status string) *TrainingJob {
+		return &TrainingJob{
+			ID:              id,
+			ChannelID:       fmt.Sprintf("training_%d_%d", kbID, versionID),
+			KnowledgeBaseID: kbID,
+			VersionID:       versionID,
+			Status:          status,
+		}
+	}
+	processing := job("run1_job_2", 1, 10, "processing")
+	processing.cancel = func() { processingCancelled = true }
+	q := &TrainingQueue{
+		jobs: []*TrainingJob{
+			job("run1_job_1", 1, 10, "completed"),
+			processing,
+			job("run1_job_3", 1, 10, "pending"),
+			job("run2_job_1", 2, 20, "completed"),
+			job("other_job_1", 3, 30, "pending"),
+		},
+		activeJobs:   make(map[string]*TrainingJob),
+		processQueue: make(chan *TrainingJob, 1),
+		wsHub:        hub,
+	}
+	var finished []int64
+	var mu sync.Mutex
+	done := make(chan struct{}, 2)
+	q.OnRunFinished(func(kbID int64) {
+		mu.Lock()
+		finished = append(finished, kbID)
+		mu.Unlock()
+		done <- struct{}{}
+	})
+
+	runs := q.CancelRuns([]int64{1, 2})
+
+	if len(runs) != 1 || runs[0].KnowledgeBaseID != 1 || runs[0].VersionID != 10 || runs[0].CancelledJobs != 2 {
+		t.Fatalf("runs = %+v, want only version 10 with 2 cancelled jobs", runs)
+	}
+	want := map[string]string{
+		"run1_job_1":  "completed",
+		"run1_job_2":  "cancelled",
+		"run1_job_3":  "cancelled",
+		"run2_job_1":  "completed",
+		"other_job_1": "pending",
+	}
+	for _, j := range q.jobs {
+		if j.Status != want[j.ID] {
+			t.Errorf("job %s status = %s, want %s", j.ID, j.Status, want[j.ID])
+		}
+	}
+	if !processingCancelled {
+		t.Error("the processing job's training service call was not stopped")
+	}
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("run finished hook was not called")
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if len(finished) != 1 || finished[0] != 1 {
+		t.Errorf("run finished hook called for %v, want only knowledge base 1", finished)
+	}
+}
+
+func TestTrainingLogMaxSize(t *testing.T) {
+	tests := []struct {
+		value string
+		want  int
+	}{
+		{value: "", want: 1000000},
+		{value: "500", want: 500},
+		{value: "0", want: 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			t.Setenv("TRAINING_LOG_MAX_SIZE", tt.value)
+			if got := trainingLogMaxSize(); got != tt.want {
+				t.Errorf("trainingLogMaxSize() = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,214 @@
+package queue
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/aithen/go-api/internal/config"
+	"github.com/aithen/go-api/internal/id"
+	"github.com/aithen/go-api/internal/models"
+	"github.com/aithen/go-api/internal/websocket"
+)
+
+// UploadFailure describes a file that could not be stored in an upload batch
+type UploadFailure struct {
+	Name  string `json:"name"`
+	Error string `json:"error"`
+}
+
+// UploadBatch tracks an asynchronous multi-file upload for a knowledge base
+type UploadBatch struct {
+	ID              string                      `json:"id"`
+	KnowledgeBaseID int64                       `json:"-"`
+	ChannelID       string                      `json:"channel"`
+	Status          string                      `json:"status"` // processing, completed, partial_failure, failed
+	TotalFiles      int                         `json:"total_files"`
+	ProcessedFiles  int                         `json:"processed_files"`
+	Files           []*models.KnowledgeBaseFile `json:"files"`
+	Failures        []UploadFailure             `json:"failures"`
+	Error           string                      `json:"error,omitempty"` // why a failed batch stopped early
+	StartedAt       time.Time                   `json:"started_at"`
+	CompletedAt     *time.Time                  `json:"completed_at,omitempty"`
+}
+
+// UploadBatchTracker keeps the status of asynchronous upload batches in memory. Finished
+// batches are kept for UPLOAD_BATCH_RETENTION seconds (default 3600) so clients can still
+// fetch their final status, then dropped. A batch gets UPLOAD_BATCH_TIMEOUT seconds
+// (default 600) to store its files.
+type UploadBatchTracker struct {
+	batches   map[string]*UploadBatch
+	mu        sync.RWMutex
+	wsHub     *websocket.Hub
+	retention time.Duration
+	timeout   time.Duration
+}
+
+var (
+	uploadTrackerInstance *UploadBatchTracker
+	uploadTrackerOnce     sync.Once
+)
+
+// GetUploadBatchTracker returns the singleton upload batch tracker
+func GetUploadBatchTracker() *UploadBatchTracker {
+	uploadTrackerOnce.Do(func() {
+		uploadTrackerInstance = &UploadBatchTracker{
+			batches:   make(map[string]*UploadBatch),
+			wsHub:     websocket.GetHub(),
+			retention: time.Duration(config.GetEnvInt("UPLOAD_BATCH_RETENTION", 3600)) * time.Second,
+			timeout:   time.Duration(config.GetEnvInt("UPLOAD_BATCH_TIMEOUT", 600)) * time.Second,
+		}
+	})
+	return uploadTrackerInstance
+}
+
+// CreateBatch registers a new upload batch and returns it. Finished batches past their
+// retention are dropped at the same time.
+func (t *UploadBatchTracker) CreateBatch(kbID int64, totalFiles int) *UploadBatch {
+	batchID := fmt.Sprintf("%d", id.Generate())
+	batch := &UploadBatch{
+		ID:              batchID,
+		KnowledgeBaseID: kbID,
+		ChannelID:       fmt.Sprintf("upload_%d_%s", kbID, batchID),
+		Status:          "processing",
+		TotalFiles:      totalFiles,
+		Files:           make([]*models.KnowledgeBaseFile, 0, totalFiles),
+		Failures:        make([]UploadFailure, 0),
+		StartedAt:       time.Now(),
+	}
+
+	t.mu.Lock()
+	t.sweepLocked(time.Now())
+	t.batches[batchID] = batch
+	t.mu.Unlock()
+
+	return batch
+}
+
+// RecordSuccess records a stored file and broadcasts batch progress
+func (t *UploadBatchTracker) RecordSuccess(batch *UploadBatch, file *models.KnowledgeBaseFile) {
+	t.mu.Lock()
+	batch.Files = append(batch.Files, file)
+	batch.ProcessedFiles++
+	progress := t.progressLocked(batch, file.Name, "stored")
+	t.mu.Unlock()
+
+	t.wsHub.Broadcast(batch.ChannelID, "upload_progress", map[string]interface{}{
+		"batch_id": batch.ID,
+		"file":     file,
+	}, progress, nil)
+}
+
+// RecordFailure records a file that failed to store and broadcasts batch progress
+func (t *UploadBatchTracker) RecordFailure(batch *UploadBatch, name string, err error) {
+	t.mu.Lock()
+	batch.Failures = append(batch.Failures, UploadFailure{Name: name, Error: err.Error()})
+	batch.ProcessedFiles++
+	progress := t.progressLocked(batch, name, "failed")
+	t.mu.Unlock()
+
+	t.wsHub.Broadcast(batch.ChannelID, "upload_progress", map[string]interface{}{
+		"batch_id": batch.ID,
+		"file":     name,
+		"error":    err.Error(),
+	}, progress, nil)
+}
+
+// Finish marks the batch as done and broadcasts the final status
+func (t *UploadBatchTracker) Finish(batch *UploadBatch) {
+	t.mu.Lock()
+	now := time.Now()
+	batch.CompletedAt = &now
+	switch {
+	case len(batch.Failures) == 0:
+		batch.Status = "completed"
+	case len(batch.Files) == 0:
+		batch.Status = "failed"
+	default:
+		batch.Status = "partial_failure"
+	}
+	data := map[string]interface{}{
+		"batch_id":  batch.ID,
+		"status":    batch.Status,
+		"succeeded": len(batch.Files),
+		"failed":    len(batch.Failures),
+		"failures":  batch.Failures,
+	}
+	t.mu.Unlock()
+
+	t.wsHub.Broadcast(batch.ChannelID, "upload_completed", data, nil, nil)
+}
+
+// Context returns the context a batch's files are stored under. It expires after the batch
+// timeout, so a stalled database or disk cannot keep a batch processing indefinitely.
+func (t *UploadBatchTracker) Context() (context.Context, context.CancelFunc) {
+	return context.WithTimeout(context.Background(), t.timeout)
+}
+
+// Fail marks the batch as failed because it stopped before all files were processed, e.g. on
+// timeout, and broadcasts the final status
+func (t *UploadBatchTracker) Fail(batch *UploadBatch, err error) {
+	t.mu.Lock()
+	now := time.Now()
+	batch.CompletedAt = &now
+	batch.Status = "failed"
+	batch.Error = err.Error()
+	data := map[string]interface{}{
+		"batch_id":  batch.ID,
+		"status":    batch.Status,
+		"error":     batch.Error,
+		"succeeded": len(batch.Files),
+		"failed":    len(batch.Failures),
+		"failures":  batch.Failures,
+	}
+	t.mu.Unlock()
+
+	t.wsHub.Broadcast(batch.ChannelID, "upload_completed", data, nil, nil)
+}
+
+// GetBatch returns a copy of the batch with the given ID. Finished batches past their
+// retention are reported as missing.
+func (t *UploadBatchTracker) GetBatch(batchID string) (*UploadBatch, bool) {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	batch, ok := t.batches[batchID]
+	if !ok || t.expiredLocked(batch, time.Now()) {
+		return nil, false
+	}
+
+	snapshot := *batch
+	snapshot.Files = append([]*models.KnowledgeBaseFile(nil), batch.Files...)
+	snapshot.Failures = append([]UploadFailure(nil), batch.Failures...)
+	return &snapshot, true
+}
+
+// sweepLocked removes finished batches past their retention (caller must hold the write lock)
+func (t *UploadBatchTracker) sweepLocked(now time.Time) {
+	for batchID, batch := range t.batches {
+		if t.expiredLocked(batch, now) {
+			delete(t.batches, batchID)
+		}
+	}
+}
+
+// expiredLocked reports whether a finished batch has outlived its retention (caller must hold the lock)
+func (t *UploadBatchTracker) expiredLocked(batch *UploadBatch, now time.Time) bool {
+	return batch.CompletedAt != nil && now.Sub(*batch.CompletedAt) >= t.retention
+}
+
+// progressLocked builds a progress update for the batch (caller must hold the lock)
+func (t *UploadBatchTracker) progressLocked(batch *UploadBatch, fileName, status string) *websocket.Progress {
+	percentage := 100
+	if batch.TotalFiles > 0 {
+		percentage = batch.ProcessedFiles * 100 / batch.TotalFiles
+	}
+	return &websocket.Progress{
+		CurrentFile:     batch.ProcessedFiles,
+		TotalFiles:      batch.TotalFiles,
+		Percentage:      percentage,
+		Status:          status,
+		CurrentFileName: fileName,
+	}
+}
@@ -0,0 +1,46 @@
+package queue
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/aithen/go-api/internal/websocket"
+)
+
+func TestUploadBatchTimeoutFailsBatch(t *testing.T) {
+	tracker := &UploadBatchTracker{
+		batches:   make(map[string]*UploadBatch),
+		wsHub:     websocket.GetHub(),
+		retention: time.Hour,
+		timeout:   20 * time.Millisecond,
+	}
+	batch := tracker.CreateBatch(1, 2)
+
+	ctx, cancel := tracker.Context()
+	defer cancel()
+	select {
+	case <-ctx.Done():
+	case <-time.After(time.Second):
+		t.Fatal("batch context did not expire after the batch timeout")
+	}
+
+	timeoutErr := errors.New("upload batch timed out")
+	tracker.RecordFailure(batch, "a.txt", timeoutErr)
+	tracker.RecordFailure(batch, "b.txt", timeoutErr)
+	tracker.Fail(batch, timeoutErr)
+
+	got, ok := tracker.GetBatch(batch.ID)
+	if !ok {
+		t.Fatal("GetBatch() did not find the failed batch")
+	}
+	if got.Status != "failed" || got.Error != timeoutErr.Error() {
+		t.Errorf("batch status = %q, error = %q, want failed with %q", got.Status, got.Error, timeoutErr)
+	}
+	if got.CompletedAt == nil {
+		t.Error("failed batch has no completed_at")
+	}
+	if got.ProcessedFiles != 2 || len(got.Failures) != 2 {
+		t.Errorf("processed = %d, failures = %d, want every file accounted for", got.ProcessedFiles, len(got.Failures))
+	}
+}
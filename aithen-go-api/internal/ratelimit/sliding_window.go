@@ -0,0 +1,91 @@
+package ratelimit
+
+import (
+	"sync"
+	"time"
+)
+
+// Key identifies a rate-limited subject (e.g. a user or organization) and its request limit
+type Key struct {
+	Name  string
+	Limit int // Requests allowed per window; zero or negative disables the limit
+}
+
+// SlidingWindow limits requests per key over a sliding time window.
+// Request timestamps are kept in memory, so limits apply per process.
+type SlidingWindow struct {
+	window    time.Duration
+	hits      map[string][]time.Time
+	lastSweep time.Time
+	mu        sync.Mutex
+}
+
+// New creates a sliding window limiter over the given window
+func New(window time.Duration) *SlidingWindow {
+	return &SlidingWindow{
+		window:    window,
+		hits:      make(map[string][]time.Time),
+		lastSweep: time.Now(),
+	}
+}
+
+// Allow records a request against every key if all of them are under their limit.
+// Otherwise nothing is recorded and the time until the blocking key frees a slot is returned.
+func (sw *SlidingWindow) Allow(keys ...Key) (bool, time.Duration) {
+	sw.mu.Lock()
+	defer sw.mu.Unlock()
+
+	now := time.Now()
+	cutoff := now.Add(-sw.window)
+	sw.sweep(now, cutoff)
+
+	var retryAfter time.Duration
+	for _, key := range keys {
+		if key.Limit <= 0 {
+			continue
+		}
+		hits := prune(sw.hits[key.Name], cutoff)
+		sw.hits[key.Name] = hits
+		if len(hits) >= key.Limit {
+			// The oldest request inside the window that must expire to free a slot
+			wait := hits[len(hits)-key.Limit].Sub(cutoff)
+			if wait > retryAfter {
+				retryAfter = wait
+			}
+		}
+	}
+	if retryAfter > 0 {
+		return false, retryAfter
+	}
+
+	for _, key := range keys {
+		if key.Limit > 0 {
+			sw.hits[key.Name] = append(sw.hits[key.Name], now)
+		}
+	}
+	return true, 0
+}
+
+// sweep drops keys with no requests inside the window, at most once per window
+func (sw *SlidingWindow) sweep(now, cutoff time.Time) {
+	if now.Sub(sw.lastSweep) < sw.window {
+		return
+	}
+	for name, hits := range sw.hits {
+		if hits = prune(hits, cutoff); len(hits) == 0 {
+			delete(sw.hits, name)
+		} else {
+			sw.hits[name] = hits
+		}
+	}
+	sw.lastSweep = now
+}
+
+// prune removes timestamps at or before cutoff; hits are ordered oldest first
+func prune(hits []time.Time, cutoff time.Time) []time.Time {
+	i := 0
+	for i < len(hits) && !hits[i].After(cutoff) {
+		i++
+	}
+	return hits[i:]
+}
@@ -0,0 +1,88 @@
+package ratelimit
+
+import (
+	"testing"
+	"time"
+)
+
+func TestSlidingWindowAllow(t *testing.T) {
+	alice := Key{Name: "user:1", Limit: 2}
+	bob := Key{Name: "user:2", Limit: 2}
+	org := Key{Name: "org:1", Limit: 3}
+
+	type request struct {
+		keys []Key
+		want bool
+	}
+	tests := []struct {
+		name     string
+		requests []request
+	}{
+		{
+			name: "blocked user does not block another user",
+			requests: []request{
+				{keys: []Key{alice}, want: true},
+				{keys: []Key{alice}, want: true},
+				{keys: []Key{alice}, want: false},
+				{keys: []Key{bob}, want: true},
+				{keys: []Key{bob}, want: true},
+				{keys: []Key{bob}, want: false},
+			},
+		},
+		{
+			name: "shared organization key blocks every user",
+			requests: []request{
+				{keys: []Key{alice, org}, want: true},
+				{keys: []Key{alice, org}, want: true},
+				{keys: []Key{bob, org}, want: true},
+				{keys: []Key{bob, org}, want: false},
+			},
+		},
+		{
+			name: "rejected request is not counted",
+			requests: []request{
+				{keys: []Key{bob, {Name: "org:3", Limit: 1}}, want: true},
+				{keys: []Key{bob, {Name: "org:3", Limit: 1}}, want: false},
+				{keys: []Key{bob}, want: true},
+			},
+		},
+		{
+			name: "zero limit is not enforced",
+			requests: []request{
+				{keys: []Key{{Name: "user:3", Limit: 0}}, want: true},
+				{keys: []Key{{Name: "user:3", Limit: 0}}, want: true},
+				{keys: []Key{{Name: "user:3", Limit: 0}}, want: true},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sw := New(time.Minute)
+			for i, req := range tt.requests {
+				ok, retryAfter := sw.Allow(req.keys...)
+				if ok != req.want {
+					t.Fatalf("request %d: Allow() = %v, want %v", i, ok, req.want)
+				}
+				if !ok && (retryAfter <= 0 || retryAfter > time.Minute) {
+					t.Errorf("request %d: retry after %v, want within the window", i, retryAfter)
+				}
+			}
+		})
+	}
+}
+
+func TestSlidingWindowFreesSlotsAfterWindow(t *testing.T) {
+	sw := New(50 * time.Millisecond)
+	key := Key{Name: "user:1", Limit: 1}
+
+	if ok, _ := sw.Allow(key); !ok {
+		t.Fatal("first request was rejected")
+	}
+	if ok, _ := sw.Allow(key); ok {
+		t.Fatal("second request inside the window was allowed")
+	}
+	time.Sleep(60 * time.Millisecond)
+	if ok, _ := sw.Allow(key); !ok {
+		t.Fatal("request after the window was rejected")
+	}
+}
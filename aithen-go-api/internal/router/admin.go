@@ -0,0 +1,21 @@
+package router
+
+import (
+	"github.com/aithen/go-api/internal/handlers"
+	"github.com/aithen/go-api/internal/middleware"
+	"github.com/aithen/go-api/internal/websocket"
+	"github.com/gin-gonic/gin"
+)
+
+// SetupAdminRoutes sets up operator/debugging routes. They require authentication and a user
+// listed in ADMIN_USER_IDS.
+func SetupAdminRoutes(api *gin.RouterGroup, hub *websocket.Hub) {
+	admin := api.Group("/admin", middleware.RequireOperator())
+	{
+		admin.GET("/ws", websocket.HandleChannelStats(hub))                       // Active WebSocket channels and client counts
+		admin.DELETE("/cache/personalities", handlers.InvalidatePersonalityCache) // Clear cached AI personalities
+		admin.POST("/uploads/purge-orphans", handlers.PurgeOrphanedUploads)       // Remove uploaded files with no database record
+		admin.GET("/maintenance", handlers.GetMaintenanceMode)                    // Whether writes are frozen
+		admin.PUT("/maintenance", handlers.SetMaintenanceMode)                    // Freeze or resume writes
+	}
+}
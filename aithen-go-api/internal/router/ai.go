@@ -2,16 +2,24 @@ package router
 
 import (
 	"github.com/aithen/go-api/internal/handlers"
+	"github.com/aithen/go-api/internal/middleware"
 	"github.com/gin-gonic/gin"
 )
 
-// SetupAIRoutes sets up AI-related routes (chat, personalities, etc.)
+// SetupAIRoutes sets up AI-related routes (chat, embeddings, personalities, etc.)
 func SetupAIRoutes(api *gin.RouterGroup) {
 	ai := api.Group("/ai")
 	{
-		// Chat endpoints
-		ai.POST("/chat", handlers.Chat)
-		ai.POST("/chat/stream", handlers.ChatStreamImproved)
+		// Chat endpoints (rate limited per user and organization)
+		chatLimit := middleware.AIChatRateLimit()
+		ai.POST("/chat", chatLimit, handlers.Chat)
+		ai.POST("/chat/stream", chatLimit, handlers.ChatStreamImproved)
+
+		// Embedding endpoint (nothing is persisted)
+		ai.POST("/embed", handlers.Embed)
+
+		// Models chat requests may use
+		ai.GET("/models", handlers.GetModels)
 
 		// Personality endpoints
 		ai.GET("/personalities", handlers.GetPersonalities)
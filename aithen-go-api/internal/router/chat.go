@@ -2,6 +2,7 @@ package router
 
 import (
 	"github.com/aithen/go-api/internal/handlers"
+	"github.com/aithen/go-api/internal/middleware"
 	"github.com/gin-gonic/gin"
 )
 
@@ -9,12 +10,21 @@ import (
 func SetupChatRoutes(api *gin.RouterGroup) {
 	chats := api.Group("/chats")
 	{
-		chats.POST("", handlers.CreateChat)              // Create new chat
-		chats.GET("", handlers.GetChats)                 // Get all chats for user
-		chats.GET("/:id", handlers.GetChat)              // Get chat by ID with messages
-		chats.PUT("/:id", handlers.UpdateChat)           // Update chat title
-		chats.DELETE("/:id", handlers.DeleteChat)        // Delete chat
-		chats.POST("/:id/messages", handlers.AddMessage) // Add message to chat
+		chats.POST("", handlers.CreateChat) // Create new chat
+		chats.GET("", handlers.GetChats)    // Get all chats for user
+
+		// Routes for one chat load it with ResolveChat, which answers 404 for other users' chats
+		chat := chats.Group("/:id", handlers.ResolveChat())
+		chat.GET("", handlers.GetChat)                                  // Get chat by ID with messages
+		chat.PUT("", handlers.UpdateChat)                               // Update chat title
+		chat.DELETE("", handlers.DeleteChat)                            // Delete chat
+		chat.POST("/messages", handlers.AddMessage)                     // Add message to chat
+		chat.GET("/messages/:message_id", handlers.GetMessage)          // Get one message of a chat
+		chat.POST("/messages/bulk-delete", handlers.BulkDeleteMessages) // Delete several messages
+		chat.POST("/branch", handlers.BranchChat)                       // Fork chat at a message
+		chat.POST("/copy", handlers.CopyChat)                           // Duplicate a whole chat
+
+		// Regenerating calls the AI service, so it shares the AI chat rate limit
+		chat.POST("/regenerate", middleware.AIChatRateLimit(), handlers.RegenerateMessage)
 	}
 }
-
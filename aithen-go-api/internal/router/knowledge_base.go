@@ -2,25 +2,70 @@ package router
 
 import (
 	"github.com/aithen/go-api/internal/handlers"
+	"github.com/aithen/go-api/internal/middleware"
+	"github.com/aithen/go-api/internal/models"
 	"github.com/gin-gonic/gin"
 )
 
 // SetupKnowledgeBaseRoutes sets up knowledge base management routes
 func SetupKnowledgeBaseRoutes(api *gin.RouterGroup) {
 	// Knowledge bases scoped to organizations
-	// Note: Using :slug to match existing /api/orgs/:slug route pattern
-	kb := api.Group("/orgs/:slug/knowledge-bases")
-	{
-		kb.GET("", handlers.GetKnowledgeBases)
-		kb.POST("", handlers.CreateKnowledgeBase)
-		kb.GET("/:id", handlers.GetKnowledgeBase)
-		kb.PUT("/:id", handlers.UpdateKnowledgeBase)
-		kb.DELETE("/:id", handlers.DeleteKnowledgeBase)
-		kb.GET("/:id/files", handlers.GetKnowledgeBaseFiles)
-		kb.POST("/:id/files", handlers.UploadKnowledgeBaseFiles)
-		kb.DELETE("/:id/files/:file_id", handlers.DeleteKnowledgeBaseFile)
-		kb.POST("/:id/train", handlers.TrainKnowledgeBase)
-		kb.GET("/:id/versions", handlers.GetKnowledgeBaseVersions)
-		kb.DELETE("/:id/versions/:version_id", handlers.DeleteKnowledgeBaseVersion)
+	// Note: Using :slug to match existing /api/orgs/:slug route pattern. The routes are also
+	// served under /org for requests naming the organization by X-Org-Slug or subdomain.
+	for _, prefix := range []string{"/orgs/:slug", "/org"} {
+		kb := api.Group(prefix + "/knowledge-bases")
+		{
+			kb.GET("", handlers.GetKnowledgeBases)
+			kb.POST("", handlers.CreateKnowledgeBase)
+			kb.POST("/import", handlers.ImportKnowledgeBase)
+			middleware.AllowUploadBody(kb.BasePath() + "/import")
+			kb.GET("/search", handlers.SearchKnowledgeBases)
+			kb.GET("/stats", handlers.GetKnowledgeBaseStats)
+			// Checks write permission on each knowledge base in the batch
+			kb.POST("/train-batch", handlers.TrainKnowledgeBasesBatch)
+			// Owners and admins only
+			kb.POST("/cancel-all", handlers.CancelAllTraining)
+			// Per-knowledge-base routes are guarded by kb_permissions (org owners/admins bypass)
+			read := handlers.RequireKBPermission(models.KBPermissionRead)
+			write := handlers.RequireKBPermission(models.KBPermissionWrite)
+			admin := handlers.RequireKBPermission(models.KBPermissionAdmin)
+
+			kb.GET("/:id", read, handlers.GetKnowledgeBase)
+			kb.PUT("/:id", write, handlers.UpdateKnowledgeBase)
+			kb.PATCH("/:id", write, handlers.UpdateKnowledgeBase)
+			kb.DELETE("/:id", admin, handlers.DeleteKnowledgeBase)
+			kb.POST("/:id/clone", read, handlers.CloneKnowledgeBase)
+			// Moving checks owner/admin membership in both organizations instead of kb_permissions
+			kb.POST("/:id/move", handlers.MoveKnowledgeBase)
+			// Owners and admins only
+			kb.POST("/:id/reset-status", handlers.ResetKnowledgeBaseStatus)
+			kb.GET("/:id/files", read, handlers.GetKnowledgeBaseFiles)
+			kb.POST("/:id/files", write, handlers.UploadKnowledgeBaseFiles)
+			middleware.AllowUploadBody(kb.BasePath() + "/:id/files")
+			kb.DELETE("/:id/files", write, handlers.DeleteAllKnowledgeBaseFiles)
+			kb.DELETE("/:id/files/:file_id", write, handlers.DeleteKnowledgeBaseFile)
+			kb.POST("/:id/files/:file_id/preview-chunks", read, handlers.PreviewFileChunks)
+			kb.GET("/:id/files/batches/:batch_id", read, handlers.GetUploadBatch)
+			kb.POST("/:id/train", write, handlers.TrainKnowledgeBase)
+			kb.POST("/:id/reembed", write, handlers.ReembedKnowledgeBase)
+			kb.GET("/:id/train-estimate", read, handlers.GetTrainingEstimate)
+			kb.POST("/:id/test-query", read, handlers.TestKnowledgeBaseQuery)
+			kb.GET("/:id/versions", read, handlers.GetKnowledgeBaseVersions)
+			kb.GET("/:id/training-history", read, handlers.GetTrainingHistory)
+			kb.DELETE("/:id/versions/:version_id", write, handlers.DeleteKnowledgeBaseVersion)
+			kb.DELETE("/:id/versions/:version_id/chunks/:chunk_id", write, handlers.DeleteKnowledgeBaseChunk)
+			kb.GET("/:id/versions/:version_id/log", read, handlers.GetTrainingLog)
+			kb.GET("/:id/versions/:version_id/errors", read, handlers.GetTrainingErrors)
+			kb.GET("/:id/versions/:version_id/quality-breakdown", read, handlers.GetVersionQualityBreakdown)
+			kb.POST("/:id/versions/:version_id/retry-failed", write, handlers.RetryFailedTrainingJobs)
+			kb.GET("/:id/export", admin, handlers.ExportKnowledgeBase)
+
+			// Permission management
+			// The caller's own effective permission, for any member
+			kb.GET("/:id/permissions/me", read, handlers.GetMyKBPermissions)
+			kb.GET("/:id/permissions", admin, handlers.GetKBPermissions)
+			kb.PUT("/:id/permissions/:user_id", admin, handlers.GrantKBPermission)
+			kb.DELETE("/:id/permissions/:user_id", admin, handlers.RevokeKBPermission)
+		}
 	}
 }
@@ -2,6 +2,7 @@ package router
 
 import (
 	"github.com/aithen/go-api/internal/handlers"
+	"github.com/aithen/go-api/internal/middleware"
 	"github.com/gin-gonic/gin"
 )
 
@@ -9,19 +10,34 @@ import (
 func SetupPublicOrganizationRoutes(r *gin.Engine) {
 	// Public organization endpoint (no auth required)
 	r.GET("/api/orgs/:slug", handlers.GetPublicOrganization)
+	// Slug availability for registration forms, rate limited per client IP
+	r.GET("/api/orgs/slug-available", middleware.SlugCheckRateLimit(), handlers.CheckSlugAvailability)
 }
 
-// SetupOrganizationRoutes sets up organization management routes (require authentication)
-// This can be expanded in the future for organization CRUD operations
+// SetupOrganizationRoutes sets up organization management routes (require authentication).
+// They are served under /orgs/:slug, and under /org for requests that name the organization
+// with the X-Org-Slug header or a subdomain instead (see middleware.ResolveOrganization).
 func SetupOrganizationRoutes(api *gin.RouterGroup) {
-	// Future organization management routes can be added here
-	// Example:
-	// orgs := api.Group("/orgs")
-	// {
-	//     orgs.GET("", handlers.GetOrganizations)
-	//     orgs.GET("/:id", handlers.GetOrganization)
-	//     orgs.PUT("/:id", handlers.UpdateOrganization)
-	//     orgs.DELETE("/:id", handlers.DeleteOrganization)
-	// }
-}
+	for _, prefix := range []string{"/orgs/:slug", "/org"} {
+		orgs := api.Group(prefix)
+		{
+			orgs.GET("/me", handlers.GetMyOrganization)           // Full organization and caller's membership
+			orgs.GET("/storage", handlers.GetOrganizationStorage) // Storage usage across knowledge bases
+			orgs.GET("/files", handlers.GetOrganizationFiles)     // Files across knowledge bases, filterable by kb_id, status and q
+
+			// Owners and admins add existing users by email; admins may only add members and viewers
+			orgs.POST("/members", handlers.AddOrganizationMember)
+			orgs.GET("/default-member-role", handlers.GetOrganizationDefaultMemberRole)
+			orgs.PUT("/default-member-role", handlers.SetOrganizationDefaultMemberRole) // Owners only
 
+			// Owners may set any role; admins may only switch members between member and viewer
+			orgs.PUT("/members/:user_id/role", handlers.UpdateOrganizationMemberRole)
+			orgs.POST("/leave", handlers.LeaveOrganization) // The sole owner must transfer ownership first
+
+			// Personalities, with the organization's default for chats that do not name one
+			orgs.GET("/personalities", handlers.GetOrganizationPersonalities)
+			orgs.GET("/default-personality", handlers.GetOrganizationDefaultPersonality)
+			orgs.PUT("/default-personality", handlers.SetOrganizationDefaultPersonality) // Owners and admins only
+		}
+	}
+}
@@ -2,6 +2,7 @@ package router
 
 import (
 	"github.com/aithen/go-api/internal/handlers"
+	"github.com/aithen/go-api/internal/middleware"
 	"github.com/aithen/go-api/internal/websocket"
 	"github.com/gin-gonic/gin"
 )
@@ -9,6 +10,12 @@ import (
 // SetupRoutes is the main entry point for setting up all routes
 // It organizes routes by domain and applies appropriate middleware
 func SetupRoutes(r *gin.Engine) {
+	// Reject writes while maintenance mode is on; registered first so it covers every route
+	r.Use(middleware.MaintenanceMode())
+
+	// Cap request bodies so oversized JSON cannot exhaust memory while binding
+	r.Use(middleware.BodySizeLimit())
+
 	// Public routes (no authentication required)
 	setupPublicRoutes(r)
 
@@ -19,12 +26,15 @@ func SetupRoutes(r *gin.Engine) {
 	SetupAuthRoutes(api)
 
 	// Setup WebSocket routes before middleware (they handle their own auth)
-	SetupWebSocketRoutes(api)
+	hub := SetupWebSocketRoutes(api)
 
 	// Apply authentication middleware to all API routes
 	// The middleware will skip auth for /api/auth/login, /api/auth/register, and /api/ws
 	ApplyAuthMiddleware(api)
 
+	// Resolve the organization from X-Org-Slug, the subdomain or the :slug path param
+	api.Use(middleware.ResolveOrganization())
+
 	// All routes below require authentication
 	{
 		// Protected authentication routes
@@ -44,6 +54,9 @@ func SetupRoutes(r *gin.Engine) {
 
 		// Knowledge base management routes
 		SetupKnowledgeBaseRoutes(api)
+
+		// Admin/debugging routes
+		SetupAdminRoutes(api, hub)
 	}
 }
 
@@ -52,14 +65,22 @@ func setupPublicRoutes(r *gin.Engine) {
 	// Health check
 	r.GET("/ping", handlers.Ping)
 
+	// Readiness check (database and AI service circuit breaker)
+	r.GET("/readyz", handlers.Readyz)
+
+	// Build information
+	r.GET("/api/version", handlers.GetVersion)
+
 	// Public organization routes
 	SetupPublicOrganizationRoutes(r)
 }
 
-// SetupWebSocketRoutes sets up WebSocket routes
-func SetupWebSocketRoutes(api *gin.RouterGroup) {
-	hub := websocket.NewHub()
-	go hub.Run()
+// SetupWebSocketRoutes sets up WebSocket routes and returns the hub clients connect to
+// The shared singleton hub is used so broadcasts from the training queue reach connected clients
+func SetupWebSocketRoutes(api *gin.RouterGroup) *websocket.Hub {
+	hub := websocket.GetHub()
 
 	api.GET("/ws", websocket.HandleWebSocket(hub))
+
+	return hub
 }
@@ -0,0 +1,21 @@
+package router
+
+import (
+	"testing"
+
+	"github.com/aithen/go-api/internal/websocket"
+	"github.com/gin-gonic/gin"
+)
+
+func TestWebSocketRoutesUseSharedHub(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	// The training queue and upload tracker broadcast on websocket.GetHub, so clients must connect to it
+	hub := SetupWebSocketRoutes(gin.New().Group("/api"))
+	if hub != websocket.GetHub() {
+		t.Fatal("SetupWebSocketRoutes() hub is not websocket.GetHub()")
+	}
+	if again := SetupWebSocketRoutes(gin.New().Group("/api")); again != hub {
+		t.Error("SetupWebSocketRoutes() created a new hub on a second call")
+	}
+}
@@ -14,5 +14,14 @@ func SetupUserRoutes(api *gin.RouterGroup) {
 		users.PUT("/:id", handlers.UpdateUser)
 		users.DELETE("/:id", handlers.DeleteUser)
 	}
+
+	// Self-service account deletion
+	api.DELETE("/me", handlers.DeleteMe)
+
+	// Organizations the current user belongs to, including pending memberships
+	api.GET("/me/memberships", handlers.GetMyMemberships)
+
+	// Aggregate chat usage for dashboards
+	api.GET("/me/stats", handlers.GetMyStats)
 }
 
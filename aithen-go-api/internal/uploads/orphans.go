@@ -0,0 +1,134 @@
+package uploads
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+	"log"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/aithen/go-api/internal/config"
+)
+
+// KnownPathsFunc returns the stored paths of every uploaded file that has a database record
+type KnownPathsFunc func(ctx context.Context) ([]string, error)
+
+// OrphanSweepResult summarizes one orphaned upload sweep
+type OrphanSweepResult struct {
+	Scanned int      `json:"scanned"`
+	Removed []string `json:"removed"`
+	Failed  int      `json:"failed"`
+}
+
+// orphanSweepMu keeps the periodic and manually triggered sweeps from overlapping
+var orphanSweepMu sync.Mutex
+
+// OrphanSweepInterval returns how often orphaned uploads are purged
+// (UPLOAD_ORPHAN_SWEEP_INTERVAL in seconds, default 3600, 0 disables the periodic sweep)
+func OrphanSweepInterval() time.Duration {
+	return time.Duration(config.GetEnvInt("UPLOAD_ORPHAN_SWEEP_INTERVAL", 3600)) * time.Second
+}
+
+// OrphanGracePeriod returns how old a file without a database record must be before it is
+// removed (UPLOAD_ORPHAN_GRACE_PERIOD in seconds, default 86400). The grace period keeps
+// uploads that are still being written, and not yet recorded, from being purged.
+func OrphanGracePeriod() time.Duration {
+	return time.Duration(config.GetEnvInt("UPLOAD_ORPHAN_GRACE_PERIOD", 86400)) * time.Second
+}
+
+// PurgeOrphans removes files under the knowledge base upload directory that have no
+// database record and were last modified more than grace ago
+func PurgeOrphans(ctx context.Context, known KnownPathsFunc, grace time.Duration) (*OrphanSweepResult, error) {
+	orphanSweepMu.Lock()
+	defer orphanSweepMu.Unlock()
+
+	paths, err := known(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load stored file paths: %w", err)
+	}
+
+	// Stored paths may be relative to the working directory or absolute
+	recorded := make(map[string]struct{}, len(paths))
+	for _, p := range paths {
+		if abs, err := filepath.Abs(p); err == nil {
+			recorded[abs] = struct{}{}
+		}
+	}
+
+	result := &OrphanSweepResult{Removed: []string{}}
+	root := filepath.Join(BaseDir(), "knowledge_bases")
+	cutoff := time.Now().Add(-grace)
+
+	err = filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if os.IsNotExist(err) && path == root {
+				return filepath.SkipDir
+			}
+			return err
+		}
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return ctxErr
+		}
+		if d.IsDir() {
+			return nil
+		}
+
+		result.Scanned++
+
+		abs, err := filepath.Abs(path)
+		if err != nil {
+			return nil
+		}
+		if _, ok := recorded[abs]; ok {
+			return nil
+		}
+
+		info, err := d.Info()
+		if err != nil || info.ModTime().After(cutoff) {
+			return nil
+		}
+
+		if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+			log.Printf("Warning: Failed to remove orphaned upload %s: %v", path, err)
+			result.Failed++
+			return nil
+		}
+		log.Printf("Removed orphaned upload %s (%d bytes, modified %s)", path, info.Size(), info.ModTime().Format(time.RFC3339))
+		result.Removed = append(result.Removed, path)
+		return nil
+	})
+	if err != nil {
+		return result, fmt.Errorf("failed to scan upload directory %s: %w", root, err)
+	}
+
+	return result, nil
+}
+
+// StartOrphanSweeper purges orphaned uploads every OrphanSweepInterval in the background.
+// It does nothing when the interval is 0.
+func StartOrphanSweeper(known KnownPathsFunc) {
+	interval := OrphanSweepInterval()
+	if interval <= 0 {
+		log.Println("Orphaned upload sweeper disabled")
+		return
+	}
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for range ticker.C {
+			result, err := PurgeOrphans(context.Background(), known, OrphanGracePeriod())
+			if err != nil {
+				log.Printf("Warning: Orphaned upload sweep failed: %v", err)
+				continue
+			}
+			if len(result.Removed) > 0 || result.Failed > 0 {
+				log.Printf("Orphaned upload sweep: scanned=%d removed=%d failed=%d", result.Scanned, len(result.Removed), result.Failed)
+			}
+		}
+	}()
+}
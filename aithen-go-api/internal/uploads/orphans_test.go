@@ -0,0 +1,89 @@
+package uploads
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestPurgeOrphans(t *testing.T) {
+	t.Setenv("UPLOAD_DIR", t.TempDir())
+
+	dir := KnowledgeBaseDir(1)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		t.Fatalf("failed to create upload directory: %v", err)
+	}
+	old := time.Now().Add(-2 * time.Hour)
+	writeFile := func(name string, modified time.Time) string {
+		t.Helper()
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(name), 0644); err != nil {
+			t.Fatalf("failed to write %s: %v", name, err)
+		}
+		if err := os.Chtimes(path, modified, modified); err != nil {
+			t.Fatalf("failed to set %s modification time: %v", name, err)
+		}
+		return path
+	}
+	recorded := writeFile("recorded.txt", old)
+	orphan := writeFile("orphan.txt", old)
+	recent := writeFile("recent.txt", time.Now())
+
+	known := func(ctx context.Context) ([]string, error) { return []string{recorded}, nil }
+	result, err := PurgeOrphans(context.Background(), known, time.Hour)
+	if err != nil {
+		t.Fatalf("PurgeOrphans() error = %v", err)
+	}
+
+	if result.Scanned != 3 || result.Failed != 0 {
+		t.Errorf("PurgeOrphans() scanned %d, failed %d, want 3 scanned and none failed", result.Scanned, result.Failed)
+	}
+	if len(result.Removed) != 1 || result.Removed[0] != orphan {
+		t.Errorf("PurgeOrphans() removed %v, want [%s]", result.Removed, orphan)
+	}
+	if _, err := os.Stat(orphan); !os.IsNotExist(err) {
+		t.Errorf("orphan still exists (stat error %v)", err)
+	}
+	for _, path := range []string{recorded, recent} {
+		if _, err := os.Stat(path); err != nil {
+			t.Errorf("%s was removed: %v", filepath.Base(path), err)
+		}
+	}
+}
+
+func TestPurgeOrphansWithoutUploads(t *testing.T) {
+	t.Setenv("UPLOAD_DIR", filepath.Join(t.TempDir(), "missing"))
+
+	known := func(ctx context.Context) ([]string, error) { return nil, nil }
+	result, err := PurgeOrphans(context.Background(), known, 0)
+	if err != nil {
+		t.Fatalf("PurgeOrphans() error = %v", err)
+	}
+	if result.Scanned != 0 || len(result.Removed) != 0 {
+		t.Errorf("PurgeOrphans() = %+v, want nothing scanned", result)
+	}
+}
+
+func TestPurgeOrphansKnownPathsError(t *testing.T) {
+	t.Setenv("UPLOAD_DIR", t.TempDir())
+	dir := KnowledgeBaseDir(1)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		t.Fatalf("failed to create upload directory: %v", err)
+	}
+	path := filepath.Join(dir, "file.txt")
+	if err := os.WriteFile(path, []byte("data"), 0644); err != nil {
+		t.Fatalf("failed to write file: %v", err)
+	}
+
+	// Without the stored paths every file would look orphaned, so nothing may be removed
+	known := func(ctx context.Context) ([]string, error) { return nil, errors.New("database down") }
+	if _, err := PurgeOrphans(context.Background(), known, 0); err == nil {
+		t.Error("PurgeOrphans() error = nil, want the lookup error")
+	}
+	if _, err := os.Stat(path); err != nil {
+		t.Errorf("file was removed after a failed lookup: %v", err)
+	}
+}
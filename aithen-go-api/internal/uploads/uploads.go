@@ -0,0 +1,52 @@
+package uploads
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/aithen/go-api/internal/config"
+)
+
+// BaseDir returns the directory uploaded files are stored under (UPLOAD_DIR, default "uploads")
+func BaseDir() string {
+	if dir := config.GetEnv("UPLOAD_DIR"); dir != "" {
+		return dir
+	}
+	return "uploads"
+}
+
+// KnowledgeBaseDir returns the directory holding a knowledge base's files
+func KnowledgeBaseDir(kbID int64) string {
+	return filepath.Join(BaseDir(), "knowledge_bases", fmt.Sprintf("%d", kbID))
+}
+
+// CheckWritable creates the upload base directory if needed and verifies files can be
+// written to it by creating and removing a probe file
+func CheckWritable() error {
+	dir := BaseDir()
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return fmt.Errorf("failed to create upload directory %s: %w", dir, err)
+	}
+
+	probe, err := os.CreateTemp(dir, ".write-probe-*")
+	if err != nil {
+		return fmt.Errorf("upload directory %s is not writable: %w", dir, err)
+	}
+	name := probe.Name()
+
+	_, writeErr := probe.WriteString("ok")
+	closeErr := probe.Close()
+	removeErr := os.Remove(name)
+	if writeErr != nil {
+		return fmt.Errorf("upload directory %s is not writable: %w", dir, writeErr)
+	}
+	if closeErr != nil {
+		return fmt.Errorf("upload directory %s is not writable: %w", dir, closeErr)
+	}
+	if removeErr != nil {
+		return fmt.Errorf("failed to remove probe file from upload directory %s: %w", dir, removeErr)
+	}
+
+	return nil
+}
@@ -0,0 +1,53 @@
+package uploads
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestCheckWritable(t *testing.T) {
+	t.Run("creates a missing directory", func(t *testing.T) {
+		dir := filepath.Join(t.TempDir(), "nested", "uploads")
+		t.Setenv("UPLOAD_DIR", dir)
+
+		if err := CheckWritable(); err != nil {
+			t.Fatalf("CheckWritable() error = %v", err)
+		}
+		entries, err := os.ReadDir(dir)
+		if err != nil {
+			t.Fatalf("upload directory was not created: %v", err)
+		}
+		if len(entries) != 0 {
+			t.Errorf("upload directory has %d entries, want the probe file removed", len(entries))
+		}
+	})
+
+	t.Run("read-only directory", func(t *testing.T) {
+		if os.Geteuid() == 0 {
+			t.Skip("root can write to read-only directories")
+		}
+		dir := t.TempDir()
+		if err := os.Chmod(dir, 0555); err != nil {
+			t.Fatalf("failed to make directory read-only: %v", err)
+		}
+		t.Cleanup(func() { os.Chmod(dir, 0755) })
+		t.Setenv("UPLOAD_DIR", dir)
+
+		if err := CheckWritable(); err == nil {
+			t.Error("CheckWritable() error = nil, want an error for a read-only directory")
+		}
+	})
+
+	t.Run("path is a file", func(t *testing.T) {
+		file := filepath.Join(t.TempDir(), "uploads")
+		if err := os.WriteFile(file, nil, 0644); err != nil {
+			t.Fatalf("failed to write file: %v", err)
+		}
+		t.Setenv("UPLOAD_DIR", filepath.Join(file, "knowledge_bases"))
+
+		if err := CheckWritable(); err == nil {
+			t.Error("CheckWritable() error = nil, want an error when the directory cannot be created")
+		}
+	})
+}
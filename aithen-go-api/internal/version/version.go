@@ -0,0 +1,49 @@
+package version
+
+import (
+	"runtime"
+	"runtime/debug"
+)
+
+// Build information, injected at build time with:
+//
+//	go build -ldflags "-X github.com/aithen/go-api/internal/version.Version=1.2.3 \
+//	  -X github.com/aithen/go-api/internal/version.Commit=$(git rev-parse HEAD) \
+//	  -X github.com/aithen/go-api/internal/version.BuildTime=$(date -u +%Y-%m-%dT%H:%M:%SZ)"
+var (
+	Version   = "dev"
+	Commit    = "unknown"
+	BuildTime = "unknown"
+)
+
+// Info describes the running build
+type Info struct {
+	Version   string `json:"version"`
+	Commit    string `json:"commit"`
+	BuildTime string `json:"build_time"`
+	GoVersion string `json:"go_version"`
+}
+
+// Get returns the build information. When the commit or build time were not injected,
+// the VCS details recorded by the Go toolchain are used if available.
+func Get() Info {
+	info := Info{
+		Version:   Version,
+		Commit:    Commit,
+		BuildTime: BuildTime,
+		GoVersion: runtime.Version(),
+	}
+
+	if buildInfo, ok := debug.ReadBuildInfo(); ok {
+		for _, setting := range buildInfo.Settings {
+			switch {
+			case setting.Key == "vcs.revision" && info.Commit == "unknown":
+				info.Commit = setting.Value
+			case setting.Key == "vcs.time" && info.BuildTime == "unknown":
+				info.BuildTime = setting.Value
+			}
+		}
+	}
+
+	return info
+}
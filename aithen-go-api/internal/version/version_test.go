@@ -0,0 +1,28 @@
+package version
+
+import (
+	"runtime"
+	"testing"
+)
+
+func TestGetUsesInjectedValues(t *testing.T) {
+	saved := [3]string{Version, Commit, BuildTime}
+	t.Cleanup(func() { Version, Commit, BuildTime = saved[0], saved[1], saved[2] })
+	Version, Commit, BuildTime = "1.2.3", "abc123", "2026-01-02T03:04:05Z"
+
+	want := Info{Version: "1.2.3", Commit: "abc123", BuildTime: "2026-01-02T03:04:05Z", GoVersion: runtime.Version()}
+	if got := Get(); got != want {
+		t.Errorf("Get() = %+v, want %+v", got, want)
+	}
+}
+
+func TestGetDefaults(t *testing.T) {
+	got := Get()
+	if got.Version != "dev" {
+		t.Errorf("Version = %q, want dev when not injected", got.Version)
+	}
+	// Without -ldflags the commit and build time come from the toolchain's VCS stamp, if any
+	if got.Commit == "" || got.BuildTime == "" {
+		t.Errorf("Get() = %+v, want a commit and build time or \"unknown\"", got)
+	}
+}
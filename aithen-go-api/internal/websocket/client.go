@@ -2,9 +2,11 @@ package websocket
 
 import (
 	"encoding/json"
+	"fmt"
 	"log"
 	"time"
 
+	"github.com/aithen/go-api/internal/auth"
 	"github.com/gorilla/websocket"
 )
 
@@ -18,40 +20,112 @@ const (
 	// Send pings to peer with this period. Must be less than pongWait.
 	pingPeriod = (pongWait * 9) / 10
 
-	// Maximum message size allowed from peer.
-	maxMessageSize = 512 * 1024 // 512KB
+	// CloseAuthExpired closes connections whose token expired without an auth_refresh
+	CloseAuthExpired = 4001
 )
 
+// authRefreshRequest is sent by clients to extend a connection's authorization:
+// {"type": "auth_refresh", "token": "<new JWT>"}
+type authRefreshRequest struct {
+	Type  string `json:"type"`
+	Token string `json:"token"`
+}
+
 // Client is a middleman between the websocket connection and the hub.
 type Client struct {
 	hub     *Hub
 	conn    *websocket.Conn
 	send    chan *Message
 	channel string // Channel ID this client is subscribed to
+
+	// userID and authExpiresAt come from the connection's token and are only used by the
+	// read pump. A zero authExpiresAt means the connection's authorization does not expire.
+	userID        int64
+	authExpiresAt time.Time
 }
 
 // readPump pumps messages from the websocket connection to the hub.
+// It also handles auth_refresh messages, and closes the connection with CloseAuthExpired
+// once its token has been expired for longer than the grace period.
 func (c *Client) readPump() {
 	defer func() {
 		c.hub.unregister <- c
 		c.conn.Close()
 	}()
 
-	c.conn.SetReadDeadline(time.Now().Add(pongWait))
-	c.conn.SetReadLimit(maxMessageSize)
+	c.resetReadDeadline()
+	// Frames larger than the limit close the connection with CloseMessageTooBig
+	c.conn.SetReadLimit(getSettings().MaxMessageSize)
 	c.conn.SetPongHandler(func(string) error {
-		c.conn.SetReadDeadline(time.Now().Add(pongWait))
+		c.resetReadDeadline()
 		return nil
 	})
 
 	for {
-		_, _, err := c.conn.ReadMessage()
+		_, data, err := c.conn.ReadMessage()
 		if err != nil {
+			if c.authExpired() {
+				closeMsg := websocket.FormatCloseMessage(CloseAuthExpired, "authentication expired")
+				c.conn.WriteControl(websocket.CloseMessage, closeMsg, time.Now().Add(writeWait))
+				break
+			}
 			if websocket.IsUnexpectedCloseError(err, websocket.CloseGoingAway, websocket.CloseAbnormalClosure) {
 				log.Printf("WebSocket error: %v", err)
 			}
 			break
 		}
+
+		var req authRefreshRequest
+		if json.Unmarshal(data, &req) == nil && req.Type == "auth_refresh" {
+			c.refreshAuth(req.Token)
+		}
+	}
+}
+
+// resetReadDeadline waits for the next pong, but no longer than the end of the auth grace period
+func (c *Client) resetReadDeadline() {
+	deadline := time.Now().Add(pongWait)
+	if !c.authExpiresAt.IsZero() {
+		if authDeadline := c.authExpiresAt.Add(getSettings().AuthGracePeriod); authDeadline.Before(deadline) {
+			deadline = authDeadline
+		}
+	}
+	c.conn.SetReadDeadline(deadline)
+}
+
+// authExpired reports whether the connection's token expired more than the grace period ago
+func (c *Client) authExpired() bool {
+	return !c.authExpiresAt.IsZero() && !time.Now().Before(c.authExpiresAt.Add(getSettings().AuthGracePeriod))
+}
+
+// refreshAuth validates a new token for the connection's user and extends its authorization
+func (c *Client) refreshAuth(tokenString string) {
+	claims, err := auth.ValidateToken(tokenString)
+	if err != nil || claims.UserID != c.userID {
+		c.reply(&Message{Type: "auth_error", Channel: c.channel, Error: "Invalid or expired token", Timestamp: time.Now()})
+		return
+	}
+
+	if claims.ExpiresAt != nil {
+		c.authExpiresAt = claims.ExpiresAt.Time
+	} else {
+		c.authExpiresAt = time.Time{}
+	}
+	c.resetReadDeadline()
+
+	c.reply(&Message{
+		Type:      "auth_refreshed",
+		Channel:   c.channel,
+		Data:      map[string]interface{}{"expires_at": c.authExpiresAt},
+		Timestamp: time.Now(),
+	})
+}
+
+// reply queues a message for this client only, dropping it if the send buffer is full
+func (c *Client) reply(message *Message) {
+	select {
+	case c.send <- message:
+	default:
 	}
 }
 
@@ -78,21 +152,31 @@ func (c *Client) writePump() {
 				return
 			}
 
-			jsonData, err := json.Marshal(message)
+			jsonData, err := marshalOutbound(message)
 			if err != nil {
 				log.Printf("Error marshaling message: %v", err)
-				continue
+				jsonData = nil
 			}
 
-			w.Write(jsonData)
+			written := len(jsonData) > 0
+			if written {
+				w.Write(jsonData)
+			}
 
 			// Add queued messages to the current websocket message.
 			n := len(c.send)
 			for i := 0; i < n; i++ {
-				w.Write([]byte{'\n'})
 				msg := <-c.send
-				jsonData, _ := json.Marshal(msg)
+				jsonData, err := marshalOutbound(msg)
+				if err != nil {
+					log.Printf("Error marshaling message: %v", err)
+					continue
+				}
+				if written {
+					w.Write([]byte{'\n'})
+				}
 				w.Write(jsonData)
+				written = true
 			}
 
 			if err := w.Close(); err != nil {
@@ -108,13 +192,28 @@ func (c *Client) writePump() {
 	}
 }
 
+// marshalOutbound encodes a message for sending, rejecting messages over the outbound size limit.
+func marshalOutbound(message *Message) ([]byte, error) {
+	jsonData, err := json.Marshal(message)
+	if err != nil {
+		return nil, err
+	}
+	if limit := getSettings().MaxOutboundMessageSize; limit > 0 && int64(len(jsonData)) > limit {
+		return nil, fmt.Errorf("message of %d bytes on channel %s exceeds outbound limit of %d bytes", len(jsonData), message.Channel, limit)
+	}
+	return jsonData, nil
+}
+
 // ServeWs handles websocket requests from the peer.
-func ServeWs(hub *Hub, conn *websocket.Conn, channel string) {
+// authExpiresAt is when the connection's token expires; zero disables expiry.
+func ServeWs(hub *Hub, conn *websocket.Conn, channel string, userID int64, authExpiresAt time.Time) {
 	client := &Client{
-		hub:     hub,
-		conn:    conn,
-		send:    make(chan *Message, 256),
-		channel: channel,
+		hub:           hub,
+		conn:          conn,
+		send:          make(chan *Message, 256),
+		channel:       channel,
+		userID:        userID,
+		authExpiresAt: authExpiresAt,
 	}
 
 	client.hub.register <- client
@@ -0,0 +1,171 @@
+package websocket
+
+import (
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aithen/go-api/internal/auth"
+	"github.com/gorilla/websocket"
+)
+
+// useTestSettings replaces the WebSocket settings for the duration of the test
+func useTestSettings(t *testing.T, settings Settings) {
+	t.Helper()
+	saved := getSettings()
+	settingsInstance = settings
+	t.Cleanup(func() { settingsInstance = saved })
+}
+
+func TestOversizedFrameClosesConnection(t *testing.T) {
+	settings := getSettings()
+	settings.MaxMessageSize = 64
+	useTestSettings(t, settings)
+
+	hub := NewHub()
+	go hub.Run()
+	conn := dial(t, wsServer(t, hub), "job-1")
+	defer conn.Close()
+	waitForChannels(t, hub, map[string]int{"job-1": 1})
+
+	// A frame within the limit is accepted
+	if err := conn.WriteMessage(websocket.TextMessage, []byte(`{"type":"ping"}`)); err != nil {
+		t.Fatalf("write small frame: %v", err)
+	}
+	if err := conn.WriteMessage(websocket.TextMessage, []byte(strings.Repeat("x", 65))); err != nil {
+		t.Fatalf("write oversized frame: %v", err)
+	}
+
+	conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+	_, _, err := conn.ReadMessage()
+	var closeErr *websocket.CloseError
+	if !errors.As(err, &closeErr) || closeErr.Code != websocket.CloseMessageTooBig {
+		t.Fatalf("read after oversized frame error = %v, want close %d", err, websocket.CloseMessageTooBig)
+	}
+	waitForChannels(t, hub, map[string]int{})
+}
+
+func TestMarshalOutboundLimit(t *testing.T) {
+	settings := getSettings()
+	settings.MaxOutboundMessageSize = 128
+	useTestSettings(t, settings)
+
+	if _, err := marshalOutbound(&Message{Type: "progress", Channel: "job-1", Data: "ok"}); err != nil {
+		t.Errorf("marshalOutbound() of a small message error = %v", err)
+	}
+	if _, err := marshalOutbound(&Message{Type: "progress", Channel: "job-1", Data: strings.Repeat("x", 200)}); err == nil {
+		t.Error("marshalOutbound() of a message over the limit succeeded, want an error")
+	}
+}
+
+// authServer serves connections on channel "job-1" for user 1 whose token expires at expiresAt
+func authServer(t *testing.T, hub *Hub, expiresAt time.Time) string {
+	t.Helper()
+	upgrader := newUpgrader()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			t.Errorf("upgrade: %v", err)
+			return
+		}
+		ServeWs(hub, conn, "job-1", 1, expiresAt)
+	}))
+	t.Cleanup(srv.Close)
+	return "ws" + strings.TrimPrefix(srv.URL, "http")
+}
+
+// readUntilClose reads messages until the connection closes and returns the close error
+func readUntilClose(t *testing.T, conn *websocket.Conn, timeout time.Duration) error {
+	t.Helper()
+	conn.SetReadDeadline(time.Now().Add(timeout))
+	for {
+		if _, _, err := conn.ReadMessage(); err != nil {
+			return err
+		}
+	}
+}
+
+func TestExpiredConnectionWithoutRefreshIsClosed(t *testing.T) {
+	settings := getSettings()
+	settings.AuthGracePeriod = 200 * time.Millisecond
+	useTestSettings(t, settings)
+
+	hub := NewHub()
+	go hub.Run()
+	conn, _, err := websocket.DefaultDialer.Dial(authServer(t, hub, time.Now()), nil)
+	if err != nil {
+		t.Fatalf("dial: %v", err)
+	}
+	defer conn.Close()
+
+	err = readUntilClose(t, conn, 2*time.Second)
+	var closeErr *websocket.CloseError
+	if !errors.As(err, &closeErr) || closeErr.Code != CloseAuthExpired {
+		t.Fatalf("read error = %v, want close %d", err, CloseAuthExpired)
+	}
+	waitForChannels(t, hub, map[string]int{})
+}
+
+func TestAuthRefresh(t *testing.T) {
+	settings := getSettings()
+	settings.AuthGracePeriod = 300 * time.Millisecond
+	useTestSettings(t, settings)
+
+	refresh := func(t *testing.T, userID int64) (*websocket.Conn, *Message) {
+		t.Helper()
+		hub := NewHub()
+		go hub.Run()
+		conn, _, err := websocket.DefaultDialer.Dial(authServer(t, hub, time.Now()), nil)
+		if err != nil {
+			t.Fatalf("dial: %v", err)
+		}
+		t.Cleanup(func() { conn.Close() })
+
+		token, err := auth.GenerateToken(userID, "user@example.com")
+		if err != nil {
+			t.Fatalf("GenerateToken() error = %v", err)
+		}
+		if err := conn.WriteJSON(map[string]string{"type": "auth_refresh", "token": token}); err != nil {
+			t.Fatalf("write auth_refresh: %v", err)
+		}
+
+		var reply Message
+		conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+		if err := conn.ReadJSON(&reply); err != nil {
+			t.Fatalf("read reply: %v", err)
+		}
+		return conn, &reply
+	}
+
+	t.Run("valid token extends the connection", func(t *testing.T) {
+		conn, reply := refresh(t, 1)
+		if reply.Type != "auth_refreshed" {
+			t.Fatalf("reply = %+v, want auth_refreshed", reply)
+		}
+
+		// Past the grace period the connection is still open: the read times out instead of closing
+		conn.SetReadDeadline(time.Now().Add(2 * settings.AuthGracePeriod))
+		_, _, err := conn.ReadMessage()
+		var netErr net.Error
+		if !errors.As(err, &netErr) || !netErr.Timeout() {
+			t.Errorf("read after the grace period error = %v, want a timeout on an open connection", err)
+		}
+	})
+
+	t.Run("another user's token is rejected", func(t *testing.T) {
+		conn, reply := refresh(t, 2)
+		if reply.Type != "auth_error" {
+			t.Fatalf("reply = %+v, want auth_error", reply)
+		}
+
+		err := readUntilClose(t, conn, 2*time.Second)
+		var closeErr *websocket.CloseError
+		if !errors.As(err, &closeErr) || closeErr.Code != CloseAuthExpired {
+			t.Errorf("read error = %v, want close %d", err, CloseAuthExpired)
+		}
+	})
+}
@@ -0,0 +1,41 @@
+package websocket
+
+import (
+	"sync"
+	"time"
+
+	"github.com/aithen/go-api/internal/config"
+)
+
+// Settings holds configurable WebSocket limits
+type Settings struct {
+	// ReadBufferSize and WriteBufferSize are the I/O buffer sizes in bytes (WS_READ_BUFFER_SIZE, WS_WRITE_BUFFER_SIZE)
+	ReadBufferSize  int
+	WriteBufferSize int
+	// MaxMessageSize caps inbound messages from clients in bytes (WS_MAX_MESSAGE_SIZE)
+	MaxMessageSize int64
+	// MaxOutboundMessageSize caps broadcast messages sent to clients in bytes (WS_MAX_OUTBOUND_MESSAGE_SIZE)
+	MaxOutboundMessageSize int64
+	// AuthGracePeriod is how long a connection stays open after its token expires without
+	// an auth_refresh message (WS_AUTH_GRACE_PERIOD, in seconds)
+	AuthGracePeriod time.Duration
+}
+
+var (
+	settingsInstance Settings
+	settingsOnce     sync.Once
+)
+
+// getSettings loads the WebSocket settings from the environment once
+func getSettings() Settings {
+	settingsOnce.Do(func() {
+		settingsInstance = Settings{
+			ReadBufferSize:         config.GetEnvInt("WS_READ_BUFFER_SIZE", 1024),
+			WriteBufferSize:        config.GetEnvInt("WS_WRITE_BUFFER_SIZE", 1024),
+			MaxMessageSize:         int64(config.GetEnvInt("WS_MAX_MESSAGE_SIZE", 512*1024)),           // 512KB
+			MaxOutboundMessageSize: int64(config.GetEnvInt("WS_MAX_OUTBOUND_MESSAGE_SIZE", 1024*1024)), // 1MB
+			AuthGracePeriod:        time.Duration(config.GetEnvInt("WS_AUTH_GRACE_PERIOD", 60)) * time.Second,
+		}
+	})
+	return settingsInstance
+}
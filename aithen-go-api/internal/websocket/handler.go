@@ -2,23 +2,30 @@ package websocket
 
 import (
 	"net/http"
+	"time"
 
 	"github.com/aithen/go-api/internal/auth"
 	"github.com/gin-gonic/gin"
 	"github.com/gorilla/websocket"
 )
 
-var upgrader = websocket.Upgrader{
-	ReadBufferSize:  1024,
-	WriteBufferSize: 1024,
-	CheckOrigin: func(r *http.Request) bool {
-		// Allow all origins for now - adjust in production
-		return true
-	},
+// newUpgrader creates the connection upgrader using the configured buffer sizes
+func newUpgrader() *websocket.Upgrader {
+	settings := getSettings()
+	return &websocket.Upgrader{
+		ReadBufferSize:  settings.ReadBufferSize,
+		WriteBufferSize: settings.WriteBufferSize,
+		CheckOrigin: func(r *http.Request) bool {
+			// Allow all origins for now - adjust in production
+			return true
+		},
+	}
 }
 
 // HandleWebSocket handles WebSocket connections with authentication
 func HandleWebSocket(hub *Hub) gin.HandlerFunc {
+	upgrader := newUpgrader()
+
 	return func(c *gin.Context) {
 		channel := c.Query("channel")
 		if channel == "" {
@@ -28,6 +35,9 @@ func HandleWebSocket(hub *Hub) gin.HandlerFunc {
 
 		// Check if user is already authenticated (from middleware)
 		userID, alreadyAuthenticated := c.Get("user_id")
+		// The token's expiry, after which the client must send auth_refresh. Unknown when
+		// authenticated by middleware, in which case the connection does not expire.
+		var authExpiresAt time.Time
 
 		// If not authenticated by middleware, authenticate here
 		if !alreadyAuthenticated {
@@ -62,9 +72,10 @@ func HandleWebSocket(hub *Hub) gin.HandlerFunc {
 			// Set user info in context
 			c.Set("user_id", claims.UserID)
 			c.Set("user_email", claims.Email)
-		} else {
-			// User already authenticated by middleware
-			_ = userID // Use the existing user ID from context
+			userID = claims.UserID
+			if claims.ExpiresAt != nil {
+				authExpiresAt = claims.ExpiresAt.Time
+			}
 		}
 
 		conn, err := upgrader.Upgrade(c.Writer, c.Request, nil)
@@ -73,6 +84,25 @@ func HandleWebSocket(hub *Hub) gin.HandlerFunc {
 			return
 		}
 
-		ServeWs(hub, conn, channel)
+		uid, _ := userID.(int64)
+		ServeWs(hub, conn, channel, uid, authExpiresAt)
+	}
+}
+
+// HandleChannelStats returns the active channels and client counts for debugging
+func HandleChannelStats(hub *Hub) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		channels := hub.Channels()
+
+		totalClients := 0
+		for _, count := range channels {
+			totalClients += count
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"channels":       channels,
+			"total_channels": len(channels),
+			"total_clients":  totalClients,
+		})
 	}
 }
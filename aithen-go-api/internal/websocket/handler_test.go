@@ -0,0 +1,77 @@
+package websocket
+
+import (
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gorilla/websocket"
+)
+
+// wsServer serves HandleWebSocket for hub at /ws, with the user already authenticated
+func wsServer(t *testing.T, hub *Hub) string {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+
+	r := gin.New()
+	r.GET("/ws", func(c *gin.Context) { c.Set("user_id", int64(1)) }, HandleWebSocket(hub))
+	srv := httptest.NewServer(r)
+	t.Cleanup(srv.Close)
+	return "ws" + strings.TrimPrefix(srv.URL, "http") + "/ws"
+}
+
+// dial opens a WebSocket connection subscribed to channel
+func dial(t *testing.T, url, channel string) *websocket.Conn {
+	t.Helper()
+	conn, _, err := websocket.DefaultDialer.Dial(url+"?channel="+channel, nil)
+	if err != nil {
+		t.Fatalf("dial %s: %v", channel, err)
+	}
+	return conn
+}
+
+// waitForChannels waits until the hub reports want
+func waitForChannels(t *testing.T, hub *Hub, want map[string]int) {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		got := hub.Channels()
+		if len(got) == len(want) {
+			equal := true
+			for channel, count := range want {
+				if got[channel] != count {
+					equal = false
+				}
+			}
+			if equal {
+				return
+			}
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("Channels() = %v, want %v", got, want)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestChannelCountsReturnToZeroAfterDisconnect(t *testing.T) {
+	hub := NewHub()
+	go hub.Run()
+	url := wsServer(t, hub)
+
+	a1 := dial(t, url, "job-a")
+	a2 := dial(t, url, "job-a")
+	b := dial(t, url, "job-b")
+	waitForChannels(t, hub, map[string]int{"job-a": 2, "job-b": 1})
+
+	a1.Close()
+	waitForChannels(t, hub, map[string]int{"job-a": 1, "job-b": 1})
+
+	// A clean close and a dropped connection both unregister
+	a2.WriteMessage(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseNormalClosure, ""))
+	a2.Close()
+	b.Close()
+	waitForChannels(t, hub, map[string]int{})
+}
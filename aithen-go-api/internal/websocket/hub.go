@@ -4,6 +4,7 @@ import (
 	"encoding/json"
 	"log"
 	"sync"
+	"time"
 )
 
 // Hub maintains the set of active clients and broadcasts messages to the clients
@@ -22,15 +23,24 @@ type Hub struct {
 
 	// Mutex for thread-safe operations
 	mu sync.RWMutex
+
+	// Last sequence number assigned per channel. seqMu is held while a message
+	// is numbered and queued so queue order matches sequence order.
+	seq   map[string]int64
+	seqMu sync.Mutex
 }
 
 // Message represents a WebSocket message
 type Message struct {
-	Type     string      `json:"type"`               // message, progress, error, complete
+	Type     string      `json:"type"`               // message, progress, error, complete, auth_refreshed, auth_error
 	Channel  string      `json:"channel"`            // Channel ID (e.g., training job ID)
 	Data     interface{} `json:"data"`               // Message payload
 	Progress *Progress   `json:"progress,omitempty"` // Progress information
 	Error    string      `json:"error,omitempty"`    // Error message if any
+	// Seq increases by one for each message broadcast on a channel, so clients can
+	// order updates and detect gaps or duplicates after a reconnect
+	Seq       int64     `json:"seq"`
+	Timestamp time.Time `json:"timestamp"` // When the message was broadcast
 }
 
 // Progress represents training progress
@@ -80,6 +90,7 @@ func NewHub() *Hub {
 		broadcast:  make(chan *Message, 256),
 		register:   make(chan *Client),
 		unregister: make(chan *Client),
+		seq:        make(map[string]int64),
 	}
 }
 
@@ -138,6 +149,18 @@ func (h *Hub) Run() {
 	}
 }
 
+// Channels returns the active channels and the number of clients connected to each
+func (h *Hub) Channels() map[string]int {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	channels := make(map[string]int, len(h.clients))
+	for channel, clients := range h.clients {
+		channels[channel] = len(clients)
+	}
+	return channels
+}
+
 // Broadcast sends a message to all clients in a channel
 func (h *Hub) Broadcast(channel string, messageType string, data interface{}, progress *Progress, err error) {
 	msg := &Message{
@@ -151,6 +174,13 @@ func (h *Hub) Broadcast(channel string, messageType string, data interface{}, pr
 		msg.Type = "error"
 	}
 
+	h.seqMu.Lock()
+	defer h.seqMu.Unlock()
+
+	h.seq[channel]++
+	msg.Seq = h.seq[channel]
+	msg.Timestamp = time.Now().UTC()
+
 	// Marshal to JSON for logging
 	jsonData, _ := json.Marshal(msg)
 	log.Printf("Broadcasting to channel %s: %s", channel, string(jsonData))
@@ -0,0 +1,79 @@
+package websocket
+
+import (
+	"testing"
+	"time"
+)
+
+// receive returns the next message queued for client
+func receive(t *testing.T, client *Client) *Message {
+	t.Helper()
+	select {
+	case msg := <-client.send:
+		return msg
+	case <-time.After(time.Second):
+		t.Fatalf("no message received on channel %s", client.channel)
+		return nil
+	}
+}
+
+func TestBroadcastSequenceNumbersPerChannel(t *testing.T) {
+	hub := NewHub()
+
+	for i := 0; i < 3; i++ {
+		hub.Broadcast("a", "progress", i, nil, nil)
+		hub.Broadcast("b", "progress", i, nil, nil)
+	}
+	close(hub.broadcast)
+
+	last := map[string]int64{}
+	var lastTime time.Time
+	for msg := range hub.broadcast {
+		if want := last[msg.Channel] + 1; msg.Seq != want {
+			t.Errorf("channel %s message seq = %d, want %d", msg.Channel, msg.Seq, want)
+		}
+		last[msg.Channel] = msg.Seq
+		if msg.Timestamp.IsZero() || msg.Timestamp.Before(lastTime) {
+			t.Errorf("channel %s message %d timestamp = %v, want set and not before %v", msg.Channel, msg.Seq, msg.Timestamp, lastTime)
+		}
+		lastTime = msg.Timestamp
+	}
+	if last["a"] != 3 || last["b"] != 3 {
+		t.Errorf("last seq per channel = %v, want 3 for each", last)
+	}
+}
+
+func TestSequenceGapVisibleAfterReconnect(t *testing.T) {
+	hub := NewHub()
+	go hub.Run()
+
+	client := &Client{hub: hub, send: make(chan *Message, 8), channel: "job-1"}
+	hub.register <- client
+	hub.Broadcast("job-1", "progress", nil, nil, nil)
+	hub.Broadcast("job-1", "progress", nil, nil, nil)
+	if first, second := receive(t, client), receive(t, client); first.Seq != 1 || second.Seq != 2 {
+		t.Fatalf("received seq %d, %d, want 1, 2", first.Seq, second.Seq)
+	}
+	seen := int64(2)
+
+	// Updates broadcast while the client is disconnected are missed
+	hub.unregister <- client
+	hub.Broadcast("job-1", "progress", nil, nil, nil)
+	// Run handles one event at a time, so once it has taken the broadcast off the queue
+	// it is delivered before the next registration
+	for len(hub.broadcast) > 0 {
+		time.Sleep(time.Millisecond)
+	}
+
+	reconnected := &Client{hub: hub, send: make(chan *Message, 8), channel: "job-1"}
+	hub.register <- reconnected
+	hub.Broadcast("job-1", "progress", nil, nil, nil)
+
+	msg := receive(t, reconnected)
+	if msg.Seq != 4 {
+		t.Fatalf("received seq %d after reconnecting, want 4", msg.Seq)
+	}
+	if gap := msg.Seq - seen - 1; gap != 1 {
+		t.Errorf("client detected %d missed messages, want 1", gap)
+	}
+}
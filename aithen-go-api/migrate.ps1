@@ -25,7 +25,7 @@ switch ($Command.ToLower()) {
         Write-Host "⚠️  WARNING: This will drop ALL tables and re-run migrations!" -ForegroundColor Yellow
         $confirm = Read-Host "Are you sure? (yes/no)"
         if ($confirm -eq "yes") {
-            go run cmd/migrate/main.go -command fresh
+            go run cmd/migrate/main.go -command fresh -force
         } else {
             Write-Host "Cancelled." -ForegroundColor Red
             exit 1
@@ -17,7 +17,7 @@ case $COMMAND in
         echo "⚠️  WARNING: This will drop ALL tables and re-run migrations!"
         read -p "Are you sure? (yes/no): " confirm
         if [ "$confirm" = "yes" ]; then
-            go run cmd/migrate/main.go -command fresh
+            go run cmd/migrate/main.go -command fresh -force
         else
             echo "Cancelled."
             exit 1
@@ -6,9 +6,11 @@
  * - chatStream: Streaming chat request (SSE)
  * - getPersonalities: List all available personalities
  * - getPersonality: Get a specific personality by ID
+ * - getOrganizationPersonalities: List personalities with an organization's default
+ * - setOrganizationDefaultPersonality: Set or clear an organization's default personality
  */
 
-import { post, streamPost, parseSSE, get, type ApiResponse, type StreamingResponse } from './index';
+import { post, put, streamPost, parseSSE, get, type ApiResponse, type StreamingResponse } from './index';
 
 /**
  * Chat message interface
@@ -26,6 +28,8 @@ export interface ChatRequest {
   personality?: string;
   max_tokens?: number;
   stream?: boolean;
+  model?: string; // Defaults to the first model from GET /ai/models
+  organization?: string; // Organization slug whose default personality applies when personality is omitted
 }
 
 /**
@@ -201,3 +205,41 @@ export const getPersonality = async (
   return get<Personality>(`/ai/personalities/${id}`);
 };
 
+/**
+ * A personality as listed for an organization
+ */
+export interface OrganizationPersonality {
+  id: string;
+  is_default: boolean;
+}
+
+/**
+ * List personalities, marking the organization's default
+ * 
+ * @param orgSlug - Organization slug
+ * @returns Personalities and the default personality ID (null when none is set)
+ */
+export const getOrganizationPersonalities = async (
+  orgSlug: string
+): Promise<ApiResponse<{ personalities: OrganizationPersonality[]; default_personality_id: string | null }>> => {
+  return get<{ personalities: OrganizationPersonality[]; default_personality_id: string | null }>(
+    `/orgs/${orgSlug}/personalities`
+  );
+};
+
+/**
+ * Set an organization's default personality (owners and admins only)
+ * 
+ * @param orgSlug - Organization slug
+ * @param personalityId - Personality ID, or null to clear the default
+ * @returns The new default personality ID
+ */
+export const setOrganizationDefaultPersonality = async (
+  orgSlug: string,
+  personalityId: string | null
+): Promise<ApiResponse<{ default_personality_id: string | null }>> => {
+  return put<{ default_personality_id: string | null }>(
+    `/orgs/${orgSlug}/default-personality`,
+    { personality_id: personalityId }
+  );
+};
@@ -8,7 +8,7 @@
  * - refresh: Refresh the JWT token
  */
 
-import { post, get } from './api';
+import { post, get, put } from './api';
 import { setToken, removeToken } from './auth';
 import type { ApiResponse } from './types';
 
@@ -49,6 +49,14 @@ export interface User {
   updated_at: string;
 }
 
+/**
+ * The current user's own account, including login details hidden from other users
+ */
+export interface CurrentUser extends User {
+  last_login_at: string | null;
+  last_login_ip: string | null;
+}
+
 /**
  * Authentication response (typically contains token and user info)
  */
@@ -158,6 +166,126 @@ export const register = async (
   return response;
 };
 
+/**
+ * Result of an organization slug availability check
+ */
+export interface SlugAvailability {
+  available: boolean;
+  normalized: string; // The slug as it would be stored, which may differ from the input
+}
+
+/**
+ * Check whether an organization slug is free before registering.
+ * The endpoint is public and rate limited per IP.
+ * 
+ * @param slug - Desired organization slug
+ * @returns Whether the normalized slug is available
+ */
+export const checkSlugAvailability = async (
+  slug: string
+): Promise<ApiResponse<SlugAvailability>> => {
+  const params = new URLSearchParams({ slug });
+  return get<SlugAvailability>(`/orgs/slug-available?${params}`, {
+    skipAuth: true,
+  });
+};
+
+/**
+ * Organization member role
+ */
+export type OrganizationRole = 'owner' | 'admin' | 'member' | 'viewer';
+
+/**
+ * A user's membership in an organization
+ */
+export interface OrganizationMember {
+  id: string;
+  organization_id: string;
+  user_id: string;
+  role: OrganizationRole;
+  status: 'active' | 'invited' | 'suspended';
+  joined_at: string;
+  created_at: string;
+  updated_at: string;
+}
+
+/**
+ * Add an existing user to an organization by email.
+ * Owners and admins may add members; admins may only give the member or viewer role.
+ * Adding a user who is already a member fails with 409.
+ * 
+ * @param orgSlug - Organization slug
+ * @param email - Email of the user to add
+ * @param role - Role to give the new member
+ * @returns The new membership
+ */
+export const addMember = async (
+  orgSlug: string,
+  email: string,
+  role?: OrganizationRole
+): Promise<ApiResponse<OrganizationMember>> => {
+  return post<OrganizationMember>(`/orgs/${orgSlug}/members`, { email, role });
+};
+
+/**
+ * Get the role given to members added to an organization without one.
+ * 
+ * @param orgSlug - Organization slug
+ * @returns The default member role
+ */
+export const getDefaultMemberRole = async (
+  orgSlug: string
+): Promise<ApiResponse<{ default_member_role: OrganizationRole }>> => {
+  return get<{ default_member_role: OrganizationRole }>(`/orgs/${orgSlug}/default-member-role`);
+};
+
+/**
+ * Set the role given to members added to an organization without one (owners only).
+ * Pass null to fall back to the server default.
+ * 
+ * @param orgSlug - Organization slug
+ * @param role - admin, member or viewer, or null to clear
+ * @returns The default member role now in effect
+ */
+export const setDefaultMemberRole = async (
+  orgSlug: string,
+  role: Exclude<OrganizationRole, 'owner'> | null
+): Promise<ApiResponse<{ default_member_role: OrganizationRole }>> => {
+  return put<{ default_member_role: OrganizationRole }>(`/orgs/${orgSlug}/default-member-role`, { role });
+};
+
+/**
+ * Change an organization member's role.
+ * Owners may set any role; admins may only switch members between member and viewer.
+ * Demoting the last owner fails with 409.
+ * 
+ * @param orgSlug - Organization slug
+ * @param userId - ID of the member's user
+ * @param role - New role
+ * @returns The updated membership
+ */
+export const updateMemberRole = async (
+  orgSlug: string,
+  userId: string,
+  role: OrganizationRole
+): Promise<ApiResponse<OrganizationMember>> => {
+  return put<OrganizationMember>(`/orgs/${orgSlug}/members/${userId}/role`, { role });
+};
+
+/**
+ * Leave an organization. The sole owner cannot leave (409) and must make
+ * another member an owner first, unless they are its only active member:
+ * then the organization is deleted and `organization_deleted` is true.
+ * 
+ * @param orgSlug - Organization slug
+ * @returns Confirmation message
+ */
+export const leaveOrganization = async (
+  orgSlug: string
+): Promise<ApiResponse<{ message: string; organization_deleted?: boolean }>> => {
+  return post<{ message: string; organization_deleted?: boolean }>(`/orgs/${orgSlug}/leave`);
+};
+
 /**
  * Sign out the current user
  * 
@@ -266,8 +394,8 @@ export const refresh = async (): Promise<ApiResponse<{ token: string }>> => {
  * }
  * ```
  */
-export const getCurrentUser = async (): Promise<ApiResponse<User>> => {
+export const getCurrentUser = async (): Promise<ApiResponse<CurrentUser>> => {
   // Token is automatically included via interceptor (skipAuth: false by default)
-  return get<User>('/auth/me');
+  return get<CurrentUser>('/auth/me');
 };
 
@@ -4,13 +4,14 @@
  * Provides API functions for chat operations:
  * - createChat: Create a new chat
  * - getChat: Get a chat by ID with messages
- * - getChats: Get all chats for the current user
+ * - getChats: Get a page of chats for the current user
  * - updateChat: Update a chat's title
  * - deleteChat: Delete a chat
+ * - deleteMessages: Delete several messages from a chat
  */
 
 import { post, get, put, del } from './api';
-import type { ApiResponse } from './types';
+import type { ApiResponse, Page } from './types';
 
 /**
  * Chat information
@@ -19,6 +20,8 @@ export interface Chat {
   id: string; // Always string to avoid precision loss with large Snowflake IDs
   user_id: string; // Always string to avoid precision loss
   title: string;
+  model?: string; // Model of the latest assistant reply
+  metadata: Record<string, unknown>; // Integrator-supplied metadata, {} when unset
   created_at: string;
   updated_at: string;
 }
@@ -31,6 +34,7 @@ export interface ChatMessage {
   chat_id: string; // Always string to avoid precision loss
   role: 'user' | 'assistant' | 'system';
   content: string;
+  model?: string; // AI model that generated an assistant message
   created_at: string;
 }
 
@@ -39,6 +43,7 @@ export interface ChatMessage {
  */
 export interface CreateChatRequest {
   title?: string;
+  metadata?: Record<string, unknown>;
 }
 
 /**
@@ -46,6 +51,7 @@ export interface CreateChatRequest {
  */
 export interface UpdateChatRequest {
   title: string;
+  metadata?: Record<string, unknown> | null; // Omitted or null keeps the current metadata
 }
 
 /**
@@ -101,22 +107,34 @@ export const getChat = async (id: number | string): Promise<ApiResponse<ChatWith
 };
 
 /**
- * Get all chats for the current user
+ * Get a page of chats for the current user, most recently updated first
  * 
- * @returns Array of chats
+ * @param options - Optional page size, the cursor returned by the previous page and a metadata filter
+ * @returns A page of chats
  * 
  * @example
  * ```ts
  * try {
  *   const response = await getChats();
- *   console.log('Chats:', response.data);
+ *   console.log('Chats:', response.data.data);
+ *   if (response.data.has_more) {
+ *     const next = await getChats({ cursor: response.data.next_cursor });
+ *   }
  * } catch (error) {
  *   console.error('Failed to get chats:', error);
  * }
  * ```
  */
-export const getChats = async (): Promise<ApiResponse<Chat[]>> => {
-  return get<Chat[]>('/chats');
+export const getChats = async (
+  options: { limit?: number; cursor?: string; metadataKey?: string; metadataValue?: string } = {}
+): Promise<ApiResponse<Page<Chat>>> => {
+  const params = new URLSearchParams();
+  if (options.limit !== undefined) params.set('limit', String(options.limit));
+  if (options.cursor) params.set('cursor', options.cursor);
+  if (options.metadataKey) params.set('metadata_key', options.metadataKey);
+  if (options.metadataValue) params.set('metadata_value', options.metadataValue);
+  const query = params.toString();
+  return get<Page<Chat>>(query ? `/chats?${query}` : '/chats');
 };
 
 /**
@@ -163,6 +181,30 @@ export const deleteChat = async (id: string): Promise<ApiResponse<void>> => {
   return del<void>(`/chats/${id}`);
 };
 
+/**
+ * Copy a chat with all its messages, e.g. to reuse it as a template
+ * 
+ * @param id - Chat ID (string)
+ * @returns The new chat, titled like the original with " (copy)" appended
+ */
+export const copyChat = async (id: string): Promise<ApiResponse<Chat>> => {
+  return post<Chat>(`/chats/${id}/copy`);
+};
+
+/**
+ * Get a single message of a chat, e.g. for a deep link to the message
+ * 
+ * @param chatId - Chat ID (string)
+ * @param messageId - Message ID (string)
+ * @returns The message with its attachments
+ */
+export const getMessage = async (
+  chatId: string,
+  messageId: string
+): Promise<ApiResponse<ChatMessage>> => {
+  return get<ChatMessage>(`/chats/${chatId}/messages/${messageId}`);
+};
+
 /**
  * Add a message to a chat
  * 
@@ -179,3 +221,21 @@ export const addMessage = async (
   return post<ChatMessage>(`/chats/${chatId}/messages`, { role, content });
 };
 
+
+/**
+ * Delete several messages from a chat
+ * 
+ * Either all of the messages are deleted or, if any of them is not in the chat, none are.
+ * 
+ * @param chatId - Chat ID (string)
+ * @param messageIds - IDs of the messages to delete
+ * @returns Number of messages deleted
+ */
+export const deleteMessages = async (
+  chatId: string,
+  messageIds: string[]
+): Promise<ApiResponse<{ message: string; deleted: number }>> => {
+  return post<{ message: string; deleted: number }>(`/chats/${chatId}/messages/bulk-delete`, {
+    message_ids: messageIds,
+  });
+};
@@ -77,6 +77,12 @@ export {
   signout,
   refresh,
   getCurrentUser,
+  checkSlugAvailability,
+  addMember,
+  getDefaultMemberRole,
+  setDefaultMemberRole,
+  updateMemberRole,
+  leaveOrganization,
 } from './authApi';
 
 export type {
@@ -85,6 +91,10 @@ export type {
   AuthResponse,
   RefreshTokenRequest,
   User,
+  CurrentUser,
+  SlugAvailability,
+  OrganizationRole,
+  OrganizationMember,
 } from './authApi';
 
 // AI API endpoints
@@ -94,6 +104,8 @@ export {
   streamChatWithCallback,
   getPersonalities,
   getPersonality,
+  getOrganizationPersonalities,
+  setOrganizationDefaultPersonality,
 } from './aiApi';
 
 export type {
@@ -102,6 +114,7 @@ export type {
   ChatResponse,
   Personality,
   ChatStreamChunk,
+  OrganizationPersonality,
 } from './aiApi';
 
 // Chat API endpoints
@@ -111,7 +124,10 @@ export {
   getChats,
   updateChat,
   deleteChat,
+  copyChat,
+  getMessage,
   addMessage,
+  deleteMessages,
 } from './chatApi';
 
 export type {
@@ -125,22 +141,50 @@ export type {
 // Knowledge Base API endpoints
 export {
   getKnowledgeBases,
+  searchKnowledgeBases,
+  getKnowledgeBaseStats,
   getKnowledgeBase,
   createKnowledgeBase,
   updateKnowledgeBase,
   deleteKnowledgeBase,
+  moveKnowledgeBase,
   getKnowledgeBaseFiles,
   uploadKnowledgeBaseFiles,
   deleteKnowledgeBaseFile,
+  getOrganizationFiles,
+  getMyKnowledgeBasePermissions,
   trainKnowledgeBase,
+  trainKnowledgeBasesBatch,
+  cancelAllTraining,
+  resetKnowledgeBaseStatus,
+  reembedKnowledgeBase,
+  getTrainingEstimate,
   getKnowledgeBaseVersions,
+  getTrainingHistory,
+  getTrainingErrors,
+  getVersionQualityBreakdown,
   deleteKnowledgeBaseVersion,
+  deleteKnowledgeBaseChunk,
 } from './knowledgeBaseApi';
 
 export type {
   KnowledgeBase,
+  KnowledgeBaseSearchResponse,
+  KnowledgeBaseStats,
   KnowledgeBaseFile,
+  OrganizationFile,
+  OrganizationFilesQuery,
   KnowledgeBaseVersion,
+  TrainingRun,
+  TrainBatchResult,
+  TrainBatchResponse,
+  CancelAllTrainingResponse,
+  MyKnowledgeBasePermissions,
+  TrainingError,
+  ChunkSizeDistribution,
+  FileEmbeddingStats,
+  VersionQualityBreakdown,
+  TrainingEstimate,
   CreateKnowledgeBaseRequest,
   UpdateKnowledgeBaseRequest,
 } from './knowledgeBaseApi';
@@ -150,6 +194,7 @@ export type {
   ApiRequestConfig,
   ApiResponse,
   ApiError,
+  Page,
   HttpMethod,
   AuthType,
   TokenGetter,
@@ -7,13 +7,15 @@
  * - createKnowledgeBase: Create a new knowledge base
  * - updateKnowledgeBase: Update a knowledge base
  * - deleteKnowledgeBase: Delete a knowledge base
+ * - moveKnowledgeBase: Move a knowledge base to another organization
  * - getKnowledgeBaseFiles: Get all files for a knowledge base
  * - uploadKnowledgeBaseFiles: Upload files to a knowledge base
  * - deleteKnowledgeBaseFile: Delete a file from a knowledge base
+ * - getOrganizationFiles: List files across all knowledge bases of an organization
  */
 
-import { get, post, put, del } from './api';
-import type { ApiResponse } from './types';
+import { get, post, patch, del } from './api';
+import type { ApiResponse, Page } from './types';
 
 /**
  * Knowledge base information
@@ -33,6 +35,7 @@ export interface KnowledgeBase {
   name: string;
   description: string;
   status: 'active' | 'training' | 'error';
+  compress_chunks: boolean; // Chunk text of later training runs is stored gzip-compressed
   created_at: string;
   updated_at: string;
   total_datasets: number;
@@ -40,6 +43,7 @@ export interface KnowledgeBase {
   total_versions: number;
   last_updated: string;
   quality_metrics?: QualityMetrics;
+  needs_retraining?: boolean; // Only in the detail response: files were added after the last completed training
 }
 
 /**
@@ -57,6 +61,45 @@ export interface KnowledgeBaseFile {
   updated_at: string;
 }
 
+/**
+ * Knowledge base file listed across an organization
+ */
+export interface OrganizationFile extends KnowledgeBaseFile {
+  knowledge_base_name: string;
+}
+
+/**
+ * Filters and paging for getOrganizationFiles
+ */
+export interface OrganizationFilesQuery {
+  kbId?: string;
+  status?: KnowledgeBaseFile['status'];
+  q?: string; // Case-insensitive match on the file name
+  limit?: number;
+  cursor?: string; // next_cursor from the previous page
+}
+
+/**
+ * List files across all knowledge bases of an organization, newest first
+ * 
+ * @param orgSlug - Organization slug
+ * @param query - Optional filters, page size and cursor
+ * @returns A page of files with the knowledge base each belongs to
+ */
+export const getOrganizationFiles = async (
+  orgSlug: string,
+  query: OrganizationFilesQuery = {}
+): Promise<ApiResponse<Page<OrganizationFile>>> => {
+  const params = new URLSearchParams();
+  if (query.kbId) params.set('kb_id', query.kbId);
+  if (query.status) params.set('status', query.status);
+  if (query.q) params.set('q', query.q);
+  if (query.limit !== undefined) params.set('limit', String(query.limit));
+  if (query.cursor) params.set('cursor', query.cursor);
+  const qs = params.toString();
+  return get<Page<OrganizationFile>>(`/orgs/${orgSlug}/files${qs ? `?${qs}` : ''}`);
+};
+
 /**
  * Create knowledge base request payload
  */
@@ -71,7 +114,7 @@ export interface CreateKnowledgeBaseRequest {
 export interface UpdateKnowledgeBaseRequest {
   name?: string;
   description?: string;
-  status?: 'active' | 'training' | 'error';
+  compress_chunks?: boolean;
 }
 
 /**
@@ -86,6 +129,59 @@ export const getKnowledgeBases = async (
   return get<KnowledgeBase[]>(`/orgs/${orgSlug}/knowledge-bases`);
 };
 
+/**
+ * Search results for knowledge bases
+ */
+export interface KnowledgeBaseSearchResponse {
+  knowledge_bases: KnowledgeBase[];
+  total: number;
+  limit: number;
+  offset: number;
+}
+
+/**
+ * Search an organization's knowledge bases by name or description
+ * 
+ * @param orgSlug - Organization slug
+ * @param query - Case-insensitive search term
+ * @param options - Optional page size and offset
+ * @returns Matching knowledge bases and the total number of matches
+ */
+export const searchKnowledgeBases = async (
+  orgSlug: string,
+  query: string,
+  options: { limit?: number; offset?: number } = {}
+): Promise<ApiResponse<KnowledgeBaseSearchResponse>> => {
+  const params = new URLSearchParams({ q: query });
+  if (options.limit !== undefined) params.set('limit', String(options.limit));
+  if (options.offset !== undefined) params.set('offset', String(options.offset));
+  return get<KnowledgeBaseSearchResponse>(`/orgs/${orgSlug}/knowledge-bases/search?${params}`);
+};
+
+/**
+ * Aggregate knowledge base counts for an organization's dashboard
+ */
+export interface KnowledgeBaseStats {
+  knowledge_bases: number;
+  by_status: Record<'active' | 'training' | 'error', number>;
+  files: number;
+  embeddings: number;
+  average_quality_score: number | null;
+}
+
+/**
+ * Get knowledge base counts by status, total files and embeddings, and the average
+ * quality score of completed versions
+ * 
+ * @param orgSlug - Organization slug
+ * @returns Aggregate knowledge base stats
+ */
+export const getKnowledgeBaseStats = async (
+  orgSlug: string
+): Promise<ApiResponse<KnowledgeBaseStats>> => {
+  return get<KnowledgeBaseStats>(`/orgs/${orgSlug}/knowledge-bases/stats`);
+};
+
 /**
  * Get a knowledge base by ID
  * 
@@ -114,6 +210,34 @@ export const createKnowledgeBase = async (
   return post<KnowledgeBase>(`/orgs/${orgSlug}/knowledge-bases`, data);
 };
 
+/**
+ * The current user's effective permission on a knowledge base and the actions it allows
+ */
+export interface MyKnowledgeBasePermissions {
+  permission: 'read' | 'write' | 'admin';
+  role: 'owner' | 'admin' | 'member' | 'viewer'; // Organization role
+  can_read: boolean;
+  can_write: boolean;
+  can_train: boolean;
+  can_delete: boolean;
+  can_manage: boolean; // Manage permissions and export
+  can_move: boolean;
+}
+
+/**
+ * Get what the current user may do with a knowledge base
+ * 
+ * @param orgSlug - Organization slug
+ * @param id - Knowledge base ID
+ * @returns The user's effective permission and allowed actions
+ */
+export const getMyKnowledgeBasePermissions = async (
+  orgSlug: string,
+  id: string
+): Promise<ApiResponse<MyKnowledgeBasePermissions>> => {
+  return get<MyKnowledgeBasePermissions>(`/orgs/${orgSlug}/knowledge-bases/${id}/permissions/me`);
+};
+
 /**
  * Update a knowledge base
  * 
@@ -127,7 +251,7 @@ export const updateKnowledgeBase = async (
   id: string,
   data: UpdateKnowledgeBaseRequest
 ): Promise<ApiResponse<KnowledgeBase>> => {
-  return put<KnowledgeBase>(`/orgs/${orgSlug}/knowledge-bases/${id}`, data);
+  return patch<KnowledgeBase>(`/orgs/${orgSlug}/knowledge-bases/${id}`, data);
 };
 
 /**
@@ -144,6 +268,26 @@ export const deleteKnowledgeBase = async (
   return del<void>(`/orgs/${orgSlug}/knowledge-bases/${id}`);
 };
 
+/**
+ * Move a knowledge base, with its files and versions, to another organization.
+ * Requires the owner or admin role in both organizations.
+ * 
+ * @param orgSlug - Slug of the organization the knowledge base belongs to
+ * @param id - Knowledge base ID
+ * @param targetOrgSlug - Slug of the organization to move it to
+ * @returns The moved knowledge base
+ */
+export const moveKnowledgeBase = async (
+  orgSlug: string,
+  id: string,
+  targetOrgSlug: string
+): Promise<ApiResponse<{ message: string; knowledge_base: KnowledgeBase }>> => {
+  return post<{ message: string; knowledge_base: KnowledgeBase }>(
+    `/orgs/${orgSlug}/knowledge-bases/${id}/move`,
+    { target_org_slug: targetOrgSlug }
+  );
+};
+
 /**
  * Get all files for a knowledge base
  * 
@@ -232,14 +376,158 @@ export const deleteKnowledgeBaseFile = async (
  * 
  * @param orgSlug - Organization slug
  * @param kbId - Knowledge base ID
+ * @param options - Optional chunking parameters (service defaults when omitted)
  * @returns Training response with version information
  */
 export const trainKnowledgeBase = async (
   orgSlug: string,
-  kbId: string
+  kbId: string,
+  options?: { chunk_size?: number; chunk_overlap?: number }
 ): Promise<ApiResponse<{ message: string; version: any; knowledge_base: KnowledgeBase; channel: string }>> => {
   return post<{ message: string; version: any; knowledge_base: KnowledgeBase; channel: string }>(
-    `/orgs/${orgSlug}/knowledge-bases/${kbId}/train`
+    `/orgs/${orgSlug}/knowledge-bases/${kbId}/train`,
+    options
+  );
+};
+
+/**
+ * Outcome of one knowledge base in a batch training request
+ */
+export interface TrainBatchResult {
+  knowledge_base_id: string;
+  status: 'started' | 'queued' | 'already_training' | 'rejected';
+  error?: string; // Why the knowledge base was rejected
+  version?: KnowledgeBaseVersion;
+  channel?: string;
+}
+
+/**
+ * Response of a batch training request
+ */
+export interface TrainBatchResponse {
+  results: TrainBatchResult[];
+  started: number;
+  queued: number;
+  already_training: number;
+  rejected: number;
+}
+
+/**
+ * Train several knowledge bases of an organization, e.g. after an embedding model change.
+ * Runs beyond the plan's concurrent training limit are queued and start as earlier ones finish.
+ * 
+ * @param orgSlug - Organization slug
+ * @param kbIds - Knowledge base IDs
+ * @param options - Optional chunking parameters and embedding model (service defaults when omitted)
+ * @returns The outcome per knowledge base
+ */
+export const trainKnowledgeBasesBatch = async (
+  orgSlug: string,
+  kbIds: string[],
+  options?: { chunk_size?: number; chunk_overlap?: number; embedding_model?: string }
+): Promise<ApiResponse<TrainBatchResponse>> => {
+  return post<TrainBatchResponse>(`/orgs/${orgSlug}/knowledge-bases/train-batch`, {
+    knowledge_base_ids: kbIds,
+    ...options,
+  });
+};
+
+/**
+ * Result of cancelling all training of an organization
+ */
+export interface CancelAllTrainingResponse {
+  cancelled_jobs: number;
+  cancelled_versions: string[];
+  dropped_waiting: number; // Batch runs that were waiting on the concurrent training limit
+}
+
+/**
+ * Cancel every pending and processing training job of an organization (owners and admins only).
+ * Affected versions are marked cancelled.
+ * 
+ * @param orgSlug - Organization slug
+ * @returns The number of cancelled jobs and the cancelled versions
+ */
+export const cancelAllTraining = async (
+  orgSlug: string
+): Promise<ApiResponse<CancelAllTrainingResponse>> => {
+  return post<CancelAllTrainingResponse>(`/orgs/${orgSlug}/knowledge-bases/cancel-all`);
+};
+
+/**
+ * Reset a knowledge base stuck in training after its run died (owners and admins only).
+ * Its training versions are marked failed. Refused with 409 while jobs are still active.
+ * 
+ * @param orgSlug - Organization slug
+ * @param kbId - Knowledge base ID
+ * @returns The reset knowledge base and the versions marked failed
+ */
+export const resetKnowledgeBaseStatus = async (
+  orgSlug: string,
+  kbId: string
+): Promise<ApiResponse<{ knowledge_base: KnowledgeBase; failed_versions: string[] }>> => {
+  return post<{ knowledge_base: KnowledgeBase; failed_versions: string[] }>(
+    `/orgs/${orgSlug}/knowledge-bases/${kbId}/reset-status`
+  );
+};
+
+/**
+ * Rough forecast of a training run over a knowledge base's current files
+ */
+export interface TrainingEstimate {
+  file_count: number;
+  total_file_size: number;
+  chunk_size: number;
+  chunk_overlap: number;
+  estimated_chunks: number;
+  estimated_embeddings: number;
+  estimated_duration_seconds: number | null; // null until some version has completed
+  throughput: {
+    versions: number;
+    total_chunks: number;
+    chunk_bytes: number;
+    total_seconds: number;
+  };
+}
+
+/**
+ * Estimate the chunks, embeddings and duration of training a knowledge base
+ * 
+ * @param orgSlug - Organization slug
+ * @param kbId - Knowledge base ID
+ * @param options - Optional chunking parameters (service defaults when omitted)
+ * @returns Training estimate
+ */
+export const getTrainingEstimate = async (
+  orgSlug: string,
+  kbId: string,
+  options: { chunk_size?: number; chunk_overlap?: number } = {}
+): Promise<ApiResponse<TrainingEstimate>> => {
+  const params = new URLSearchParams();
+  if (options.chunk_size !== undefined) params.set('chunk_size', String(options.chunk_size));
+  if (options.chunk_overlap !== undefined) params.set('chunk_overlap', String(options.chunk_overlap));
+  const query = params.toString();
+  return get<TrainingEstimate>(
+    `/orgs/${orgSlug}/knowledge-bases/${kbId}/train-estimate${query ? `?${query}` : ''}`
+  );
+};
+
+/**
+ * Retrain a knowledge base with another embedding model (creates a new version)
+ * 
+ * @param orgSlug - Organization slug
+ * @param kbId - Knowledge base ID
+ * @param embeddingModel - Embedding model for the new version
+ * @returns Training response with version information
+ */
+export const reembedKnowledgeBase = async (
+  orgSlug: string,
+  kbId: string,
+  embeddingModel: string
+): Promise<ApiResponse<{ message: string; version: KnowledgeBaseVersion; knowledge_base: KnowledgeBase; channel: string }>> => {
+  return post<{ message: string; version: KnowledgeBaseVersion; knowledge_base: KnowledgeBase; channel: string }>(
+    `/orgs/${orgSlug}/knowledge-bases/${kbId}/reembed`,
+    { embedding_model: embeddingModel }
   );
 };
 
@@ -251,7 +539,7 @@ export interface KnowledgeBaseVersion {
   knowledge_base_id: string;
   version_number: number;
   version_string: string;
-  status: 'training' | 'completed' | 'failed';
+  status: 'training' | 'completed' | 'failed' | 'cancelled';
   training_started_at: string;
   training_completed_at?: string;
   total_embeddings: number;
@@ -259,7 +547,11 @@ export interface KnowledgeBaseVersion {
   embedding_dimension: number;
   total_storage_size: number;
   average_chunk_size: number;
+  compression_savings: number; // Bytes saved by compressing chunk text
   quality_score?: number;
+  chunk_size: number | null;
+  chunk_overlap: number | null;
+  embedding_model: string | null; // null when the training service default was used
   created_at: string;
   updated_at: string;
 }
@@ -278,6 +570,126 @@ export const getKnowledgeBaseVersions = async (
   return get<KnowledgeBaseVersion[]>(`/orgs/${orgSlug}/knowledge-bases/${kbId}/versions`);
 };
 
+/**
+ * A training run in a knowledge base's training history
+ */
+export interface TrainingRun {
+  version_id: string;
+  version_number: number;
+  version_string: string;
+  status: 'training' | 'completed' | 'failed' | 'cancelled';
+  training_started_at: string;
+  training_completed_at: string | null;
+  duration_seconds: number | null; // null while the run is in progress
+  quality_score: number | null;
+}
+
+/**
+ * Get the training runs of a knowledge base, oldest first
+ * 
+ * @param orgSlug - Organization slug
+ * @param kbId - Knowledge base ID
+ * @returns Training runs with their outcome and duration
+ */
+export const getTrainingHistory = async (
+  orgSlug: string,
+  kbId: string
+): Promise<ApiResponse<{ runs: TrainingRun[] }>> => {
+  return get<{ runs: TrainingRun[] }>(`/orgs/${orgSlug}/knowledge-bases/${kbId}/training-history`);
+};
+
+/**
+ * A file that failed during a version's training run
+ */
+export interface TrainingError {
+  id: string;
+  version_id: string;
+  file_id: string;
+  file_name: string;
+  job_id: string;
+  job_index: number;
+  error_message: string;
+  created_at: string;
+}
+
+/**
+ * Get the files that failed in a version's training run and why
+ * 
+ * @param orgSlug - Organization slug
+ * @param kbId - Knowledge base ID
+ * @param versionId - Version ID
+ * @returns The version and its per-file training errors
+ */
+export const getTrainingErrors = async (
+  orgSlug: string,
+  kbId: string,
+  versionId: string
+): Promise<ApiResponse<{ version: KnowledgeBaseVersion; errors: TrainingError[] }>> => {
+  return get<{ version: KnowledgeBaseVersion; errors: TrainingError[] }>(
+    `/orgs/${orgSlug}/knowledge-bases/${kbId}/versions/${versionId}/errors`
+  );
+};
+
+/**
+ * Sizes in characters of a version's chunks
+ */
+export interface ChunkSizeDistribution {
+  chunks: number;
+  min: number;
+  max: number;
+  average: number;
+  p25: number;
+  median: number;
+  p75: number;
+  p90: number;
+  p99: number;
+}
+
+/**
+ * Embeddings a version produced for one file
+ */
+export interface FileEmbeddingStats {
+  file_id: string;
+  name: string;
+  embeddings: number;
+  total_chunk_size: number;
+  average_chunk_size: number;
+}
+
+/**
+ * Breakdown of a version's quality score
+ */
+export interface VersionQualityBreakdown {
+  version: KnowledgeBaseVersion;
+  chunk_sizes: ChunkSizeDistribution;
+  coverage: {
+    files: number;
+    files_embedded: number;
+    files_not_embedded: number;
+    coverage_percent: number;
+  };
+  files: FileEmbeddingStats[]; // Most embeddings first
+}
+
+/**
+ * Get what lies behind a version's quality score: the chunk size distribution,
+ * embeddings per file and the files that produced no embeddings
+ * 
+ * @param orgSlug - Organization slug
+ * @param kbId - Knowledge base ID
+ * @param versionId - Version ID
+ * @returns The version's quality breakdown
+ */
+export const getVersionQualityBreakdown = async (
+  orgSlug: string,
+  kbId: string,
+  versionId: string
+): Promise<ApiResponse<VersionQualityBreakdown>> => {
+  return get<VersionQualityBreakdown>(
+    `/orgs/${orgSlug}/knowledge-bases/${kbId}/versions/${versionId}/quality-breakdown`
+  );
+};
+
 /**
  * Delete a specific version
  * 
@@ -294,3 +706,23 @@ export const deleteKnowledgeBaseVersion = async (
   return del<void>(`/orgs/${orgSlug}/knowledge-bases/${kbId}/versions/${versionId}`);
 };
 
+/**
+ * Delete a single chunk (embedding) from a version
+ * 
+ * @param orgSlug - Organization slug
+ * @param kbId - Knowledge base ID
+ * @param versionId - Version ID
+ * @param chunkId - Chunk (embedding) ID
+ * @returns The version with recomputed quality metrics
+ */
+export const deleteKnowledgeBaseChunk = async (
+  orgSlug: string,
+  kbId: string,
+  versionId: string,
+  chunkId: string
+): Promise<ApiResponse<{ message: string; version: KnowledgeBaseVersion }>> => {
+  return del<{ message: string; version: KnowledgeBaseVersion }>(
+    `/orgs/${orgSlug}/knowledge-bases/${kbId}/versions/${versionId}/chunks/${chunkId}`
+  );
+};
+
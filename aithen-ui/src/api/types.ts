@@ -46,6 +46,15 @@ export interface ApiResponse<T = any> {
   headers: Headers;
 }
 
+/**
+ * Standard envelope for cursor-paginated lists
+ */
+export interface Page<T> {
+  data: T[];
+  next_cursor?: string; // Pass back as `cursor` to fetch the next page
+  has_more: boolean;
+}
+
 export interface ApiError {
   message: string;
   status?: number;
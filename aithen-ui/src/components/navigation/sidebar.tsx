@@ -27,7 +27,7 @@ export default function Sidebar({ onNewChat, onSettingsClick, isOpen = false, on
       const response = await getChats();
       // Ensure we always have an array, even if API returns null/undefined
       // IDs are now strings to preserve precision (handled by backend JSON marshaling)
-      setChats(Array.isArray(response.data) ? response.data : []);
+      setChats(Array.isArray(response.data?.data) ? response.data.data : []);
     } catch (error) {
       console.error('Failed to load chats:', error);
       setChats([]);
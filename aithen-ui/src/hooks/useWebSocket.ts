@@ -17,6 +17,8 @@ export interface WebSocketMessage {
     message?: string;
   };
   error?: string;
+  seq?: number; // increases by one per message on a channel
+  timestamp?: string;
 }
 
 export interface UseWebSocketOptions {